@@ -0,0 +1,204 @@
+package main
+
+import (
+	"crypto/rand"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/argon2"
+
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/config"
+	"github.com/sahays/grpc-proto-go-flutter-template/pkg/password"
+)
+
+const calibrateUsage = `usage: server calibrate-argon2 [flags]
+
+Benchmarks Argon2id on this host and prints the ARGON2_MEMORY,
+ARGON2_ITERATIONS and ARGON2_PARALLELISM values that make one hash take
+about -duration. Memory is doubled up to -max-memory first, then iterations
+are added, since memory is what makes guessing costly on GPUs. Run it on
+hardware like the servers'. With -write, the values are set in an env file
+instead; passwords are re-hashed with them at their users' next login.
+
+flags:`
+
+const (
+	// calibrateMinMemory is the memory calibration starts from, in KiB
+	calibrateMinMemory = 16 * 1024
+	// calibrateMaxIterations bounds the iterations calibration tries
+	calibrateMaxIterations = 32
+)
+
+// argon2Params is a set of Argon2id parameters calibration measures
+type argon2Params struct {
+	memory      uint32 // KiB
+	iterations  uint32
+	parallelism uint8
+}
+
+// runCalibrateArgon2 runs the calibrate-argon2 subcommand
+func runCalibrateArgon2(cfg *config.Config, args []string) error {
+	flags := flag.NewFlagSet("calibrate-argon2", flag.ContinueOnError)
+	target := flags.Duration("duration", 500*time.Millisecond, "time one hash should take")
+	maxMemory := flags.Uint("max-memory", 256, "most memory one hash may use, in MiB; each concurrent login needs this much")
+	parallelism := flags.Uint("parallelism", uint(cfg.Argon2.Parallelism), "threads one hash uses, 1 to 255")
+	samples := flags.Int("samples", 3, "hashes timed for each set of parameters; the median is used")
+	envFile := flags.String("write", "", "env file to set the values in, such as .env, instead of printing them")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), calibrateUsage)
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); errors.Is(err, flag.ErrHelp) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if *target <= 0 {
+		return fmt.Errorf("-duration must be positive")
+	}
+	if *maxMemory*1024 < calibrateMinMemory || *maxMemory*1024 > password.MaxMemory {
+		return fmt.Errorf("-max-memory must be between %d and %d MiB", calibrateMinMemory/1024, password.MaxMemory/1024)
+	}
+	if *parallelism < 1 || *parallelism > 255 {
+		return fmt.Errorf("-parallelism must be between 1 and 255")
+	}
+	if *samples < 1 {
+		return fmt.Errorf("-samples must be at least 1")
+	}
+
+	measure := func(params argon2Params) (time.Duration, error) {
+		took, err := timeArgon2(cfg, params, *samples)
+		if err != nil {
+			return 0, err
+		}
+		fmt.Fprintf(os.Stderr, "m=%d t=%d p=%d: %v\n", params.memory, params.iterations, params.parallelism, took.Round(time.Millisecond))
+		return took, nil
+	}
+
+	best := argon2Params{memory: calibrateMinMemory, iterations: 1, parallelism: uint8(*parallelism)}
+	took, err := measure(best)
+	if err != nil {
+		return err
+	}
+	if took > *target {
+		fmt.Fprintf(os.Stderr, "warning: the smallest parameters tried already take %v, more than %v\n",
+			took.Round(time.Millisecond), *target)
+	}
+
+	// Memory first, then iterations, keeping the last parameters within target
+	limit := uint32(*maxMemory * 1024)
+	for took <= *target && best.memory*2 <= limit {
+		next := best
+		next.memory *= 2
+		nextTook, err := measure(next)
+		if err != nil {
+			return err
+		}
+		if nextTook > *target {
+			break
+		}
+		best, took = next, nextTook
+	}
+	for took <= *target && best.iterations < calibrateMaxIterations {
+		next := best
+		next.iterations++
+		nextTook, err := measure(next)
+		if err != nil {
+			return err
+		}
+		if nextTook > *target {
+			break
+		}
+		best, took = next, nextTook
+	}
+
+	values := []struct{ key, value string }{
+		{"ARGON2_MEMORY", strconv.FormatUint(uint64(best.memory), 10)},
+		{"ARGON2_ITERATIONS", strconv.FormatUint(uint64(best.iterations), 10)},
+		{"ARGON2_PARALLELISM", strconv.FormatUint(uint64(best.parallelism), 10)},
+	}
+	summary := fmt.Sprintf("# %d MiB, %d iterations, %d threads: %v per hash on this host",
+		best.memory/1024, best.iterations, best.parallelism, took.Round(time.Millisecond))
+
+	if *envFile != "" {
+		if err := setEnvValues(*envFile, values); err != nil {
+			return err
+		}
+		fmt.Println(summary)
+		fmt.Printf("# Written to %s\n", *envFile)
+		return nil
+	}
+	fmt.Println(summary)
+	for _, v := range values {
+		fmt.Printf("%s=%s\n", v.key, v.value)
+	}
+	return nil
+}
+
+// timeArgon2 returns the median time of samples Argon2id hashes of a random
+// password with params and the configured salt and key lengths
+func timeArgon2(cfg *config.Config, params argon2Params, samples int) (time.Duration, error) {
+	input := make([]byte, 32)
+	salt := make([]byte, cfg.Argon2.SaltLength)
+	times := make([]time.Duration, samples)
+	for i := range times {
+		if _, err := rand.Read(input); err != nil {
+			return 0, err
+		}
+		if _, err := rand.Read(salt); err != nil {
+			return 0, err
+		}
+		start := time.Now()
+		argon2.IDKey(input, salt, params.iterations, params.memory, params.parallelism, cfg.Argon2.KeyLength)
+		times[i] = time.Since(start)
+	}
+	sort.Slice(times, func(i, j int) bool { return times[i] < times[j] })
+	return times[samples/2], nil
+}
+
+// setEnvValues sets each key in the env file at path, replacing the value of
+// a KEY=value line and keeping any comment after it, or appending a line.
+// The file is created if it doesn't exist.
+func setEnvValues(path string, values []struct{ key, value string }) error {
+	mode := os.FileMode(0600)
+	content, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	lines := strings.Split(strings.TrimSuffix(string(content), "\n"), "\n")
+	if len(content) == 0 {
+		lines = nil
+	}
+	for _, v := range values {
+		found := false
+		for i, line := range lines {
+			rest, ok := strings.CutPrefix(strings.TrimLeft(line, " \t"), v.key+"=")
+			if !ok {
+				continue
+			}
+			// Keep the padding and comment after the old value
+			end := strings.IndexAny(rest, " \t")
+			if end < 0 {
+				end = len(rest)
+			}
+			lines[i] = v.key + "=" + v.value + rest[end:]
+			found = true
+		}
+		if !found {
+			lines = append(lines, v.key+"="+v.value)
+		}
+	}
+
+	return os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), mode)
+}
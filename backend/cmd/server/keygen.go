@@ -0,0 +1,102 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/config"
+	"github.com/sahays/grpc-proto-go-flutter-template/pkg/jwt"
+)
+
+const keygenUsage = `usage: server keygen [flags]
+
+Generates a JWT signing key pair for the algorithm, or an HS256 secret, and
+prints the environment variables that configure the server to use it. The
+private key is readable only by its owner.
+
+flags:`
+
+// keygenSecretBytes is the size of a generated HS256 secret, comfortably
+// above config.MinJWTSecretLength
+const keygenSecretBytes = 48
+
+// runKeygen runs the keygen subcommand
+func runKeygen(cfg *config.Config, args []string) error {
+	flags := flag.NewFlagSet("keygen", flag.ContinueOnError)
+	algorithm := flags.String("alg", cfg.JWT.Algorithm, "algorithm to generate a key for: RS256, ES256, EdDSA or HS256")
+	bits := flags.Int("bits", cfg.JWT.RSAKeyBits, "RSA key size for RS256: 2048, 3072 or 4096")
+	dir := flags.String("out", "keys", "directory to write the key pair to")
+	kid := flags.String("kid", "", "key ID; prints a JWT_KEYS entry to append instead of the key path variables")
+	force := flags.Bool("force", false, "overwrite existing key files")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), keygenUsage)
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); errors.Is(err, flag.ErrHelp) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	switch *algorithm {
+	case jwt.AlgorithmRS256, jwt.AlgorithmES256, jwt.AlgorithmEdDSA:
+	case jwt.AlgorithmHS256:
+		// A shared secret goes in the environment, not a file
+		secret := make([]byte, keygenSecretBytes)
+		if _, err := rand.Read(secret); err != nil {
+			return err
+		}
+		fmt.Printf("JWT_ALGORITHM=%s\n", jwt.AlgorithmHS256)
+		fmt.Printf("JWT_SECRET=%s\n", base64.StdEncoding.EncodeToString(secret))
+		return nil
+	default:
+		return fmt.Errorf("unsupported algorithm %q: want RS256, ES256, EdDSA or HS256", *algorithm)
+	}
+
+	name := "jwt"
+	if *kid != "" {
+		// The ID names the files and ends at the first colon in JWT_KEYS
+		if strings.ContainsAny(*kid, `:/\`) {
+			return fmt.Errorf("key ID %q must not contain a colon or a path separator", *kid)
+		}
+		name = *kid
+	}
+	privatePath, err := filepath.Abs(filepath.Join(*dir, name+".pem"))
+	if err != nil {
+		return err
+	}
+	publicPath, err := filepath.Abs(filepath.Join(*dir, name+".pub"))
+	if err != nil {
+		return err
+	}
+	if !*force {
+		for _, path := range []string{privatePath, publicPath} {
+			if _, err := os.Stat(path); err == nil {
+				return fmt.Errorf("%s already exists; use -force to overwrite it", path)
+			}
+		}
+	}
+
+	if err := os.MkdirAll(*dir, 0700); err != nil {
+		return err
+	}
+	if err := jwt.GenerateKeyPair(*algorithm, *bits, privatePath, publicPath); err != nil {
+		return fmt.Errorf("failed to generate %s key pair: %w", *algorithm, err)
+	}
+
+	fmt.Printf("JWT_ALGORITHM=%s\n", *algorithm)
+	if *kid != "" {
+		fmt.Printf("# Append to JWT_KEYS, after any keys already in it\n")
+		fmt.Printf("JWT_KEYS=%s:%s\n", *kid, privatePath)
+		return nil
+	}
+	fmt.Printf("JWT_PRIVATE_KEY_PATH=%s\n", privatePath)
+	fmt.Printf("JWT_PUBLIC_KEY_PATH=%s\n", publicPath)
+	return nil
+}
@@ -2,38 +2,72 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
-	"net"
+	"net/http"
 	"os"
 	"os/signal"
+	"sort"
 	"syscall"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/admin"
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/audit"
 	"github.com/sahays/grpc-proto-go-flutter-template/internal/auth"
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/authz"
 	"github.com/sahays/grpc-proto-go-flutter-template/internal/cache"
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/chat"
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/clientip"
 	"github.com/sahays/grpc-proto-go-flutter-template/internal/config"
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/connectapi"
 	"github.com/sahays/grpc-proto-go-flutter-template/internal/db"
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/embedded"
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/gateway"
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/grpcweb"
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/health"
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/history"
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/ipfilter"
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/mesh"
 	"github.com/sahays/grpc-proto-go-flutter-template/internal/middleware"
 	"github.com/sahays/grpc-proto-go-flutter-template/internal/models"
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/outbox"
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/protovalidate"
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/retry"
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/seed"
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/server"
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/tenant"
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/tracing"
+	"github.com/sahays/grpc-proto-go-flutter-template/pkg/crypto"
 	"github.com/sahays/grpc-proto-go-flutter-template/pkg/jwt"
+	"github.com/sahays/grpc-proto-go-flutter-template/pkg/kms"
 	"github.com/sahays/grpc-proto-go-flutter-template/pkg/logger"
 	"github.com/sahays/grpc-proto-go-flutter-template/pkg/password"
 	pb "github.com/sahays/grpc-proto-go-flutter-template/proto"
+	adminpb "github.com/sahays/grpc-proto-go-flutter-template/proto/admin"
+	chatpb "github.com/sahays/grpc-proto-go-flutter-template/proto/chat"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.uber.org/zap"
 	"google.golang.org/grpc"
+	channelzservice "google.golang.org/grpc/channelz/service"
+	_ "google.golang.org/grpc/encoding/gzip" // Register gzip compressor
+	"google.golang.org/grpc/keepalive"
 	"google.golang.org/grpc/reflection"
+	"google.golang.org/protobuf/reflect/protoreflect"
 )
 
 var (
 	healthCheck = flag.Bool("health-check", false, "Perform health check and exit")
+	configFile  = flag.String("config", os.Getenv("CONFIG_FILE"), "YAML, JSON or TOML config file; environment variables override its settings")
 )
 
 func main() {
 	flag.Parse()
 
 	// Load configuration
-	cfg, err := config.Load()
+	cfg, err := config.Load(*configFile)
 	if err != nil {
 		log.Fatalf("Failed to load configuration: %v", err)
 	}
@@ -47,110 +81,469 @@ func main() {
 		os.Exit(0)
 	}
 
+	// Schema migrations (server migrate up|down|status|force)
+	if flag.Arg(0) == "migrate" {
+		if err := runMigrate(cfg, flag.Args()[1:]); err != nil {
+			log.Fatalf("Migration failed: %v", err)
+		}
+		os.Exit(0)
+	}
+
+	// Encryption key rotation (server rotate-keys)
+	if flag.Arg(0) == "rotate-keys" {
+		if err := runRotateKeys(cfg); err != nil {
+			log.Fatalf("Key rotation failed: %v", err)
+		}
+		os.Exit(0)
+	}
+
+	// JWT signing keys (server keygen [flags])
+	if flag.Arg(0) == "keygen" {
+		if err := runKeygen(cfg, flag.Args()[1:]); err != nil {
+			log.Fatalf("Key generation failed: %v", err)
+		}
+		os.Exit(0)
+	}
+
+	// Argon2 parameters for this host (server calibrate-argon2 [flags])
+	if flag.Arg(0) == "calibrate-argon2" {
+		if err := runCalibrateArgon2(cfg, flag.Args()[1:]); err != nil {
+			log.Fatalf("Calibration failed: %v", err)
+		}
+		os.Exit(0)
+	}
+
+	// Demo data (server seed [flags])
+	if flag.Arg(0) == "seed" {
+		if err := runSeed(cfg, flag.Args()[1:]); err != nil {
+			log.Fatalf("Seeding failed: %v", err)
+		}
+		os.Exit(0)
+	}
+
+	if err := run(cfg); err != nil {
+		log.Fatalf("Server error: %v", err)
+	}
+}
+
+// run wires up dependencies and serves until SIGINT/SIGTERM or a listener
+// fails. Returning (rather than exiting) lets deferred cleanup run.
+func run(cfg *config.Config) error {
+	// Compile the validation rules of every request up front, so an invalid
+	// rule in a proto file stops the server here
+	requestValidator, err := protovalidate.New(protovalidate.WithMessageDescriptors(
+		requestDescriptors(pb.File_auth_proto, adminpb.File_admin_admin_proto, chatpb.File_chat_chat_proto)...,
+	))
+	if err != nil {
+		return fmt.Errorf("invalid validation rules in proto files: %w", err)
+	}
+
+	// Initialize tracing before any instrumented clients are created
+	shutdownTracing, err := tracing.New(context.Background(), cfg)
+	if err != nil {
+		return fmt.Errorf("failed to initialize tracing: %w", err)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			log.Printf("Tracing shutdown error: %v", err)
+		}
+	}()
+
+	// Start embedded PostgreSQL and Redis, if enabled, before connecting
+	stopEmbedded, err := embedded.Start(cfg)
+	if err != nil {
+		return err
+	}
+	defer stopEmbedded()
+
+	// PostgreSQL and Redis may still be starting; SIGINT or SIGTERM ends the
+	// wait
+	startupCtx, stopStartup := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stopStartup()
+
 	// Initialize database
-	database, err := db.New(cfg)
+	var database *db.DB
+	err = retry.Do(startupCtx, cfg.StartupRetry, "PostgreSQL", func() (err error) {
+		database, err = db.New(cfg)
+		return err
+	})
 	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
+		return fmt.Errorf("failed to connect to database: %w", err)
 	}
 	defer database.Close()
 	log.Println("Connected to PostgreSQL")
 
-	// Initialize Redis cache
-	redisCache, err := cache.New(cfg)
+	// Initialize the cache, in Redis unless CACHE_BACKEND=memory
+	var redisCache *cache.Cache
+	err = retry.Do(startupCtx, cfg.StartupRetry, "Redis", func() (err error) {
+		redisCache, err = cache.New(cfg)
+		return err
+	})
 	if err != nil {
-		log.Fatalf("Failed to connect to Redis: %v", err)
+		return fmt.Errorf("failed to connect to Redis: %w", err)
 	}
 	defer redisCache.Close()
-	log.Println("Connected to Redis")
+	log.Printf("Connected to %s cache", redisCache.Backend())
+	stopStartup()
 
-	// Check database migrations
+	// Apply pending schema migrations
 	ctx := context.Background()
-	if err := database.RunMigrations(ctx); err != nil {
-		log.Printf("Warning: Migration check failed: %v", err)
+	if cfg.Database.AutoMigrate {
+		if err := database.RunMigrations(ctx); err != nil {
+			return fmt.Errorf("failed to run migrations: %w", err)
+		}
+		log.Println("Database migrations applied")
 	}
 
-	// Print database stats
-	stats := database.Stats()
-	log.Printf("Database pool: OpenConnections=%d, InUse=%d, Idle=%d",
-		stats.OpenConnections, stats.InUse, stats.Idle)
-
 	// Initialize logger
 	zapLogger, err := logger.New(cfg)
 	if err != nil {
-		log.Fatalf("Failed to initialize logger: %v", err)
+		return fmt.Errorf("failed to initialize logger: %w", err)
 	}
 	defer zapLogger.Sync()
 
-	// Initialize JWT service
-	jwtService, err := jwt.New(cfg)
-	if err != nil {
-		log.Fatalf("Failed to initialize JWT service: %v", err)
+	// Initialize JWT service, signing in the KMS if one holds the key
+	var jwtService *jwt.Service
+	// kmsHealth checks the KMS, if its signer supports that
+	var kmsHealth health.Dependency
+	if cfg.JWT.KMSProvider != "" {
+		signer, err := kms.NewSigner(ctx, cfg)
+		if err != nil {
+			return fmt.Errorf("failed to initialize KMS signer: %w", err)
+		}
+		kmsHealth, _ = signer.(health.Dependency)
+		jwtService, err = jwt.NewWithSigner(cfg, signer)
+		if err != nil {
+			return fmt.Errorf("failed to initialize JWT service: %w", err)
+		}
+	} else {
+		jwtService, err = jwt.New(cfg)
+		if err != nil {
+			return fmt.Errorf("failed to initialize JWT service: %w", err)
+		}
 	}
 	zapLogger.Info("JWT service initialized")
 
+	// Initialize authorization policy
+	authorizer, err := authz.New(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to initialize authorization: %w", err)
+	}
+	zapLogger.Info("Authorization policy loaded")
+
+	// Initialize the event bus that broadcasts cache invalidations, forced
+	// logouts and config changes between instances
+	bus := cache.NewBus(redisCache, zapLogger)
+	busCtx, stopBus := context.WithCancel(ctx)
+	defer stopBus()
+	go bus.Run(busCtx)
+
+	// SIGHUP on any instance reloads the authorization policy files on all
+	// of them
+	bus.Handle(cache.EventConfigChanged, func(key string) {
+		if key != cache.ConfigAuthzPolicy {
+			return
+		}
+		if err := authorizer.Reload(); err != nil {
+			zapLogger.Error("failed to reload authorization policy", zap.Error(err))
+			return
+		}
+		zapLogger.Info("Authorization policy reloaded")
+	})
+	go reloadOnHangup(busCtx, bus, zapLogger)
+
+	// Initialize client IP resolution behind trusted proxies
+	clientIPResolver, err := clientip.New(cfg.Security.TrustedProxies)
+	if err != nil {
+		return fmt.Errorf("invalid TRUSTED_PROXIES: %w", err)
+	}
+
+	// Initialize IP filter; the dynamic denylist syncs from Redis until shutdown
+	ipFilter, err := ipfilter.New(cfg, redisCache, zapLogger)
+	if err != nil {
+		return fmt.Errorf("failed to initialize IP filter: %w", err)
+	}
+	filterCtx, stopFilter := context.WithCancel(ctx)
+	defer stopFilter()
+	go ipFilter.Run(filterCtx)
+
+	// Initialize audit log writer; pending entries are flushed on shutdown
+	var auditWriter *audit.Writer
+	if cfg.Audit.Enabled {
+		auditWriter = audit.NewWriter(models.NewAuditLogRepository(database.Pool), cfg.Audit.BufferSize, zapLogger)
+		defer auditWriter.Close()
+	}
+
 	// Initialize password service
-	passService := password.New(cfg)
+	passService, err := password.New(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to initialize password hashing: %w", err)
+	}
+
+	// Route lag-tolerant reads to read replicas, if any are configured
+	dbRouter, err := db.NewRouter(cfg, database.Pool)
+	if err != nil {
+		return fmt.Errorf("failed to initialize read replicas: %w", err)
+	}
+	defer dbRouter.Close()
+	if n := len(cfg.Database.Replicas); n > 0 {
+		log.Printf("Read replica routing enabled for %d replicas", n)
+	}
+
+	// Initialize the keyring that encrypts personal data at rest
+	keyring, err := crypto.New(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to initialize encryption: %w", err)
+	}
+
+	// Cache user lookups in memory, and in the shared cache unless its TTL
+	// is zero; every instance drops a changed user when PostgreSQL announces
+	// the change
+	var userCache *models.UserCache
+	if cfg.UserCache.Enabled {
+		var shared *models.SharedUserCache
+		if cfg.UserCache.SharedTTL > 0 {
+			shared = models.NewSharedUserCache(redisCache, cfg.UserCache.SharedTTL, keyring)
+		}
+		userCache = models.NewUserCache(cfg.UserCache.TTL, cfg.UserCache.MaxEntries, shared)
+		listenCtx, stopListening := context.WithCancel(ctx)
+		defer stopListening()
+		go db.NewListener(database.Pool, models.UserChangedChannel, userCache).Run(listenCtx)
+		log.Printf("User cache enabled")
+	}
 
 	// Initialize repositories
-	userRepo := models.NewUserRepository(database.DB)
+	userRepo := models.NewUserRepository(dbRouter, userCache, models.NewIDGenerator(cfg.Database.UUIDv7), keyring)
+	txManager := db.NewTxManager(database.Pool)
+	userHistoryRepo := models.NewUserHistoryRepository(database.Pool)
+	historyRecorder := history.NewRecorder(userRepo, userHistoryRepo, txManager)
+
+	// Initialize outbox relay; events are recorded only while it runs
+	var eventOutbox auth.EventOutbox
+	if cfg.Outbox.Enabled {
+		outboxRepo := models.NewOutboxRepository(database.Pool)
+		publisher, err := outbox.NewPublisher(cfg.Outbox, redisCache)
+		if err != nil {
+			return fmt.Errorf("failed to initialize outbox publisher: %w", err)
+		}
+		relayCtx, stopRelay := context.WithCancel(ctx)
+		defer stopRelay()
+		go outbox.NewRelay(cfg.Outbox, outboxRepo, txManager, publisher, redisCache, zapLogger).Run(relayCtx)
+		eventOutbox = outboxRepo
+		zapLogger.Info("Outbox relay started")
+	}
 
 	// Initialize auth service
-	authService := auth.NewService(cfg, userRepo, redisCache, jwtService, passService)
+	authService := auth.NewService(cfg, userRepo, txManager, eventOutbox, historyRecorder, redisCache, redisCache, authorizer, jwtService, passService)
 	zapLogger.Info("Auth service initialized")
 
-	// Create gRPC server with interceptors
-	grpcServer := grpc.NewServer(
+	// Initialize tenant resolver, shared by the tenant interceptors and the
+	// admin service
+	tenantRepo := models.NewTenantRepository(database.Pool)
+	tenantResolver := tenant.NewResolver(tenantRepo, cfg.Tenancy.CacheTTL)
+	bus.Handle(cache.EventTenantChanged, tenantResolver.Forget)
+	if cfg.Tenancy.Enabled {
+		log.Printf("Multi-tenancy enabled")
+	}
+
+	// Fill an empty development database with demo data
+	if cfg.Database.Seed {
+		seeder := seed.New(tenantRepo, userRepo, redisCache, passService)
+		if err := seedIfEmpty(ctx, cfg, seeder, userRepo); err != nil {
+			return fmt.Errorf("failed to seed database: %w", err)
+		}
+	}
+
+	// Initialize admin service
+	healthChecker := health.NewChecker(database, dbRouter, redisCache)
+	if kmsHealth != nil {
+		healthChecker.Add(health.KMS+":"+cfg.JWT.KMSProvider, kmsHealth)
+	}
+	adminService := admin.NewService(redisCache, bus, tenantRepo, tenantResolver, userRepo, historyRecorder, userHistoryRepo, healthChecker)
+
+	// End a user's open streams on every instance when an admin
+	// deactivates them
+	streamCloser := middleware.NewStreamCloser()
+	bus.Handle(cache.EventForcedLogout, streamCloser.CloseUser)
+
+	// Initialize chat service (bidirectional streaming example)
+	chatService := chat.NewService()
+
+	// Create gRPC server with interceptors; in xDS mode the service mesh
+	// control plane manages its listener, TLS, and routing
+	meshCtx, stopMesh := context.WithCancel(ctx)
+	defer stopMesh()
+	grpcServer, err := mesh.NewServer(meshCtx, cfg.XDS, zapLogger,
+		grpc.KeepaliveParams(keepalive.ServerParameters{
+			Time:                  cfg.Server.KeepaliveTime,
+			Timeout:               cfg.Server.KeepaliveTimeout,
+			MaxConnectionIdle:     cfg.Server.MaxConnectionIdle,
+			MaxConnectionAge:      cfg.Server.MaxConnectionAge,
+			MaxConnectionAgeGrace: cfg.Server.MaxConnectionAgeGrace,
+		}),
+		grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{
+			MinTime:             cfg.Server.KeepaliveMinTime,
+			PermitWithoutStream: cfg.Server.KeepalivePermitWithoutStream,
+		}),
+		grpc.MaxRecvMsgSize(cfg.Server.MaxRecvMsgSize),
+		grpc.MaxSendMsgSize(cfg.Server.MaxSendMsgSize),
+		// Spans for each RPC, continuing trace context from incoming metadata
+		grpc.StatsHandler(otelgrpc.NewServerHandler()),
 		grpc.ChainUnaryInterceptor(
-			middleware.LoggingInterceptor(zapLogger),
+			middleware.RequestIDInterceptor(),
+			middleware.ClientIPInterceptor(clientIPResolver),
+			middleware.LocalizationInterceptor(),
+			middleware.MetricsInterceptor(),
+			middleware.LoggingInterceptor(zapLogger, cfg.Environment),
+			middleware.IPFilterInterceptor(ipFilter),
+			middleware.LoadShedInterceptor(cfg.LoadShed),
+			middleware.CompressionInterceptor(cfg.Server.CompressionMode, cfg.Server.CompressionMinSize),
+			middleware.RecoveryInterceptor(zapLogger),
+			middleware.TimeoutInterceptor(cfg.Server.DefaultRPCTimeout, cfg.Server.MaxRPCTimeout, cfg.Server.MethodTimeouts),
+			middleware.AuthInterceptor(jwtService, redisCache, cfg.Security.PublicMethods, cfg.Security.CacheOutage.TokenRevocation),
+			middleware.TenantInterceptor(tenantResolver, cfg.Tenancy.Enabled),
+			middleware.AuditInterceptor(auditWriter, cfg.Audit.RedactFields),
+			middleware.AuthorizationInterceptor(authorizer, zapLogger),
+			middleware.RateLimitInterceptor(redisCache, cfg.RateLimit, cfg.Security.CacheOutage.RateLimit),
+			middleware.ValidationInterceptor(requestValidator),
+			middleware.IdempotencyInterceptor(redisCache, keyring, cfg.Idempotency, cfg.Security.CacheOutage.Idempotency),
+		),
+		grpc.ChainStreamInterceptor(
+			middleware.StreamRequestIDInterceptor(),
+			middleware.StreamClientIPInterceptor(clientIPResolver),
+			middleware.StreamLocalizationInterceptor(),
+			middleware.StreamMetricsInterceptor(),
+			middleware.StreamLoggingInterceptor(zapLogger, cfg.Environment),
+			middleware.StreamIPFilterInterceptor(ipFilter),
+			middleware.StreamRecoveryInterceptor(zapLogger),
+			middleware.StreamReflectionAuthInterceptor(jwtService, redisCache, cfg.Server.ReflectionRequireAdmin, cfg.Security.CacheOutage.TokenRevocation),
+			middleware.StreamAuthInterceptor(jwtService, redisCache, cfg.Security.PublicMethods, cfg.Security.CacheOutage.TokenRevocation),
+			middleware.StreamCloserInterceptor(streamCloser),
+			middleware.StreamTenantInterceptor(tenantResolver, cfg.Tenancy.Enabled),
+			middleware.StreamAuthorizationInterceptor(authorizer, zapLogger),
+			middleware.StreamRateLimitInterceptor(redisCache, cfg.RateLimit, cfg.Security.CacheOutage.RateLimit),
+			middleware.StreamValidationInterceptor(requestValidator),
 		),
 	)
+	if err != nil {
+		return fmt.Errorf("failed to create gRPC server: %w", err)
+	}
 
 	// Register services
 	pb.RegisterAuthServiceServer(grpcServer, authService)
 	zapLogger.Info("AuthService registered")
 
+	adminpb.RegisterAdminServiceServer(grpcServer, adminService)
+	zapLogger.Info("AdminService registered")
+
+	chatpb.RegisterChatServiceServer(grpcServer, chatService)
+	zapLogger.Info("ChatService registered")
+
 	// Enable reflection for grpcurl
-	reflection.Register(grpcServer)
+	if cfg.Server.ReflectionEnabled {
+		reflection.Register(grpcServer)
+		zapLogger.Info("Server reflection enabled")
+	}
 
-	// Start server
-	address := fmt.Sprintf("%s:%s", cfg.Server.Host, cfg.Server.Port)
-	listener, err := net.Listen("tcp", address)
-	if err != nil {
-		log.Fatalf("Failed to listen on %s: %v", address, err)
+	// Listeners start in the order added and stop in reverse: diagnostics
+	// first so they outlive everything else, then gRPC, then the HTTP fronts
+	// that proxy to it
+	rt := server.New(cfg.Security.ShutdownTimeout)
+
+	if cfg.Monitoring.MetricsEnabled {
+		// Export connection pool statistics alongside the request metrics
+		samplerCtx, stopSampler := context.WithCancel(ctx)
+		defer stopSampler()
+		go health.NewPoolSampler(database, dbRouter, redisCache).Run(samplerCtx, cfg.Monitoring.PoolSampleInterval)
+
+		metricsMux := http.NewServeMux()
+		metricsMux.Handle("/metrics", promhttp.Handler())
+		rt.AddHTTP("Metrics server", fmt.Sprintf("%s:%s", cfg.Server.Host, cfg.Monitoring.MetricsPort), metricsMux)
 	}
 
-	// Setup graceful shutdown
-	go func() {
-		log.Printf("gRPC server listening on %s", address)
-		log.Printf("Environment: %s", cfg.Environment.Environment)
-		if err := grpcServer.Serve(listener); err != nil {
-			log.Fatalf("Failed to serve: %v", err)
+	if cfg.Monitoring.PprofEnabled {
+		rt.AddHTTP("pprof server", fmt.Sprintf("%s:%s", cfg.Monitoring.PprofHost, cfg.Monitoring.PprofPort), server.PprofHandler())
+	}
+
+	// Channelz state is process-wide, so the admin server reports on the main
+	// server's connections too
+	if cfg.Monitoring.ChannelzEnabled {
+		adminServer := grpc.NewServer()
+		channelzservice.RegisterChannelzServiceToServer(adminServer)
+		if cfg.Server.ReflectionEnabled {
+			reflection.Register(adminServer)
 		}
-	}()
+		rt.AddGRPC("Channelz admin server", fmt.Sprintf("%s:%s", cfg.Monitoring.ChannelzHost, cfg.Monitoring.ChannelzPort), adminServer)
+	}
 
-	// Wait for interrupt signal
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
+	rt.AddGRPC("gRPC server", fmt.Sprintf("%s:%s", cfg.Server.Host, cfg.Server.Port), grpcServer)
 
-	log.Println("Shutting down server...")
+	if cfg.Server.GatewayEnabled {
+		gatewayHandler, err := gateway.New(ctx, cfg, jwtService)
+		if err != nil {
+			return fmt.Errorf("failed to initialize gateway: %w", err)
+		}
+		rt.AddHTTP("REST gateway", fmt.Sprintf("%s:%s", cfg.Server.Host, cfg.Server.GatewayPort), gatewayHandler)
+	}
 
-	// Graceful shutdown with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), cfg.Security.ShutdownTimeout)
-	defer cancel()
+	if cfg.Server.GRPCWebEnabled {
+		// Config validation rules out gRPC-Web in xDS mode, so this is the
+		// plain server
+		rt.AddHTTP("gRPC-Web server", fmt.Sprintf("%s:%s", cfg.Server.Host, cfg.Server.GRPCWebPort), grpcweb.New(grpcServer.(*grpc.Server), cfg))
+	}
 
-	done := make(chan struct{})
-	go func() {
-		grpcServer.GracefulStop()
-		close(done)
-	}()
+	if cfg.Server.ConnectEnabled {
+		connectHandler, err := connectapi.New(ctx, cfg)
+		if err != nil {
+			return fmt.Errorf("failed to initialize Connect server: %w", err)
+		}
+		rt.AddHTTP("Connect server", fmt.Sprintf("%s:%s", cfg.Server.Host, cfg.Server.ConnectPort), connectHandler)
+	}
+
+	// End long-lived streams so the graceful stop doesn't wait on them
+	rt.OnShutdown(chatService.Close)
 
-	select {
-	case <-done:
-		log.Println("Server gracefully stopped")
-	case <-ctx.Done():
-		log.Println("Shutdown timeout exceeded, forcing stop")
-		grpcServer.Stop()
+	log.Printf("Environment: %s", cfg.Environment.Environment)
+
+	ctx, stop := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	return rt.Run(ctx)
+}
+
+// requestDescriptors returns the request messages of the services in files
+func requestDescriptors(files ...protoreflect.FileDescriptor) []protoreflect.MessageDescriptor {
+	var descs []protoreflect.MessageDescriptor
+	for _, file := range files {
+		services := file.Services()
+		for i := 0; i < services.Len(); i++ {
+			methods := services.Get(i).Methods()
+			for j := 0; j < methods.Len(); j++ {
+				descs = append(descs, methods.Get(j).Input())
+			}
+		}
+	}
+	return descs
+}
+
+// reloadOnHangup announces an authorization policy change on the bus each
+// time the process gets SIGHUP, until ctx ends
+func reloadOnHangup(ctx context.Context, bus *cache.Bus, logger *zap.Logger) {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	defer signal.Stop(hangup)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hangup:
+			if err := bus.Publish(ctx, cache.EventConfigChanged, cache.ConfigAuthzPolicy); err != nil {
+				logger.Warn("failed to announce the policy reload to other instances", zap.Error(err))
+			}
+		}
 	}
 }
 
@@ -165,10 +558,6 @@ func performHealthCheck(cfg *config.Config) error {
 	}
 	defer database.Close()
 
-	if err := database.Health(ctx); err != nil {
-		return fmt.Errorf("database health check failed: %w", err)
-	}
-
 	// Check Redis connectivity
 	redisCache, err := cache.New(cfg)
 	if err != nil {
@@ -176,9 +565,38 @@ func performHealthCheck(cfg *config.Config) error {
 	}
 	defer redisCache.Close()
 
-	if err := redisCache.Health(ctx); err != nil {
-		return fmt.Errorf("redis health check failed: %w", err)
-	}
+	// Report every component, then fail if any is unhealthy
+	report := health.NewChecker(database, nil, redisCache).Check(ctx)
+	printHealthReport(report)
 
+	if !report.Healthy {
+		return errors.New("one or more components are unhealthy")
+	}
 	return nil
 }
+
+// printHealthReport prints one line per component, with its details sorted
+// by name, and its last error if any
+func printHealthReport(report health.Report) {
+	for _, c := range report.Components {
+		state := "ok"
+		if !c.Healthy {
+			state = "FAIL"
+		}
+
+		keys := make([]string, 0, len(c.Details))
+		for k := range c.Details {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		fmt.Printf("%-8s %-4s %10s", c.Name, state, c.Latency.Round(time.Microsecond))
+		for _, k := range keys {
+			fmt.Printf(" %s=%s", k, c.Details[k])
+		}
+		fmt.Println()
+		if c.LastError != "" {
+			fmt.Printf("  error: %s\n", c.LastError)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/config"
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/db"
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/embedded"
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/retry"
+)
+
+const migrateUsage = `usage: server migrate <command>
+
+commands:
+  up             apply all pending migrations
+  down [N]       roll back the last N migrations (default 1)
+  status         show the applied and latest versions
+  force VERSION  mark VERSION as applied and clear the dirty flag (-1 for none)`
+
+// runMigrate runs a migrate subcommand against the configured database
+func runMigrate(cfg *config.Config, args []string) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+	switch args[0] {
+	case "up", "down", "status", "force":
+	default:
+		return errors.New(migrateUsage)
+	}
+
+	stopEmbedded, err := embedded.Start(cfg)
+	if err != nil {
+		return err
+	}
+	defer stopEmbedded()
+
+	ctx := context.Background()
+
+	var database *db.DB
+	err = retry.Do(ctx, cfg.StartupRetry, "PostgreSQL", func() (err error) {
+		database, err = db.New(cfg)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer database.Close()
+
+	switch args[0] {
+	case "up":
+		if err := database.RunMigrations(ctx); err != nil {
+			return err
+		}
+
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				return fmt.Errorf("invalid step count %q", args[1])
+			}
+		}
+		if err := database.MigrateDown(ctx, steps); err != nil {
+			return err
+		}
+
+	case "force":
+		if len(args) < 2 {
+			return errors.New(migrateUsage)
+		}
+		version, err := strconv.Atoi(args[1])
+		if err != nil || version < -1 {
+			return fmt.Errorf("invalid version %q", args[1])
+		}
+		if err := database.ForceMigrationVersion(ctx, version); err != nil {
+			return err
+		}
+	}
+
+	return printMigrationStatus(ctx, database)
+}
+
+func printMigrationStatus(ctx context.Context, database *db.DB) error {
+	status, err := database.MigrationStatus(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to read migration status: %w", err)
+	}
+
+	fmt.Printf("version: %d\n", status.Version)
+	fmt.Printf("latest:  %d\n", status.Latest)
+	fmt.Printf("dirty:   %t\n", status.Dirty)
+	if status.Pending() {
+		fmt.Printf("pending: %d migration(s)\n", status.Latest-status.Version)
+	}
+	return nil
+}
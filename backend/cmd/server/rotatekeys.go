@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/config"
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/db"
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/embedded"
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/models"
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/retry"
+	"github.com/sahays/grpc-proto-go-flutter-template/pkg/crypto"
+)
+
+// rotateKeysPageSize is how many users and tenants are read at a time
+const rotateKeysPageSize = 500
+
+// runRotateKeys re-wraps every encrypted value in every tenant with
+// ENCRYPTION_ACTIVE_KEY. Afterwards, keys no value names can be removed
+// from ENCRYPTION_KEYS.
+func runRotateKeys(cfg *config.Config) error {
+	stopEmbedded, err := embedded.Start(cfg)
+	if err != nil {
+		return err
+	}
+	defer stopEmbedded()
+
+	ctx := context.Background()
+
+	var database *db.DB
+	err = retry.Do(ctx, cfg.StartupRetry, "PostgreSQL", func() (err error) {
+		database, err = db.New(cfg)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer database.Close()
+
+	dbRouter, err := db.NewRouter(cfg, database.Pool)
+	if err != nil {
+		return fmt.Errorf("failed to initialize read replicas: %w", err)
+	}
+	defer dbRouter.Close()
+
+	keyring, err := crypto.New(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to initialize encryption: %w", err)
+	}
+
+	tenants := models.NewTenantRepository(database.Pool)
+	users := models.NewUserRepository(dbRouter, nil, nil, keyring)
+
+	total := 0
+	pageToken := ""
+	for {
+		page, next, err := tenants.List(ctx, rotateKeysPageSize, pageToken)
+		if err != nil {
+			return err
+		}
+
+		for _, t := range page {
+			n, err := users.RewrapPII(db.WithTenant(ctx, t.ID), rotateKeysPageSize)
+			total += n
+			if err != nil {
+				return fmt.Errorf("tenant %s: %w", t.Slug, err)
+			}
+			if n > 0 {
+				fmt.Printf("%s: %d user(s) re-wrapped\n", t.Slug, n)
+			}
+		}
+
+		if next == "" {
+			break
+		}
+		pageToken = next
+	}
+
+	fmt.Printf("re-wrapped %d user(s) in total\n", total)
+	return nil
+}
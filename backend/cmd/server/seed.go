@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/cache"
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/config"
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/db"
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/embedded"
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/models"
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/retry"
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/seed"
+	"github.com/sahays/grpc-proto-go-flutter-template/pkg/crypto"
+	"github.com/sahays/grpc-proto-go-flutter-template/pkg/password"
+)
+
+const seedUsage = `usage: server seed [flags]
+
+Fills the database with demo tenants, users and sessions. The same seed
+always produces the same data; records that already exist are skipped.
+
+flags:`
+
+// runSeed runs the seed subcommand against the configured database and Redis
+func runSeed(cfg *config.Config, args []string) error {
+	if cfg.IsProduction() {
+		return fmt.Errorf("refusing to seed a production database")
+	}
+
+	opts := seed.DefaultOptions()
+	opts.SessionTTL = cfg.JWT.RefreshTokenExpiry
+
+	flags := flag.NewFlagSet("seed", flag.ContinueOnError)
+	flags.Int64Var(&opts.Seed, "seed", opts.Seed, "seed for the generated data")
+	flags.IntVar(&opts.Tenants, "tenants", opts.Tenants, fmt.Sprintf("tenants to create besides the default one (at most %d)", seed.MaxTenants))
+	flags.IntVar(&opts.UsersPerTenant, "users", opts.UsersPerTenant, "users to generate in each tenant")
+	flags.StringVar(&opts.Password, "password", opts.Password, "password of every seeded user")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), seedUsage)
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); errors.Is(err, flag.ErrHelp) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	stopEmbedded, err := embedded.Start(cfg)
+	if err != nil {
+		return err
+	}
+	defer stopEmbedded()
+
+	ctx := context.Background()
+
+	var database *db.DB
+	err = retry.Do(ctx, cfg.StartupRetry, "PostgreSQL", func() (err error) {
+		database, err = db.New(cfg)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer database.Close()
+
+	var redisCache *cache.Cache
+	err = retry.Do(ctx, cfg.StartupRetry, "Redis", func() (err error) {
+		redisCache, err = cache.New(cfg)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to connect to Redis: %w", err)
+	}
+	defer redisCache.Close()
+
+	dbRouter, err := db.NewRouter(cfg, database.Pool)
+	if err != nil {
+		return fmt.Errorf("failed to initialize read replicas: %w", err)
+	}
+	defer dbRouter.Close()
+
+	keyring, err := crypto.New(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to initialize encryption: %w", err)
+	}
+
+	passService, err := password.New(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to initialize password hashing: %w", err)
+	}
+
+	seeder := seed.New(
+		models.NewTenantRepository(database.Pool),
+		models.NewUserRepository(dbRouter, nil, nil, keyring),
+		redisCache,
+		passService,
+	)
+
+	summary, err := seeder.Run(ctx, opts)
+	if err != nil {
+		return err
+	}
+
+	printSeedSummary(os.Stdout, summary, opts)
+	return nil
+}
+
+// seedIfEmpty seeds the default data set when the default tenant has no
+// users yet, for DB_SEED
+func seedIfEmpty(ctx context.Context, cfg *config.Config, seeder *seed.Seeder, users *models.UserRepository) error {
+	count, err := users.Count(db.WithTenant(ctx, db.DefaultTenantID))
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	opts := seed.DefaultOptions()
+	opts.SessionTTL = cfg.JWT.RefreshTokenExpiry
+
+	summary, err := seeder.Run(ctx, opts)
+	if err != nil {
+		return err
+	}
+
+	printSeedSummary(log.Writer(), summary, opts)
+	return nil
+}
+
+func printSeedSummary(w io.Writer, summary seed.Summary, opts seed.Options) {
+	fmt.Fprintf(w, "tenants:  %d created\n", summary.TenantsCreated)
+	fmt.Fprintf(w, "users:    %d created, %d already present\n", summary.UsersCreated, summary.UsersSkipped)
+	fmt.Fprintf(w, "sessions: %d created\n", summary.Sessions)
+	fmt.Fprintf(w, "sign in as %s or %s with password %q\n", seed.AdminEmail, seed.DemoEmail, opts.Password)
+}
@@ -0,0 +1,394 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/apierror"
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/cache"
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/clientip"
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/db"
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/health"
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/history"
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/models"
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/tenant"
+	pb "github.com/sahays/grpc-proto-go-flutter-template/proto/admin"
+)
+
+// Service implements the AdminService gRPC service. Access is restricted to
+// admins by the authorization policy, and to the default tenant by the
+// tenant interceptor, not here.
+type Service struct {
+	pb.UnimplementedAdminServiceServer
+	cache       *cache.Cache
+	bus         *cache.Bus
+	tenants     *models.TenantRepository
+	resolver    *tenant.Resolver
+	users       *models.UserRepository
+	recorder    *history.Recorder
+	userHistory *models.UserHistoryRepository
+	health      *health.Checker
+}
+
+// NewService creates a new admin service
+func NewService(
+	c *cache.Cache,
+	bus *cache.Bus,
+	tenants *models.TenantRepository,
+	resolver *tenant.Resolver,
+	users *models.UserRepository,
+	recorder *history.Recorder,
+	userHistory *models.UserHistoryRepository,
+	checker *health.Checker,
+) *Service {
+	return &Service{
+		cache:       c,
+		bus:         bus,
+		tenants:     tenants,
+		resolver:    resolver,
+		users:       users,
+		recorder:    recorder,
+		userHistory: userHistory,
+		health:      checker,
+	}
+}
+
+// DenyIP adds an entry to the dynamic IP denylist
+func (s *Service) DenyIP(ctx context.Context, req *pb.DenyIPRequest) (*pb.DenyIPResponse, error) {
+	ipNet, err := clientip.ParseCIDR(req.Cidr)
+	if err != nil {
+		return nil, apierror.FieldViolation(apierror.ReasonInvalidArgument, "cidr", "must be an IP address or CIDR range")
+	}
+
+	// Unset means no expiry; a set ttl is positive, checked by its rule
+	var ttl time.Duration
+	if req.Ttl != nil {
+		ttl = req.Ttl.AsDuration()
+	}
+
+	cidr := ipNet.String()
+	if err := s.cache.DenyIP(ctx, cidr, req.Reason, ttl); err != nil {
+		return nil, apierror.Internal("failed to update IP denylist")
+	}
+
+	entry := &pb.DeniedIP{Cidr: cidr, Reason: req.Reason}
+	if ttl > 0 {
+		entry.ExpiresAt = timestamppb.New(time.Now().Add(ttl))
+	}
+
+	return &pb.DenyIPResponse{Entry: entry}, nil
+}
+
+// RemoveDeniedIP removes an entry from the dynamic IP denylist
+func (s *Service) RemoveDeniedIP(ctx context.Context, req *pb.RemoveDeniedIPRequest) (*pb.RemoveDeniedIPResponse, error) {
+	ipNet, err := clientip.ParseCIDR(req.Cidr)
+	if err != nil {
+		return nil, apierror.FieldViolation(apierror.ReasonInvalidArgument, "cidr", "must be an IP address or CIDR range")
+	}
+
+	removed, err := s.cache.RemoveDeniedIP(ctx, ipNet.String())
+	if err != nil {
+		return nil, apierror.Internal("failed to update IP denylist")
+	}
+
+	return &pb.RemoveDeniedIPResponse{Removed: removed}, nil
+}
+
+// ListDeniedIPs lists the dynamic IP denylist
+func (s *Service) ListDeniedIPs(ctx context.Context, req *pb.ListDeniedIPsRequest) (*pb.ListDeniedIPsResponse, error) {
+	entries, err := s.cache.DeniedIPs(ctx)
+	if err != nil {
+		return nil, apierror.Internal("failed to load IP denylist")
+	}
+
+	resp := &pb.ListDeniedIPsResponse{Entries: make([]*pb.DeniedIP, 0, len(entries))}
+	for _, entry := range entries {
+		denied := &pb.DeniedIP{Cidr: entry.CIDR, Reason: entry.Reason}
+		if !entry.ExpiresAt.IsZero() {
+			denied.ExpiresAt = timestamppb.New(entry.ExpiresAt)
+		}
+		resp.Entries = append(resp.Entries, denied)
+	}
+
+	return resp, nil
+}
+
+// CreateTenant provisions a new tenant
+func (s *Service) CreateTenant(ctx context.Context, req *pb.CreateTenantRequest) (*pb.CreateTenantResponse, error) {
+	t, err := s.tenants.Create(ctx, req.Slug, req.Name)
+	if errors.Is(err, models.ErrSlugTaken) {
+		return nil, apierror.New(codes.AlreadyExists, apierror.ReasonTenantSlugTaken, "tenant slug already in use", apierror.BadRequest("slug", "is already in use"))
+	}
+	if err != nil {
+		return nil, apierror.Database(err, "failed to create tenant")
+	}
+
+	return &pb.CreateTenantResponse{Tenant: toProtoTenant(t)}, nil
+}
+
+// GetTenant looks a tenant up by ID or slug
+func (s *Service) GetTenant(ctx context.Context, req *pb.GetTenantRequest) (*pb.GetTenantResponse, error) {
+	var t *models.Tenant
+	var err error
+	if uuid.Validate(req.Tenant) == nil {
+		t, err = s.tenants.GetByID(ctx, req.Tenant)
+	} else {
+		t, err = s.tenants.GetBySlug(ctx, req.Tenant)
+	}
+	if errors.Is(err, models.ErrTenantNotFound) {
+		return nil, apierror.New(codes.NotFound, apierror.ReasonTenantNotFound, "tenant not found")
+	}
+	if err != nil {
+		return nil, apierror.Database(err, "failed to load tenant")
+	}
+
+	return &pb.GetTenantResponse{Tenant: toProtoTenant(t)}, nil
+}
+
+// ListTenants lists tenants, newest first
+func (s *Service) ListTenants(ctx context.Context, req *pb.ListTenantsRequest) (*pb.ListTenantsResponse, error) {
+	tenants, next, err := s.tenants.List(ctx, int(req.PageSize), req.PageToken)
+	if errors.Is(err, db.ErrInvalidPageToken) {
+		return nil, apierror.New(codes.InvalidArgument, apierror.ReasonInvalidPageToken, "invalid page token", apierror.BadRequest("page_token", "is invalid"))
+	}
+	if err != nil {
+		return nil, apierror.Database(err, "failed to list tenants")
+	}
+
+	resp := &pb.ListTenantsResponse{
+		Tenants:       make([]*pb.Tenant, 0, len(tenants)),
+		NextPageToken: next,
+	}
+	for _, t := range tenants {
+		resp.Tenants = append(resp.Tenants, toProtoTenant(t))
+	}
+
+	return resp, nil
+}
+
+// SetTenantActive deactivates or reactivates a tenant. The default tenant
+// holds the operators and can't be deactivated.
+func (s *Service) SetTenantActive(ctx context.Context, req *pb.SetTenantActiveRequest) (*pb.SetTenantActiveResponse, error) {
+	if req.TenantId == db.DefaultTenantID && !req.Active {
+		return nil, apierror.FieldViolation(apierror.ReasonInvalidArgument, "tenant_id", "must not be the default tenant")
+	}
+
+	t, err := s.tenants.SetActive(ctx, req.TenantId, req.Active)
+	if errors.Is(err, models.ErrTenantNotFound) {
+		return nil, apierror.New(codes.NotFound, apierror.ReasonTenantNotFound, "tenant not found")
+	}
+	if err != nil {
+		return nil, apierror.Database(err, "failed to update tenant")
+	}
+
+	// Every instance drops its cached copy; one that misses the event picks
+	// the change up when its copy expires
+	if err := s.bus.Publish(ctx, cache.EventTenantChanged, t.ID); err != nil {
+		log.Printf("Failed to announce tenant change: %v", err)
+	}
+
+	return &pb.SetTenantActiveResponse{Tenant: toProtoTenant(t)}, nil
+}
+
+// SetUserActive deactivates or reactivates a user and records the change.
+// Deactivation revokes the user's tokens and ends their open streams.
+// Operators act across tenants, so the call is scoped to the user's tenant
+// rather than the caller's.
+func (s *Service) SetUserActive(ctx context.Context, req *pb.SetUserActiveRequest) (*pb.SetUserActiveResponse, error) {
+	action := models.HistoryDeactivated
+	if req.Active {
+		action = models.HistoryActivated
+	}
+
+	entry, err := s.changeUser(ctx, req.TenantId, req.UserId, action, func(ctx context.Context) error {
+		return s.users.SetActive(ctx, req.UserId, req.Active)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Sign a deactivated user out everywhere; calling again retries this
+	if !req.Active {
+		if err := s.cache.RevokeUserTokens(ctx, req.UserId); err != nil {
+			return nil, apierror.Internal("failed to revoke user tokens")
+		}
+		if err := s.bus.Publish(ctx, cache.EventForcedLogout, req.UserId); err != nil {
+			log.Printf("Failed to announce forced logout: %v", err)
+		}
+	}
+
+	return &pb.SetUserActiveResponse{Change: entry}, nil
+}
+
+// SetUserRole changes a user's role and records the change
+func (s *Service) SetUserRole(ctx context.Context, req *pb.SetUserRoleRequest) (*pb.SetUserRoleResponse, error) {
+	entry, err := s.changeUser(ctx, req.TenantId, req.UserId, models.HistoryRoleChanged, func(ctx context.Context) error {
+		return s.users.SetRole(ctx, req.UserId, req.Role)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.SetUserRoleResponse{Change: entry}, nil
+}
+
+// SetUserMustResetPassword flags or unflags a user as required to change
+// their password, and records the change. Flagging revokes the user's tokens
+// and ends their open streams, so sessions from before a breach can't carry
+// on; the next Login only gets a token for ChangePassword.
+func (s *Service) SetUserMustResetPassword(ctx context.Context, req *pb.SetUserMustResetPasswordRequest) (*pb.SetUserMustResetPasswordResponse, error) {
+	action := models.HistoryResetLifted
+	if req.MustResetPassword {
+		action = models.HistoryResetRequired
+	}
+
+	entry, err := s.changeUser(ctx, req.TenantId, req.UserId, action, func(ctx context.Context) error {
+		return s.users.SetMustResetPassword(ctx, req.UserId, req.MustResetPassword)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Sign a flagged user out everywhere; calling again retries this
+	if req.MustResetPassword {
+		if err := s.cache.RevokeUserTokens(ctx, req.UserId); err != nil {
+			return nil, apierror.Internal("failed to revoke user tokens")
+		}
+		if err := s.bus.Publish(ctx, cache.EventForcedLogout, req.UserId); err != nil {
+			log.Printf("Failed to announce forced logout: %v", err)
+		}
+	}
+
+	return &pb.SetUserMustResetPasswordResponse{Change: entry}, nil
+}
+
+// changeUser runs change on a user of the given tenant, recording it as
+// action, and returns the recorded entry
+func (s *Service) changeUser(ctx context.Context, tenantID, userID, action string, change func(ctx context.Context) error) (*pb.UserHistoryEntry, error) {
+	ctx = db.WithTenant(ctx, tenantID)
+
+	_, err := s.users.GetByID(ctx, userID)
+	if db.IsRetryable(err) {
+		return nil, apierror.Database(err, "failed to load user")
+	}
+	if err != nil {
+		return nil, apierror.New(codes.NotFound, apierror.ReasonUserNotFound, "user not found")
+	}
+
+	entry, err := s.recorder.Track(ctx, userID, action, change)
+	if err != nil {
+		return nil, apierror.Database(err, "failed to update user")
+	}
+
+	return toProtoHistoryEntry(entry)
+}
+
+// ListUserHistory lists a user's recorded changes, newest first
+func (s *Service) ListUserHistory(ctx context.Context, req *pb.ListUserHistoryRequest) (*pb.ListUserHistoryResponse, error) {
+	entries, next, err := s.userHistory.ListForUser(ctx, req.TenantId, req.UserId, int(req.PageSize), req.PageToken)
+	if errors.Is(err, db.ErrInvalidPageToken) {
+		return nil, apierror.New(codes.InvalidArgument, apierror.ReasonInvalidPageToken, "invalid page token", apierror.BadRequest("page_token", "is invalid"))
+	}
+	if err != nil {
+		return nil, apierror.Database(err, "failed to list user history")
+	}
+
+	resp := &pb.ListUserHistoryResponse{
+		Entries:       make([]*pb.UserHistoryEntry, 0, len(entries)),
+		NextPageToken: next,
+	}
+	for _, entry := range entries {
+		converted, err := toProtoHistoryEntry(entry)
+		if err != nil {
+			return nil, err
+		}
+		resp.Entries = append(resp.Entries, converted)
+	}
+
+	return resp, nil
+}
+
+func toProtoHistoryEntry(entry *models.UserHistoryEntry) (*pb.UserHistoryEntry, error) {
+	before, err := snapshotStruct(entry.Before)
+	if err != nil {
+		return nil, apierror.Internal("failed to load user history")
+	}
+	after, err := snapshotStruct(entry.After)
+	if err != nil {
+		return nil, apierror.Internal("failed to load user history")
+	}
+
+	return &pb.UserHistoryEntry{
+		Id:        entry.ID,
+		TenantId:  entry.TenantID,
+		UserId:    entry.UserID,
+		Action:    entry.Action,
+		ActorId:   entry.ActorID,
+		ActorRole: entry.ActorRole,
+		RequestId: entry.RequestID,
+		Before:    before,
+		After:     after,
+		CreatedAt: timestamppb.New(entry.CreatedAt),
+	}, nil
+}
+
+// snapshotStruct converts a snapshot to a Struct with the same JSON fields
+func snapshotStruct(snapshot models.UserSnapshot) (*structpb.Struct, error) {
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	return structpb.NewStruct(fields)
+}
+
+// GetHealth reports the state of this instance's dependencies. An unhealthy
+// component is reported, not returned as an error.
+func (s *Service) GetHealth(ctx context.Context, req *pb.GetHealthRequest) (*pb.GetHealthResponse, error) {
+	report := s.health.Check(ctx)
+
+	resp := &pb.GetHealthResponse{
+		Healthy:   report.Healthy,
+		CheckedAt: timestamppb.New(report.CheckedAt),
+	}
+	for _, c := range report.Components {
+		component := &pb.ComponentHealth{
+			Name:      c.Name,
+			Healthy:   c.Healthy,
+			Details:   c.Details,
+			LastError: c.LastError,
+		}
+		if c.Latency > 0 {
+			component.Latency = durationpb.New(c.Latency)
+		}
+		if !c.LastErrorAt.IsZero() {
+			component.LastErrorAt = timestamppb.New(c.LastErrorAt)
+		}
+		resp.Components = append(resp.Components, component)
+	}
+
+	return resp, nil
+}
+
+func toProtoTenant(t *models.Tenant) *pb.Tenant {
+	return &pb.Tenant{
+		Id:        t.ID,
+		Slug:      t.Slug,
+		Name:      t.Name,
+		Active:    t.IsActive,
+		CreatedAt: timestamppb.New(t.CreatedAt),
+	}
+}
@@ -0,0 +1,107 @@
+// Package apierror builds gRPC status errors that carry google.rpc error
+// details, so clients can branch on stable reasons and map field violations
+// to form fields instead of parsing message text
+package apierror
+
+import (
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// Domain identifies this service in ErrorInfo details
+const Domain = "auth"
+
+// Reasons sent in ErrorInfo details. They are part of the API contract:
+// add new ones as needed but never rename existing ones.
+const (
+	ReasonInvalidArgument        = "INVALID_ARGUMENT"
+	ReasonWeakPassword           = "WEAK_PASSWORD"
+	ReasonPasswordUnchanged      = "PASSWORD_UNCHANGED"
+	ReasonRecoveryEmailIsPrimary = "RECOVERY_EMAIL_IS_PRIMARY"
+	ReasonEmailTaken             = "EMAIL_ALREADY_REGISTERED"
+	ReasonInvalidCredentials     = "INVALID_CREDENTIALS"
+	ReasonIncorrectPassword      = "INCORRECT_PASSWORD"
+	ReasonAccountDisabled        = "ACCOUNT_DISABLED"
+	ReasonPasswordResetRequired  = "PASSWORD_RESET_REQUIRED"
+	ReasonEmailNotVerified       = "EMAIL_NOT_VERIFIED"
+	ReasonTooManyLoginAttempts   = "TOO_MANY_LOGIN_ATTEMPTS"
+	ReasonRateLimited            = "RATE_LIMITED"
+	ReasonInvalidToken           = "INVALID_TOKEN"
+	ReasonUnauthenticated        = "UNAUTHENTICATED"
+	ReasonTokenScopeRestricted   = "TOKEN_SCOPE_RESTRICTED"
+	ReasonPermissionDenied       = "PERMISSION_DENIED"
+	ReasonIPDenied               = "IP_DENIED"
+	ReasonUserNotFound           = "USER_NOT_FOUND"
+	ReasonNotJoined              = "NOT_JOINED"
+	ReasonAlreadyJoined          = "ALREADY_JOINED"
+	ReasonSlowConsumer           = "SLOW_CONSUMER"
+	ReasonShuttingDown           = "SHUTTING_DOWN"
+	ReasonOverloaded             = "OVERLOADED"
+	ReasonUnavailable            = "UNAVAILABLE"
+	ReasonInternal               = "INTERNAL"
+	ReasonTenantRequired         = "TENANT_REQUIRED"
+	ReasonTenantNotFound         = "TENANT_NOT_FOUND"
+	ReasonTenantInactive         = "TENANT_INACTIVE"
+	ReasonTenantMismatch         = "TENANT_MISMATCH"
+	ReasonTenantSlugTaken        = "TENANT_SLUG_TAKEN"
+	ReasonInvalidPageToken       = "INVALID_PAGE_TOKEN"
+	ReasonVersionConflict        = "VERSION_CONFLICT"
+	ReasonTransactionConflict    = "TRANSACTION_CONFLICT"
+	ReasonIdempotencyKeyReused   = "IDEMPOTENCY_KEY_REUSED"
+	ReasonIdempotencyKeyInUse    = "IDEMPOTENCY_KEY_IN_USE"
+	ReasonDeviceMismatch         = "DEVICE_MISMATCH"
+)
+
+// New returns a status error with an ErrorInfo detail for reason, followed
+// by any additional details
+func New(code codes.Code, reason, message string, details ...protoadapt.MessageV1) error {
+	return NewWithMetadata(code, reason, message, nil, details...)
+}
+
+// NewWithMetadata is New with key/value context attached to the ErrorInfo
+func NewWithMetadata(code codes.Code, reason, message string, metadata map[string]string, details ...protoadapt.MessageV1) error {
+	st := status.New(code, message)
+
+	info := &errdetails.ErrorInfo{
+		Reason:   reason,
+		Domain:   Domain,
+		Metadata: metadata,
+	}
+
+	detailed, err := st.WithDetails(append([]protoadapt.MessageV1{info}, details...)...)
+	if err != nil {
+		return st.Err()
+	}
+
+	return detailed.Err()
+}
+
+// FieldViolation returns an InvalidArgument error for a single request
+// field, with a BadRequest detail naming the field
+func FieldViolation(reason, field, description string) error {
+	return New(codes.InvalidArgument, reason, field+" "+description, BadRequest(field, description))
+}
+
+// BadRequest returns a BadRequest detail for a single field
+func BadRequest(field, description string) *errdetails.BadRequest {
+	return &errdetails.BadRequest{
+		FieldViolations: []*errdetails.BadRequest_FieldViolation{
+			{Field: field, Description: description},
+		},
+	}
+}
+
+// RetryAfter returns a RetryInfo detail telling the client how long to back off
+func RetryAfter(delay time.Duration) *errdetails.RetryInfo {
+	return &errdetails.RetryInfo{RetryDelay: durationpb.New(delay)}
+}
+
+// Internal returns a codes.Internal error. message must not leak internals.
+func Internal(message string) error {
+	return New(codes.Internal, ReasonInternal, message)
+}
@@ -0,0 +1,18 @@
+package apierror
+
+import (
+	"time"
+
+	"google.golang.org/grpc/codes"
+)
+
+// cacheRetryDelay is how long clients are asked to wait before retrying a
+// request rejected because the cache failed
+const cacheRetryDelay = time.Second
+
+// CacheUnavailable returns the error for a request whose check failed closed
+// because the cache couldn't be reached (see config.CacheOutageConfig)
+func CacheUnavailable() error {
+	return New(codes.Unavailable, ReasonUnavailable, "service is unavailable, please try again later",
+		RetryAfter(cacheRetryDelay))
+}
@@ -0,0 +1,29 @@
+package apierror
+
+import (
+	"time"
+
+	"google.golang.org/grpc/codes"
+
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/db"
+)
+
+// databaseRetryDelay is how long clients are asked to wait before retrying a
+// request that failed on a transient database error
+const databaseRetryDelay = time.Second
+
+// Database returns the error for a request that failed on a database call,
+// by the class of err (see db.Classify): Unavailable with a retry delay if
+// the database failed transiently, Aborted if the request lost a race with
+// a concurrent transaction, and Internal otherwise. Either way the client
+// sees only message.
+func Database(err error, message string) error {
+	switch db.Classify(err) {
+	case db.ErrorTransient:
+		return New(codes.Unavailable, ReasonUnavailable, message, RetryAfter(databaseRetryDelay))
+	case db.ErrorConflict:
+		return New(codes.Aborted, ReasonTransactionConflict, message)
+	default:
+		return Internal(message)
+	}
+}
@@ -0,0 +1,117 @@
+// Package audit writes audit log entries to the database in the background,
+// so recording a call never adds a database round trip to it
+package audit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/models"
+)
+
+const (
+	// maxBatch is the most entries written in one statement
+	maxBatch = 100
+	// flushInterval bounds how long an entry waits for a batch to fill
+	flushInterval = time.Second
+	// writeTimeout bounds each batch insert
+	writeTimeout = 5 * time.Second
+)
+
+var droppedTotal = promauto.NewCounter(
+	prometheus.CounterOpts{
+		Name: "audit_log_dropped_total",
+		Help: "Total number of audit log entries dropped because the buffer was full or the write failed.",
+	},
+)
+
+// Writer buffers entries and inserts them in batches
+type Writer struct {
+	repo    *models.AuditLogRepository
+	entries chan models.AuditLog
+	logger  *zap.Logger
+
+	wg        sync.WaitGroup
+	closeOnce sync.Once
+}
+
+// NewWriter starts a writer holding up to bufferSize pending entries
+func NewWriter(repo *models.AuditLogRepository, bufferSize int, logger *zap.Logger) *Writer {
+	w := &Writer{
+		repo:    repo,
+		entries: make(chan models.AuditLog, bufferSize),
+		logger:  logger,
+	}
+
+	w.wg.Add(1)
+	go w.run()
+
+	return w
+}
+
+// Record queues an entry. It never blocks: when the buffer is full the
+// entry is dropped and counted rather than slowing the call down.
+func (w *Writer) Record(entry models.AuditLog) {
+	select {
+	case w.entries <- entry:
+	default:
+		droppedTotal.Inc()
+	}
+}
+
+// Close flushes pending entries and stops the writer. Record must not be
+// called afterwards.
+func (w *Writer) Close() {
+	w.closeOnce.Do(func() {
+		close(w.entries)
+		w.wg.Wait()
+	})
+}
+
+func (w *Writer) run() {
+	defer w.wg.Done()
+
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]models.AuditLog, 0, maxBatch)
+	for {
+		select {
+		case entry, ok := <-w.entries:
+			if !ok {
+				w.flush(batch)
+				return
+			}
+			batch = append(batch, entry)
+			if len(batch) >= maxBatch {
+				w.flush(batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			w.flush(batch)
+			batch = batch[:0]
+		}
+	}
+}
+
+func (w *Writer) flush(batch []models.AuditLog) {
+	if len(batch) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), writeTimeout)
+	defer cancel()
+
+	if err := w.repo.InsertBatch(ctx, batch); err != nil {
+		droppedTotal.Add(float64(len(batch)))
+		w.logger.Error("failed to write audit log",
+			zap.Int("entries", len(batch)),
+			zap.Error(err),
+		)
+	}
+}
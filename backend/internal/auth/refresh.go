@@ -0,0 +1,75 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/config"
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/models"
+)
+
+// opaqueRefreshTokenBytes is the randomness of an opaque refresh token
+const opaqueRefreshTokenBytes = 32
+
+// newRefreshToken issues a refresh token for user in the configured format
+// and returns it with the ID of the session it opens. An opaque token is
+// only random; its session is keyed by its hash, so the token itself is
+// never stored.
+func (s *Service) newRefreshToken(user *models.User) (token, sessionID string, err error) {
+	if s.config.JWT.RefreshTokenFormat == config.RefreshTokenOpaque {
+		raw := make([]byte, opaqueRefreshTokenBytes)
+		if _, err := rand.Read(raw); err != nil {
+			return "", "", err
+		}
+		token = base64.RawURLEncoding.EncodeToString(raw)
+		return token, opaqueSessionID(token), nil
+	}
+
+	token, err = s.jwtService.CreateRefreshToken(user.ID, user.TenantID)
+	if err != nil {
+		return "", "", err
+	}
+	sessionID, err = s.jwtService.GetTokenID(token)
+	if err != nil {
+		return "", "", err
+	}
+	return token, sessionID, nil
+}
+
+// refreshTokenSessionID returns the ID of the session token belongs to. Both
+// formats are accepted whatever the configured one, so switching formats
+// doesn't sign anyone out; a signed token, JWT or PASETO, has dots, which an
+// opaque token can't.
+func (s *Service) refreshTokenSessionID(token string) (string, error) {
+	if strings.Contains(token, ".") {
+		return s.jwtService.GetTokenID(token)
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || len(raw) != opaqueRefreshTokenBytes {
+		return "", fmt.Errorf("invalid refresh token")
+	}
+	return opaqueSessionID(token), nil
+}
+
+// deviceHash is what a session stores of a client's device ID, which may be
+// a fingerprint of the device, so only its hash is kept; "" for no ID
+func deviceHash(deviceID string) string {
+	if deviceID == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(deviceID))
+	return hex.EncodeToString(sum[:])
+}
+
+// opaqueSessionID is the session ID of an opaque refresh token: its SHA-256
+// hash. The token has enough entropy that a hash without salt can't be
+// reversed.
+func opaqueSessionID(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
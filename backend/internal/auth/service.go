@@ -2,15 +2,25 @@ package auth
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"log"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
 
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/apierror"
 	"github.com/sahays/grpc-proto-go-flutter-template/internal/cache"
 	"github.com/sahays/grpc-proto-go-flutter-template/internal/config"
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/db"
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/i18n"
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/middleware"
 	"github.com/sahays/grpc-proto-go-flutter-template/internal/models"
 	"github.com/sahays/grpc-proto-go-flutter-template/pkg/jwt"
 	"github.com/sahays/grpc-proto-go-flutter-template/pkg/password"
@@ -21,24 +31,42 @@ import (
 type Service struct {
 	pb.UnimplementedAuthServiceServer
 	config      *config.Config
-	userRepo    *models.UserRepository
-	cache       *cache.Cache
+	userRepo    UserStore
+	txManager   Transactor
+	outbox      EventOutbox
+	history     ChangeTracker
+	cache       TokenCache
+	events      SecurityEventBus
+	grants      RoleGrants
 	jwtService  *jwt.Service
 	passService *password.Service
 }
 
-// NewService creates a new auth service
+// NewService creates a new auth service. In production *cache.Cache serves
+// as both the token cache and the event bus. outbox may be nil, in which case
+// no outbound events are recorded, and so may history, in which case user
+// changes are not recorded.
 func NewService(
 	cfg *config.Config,
-	userRepo *models.UserRepository,
-	cache *cache.Cache,
+	userRepo UserStore,
+	txManager Transactor,
+	outbox EventOutbox,
+	history ChangeTracker,
+	cache TokenCache,
+	events SecurityEventBus,
+	grants RoleGrants,
 	jwtService *jwt.Service,
 	passService *password.Service,
 ) *Service {
 	return &Service{
 		config:      cfg,
 		userRepo:    userRepo,
+		txManager:   txManager,
+		outbox:      outbox,
+		history:     history,
 		cache:       cache,
+		events:      events,
+		grants:      grants,
 		jwtService:  jwtService,
 		passService: passService,
 	}
@@ -46,42 +74,19 @@ func NewService(
 
 // SignUp handles user registration
 func (s *Service) SignUp(ctx context.Context, req *pb.SignUpRequest) (*pb.SignUpResponse, error) {
-	// Validate inputs
-	if err := ValidateEmail(req.Email); err != nil {
+	// Field constraints are enforced by the validation interceptor
+	if err := s.validateNewPassword("password", req.Password, req.Email, req.FirstName, req.LastName); err != nil {
 		return nil, err
 	}
 
-	if err := ValidatePassword(req.Password); err != nil {
-		return nil, err
-	}
-
-	if err := ValidateName(req.FirstName, "first_name"); err != nil {
-		return nil, err
-	}
-
-	if err := ValidateName(req.LastName, "last_name"); err != nil {
-		return nil, err
-	}
-
-	// Check if email already exists
-	exists, err := s.userRepo.EmailExists(ctx, req.Email)
-	if err != nil {
-		return nil, status.Error(codes.Internal, "failed to check email existence")
-	}
-
-	if exists {
-		return nil, status.Error(codes.AlreadyExists, "email already registered")
-	}
-
-	// Hash password
+	// Hash password before opening the transaction so it isn't held open
+	// for the duration of the hash
 	passwordHash, err := s.passService.Hash(req.Password)
 	if err != nil {
-		return nil, status.Error(codes.Internal, "failed to hash password")
+		return nil, apierror.Internal("failed to hash password")
 	}
 
-	// Create user
 	user := &models.User{
-		ID:           uuid.New().String(),
 		Email:        req.Email,
 		PasswordHash: passwordHash,
 		FirstName:    req.FirstName,
@@ -90,118 +95,196 @@ func (s *Service) SignUp(ctx context.Context, req *pb.SignUpRequest) (*pb.SignUp
 		IsVerified:   false, // Require email verification in production
 	}
 
-	if err := s.userRepo.Create(ctx, user); err != nil {
-		return nil, status.Error(codes.Internal, "failed to create user")
+	// Create the user and any related rows all-or-nothing
+	err = s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		exists, err := s.userRepo.EmailExists(ctx, req.Email)
+		if err != nil {
+			return apierror.Database(err, "failed to check email existence")
+		}
+
+		if exists {
+			return apierror.New(codes.AlreadyExists, apierror.ReasonEmailTaken, "email already registered", apierror.BadRequest("email", "is already registered"))
+		}
+
+		// A concurrent signup can still win the race; the unique index on
+		// email catches it
+		err = s.userRepo.Create(ctx, user)
+		if errors.Is(err, models.ErrEmailTaken) {
+			return apierror.New(codes.AlreadyExists, apierror.ReasonEmailTaken, "email already registered", apierror.BadRequest("email", "is already registered"))
+		}
+		if err != nil {
+			return apierror.Database(err, "failed to create user")
+		}
+
+		err = s.recordEvent(ctx, models.EventUserCreated, user.ID, userCreatedEvent{
+			UserID:    user.ID,
+			Email:     user.Email,
+			FirstName: user.FirstName,
+			LastName:  user.LastName,
+			CreatedAt: user.CreatedAt,
+		})
+		if err != nil {
+			return apierror.Database(err, "failed to create user")
+		}
+		return nil
+	})
+	if err != nil {
+		if _, ok := status.FromError(err); ok {
+			return nil, err
+		}
+		return nil, apierror.Database(err, "failed to create user")
 	}
 
+	// Send verification email; the account stays usable if this fails and the
+	// user can request another link via ResendVerification
+	_ = s.sendVerificationEmail(ctx, user)
+
 	// Return response
 	return &pb.SignUpResponse{
 		Success: true,
 		Message: "User registered successfully",
-		User: &pb.User{
-			Id:        user.ID,
-			Email:     user.Email,
-			FirstName: user.FirstName,
-			LastName:  user.LastName,
-		},
+		User:    toProtoUser(user),
 	}, nil
 }
 
 // Login handles user authentication
 func (s *Service) Login(ctx context.Context, req *pb.LoginRequest) (*pb.LoginResponse, error) {
-	// Validate inputs
-	if err := ValidateEmail(req.Email); err != nil {
-		return nil, err
-	}
-
-	if req.Password == "" {
-		return nil, status.Error(codes.InvalidArgument, "password is required")
-	}
-
-	// Check login attempts (rate limiting)
-	attempts, err := s.cache.TrackLoginAttempt(ctx, req.Email, s.config.Security.LockoutDuration)
-	if err != nil {
-		// Log error but don't fail the request
-	}
-
-	if attempts > int64(s.config.Security.MaxLoginAttempts) {
-		return nil, status.Error(codes.PermissionDenied, "too many failed login attempts, please try again later")
+	// Check login attempts (rate limiting). Counted per tenant, since the
+	// same email may be registered in several.
+	tenantID, _ := db.TenantFrom(ctx)
+	attemptKey := tenantID + ":" + req.Email
+	// MaxLoginAttempts may be made at once, then one more each
+	// LockoutDuration/MaxLoginAttempts. A cache error skips the check unless
+	// it fails closed.
+	limit := cache.PerPeriod(s.config.Security.MaxLoginAttempts, s.config.Security.LockoutDuration)
+	attempt, err := s.cache.AllowLoginAttempt(ctx, attemptKey, limit)
+	if err != nil && s.config.Security.CacheOutage.LoginAttempts == config.FailClosed {
+		return nil, apierror.CacheUnavailable()
+	}
+	if err == nil && !attempt.Allowed {
+		return nil, apierror.New(codes.PermissionDenied, apierror.ReasonTooManyLoginAttempts, "too many failed login attempts, please try again later",
+			apierror.RetryAfter(attempt.RetryAfter))
 	}
 
 	// Get user by email
 	user, err := s.userRepo.GetByEmail(ctx, req.Email)
+	if db.IsRetryable(err) {
+		return nil, apierror.Database(err, "failed to load user")
+	}
 	if err != nil {
-		return nil, status.Error(codes.Unauthenticated, "invalid email or password")
+		// Spend the time verifying the password would have taken, so the
+		// response time doesn't tell which emails have accounts
+		s.passService.VerifyDummy(req.Password)
+		return nil, apierror.New(codes.Unauthenticated, apierror.ReasonInvalidCredentials, "invalid email or password")
 	}
 
 	// Check if user is active
 	if !user.IsActive {
-		return nil, status.Error(codes.PermissionDenied, "account is disabled")
+		return nil, apierror.New(codes.PermissionDenied, apierror.ReasonAccountDisabled, "account is disabled")
 	}
 
 	// Verify password
 	valid, err := s.passService.Verify(req.Password, user.PasswordHash)
 	if err != nil || !valid {
-		return nil, status.Error(codes.Unauthenticated, "invalid email or password")
+		return nil, apierror.New(codes.Unauthenticated, apierror.ReasonInvalidCredentials, "invalid email or password")
+	}
+	s.rehashPassword(ctx, user, req.Password)
+
+	// Optionally block unverified accounts; checked after the password so the
+	// verification state of an account isn't revealed to anyone else
+	if s.config.Security.RequireEmailVerification && !user.IsVerified {
+		return nil, emailNotVerifiedError()
 	}
 
 	// Clear login attempts on successful login
-	_ = s.cache.ClearLoginAttempts(ctx, req.Email)
+	_ = s.cache.ClearLoginAttempts(ctx, attemptKey)
 
 	// Update last login
 	_ = s.userRepo.UpdateLastLogin(ctx, user.ID)
 
-	// Generate tokens
-	accessToken, err := s.jwtService.CreateAccessToken(user.ID, user.Email)
+	id, err := s.identity(user)
 	if err != nil {
-		return nil, status.Error(codes.Internal, "failed to create access token")
+		return nil, apierror.Internal("failed to create access token")
 	}
 
-	refreshToken, err := s.jwtService.CreateRefreshToken(user.ID)
-	if err != nil {
-		return nil, status.Error(codes.Internal, "failed to create refresh token")
+	// Users flagged for a forced reset only get a token usable for ChangePassword
+	if user.MustResetPassword {
+		accessToken, err := s.jwtService.CreateScopedAccessToken(id, jwt.ScopePasswordChange)
+		if err != nil {
+			return nil, apierror.Internal("failed to create access token")
+		}
+
+		s.publishSecurityEvent(ctx, user.ID, pb.SecurityEventType_SECURITY_EVENT_TYPE_LOGIN)
+
+		return &pb.LoginResponse{
+			AccessToken:       accessToken,
+			ExpiresIn:         int64(s.config.JWT.AccessTokenExpiry.Seconds()),
+			User:              toProtoUser(user),
+			MustResetPassword: true,
+		}, nil
 	}
 
-	// Store refresh token in Redis
-	tokenID, err := s.jwtService.GetTokenID(refreshToken)
+	// Generate tokens; the refresh token identifies the session, which the
+	// access token names so signing out the session rejects it too
+	refreshToken, tokenID, err := s.newRefreshToken(user)
 	if err != nil {
-		return nil, status.Error(codes.Internal, "failed to get token ID")
+		return nil, apierror.Internal("failed to create refresh token")
 	}
 
-	err = s.cache.SetRefreshToken(ctx, tokenID, user.ID, s.config.JWT.RefreshTokenExpiry)
+	accessToken, err := s.jwtService.CreateAccessToken(id, tokenID)
+	if err != nil {
+		return nil, apierror.Internal("failed to create access token")
+	}
+
+	now := time.Now()
+	err = s.cache.CreateSession(ctx, &cache.Session{
+		ID:         tokenID,
+		UserID:     user.ID,
+		Device:     strings.TrimSpace(req.DeviceName),
+		DeviceHash: deviceHash(strings.TrimSpace(req.DeviceId)),
+		IP:         middleware.ClientIPFromContext(ctx),
+		UserAgent:  middleware.UserAgentFromContext(ctx),
+		CreatedAt:  now,
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(s.config.JWT.RefreshTokenExpiry),
+	})
 	if err != nil {
-		return nil, status.Error(codes.Internal, "failed to store refresh token")
+		return nil, apierror.Internal("failed to store refresh token")
 	}
 
+	s.publishSecurityEvent(ctx, user.ID, pb.SecurityEventType_SECURITY_EVENT_TYPE_LOGIN)
+
 	// Return response
 	return &pb.LoginResponse{
 		AccessToken:  accessToken,
 		RefreshToken: refreshToken,
 		ExpiresIn:    int64(s.config.JWT.AccessTokenExpiry.Seconds()),
-		User: &pb.User{
-			Id:        user.ID,
-			Email:     user.Email,
-			FirstName: user.FirstName,
-			LastName:  user.LastName,
-		},
+		User:         toProtoUser(user),
 	}, nil
 }
 
 // ForgotPassword handles password reset requests
 func (s *Service) ForgotPassword(ctx context.Context, req *pb.ForgotPasswordRequest) (*pb.ForgotPasswordResponse, error) {
-	// Validate email
-	if err := ValidateEmail(req.Email); err != nil {
-		return nil, err
+	genericResponse := &pb.ForgotPasswordResponse{
+		Success: true,
+		Message: "If your email is registered, you will receive a password reset link",
 	}
 
 	// Check if user exists
 	user, err := s.userRepo.GetByEmail(ctx, req.Email)
 	if err != nil {
 		// Don't reveal if email exists or not (security)
-		return &pb.ForgotPasswordResponse{
-			Success: true,
-			Message: "If your email is registered, you will receive a password reset link",
-		}, nil
+		return genericResponse, nil
+	}
+
+	// Pick the delivery address; only a verified recovery email may be used
+	deliverTo := user.Email
+	if req.UseRecoveryEmail {
+		if user.RecoveryEmail == nil || !user.RecoveryEmailVerified {
+			// Same response as an unknown email so recovery settings aren't revealed
+			return genericResponse, nil
+		}
+		deliverTo = *user.RecoveryEmail
 	}
 
 	// Generate reset token
@@ -210,72 +293,100 @@ func (s *Service) ForgotPassword(ctx context.Context, req *pb.ForgotPasswordRequ
 	// Store reset token in Redis with 1 hour expiry
 	err = s.cache.SetPasswordResetToken(ctx, resetToken, user.ID, 1*time.Hour)
 	if err != nil {
-		return nil, status.Error(codes.Internal, "failed to create reset token")
+		return nil, apierror.Internal("failed to create reset token")
 	}
 
-	// TODO: Send email with reset link
 	// In production, send email: https://yourapp.com/reset-password?token=resetToken
-	// For development, log the token
-	log.Printf("=== PASSWORD RESET TOKEN ===")
-	log.Printf("Email: %s", user.Email)
-	log.Printf("Token: %s", resetToken)
-	log.Printf("This token expires in 1 hour")
-	log.Printf("=============================")
+	sendEmail(deliverTo, "PASSWORD RESET TOKEN",
+		"Token: "+resetToken,
+		"This token expires in 1 hour",
+	)
 
-	return &pb.ForgotPasswordResponse{
-		Success: true,
-		Message: "If your email is registered, you will receive a password reset link",
-	}, nil
+	// Let the account owner know a reset link went to their recovery address
+	if deliverTo != user.Email {
+		sendEmail(user.Email, "PASSWORD RESET NOTICE",
+			"A password reset link was sent to your recovery email",
+			"If this wasn't you, secure your account immediately",
+		)
+	}
+
+	return genericResponse, nil
 }
 
 // ResetPassword handles password reset
 func (s *Service) ResetPassword(ctx context.Context, req *pb.ResetPasswordRequest) (*pb.ResetPasswordResponse, error) {
-	// Validate token
-	if err := ValidateToken(req.Token); err != nil {
-		return nil, err
-	}
-
 	// Validate new password
-	if err := ValidatePassword(req.NewPassword); err != nil {
+	if err := s.validateNewPassword("new_password", req.NewPassword); err != nil {
 		return nil, err
 	}
 
 	// Get user ID from reset token
 	userID, err := s.cache.GetPasswordResetToken(ctx, req.Token)
 	if err != nil {
-		return nil, status.Error(codes.InvalidArgument, "invalid or expired reset token")
+		return nil, apierror.New(codes.InvalidArgument, apierror.ReasonInvalidToken, "invalid or expired reset token", apierror.BadRequest("token", "is invalid or expired"))
 	}
 
 	// Hash new password
 	passwordHash, err := s.passService.Hash(req.NewPassword)
 	if err != nil {
-		return nil, status.Error(codes.Internal, "failed to hash password")
-	}
-
-	// Update password
-	err = s.userRepo.UpdatePassword(ctx, userID, passwordHash)
+		return nil, apierror.Internal("failed to hash password")
+	}
+
+	// Update password and record the change atomically
+	err = s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		err := s.track(ctx, userID, models.HistoryPasswordReset, func(ctx context.Context) error {
+			return s.userRepo.UpdatePassword(ctx, userID, passwordHash)
+		})
+		if err != nil {
+			return err
+		}
+		return s.recordEvent(ctx, models.EventPasswordChanged, userID, passwordChangedEvent{
+			UserID: userID,
+			Method: "reset",
+		})
+	})
 	if err != nil {
-		return nil, status.Error(codes.Internal, "failed to update password")
+		return nil, apierror.Database(err, "failed to update password")
 	}
 
 	// Delete reset token
 	_ = s.cache.DeletePasswordResetToken(ctx, req.Token)
 
+	s.revokeUserTokens(ctx, userID)
+	s.publishSecurityEvent(ctx, userID, pb.SecurityEventType_SECURITY_EVENT_TYPE_PASSWORD_CHANGED)
+
 	return &pb.ResetPasswordResponse{
 		Success: true,
 		Message: "Password reset successfully",
 	}, nil
 }
 
+// CheckPasswordStrength scores a password for a form to show as the user
+// types it, with its feedback in the caller's language
+func (s *Service) CheckPasswordStrength(ctx context.Context, req *pb.CheckPasswordStrengthRequest) (*pb.CheckPasswordStrengthResponse, error) {
+	userInputs := []string{req.Email, req.FirstName, req.LastName}
+	strength := password.EstimateStrength(req.Password, userInputs...)
+	acceptable := s.passService.ValidateStrength(req.Password, userInputs...) == nil
+
+	lang := middleware.Language(ctx)
+	suggestions := make([]string, len(strength.Suggestions))
+	for i, suggestion := range strength.Suggestions {
+		suggestions[i] = i18n.Translate(lang, suggestion)
+	}
+
+	return &pb.CheckPasswordStrengthResponse{
+		Score:        int32(strength.Score),
+		Acceptable:   acceptable,
+		GuessesLog10: strength.GuessesLog10,
+		Warning:      i18n.Translate(lang, strength.Warning),
+		Suggestions:  suggestions,
+	}, nil
+}
+
 // ValidateToken validates an access token
 func (s *Service) ValidateToken(ctx context.Context, req *pb.ValidateTokenRequest) (*pb.ValidateTokenResponse, error) {
-	// Validate token
-	if err := ValidateToken(req.AccessToken); err != nil {
-		return nil, err
-	}
-
 	// Verify token
-	claims, err := s.jwtService.ValidateToken(req.AccessToken)
+	claims, err := s.jwtService.ValidateAccessToken(req.AccessToken)
 	if err != nil {
 		return &pb.ValidateTokenResponse{
 			Valid:   false,
@@ -283,6 +394,28 @@ func (s *Service) ValidateToken(ctx context.Context, req *pb.ValidateTokenReques
 		}, nil
 	}
 
+	// Revoked by logout, a password change or a ban. Checked strictly here,
+	// unlike in the auth interceptor, since callers act on the answer.
+	var issuedAt time.Time
+	if claims.IssuedAt != nil {
+		issuedAt = claims.IssuedAt.Time
+	}
+	revoked, err := s.cache.IsTokenRevoked(ctx, claims.ID, claims.UserID, issuedAt)
+	if err != nil || revoked {
+		return &pb.ValidateTokenResponse{
+			Valid:   false,
+			Message: "token has been revoked",
+		}, nil
+	}
+
+	// Restricted tokens are only good for ChangePassword
+	if claims.IsRestricted() {
+		return &pb.ValidateTokenResponse{
+			Valid:   false,
+			Message: "token is restricted to password change",
+		}, nil
+	}
+
 	// Get user
 	user, err := s.userRepo.GetByID(ctx, claims.UserID)
 	if err != nil {
@@ -301,13 +434,567 @@ func (s *Service) ValidateToken(ctx context.Context, req *pb.ValidateTokenReques
 	}
 
 	return &pb.ValidateTokenResponse{
-		Valid: true,
-		User: &pb.User{
-			Id:        user.ID,
-			Email:     user.Email,
-			FirstName: user.FirstName,
-			LastName:  user.LastName,
-		},
+		Valid:   true,
+		User:    toProtoUser(user),
 		Message: "token is valid",
 	}, nil
 }
+
+// ChangePassword changes the password of the authenticated user.
+// Password-change-scoped tokens issued for forced resets are accepted.
+func (s *Service) ChangePassword(ctx context.Context, req *pb.ChangePasswordRequest) (*pb.ChangePasswordResponse, error) {
+	claims, ok := middleware.ClaimsFromContext(ctx)
+	if !ok {
+		return nil, apierror.New(codes.Unauthenticated, apierror.ReasonUnauthenticated, "authentication required")
+	}
+
+	if err := s.validateNewPassword("new_password", req.NewPassword, claims.Email); err != nil {
+		return nil, err
+	}
+
+	if password.Normalize(req.NewPassword) == password.Normalize(req.CurrentPassword) {
+		return nil, apierror.FieldViolation(apierror.ReasonPasswordUnchanged, "new_password", "must differ from current password")
+	}
+
+	user, err := s.userRepo.GetByID(ctx, claims.UserID)
+	if db.IsRetryable(err) {
+		return nil, apierror.Database(err, "failed to load user")
+	}
+	if err != nil {
+		return nil, apierror.New(codes.Unauthenticated, apierror.ReasonUserNotFound, "user not found")
+	}
+
+	if !user.IsActive {
+		return nil, apierror.New(codes.PermissionDenied, apierror.ReasonAccountDisabled, "account is disabled")
+	}
+
+	// Verify current password
+	valid, err := s.passService.Verify(req.CurrentPassword, user.PasswordHash)
+	if err != nil || !valid {
+		return nil, apierror.New(codes.Unauthenticated, apierror.ReasonIncorrectPassword, "current password is incorrect", apierror.BadRequest("current_password", "is incorrect"))
+	}
+
+	// Hash new password
+	passwordHash, err := s.passService.Hash(req.NewPassword)
+	if err != nil {
+		return nil, apierror.Internal("failed to hash password")
+	}
+
+	// Update password (also clears must_reset_password) and record the
+	// change atomically
+	err = s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		err := s.track(ctx, user.ID, models.HistoryPasswordChanged, func(ctx context.Context) error {
+			return s.userRepo.UpdatePassword(ctx, user.ID, passwordHash)
+		})
+		if err != nil {
+			return err
+		}
+		return s.recordEvent(ctx, models.EventPasswordChanged, user.ID, passwordChangedEvent{
+			UserID: user.ID,
+			Method: "change",
+		})
+	})
+	if err != nil {
+		return nil, apierror.Database(err, "failed to update password")
+	}
+
+	s.revokeUserTokens(ctx, user.ID)
+	s.publishSecurityEvent(ctx, user.ID, pb.SecurityEventType_SECURITY_EVENT_TYPE_PASSWORD_CHANGED)
+
+	return &pb.ChangePasswordResponse{
+		Success: true,
+		Message: "Password changed successfully",
+	}, nil
+}
+
+// UpdateProfile changes the authenticated user's name. The edit applies only
+// if the user is still at the version the client read, so one device can't
+// silently overwrite a change made from another.
+func (s *Service) UpdateProfile(ctx context.Context, req *pb.UpdateProfileRequest) (*pb.UpdateProfileResponse, error) {
+	claims, ok := middleware.ClaimsFromContext(ctx)
+	if !ok {
+		return nil, apierror.New(codes.Unauthenticated, apierror.ReasonUnauthenticated, "authentication required")
+	}
+
+	user, err := s.userRepo.GetByID(ctx, claims.UserID)
+	if db.IsRetryable(err) {
+		return nil, apierror.Database(err, "failed to load user")
+	}
+	if err != nil {
+		return nil, apierror.New(codes.Unauthenticated, apierror.ReasonUserNotFound, "user not found")
+	}
+
+	if !user.IsActive {
+		return nil, apierror.New(codes.PermissionDenied, apierror.ReasonAccountDisabled, "account is disabled")
+	}
+
+	// Update writes every field of user, so it must be the version the
+	// client edited; the repository then checks the row is still at it
+	if user.Version != req.Version {
+		return nil, versionConflictError()
+	}
+
+	user.FirstName = req.FirstName
+	user.LastName = req.LastName
+	if req.PhoneNumber != nil {
+		user.PhoneNumber = nil
+		if number := req.GetPhoneNumber(); number != "" {
+			user.PhoneNumber = &number
+		}
+	}
+	if req.Metadata != nil {
+		user.Metadata = req.Metadata.Values
+	}
+
+	err = s.track(ctx, user.ID, models.HistoryProfileUpdated, func(ctx context.Context) error {
+		return s.userRepo.Update(ctx, user)
+	})
+	if errors.Is(err, models.ErrVersionConflict) {
+		return nil, versionConflictError()
+	}
+	if err != nil {
+		return nil, apierror.Database(err, "failed to update profile")
+	}
+
+	return &pb.UpdateProfileResponse{User: toProtoUser(user)}, nil
+}
+
+// versionConflictError reports an edit based on an outdated copy of the user
+func versionConflictError() error {
+	return apierror.New(codes.Aborted, apierror.ReasonVersionConflict, "user was changed by another request")
+}
+
+// SetRecoveryEmail registers a secondary email for account recovery and sends
+// a verification token to it
+func (s *Service) SetRecoveryEmail(ctx context.Context, req *pb.SetRecoveryEmailRequest) (*pb.SetRecoveryEmailResponse, error) {
+	claims, ok := middleware.ClaimsFromContext(ctx)
+	if !ok {
+		return nil, apierror.New(codes.Unauthenticated, apierror.ReasonUnauthenticated, "authentication required")
+	}
+
+	recoveryEmail := strings.TrimSpace(req.RecoveryEmail)
+	if strings.EqualFold(recoveryEmail, claims.Email) {
+		return nil, apierror.FieldViolation(apierror.ReasonRecoveryEmailIsPrimary, "recovery_email", "must differ from primary email")
+	}
+
+	err := s.track(ctx, claims.UserID, models.HistoryRecoveryEmailSet, func(ctx context.Context) error {
+		return s.userRepo.SetRecoveryEmail(ctx, claims.UserID, recoveryEmail)
+	})
+	if err != nil {
+		return nil, apierror.Database(err, "failed to set recovery email")
+	}
+
+	// Generate verification token
+	verifyToken := uuid.New().String()
+
+	err = s.cache.SetRecoveryEmailToken(ctx, verifyToken, claims.UserID, recoveryEmail, 24*time.Hour)
+	if err != nil {
+		return nil, apierror.Internal("failed to create verification token")
+	}
+
+	sendEmail(recoveryEmail, "RECOVERY EMAIL VERIFICATION TOKEN",
+		"Token: "+verifyToken,
+		"This token expires in 24 hours",
+	)
+
+	sendEmail(claims.Email, "RECOVERY EMAIL CHANGED",
+		"A new recovery email was added to your account",
+	)
+
+	return &pb.SetRecoveryEmailResponse{
+		Success: true,
+		Message: "Verification link sent to recovery email",
+	}, nil
+}
+
+// VerifyRecoveryEmail confirms ownership of a recovery email
+func (s *Service) VerifyRecoveryEmail(ctx context.Context, req *pb.VerifyRecoveryEmailRequest) (*pb.VerifyRecoveryEmailResponse, error) {
+	userID, recoveryEmail, err := s.cache.GetRecoveryEmailToken(ctx, req.Token)
+	if err != nil {
+		return nil, apierror.New(codes.InvalidArgument, apierror.ReasonInvalidToken, "invalid or expired verification token", apierror.BadRequest("token", "is invalid or expired"))
+	}
+
+	// Fails if the recovery email was replaced after this token was issued
+	err = s.track(ctx, userID, models.HistoryRecoveryEmailVerified, func(ctx context.Context) error {
+		return s.userRepo.VerifyRecoveryEmail(ctx, userID, recoveryEmail)
+	})
+	if err != nil {
+		return nil, apierror.New(codes.InvalidArgument, apierror.ReasonInvalidToken, "invalid or expired verification token", apierror.BadRequest("token", "is invalid or expired"))
+	}
+
+	_ = s.cache.DeleteRecoveryEmailToken(ctx, req.Token)
+
+	return &pb.VerifyRecoveryEmailResponse{
+		Success: true,
+		Message: "Recovery email verified",
+	}, nil
+}
+
+// VerifyEmail confirms ownership of the primary email
+func (s *Service) VerifyEmail(ctx context.Context, req *pb.VerifyEmailRequest) (*pb.VerifyEmailResponse, error) {
+	userID, err := s.cache.GetEmailVerificationToken(ctx, req.Token)
+	if err != nil {
+		return nil, apierror.New(codes.InvalidArgument, apierror.ReasonInvalidToken, "invalid or expired verification token", apierror.BadRequest("token", "is invalid or expired"))
+	}
+
+	err = s.track(ctx, userID, models.HistoryEmailVerified, func(ctx context.Context) error {
+		return s.userRepo.MarkVerified(ctx, userID)
+	})
+	if err != nil {
+		return nil, apierror.Database(err, "failed to verify email")
+	}
+
+	_ = s.cache.DeleteEmailVerificationToken(ctx, req.Token)
+
+	return &pb.VerifyEmailResponse{
+		Success: true,
+		Message: "Email verified successfully",
+	}, nil
+}
+
+// ResendVerification sends a new email verification link
+func (s *Service) ResendVerification(ctx context.Context, req *pb.ResendVerificationRequest) (*pb.ResendVerificationResponse, error) {
+	genericResponse := &pb.ResendVerificationResponse{
+		Success: true,
+		Message: "If your email is registered and unverified, you will receive a verification link",
+	}
+
+	// Don't reveal if email exists or is already verified (security)
+	user, err := s.userRepo.GetByEmail(ctx, req.Email)
+	if err != nil || user.IsVerified {
+		return genericResponse, nil
+	}
+
+	if err := s.sendVerificationEmail(ctx, user); err != nil {
+		return nil, apierror.Internal("failed to create verification token")
+	}
+
+	return genericResponse, nil
+}
+
+// RefreshToken issues a new access token for the session of a refresh
+// token. A session bound to a device at login only refreshes from that
+// device; a refresh from another is rejected and reported to the user's
+// security event subscribers, as the token may have been stolen.
+func (s *Service) RefreshToken(ctx context.Context, req *pb.RefreshTokenRequest) (*pb.RefreshTokenResponse, error) {
+	invalidToken := apierror.New(codes.Unauthenticated, apierror.ReasonInvalidToken, "invalid refresh token")
+
+	tokenID, err := s.refreshTokenSessionID(req.RefreshToken)
+	if err != nil {
+		return nil, invalidToken
+	}
+
+	// Signed-out and expired sessions are gone
+	session, err := s.cache.GetSession(ctx, tokenID)
+	if errors.Is(err, cache.ErrNotFound) {
+		return nil, invalidToken
+	}
+	if err != nil {
+		return nil, apierror.CacheUnavailable()
+	}
+
+	if session.DeviceHash != "" && session.DeviceHash != deviceHash(strings.TrimSpace(req.DeviceId)) {
+		log.Printf("Refresh token of session %s used from another device", session.ID)
+		s.publishSecurityEvent(ctx, session.UserID, pb.SecurityEventType_SECURITY_EVENT_TYPE_DEVICE_MISMATCH)
+		return nil, apierror.New(codes.PermissionDenied, apierror.ReasonDeviceMismatch, "refresh token was issued to another device")
+	}
+
+	user, err := s.userRepo.GetByID(ctx, session.UserID)
+	if db.IsRetryable(err) {
+		return nil, apierror.Database(err, "failed to load user")
+	}
+	if err != nil {
+		return nil, invalidToken
+	}
+	if !user.IsActive {
+		return nil, apierror.New(codes.PermissionDenied, apierror.ReasonAccountDisabled, "account is disabled")
+	}
+	// A session from before the user was flagged mustn't outlive the flag;
+	// signing in again gets the token for ChangePassword
+	if user.MustResetPassword {
+		return nil, apierror.New(codes.PermissionDenied, apierror.ReasonPasswordResetRequired, "password must be changed; sign in again to change it")
+	}
+
+	id, err := s.identity(user)
+	if err != nil {
+		return nil, apierror.Internal("failed to create access token")
+	}
+	accessToken, err := s.jwtService.CreateAccessToken(id, session.ID)
+	if err != nil {
+		return nil, apierror.Internal("failed to create access token")
+	}
+
+	return &pb.RefreshTokenResponse{
+		AccessToken: accessToken,
+		ExpiresIn:   int64(s.config.JWT.AccessTokenExpiry.Seconds()),
+	}, nil
+}
+
+// Logout revokes the caller's refresh token and access token
+func (s *Service) Logout(ctx context.Context, req *pb.LogoutRequest) (*pb.LogoutResponse, error) {
+	claims, ok := middleware.ClaimsFromContext(ctx)
+	if !ok {
+		return nil, apierror.New(codes.Unauthenticated, apierror.ReasonUnauthenticated, "authentication required")
+	}
+
+	tokenID, err := s.refreshTokenSessionID(req.RefreshToken)
+	if err != nil {
+		return nil, apierror.New(codes.InvalidArgument, apierror.ReasonInvalidToken, "invalid refresh token", apierror.BadRequest("refresh_token", "is invalid"))
+	}
+
+	response := &pb.LogoutResponse{
+		Success: true,
+		Message: "Logged out successfully",
+	}
+
+	// An expired or already revoked session leaves nothing to do. If the
+	// cache fails, failing open reports success without revoking anything.
+	session, err := s.cache.GetSession(ctx, tokenID)
+	if err != nil && !errors.Is(err, cache.ErrNotFound) && s.config.Security.CacheOutage.RefreshTokens == config.FailClosed {
+		return nil, apierror.CacheUnavailable()
+	}
+	if err != nil {
+		return response, nil
+	}
+
+	if session.UserID != claims.UserID {
+		return nil, apierror.New(codes.InvalidArgument, apierror.ReasonInvalidToken, "invalid refresh token", apierror.BadRequest("refresh_token", "is invalid"))
+	}
+
+	if _, err := s.cache.DeleteSession(ctx, session.UserID, session.ID); err != nil {
+		return nil, apierror.Internal("failed to revoke refresh token")
+	}
+
+	// The access token would otherwise stay valid until it expires
+	if claims.ExpiresAt != nil {
+		if err := s.cache.DenyToken(ctx, claims.ID, claims.ExpiresAt.Time); err != nil {
+			return nil, apierror.Internal("failed to revoke access token")
+		}
+	}
+
+	s.publishSecurityEvent(ctx, claims.UserID, pb.SecurityEventType_SECURITY_EVENT_TYPE_LOGOUT)
+
+	return response, nil
+}
+
+// StreamSecurityEvents pushes the authenticated user's security events until
+// the client disconnects
+func (s *Service) StreamSecurityEvents(req *pb.StreamSecurityEventsRequest, stream pb.AuthService_StreamSecurityEventsServer) error {
+	ctx := stream.Context()
+
+	claims, ok := middleware.ClaimsFromContext(ctx)
+	if !ok {
+		return apierror.New(codes.Unauthenticated, apierror.ReasonUnauthenticated, "authentication required")
+	}
+
+	// The subscription is confirmed on return, so no event published after
+	// this call returns is missed
+	sub, err := s.events.SubscribeSecurityEvents(ctx, claims.UserID)
+	if err != nil {
+		return apierror.New(codes.Unavailable, apierror.ReasonUnavailable, "failed to subscribe to security events")
+	}
+	defer sub.Close()
+
+	messages := sub.Messages()
+	for {
+		select {
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		case msg, ok := <-messages:
+			if !ok {
+				return apierror.New(codes.Unavailable, apierror.ReasonUnavailable, "security event subscription closed")
+			}
+
+			event := &pb.SecurityEvent{}
+			if err := proto.Unmarshal(msg, event); err != nil {
+				log.Printf("Discarding malformed security event: %v", err)
+				continue
+			}
+
+			if err := stream.Send(event); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// revokeUserTokens revokes every token issued to the user so far, signing
+// out all sessions after a password change. The password has already
+// changed, so a failure is only logged.
+func (s *Service) revokeUserTokens(ctx context.Context, userID string) {
+	if err := s.cache.RevokeUserTokens(ctx, userID); err != nil {
+		log.Printf("Failed to revoke tokens after password change: %v", err)
+	}
+}
+
+// rehashPassword replaces the user's password hash, which password has just
+// verified against, if it is outdated: the bcrypt hash of an imported user,
+// or one made with other Argon2 parameters than the configured ones. The old
+// hash still verifies, so a failure is only logged and the rehash is retried
+// at the next login.
+func (s *Service) rehashPassword(ctx context.Context, user *models.User, password string) {
+	if !s.passService.NeedsRehash(user.PasswordHash) {
+		return
+	}
+
+	newHash, err := s.passService.Hash(password)
+	if err == nil {
+		err = s.userRepo.RehashPassword(ctx, user.ID, user.PasswordHash, newHash)
+	}
+	if err != nil {
+		log.Printf("Failed to rehash password: %v", err)
+		return
+	}
+	user.PasswordHash = newHash
+}
+
+// identity is who user's access tokens are issued to, with the grants of
+// their role, so the token's holder can be authorized without a lookup
+func (s *Service) identity(user *models.User) (jwt.Identity, error) {
+	roles, permissions, err := s.grants.Grants(user.Role)
+	if err != nil {
+		return jwt.Identity{}, err
+	}
+	return jwt.Identity{
+		UserID:      user.ID,
+		TenantID:    user.TenantID,
+		Email:       user.Email,
+		Role:        user.Role,
+		Roles:       roles,
+		Permissions: permissions,
+	}, nil
+}
+
+// publishSecurityEvent notifies the user's StreamSecurityEvents subscribers.
+// Delivery is best effort and never fails the calling RPC.
+func (s *Service) publishSecurityEvent(ctx context.Context, userID string, eventType pb.SecurityEventType) {
+	payload, err := proto.Marshal(&pb.SecurityEvent{
+		Type:       eventType,
+		OccurredAt: timestamppb.Now(),
+	})
+	if err != nil {
+		log.Printf("Failed to encode security event: %v", err)
+		return
+	}
+
+	if err := s.events.PublishSecurityEvent(ctx, userID, payload); err != nil {
+		log.Printf("Failed to publish security event: %v", err)
+	}
+}
+
+// userCreatedEvent is the payload of user.created
+type userCreatedEvent struct {
+	UserID    string    `json:"user_id"`
+	Email     string    `json:"email"`
+	FirstName string    `json:"first_name"`
+	LastName  string    `json:"last_name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// passwordChangedEvent is the payload of password.changed. Method is
+// "change" (by the signed-in user) or "reset" (with an emailed token).
+type passwordChangedEvent struct {
+	UserID string `json:"user_id"`
+	Method string `json:"method"`
+}
+
+// recordEvent adds an outbound event to the outbox. Called with the ctx
+// given by WithinTx, the event commits or rolls back with the change it
+// describes.
+func (s *Service) recordEvent(ctx context.Context, eventType, userID string, payload interface{}) error {
+	if s.outbox == nil {
+		return nil
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	return s.outbox.Add(ctx, eventType, userID, body)
+}
+
+// track runs change, which modifies the user, recording it in the user
+// history as action when history is configured. It joins the transaction in
+// ctx, if any.
+func (s *Service) track(ctx context.Context, userID, action string, change func(ctx context.Context) error) error {
+	if s.history == nil {
+		return change(ctx)
+	}
+	_, err := s.history.Track(ctx, userID, action, change)
+	return err
+}
+
+// verificationEmailInterval is the least time between verification emails
+// to one user, across every server instance
+const verificationEmailInterval = time.Minute
+
+// sendVerificationEmail issues a verification token and emails it to the
+// user, unless one was sent within verificationEmailInterval. The check is
+// skipped if the cache fails, as rate limits are.
+func (s *Service) sendVerificationEmail(ctx context.Context, user *models.User) error {
+	// The lock is left to expire, so it allows one send per interval
+	if _, err := s.cache.Lock(ctx, "verification_email:"+user.ID, verificationEmailInterval); errors.Is(err, cache.ErrLockHeld) {
+		return nil
+	}
+
+	verifyToken := uuid.New().String()
+
+	if err := s.cache.SetEmailVerificationToken(ctx, verifyToken, user.ID, 24*time.Hour); err != nil {
+		return err
+	}
+
+	sendEmail(user.Email, "EMAIL VERIFICATION TOKEN",
+		"Token: "+verifyToken,
+		"This token expires in 24 hours",
+	)
+
+	return nil
+}
+
+// emailNotVerifiedError builds the FailedPrecondition status returned by Login
+// when verification is required, with details pointing at ResendVerification
+func emailNotVerifiedError() error {
+	return apierror.NewWithMetadata(codes.FailedPrecondition, apierror.ReasonEmailNotVerified, "email address is not verified",
+		map[string]string{
+			"resend_method": pb.AuthService_ResendVerification_FullMethodName,
+		},
+		&errdetails.PreconditionFailure{
+			Violations: []*errdetails.PreconditionFailure_Violation{
+				{
+					Type:        "EMAIL_VERIFICATION",
+					Subject:     "email",
+					Description: "Verify your email address or request a new link with ResendVerification",
+				},
+			},
+		},
+	)
+}
+
+// toProtoUser converts a user model to its protobuf representation
+func toProtoUser(user *models.User) *pb.User {
+	u := &pb.User{
+		Id:        user.ID,
+		Email:     user.Email,
+		FirstName: user.FirstName,
+		LastName:  user.LastName,
+		Version:   user.Version,
+		Metadata:  user.Metadata,
+		CreatedAt: timestamppb.New(user.CreatedAt),
+		UpdatedAt: timestamppb.New(user.UpdatedAt),
+	}
+	if user.PhoneNumber != nil {
+		u.PhoneNumber = *user.PhoneNumber
+	}
+	return u
+}
+
+// sendEmail delivers a transactional email.
+// TODO: Integrate an email provider; for development the message is logged
+func sendEmail(to, subject string, lines ...string) {
+	log.Printf("=== %s ===", subject)
+	log.Printf("Email: %s", to)
+	for _, line := range lines {
+		log.Printf("%s", line)
+	}
+	log.Printf("=============================")
+}
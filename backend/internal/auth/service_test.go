@@ -0,0 +1,457 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/apierror"
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/cache"
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/config"
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/middleware"
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/models"
+	"github.com/sahays/grpc-proto-go-flutter-template/pkg/jwt"
+	"github.com/sahays/grpc-proto-go-flutter-template/pkg/password"
+	pb "github.com/sahays/grpc-proto-go-flutter-template/proto"
+)
+
+// testPassword is the password of every user newTestService creates
+const testPassword = "correct horse battery staple"
+
+// fakeUsers is a UserStore in memory. err, if set, fails every lookup.
+type fakeUsers struct {
+	byID map[string]*models.User
+	err  error
+}
+
+var errUserNotFound = errors.New("user not found")
+
+func (f *fakeUsers) add(user *models.User) {
+	f.byID[user.ID] = user
+}
+
+func (f *fakeUsers) get(id string) (*models.User, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	user, ok := f.byID[id]
+	if !ok {
+		return nil, errUserNotFound
+	}
+	// Callers get their own copy, as from the database
+	copied := *user
+	return &copied, nil
+}
+
+func (f *fakeUsers) Create(ctx context.Context, user *models.User) error {
+	f.add(user)
+	return nil
+}
+
+func (f *fakeUsers) GetByID(ctx context.Context, id string) (*models.User, error) {
+	return f.get(id)
+}
+
+func (f *fakeUsers) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	for _, user := range f.byID {
+		if user.Email == email {
+			return f.get(user.ID)
+		}
+	}
+	return nil, errUserNotFound
+}
+
+func (f *fakeUsers) EmailExists(ctx context.Context, email string) (bool, error) {
+	_, err := f.GetByEmail(ctx, email)
+	return err == nil, nil
+}
+
+func (f *fakeUsers) Update(ctx context.Context, user *models.User) error {
+	f.add(user)
+	return nil
+}
+
+func (f *fakeUsers) UpdateLastLogin(ctx context.Context, userID string) error {
+	return nil
+}
+
+// UpdatePassword clears MustResetPassword, as UserRepository does
+func (f *fakeUsers) UpdatePassword(ctx context.Context, userID, passwordHash string) error {
+	user, ok := f.byID[userID]
+	if !ok {
+		return errUserNotFound
+	}
+	user.PasswordHash = passwordHash
+	user.MustResetPassword = false
+	return nil
+}
+
+func (f *fakeUsers) RehashPassword(ctx context.Context, userID, oldHash, newHash string) error {
+	user, ok := f.byID[userID]
+	if ok && user.PasswordHash == oldHash {
+		user.PasswordHash = newHash
+	}
+	return nil
+}
+
+func (f *fakeUsers) MarkVerified(ctx context.Context, userID string) error {
+	return nil
+}
+
+func (f *fakeUsers) SetRecoveryEmail(ctx context.Context, userID, recoveryEmail string) error {
+	return nil
+}
+
+func (f *fakeUsers) VerifyRecoveryEmail(ctx context.Context, userID, recoveryEmail string) error {
+	return nil
+}
+
+// fakeTx runs functions without a transaction
+type fakeTx struct{}
+
+func (fakeTx) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+// fakeOutbox records the types of the events added to it
+type fakeOutbox struct {
+	events []string
+}
+
+func (f *fakeOutbox) Add(ctx context.Context, eventType, aggregateID string, payload []byte) error {
+	f.events = append(f.events, eventType)
+	return nil
+}
+
+// fakeGrants grants each role only itself
+type fakeGrants struct{}
+
+func (fakeGrants) Grants(role string) ([]string, []string, error) {
+	return []string{role}, nil, nil
+}
+
+// testService is a Service with fake stores and the real cache, token and
+// password services
+type testService struct {
+	*Service
+	users  *fakeUsers
+	outbox *fakeOutbox
+	cache  *cache.Cache
+	jwt    *jwt.Service
+}
+
+func newTestConfig() *config.Config {
+	cfg := &config.Config{}
+	cfg.JWT.Algorithm = jwt.AlgorithmHS256
+	cfg.JWT.Format = config.TokenFormatJWT
+	cfg.JWT.RefreshTokenFormat = config.RefreshTokenJWT
+	cfg.JWT.Issuer = "test-issuer"
+	cfg.JWT.AccessTokenExpiry = time.Minute
+	cfg.JWT.RefreshTokenExpiry = time.Hour
+	// Cheap hashes; the parameters don't change what's tested
+	cfg.Argon2 = config.Argon2Config{Memory: 64, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+	cfg.Password = config.PasswordConfig{MinLength: 8, MaxLength: 128, MinScore: 3, BlockCommon: true}
+	cfg.Security.MaxLoginAttempts = 3
+	cfg.Security.LockoutDuration = time.Minute
+	return cfg
+}
+
+// newTestService creates a service with configure applied to its config,
+// and with the users "active", "disabled", "unverified" and "must-reset",
+// whose emails are their IDs at example.com
+func newTestService(t *testing.T, configure func(cfg *config.Config)) *testService {
+	t.Helper()
+
+	cfg := newTestConfig()
+	if configure != nil {
+		configure(cfg)
+	}
+
+	jwtService, err := jwt.New(cfg)
+	if err != nil {
+		t.Fatalf("jwt.New: %v", err)
+	}
+	passService, err := password.New(cfg)
+	if err != nil {
+		t.Fatalf("password.New: %v", err)
+	}
+	hash, err := passService.Hash(testPassword)
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+
+	users := &fakeUsers{byID: make(map[string]*models.User)}
+	for _, user := range []*models.User{
+		{ID: "active", IsActive: true, IsVerified: true},
+		{ID: "disabled", IsVerified: true},
+		{ID: "unverified", IsActive: true},
+		{ID: "must-reset", IsActive: true, IsVerified: true, MustResetPassword: true},
+	} {
+		user.Email = user.ID + "@example.com"
+		user.PasswordHash = hash
+		user.Role = "user"
+		users.add(user)
+	}
+
+	outbox := &fakeOutbox{}
+	c := cache.NewWithStore(cfg, cache.NewMemoryStore())
+	return &testService{
+		Service: NewService(cfg, users, fakeTx{}, outbox, nil, c, c, fakeGrants{}, jwtService, passService),
+		users:   users,
+		outbox:  outbox,
+		cache:   c,
+		jwt:     jwtService,
+	}
+}
+
+// checkStatus fails the test unless err has code and, in its ErrorInfo,
+// reason
+func checkStatus(t *testing.T, err error, code codes.Code, reason string) {
+	t.Helper()
+
+	st := status.Convert(err)
+	var gotReason string
+	for _, detail := range st.Details() {
+		if info, ok := detail.(*errdetails.ErrorInfo); ok {
+			gotReason = info.Reason
+		}
+	}
+	if st.Code() != code || gotReason != reason {
+		t.Errorf("error = %v %s (%v), want %v %s", st.Code(), gotReason, err, code, reason)
+	}
+}
+
+func TestLogin(t *testing.T) {
+	tests := []struct {
+		name      string
+		configure func(cfg *config.Config)
+		// failures is how many logins with a wrong password come first
+		failures int
+		// lookupErr fails the user lookups
+		lookupErr error
+		email     string
+		password  string
+		wantCode  codes.Code
+		// wantReason is the ErrorInfo reason of an error
+		wantReason string
+		// wantRefresh is whether a refresh token and session are issued;
+		// false for a token scoped to ChangePassword
+		wantRefresh bool
+	}{
+		{name: "valid", email: "active@example.com", password: testPassword, wantRefresh: true},
+		{name: "unverified, not required", email: "unverified@example.com", password: testPassword, wantRefresh: true},
+		{name: "wrong password", email: "active@example.com", password: "wrong password", wantCode: codes.Unauthenticated, wantReason: apierror.ReasonInvalidCredentials},
+		{name: "unknown email", email: "nobody@example.com", password: testPassword, wantCode: codes.Unauthenticated, wantReason: apierror.ReasonInvalidCredentials},
+		{name: "disabled", email: "disabled@example.com", password: testPassword, wantCode: codes.PermissionDenied, wantReason: apierror.ReasonAccountDisabled},
+		{
+			name:      "unverified, required",
+			configure: func(cfg *config.Config) { cfg.Security.RequireEmailVerification = true },
+			email:     "unverified@example.com", password: testPassword,
+			wantCode: codes.FailedPrecondition, wantReason: apierror.ReasonEmailNotVerified,
+		},
+		{
+			name:      "unverified, required, wrong password",
+			configure: func(cfg *config.Config) { cfg.Security.RequireEmailVerification = true },
+			email:     "unverified@example.com", password: "wrong password",
+			wantCode: codes.Unauthenticated, wantReason: apierror.ReasonInvalidCredentials,
+		},
+		{name: "must reset password", email: "must-reset@example.com", password: testPassword},
+		{name: "after failures below the limit", failures: 2, email: "active@example.com", password: testPassword, wantRefresh: true},
+		{name: "after too many failures", failures: 3, email: "active@example.com", password: testPassword, wantCode: codes.PermissionDenied, wantReason: apierror.ReasonTooManyLoginAttempts},
+		{name: "database unreachable", lookupErr: io.ErrUnexpectedEOF, email: "active@example.com", password: testPassword, wantCode: codes.Unavailable, wantReason: apierror.ReasonUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService(t, tt.configure)
+			ctx := context.Background()
+
+			for i := 0; i < tt.failures; i++ {
+				_, _ = s.Login(ctx, &pb.LoginRequest{Email: tt.email, Password: "wrong password"})
+			}
+			s.users.err = tt.lookupErr
+
+			resp, err := s.Login(ctx, &pb.LoginRequest{Email: tt.email, Password: tt.password, DeviceId: "phone"})
+			if tt.wantCode != codes.OK {
+				checkStatus(t, err, tt.wantCode, tt.wantReason)
+				return
+			}
+			if err != nil {
+				t.Fatalf("Login: %v", err)
+			}
+
+			claims, err := s.jwt.ValidateAccessToken(resp.AccessToken)
+			if err != nil {
+				t.Fatalf("ValidateAccessToken: %v", err)
+			}
+			if claims.Email != tt.email {
+				t.Errorf("access token email = %q, want %q", claims.Email, tt.email)
+			}
+			if claims.IsRestricted() == tt.wantRefresh || resp.MustResetPassword == tt.wantRefresh {
+				t.Errorf("restricted token = %v, MustResetPassword = %v, want %v", claims.IsRestricted(), resp.MustResetPassword, !tt.wantRefresh)
+			}
+			if (resp.RefreshToken != "") != tt.wantRefresh {
+				t.Fatalf("refresh token = %q, want one %v", resp.RefreshToken, tt.wantRefresh)
+			}
+			if !tt.wantRefresh {
+				return
+			}
+
+			sessionID, err := s.refreshTokenSessionID(resp.RefreshToken)
+			if err != nil {
+				t.Fatalf("refreshTokenSessionID: %v", err)
+			}
+			session, err := s.cache.GetSession(ctx, sessionID)
+			if err != nil {
+				t.Fatalf("GetSession: %v", err)
+			}
+			if session.UserID != claims.UserID || session.DeviceHash != deviceHash("phone") {
+				t.Errorf("session = %+v, want user %q on the login's device", session, claims.UserID)
+			}
+		})
+	}
+}
+
+func TestRefreshToken(t *testing.T) {
+	tests := []struct {
+		name string
+		// token makes the refresh token sent from the one issued at login
+		token    func(issued string) string
+		deviceID string
+		// change alters the user after login
+		change     func(user *models.User)
+		lookupErr  error
+		wantCode   codes.Code
+		wantReason string
+	}{
+		{name: "valid", deviceID: "phone"},
+		{name: "device ID trimmed", deviceID: " phone\n"},
+		{name: "malformed", token: func(string) string { return "not-a-token" }, deviceID: "phone", wantCode: codes.Unauthenticated, wantReason: apierror.ReasonInvalidToken},
+		{
+			name:     "opaque, no session",
+			token:    func(string) string { return "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA" },
+			deviceID: "phone", wantCode: codes.Unauthenticated, wantReason: apierror.ReasonInvalidToken,
+		},
+		{name: "tampered", token: func(issued string) string { return issued + "x" }, deviceID: "phone", wantCode: codes.Unauthenticated, wantReason: apierror.ReasonInvalidToken},
+		{name: "other device", deviceID: "laptop", wantCode: codes.PermissionDenied, wantReason: apierror.ReasonDeviceMismatch},
+		{name: "no device", wantCode: codes.PermissionDenied, wantReason: apierror.ReasonDeviceMismatch},
+		{name: "user disabled", deviceID: "phone", change: func(user *models.User) { user.IsActive = false }, wantCode: codes.PermissionDenied, wantReason: apierror.ReasonAccountDisabled},
+		{name: "user flagged for reset", deviceID: "phone", change: func(user *models.User) { user.MustResetPassword = true }, wantCode: codes.PermissionDenied, wantReason: apierror.ReasonPasswordResetRequired},
+		{name: "user deleted", deviceID: "phone", change: func(user *models.User) { user.ID = "deleted" }, wantCode: codes.Unauthenticated, wantReason: apierror.ReasonInvalidToken},
+		{name: "database unreachable", deviceID: "phone", lookupErr: io.ErrUnexpectedEOF, wantCode: codes.Unavailable, wantReason: apierror.ReasonUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService(t, nil)
+			ctx := context.Background()
+
+			login, err := s.Login(ctx, &pb.LoginRequest{Email: "active@example.com", Password: testPassword, DeviceId: "phone"})
+			if err != nil {
+				t.Fatalf("Login: %v", err)
+			}
+			if tt.change != nil {
+				user := s.users.byID["active"]
+				delete(s.users.byID, user.ID)
+				tt.change(user)
+				s.users.add(user)
+			}
+			s.users.err = tt.lookupErr
+
+			token := login.RefreshToken
+			if tt.token != nil {
+				token = tt.token(token)
+			}
+			resp, err := s.RefreshToken(ctx, &pb.RefreshTokenRequest{RefreshToken: token, DeviceId: tt.deviceID})
+			if tt.wantCode != codes.OK {
+				checkStatus(t, err, tt.wantCode, tt.wantReason)
+				return
+			}
+			if err != nil {
+				t.Fatalf("RefreshToken: %v", err)
+			}
+
+			claims, err := s.jwt.ValidateAccessToken(resp.AccessToken)
+			if err != nil {
+				t.Fatalf("ValidateAccessToken: %v", err)
+			}
+			sessionID, _ := s.refreshTokenSessionID(login.RefreshToken)
+			if claims.UserID != "active" || claims.SessionID != sessionID {
+				t.Errorf("access token of user %q, session %q, want %q, %q", claims.UserID, claims.SessionID, "active", sessionID)
+			}
+		})
+	}
+}
+
+func TestChangePassword(t *testing.T) {
+	const newPassword = "violet tundra ledger 87"
+
+	tests := []struct {
+		name string
+		// userID is the authenticated user; "" for none
+		userID          string
+		currentPassword string
+		newPassword     string
+		wantCode        codes.Code
+		wantReason      string
+	}{
+		{name: "valid", userID: "active", currentPassword: testPassword, newPassword: newPassword},
+		{name: "clears forced reset", userID: "must-reset", currentPassword: testPassword, newPassword: newPassword},
+		{name: "unauthenticated", currentPassword: testPassword, newPassword: newPassword, wantCode: codes.Unauthenticated, wantReason: apierror.ReasonUnauthenticated},
+		{name: "weak", userID: "active", currentPassword: testPassword, newPassword: "password1", wantCode: codes.InvalidArgument, wantReason: apierror.ReasonWeakPassword},
+		{name: "built from email", userID: "active", currentPassword: testPassword, newPassword: "example-active", wantCode: codes.InvalidArgument, wantReason: apierror.ReasonWeakPassword},
+		{name: "unchanged", userID: "active", currentPassword: testPassword, newPassword: testPassword, wantCode: codes.InvalidArgument, wantReason: apierror.ReasonPasswordUnchanged},
+		{name: "wrong current password", userID: "active", currentPassword: "wrong password", newPassword: newPassword, wantCode: codes.Unauthenticated, wantReason: apierror.ReasonIncorrectPassword},
+		{name: "disabled", userID: "disabled", currentPassword: testPassword, newPassword: newPassword, wantCode: codes.PermissionDenied, wantReason: apierror.ReasonAccountDisabled},
+		{name: "unknown user", userID: "deleted", currentPassword: testPassword, newPassword: newPassword, wantCode: codes.Unauthenticated, wantReason: apierror.ReasonUserNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService(t, nil)
+			ctx := context.Background()
+
+			// A session of the user's, which a change must sign out
+			session := &cache.Session{ID: "session", UserID: tt.userID, ExpiresAt: time.Now().Add(time.Hour)}
+			if err := s.cache.CreateSession(ctx, session); err != nil {
+				t.Fatalf("CreateSession: %v", err)
+			}
+			if tt.userID != "" {
+				ctx = middleware.ContextWithClaims(ctx, &jwt.Claims{UserID: tt.userID, Email: tt.userID + "@example.com"})
+			}
+
+			_, err := s.ChangePassword(ctx, &pb.ChangePasswordRequest{CurrentPassword: tt.currentPassword, NewPassword: tt.newPassword})
+			_, sessionErr := s.cache.GetSession(context.Background(), session.ID)
+			if tt.wantCode != codes.OK {
+				checkStatus(t, err, tt.wantCode, tt.wantReason)
+				if len(s.outbox.events) != 0 || sessionErr != nil {
+					t.Errorf("rejected change recorded events %q, session lookup error %v", s.outbox.events, sessionErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ChangePassword: %v", err)
+			}
+
+			user := s.users.byID[tt.userID]
+			if valid, _ := s.passService.Verify(tt.newPassword, user.PasswordHash); !valid {
+				t.Error("new password doesn't verify")
+			}
+			if user.MustResetPassword {
+				t.Error("MustResetPassword still set")
+			}
+			if len(s.outbox.events) != 1 || s.outbox.events[0] != models.EventPasswordChanged {
+				t.Errorf("events = %q, want %q", s.outbox.events, models.EventPasswordChanged)
+			}
+			if !errors.Is(sessionErr, cache.ErrNotFound) {
+				t.Errorf("GetSession error = %v, want the session signed out", sessionErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package auth
+
+import (
+	"context"
+	"time"
+
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/authz"
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/cache"
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/db"
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/history"
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/models"
+)
+
+// UserStore is the user persistence the service needs, implemented by
+// *models.UserRepository
+type UserStore interface {
+	Create(ctx context.Context, user *models.User) error
+	GetByID(ctx context.Context, id string) (*models.User, error)
+	GetByEmail(ctx context.Context, email string) (*models.User, error)
+	EmailExists(ctx context.Context, email string) (bool, error)
+	Update(ctx context.Context, user *models.User) error
+	UpdateLastLogin(ctx context.Context, userID string) error
+	UpdatePassword(ctx context.Context, userID, passwordHash string) error
+	RehashPassword(ctx context.Context, userID, oldHash, newHash string) error
+	MarkVerified(ctx context.Context, userID string) error
+	SetRecoveryEmail(ctx context.Context, userID, recoveryEmail string) error
+	VerifyRecoveryEmail(ctx context.Context, userID, recoveryEmail string) error
+}
+
+// TokenCache stores the service's sessions, short-lived tokens, token
+// revocations, login attempt counters and locks, implemented by *cache.Cache
+type TokenCache interface {
+	CreateSession(ctx context.Context, session *cache.Session) error
+	GetSession(ctx context.Context, id string) (*cache.Session, error)
+	DeleteSession(ctx context.Context, userID, id string) (bool, error)
+
+	SetPasswordResetToken(ctx context.Context, token, userID string, ttl time.Duration) error
+	GetPasswordResetToken(ctx context.Context, token string) (string, error)
+	DeletePasswordResetToken(ctx context.Context, token string) error
+
+	SetEmailVerificationToken(ctx context.Context, token, userID string, ttl time.Duration) error
+	GetEmailVerificationToken(ctx context.Context, token string) (string, error)
+	DeleteEmailVerificationToken(ctx context.Context, token string) error
+
+	SetRecoveryEmailToken(ctx context.Context, token, userID, recoveryEmail string, ttl time.Duration) error
+	GetRecoveryEmailToken(ctx context.Context, token string) (string, string, error)
+	DeleteRecoveryEmailToken(ctx context.Context, token string) error
+
+	AllowLoginAttempt(ctx context.Context, identifier string, limit cache.Limit) (cache.RateLimitResult, error)
+	ClearLoginAttempts(ctx context.Context, identifier string) error
+
+	DenyToken(ctx context.Context, jti string, expiresAt time.Time) error
+	RevokeUserTokens(ctx context.Context, userID string) error
+	IsTokenRevoked(ctx context.Context, jti, userID string, issuedAt time.Time) (bool, error)
+
+	Lock(ctx context.Context, key string, ttl time.Duration) (*cache.Lock, error)
+}
+
+// SecurityEventBus fans security events out to every server instance,
+// implemented by *cache.Cache
+type SecurityEventBus interface {
+	PublishSecurityEvent(ctx context.Context, userID string, event []byte) error
+	SubscribeSecurityEvents(ctx context.Context, userID string) (cache.Subscription, error)
+}
+
+// RoleGrants resolves the roles and permissions access tokens carry,
+// implemented by *authz.Authorizer
+type RoleGrants interface {
+	Grants(role string) (roles, permissions []string, err error)
+}
+
+// Transactor runs a function atomically, implemented by *db.TxManager. Store
+// calls made with the ctx passed to fn join the transaction.
+type Transactor interface {
+	WithinTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// EventOutbox records outbound events for delivery by the outbox relay,
+// implemented by *models.OutboxRepository. Add joins the transaction in ctx.
+type EventOutbox interface {
+	Add(ctx context.Context, eventType, aggregateID string, payload []byte) error
+}
+
+// ChangeTracker records changes to users in the user history, implemented
+// by *history.Recorder. Track joins the transaction in ctx.
+type ChangeTracker interface {
+	Track(ctx context.Context, userID, action string, change func(ctx context.Context) error) (*models.UserHistoryEntry, error)
+}
+
+var (
+	_ UserStore        = (*models.UserRepository)(nil)
+	_ TokenCache       = (*cache.Cache)(nil)
+	_ SecurityEventBus = (*cache.Cache)(nil)
+	_ RoleGrants       = (*authz.Authorizer)(nil)
+	_ Transactor       = (*db.TxManager)(nil)
+	_ EventOutbox      = (*models.OutboxRepository)(nil)
+	_ ChangeTracker    = (*history.Recorder)(nil)
+)
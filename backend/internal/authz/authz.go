@@ -0,0 +1,161 @@
+// Package authz decides whether a caller may invoke a gRPC method, using a
+// Casbin model and policy instead of checks scattered through handlers
+package authz
+
+import (
+	_ "embed"
+	"fmt"
+	"os"
+	"sync/atomic"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/casbin/casbin/v2/model"
+	stringadapter "github.com/casbin/casbin/v2/persist/string-adapter"
+
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/config"
+)
+
+// Built-in roles. Users get RoleUser unless their row says otherwise.
+const (
+	RoleAnonymous = "anonymous"
+	RoleUser      = "user"
+	RoleAdmin     = "admin"
+)
+
+//go:embed policy/model.conf
+var defaultModel string
+
+//go:embed policy/policy.csv
+var defaultPolicy string
+
+// Subject is the caller, exposed to policy rules as r.sub
+type Subject struct {
+	ID   string
+	Role string
+}
+
+// Resource describes what the request acts on, exposed to policy rules as r.res
+type Resource struct {
+	// Owner is the user the request targets, empty if it targets none
+	Owner string
+}
+
+// ownedRequest is implemented by generated messages with a user_id field
+type ownedRequest interface {
+	GetUserId() string
+}
+
+// ResourceOf extracts the resource attributes of a request message
+func ResourceOf(req interface{}) Resource {
+	var res Resource
+	if owned, ok := req.(ownedRequest); ok {
+		res.Owner = owned.GetUserId()
+	}
+	return res
+}
+
+// Authorizer evaluates the authorization policy
+type Authorizer struct {
+	cfg      *config.Config
+	enforcer atomic.Pointer[casbin.SyncedEnforcer]
+}
+
+// New loads the model and policy from the configured files, falling back to
+// the built-in ones
+func New(cfg *config.Config) (*Authorizer, error) {
+	enforcer, err := load(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	a := &Authorizer{cfg: cfg}
+	a.enforcer.Store(enforcer)
+	return a, nil
+}
+
+// Reload reads the model and policy files again. Calls being authorized
+// meanwhile use the old policy; if the files are invalid it is kept.
+func (a *Authorizer) Reload() error {
+	enforcer, err := load(a.cfg)
+	if err != nil {
+		return err
+	}
+
+	a.enforcer.Store(enforcer)
+	return nil
+}
+
+// Authorize reports whether sub may call method on res
+func (a *Authorizer) Authorize(sub Subject, method string, res Resource) (bool, error) {
+	return a.enforcer.Load().Enforce(sub, method, res)
+}
+
+// Grants returns role and every role it inherits, and the method patterns
+// those roles are granted unconditionally. Grants with a rule depend on the
+// request, so they are left out, as is what anonymous callers may do.
+// Access tokens carry both, so other services can authorize callers without
+// the policy.
+func (a *Authorizer) Grants(role string) (roles, permissions []string, err error) {
+	enforcer := a.enforcer.Load()
+
+	inherited, err := enforcer.GetImplicitRolesForUser(role)
+	if err != nil {
+		return nil, nil, err
+	}
+	roles = []string{role}
+	for _, r := range inherited {
+		if r != RoleAnonymous {
+			roles = append(roles, r)
+		}
+	}
+
+	rules, err := enforcer.GetImplicitPermissionsForUser(role)
+	if err != nil {
+		return nil, nil, err
+	}
+	seen := make(map[string]bool, len(rules))
+	for _, rule := range rules {
+		// Rules are (role, method, rule)
+		if len(rule) < 3 || rule[0] == RoleAnonymous || rule[2] != "true" || seen[rule[1]] {
+			continue
+		}
+		seen[rule[1]] = true
+		permissions = append(permissions, rule[1])
+	}
+	return roles, permissions, nil
+}
+
+func load(cfg *config.Config) (*casbin.SyncedEnforcer, error) {
+	modelText, err := readOrDefault(cfg.Security.AuthzModelPath, defaultModel)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read authorization model: %w", err)
+	}
+
+	policyText, err := readOrDefault(cfg.Security.AuthzPolicyPath, defaultPolicy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read authorization policy: %w", err)
+	}
+
+	m, err := model.NewModelFromString(modelText)
+	if err != nil {
+		return nil, fmt.Errorf("invalid authorization model: %w", err)
+	}
+
+	enforcer, err := casbin.NewSyncedEnforcer(m, stringadapter.NewAdapter(policyText))
+	if err != nil {
+		return nil, fmt.Errorf("invalid authorization policy: %w", err)
+	}
+	return enforcer, nil
+}
+
+func readOrDefault(path, fallback string) (string, error) {
+	if path == "" {
+		return fallback, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
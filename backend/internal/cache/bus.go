@@ -0,0 +1,155 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// Event types sent on the Bus. Each names what changed; Event.Key says
+// which one.
+const (
+	// EventTenantChanged drops a tenant from every instance's tenant cache;
+	// the key is the tenant ID
+	EventTenantChanged = "tenant_changed"
+	// EventForcedLogout ends a user's open streams on every instance; the
+	// key is the user ID
+	EventForcedLogout = "forced_logout"
+	// EventConfigChanged reloads a piece of runtime configuration on every
+	// instance; the key names it, such as ConfigAuthzPolicy
+	EventConfigChanged = "config_changed"
+)
+
+// ConfigAuthzPolicy is the EventConfigChanged key of the authorization
+// policy
+const ConfigAuthzPolicy = "authz_policy"
+
+const (
+	busChannel = "bus:events"
+	// busRetryInterval is how often a failed subscription is retried
+	busRetryInterval = 5 * time.Second
+)
+
+// Event is a message on the Bus
+type Event struct {
+	Type string `json:"type"`
+	Key  string `json:"key"`
+	// Origin identifies the publishing instance, so it skips its own events
+	// when they come back from the store
+	Origin string `json:"origin"`
+}
+
+// Handler handles an event's key. It runs on the publishing goroutine for
+// this instance's events and on the Run goroutine for other instances', so
+// it must be safe for concurrent use and must not block.
+type Handler func(key string)
+
+// Bus broadcasts events such as cache invalidations, forced logouts and
+// config changes to every server instance, over the cache's pub/sub. Events
+// are fire and forget: an instance that isn't subscribed when an event is
+// published, such as during a Redis outage, never sees it.
+type Bus struct {
+	cache  *Cache
+	origin string
+	logger *zap.Logger
+
+	mu       sync.RWMutex
+	handlers map[string][]Handler
+}
+
+// NewBus creates a bus on c. Register handlers with Handle, then call Run to
+// receive other instances' events.
+func NewBus(c *Cache, logger *zap.Logger) *Bus {
+	return &Bus{
+		cache:    c,
+		origin:   uuid.NewString(),
+		logger:   logger,
+		handlers: make(map[string][]Handler),
+	}
+}
+
+// Handle registers handler for events of eventType
+func (b *Bus) Handle(eventType string, handler Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers[eventType] = append(b.handlers[eventType], handler)
+}
+
+// Publish runs this instance's handlers for the event, then sends it to the
+// other instances. The local handlers run even if sending fails.
+func (b *Bus) Publish(ctx context.Context, eventType, key string) error {
+	event := Event{Type: eventType, Key: key, Origin: b.origin}
+	b.dispatch(event)
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return b.cache.store.Publish(ctx, busChannel, data)
+}
+
+// Run delivers other instances' events to the handlers until ctx ends. If
+// subscribing fails it is retried every few seconds.
+func (b *Bus) Run(ctx context.Context) {
+	var sub Subscription
+	defer func() {
+		if sub != nil {
+			_ = sub.Close()
+		}
+	}()
+	var messages <-chan []byte
+	subscribe := func() {
+		var err error
+		sub, err = b.cache.store.Subscribe(ctx, busChannel)
+		if err != nil {
+			if ctx.Err() == nil {
+				b.logger.Warn("failed to subscribe to the event bus", zap.Error(err))
+			}
+			return
+		}
+		messages = sub.Messages()
+	}
+	subscribe()
+
+	ticker := time.NewTicker(busRetryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case data, ok := <-messages:
+			if !ok {
+				// Closed: resubscribe on the next tick
+				sub, messages = nil, nil
+				continue
+			}
+			var event Event
+			if err := json.Unmarshal(data, &event); err != nil {
+				b.logger.Warn("malformed event bus message", zap.Error(err))
+				continue
+			}
+			if event.Origin != b.origin {
+				b.dispatch(event)
+			}
+		case <-ticker.C:
+			if sub == nil {
+				subscribe()
+			}
+		}
+	}
+}
+
+func (b *Bus) dispatch(event Event) {
+	b.mu.RLock()
+	handlers := b.handlers[event.Type]
+	b.mu.RUnlock()
+
+	for _, handler := range handlers {
+		handler(event.Key)
+	}
+}
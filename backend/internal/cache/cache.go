@@ -0,0 +1,291 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/config"
+)
+
+// Cache stores the server's tokens, counters and pub/sub channels in a Store
+type Cache struct {
+	store  Store
+	config *config.Config
+	// fallback enforces rate limits in process while the store fails; nil
+	// to return errors
+	fallback *localLimiter
+	sessions *Typed[Session]
+}
+
+// New creates the cache in the store selected by CACHE_BACKEND: Redis, or
+// process memory. For Redis with REDIS_SENTINEL_ADDRS set it asks the
+// sentinels for the master and reconnects to the new one after a failover;
+// otherwise it connects to REDIS_URL or REDIS_HOST:REDIS_PORT.
+func New(cfg *config.Config) (*Cache, error) {
+	var store Store
+	switch cfg.Cache.Backend {
+	case "memory":
+		store = NewMemoryStore()
+	default:
+		redisStore, err := NewRedisStore(cfg)
+		if err != nil {
+			return nil, err
+		}
+		store = redisStore
+	}
+	return NewWithStore(cfg, store), nil
+}
+
+// NewWithStore creates a cache in store, such as a MemoryStore in tests or
+// another backend. Operations are metered, and keys are put under
+// CACHE_KEY_PREFIX, if set.
+func NewWithStore(cfg *config.Config, store Store) *Cache {
+	if cfg.Cache.KeyPrefix != "" {
+		store = &prefixedStore{Store: store, prefix: cfg.Cache.KeyPrefix}
+	}
+	store = &meteredStore{Store: store}
+
+	c := &Cache{
+		store:  store,
+		config: cfg,
+	}
+	if cfg.Redis.Fallback == FallbackLocal {
+		c.fallback = newLocalLimiter(cfg.Redis.FallbackMaxEntries)
+	}
+	// Each session expires with its refresh token, so it has no default TTL
+	c.sessions = NewTyped(c, "session", JSONCodec[Session](), 0, 0)
+	return c
+}
+
+// Backend names the store, as selected by CACHE_BACKEND
+func (c *Cache) Backend() string {
+	store := c.store
+	if metered, ok := store.(*meteredStore); ok {
+		store = metered.Store
+	}
+	if prefixed, ok := store.(*prefixedStore); ok {
+		store = prefixed.Store
+	}
+
+	if _, ok := store.(*MemoryStore); ok {
+		return "memory"
+	}
+	if _, ok := store.(*RedisStore); ok {
+		return "redis"
+	}
+	return fmt.Sprintf("%T", store)
+}
+
+// Close closes the store
+func (c *Cache) Close() error {
+	return c.store.Close()
+}
+
+// Health checks that the store answers
+func (c *Cache) Health(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	if err := c.store.Ping(ctx); err != nil {
+		return fmt.Errorf("%s ping failed: %w", c.Backend(), err)
+	}
+
+	return nil
+}
+
+// Set stores a key-value pair with TTL
+func (c *Cache) Set(ctx context.Context, key, value string, ttl time.Duration) error {
+	return c.store.Set(ctx, key, value, ttl)
+}
+
+// Get retrieves a value by key, returning ErrNotFound if there is none
+func (c *Cache) Get(ctx context.Context, key string) (string, error) {
+	return c.store.Get(ctx, key)
+}
+
+// GetMany retrieves the keys that exist in one round trip, by key
+func (c *Cache) GetMany(ctx context.Context, keys ...string) (map[string]string, error) {
+	return c.store.GetMany(ctx, keys...)
+}
+
+// Delete removes a key
+func (c *Cache) Delete(ctx context.Context, keys ...string) error {
+	return c.store.Delete(ctx, keys...)
+}
+
+// Exists checks if a key exists
+func (c *Cache) Exists(ctx context.Context, key string) (bool, error) {
+	return c.store.Exists(ctx, key)
+}
+
+// SetNX sets a key only if it doesn't exist (for distributed locks)
+func (c *Cache) SetNX(ctx context.Context, key, value string, ttl time.Duration) (bool, error) {
+	return c.store.SetNX(ctx, key, value, ttl)
+}
+
+// Expire sets a TTL on an existing key
+func (c *Cache) Expire(ctx context.Context, key string, ttl time.Duration) error {
+	return c.store.Expire(ctx, key, ttl)
+}
+
+// Increment increments a counter (for rate limiting)
+func (c *Cache) Increment(ctx context.Context, key string) (int64, error) {
+	return c.store.IncrementBy(ctx, key, 1)
+}
+
+// IncrementBy increments a counter by a specific amount
+func (c *Cache) IncrementBy(ctx context.Context, key string, value int64) (int64, error) {
+	return c.store.IncrementBy(ctx, key, value)
+}
+
+// SetPasswordResetToken stores a password reset token
+func (c *Cache) SetPasswordResetToken(ctx context.Context, token, userID string, ttl time.Duration) error {
+	key := fmt.Sprintf("password_reset:%s", token)
+	return c.Set(ctx, key, userID, ttl)
+}
+
+// GetPasswordResetToken retrieves user ID from password reset token
+func (c *Cache) GetPasswordResetToken(ctx context.Context, token string) (string, error) {
+	key := fmt.Sprintf("password_reset:%s", token)
+	return c.Get(ctx, key)
+}
+
+// DeletePasswordResetToken removes a password reset token
+func (c *Cache) DeletePasswordResetToken(ctx context.Context, token string) error {
+	key := fmt.Sprintf("password_reset:%s", token)
+	return c.Delete(ctx, key)
+}
+
+// SetEmailVerificationToken stores an email verification token
+func (c *Cache) SetEmailVerificationToken(ctx context.Context, token, userID string, ttl time.Duration) error {
+	key := fmt.Sprintf("email_verify:%s", token)
+	return c.Set(ctx, key, userID, ttl)
+}
+
+// GetEmailVerificationToken retrieves user ID from an email verification token
+func (c *Cache) GetEmailVerificationToken(ctx context.Context, token string) (string, error) {
+	key := fmt.Sprintf("email_verify:%s", token)
+	return c.Get(ctx, key)
+}
+
+// DeleteEmailVerificationToken removes an email verification token
+func (c *Cache) DeleteEmailVerificationToken(ctx context.Context, token string) error {
+	key := fmt.Sprintf("email_verify:%s", token)
+	return c.Delete(ctx, key)
+}
+
+// SetRecoveryEmailToken stores a recovery email verification token
+func (c *Cache) SetRecoveryEmailToken(ctx context.Context, token, userID, recoveryEmail string, ttl time.Duration) error {
+	key := fmt.Sprintf("recovery_email_verify:%s", token)
+	return c.Set(ctx, key, userID+"|"+recoveryEmail, ttl)
+}
+
+// GetRecoveryEmailToken retrieves the user ID and address a recovery email
+// verification token was issued for
+func (c *Cache) GetRecoveryEmailToken(ctx context.Context, token string) (string, string, error) {
+	key := fmt.Sprintf("recovery_email_verify:%s", token)
+	val, err := c.Get(ctx, key)
+	if err != nil {
+		return "", "", err
+	}
+	userID, recoveryEmail, ok := strings.Cut(val, "|")
+	if !ok {
+		return "", "", fmt.Errorf("malformed recovery email token: %s", token)
+	}
+	return userID, recoveryEmail, nil
+}
+
+// DeleteRecoveryEmailToken removes a recovery email verification token
+func (c *Cache) DeleteRecoveryEmailToken(ctx context.Context, token string) error {
+	key := fmt.Sprintf("recovery_email_verify:%s", token)
+	return c.Delete(ctx, key)
+}
+
+// PublishSecurityEvent broadcasts an encoded security event to the user's
+// subscribers on every server instance
+func (c *Cache) PublishSecurityEvent(ctx context.Context, userID string, event []byte) error {
+	channel := fmt.Sprintf("security_events:%s", userID)
+	return c.store.Publish(ctx, channel, event)
+}
+
+// SubscribeSecurityEvents subscribes to the user's security events. Events
+// published after it returns are delivered; the caller must Close the
+// returned subscription.
+func (c *Cache) SubscribeSecurityEvents(ctx context.Context, userID string) (Subscription, error) {
+	channel := fmt.Sprintf("security_events:%s", userID)
+	return c.store.Subscribe(ctx, channel)
+}
+
+// AddToStream appends an entry to a stream, trimming the stream to about
+// maxLen entries, and returns the entry ID. Only Redis has streams.
+func (c *Cache) AddToStream(ctx context.Context, stream string, maxLen int64, values map[string]interface{}) (string, error) {
+	return c.store.AddToStream(ctx, stream, maxLen, values)
+}
+
+// Dynamic IP denylist: a set of CIDRs with their reasons as values, and a
+// channel announcing changes
+const (
+	ipDenylistKey     = "ip_denylist"
+	ipDenylistChannel = "ip_denylist:changed"
+)
+
+// DeniedIP is an entry of the dynamic IP denylist
+type DeniedIP struct {
+	CIDR   string
+	Reason string
+	// ExpiresAt is zero for permanent entries
+	ExpiresAt time.Time
+}
+
+// DenyIP adds or replaces a dynamic denylist entry; a zero ttl never expires.
+// Subscribers are notified so every instance picks up the change.
+func (c *Cache) DenyIP(ctx context.Context, cidr, reason string, ttl time.Duration) error {
+	member := Member{Name: cidr, Value: reason}
+	if ttl > 0 {
+		member.ExpiresAt = time.Now().Add(ttl)
+	}
+
+	if err := c.store.AddMember(ctx, ipDenylistKey, member); err != nil {
+		return err
+	}
+	return c.store.Publish(ctx, ipDenylistChannel, []byte(cidr))
+}
+
+// RemoveDeniedIP deletes a dynamic denylist entry and reports whether it existed
+func (c *Cache) RemoveDeniedIP(ctx context.Context, cidr string) (bool, error) {
+	removed, err := c.store.RemoveMember(ctx, ipDenylistKey, cidr)
+	if err != nil {
+		return false, err
+	}
+	return removed, c.store.Publish(ctx, ipDenylistChannel, []byte(cidr))
+}
+
+// DeniedIPs returns the unexpired dynamic denylist entries, pruning expired ones
+func (c *Cache) DeniedIPs(ctx context.Context) ([]DeniedIP, error) {
+	members, err := c.store.Members(ctx, ipDenylistKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(members) == 0 {
+		return nil, nil
+	}
+
+	entries := make([]DeniedIP, len(members))
+	for i, m := range members {
+		entries[i] = DeniedIP{CIDR: m.Name, Reason: m.Value, ExpiresAt: m.ExpiresAt}
+	}
+	return entries, nil
+}
+
+// SubscribeIPDenylist subscribes to dynamic denylist changes. The caller must
+// Close the returned subscription.
+func (c *Cache) SubscribeIPDenylist(ctx context.Context) (Subscription, error) {
+	return c.store.Subscribe(ctx, ipDenylistChannel)
+}
+
+// Stats returns the store's connection pool statistics
+func (c *Cache) Stats() PoolStats {
+	return c.store.Stats()
+}
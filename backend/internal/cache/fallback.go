@@ -0,0 +1,85 @@
+package cache
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Fallback policies for non-critical data when Redis can't be reached
+const (
+	// FallbackLocal keeps limiting in process, so rate limits and login
+	// attempt limits still apply per instance, unless their cache outage
+	// mode is config.FailClosed
+	FallbackLocal = "local"
+	// FallbackNone returns the Redis error, and callers skip the check
+	FallbackNone = "none"
+)
+
+var fallbackTotal = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "redis_fallback_total",
+		Help: "Total number of operations served by the in-process fallback because Redis failed.",
+	},
+	[]string{"operation"},
+)
+
+// localLimiter is a bounded in-process table of rate limit buckets. It
+// stands in for Redis for rate limits and login attempts while Redis is
+// unreachable. Buckets are per instance, and are not merged back into Redis
+// when it recovers.
+type localLimiter struct {
+	maxEntries int
+
+	mu sync.Mutex
+	// tats holds each bucket's theoretical arrival time (see gcra): when it
+	// will next be empty, after which the entry can be dropped
+	tats map[string]time.Time
+}
+
+func newLocalLimiter(maxEntries int) *localLimiter {
+	return &localLimiter{maxEntries: maxEntries, tats: make(map[string]time.Time)}
+}
+
+// allow counts one request under key against limit
+func (l *localLimiter) allow(key string, limit Limit) RateLimitResult {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	tat, ok := l.tats[key]
+	if !ok && len(l.tats) >= l.maxEntries {
+		l.evict(now)
+	}
+	tat, result := gcra(tat, now, limit)
+	if result.Allowed {
+		l.tats[key] = tat
+	}
+	return result
+}
+
+func (l *localLimiter) delete(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.tats, key)
+}
+
+// evict makes room for one bucket, dropping empty buckets or, failing that,
+// an arbitrary one
+func (l *localLimiter) evict(now time.Time) {
+	for key, tat := range l.tats {
+		if !tat.After(now) {
+			delete(l.tats, key)
+		}
+	}
+
+	if len(l.tats) < l.maxEntries {
+		return
+	}
+	for key := range l.tats {
+		delete(l.tats, key)
+		return
+	}
+}
@@ -0,0 +1,39 @@
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// idempotencyPending is stored under an idempotency key while its first
+// request runs
+const idempotencyPending = ""
+
+func idempotencyKey(key string) string {
+	return "idempotency:" + key
+}
+
+// ReserveIdempotencyKey claims key for a request for ttl, which should cover
+// the request. If another request already claimed it, reserved is false and
+// result is what that request stored with StoreIdempotentResult, or empty if
+// it is still running. Claiming and reading are one atomic step, so a claim
+// expiring or being released in between can't be mistaken for either.
+func (c *Cache) ReserveIdempotencyKey(ctx context.Context, key string, ttl time.Duration) (result []byte, reserved bool, err error) {
+	value, reserved, err := c.store.SetNXOrGet(ctx, idempotencyKey(key), idempotencyPending, ttl)
+	if err != nil || reserved {
+		return nil, reserved, err
+	}
+	return []byte(value), false, nil
+}
+
+// StoreIdempotentResult records the result of the request holding key, for
+// later requests with the same key, and keeps it for ttl
+func (c *Cache) StoreIdempotentResult(ctx context.Context, key string, result []byte, ttl time.Duration) error {
+	return c.Set(ctx, idempotencyKey(key), string(result), ttl)
+}
+
+// ReleaseIdempotencyKey frees key without a result, such as when its
+// request failed, so a retry runs again
+func (c *Cache) ReleaseIdempotencyKey(ctx context.Context, key string) error {
+	return c.Delete(ctx, idempotencyKey(key))
+}
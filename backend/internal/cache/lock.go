@@ -0,0 +1,69 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrLockHeld is returned by Lock when another holder has the lock
+	ErrLockHeld = errors.New("lock is held")
+	// ErrLockExpired is returned by Unlock and Extend when the lock expired
+	// before the call, and so may now be held by another holder
+	ErrLockExpired = errors.New("lock expired")
+)
+
+// Lock is a lock held on a key, across every server instance sharing the
+// store. It expires after its TTL even if never unlocked, so a crashed
+// holder can't keep it forever.
+type Lock struct {
+	store Store
+	key   string
+	// token identifies this holder, so it never unlocks or extends a lock
+	// taken by another after its own expired
+	token string
+}
+
+// Lock takes the lock on key for ttl, or returns ErrLockHeld at once if
+// another holder has it. Work that must not run twice either finishes
+// within ttl or calls Extend. A lock left to expire, rather than unlocked,
+// limits the work to once per ttl.
+func (c *Cache) Lock(ctx context.Context, key string, ttl time.Duration) (*Lock, error) {
+	lock := &Lock{store: c.store, key: "lock:" + key, token: uuid.NewString()}
+
+	acquired, err := c.store.SetNX(ctx, lock.key, lock.token, ttl)
+	if err != nil {
+		return nil, err
+	}
+	if !acquired {
+		return nil, ErrLockHeld
+	}
+	return lock, nil
+}
+
+// Unlock releases the lock
+func (l *Lock) Unlock(ctx context.Context) error {
+	released, err := l.store.DeleteIfEqual(ctx, l.key, l.token)
+	if err != nil {
+		return err
+	}
+	if !released {
+		return ErrLockExpired
+	}
+	return nil
+}
+
+// Extend resets the lock's TTL to ttl from now
+func (l *Lock) Extend(ctx context.Context, ttl time.Duration) error {
+	extended, err := l.store.ExpireIfEqual(ctx, l.key, l.token, ttl)
+	if err != nil {
+		return err
+	}
+	if !extended {
+		return ErrLockExpired
+	}
+	return nil
+}
@@ -0,0 +1,416 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// memorySweepInterval is how often writes drop expired keys, so keys that
+// are never read again don't stay in memory
+const memorySweepInterval = time.Minute
+
+// memorySubscriberBuffer is the number of messages a slow subscriber can
+// fall behind before further messages to it are dropped
+const memorySubscriberBuffer = 64
+
+// MemoryStore is a Store in process memory. Nothing is shared with other
+// server instances, so it suits tests and single-instance deployments
+// only. It has no streams.
+type MemoryStore struct {
+	mu        sync.Mutex
+	values    map[string]memoryValue
+	sets      map[string]map[string]Member
+	lastSweep time.Time
+
+	subsMu sync.RWMutex
+	subs   map[string]map[*memorySubscription]struct{}
+}
+
+type memoryValue struct {
+	value string
+	// expires is zero for keys without a TTL
+	expires time.Time
+}
+
+func (v memoryValue) expired(now time.Time) bool {
+	return !v.expires.IsZero() && !now.Before(v.expires)
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		values: make(map[string]memoryValue),
+		sets:   make(map[string]map[string]Member),
+		subs:   make(map[string]map[*memorySubscription]struct{}),
+	}
+}
+
+func expiry(now time.Time, ttl time.Duration) time.Time {
+	if ttl <= 0 {
+		return time.Time{}
+	}
+	return now.Add(ttl)
+}
+
+// lookup returns the unexpired value at key; the caller holds s.mu
+func (s *MemoryStore) lookup(key string, now time.Time) (memoryValue, bool) {
+	v, ok := s.values[key]
+	if !ok {
+		return memoryValue{}, false
+	}
+	if v.expired(now) {
+		delete(s.values, key)
+		return memoryValue{}, false
+	}
+	return v, true
+}
+
+// store writes the value at key, sweeping expired keys at most once per
+// memorySweepInterval; the caller holds s.mu
+func (s *MemoryStore) store(key string, v memoryValue, now time.Time) {
+	if now.Sub(s.lastSweep) >= memorySweepInterval {
+		for k, old := range s.values {
+			if old.expired(now) {
+				delete(s.values, k)
+			}
+		}
+		s.lastSweep = now
+	}
+	s.values[key] = v
+}
+
+// Get returns the value at key, or ErrNotFound
+func (s *MemoryStore) Get(ctx context.Context, key string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, ok := s.lookup(key, time.Now())
+	if !ok {
+		return "", ErrNotFound
+	}
+	return v.value, nil
+}
+
+// GetMany returns the values of the keys that exist
+func (s *MemoryStore) GetMany(ctx context.Context, keys ...string) (map[string]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	values := make(map[string]string, len(keys))
+	for _, key := range keys {
+		if v, ok := s.lookup(key, now); ok {
+			values[key] = v.value
+		}
+	}
+	return values, nil
+}
+
+// Set stores a key-value pair with TTL
+func (s *MemoryStore) Set(ctx context.Context, key, value string, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.store(key, memoryValue{value: value, expires: expiry(now, ttl)}, now)
+	return nil
+}
+
+// SetNX sets a key only if it doesn't exist
+func (s *MemoryStore) SetNX(ctx context.Context, key, value string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if _, ok := s.lookup(key, now); ok {
+		return false, nil
+	}
+	s.store(key, memoryValue{value: value, expires: expiry(now, ttl)}, now)
+	return true, nil
+}
+
+// SetNXOrGet sets a key if it doesn't exist, or returns its value
+func (s *MemoryStore) SetNXOrGet(ctx context.Context, key, value string, ttl time.Duration) (string, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if v, ok := s.lookup(key, now); ok {
+		return v.value, false, nil
+	}
+	s.store(key, memoryValue{value: value, expires: expiry(now, ttl)}, now)
+	return "", true, nil
+}
+
+// Delete removes keys
+func (s *MemoryStore) Delete(ctx context.Context, keys ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, key := range keys {
+		delete(s.values, key)
+		delete(s.sets, key)
+	}
+	return nil
+}
+
+// Exists checks if a key exists
+func (s *MemoryStore) Exists(ctx context.Context, key string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, ok := s.lookup(key, time.Now())
+	return ok, nil
+}
+
+// Expire sets a TTL on an existing key
+func (s *MemoryStore) Expire(ctx context.Context, key string, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	v, ok := s.lookup(key, now)
+	if !ok {
+		return nil
+	}
+	v.expires = expiry(now, ttl)
+	if v.expired(now) {
+		delete(s.values, key)
+		return nil
+	}
+	s.values[key] = v
+	return nil
+}
+
+// DeleteIfEqual deletes key if its value is value
+func (s *MemoryStore) DeleteIfEqual(ctx context.Context, key, value string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, ok := s.lookup(key, time.Now())
+	if !ok || v.value != value {
+		return false, nil
+	}
+	delete(s.values, key)
+	return true, nil
+}
+
+// ExpireIfEqual sets the TTL of key if its value is value
+func (s *MemoryStore) ExpireIfEqual(ctx context.Context, key, value string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	v, ok := s.lookup(key, now)
+	if !ok || v.value != value {
+		return false, nil
+	}
+	v.expires = expiry(now, ttl)
+	s.values[key] = v
+	return true, nil
+}
+
+// IncrementBy increments a counter by n
+func (s *MemoryStore) IncrementBy(ctx context.Context, key string, n int64) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	v, _ := s.lookup(key, now)
+	var count int64
+	if v.value != "" {
+		var err error
+		count, err = strconv.ParseInt(v.value, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("value is not an integer")
+		}
+	}
+	count += n
+	v.value = strconv.FormatInt(count, 10)
+	s.store(key, v, now)
+	return count, nil
+}
+
+// Allow counts one request under key against limit. The bucket's tat (see
+// gcra) is kept at key in unix microseconds, as in RedisStore.
+func (s *MemoryStore) Allow(ctx context.Context, key string, limit Limit) (RateLimitResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	var tat time.Time
+	if v, ok := s.lookup(key, now); ok {
+		micros, err := strconv.ParseInt(v.value, 10, 64)
+		if err != nil {
+			return RateLimitResult{}, fmt.Errorf("value is not an integer")
+		}
+		tat = time.UnixMicro(micros)
+	}
+
+	tat, result := gcra(tat, now, limit)
+	if result.Allowed {
+		s.store(key, memoryValue{value: strconv.FormatInt(tat.UnixMicro(), 10), expires: tat}, now)
+	}
+	return result, nil
+}
+
+// AddMember adds or replaces a set member
+func (s *MemoryStore) AddMember(ctx context.Context, key string, member Member) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	set, ok := s.sets[key]
+	if !ok {
+		set = make(map[string]Member)
+		s.sets[key] = set
+	}
+	set[member.Name] = member
+	return nil
+}
+
+// RemoveMember deletes a set member and reports whether it existed
+func (s *MemoryStore) RemoveMember(ctx context.Context, key, name string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	set := s.sets[key]
+	if _, ok := set[name]; !ok {
+		return false, nil
+	}
+	delete(set, name)
+	if len(set) == 0 {
+		delete(s.sets, key)
+	}
+	return true, nil
+}
+
+// Members returns the unexpired set members by expiry, pruning expired ones
+func (s *MemoryStore) Members(ctx context.Context, key string) ([]Member, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	set := s.sets[key]
+	var members []Member
+	for name, m := range set {
+		if !m.ExpiresAt.IsZero() && m.ExpiresAt.Before(now) {
+			delete(set, name)
+			continue
+		}
+		members = append(members, m)
+	}
+	if len(set) == 0 {
+		delete(s.sets, key)
+	}
+
+	// Soonest to expire first, then by name, as RedisStore orders them
+	sort.Slice(members, func(i, j int) bool {
+		a, b := members[i], members[j]
+		if a.ExpiresAt.IsZero() != b.ExpiresAt.IsZero() {
+			return b.ExpiresAt.IsZero()
+		}
+		if !a.ExpiresAt.Equal(b.ExpiresAt) {
+			return a.ExpiresAt.Before(b.ExpiresAt)
+		}
+		return a.Name < b.Name
+	})
+	return members, nil
+}
+
+// Publish delivers message to the channel's subscribers in this process.
+// Subscribers whose buffer is full miss the message.
+func (s *MemoryStore) Publish(ctx context.Context, channel string, message []byte) error {
+	s.subsMu.RLock()
+	defer s.subsMu.RUnlock()
+
+	for sub := range s.subs[channel] {
+		select {
+		case sub.messages <- message:
+		default:
+		}
+	}
+	return nil
+}
+
+// Subscribe subscribes to a channel in this process
+func (s *MemoryStore) Subscribe(ctx context.Context, channel string) (Subscription, error) {
+	sub := &memorySubscription{store: s, channel: channel, messages: make(chan []byte, memorySubscriberBuffer)}
+
+	s.subsMu.Lock()
+	defer s.subsMu.Unlock()
+
+	subs, ok := s.subs[channel]
+	if !ok {
+		subs = make(map[*memorySubscription]struct{})
+		s.subs[channel] = subs
+	}
+	subs[sub] = struct{}{}
+	return sub, nil
+}
+
+type memorySubscription struct {
+	store     *MemoryStore
+	channel   string
+	messages  chan []byte
+	closeOnce sync.Once
+}
+
+func (s *memorySubscription) Messages() <-chan []byte {
+	return s.messages
+}
+
+func (s *memorySubscription) Close() error {
+	s.closeOnce.Do(func() {
+		s.store.subsMu.Lock()
+		defer s.store.subsMu.Unlock()
+
+		subs := s.store.subs[s.channel]
+		delete(subs, s)
+		if len(subs) == 0 {
+			delete(s.store.subs, s.channel)
+		}
+		close(s.messages)
+	})
+	return nil
+}
+
+// AddToStream is unsupported: MemoryStore has no streams
+func (s *MemoryStore) AddToStream(ctx context.Context, stream string, maxLen int64, values map[string]interface{}) (string, error) {
+	return "", fmt.Errorf("memory store has no streams: %w", errors.ErrUnsupported)
+}
+
+// Ping always succeeds
+func (s *MemoryStore) Ping(ctx context.Context) error {
+	return nil
+}
+
+// Stats is zero: MemoryStore has no connections
+func (s *MemoryStore) Stats() PoolStats {
+	return PoolStats{}
+}
+
+// Close drops every key and closes every subscription
+func (s *MemoryStore) Close() error {
+	s.subsMu.Lock()
+	var subs []*memorySubscription
+	for _, channelSubs := range s.subs {
+		for sub := range channelSubs {
+			subs = append(subs, sub)
+		}
+	}
+	s.subsMu.Unlock()
+	for _, sub := range subs {
+		_ = sub.Close()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values = make(map[string]memoryValue)
+	s.sets = make(map[string]map[string]Member)
+	return nil
+}
@@ -0,0 +1,225 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	operationDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "cache_operation_duration_seconds",
+			Help:    "Histogram of cache operation latency (seconds).",
+			Buckets: []float64{0.0001, 0.00025, 0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 1},
+		},
+		[]string{"operation", "family"},
+	)
+
+	operationsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cache_operations_total",
+			Help: "Total number of cache operations, by result: hit or miss for reads, ok otherwise, or error.",
+		},
+		[]string{"operation", "family", "result"},
+	)
+)
+
+// Operation results
+const (
+	resultHit   = "hit"
+	resultMiss  = "miss"
+	resultOK    = "ok"
+	resultError = "error"
+)
+
+// keyFamily names the kind of key, the part before the first colon, such as
+// "session" for "session:<id>". Keys without one are "other", so IDs never
+// become label values.
+func keyFamily(key string) string {
+	family, _, found := strings.Cut(key, ":")
+	if !found || family == "" {
+		return "other"
+	}
+	return family
+}
+
+// meteredStore records the latency and result of every Store operation by
+// key family. It wraps any prefixedStore, so families don't include the
+// prefix.
+type meteredStore struct {
+	Store
+}
+
+// observe records an operation on key that started at start
+func observe(operation, key string, start time.Time, result string) {
+	family := keyFamily(key)
+	operationDuration.WithLabelValues(operation, family).Observe(time.Since(start).Seconds())
+	operationsTotal.WithLabelValues(operation, family, result).Inc()
+}
+
+// result is resultOK, or resultError if err is set
+func result(err error) string {
+	if err != nil {
+		return resultError
+	}
+	return resultOK
+}
+
+// lookupResult is the result of a read that found, or didn't find, its key
+func lookupResult(found bool, err error) string {
+	switch {
+	case err != nil:
+		return resultError
+	case found:
+		return resultHit
+	default:
+		return resultMiss
+	}
+}
+
+func (s *meteredStore) Get(ctx context.Context, key string) (string, error) {
+	start := time.Now()
+	val, err := s.Store.Get(ctx, key)
+	if errors.Is(err, ErrNotFound) {
+		observe("get", key, start, resultMiss)
+	} else {
+		observe("get", key, start, lookupResult(err == nil, err))
+	}
+	return val, err
+}
+
+// GetMany counts a hit or miss for every key, and the latency once, under
+// the first key's family
+func (s *meteredStore) GetMany(ctx context.Context, keys ...string) (map[string]string, error) {
+	start := time.Now()
+	values, err := s.Store.GetMany(ctx, keys...)
+	if len(keys) == 0 {
+		return values, err
+	}
+
+	operationDuration.WithLabelValues("get_many", keyFamily(keys[0])).Observe(time.Since(start).Seconds())
+	for _, key := range keys {
+		_, found := values[key]
+		operationsTotal.WithLabelValues("get_many", keyFamily(key), lookupResult(found, err)).Inc()
+	}
+	return values, err
+}
+
+func (s *meteredStore) Set(ctx context.Context, key, value string, ttl time.Duration) error {
+	start := time.Now()
+	err := s.Store.Set(ctx, key, value, ttl)
+	observe("set", key, start, result(err))
+	return err
+}
+
+func (s *meteredStore) SetNX(ctx context.Context, key, value string, ttl time.Duration) (bool, error) {
+	start := time.Now()
+	set, err := s.Store.SetNX(ctx, key, value, ttl)
+	observe("set_nx", key, start, result(err))
+	return set, err
+}
+
+func (s *meteredStore) SetNXOrGet(ctx context.Context, key, value string, ttl time.Duration) (string, bool, error) {
+	start := time.Now()
+	existing, set, err := s.Store.SetNXOrGet(ctx, key, value, ttl)
+	observe("set_nx_or_get", key, start, result(err))
+	return existing, set, err
+}
+
+func (s *meteredStore) Delete(ctx context.Context, keys ...string) error {
+	start := time.Now()
+	err := s.Store.Delete(ctx, keys...)
+	if len(keys) > 0 {
+		observe("delete", keys[0], start, result(err))
+	}
+	return err
+}
+
+func (s *meteredStore) Exists(ctx context.Context, key string) (bool, error) {
+	start := time.Now()
+	exists, err := s.Store.Exists(ctx, key)
+	observe("exists", key, start, lookupResult(exists, err))
+	return exists, err
+}
+
+func (s *meteredStore) Expire(ctx context.Context, key string, ttl time.Duration) error {
+	start := time.Now()
+	err := s.Store.Expire(ctx, key, ttl)
+	observe("expire", key, start, result(err))
+	return err
+}
+
+func (s *meteredStore) DeleteIfEqual(ctx context.Context, key, value string) (bool, error) {
+	start := time.Now()
+	deleted, err := s.Store.DeleteIfEqual(ctx, key, value)
+	observe("delete_if_equal", key, start, result(err))
+	return deleted, err
+}
+
+func (s *meteredStore) ExpireIfEqual(ctx context.Context, key, value string, ttl time.Duration) (bool, error) {
+	start := time.Now()
+	extended, err := s.Store.ExpireIfEqual(ctx, key, value, ttl)
+	observe("expire_if_equal", key, start, result(err))
+	return extended, err
+}
+
+func (s *meteredStore) IncrementBy(ctx context.Context, key string, n int64) (int64, error) {
+	start := time.Now()
+	count, err := s.Store.IncrementBy(ctx, key, n)
+	observe("increment", key, start, result(err))
+	return count, err
+}
+
+func (s *meteredStore) Allow(ctx context.Context, key string, limit Limit) (RateLimitResult, error) {
+	start := time.Now()
+	res, err := s.Store.Allow(ctx, key, limit)
+	observe("allow", key, start, result(err))
+	return res, err
+}
+
+func (s *meteredStore) AddMember(ctx context.Context, key string, member Member) error {
+	start := time.Now()
+	err := s.Store.AddMember(ctx, key, member)
+	observe("add_member", key, start, result(err))
+	return err
+}
+
+func (s *meteredStore) RemoveMember(ctx context.Context, key, name string) (bool, error) {
+	start := time.Now()
+	removed, err := s.Store.RemoveMember(ctx, key, name)
+	observe("remove_member", key, start, result(err))
+	return removed, err
+}
+
+func (s *meteredStore) Members(ctx context.Context, key string) ([]Member, error) {
+	start := time.Now()
+	members, err := s.Store.Members(ctx, key)
+	observe("members", key, start, result(err))
+	return members, err
+}
+
+func (s *meteredStore) Publish(ctx context.Context, channel string, message []byte) error {
+	start := time.Now()
+	err := s.Store.Publish(ctx, channel, message)
+	observe("publish", channel, start, result(err))
+	return err
+}
+
+func (s *meteredStore) Subscribe(ctx context.Context, channel string) (Subscription, error) {
+	start := time.Now()
+	sub, err := s.Store.Subscribe(ctx, channel)
+	observe("subscribe", channel, start, result(err))
+	return sub, err
+}
+
+func (s *meteredStore) AddToStream(ctx context.Context, stream string, maxLen int64, values map[string]interface{}) (string, error) {
+	start := time.Now()
+	id, err := s.Store.AddToStream(ctx, stream, maxLen, values)
+	observe("add_to_stream", stream, start, result(err))
+	return id, err
+}
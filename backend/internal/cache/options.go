@@ -0,0 +1,112 @@
+package cache
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/config"
+)
+
+const (
+	dialTimeout  = 5 * time.Second
+	readTimeout  = 3 * time.Second
+	writeTimeout = 3 * time.Second
+)
+
+// newClient creates the Redis client described by cfg
+func newClient(cfg config.RedisConfig) (*redis.Client, error) {
+	tlsConfig, err := newTLSConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(cfg.SentinelAddrs) > 0 {
+		return redis.NewFailoverClient(&redis.FailoverOptions{
+			MasterName:       cfg.SentinelMaster,
+			SentinelAddrs:    cfg.SentinelAddrs,
+			SentinelPassword: cfg.SentinelPassword,
+			Username:         cfg.Username,
+			Password:         cfg.Password,
+			DB:               cfg.DB,
+			MaxRetries:       cfg.MaxRetries,
+			PoolSize:         cfg.PoolSize,
+			DialTimeout:      dialTimeout,
+			ReadTimeout:      readTimeout,
+			WriteTimeout:     writeTimeout,
+			TLSConfig:        tlsConfig,
+		}), nil
+	}
+
+	opts := &redis.Options{
+		Addr:     net.JoinHostPort(cfg.Host, cfg.Port),
+		Username: cfg.Username,
+		Password: cfg.Password,
+		DB:       cfg.DB,
+	}
+	if cfg.URL != "" {
+		// The URL holds the password, so it is left out of the error
+		opts, err = redis.ParseURL(cfg.URL)
+		if err != nil {
+			return nil, errors.New("invalid REDIS_URL: expected redis://[user:password@]host:port[/db] or rediss://")
+		}
+		if opts.Username == "" {
+			opts.Username = cfg.Username
+		}
+		if opts.Password == "" {
+			opts.Password = cfg.Password
+		}
+	}
+
+	opts.MaxRetries = cfg.MaxRetries
+	opts.PoolSize = cfg.PoolSize
+	opts.DialTimeout = dialTimeout
+	opts.ReadTimeout = readTimeout
+	opts.WriteTimeout = writeTimeout
+	if tlsConfig != nil {
+		opts.TLSConfig = tlsConfig
+	}
+	return redis.NewClient(opts), nil
+}
+
+// newTLSConfig returns the TLS settings for Redis connections, or nil to
+// connect in plain text. The server name defaults to the host dialed.
+func newTLSConfig(cfg config.RedisConfig) (*tls.Config, error) {
+	if !cfg.TLSEnabled && !strings.HasPrefix(cfg.URL, "rediss://") {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: cfg.TLSServerName,
+	}
+
+	if cfg.TLSCAFile != "" {
+		pem, err := os.ReadFile(cfg.TLSCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read REDIS_TLS_CA_FILE: %w", err)
+		}
+		roots := x509.NewCertPool()
+		if !roots.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("REDIS_TLS_CA_FILE %s contains no PEM certificates", cfg.TLSCAFile)
+		}
+		tlsConfig.RootCAs = roots
+	}
+
+	if cfg.TLSCertFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.TLSCertFile, cfg.TLSKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load Redis client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
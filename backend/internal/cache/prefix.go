@@ -0,0 +1,110 @@
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// prefixedStore puts every key, channel and stream of a Store under a
+// prefix, so several environments or services can share one Redis (see
+// CACHE_KEY_PREFIX). Set member names aren't keys and are left alone.
+type prefixedStore struct {
+	Store
+	prefix string
+}
+
+func (s *prefixedStore) key(key string) string {
+	return s.prefix + key
+}
+
+func (s *prefixedStore) keys(keys []string) []string {
+	prefixed := make([]string, len(keys))
+	for i, key := range keys {
+		prefixed[i] = s.key(key)
+	}
+	return prefixed
+}
+
+func (s *prefixedStore) Get(ctx context.Context, key string) (string, error) {
+	return s.Store.Get(ctx, s.key(key))
+}
+
+// GetMany returns the values by unprefixed key
+func (s *prefixedStore) GetMany(ctx context.Context, keys ...string) (map[string]string, error) {
+	prefixed, err := s.Store.GetMany(ctx, s.keys(keys)...)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]string, len(prefixed))
+	for _, key := range keys {
+		if val, ok := prefixed[s.key(key)]; ok {
+			values[key] = val
+		}
+	}
+	return values, nil
+}
+
+func (s *prefixedStore) Set(ctx context.Context, key, value string, ttl time.Duration) error {
+	return s.Store.Set(ctx, s.key(key), value, ttl)
+}
+
+func (s *prefixedStore) SetNX(ctx context.Context, key, value string, ttl time.Duration) (bool, error) {
+	return s.Store.SetNX(ctx, s.key(key), value, ttl)
+}
+
+func (s *prefixedStore) SetNXOrGet(ctx context.Context, key, value string, ttl time.Duration) (string, bool, error) {
+	return s.Store.SetNXOrGet(ctx, s.key(key), value, ttl)
+}
+
+func (s *prefixedStore) Delete(ctx context.Context, keys ...string) error {
+	return s.Store.Delete(ctx, s.keys(keys)...)
+}
+
+func (s *prefixedStore) Exists(ctx context.Context, key string) (bool, error) {
+	return s.Store.Exists(ctx, s.key(key))
+}
+
+func (s *prefixedStore) Expire(ctx context.Context, key string, ttl time.Duration) error {
+	return s.Store.Expire(ctx, s.key(key), ttl)
+}
+
+func (s *prefixedStore) DeleteIfEqual(ctx context.Context, key, value string) (bool, error) {
+	return s.Store.DeleteIfEqual(ctx, s.key(key), value)
+}
+
+func (s *prefixedStore) ExpireIfEqual(ctx context.Context, key, value string, ttl time.Duration) (bool, error) {
+	return s.Store.ExpireIfEqual(ctx, s.key(key), value, ttl)
+}
+
+func (s *prefixedStore) IncrementBy(ctx context.Context, key string, n int64) (int64, error) {
+	return s.Store.IncrementBy(ctx, s.key(key), n)
+}
+
+func (s *prefixedStore) Allow(ctx context.Context, key string, limit Limit) (RateLimitResult, error) {
+	return s.Store.Allow(ctx, s.key(key), limit)
+}
+
+func (s *prefixedStore) AddMember(ctx context.Context, key string, member Member) error {
+	return s.Store.AddMember(ctx, s.key(key), member)
+}
+
+func (s *prefixedStore) RemoveMember(ctx context.Context, key, name string) (bool, error) {
+	return s.Store.RemoveMember(ctx, s.key(key), name)
+}
+
+func (s *prefixedStore) Members(ctx context.Context, key string) ([]Member, error) {
+	return s.Store.Members(ctx, s.key(key))
+}
+
+func (s *prefixedStore) Publish(ctx context.Context, channel string, message []byte) error {
+	return s.Store.Publish(ctx, s.key(channel), message)
+}
+
+func (s *prefixedStore) Subscribe(ctx context.Context, channel string) (Subscription, error) {
+	return s.Store.Subscribe(ctx, s.key(channel))
+}
+
+func (s *prefixedStore) AddToStream(ctx context.Context, stream string, maxLen int64, values map[string]interface{}) (string, error) {
+	return s.Store.AddToStream(ctx, s.key(stream), maxLen, values)
+}
@@ -0,0 +1,131 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/config"
+)
+
+// Limit allows Burst requests at once, then Rate requests per Period, spread
+// evenly. It is enforced with the generic cell rate algorithm (GCRA): each
+// key stores the time its bucket will next be empty, so a limit never resets
+// all at once at a window boundary.
+type Limit struct {
+	Rate   int
+	Period time.Duration
+	Burst  int
+}
+
+// PerPeriod allows n requests per period, all of them at once if need be
+func PerPeriod(n int, period time.Duration) Limit {
+	return Limit{Rate: n, Period: period, Burst: n}
+}
+
+// emissionInterval is the time one request takes to drain from the bucket
+func (l Limit) emissionInterval() time.Duration {
+	return l.Period / time.Duration(l.Rate)
+}
+
+// tolerance is the time a full bucket takes to drain
+func (l Limit) tolerance() time.Duration {
+	return l.emissionInterval() * time.Duration(l.Burst)
+}
+
+func (l Limit) valid() bool {
+	return l.Rate > 0 && l.Burst > 0 && l.Period >= time.Duration(l.Rate)
+}
+
+// RateLimitResult is the outcome of one request against a Limit
+type RateLimitResult struct {
+	Allowed bool
+	// Remaining is how many more requests would be allowed right now
+	Remaining int
+	// RetryAfter is how long until a denied request would be allowed; zero
+	// when allowed
+	RetryAfter time.Duration
+	// ResetAfter is how long until the bucket is empty again
+	ResetAfter time.Duration
+}
+
+// gcra applies one request to a bucket that is next empty at tat, returning
+// the new tat, unchanged if the request is denied. Store implementations
+// without server-side scripts use it; RedisStore's script does the same.
+func gcra(tat, now time.Time, limit Limit) (time.Time, RateLimitResult) {
+	if tat.Before(now) {
+		tat = now
+	}
+
+	emission, tolerance := limit.emissionInterval(), limit.tolerance()
+	newTAT := tat.Add(emission)
+	if allowAt := newTAT.Add(-tolerance); now.Before(allowAt) {
+		return tat, RateLimitResult{
+			RetryAfter: allowAt.Sub(now),
+			ResetAfter: tat.Sub(now),
+		}
+	}
+
+	return newTAT, rateLimitResult(true, 0, newTAT.Sub(now), limit)
+}
+
+// rateLimitResult fills in Remaining from how far the bucket is from empty
+func rateLimitResult(allowed bool, retryAfter, resetAfter time.Duration, limit Limit) RateLimitResult {
+	result := RateLimitResult{Allowed: allowed, RetryAfter: retryAfter, ResetAfter: resetAfter}
+	if allowed {
+		result.Remaining = int((limit.tolerance() - resetAfter) / limit.emissionInterval())
+	}
+	return result
+}
+
+// Allow counts one request under key against limit. While the store fails
+// requests are counted in process, if the fallback is enabled.
+func (c *Cache) Allow(ctx context.Context, key string, limit Limit) (RateLimitResult, error) {
+	return c.allow(ctx, "allow", key, limit, config.FailOpen)
+}
+
+// AllowLoginAttempt counts a login attempt by identifier against limit.
+// ClearLoginAttempts forgets the attempts after a successful login. With
+// CACHE_OUTAGE_LOGIN_ATTEMPTS=closed a store error is returned even if the
+// fallback is enabled, for the caller to reject the login.
+func (c *Cache) AllowLoginAttempt(ctx context.Context, identifier string, limit Limit) (RateLimitResult, error) {
+	return c.allow(ctx, "allow_login_attempt", "login_attempts:"+identifier, limit, c.config.Security.CacheOutage.LoginAttempts)
+}
+
+// AllowRequest counts an API request by identifier against limit. With
+// CACHE_OUTAGE_RATE_LIMIT=closed a store error is returned even if the
+// fallback is enabled, for the caller to reject the request.
+func (c *Cache) AllowRequest(ctx context.Context, identifier string, limit Limit) (RateLimitResult, error) {
+	return c.allow(ctx, "allow_request", "rate_limit:"+identifier, limit, c.config.Security.CacheOutage.RateLimit)
+}
+
+// ClearLoginAttempts forgets login attempts, including attempts counted in
+// process while the store failed
+func (c *Cache) ClearLoginAttempts(ctx context.Context, identifier string) error {
+	key := "login_attempts:" + identifier
+	if c.fallback != nil {
+		c.fallback.delete(key)
+	}
+	err := c.Delete(ctx, key)
+	if err != nil && c.fallback != nil {
+		fallbackTotal.WithLabelValues("clear_login_attempts").Inc()
+		return nil
+	}
+	return err
+}
+
+// allow runs the limit in the store, falling back to the in-process limiter
+// on error unless outage is config.FailClosed; operation labels
+// fallbackTotal
+func (c *Cache) allow(ctx context.Context, operation, key string, limit Limit, outage string) (RateLimitResult, error) {
+	if !limit.valid() {
+		return RateLimitResult{}, fmt.Errorf("invalid rate limit %+v", limit)
+	}
+
+	result, err := c.store.Allow(ctx, key, limit)
+	if err != nil && c.fallback != nil && outage != config.FailClosed {
+		fallbackTotal.WithLabelValues(operation).Inc()
+		return c.fallback.allow(key, limit), nil
+	}
+	return result, err
+}
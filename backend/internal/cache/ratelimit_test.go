@@ -0,0 +1,86 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/config"
+)
+
+// newTestConfig returns the settings a cache needs in tests, with the local
+// fallback enabled as by default
+func newTestConfig(m *miniredis.Miniredis) *config.Config {
+	cfg := &config.Config{}
+	cfg.Redis.Host = m.Host()
+	cfg.Redis.Port = m.Port()
+	// Fail at once when the server is closed
+	cfg.Redis.MaxRetries = -1
+	cfg.Redis.Fallback = FallbackLocal
+	cfg.Redis.FallbackMaxEntries = 100
+	cfg.Security.CacheOutage = config.CacheOutageConfig{
+		RateLimit:     config.FailOpen,
+		LoginAttempts: config.FailOpen,
+	}
+	return cfg
+}
+
+// newTestRedisCache returns a cache in a miniredis server, which the test may
+// close to simulate an outage
+func newTestRedisCache(t *testing.T, cfg func(*config.Config)) (*Cache, *miniredis.Miniredis) {
+	t.Helper()
+
+	m := miniredis.RunT(t)
+	c := newTestConfig(m)
+	if cfg != nil {
+		cfg(c)
+	}
+	store, err := NewRedisStore(c)
+	if err != nil {
+		t.Fatalf("NewRedisStore: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	return NewWithStore(c, store), m
+}
+
+func TestAllowDuringOutage(t *testing.T) {
+	limit := PerPeriod(5, time.Minute)
+
+	tests := []struct {
+		name     string
+		fallback string
+		outage   string
+		wantErr  bool
+	}{
+		{name: "local fallback, open", fallback: FallbackLocal, outage: config.FailOpen},
+		{name: "local fallback, closed", fallback: FallbackLocal, outage: config.FailClosed, wantErr: true},
+		{name: "no fallback, open", fallback: FallbackNone, outage: config.FailOpen, wantErr: true},
+		{name: "no fallback, closed", fallback: FallbackNone, outage: config.FailClosed, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, m := newTestRedisCache(t, func(cfg *config.Config) {
+				cfg.Redis.Fallback = tt.fallback
+				cfg.Security.CacheOutage.RateLimit = tt.outage
+				cfg.Security.CacheOutage.LoginAttempts = tt.outage
+			})
+			m.Close()
+
+			ctx := context.Background()
+			for name, allow := range map[string]func() (RateLimitResult, error){
+				"AllowRequest":      func() (RateLimitResult, error) { return c.AllowRequest(ctx, "10.0.0.1", limit) },
+				"AllowLoginAttempt": func() (RateLimitResult, error) { return c.AllowLoginAttempt(ctx, "a@b.com", limit) },
+			} {
+				result, err := allow()
+				if (err != nil) != tt.wantErr {
+					t.Fatalf("%s error = %v, want error %v", name, err, tt.wantErr)
+				}
+				if err == nil && !result.Allowed {
+					t.Errorf("%s denied the first request by the fallback", name)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,385 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/extra/redisotel/v9"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/config"
+)
+
+// memberValuesSuffix names the hash holding a set's member values, next to
+// the sorted set of members scored by expiry. It is named for the IP
+// denylist, the first set, so denylists written before sets were generic
+// keep their reasons.
+const memberValuesSuffix = ":reasons"
+
+// Scripts comparing a key's value before changing it, run atomically by
+// Redis
+var (
+	deleteIfEqualScript = redis.NewScript(`
+		if redis.call("GET", KEYS[1]) == ARGV[1] then
+			return redis.call("DEL", KEYS[1])
+		end
+		return 0
+	`)
+	// allowScript is gcra, timed by the Redis clock so every instance
+	// agrees. Times are in microseconds. It returns whether the request is
+	// allowed, the retry-after and the reset-after.
+	allowScript = redis.NewScript(`
+		local emission = tonumber(ARGV[1])
+		local tolerance = tonumber(ARGV[2])
+		local time = redis.call("TIME")
+		local now = tonumber(time[1]) * 1000000 + tonumber(time[2])
+
+		local tat = tonumber(redis.call("GET", KEYS[1])) or now
+		if tat < now then
+			tat = now
+		end
+
+		local new_tat = tat + emission
+		local allow_at = new_tat - tolerance
+		if now < allow_at then
+			return {0, allow_at - now, tat - now}
+		end
+
+		redis.call("SET", KEYS[1], new_tat, "PX", math.ceil((new_tat - now) / 1000))
+		return {1, 0, new_tat - now}
+	`)
+	// setNXOrGetScript sets the key if it is free, with a TTL in
+	// milliseconds unless it is zero, or returns its value
+	setNXOrGetScript = redis.NewScript(`
+		local set
+		if ARGV[2] == "0" then
+			set = redis.call("SET", KEYS[1], ARGV[1], "NX")
+		else
+			set = redis.call("SET", KEYS[1], ARGV[1], "NX", "PX", ARGV[2])
+		end
+		if set then
+			return {1, ""}
+		end
+		return {0, redis.call("GET", KEYS[1])}
+	`)
+	expireIfEqualScript = redis.NewScript(`
+		if redis.call("GET", KEYS[1]) == ARGV[1] then
+			return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+		end
+		return 0
+	`)
+)
+
+// RedisStore is a Store in Redis, shared by every server instance
+type RedisStore struct {
+	client *redis.Client
+}
+
+// NewRedisStore connects to the Redis described by cfg (see newClient) and
+// checks that it answers
+func NewRedisStore(cfg *config.Config) (*RedisStore, error) {
+	client, err := newClient(cfg.Redis)
+	if err != nil {
+		return nil, err
+	}
+
+	// Child spans for every command when tracing is enabled
+	if cfg.Tracing.Enabled {
+		if err := redisotel.InstrumentTracing(client); err != nil {
+			_ = client.Close()
+			return nil, fmt.Errorf("failed to instrument Redis tracing: %w", err)
+		}
+	}
+
+	// Verify connection
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := client.Ping(ctx).Err(); err != nil {
+		_ = client.Close()
+		return nil, fmt.Errorf("failed to connect to Redis: %w", err)
+	}
+
+	return &RedisStore{client: client}, nil
+}
+
+// Get returns the value at key, or ErrNotFound
+func (s *RedisStore) Get(ctx context.Context, key string) (string, error) {
+	val, err := s.client.Get(ctx, key).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", ErrNotFound
+	}
+	return val, err
+}
+
+// GetMany reads the keys with one MGET
+func (s *RedisStore) GetMany(ctx context.Context, keys ...string) (map[string]string, error) {
+	values := make(map[string]string, len(keys))
+	if len(keys) == 0 {
+		return values, nil
+	}
+
+	results, err := s.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+	for i, result := range results {
+		if val, ok := result.(string); ok {
+			values[keys[i]] = val
+		}
+	}
+	return values, nil
+}
+
+// Set stores a key-value pair with TTL
+func (s *RedisStore) Set(ctx context.Context, key, value string, ttl time.Duration) error {
+	return s.client.Set(ctx, key, value, ttl).Err()
+}
+
+// SetNX sets a key only if it doesn't exist
+func (s *RedisStore) SetNX(ctx context.Context, key, value string, ttl time.Duration) (bool, error) {
+	return s.client.SetNX(ctx, key, value, ttl).Result()
+}
+
+// SetNXOrGet sets a key if it doesn't exist, or returns its value
+func (s *RedisStore) SetNXOrGet(ctx context.Context, key, value string, ttl time.Duration) (string, bool, error) {
+	values, err := setNXOrGetScript.Run(ctx, s.client, []string{key}, value, ttl.Milliseconds()).Slice()
+	if err != nil {
+		return "", false, err
+	}
+	if len(values) != 2 {
+		return "", false, fmt.Errorf("unexpected set-or-get reply %v", values)
+	}
+	set, _ := values[0].(int64)
+	existing, _ := values[1].(string)
+	return existing, set == 1, nil
+}
+
+// Delete removes keys
+func (s *RedisStore) Delete(ctx context.Context, keys ...string) error {
+	return s.client.Del(ctx, keys...).Err()
+}
+
+// Exists checks if a key exists
+func (s *RedisStore) Exists(ctx context.Context, key string) (bool, error) {
+	count, err := s.client.Exists(ctx, key).Result()
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// Expire sets a TTL on an existing key
+func (s *RedisStore) Expire(ctx context.Context, key string, ttl time.Duration) error {
+	return s.client.Expire(ctx, key, ttl).Err()
+}
+
+// DeleteIfEqual deletes key if its value is value
+func (s *RedisStore) DeleteIfEqual(ctx context.Context, key, value string) (bool, error) {
+	n, err := deleteIfEqualScript.Run(ctx, s.client, []string{key}, value).Int()
+	return n > 0, err
+}
+
+// ExpireIfEqual sets the TTL of key if its value is value
+func (s *RedisStore) ExpireIfEqual(ctx context.Context, key, value string, ttl time.Duration) (bool, error) {
+	n, err := expireIfEqualScript.Run(ctx, s.client, []string{key}, value, ttl.Milliseconds()).Int()
+	return n > 0, err
+}
+
+// IncrementBy increments a counter by n
+func (s *RedisStore) IncrementBy(ctx context.Context, key string, n int64) (int64, error) {
+	return s.client.IncrBy(ctx, key, n).Result()
+}
+
+// Allow counts one request under key against limit
+func (s *RedisStore) Allow(ctx context.Context, key string, limit Limit) (RateLimitResult, error) {
+	emission, tolerance := limit.emissionInterval().Microseconds(), limit.tolerance().Microseconds()
+	values, err := allowScript.Run(ctx, s.client, []string{key}, emission, tolerance).Int64Slice()
+	if err != nil {
+		return RateLimitResult{}, err
+	}
+	if len(values) != 3 {
+		return RateLimitResult{}, fmt.Errorf("unexpected rate limit script result %v", values)
+	}
+
+	return rateLimitResult(values[0] == 1, time.Duration(values[1])*time.Microsecond, time.Duration(values[2])*time.Microsecond, limit), nil
+}
+
+// AddMember adds or replaces a set member. The set is a sorted set of names
+// scored by expiry (unix seconds, +inf for none) plus a hash of the values
+// that aren't empty.
+func (s *RedisStore) AddMember(ctx context.Context, key string, member Member) error {
+	score := math.Inf(1)
+	if !member.ExpiresAt.IsZero() {
+		score = float64(member.ExpiresAt.Unix())
+	}
+
+	pipe := s.client.TxPipeline()
+	pipe.ZAdd(ctx, key, redis.Z{Score: score, Member: member.Name})
+	if member.Value != "" {
+		pipe.HSet(ctx, key+memberValuesSuffix, member.Name, member.Value)
+	} else {
+		pipe.HDel(ctx, key+memberValuesSuffix, member.Name)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// RemoveMember deletes a set member and reports whether it existed
+func (s *RedisStore) RemoveMember(ctx context.Context, key, name string) (bool, error) {
+	pipe := s.client.TxPipeline()
+	removed := pipe.ZRem(ctx, key, name)
+	pipe.HDel(ctx, key+memberValuesSuffix, name)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return false, err
+	}
+	return removed.Val() > 0, nil
+}
+
+// Members returns the unexpired set members, pruning expired ones
+func (s *RedisStore) Members(ctx context.Context, key string) ([]Member, error) {
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	valuesKey := key + memberValuesSuffix
+
+	expired, err := s.client.ZRangeByScore(ctx, key, &redis.ZRangeBy{Min: "-inf", Max: "(" + now}).Result()
+	if err != nil {
+		return nil, err
+	}
+	if len(expired) > 0 {
+		pipe := s.client.TxPipeline()
+		pipe.ZRem(ctx, key, stringsToMembers(expired)...)
+		pipe.HDel(ctx, valuesKey, expired...)
+		if _, err := pipe.Exec(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	scored, err := s.client.ZRangeWithScores(ctx, key, 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+	if len(scored) == 0 {
+		return nil, nil
+	}
+
+	names := make([]string, len(scored))
+	for i, z := range scored {
+		names[i] = z.Member.(string)
+	}
+
+	values, err := s.client.HMGet(ctx, valuesKey, names...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	members := make([]Member, len(scored))
+	for i, z := range scored {
+		members[i].Name = names[i]
+		if value, ok := values[i].(string); ok {
+			members[i].Value = value
+		}
+		if !math.IsInf(z.Score, 1) {
+			members[i].ExpiresAt = time.Unix(int64(z.Score), 0)
+		}
+	}
+
+	return members, nil
+}
+
+// stringsToMembers converts strings to the []interface{} ZRem expects
+func stringsToMembers(values []string) []interface{} {
+	members := make([]interface{}, len(values))
+	for i, v := range values {
+		members[i] = v
+	}
+	return members
+}
+
+// Publish sends message on a Redis channel
+func (s *RedisStore) Publish(ctx context.Context, channel string, message []byte) error {
+	return s.client.Publish(ctx, channel, message).Err()
+}
+
+// Subscribe subscribes to a Redis channel, waiting for Redis to confirm. The
+// subscription reconnects by itself if the connection drops.
+func (s *RedisStore) Subscribe(ctx context.Context, channel string) (Subscription, error) {
+	pubsub := s.client.Subscribe(ctx, channel)
+	if _, err := pubsub.Receive(ctx); err != nil {
+		_ = pubsub.Close()
+		return nil, err
+	}
+
+	sub := &redisSubscription{pubsub: pubsub, messages: make(chan []byte), done: make(chan struct{})}
+	go sub.forward()
+	return sub, nil
+}
+
+type redisSubscription struct {
+	pubsub    *redis.PubSub
+	messages  chan []byte
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// forward relays messages until the subscription is closed, even if the
+// subscriber has stopped reading
+func (s *redisSubscription) forward() {
+	defer close(s.messages)
+	for msg := range s.pubsub.Channel() {
+		select {
+		case s.messages <- []byte(msg.Payload):
+		case <-s.done:
+			return
+		}
+	}
+}
+
+func (s *redisSubscription) Messages() <-chan []byte {
+	return s.messages
+}
+
+func (s *redisSubscription) Close() error {
+	s.closeOnce.Do(func() { close(s.done) })
+	return s.pubsub.Close()
+}
+
+// AddToStream appends an entry to a Redis stream, trimming the stream to
+// about maxLen entries, and returns the entry ID
+func (s *RedisStore) AddToStream(ctx context.Context, stream string, maxLen int64, values map[string]interface{}) (string, error) {
+	return s.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: stream,
+		MaxLen: maxLen,
+		Approx: true,
+		Values: values,
+	}).Result()
+}
+
+// Ping checks that Redis answers
+func (s *RedisStore) Ping(ctx context.Context) error {
+	return s.client.Ping(ctx).Err()
+}
+
+// Stats returns the client's connection pool statistics
+func (s *RedisStore) Stats() PoolStats {
+	stats := s.client.PoolStats()
+	return PoolStats{
+		TotalConns:   stats.TotalConns,
+		IdleConns:    stats.IdleConns,
+		StaleConns:   stats.StaleConns,
+		Hits:         stats.Hits,
+		Misses:       stats.Misses,
+		Timeouts:     stats.Timeouts,
+		WaitCount:    stats.WaitCount,
+		WaitDuration: time.Duration(stats.WaitDurationNs),
+	}
+}
+
+// Close closes the Redis connections
+func (s *RedisStore) Close() error {
+	return s.client.Close()
+}
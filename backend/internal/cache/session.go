@@ -0,0 +1,152 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// sessionTouchInterval is the least time between LastSeenAt updates of a
+// session that stays on the same IP, so active clients don't write on every
+// request
+const sessionTouchInterval = time.Minute
+
+// Session is a signed-in device: one refresh token and the access tokens
+// issued with it
+type Session struct {
+	// ID is the refresh token's ID
+	ID     string `json:"id"`
+	UserID string `json:"user_id"`
+	// Device is the client's name for itself, if it gave one
+	Device string `json:"device,omitempty"`
+	// DeviceHash is the hash of the device ID the client gave at login, if
+	// any; the session's refresh token only works from that device
+	DeviceHash string `json:"device_hash,omitempty"`
+	IP         string `json:"ip,omitempty"`
+	UserAgent  string `json:"user_agent,omitempty"`
+
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// Sessions are stored in Cache.sessions, under "session:<id>", and each
+// user's session IDs in a set that expires with the sessions
+func userSessionsKey(userID string) string {
+	return "user_sessions:" + userID
+}
+
+// CreateSession stores a new session, expiring at session.ExpiresAt. The
+// user's expired sessions are dropped from their set of sessions, which
+// otherwise only ListSessions does.
+func (c *Cache) CreateSession(ctx context.Context, session *Session) error {
+	if err := c.putSession(ctx, session); err != nil {
+		return err
+	}
+
+	key := userSessionsKey(session.UserID)
+	if _, err := c.store.Members(ctx, key); err != nil {
+		return err
+	}
+	return c.store.AddMember(ctx, key, Member{Name: session.ID, ExpiresAt: session.ExpiresAt})
+}
+
+// putSession writes session with a TTL running to its expiry
+func (c *Cache) putSession(ctx context.Context, session *Session) error {
+	ttl := time.Until(session.ExpiresAt)
+	if ttl <= 0 {
+		return nil
+	}
+
+	return c.sessions.SetWithTTL(ctx, session.ID, *session, ttl)
+}
+
+// GetSession returns the session with the given ID, or ErrNotFound if it
+// expired or was revoked
+func (c *Cache) GetSession(ctx context.Context, id string) (*Session, error) {
+	session, err := c.sessions.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// TouchSession records activity on a session from ip and userAgent and
+// returns it, or ErrNotFound if it expired or was revoked. LastSeenAt is
+// only written once per sessionTouchInterval unless the IP changed.
+func (c *Cache) TouchSession(ctx context.Context, id, ip, userAgent string) (*Session, error) {
+	session, err := c.GetSession(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return session, c.RecordSessionActivity(ctx, session, ip, userAgent)
+}
+
+// RecordSessionActivity is TouchSession for a session already read, such
+// as by CheckAccessToken
+func (c *Cache) RecordSessionActivity(ctx context.Context, session *Session, ip, userAgent string) error {
+	now := time.Now()
+	if now.Sub(session.LastSeenAt) < sessionTouchInterval && session.IP == ip {
+		return nil
+	}
+
+	session.LastSeenAt = now
+	session.IP = ip
+	if userAgent != "" {
+		session.UserAgent = userAgent
+	}
+	return c.putSession(ctx, session)
+}
+
+// ListSessions returns the user's unexpired sessions, soonest to expire
+// first
+func (c *Cache) ListSessions(ctx context.Context, userID string) ([]*Session, error) {
+	members, err := c.store.Members(ctx, userSessionsKey(userID))
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := make([]*Session, 0, len(members))
+	for _, member := range members {
+		session, err := c.GetSession(ctx, member.Name)
+		if errors.Is(err, ErrNotFound) {
+			// Revoked while listing
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+
+	return sessions, nil
+}
+
+// DeleteSession revokes the user's session with the given ID and reports
+// whether it existed. Its access tokens are rejected from then on.
+func (c *Cache) DeleteSession(ctx context.Context, userID, id string) (bool, error) {
+	existed, err := c.store.RemoveMember(ctx, userSessionsKey(userID), id)
+	if err != nil {
+		return false, err
+	}
+	if err := c.sessions.Delete(ctx, id); err != nil {
+		return false, err
+	}
+	return existed, nil
+}
+
+// DeleteUserSessions revokes every session of the user and returns how
+// many there were
+func (c *Cache) DeleteUserSessions(ctx context.Context, userID string) (int, error) {
+	members, err := c.store.Members(ctx, userSessionsKey(userID))
+	if err != nil {
+		return 0, err
+	}
+
+	for _, member := range members {
+		if _, err := c.DeleteSession(ctx, userID, member.Name); err != nil {
+			return 0, err
+		}
+	}
+	return len(members), nil
+}
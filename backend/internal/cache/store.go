@@ -0,0 +1,102 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrNotFound is returned by Get for a missing or expired key
+var ErrNotFound = errors.New("key not found")
+
+// Store is the storage behind Cache. RedisStore is the production
+// implementation, shared by every server instance. MemoryStore keeps
+// everything in process, for tests and single-instance deployments without
+// Redis. Other backends, such as memcached or DynamoDB, implement Store to
+// plug in. Implementations must be safe for concurrent use; a zero ttl
+// means no expiry.
+type Store interface {
+	// Get returns the value at key, or ErrNotFound
+	Get(ctx context.Context, key string) (string, error)
+	// GetMany returns the values of the keys that exist, by key, in one
+	// round trip
+	GetMany(ctx context.Context, keys ...string) (map[string]string, error)
+	Set(ctx context.Context, key, value string, ttl time.Duration) error
+	// SetNX sets key only if it doesn't exist, and reports whether it did
+	SetNX(ctx context.Context, key, value string, ttl time.Duration) (bool, error)
+	// SetNXOrGet sets key like SetNX, or returns the value it already has,
+	// atomically
+	SetNXOrGet(ctx context.Context, key, value string, ttl time.Duration) (existing string, set bool, err error)
+	Delete(ctx context.Context, keys ...string) error
+	Exists(ctx context.Context, key string) (bool, error)
+	// Expire sets the TTL of an existing key
+	Expire(ctx context.Context, key string, ttl time.Duration) error
+	// DeleteIfEqual deletes key if its value is value, atomically, and
+	// reports whether it did
+	DeleteIfEqual(ctx context.Context, key, value string) (bool, error)
+	// ExpireIfEqual sets the TTL of key if its value is value, atomically,
+	// and reports whether it did
+	ExpireIfEqual(ctx context.Context, key, value string, ttl time.Duration) (bool, error)
+	// IncrementBy adds n to the counter at key, creating it at zero without
+	// a TTL, and returns the new count
+	IncrementBy(ctx context.Context, key string, n int64) (int64, error)
+
+	// Allow counts one request under key against limit, atomically across
+	// every instance sharing the store (see Limit). Denied requests aren't
+	// counted.
+	Allow(ctx context.Context, key string, limit Limit) (RateLimitResult, error)
+
+	// AddMember adds or replaces a member of the set at key, with a value
+	// and an expiry; a zero expiresAt never expires
+	AddMember(ctx context.Context, key string, member Member) error
+	// RemoveMember removes a member and reports whether it existed
+	RemoveMember(ctx context.Context, key, name string) (bool, error)
+	// Members returns the set's unexpired members, dropping expired ones
+	Members(ctx context.Context, key string) ([]Member, error)
+
+	// Publish sends message to the channel's subscribers on every instance
+	// sharing the store
+	Publish(ctx context.Context, channel string, message []byte) error
+	// Subscribe subscribes to channel. Messages published after it returns
+	// are delivered; the caller must Close the subscription.
+	Subscribe(ctx context.Context, channel string) (Subscription, error)
+
+	// AddToStream appends an entry to a stream trimmed to about maxLen
+	// entries and returns its ID. Stores without streams return
+	// errors.ErrUnsupported.
+	AddToStream(ctx context.Context, stream string, maxLen int64, values map[string]interface{}) (string, error)
+
+	Ping(ctx context.Context) error
+	// Stats reports the connection pool, zero for stores without one
+	Stats() PoolStats
+	Close() error
+}
+
+// Member is a member of a set in a Store
+type Member struct {
+	Name  string
+	Value string
+	// ExpiresAt is zero for members that never expire
+	ExpiresAt time.Time
+}
+
+// Subscription delivers the messages published to a channel
+type Subscription interface {
+	// Messages is closed when the subscription is
+	Messages() <-chan []byte
+	Close() error
+}
+
+// PoolStats are a store's connection pool statistics. The counts other
+// than TotalConns and IdleConns are running totals.
+type PoolStats struct {
+	TotalConns uint32
+	IdleConns  uint32
+	StaleConns uint32
+	Hits       uint32
+	Misses     uint32
+	Timeouts   uint32
+	WaitCount  uint32
+	// WaitDuration is the total time spent waiting for a connection
+	WaitDuration time.Duration
+}
@@ -0,0 +1,190 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+// testStore is a Store under test, with a way to let time pass
+type testStore struct {
+	name  string
+	store Store
+	// advance lets d pass for TTLs and rate limits alike
+	advance func(d time.Duration)
+}
+
+// newTestStores returns a MemoryStore and a RedisStore in miniredis, so each
+// test checks that both behave the same
+func newTestStores(t *testing.T) []testStore {
+	t.Helper()
+
+	m := miniredis.RunT(t)
+	redisStore, err := NewRedisStore(newTestConfig(m))
+	if err != nil {
+		t.Fatalf("NewRedisStore: %v", err)
+	}
+	t.Cleanup(func() { _ = redisStore.Close() })
+
+	return []testStore{
+		{name: "memory", store: NewMemoryStore(), advance: time.Sleep},
+		{
+			name:  "redis",
+			store: redisStore,
+			// miniredis expires keys when told to, and its TIME, which the
+			// rate limit script reads, follows the real clock
+			advance: func(d time.Duration) {
+				time.Sleep(d)
+				m.FastForward(d)
+			},
+		},
+	}
+}
+
+func TestStoreAllow(t *testing.T) {
+	// Two requests at once, then one every 50ms
+	limit := Limit{Rate: 4, Period: 200 * time.Millisecond, Burst: 2}
+	emission := limit.emissionInterval()
+
+	for _, ts := range newTestStores(t) {
+		t.Run(ts.name, func(t *testing.T) {
+			ctx := context.Background()
+
+			tests := []struct {
+				name string
+				// wait is how long to let pass before the request
+				wait          time.Duration
+				wantAllowed   bool
+				wantRemaining int
+			}{
+				{name: "first request", wantAllowed: true, wantRemaining: 1},
+				{name: "burst", wantAllowed: true, wantRemaining: 0},
+				{name: "over burst", wantAllowed: false},
+				{name: "denied requests aren't counted", wantAllowed: false},
+				{name: "after one interval", wait: emission + 10*time.Millisecond, wantAllowed: true, wantRemaining: 0},
+				{name: "after the bucket empties", wait: limit.tolerance() + 10*time.Millisecond, wantAllowed: true, wantRemaining: 1},
+			}
+			for _, tt := range tests {
+				ts.advance(tt.wait)
+
+				result, err := ts.store.Allow(ctx, "allow", limit)
+				if err != nil {
+					t.Fatalf("%s: Allow: %v", tt.name, err)
+				}
+				if result.Allowed != tt.wantAllowed || result.Remaining != tt.wantRemaining {
+					t.Errorf("%s: allowed %v with %d remaining, want %v with %d", tt.name, result.Allowed, result.Remaining, tt.wantAllowed, tt.wantRemaining)
+				}
+				if result.Allowed && result.RetryAfter != 0 {
+					t.Errorf("%s: retry after %v for an allowed request", tt.name, result.RetryAfter)
+				}
+				if !result.Allowed && (result.RetryAfter <= 0 || result.RetryAfter > emission) {
+					t.Errorf("%s: retry after %v, want (0, %v]", tt.name, result.RetryAfter, emission)
+				}
+				if result.ResetAfter <= 0 || result.ResetAfter > limit.tolerance() {
+					t.Errorf("%s: reset after %v, want (0, %v]", tt.name, result.ResetAfter, limit.tolerance())
+				}
+			}
+
+			// Keys are limited separately
+			result, err := ts.store.Allow(ctx, "allow-other", limit)
+			if err != nil || !result.Allowed {
+				t.Errorf("Allow on another key = %+v, %v, want allowed", result, err)
+			}
+		})
+	}
+}
+
+func TestStoreSetNXOrGet(t *testing.T) {
+	const ttl = 100 * time.Millisecond
+
+	for _, ts := range newTestStores(t) {
+		t.Run(ts.name, func(t *testing.T) {
+			ctx := context.Background()
+
+			tests := []struct {
+				name  string
+				key   string
+				value string
+				ttl   time.Duration
+				// wait is how long to let pass before the call
+				wait         time.Duration
+				wantExisting string
+				wantSet      bool
+			}{
+				{name: "free key", key: "a", value: "first", ttl: ttl, wantSet: true},
+				{name: "taken key", key: "a", value: "second", ttl: ttl, wantExisting: "first"},
+				{name: "taken key keeps its value", key: "a", value: "third", ttl: ttl, wantExisting: "first"},
+				{name: "expired key", key: "a", value: "fourth", ttl: ttl, wait: ttl + 50*time.Millisecond, wantSet: true},
+				{name: "no ttl", key: "b", value: "kept", wantSet: true},
+				{name: "no ttl, later", key: "b", value: "other", wait: ttl + 50*time.Millisecond, wantExisting: "kept"},
+			}
+			for _, tt := range tests {
+				ts.advance(tt.wait)
+
+				existing, set, err := ts.store.SetNXOrGet(ctx, tt.key, tt.value, tt.ttl)
+				if err != nil {
+					t.Fatalf("%s: SetNXOrGet: %v", tt.name, err)
+				}
+				if existing != tt.wantExisting || set != tt.wantSet {
+					t.Errorf("%s: SetNXOrGet = %q, %v, want %q, %v", tt.name, existing, set, tt.wantExisting, tt.wantSet)
+				}
+			}
+		})
+	}
+}
+
+func TestStoreExpireIfEqual(t *testing.T) {
+	const ttl = 100 * time.Millisecond
+
+	for _, ts := range newTestStores(t) {
+		t.Run(ts.name, func(t *testing.T) {
+			ctx := context.Background()
+
+			tests := []struct {
+				name string
+				// held is whether the key is set to "token", with ttl, first
+				held  bool
+				value string
+				ttl   time.Duration
+				// wantExtended is what ExpireIfEqual reports, and wantAlive
+				// whether the key exists once the first ttl passed
+				wantExtended bool
+				wantAlive    bool
+			}{
+				{name: "missing key", value: "token", ttl: time.Minute},
+				{name: "other value", held: true, value: "not-the-token", ttl: time.Minute},
+				{name: "same value, longer ttl", held: true, value: "token", ttl: time.Minute, wantExtended: true, wantAlive: true},
+				{name: "same value, shorter ttl", held: true, value: "token", ttl: ttl / 2, wantExtended: true},
+			}
+			for _, tt := range tests {
+				if err := ts.store.Delete(ctx, "lock"); err != nil {
+					t.Fatalf("%s: Delete: %v", tt.name, err)
+				}
+				if tt.held {
+					if err := ts.store.Set(ctx, "lock", "token", ttl); err != nil {
+						t.Fatalf("%s: Set: %v", tt.name, err)
+					}
+				}
+
+				extended, err := ts.store.ExpireIfEqual(ctx, "lock", tt.value, tt.ttl)
+				if err != nil {
+					t.Fatalf("%s: ExpireIfEqual: %v", tt.name, err)
+				}
+				if extended != tt.wantExtended {
+					t.Errorf("%s: ExpireIfEqual = %v, want %v", tt.name, extended, tt.wantExtended)
+				}
+
+				ts.advance(ttl + 10*time.Millisecond)
+				alive, err := ts.store.Exists(ctx, "lock")
+				if err != nil {
+					t.Fatalf("%s: Exists: %v", tt.name, err)
+				}
+				if alive != tt.wantAlive {
+					t.Errorf("%s: key exists = %v after %v, want %v", tt.name, alive, ttl, tt.wantAlive)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,186 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// ErrAbsent is returned by Typed.Get for a key cached as absent by
+// SetAbsent. It wraps ErrNotFound, so callers that don't care which can
+// check for that alone.
+var ErrAbsent = fmt.Errorf("%w: cached as absent", ErrNotFound)
+
+// Codec encodes the values of a Typed cache
+type Codec[T any] interface {
+	Marshal(value T) ([]byte, error)
+	Unmarshal(data []byte) (T, error)
+}
+
+// JSONCodec encodes values as JSON
+func JSONCodec[T any]() Codec[T] {
+	return jsonCodec[T]{}
+}
+
+type jsonCodec[T any] struct{}
+
+func (jsonCodec[T]) Marshal(value T) ([]byte, error) {
+	return json.Marshal(value)
+}
+
+func (jsonCodec[T]) Unmarshal(data []byte) (T, error) {
+	var value T
+	err := json.Unmarshal(data, &value)
+	return value, err
+}
+
+// ProtoCodec encodes generated proto messages in the binary wire format
+func ProtoCodec[T proto.Message]() Codec[T] {
+	return protoCodec[T]{}
+}
+
+type protoCodec[T proto.Message] struct{}
+
+func (protoCodec[T]) Marshal(value T) ([]byte, error) {
+	return proto.Marshal(value)
+}
+
+func (protoCodec[T]) Unmarshal(data []byte) (T, error) {
+	// A nil generated message still reports its type
+	var zero T
+	value := zero.ProtoReflect().Type().New().Interface().(T)
+	err := proto.Unmarshal(data, value)
+	return value, err
+}
+
+// Typed stores values of T under the keys of one family, "<family>:<key>",
+// encoded with a codec, so call sites don't marshal by hand. Keys can also
+// be cached as absent, for a shorter TTL, so repeated lookups of something
+// that doesn't exist skip the source too. Absent keys are marked under
+// "<family>:absent:<key>", leaving the value encoding untouched, so keys
+// must not themselves start with "absent:".
+type Typed[T any] struct {
+	cache       *Cache
+	family      string
+	codec       Codec[T]
+	ttl         time.Duration
+	negativeTTL time.Duration
+}
+
+// NewTyped creates a typed cache of the family's keys, keeping values for
+// ttl and absent keys for negativeTTL; a zero negativeTTL caches no absent
+// keys
+func NewTyped[T any](c *Cache, family string, codec Codec[T], ttl, negativeTTL time.Duration) *Typed[T] {
+	return &Typed[T]{
+		cache:       c,
+		family:      family,
+		codec:       codec,
+		ttl:         ttl,
+		negativeTTL: negativeTTL,
+	}
+}
+
+func (t *Typed[T]) key(key string) string {
+	return t.family + ":" + key
+}
+
+func (t *Typed[T]) absentKey(key string) string {
+	return t.family + ":absent:" + key
+}
+
+// Get returns the value at key, ErrAbsent if the key is cached as absent,
+// or ErrNotFound if it isn't cached at all
+func (t *Typed[T]) Get(ctx context.Context, key string) (T, error) {
+	var zero T
+
+	keys := []string{t.key(key)}
+	if t.negativeTTL > 0 {
+		keys = append(keys, t.absentKey(key))
+	}
+	values, err := t.cache.GetMany(ctx, keys...)
+	if err != nil {
+		return zero, err
+	}
+
+	if data, ok := values[t.key(key)]; ok {
+		return t.decode(data)
+	}
+	if _, ok := values[t.absentKey(key)]; ok {
+		return zero, ErrAbsent
+	}
+	return zero, ErrNotFound
+}
+
+// decode decodes a value read from the family's keys directly, such as in
+// a GetMany with other keys
+func (t *Typed[T]) decode(data string) (T, error) {
+	value, err := t.codec.Unmarshal([]byte(data))
+	if err != nil {
+		var zero T
+		return zero, fmt.Errorf("malformed %s: %w", t.family, err)
+	}
+	return value, nil
+}
+
+// Set stores value at key for the cache's TTL
+func (t *Typed[T]) Set(ctx context.Context, key string, value T) error {
+	return t.SetWithTTL(ctx, key, value, t.ttl)
+}
+
+// SetWithTTL stores value at key for ttl instead of the cache's TTL
+func (t *Typed[T]) SetWithTTL(ctx context.Context, key string, value T, ttl time.Duration) error {
+	data, err := t.codec.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return t.cache.Set(ctx, t.key(key), string(data), ttl)
+}
+
+// SetAbsent caches key as absent for the negative TTL, dropping any value.
+// It does nothing if the cache keeps no absent keys.
+func (t *Typed[T]) SetAbsent(ctx context.Context, key string) error {
+	if t.negativeTTL <= 0 {
+		return nil
+	}
+	if err := t.cache.Delete(ctx, t.key(key)); err != nil {
+		return err
+	}
+	return t.cache.Set(ctx, t.absentKey(key), "1", t.negativeTTL)
+}
+
+// Delete drops key, whether cached with a value or as absent
+func (t *Typed[T]) Delete(ctx context.Context, key string) error {
+	return t.cache.Delete(ctx, t.key(key), t.absentKey(key))
+}
+
+// GetOrLoad returns the cached value at key, or loads and caches it. load
+// returns ErrNotFound for a key that doesn't exist, which is cached as
+// absent. Cache errors fall through to load, so an outage only costs the
+// caching.
+func (t *Typed[T]) GetOrLoad(ctx context.Context, key string, load func(ctx context.Context) (T, error)) (T, error) {
+	var zero T
+
+	value, err := t.Get(ctx, key)
+	if err == nil {
+		return value, nil
+	}
+	if errors.Is(err, ErrAbsent) {
+		return zero, ErrNotFound
+	}
+
+	value, err = load(ctx)
+	if errors.Is(err, ErrNotFound) {
+		_ = t.SetAbsent(ctx, key)
+		return zero, ErrNotFound
+	}
+	if err != nil {
+		return zero, err
+	}
+
+	_ = t.Set(ctx, key, value)
+	return value, nil
+}
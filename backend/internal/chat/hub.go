@@ -0,0 +1,123 @@
+package chat
+
+import (
+	"sort"
+	"sync"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "github.com/sahays/grpc-proto-go-flutter-template/proto/chat"
+)
+
+// sendBufferSize is how many events may queue for a client before it is
+// considered too slow and disconnected
+const sendBufferSize = 64
+
+// hub fans events out to the members of each room. It is in-memory, so
+// clients only see peers connected to the same server instance.
+type hub struct {
+	mu    sync.Mutex
+	rooms map[string]map[*client]struct{}
+}
+
+// client is one connected stream in a room
+type client struct {
+	user   string
+	events chan *pb.ChatEvent
+
+	// dropped is closed when the client falls behind and must disconnect
+	dropped  chan struct{}
+	dropOnce sync.Once
+}
+
+func newHub() *hub {
+	return &hub{rooms: make(map[string]map[*client]struct{})}
+}
+
+// join adds a client for user to room, queues a Joined event listing the
+// members already present, and announces the new member to them
+func (h *hub) join(room, user string) *client {
+	c := &client{
+		user:    user,
+		events:  make(chan *pb.ChatEvent, sendBufferSize),
+		dropped: make(chan struct{}),
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	members := h.rooms[room]
+	if members == nil {
+		members = make(map[*client]struct{})
+		h.rooms[room] = members
+	}
+
+	c.events <- newEvent(&pb.ChatEvent{Event: &pb.ChatEvent_Joined{
+		Joined: &pb.Joined{Room: room, Members: memberNames(members)},
+	}})
+
+	h.broadcastLocked(room, newEvent(&pb.ChatEvent{Event: &pb.ChatEvent_Presence{
+		Presence: &pb.PresenceChange{User: user, Online: true},
+	}}))
+
+	members[c] = struct{}{}
+	return c
+}
+
+// leave removes a client from room and announces its departure
+func (h *hub) leave(room string, c *client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	members := h.rooms[room]
+	if _, ok := members[c]; !ok {
+		return
+	}
+
+	delete(members, c)
+	if len(members) == 0 {
+		delete(h.rooms, room)
+		return
+	}
+
+	h.broadcastLocked(room, newEvent(&pb.ChatEvent{Event: &pb.ChatEvent_Presence{
+		Presence: &pb.PresenceChange{User: c.user, Online: false},
+	}}))
+}
+
+// say delivers a message from sender to everyone in room, sender included
+func (h *hub) say(room, sender, text string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.broadcastLocked(room, newEvent(&pb.ChatEvent{Event: &pb.ChatEvent_Message{
+		Message: &pb.ChatMessage{Sender: sender, Text: text},
+	}}))
+}
+
+// broadcastLocked queues event for every member of room without blocking.
+// A member whose queue is full is dropped rather than stalling the room.
+// h.mu must be held.
+func (h *hub) broadcastLocked(room string, event *pb.ChatEvent) {
+	for c := range h.rooms[room] {
+		select {
+		case c.events <- event:
+		default:
+			c.dropOnce.Do(func() { close(c.dropped) })
+		}
+	}
+}
+
+func memberNames(members map[*client]struct{}) []string {
+	names := make([]string, 0, len(members))
+	for c := range members {
+		names = append(names, c.user)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func newEvent(event *pb.ChatEvent) *pb.ChatEvent {
+	event.OccurredAt = timestamppb.Now()
+	return event
+}
@@ -0,0 +1,105 @@
+package chat
+
+import (
+	"io"
+	"sync"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/apierror"
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/middleware"
+	pb "github.com/sahays/grpc-proto-go-flutter-template/proto/chat"
+)
+
+// Service implements the ChatService gRPC service
+type Service struct {
+	pb.UnimplementedChatServiceServer
+	hub *hub
+
+	// done is closed on shutdown to end open streams
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewService creates a new chat service
+func NewService() *Service {
+	return &Service{
+		hub:  newHub(),
+		done: make(chan struct{}),
+	}
+}
+
+// Close ends all open chat streams so the server can stop gracefully
+func (s *Service) Close() {
+	s.closeOnce.Do(func() { close(s.done) })
+}
+
+// Chat runs one client's session: it waits for a join, then relays the
+// client's messages to the room and the room's events to the client
+func (s *Service) Chat(stream pb.ChatService_ChatServer) error {
+	ctx := stream.Context()
+
+	claims, ok := middleware.ClaimsFromContext(ctx)
+	if !ok {
+		return apierror.New(codes.Unauthenticated, apierror.ReasonUnauthenticated, "authentication required")
+	}
+
+	req, err := stream.Recv()
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	join := req.GetJoin()
+	if join == nil {
+		return apierror.New(codes.FailedPrecondition, apierror.ReasonNotJoined, "first message must join a room")
+	}
+
+	c := s.hub.join(join.Room, claims.Email)
+	defer s.hub.leave(join.Room, c)
+
+	// Receive on a separate goroutine so a slow client doesn't block the room.
+	// Recv returns once this handler exits, so the goroutine can't leak.
+	recvErr := make(chan error, 1)
+	go func() {
+		for {
+			req, err := stream.Recv()
+			if err != nil {
+				recvErr <- err
+				return
+			}
+
+			if req.GetJoin() != nil {
+				recvErr <- apierror.New(codes.FailedPrecondition, apierror.ReasonAlreadyJoined, "already joined a room")
+				return
+			}
+
+			s.hub.say(join.Room, c.user, req.GetMessage().GetText())
+		}
+	}()
+
+	for {
+		select {
+		case event := <-c.events:
+			// Send blocks under HTTP/2 flow control, which is what lets the
+			// queue fill up for clients that stop reading
+			if err := stream.Send(event); err != nil {
+				return err
+			}
+		case err := <-recvErr:
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		case <-c.dropped:
+			return apierror.New(codes.ResourceExhausted, apierror.ReasonSlowConsumer, "client is not keeping up with the room")
+		case <-s.done:
+			return apierror.New(codes.Unavailable, apierror.ReasonShuttingDown, "server is shutting down")
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		}
+	}
+}
@@ -0,0 +1,118 @@
+// Package clientip works out the address of the real client behind trusted
+// proxies such as the REST gateway or a load balancer
+package clientip
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"google.golang.org/grpc/metadata"
+)
+
+// Metadata keys proxies use to pass on the client address
+const (
+	ForwardedForHeader = "x-forwarded-for"
+	RealIPHeader       = "x-real-ip"
+)
+
+// Resolver resolves client addresses, believing forwarding metadata only
+// when it comes from a trusted proxy
+type Resolver struct {
+	trusted []*net.IPNet
+}
+
+// New creates a resolver trusting peers in the given IPs or CIDR ranges
+func New(trustedProxies []string) (*Resolver, error) {
+	trusted, err := ParseCIDRs(trustedProxies)
+	if err != nil {
+		return nil, err
+	}
+	return &Resolver{trusted: trusted}, nil
+}
+
+// Resolve returns the client address for a call from peer. When peer is a
+// trusted proxy, X-Forwarded-For is walked from the right (the entry added
+// by the nearest proxy), stopping at the first address no trusted proxy
+// vouches for; X-Real-IP is used if there is no X-Forwarded-For.
+func (r *Resolver) Resolve(peer net.IP, md metadata.MD) net.IP {
+	if peer == nil || !r.isTrusted(peer) {
+		return peer
+	}
+
+	var hops []string
+	for _, value := range md.Get(ForwardedForHeader) {
+		hops = append(hops, strings.Split(value, ",")...)
+	}
+
+	if len(hops) == 0 {
+		if values := md.Get(RealIPHeader); len(values) > 0 {
+			if ip := net.ParseIP(strings.TrimSpace(values[0])); ip != nil {
+				return ip
+			}
+		}
+		return peer
+	}
+
+	client := peer
+	for i := len(hops) - 1; i >= 0; i-- {
+		ip := net.ParseIP(strings.TrimSpace(hops[i]))
+		if ip == nil {
+			break
+		}
+		client = ip
+		if !r.isTrusted(ip) {
+			break
+		}
+	}
+
+	return client
+}
+
+func (r *Resolver) isTrusted(ip net.IP) bool {
+	return Contains(r.trusted, ip)
+}
+
+// Contains reports whether any of nets contains ip
+func Contains(nets []*net.IPNet, ip net.IP) bool {
+	for _, n := range nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseCIDRs parses IPs and CIDR ranges; bare IPs become single-address ranges
+func ParseCIDRs(values []string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(values))
+	for _, value := range values {
+		ipNet, err := ParseCIDR(value)
+		if err != nil {
+			return nil, err
+		}
+		nets = append(nets, ipNet)
+	}
+	return nets, nil
+}
+
+// ParseCIDR parses an IP or CIDR range into its normalized network
+func ParseCIDR(value string) (*net.IPNet, error) {
+	value = strings.TrimSpace(value)
+	if !strings.Contains(value, "/") {
+		ip := net.ParseIP(value)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid IP address %q", value)
+		}
+		if v4 := ip.To4(); v4 != nil {
+			return &net.IPNet{IP: v4, Mask: net.CIDRMask(32, 32)}, nil
+		}
+		return &net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)}, nil
+	}
+
+	_, ipNet, err := net.ParseCIDR(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CIDR %q", value)
+	}
+	return ipNet, nil
+}
@@ -2,7 +2,9 @@ package config
 
 import (
 	"fmt"
+	"net"
 	"os"
+	"runtime"
 	"strconv"
 	"time"
 
@@ -11,181 +13,1022 @@ import (
 
 // Config holds all configuration for the application
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	Redis    RedisConfig
-	JWT      JWTConfig
-	Argon2   Argon2Config
-	RateLimit RateLimitConfig
-	BotDetection BotDetectionConfig
-	CORS     CORSConfig
-	Environment EnvironmentConfig
-	Monitoring MonitoringConfig
-	Security SecurityConfig
+	Server       ServerConfig       `yaml:"server"`
+	Database     DatabaseConfig     `yaml:"database"`
+	Redis        RedisConfig        `yaml:"redis"`
+	Cache        CacheConfig        `yaml:"cache"`
+	JWT          JWTConfig          `yaml:"jwt"`
+	Argon2       Argon2Config       `yaml:"argon2"`
+	Password     PasswordConfig     `yaml:"password"`
+	RateLimit    RateLimitConfig    `yaml:"rate_limit"`
+	Idempotency  IdempotencyConfig  `yaml:"idempotency"`
+	IPFilter     IPFilterConfig     `yaml:"ip_filter"`
+	LoadShed     LoadShedConfig     `yaml:"load_shed"`
+	BotDetection BotDetectionConfig `yaml:"bot_detection"`
+	CORS         CORSConfig         `yaml:"cors"`
+	Environment  EnvironmentConfig  `yaml:",inline"`
+	Monitoring   MonitoringConfig   `yaml:"monitoring"`
+	Tracing      TracingConfig      `yaml:"tracing"`
+	Security     SecurityConfig     `yaml:"security"`
+	Audit        AuditConfig        `yaml:"audit"`
+	XDS          XDSConfig          `yaml:"xds"`
+	StartupRetry StartupRetryConfig `yaml:"startup_retry"`
+	Outbox       OutboxConfig       `yaml:"outbox"`
+	Tenancy      TenancyConfig      `yaml:"tenancy"`
+	UserCache    UserCacheConfig    `yaml:"user_cache"`
+	Embedded     EmbeddedConfig     `yaml:"embedded"`
+	Encryption   EncryptionConfig   `yaml:"encryption"`
+	Vault        VaultConfig        `yaml:"vault"`
 }
 
 type ServerConfig struct {
-	Port string
-	Host string
+	Port string `yaml:"port"`
+	Host string `yaml:"host"`
+	// GatewayEnabled serves the REST/JSON gateway on GatewayPort
+	GatewayEnabled bool   `yaml:"gateway_enabled"`
+	GatewayPort    string `yaml:"gateway_port"`
+	// OpenAPIEnabled serves the generated OpenAPI document on the gateway port
+	OpenAPIEnabled   bool `yaml:"openapi_enabled"`
+	SwaggerUIEnabled bool `yaml:"swagger_ui_enabled"`
+	// JWKSEnabled publishes the JWT public keys at /.well-known/jwks.json
+	// on the gateway port
+	JWKSEnabled bool `yaml:"jwks_enabled"`
+	// GRPCWebEnabled serves gRPC-Web on GRPCWebPort for browser clients
+	GRPCWebEnabled bool   `yaml:"grpc_web_enabled"`
+	GRPCWebPort    string `yaml:"grpc_web_port"`
+	// ConnectEnabled serves the Connect, gRPC, and gRPC-Web protocols over
+	// HTTP/1.1 and h2c on ConnectPort
+	ConnectEnabled bool   `yaml:"connect_enabled"`
+	ConnectPort    string `yaml:"connect_port"`
+	// DefaultRPCTimeout applies to calls without a client deadline;
+	// MethodTimeouts overrides it per full method name
+	DefaultRPCTimeout time.Duration            `yaml:"default_rpc_timeout"`
+	MaxRPCTimeout     time.Duration            `yaml:"max_rpc_timeout"`
+	MethodTimeouts    map[string]time.Duration `yaml:"method_timeouts"`
+	// Keepalive pings detect dead connections (e.g., mobile clients behind NAT)
+	KeepaliveTime     time.Duration `yaml:"keepalive_time"`
+	KeepaliveTimeout  time.Duration `yaml:"keepalive_timeout"`
+	MaxConnectionIdle time.Duration `yaml:"max_connection_idle"`
+	// MaxConnectionAge cycles connections (with jitter) so clients reconnect
+	// and rebalance across instances; in-flight RPCs get MaxConnectionAgeGrace
+	// to finish. 0 disables each.
+	MaxConnectionAge      time.Duration `yaml:"max_connection_age"`
+	MaxConnectionAgeGrace time.Duration `yaml:"max_connection_age_grace"`
+	// Enforcement policy: clients pinging more often than KeepaliveMinTime are disconnected
+	KeepaliveMinTime             time.Duration `yaml:"keepalive_min_time"`
+	KeepalivePermitWithoutStream bool          `yaml:"keepalive_permit_without_stream"`
+	MaxRecvMsgSize               int           `yaml:"max_recv_msg_size"`
+	MaxSendMsgSize               int           `yaml:"max_send_msg_size"`
+	// CompressionMode is off, prefer (responses >= CompressionMinSize), or always
+	CompressionMode    string `yaml:"compression_mode"`
+	CompressionMinSize int    `yaml:"compression_min_size"`
+	// ReflectionEnabled registers server reflection (on by default outside
+	// production); ReflectionRequireAdmin limits it to admin tokens
+	ReflectionEnabled      bool `yaml:"reflection_enabled"`
+	ReflectionRequireAdmin bool `yaml:"reflection_require_admin"`
 }
 
 type DatabaseConfig struct {
-	Host            string
-	Port            string
-	User            string
-	Password        string
-	DBName          string
-	SSLMode         string
-	MaxOpenConns    int
-	MaxIdleConns    int
-	ConnMaxLifetime time.Duration
+	Host         string `yaml:"host"`
+	Port         string `yaml:"port"`
+	User         string `yaml:"user"`
+	Password     string `yaml:"password"`
+	DBName       string `yaml:"name"`
+	SSLMode      string `yaml:"ssl_mode"`
+	MaxOpenConns int    `yaml:"max_open_conns"`
+	// MinConns is how many connections the pool keeps open while idle
+	MinConns        int           `yaml:"min_conns"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime"`
+	// ConnMaxIdleTime closes connections above MinConns that sit unused
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time"`
+	// AutoMigrate applies pending embedded migrations at startup. It is off
+	// by default in production, where operators run "server migrate"
+	// deliberately.
+	AutoMigrate bool `yaml:"auto_migrate"`
+	// UUIDv7 generates new user IDs as time-ordered UUIDv7s instead of
+	// random UUIDv4s
+	UUIDv7 bool `yaml:"uuidv7"`
+	// PrepareStatements prepares hot statements, such as the login lookups,
+	// on every connection as soon as it opens (see db.Prepare)
+	PrepareStatements bool `yaml:"prepare_statements"`
+	// Seed fills an empty database with demo data at startup (see "server
+	// seed"). It is rejected in production.
+	Seed bool `yaml:"seed"`
+	// Replicas are read replica addresses as host or host:port. They share
+	// the primary's credentials, database name and pool settings.
+	Replicas []string `yaml:"replicas"`
+	// ReplicaMaxLag is how far behind the primary a replica may fall before
+	// reads go back to the primary
+	ReplicaMaxLag time.Duration `yaml:"replica_max_lag"`
+	// ReplicaCheckInterval is how often replica lag is measured
+	ReplicaCheckInterval time.Duration `yaml:"replica_check_interval"`
+	// ReadRetries is how many times a read-only query that failed
+	// transiently, such as on a lost connection, is retried; 0 disables
+	ReadRetries int `yaml:"read_retries"`
+	// SlowQueryThreshold logs queries that take at least this long, with
+	// their SQL but not their arguments; 0 disables the log
+	SlowQueryThreshold time.Duration `yaml:"slow_query_threshold"`
 }
 
 type RedisConfig struct {
-	Host        string
-	Port        string
-	Password    string
-	DB          int
-	MaxRetries  int
-	PoolSize    int
+	Host       string `yaml:"host"`
+	Port       string `yaml:"port"`
+	Password   string `yaml:"password"`
+	DB         int    `yaml:"db"`
+	MaxRetries int    `yaml:"max_retries"`
+	PoolSize   int    `yaml:"pool_size"`
+	// SentinelAddrs are Redis Sentinel addresses as host:port. When set,
+	// the current master of SentinelMaster is found through them instead of
+	// connecting to Host:Port, and clients follow it across failovers.
+	SentinelAddrs  []string `yaml:"sentinel_addrs"`
+	SentinelMaster string   `yaml:"sentinel_master"`
+	// SentinelPassword authenticates to the sentinels, if they require it;
+	// Password is still used for the master
+	SentinelPassword string `yaml:"sentinel_password"`
+	// URL is a redis:// or rediss:// (TLS) URL, as given by managed Redis
+	// services. It replaces Host, Port, Username, Password and DB.
+	URL string `yaml:"url"`
+	// Username is the ACL user to authenticate as with Password; empty
+	// authenticates as the default user
+	Username string `yaml:"username"`
+	// TLSEnabled connects over TLS, verifying the server against the system
+	// roots or TLSCAFile. A rediss:// URL enables it too.
+	TLSEnabled bool   `yaml:"tls_enabled"`
+	TLSCAFile  string `yaml:"tls_ca_file"`
+	// TLSCertFile and TLSKeyFile are a client certificate, for servers that
+	// require mutual TLS
+	TLSCertFile string `yaml:"tls_cert_file"`
+	TLSKeyFile  string `yaml:"tls_key_file"`
+	// TLSServerName overrides the host name the server certificate must
+	// match
+	TLSServerName string `yaml:"tls_server_name"`
+	// Fallback is what happens to rate limit and login attempt counters
+	// while Redis fails: "local" counts them in process, in at most
+	// FallbackMaxEntries counters; "none" leaves it to
+	// SecurityConfig.CacheOutage
+	Fallback           string `yaml:"fallback"`
+	FallbackMaxEntries int    `yaml:"fallback_max_entries"`
 }
 
+// CacheConfig selects where tokens, counters and pub/sub live
+type CacheConfig struct {
+	// Backend is "redis", shared by every instance, or "memory", in
+	// process, for tests and single-instance deployments without Redis
+	Backend string `yaml:"backend"`
+	// KeyPrefix is put before every key, channel and stream name, such as
+	// "prod:auth:", so environments can share a Redis
+	KeyPrefix string `yaml:"key_prefix"`
+}
+
+// MinJWTSecretLength is the shortest HS256 JWT_SECRET accepted: the size of
+// the SHA-256 output, as RFC 7518 section 3.2 requires
+const MinJWTSecretLength = 32
+
+// MinRSAKeyBits is the smallest RSA key accepted for signing or verifying
+// tokens, as NIST SP 800-57 requires past 2030 (RFC 7518 section 3.3 asks
+// for at least 2048 bits)
+const MinRSAKeyBits = 2048
+
+// Refresh token formats
+const (
+	// RefreshTokenJWT issues refresh tokens signed like access tokens, as
+	// JWTs or PASETO tokens
+	RefreshTokenJWT = "jwt"
+	// RefreshTokenOpaque issues random strings, stored only as a hash
+	RefreshTokenOpaque = "opaque"
+)
+
+// Token formats
+const (
+	// TokenFormatJWT issues JWTs, whose alg header names the algorithm
+	TokenFormatJWT = "jwt"
+	// TokenFormatPASETO issues PASETO v4.public tokens, whose only algorithm
+	// is Ed25519
+	TokenFormatPASETO = "paseto"
+)
+
+// KMS providers a JWT signing key can be held in
+const (
+	KMSProviderAWS = "aws"
+	KMSProviderGCP = "gcp"
+	// KMSProviderVault is HashiCorp Vault's transit engine; see VaultConfig
+	KMSProviderVault = "vault"
+)
+
 type JWTConfig struct {
-	AccessTokenExpiry  time.Duration
-	RefreshTokenExpiry time.Duration
-	Issuer             string
-	PrivateKeyPath     string
-	PublicKeyPath      string
+	// Format is TokenFormatJWT or TokenFormatPASETO
+	Format             string        `yaml:"format"`
+	AccessTokenExpiry  time.Duration `yaml:"access_token_expiry"`
+	RefreshTokenExpiry time.Duration `yaml:"refresh_token_expiry"`
+	// RefreshTokenFormat is RefreshTokenJWT or RefreshTokenOpaque
+	RefreshTokenFormat string `yaml:"refresh_token_format"`
+	// Leeway is how far exp and nbf may be off when tokens are validated,
+	// to absorb clock skew between the hosts that issue and check them
+	Leeway time.Duration `yaml:"leeway"`
+	Issuer string        `yaml:"issuer"`
+	// Audiences go in the aud claim of issued tokens, naming the APIs they
+	// are for. When set, validation rejects tokens that name none of them.
+	Audiences []string `yaml:"audiences"`
+	// Algorithm is the signing algorithm: RS256, ES256, EdDSA or HS256. The
+	// signing key must be of its type.
+	Algorithm string `yaml:"algorithm"`
+	// RSAKeyBits is the size of RSA keys generated in memory or by keygen:
+	// 2048, 3072 or 4096
+	RSAKeyBits     int    `yaml:"rsa_key_bits"`
+	PrivateKeyPath string `yaml:"private_key_path"`
+	PublicKeyPath  string `yaml:"public_key_path"`
+	// Secret is the shared HMAC key of HS256, which both signs and verifies,
+	// for deployments where no other service verifies tokens
+	Secret string `yaml:"secret"`
+	// Keys are "kid:path" entries of PEM key files, oldest first, replacing
+	// PrivateKeyPath and PublicKeyPath. The newest signs new tokens, so it
+	// must be a private key; the others only verify tokens they signed, so
+	// they may be public keys.
+	Keys []string `yaml:"keys"`
+	// KMSProvider is KMSProviderAWS, KMSProviderGCP or KMSProviderVault to
+	// sign with KMSKey, whose private half never leaves the KMS; empty to
+	// sign with a local key. JWT_KEYS then only verifies tokens signed before.
+	KMSProvider string `yaml:"kms_provider"`
+	// KMSKey is the AWS key ID or ARN, or the GCP CryptoKeyVersion resource
+	// name, of an RSA or P-256 signing key, or the name of a Vault transit
+	// key, which may also be Ed25519
+	KMSKey string `yaml:"kms_key"`
+	// EncryptionKeys are "id:base64key" entries of 32-byte keys. When set,
+	// signed tokens are encrypted as JWEs with EncryptionActiveKey, so their
+	// claims can't be read off a device. Keep retired keys until tokens
+	// encrypted with them have expired.
+	EncryptionKeys []string `yaml:"encryption_keys"`
+	// EncryptionActiveKey names the key new tokens are encrypted with
+	EncryptionActiveKey string `yaml:"encryption_active_key"`
 }
 
 type Argon2Config struct {
-	Memory      uint32
-	Iterations  uint32
-	Parallelism uint8
-	SaltLength  uint32
-	KeyLength   uint32
+	Memory      uint32 `yaml:"memory"`
+	Iterations  uint32 `yaml:"iterations"`
+	Parallelism uint8  `yaml:"parallelism"`
+	SaltLength  uint32 `yaml:"salt_length"`
+	KeyLength   uint32 `yaml:"key_length"`
+}
+
+// PasswordConfig holds the peppers mixed into password hashes and the
+// policy new passwords must meet (see pkg/password)
+type PasswordConfig struct {
+	// Peppers are "id:base64pepper" entries of secrets of at least 32 bytes,
+	// kept out of the database so a leaked hash can't be cracked without
+	// them. Keep retired peppers until every hash made with them has been
+	// re-hashed, at each user's next login.
+	Peppers []string `yaml:"peppers"`
+	// ActivePepper names the pepper new hashes are made with
+	ActivePepper string `yaml:"active_pepper"`
+	// MinLength and MaxLength bound new passwords, in characters. auth.proto
+	// also declares 8 to 128, which requests are checked against first.
+	MinLength int `yaml:"min_length"`
+	MaxLength int `yaml:"max_length"`
+	// MinScore is the lowest strength score, 0 to 4, new passwords may have
+	MinScore int `yaml:"min_score"`
+	// BlockCommon rejects new passwords that are, or nearly are, common
+	// passwords: the embedded list, and those in BlocklistPath
+	BlockCommon bool `yaml:"block_common"`
+	// BlocklistPath is a file of more passwords to reject, one per line,
+	// such as a top-100k list of leaked passwords
+	BlocklistPath string `yaml:"blocklist_path"`
 }
 
 type RateLimitConfig struct {
-	Public        int
-	Authenticated int
-	Window        time.Duration
+	Public        int           `yaml:"public"`
+	Authenticated int           `yaml:"authenticated"`
+	Window        time.Duration `yaml:"window"`
+}
+
+// IdempotencyConfig lists the methods that honor an idempotency-key header
+type IdempotencyConfig struct {
+	// Methods are full gRPC method names whose responses are replayed to
+	// retries carrying the same key
+	Methods []string `yaml:"methods"`
+	// TTL is how long a key and its response are kept
+	TTL time.Duration `yaml:"ttl"`
+}
+
+// IPFilterConfig holds CIDR allow/deny lists. Entries may be bare IPs.
+type IPFilterConfig struct {
+	// Allowlist, when non-empty, rejects every client outside it
+	Allowlist []string `yaml:"allowlist"`
+	// Denylist is the static denylist; admins add dynamic entries at runtime
+	Denylist []string `yaml:"denylist"`
+	// RefreshInterval bounds how long an expired dynamic entry keeps applying
+	// and how stale an instance gets if it misses a change notification
+	RefreshInterval time.Duration `yaml:"refresh_interval"`
+}
+
+// LoadShedConfig bounds concurrent unary RPCs. The global limit adapts
+// between MinLimit and MaxLimit: it shrinks when requests take longer than
+// TargetLatency and grows slowly while they don't.
+type LoadShedConfig struct {
+	Enabled       bool          `yaml:"enabled"`
+	InitialLimit  int           `yaml:"initial_limit"`
+	MinLimit      int           `yaml:"min_limit"`
+	MaxLimit      int           `yaml:"max_limit"`
+	TargetLatency time.Duration `yaml:"target_latency"`
+	// Requests over the limit wait up to QueueTimeout in a queue of at most
+	// MaxQueue before being rejected
+	MaxQueue     int           `yaml:"max_queue"`
+	QueueTimeout time.Duration `yaml:"queue_timeout"`
+	// RetryAfter is the back-off hint sent with rejections
+	RetryAfter time.Duration `yaml:"retry_after"`
+	// MethodLimits caps individual expensive methods (e.g. Argon2 hashing)
+	// regardless of the global limit
+	MethodLimits map[string]int `yaml:"method_limits"`
+}
+
+// XDSConfig controls the xDS-managed server mode. The control plane is
+// located through the standard GRPC_XDS_BOOTSTRAP (file path) or
+// GRPC_XDS_BOOTSTRAP_CONFIG (inline JSON) variables.
+type XDSConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// LoadReportInterval is the shortest interval at which ORCA load reports
+	// are streamed to clients that ask for them
+	LoadReportInterval time.Duration `yaml:"load_report_interval"`
+}
+
+// StartupRetryConfig controls how long startup waits for PostgreSQL and Redis
+// to accept connections. The wait between attempts doubles from
+// InitialBackoff up to MaxBackoff, with jitter.
+type StartupRetryConfig struct {
+	// MaxAttempts is the number of connection attempts; 1 disables retries
+	MaxAttempts    int           `yaml:"max_attempts"`
+	InitialBackoff time.Duration `yaml:"initial_backoff"`
+	MaxBackoff     time.Duration `yaml:"max_backoff"`
+}
+
+// AuditConfig controls the audit log of mutating RPCs
+type AuditConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// BufferSize is how many entries may wait to be written before new ones
+	// are dropped
+	BufferSize int `yaml:"buffer_size"`
+	// RedactFields are proto field names masked in recorded payloads, on top
+	// of fields marked debug_redact in the proto
+	RedactFields []string `yaml:"redact_fields"`
+}
+
+// OutboxConfig controls delivery of events recorded in the outbox table.
+// Events are delivered at least once, so consumers should deduplicate on the
+// event ID.
+type OutboxConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Publisher is where events are delivered: "redis" (a Redis stream) or
+	// "webhook" (an HTTP POST per event)
+	Publisher string `yaml:"publisher"`
+	// RedisStream is the stream events are added to, trimmed to about
+	// RedisStreamMaxLen entries
+	RedisStream       string `yaml:"redis_stream"`
+	RedisStreamMaxLen int    `yaml:"redis_stream_max_len"`
+	WebhookURL        string `yaml:"webhook_url"`
+	// WebhookSecret signs webhook bodies (HMAC-SHA256) when set
+	WebhookSecret  string        `yaml:"webhook_secret"`
+	WebhookTimeout time.Duration `yaml:"webhook_timeout"`
+	// PollInterval is how often the relay looks for undelivered events
+	PollInterval time.Duration `yaml:"poll_interval"`
+	// BatchSize is the most events delivered per poll
+	BatchSize int `yaml:"batch_size"`
+	// Retention is how long delivered events are kept before being deleted
+	Retention time.Duration `yaml:"retention"`
+}
+
+// TenancyConfig controls multi-tenancy. Disabled, every call runs in the
+// default tenant and x-tenant is ignored.
+type TenancyConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// CacheTTL is how long resolved tenants are cached per instance, and so
+	// how long a deactivation takes to reach an instance that misses the
+	// event bus announcement
+	CacheTTL time.Duration `yaml:"cache_ttl"`
+}
+
+// UserCacheConfig controls the in-memory cache of user lookups, and the
+// shared cache behind it. Entries are invalidated on every instance through
+// PostgreSQL LISTEN/NOTIFY.
+type UserCacheConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// TTL bounds how long an entry is kept even if no change is announced
+	TTL        time.Duration `yaml:"ttl"`
+	MaxEntries int           `yaml:"max_entries"`
+	// SharedTTL is how long users are kept in the shared cache; zero keeps
+	// them in memory only
+	SharedTTL time.Duration `yaml:"shared_ttl"`
+}
+
+// EncryptionConfig holds the keys that encrypt personal data at rest (see
+// pkg/crypto)
+type EncryptionConfig struct {
+	// Keys are "id:base64key" entries of 32-byte AES-256 keys. Keep retired
+	// keys until "server rotate-keys" has moved every value off them.
+	Keys []string `yaml:"keys"`
+	// ActiveKeyID names the key new values are encrypted with
+	ActiveKeyID string `yaml:"active_key_id"`
+}
+
+// VaultConfig is the HashiCorp Vault server whose transit engine signs
+// tokens with JWT_KMS_PROVIDER=vault
+type VaultConfig struct {
+	Address string `yaml:"address"`
+	Token   string `yaml:"token"`
+	// TransitMount is the path the transit engine is mounted at
+	TransitMount string `yaml:"transit_mount"`
+	// PublicKeyCacheTTL is how long the transit key's public keys are
+	// cached before Vault is asked again for versions it has since rotated
+	// to
+	PublicKeyCacheTTL time.Duration `yaml:"public_key_cache_ttl"`
+}
+
+// EmbeddedConfig starts PostgreSQL and Redis along with the server, at the
+// configured addresses, for development without Docker
+type EmbeddedConfig struct {
+	Postgres bool `yaml:"postgres"`
+	Redis    bool `yaml:"redis"`
+	// DataDir holds the embedded PostgreSQL data between runs
+	DataDir string `yaml:"data_dir"`
 }
 
 type BotDetectionConfig struct {
-	Enabled         bool
-	Threshold       int
-	IPReputationTTL time.Duration
+	Enabled         bool          `yaml:"enabled"`
+	Threshold       int           `yaml:"threshold"`
+	IPReputationTTL time.Duration `yaml:"ip_reputation_ttl"`
 }
 
 type CORSConfig struct {
-	AllowedOrigins []string
-	AllowedMethods []string
-	AllowedHeaders []string
+	AllowedOrigins []string `yaml:"allowed_origins"`
+	AllowedMethods []string `yaml:"allowed_methods"`
+	AllowedHeaders []string `yaml:"allowed_headers"`
 }
 
 type EnvironmentConfig struct {
-	Environment string
-	LogLevel    string
-	LogFormat   string
+	Environment string `yaml:"environment"`
+	LogLevel    string `yaml:"log_level"`
+	LogFormat   string `yaml:"log_format"`
+	// LogPayloads adds request and response bodies to request logs, with
+	// sensitive fields redacted. Development only.
+	LogPayloads bool `yaml:"log_payloads"`
+	// LogRedactFields are proto field names masked in logged payloads, on top
+	// of fields marked debug_redact in the proto
+	LogRedactFields []string `yaml:"log_redact_fields"`
 }
 
 type MonitoringConfig struct {
-	MetricsEnabled     bool
-	MetricsPort        string
-	HealthCheckEnabled bool
+	MetricsEnabled bool   `yaml:"metrics_enabled"`
+	MetricsPort    string `yaml:"metrics_port"`
+	// PoolSampleInterval is how often connection pool statistics are
+	// copied into the metrics
+	PoolSampleInterval time.Duration `yaml:"pool_sample_interval"`
+	HealthCheckEnabled bool          `yaml:"health_check_enabled"`
+	// ChannelzEnabled serves channelz and reflection on a separate,
+	// unauthenticated admin gRPC listener at ChannelzHost:ChannelzPort
+	ChannelzEnabled bool   `yaml:"channelz_enabled"`
+	ChannelzHost    string `yaml:"channelz_host"`
+	ChannelzPort    string `yaml:"channelz_port"`
+	// PprofEnabled serves net/http/pprof at PprofHost:PprofPort
+	PprofEnabled bool   `yaml:"pprof_enabled"`
+	PprofHost    string `yaml:"pprof_host"`
+	PprofPort    string `yaml:"pprof_port"`
+}
+
+type TracingConfig struct {
+	Enabled      bool    `yaml:"enabled"`
+	ServiceName  string  `yaml:"service_name"`
+	OTLPEndpoint string  `yaml:"otlp_endpoint"`
+	Insecure     bool    `yaml:"insecure"`
+	SampleRatio  float64 `yaml:"sample_ratio"`
 }
 
 type SecurityConfig struct {
-	BCryptCost        int
-	SessionTimeout    time.Duration
-	MaxLoginAttempts  int
-	LockoutDuration   time.Duration
-	ShutdownTimeout   time.Duration
+	BCryptCost       int           `yaml:"bcrypt_cost"`
+	SessionTimeout   time.Duration `yaml:"session_timeout"`
+	MaxLoginAttempts int           `yaml:"max_login_attempts"`
+	LockoutDuration  time.Duration `yaml:"lockout_duration"`
+	ShutdownTimeout  time.Duration `yaml:"shutdown_timeout"`
+	// RequireEmailVerification blocks Login until the user verifies their email
+	RequireEmailVerification bool `yaml:"require_email_verification"`
+	// PublicMethods are full gRPC method names that skip authentication
+	PublicMethods []string `yaml:"public_methods"`
+	// TrustedProxies are peers (e.g. the REST gateway, a load balancer) whose
+	// X-Forwarded-For and X-Real-IP metadata is believed
+	TrustedProxies []string `yaml:"trusted_proxies"`
+	// AuthzModelPath and AuthzPolicyPath override the built-in Casbin
+	// authorization model and policy (see internal/authz/policy)
+	AuthzModelPath  string `yaml:"authz_model_path"`
+	AuthzPolicyPath string `yaml:"authz_policy_path"`
+	// CacheOutage is what each cache-backed check does when the cache fails
+	CacheOutage CacheOutageConfig `yaml:"cache_outage"`
 }
 
-// Load reads configuration from environment variables
-func Load() (*Config, error) {
+// Cache outage modes of a check (see CacheOutageConfig)
+const (
+	// FailOpen skips the check, so the call goes ahead
+	FailOpen = "open"
+	// FailClosed rejects the call as UNAVAILABLE
+	FailClosed = "closed"
+)
+
+// CacheOutageConfig sets whether each check that reads the cache fails open
+// or closed when the cache can't be reached
+type CacheOutageConfig struct {
+	// RateLimit covers the per-caller API rate limit
+	RateLimit string `yaml:"rate_limit"`
+	// LoginAttempts covers the failed login attempt limit
+	LoginAttempts string `yaml:"login_attempts"`
+	// TokenRevocation covers the check that an access token wasn't revoked
+	// and its session wasn't signed out, made on every authenticated call
+	TokenRevocation string `yaml:"token_revocation"`
+	// RefreshTokens covers reading the session of a refresh token
+	RefreshTokens string `yaml:"refresh_tokens"`
+	// Idempotency covers idempotency keys
+	Idempotency string `yaml:"idempotency"`
+}
+
+// Load reads configuration from environment variables, with defaults from
+// configFile if it isn't empty
+func Load(configFile string) (*Config, error) {
 	// Load .env file if it exists (for local development)
 	_ = godotenv.Load()
 
-	cfg := &Config{
+	// Built-in defaults, or those of the config file
+	defaults := defaultConfig(getEnv("ENVIRONMENT", "development"))
+	if configFile != "" {
+		var err error
+		if defaults, err = loadFile(configFile); err != nil {
+			return nil, err
+		}
+	}
+
+	cfg := loadEnv(defaults)
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("configuration validation failed: %w", err)
+	}
+
+	return cfg, nil
+}
+
+// defaultConfig returns the built-in configuration for environment, which
+// decides defaults such as whether reflection is served
+func defaultConfig(environment string) *Config {
+	return &Config{
 		Server: ServerConfig{
-			Port: getEnv("SERVER_PORT", "50051"),
-			Host: getEnv("SERVER_HOST", "0.0.0.0"),
+			Port:               "50051",
+			Host:               "0.0.0.0",
+			GatewayEnabled:     true,
+			GatewayPort:        "8081",
+			OpenAPIEnabled:     true,
+			JWKSEnabled:        true,
+			GRPCWebPort:        "8080",
+			ConnectPort:        "8082",
+			DefaultRPCTimeout:  10 * time.Second,
+			MaxRPCTimeout:      30 * time.Second,
+			MethodTimeouts:     map[string]time.Duration{},
+			KeepaliveTime:      2 * time.Hour,
+			KeepaliveTimeout:   20 * time.Second,
+			KeepaliveMinTime:   5 * time.Minute,
+			MaxRecvMsgSize:     4 * 1024 * 1024,
+			MaxSendMsgSize:     4 * 1024 * 1024,
+			CompressionMode:    "prefer",
+			CompressionMinSize: 1024,
+			ReflectionEnabled:  environment != "production",
 		},
 		Database: DatabaseConfig{
-			Host:            getEnv("DB_HOST", "localhost"),
-			Port:            getEnv("DB_PORT", "5432"),
-			User:            getEnv("DB_USER", "postgres"),
-			Password:        getEnv("DB_PASSWORD", "postgres"),
-			DBName:          getEnv("DB_NAME", "saas_db"),
-			SSLMode:         getEnv("DB_SSL_MODE", "disable"),
-			MaxOpenConns:    getEnvAsInt("DB_MAX_OPEN_CONNS", 25),
-			MaxIdleConns:    getEnvAsInt("DB_MAX_IDLE_CONNS", 10),
-			ConnMaxLifetime: getEnvAsDuration("DB_CONN_MAX_LIFETIME", 5*time.Minute),
+			Host:                 "localhost",
+			Port:                 "5432",
+			User:                 "postgres",
+			Password:             "postgres",
+			DBName:               "saas_db",
+			SSLMode:              "disable",
+			MaxOpenConns:         25,
+			MinConns:             2,
+			ConnMaxLifetime:      5 * time.Minute,
+			ConnMaxIdleTime:      30 * time.Minute,
+			AutoMigrate:          environment != "production",
+			PrepareStatements:    true,
+			ReplicaMaxLag:        5 * time.Second,
+			ReplicaCheckInterval: 5 * time.Second,
+			ReadRetries:          2,
+			SlowQueryThreshold:   200 * time.Millisecond,
 		},
 		Redis: RedisConfig{
-			Host:       getEnv("REDIS_HOST", "localhost"),
-			Port:       getEnv("REDIS_PORT", "6379"),
-			Password:   getEnv("REDIS_PASSWORD", ""),
-			DB:         getEnvAsInt("REDIS_DB", 0),
-			MaxRetries: getEnvAsInt("REDIS_MAX_RETRIES", 3),
-			PoolSize:   getEnvAsInt("REDIS_POOL_SIZE", 10),
+			Host:               "localhost",
+			Port:               "6379",
+			MaxRetries:         3,
+			PoolSize:           10,
+			Fallback:           "local",
+			FallbackMaxEntries: 100000,
+		},
+		Cache: CacheConfig{
+			Backend: "redis",
 		},
 		JWT: JWTConfig{
-			AccessTokenExpiry:  getEnvAsDuration("JWT_ACCESS_TOKEN_EXPIRY", 15*time.Minute),
-			RefreshTokenExpiry: getEnvAsDuration("JWT_REFRESH_TOKEN_EXPIRY", 168*time.Hour),
-			Issuer:             getEnv("JWT_ISSUER", "saas-platform"),
-			PrivateKeyPath:     getEnv("JWT_PRIVATE_KEY_PATH", ""),
-			PublicKeyPath:      getEnv("JWT_PUBLIC_KEY_PATH", ""),
+			Format:             TokenFormatJWT,
+			AccessTokenExpiry:  15 * time.Minute,
+			RefreshTokenExpiry: 168 * time.Hour,
+			RefreshTokenFormat: RefreshTokenJWT,
+			Issuer:             "saas-platform",
+			Algorithm:          "RS256",
+			RSAKeyBits:         MinRSAKeyBits,
 		},
 		Argon2: Argon2Config{
-			Memory:      uint32(getEnvAsInt("ARGON2_MEMORY", 65536)),
-			Iterations:  uint32(getEnvAsInt("ARGON2_ITERATIONS", 3)),
-			Parallelism: uint8(getEnvAsInt("ARGON2_PARALLELISM", 2)),
-			SaltLength:  uint32(getEnvAsInt("ARGON2_SALT_LENGTH", 16)),
-			KeyLength:   uint32(getEnvAsInt("ARGON2_KEY_LENGTH", 32)),
+			Memory:      65536,
+			Iterations:  3,
+			Parallelism: 2,
+			SaltLength:  16,
+			KeyLength:   32,
+		},
+		Password: PasswordConfig{
+			MinLength:   8,
+			MaxLength:   128,
+			MinScore:    3,
+			BlockCommon: true,
 		},
 		RateLimit: RateLimitConfig{
-			Public:        getEnvAsInt("RATE_LIMIT_PUBLIC", 5),
-			Authenticated: getEnvAsInt("RATE_LIMIT_AUTHENTICATED", 100),
-			Window:        getEnvAsDuration("RATE_LIMIT_WINDOW", 1*time.Minute),
+			Public:        5,
+			Authenticated: 100,
+			Window:        1 * time.Minute,
+		},
+		Idempotency: IdempotencyConfig{
+			Methods: []string{
+				"/auth.AuthService/SignUp",
+				"/auth.AuthService/ForgotPassword",
+				"/auth.AuthService/ResetPassword",
+			},
+			TTL: 24 * time.Hour,
+		},
+		IPFilter: IPFilterConfig{
+			RefreshInterval: 30 * time.Second,
+		},
+		LoadShed: LoadShedConfig{
+			Enabled:       true,
+			InitialLimit:  100,
+			MinLimit:      10,
+			MaxLimit:      1000,
+			TargetLatency: 500 * time.Millisecond,
+			MaxQueue:      100,
+			QueueTimeout:  100 * time.Millisecond,
+			RetryAfter:    1 * time.Second,
+			MethodLimits: map[string]int{
+				"/auth.AuthService/Login":          2 * runtime.NumCPU(),
+				"/auth.AuthService/SignUp":         2 * runtime.NumCPU(),
+				"/auth.AuthService/ChangePassword": 2 * runtime.NumCPU(),
+				"/auth.AuthService/ResetPassword":  2 * runtime.NumCPU(),
+			},
 		},
 		BotDetection: BotDetectionConfig{
-			Enabled:         getEnvAsBool("BOT_DETECTION_ENABLED", true),
-			Threshold:       getEnvAsInt("BOT_DETECTION_THRESHOLD", 10),
-			IPReputationTTL: getEnvAsDuration("IP_REPUTATION_TTL", 24*time.Hour),
+			Enabled:         true,
+			Threshold:       10,
+			IPReputationTTL: 24 * time.Hour,
 		},
 		CORS: CORSConfig{
-			AllowedOrigins: getEnvAsSlice("CORS_ALLOWED_ORIGINS", []string{"*"}),
-			AllowedMethods: getEnvAsSlice("CORS_ALLOWED_METHODS", []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}),
-			AllowedHeaders: getEnvAsSlice("CORS_ALLOWED_HEADERS", []string{"Content-Type", "Authorization"}),
+			AllowedOrigins: []string{"*"},
+			AllowedMethods: []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+			// The headers requests in this API read, and those gRPC-Web
+			// clients send
+			AllowedHeaders: []string{
+				"Content-Type", "Authorization", "X-Request-Id", "Accept-Language", "Idempotency-Key", "X-Tenant",
+				"X-Grpc-Web", "X-User-Agent", "Grpc-Timeout",
+			},
 		},
 		Environment: EnvironmentConfig{
-			Environment: getEnv("ENVIRONMENT", "development"),
-			LogLevel:    getEnv("LOG_LEVEL", "debug"),
-			LogFormat:   getEnv("LOG_FORMAT", "json"),
+			Environment: environment,
+			LogLevel:    "debug",
+			LogFormat:   "json",
 		},
 		Monitoring: MonitoringConfig{
-			MetricsEnabled:     getEnvAsBool("METRICS_ENABLED", true),
-			MetricsPort:        getEnv("METRICS_PORT", "9091"),
-			HealthCheckEnabled: getEnvAsBool("HEALTH_CHECK_ENABLED", true),
+			MetricsEnabled:     true,
+			MetricsPort:        "9091",
+			PoolSampleInterval: 15 * time.Second,
+			HealthCheckEnabled: true,
+			ChannelzHost:       "127.0.0.1",
+			ChannelzPort:       "50052",
+			PprofHost:          "127.0.0.1",
+			PprofPort:          "6060",
+		},
+		Tracing: TracingConfig{
+			ServiceName:  "auth-backend",
+			OTLPEndpoint: "localhost:4317",
+			Insecure:     true,
+			SampleRatio:  1.0,
 		},
 		Security: SecurityConfig{
-			BCryptCost:       getEnvAsInt("BCRYPT_COST", 12),
-			SessionTimeout:   getEnvAsDuration("SESSION_TIMEOUT", 24*time.Hour),
-			MaxLoginAttempts: getEnvAsInt("MAX_LOGIN_ATTEMPTS", 5),
-			LockoutDuration:  getEnvAsDuration("LOCKOUT_DURATION", 15*time.Minute),
-			ShutdownTimeout:  getEnvAsDuration("SHUTDOWN_TIMEOUT", 30*time.Second),
+			BCryptCost:       12,
+			SessionTimeout:   24 * time.Hour,
+			MaxLoginAttempts: 5,
+			LockoutDuration:  15 * time.Minute,
+			ShutdownTimeout:  30 * time.Second,
+			PublicMethods: []string{
+				"/auth.AuthService/SignUp",
+				"/auth.AuthService/Login",
+				"/auth.AuthService/ForgotPassword",
+				"/auth.AuthService/ResetPassword",
+				"/auth.AuthService/CheckPasswordStrength",
+				"/auth.AuthService/ValidateToken",
+				"/auth.AuthService/RefreshToken",
+				"/auth.AuthService/VerifyEmail",
+				"/auth.AuthService/VerifyRecoveryEmail",
+				"/auth.AuthService/ResendVerification",
+				"/grpc.reflection.v1.ServerReflection/ServerReflectionInfo",
+				"/grpc.reflection.v1alpha.ServerReflection/ServerReflectionInfo",
+				"/xds.service.orca.v3.OpenRcaService/StreamCoreMetrics",
+			},
+			TrustedProxies: []string{"127.0.0.1/32", "::1/128"},
+			CacheOutage: CacheOutageConfig{
+				RateLimit:       FailOpen,
+				LoginAttempts:   FailOpen,
+				TokenRevocation: FailOpen,
+				RefreshTokens:   FailClosed,
+				Idempotency:     FailOpen,
+			},
+		},
+		Audit: AuditConfig{
+			Enabled:    true,
+			BufferSize: 1000,
+		},
+		XDS: XDSConfig{
+			LoadReportInterval: 30 * time.Second,
+		},
+		Outbox: OutboxConfig{
+			Enabled:           true,
+			Publisher:         "redis",
+			RedisStream:       "events",
+			RedisStreamMaxLen: 100000,
+			WebhookTimeout:    10 * time.Second,
+			PollInterval:      time.Second,
+			BatchSize:         100,
+			Retention:         7 * 24 * time.Hour,
+		},
+		StartupRetry: StartupRetryConfig{
+			MaxAttempts:    10,
+			InitialBackoff: 500 * time.Millisecond,
+			MaxBackoff:     10 * time.Second,
+		},
+		Tenancy: TenancyConfig{
+			CacheTTL: 30 * time.Second,
+		},
+		UserCache: UserCacheConfig{
+			Enabled:    true,
+			TTL:        5 * time.Minute,
+			MaxEntries: 10000,
+			SharedTTL:  30 * time.Second,
+		},
+		Encryption: EncryptionConfig{},
+		Vault: VaultConfig{
+			Address:           "http://127.0.0.1:8200",
+			TransitMount:      "transit",
+			PublicKeyCacheTTL: 5 * time.Minute,
+		},
+		Embedded: EmbeddedConfig{
+			DataDir: ".embedded",
 		},
 	}
+}
 
-	if err := cfg.Validate(); err != nil {
-		return nil, fmt.Errorf("configuration validation failed: %w", err)
+// loadEnv returns defaults with the settings of environment variables applied
+func loadEnv(defaults *Config) *Config {
+	return &Config{
+		Server: ServerConfig{
+			Port:                         getEnv("SERVER_PORT", defaults.Server.Port),
+			Host:                         getEnv("SERVER_HOST", defaults.Server.Host),
+			GatewayEnabled:               getEnvAsBool("GATEWAY_ENABLED", defaults.Server.GatewayEnabled),
+			GatewayPort:                  getEnv("GATEWAY_PORT", defaults.Server.GatewayPort),
+			OpenAPIEnabled:               getEnvAsBool("OPENAPI_ENABLED", defaults.Server.OpenAPIEnabled),
+			SwaggerUIEnabled:             getEnvAsBool("SWAGGER_UI_ENABLED", defaults.Server.SwaggerUIEnabled),
+			JWKSEnabled:                  getEnvAsBool("JWKS_ENABLED", defaults.Server.JWKSEnabled),
+			GRPCWebEnabled:               getEnvAsBool("GRPC_WEB_ENABLED", defaults.Server.GRPCWebEnabled),
+			GRPCWebPort:                  getEnv("GRPC_WEB_PORT", defaults.Server.GRPCWebPort),
+			ConnectEnabled:               getEnvAsBool("CONNECT_ENABLED", defaults.Server.ConnectEnabled),
+			ConnectPort:                  getEnv("CONNECT_PORT", defaults.Server.ConnectPort),
+			DefaultRPCTimeout:            getEnvAsDuration("RPC_DEFAULT_TIMEOUT", defaults.Server.DefaultRPCTimeout),
+			MaxRPCTimeout:                getEnvAsDuration("RPC_MAX_TIMEOUT", defaults.Server.MaxRPCTimeout),
+			MethodTimeouts:               getEnvAsDurationMap("RPC_METHOD_TIMEOUTS", defaults.Server.MethodTimeouts),
+			KeepaliveTime:                getEnvAsDuration("GRPC_KEEPALIVE_TIME", defaults.Server.KeepaliveTime),
+			KeepaliveTimeout:             getEnvAsDuration("GRPC_KEEPALIVE_TIMEOUT", defaults.Server.KeepaliveTimeout),
+			MaxConnectionIdle:            getEnvAsDuration("GRPC_MAX_CONNECTION_IDLE", defaults.Server.MaxConnectionIdle),
+			MaxConnectionAge:             getEnvAsDuration("GRPC_MAX_CONNECTION_AGE", defaults.Server.MaxConnectionAge),
+			MaxConnectionAgeGrace:        getEnvAsDuration("GRPC_MAX_CONNECTION_AGE_GRACE", defaults.Server.MaxConnectionAgeGrace),
+			KeepaliveMinTime:             getEnvAsDuration("GRPC_KEEPALIVE_MIN_TIME", defaults.Server.KeepaliveMinTime),
+			KeepalivePermitWithoutStream: getEnvAsBool("GRPC_KEEPALIVE_PERMIT_WITHOUT_STREAM", defaults.Server.KeepalivePermitWithoutStream),
+			MaxRecvMsgSize:               getEnvAsInt("GRPC_MAX_RECV_MSG_SIZE", defaults.Server.MaxRecvMsgSize),
+			MaxSendMsgSize:               getEnvAsInt("GRPC_MAX_SEND_MSG_SIZE", defaults.Server.MaxSendMsgSize),
+			CompressionMode:              getEnv("GRPC_COMPRESSION", defaults.Server.CompressionMode),
+			CompressionMinSize:           getEnvAsInt("GRPC_COMPRESSION_MIN_SIZE", defaults.Server.CompressionMinSize),
+			ReflectionEnabled:            getEnvAsBool("REFLECTION_ENABLED", defaults.Server.ReflectionEnabled),
+			ReflectionRequireAdmin:       getEnvAsBool("REFLECTION_REQUIRE_ADMIN", defaults.Server.ReflectionRequireAdmin),
+		},
+		Database: DatabaseConfig{
+			Host:                 getEnv("DB_HOST", defaults.Database.Host),
+			Port:                 getEnv("DB_PORT", defaults.Database.Port),
+			User:                 getEnv("DB_USER", defaults.Database.User),
+			Password:             getEnv("DB_PASSWORD", defaults.Database.Password),
+			DBName:               getEnv("DB_NAME", defaults.Database.DBName),
+			SSLMode:              getEnv("DB_SSL_MODE", defaults.Database.SSLMode),
+			MaxOpenConns:         getEnvAsInt("DB_MAX_OPEN_CONNS", defaults.Database.MaxOpenConns),
+			MinConns:             getEnvAsInt("DB_MIN_CONNS", defaults.Database.MinConns),
+			ConnMaxLifetime:      getEnvAsDuration("DB_CONN_MAX_LIFETIME", defaults.Database.ConnMaxLifetime),
+			ConnMaxIdleTime:      getEnvAsDuration("DB_CONN_MAX_IDLE_TIME", defaults.Database.ConnMaxIdleTime),
+			AutoMigrate:          getEnvAsBool("DB_AUTO_MIGRATE", defaults.Database.AutoMigrate),
+			UUIDv7:               getEnvAsBool("DB_UUIDV7", defaults.Database.UUIDv7),
+			PrepareStatements:    getEnvAsBool("DB_PREPARE_STATEMENTS", defaults.Database.PrepareStatements),
+			Seed:                 getEnvAsBool("DB_SEED", defaults.Database.Seed),
+			Replicas:             getEnvAsSlice("DB_REPLICA_HOSTS", defaults.Database.Replicas),
+			ReplicaMaxLag:        getEnvAsDuration("DB_REPLICA_MAX_LAG", defaults.Database.ReplicaMaxLag),
+			ReplicaCheckInterval: getEnvAsDuration("DB_REPLICA_CHECK_INTERVAL", defaults.Database.ReplicaCheckInterval),
+			ReadRetries:          getEnvAsInt("DB_READ_RETRIES", defaults.Database.ReadRetries),
+			SlowQueryThreshold:   getEnvAsDuration("DB_SLOW_QUERY_THRESHOLD", defaults.Database.SlowQueryThreshold),
+		},
+		Redis: RedisConfig{
+			Host:               getEnv("REDIS_HOST", defaults.Redis.Host),
+			Port:               getEnv("REDIS_PORT", defaults.Redis.Port),
+			Password:           getEnv("REDIS_PASSWORD", defaults.Redis.Password),
+			DB:                 getEnvAsInt("REDIS_DB", defaults.Redis.DB),
+			MaxRetries:         getEnvAsInt("REDIS_MAX_RETRIES", defaults.Redis.MaxRetries),
+			PoolSize:           getEnvAsInt("REDIS_POOL_SIZE", defaults.Redis.PoolSize),
+			SentinelAddrs:      getEnvAsSlice("REDIS_SENTINEL_ADDRS", defaults.Redis.SentinelAddrs),
+			SentinelMaster:     getEnv("REDIS_SENTINEL_MASTER", defaults.Redis.SentinelMaster),
+			SentinelPassword:   getEnv("REDIS_SENTINEL_PASSWORD", defaults.Redis.SentinelPassword),
+			URL:                getEnv("REDIS_URL", defaults.Redis.URL),
+			Username:           getEnv("REDIS_USERNAME", defaults.Redis.Username),
+			TLSEnabled:         getEnvAsBool("REDIS_TLS_ENABLED", defaults.Redis.TLSEnabled),
+			TLSCAFile:          getEnv("REDIS_TLS_CA_FILE", defaults.Redis.TLSCAFile),
+			TLSCertFile:        getEnv("REDIS_TLS_CERT_FILE", defaults.Redis.TLSCertFile),
+			TLSKeyFile:         getEnv("REDIS_TLS_KEY_FILE", defaults.Redis.TLSKeyFile),
+			TLSServerName:      getEnv("REDIS_TLS_SERVER_NAME", defaults.Redis.TLSServerName),
+			Fallback:           getEnv("REDIS_FALLBACK", defaults.Redis.Fallback),
+			FallbackMaxEntries: getEnvAsInt("REDIS_FALLBACK_MAX_ENTRIES", defaults.Redis.FallbackMaxEntries),
+		},
+		Cache: CacheConfig{
+			Backend:   getEnv("CACHE_BACKEND", defaults.Cache.Backend),
+			KeyPrefix: getEnv("CACHE_KEY_PREFIX", defaults.Cache.KeyPrefix),
+		},
+		JWT: JWTConfig{
+			Format:              getEnv("JWT_TOKEN_FORMAT", defaults.JWT.Format),
+			AccessTokenExpiry:   getEnvAsDuration("JWT_ACCESS_TOKEN_EXPIRY", defaults.JWT.AccessTokenExpiry),
+			RefreshTokenExpiry:  getEnvAsDuration("JWT_REFRESH_TOKEN_EXPIRY", defaults.JWT.RefreshTokenExpiry),
+			RefreshTokenFormat:  getEnv("JWT_REFRESH_TOKEN_FORMAT", defaults.JWT.RefreshTokenFormat),
+			Leeway:              getEnvAsDuration("JWT_LEEWAY", defaults.JWT.Leeway),
+			Issuer:              getEnv("JWT_ISSUER", defaults.JWT.Issuer),
+			Audiences:           getEnvAsSlice("JWT_AUDIENCE", defaults.JWT.Audiences),
+			Algorithm:           getEnv("JWT_ALGORITHM", defaults.JWT.Algorithm),
+			RSAKeyBits:          getEnvAsInt("JWT_RSA_KEY_BITS", defaults.JWT.RSAKeyBits),
+			PrivateKeyPath:      getEnv("JWT_PRIVATE_KEY_PATH", defaults.JWT.PrivateKeyPath),
+			PublicKeyPath:       getEnv("JWT_PUBLIC_KEY_PATH", defaults.JWT.PublicKeyPath),
+			Keys:                getEnvAsSlice("JWT_KEYS", defaults.JWT.Keys),
+			Secret:              getEnv("JWT_SECRET", defaults.JWT.Secret),
+			KMSProvider:         getEnv("JWT_KMS_PROVIDER", defaults.JWT.KMSProvider),
+			KMSKey:              getEnv("JWT_KMS_KEY", defaults.JWT.KMSKey),
+			EncryptionKeys:      getEnvAsSlice("JWT_ENCRYPTION_KEYS", defaults.JWT.EncryptionKeys),
+			EncryptionActiveKey: getEnv("JWT_ENCRYPTION_ACTIVE_KEY", defaults.JWT.EncryptionActiveKey),
+		},
+		Argon2: Argon2Config{
+			Memory:      uint32(getEnvAsInt("ARGON2_MEMORY", int(defaults.Argon2.Memory))),
+			Iterations:  uint32(getEnvAsInt("ARGON2_ITERATIONS", int(defaults.Argon2.Iterations))),
+			Parallelism: uint8(getEnvAsInt("ARGON2_PARALLELISM", int(defaults.Argon2.Parallelism))),
+			SaltLength:  uint32(getEnvAsInt("ARGON2_SALT_LENGTH", int(defaults.Argon2.SaltLength))),
+			KeyLength:   uint32(getEnvAsInt("ARGON2_KEY_LENGTH", int(defaults.Argon2.KeyLength))),
+		},
+		Password: PasswordConfig{
+			Peppers:       getEnvAsSlice("PASSWORD_PEPPERS", defaults.Password.Peppers),
+			ActivePepper:  getEnv("PASSWORD_ACTIVE_PEPPER", defaults.Password.ActivePepper),
+			MinLength:     getEnvAsInt("PASSWORD_MIN_LENGTH", defaults.Password.MinLength),
+			MaxLength:     getEnvAsInt("PASSWORD_MAX_LENGTH", defaults.Password.MaxLength),
+			MinScore:      getEnvAsInt("PASSWORD_MIN_SCORE", defaults.Password.MinScore),
+			BlockCommon:   getEnvAsBool("PASSWORD_BLOCK_COMMON", defaults.Password.BlockCommon),
+			BlocklistPath: getEnv("PASSWORD_BLOCKLIST_PATH", defaults.Password.BlocklistPath),
+		},
+		RateLimit: RateLimitConfig{
+			Public:        getEnvAsInt("RATE_LIMIT_PUBLIC", defaults.RateLimit.Public),
+			Authenticated: getEnvAsInt("RATE_LIMIT_AUTHENTICATED", defaults.RateLimit.Authenticated),
+			Window:        getEnvAsDuration("RATE_LIMIT_WINDOW", defaults.RateLimit.Window),
+		},
+		Idempotency: IdempotencyConfig{
+			Methods: getEnvAsSlice("IDEMPOTENCY_METHODS", defaults.Idempotency.Methods),
+			TTL:     getEnvAsDuration("IDEMPOTENCY_TTL", defaults.Idempotency.TTL),
+		},
+		IPFilter: IPFilterConfig{
+			Allowlist:       getEnvAsSlice("IP_ALLOWLIST", defaults.IPFilter.Allowlist),
+			Denylist:        getEnvAsSlice("IP_DENYLIST", defaults.IPFilter.Denylist),
+			RefreshInterval: getEnvAsDuration("IP_DENYLIST_REFRESH_INTERVAL", defaults.IPFilter.RefreshInterval),
+		},
+		LoadShed: LoadShedConfig{
+			Enabled:       getEnvAsBool("LOAD_SHED_ENABLED", defaults.LoadShed.Enabled),
+			InitialLimit:  getEnvAsInt("LOAD_SHED_INITIAL_LIMIT", defaults.LoadShed.InitialLimit),
+			MinLimit:      getEnvAsInt("LOAD_SHED_MIN_LIMIT", defaults.LoadShed.MinLimit),
+			MaxLimit:      getEnvAsInt("LOAD_SHED_MAX_LIMIT", defaults.LoadShed.MaxLimit),
+			TargetLatency: getEnvAsDuration("LOAD_SHED_TARGET_LATENCY", defaults.LoadShed.TargetLatency),
+			MaxQueue:      getEnvAsInt("LOAD_SHED_MAX_QUEUE", defaults.LoadShed.MaxQueue),
+			QueueTimeout:  getEnvAsDuration("LOAD_SHED_QUEUE_TIMEOUT", defaults.LoadShed.QueueTimeout),
+			RetryAfter:    getEnvAsDuration("LOAD_SHED_RETRY_AFTER", defaults.LoadShed.RetryAfter),
+			MethodLimits:  getEnvAsIntMap("LOAD_SHED_METHOD_LIMITS", defaults.LoadShed.MethodLimits),
+		},
+		BotDetection: BotDetectionConfig{
+			Enabled:         getEnvAsBool("BOT_DETECTION_ENABLED", defaults.BotDetection.Enabled),
+			Threshold:       getEnvAsInt("BOT_DETECTION_THRESHOLD", defaults.BotDetection.Threshold),
+			IPReputationTTL: getEnvAsDuration("IP_REPUTATION_TTL", defaults.BotDetection.IPReputationTTL),
+		},
+		CORS: CORSConfig{
+			AllowedOrigins: getEnvAsSlice("CORS_ALLOWED_ORIGINS", defaults.CORS.AllowedOrigins),
+			AllowedMethods: getEnvAsSlice("CORS_ALLOWED_METHODS", defaults.CORS.AllowedMethods),
+			AllowedHeaders: getEnvAsSlice("CORS_ALLOWED_HEADERS", defaults.CORS.AllowedHeaders),
+		},
+		Environment: EnvironmentConfig{
+			Environment:     getEnv("ENVIRONMENT", defaults.Environment.Environment),
+			LogLevel:        getEnv("LOG_LEVEL", defaults.Environment.LogLevel),
+			LogFormat:       getEnv("LOG_FORMAT", defaults.Environment.LogFormat),
+			LogPayloads:     getEnvAsBool("LOG_PAYLOADS", defaults.Environment.LogPayloads),
+			LogRedactFields: getEnvAsSlice("LOG_REDACT_FIELDS", defaults.Environment.LogRedactFields),
+		},
+		Monitoring: MonitoringConfig{
+			MetricsEnabled:     getEnvAsBool("METRICS_ENABLED", defaults.Monitoring.MetricsEnabled),
+			MetricsPort:        getEnv("METRICS_PORT", defaults.Monitoring.MetricsPort),
+			PoolSampleInterval: getEnvAsDuration("METRICS_POOL_SAMPLE_INTERVAL", defaults.Monitoring.PoolSampleInterval),
+			HealthCheckEnabled: getEnvAsBool("HEALTH_CHECK_ENABLED", defaults.Monitoring.HealthCheckEnabled),
+			ChannelzEnabled:    getEnvAsBool("CHANNELZ_ENABLED", defaults.Monitoring.ChannelzEnabled),
+			ChannelzHost:       getEnv("CHANNELZ_HOST", defaults.Monitoring.ChannelzHost),
+			ChannelzPort:       getEnv("CHANNELZ_PORT", defaults.Monitoring.ChannelzPort),
+			PprofEnabled:       getEnvAsBool("PPROF_ENABLED", defaults.Monitoring.PprofEnabled),
+			PprofHost:          getEnv("PPROF_HOST", defaults.Monitoring.PprofHost),
+			PprofPort:          getEnv("PPROF_PORT", defaults.Monitoring.PprofPort),
+		},
+		Tracing: TracingConfig{
+			Enabled:      getEnvAsBool("TRACING_ENABLED", defaults.Tracing.Enabled),
+			ServiceName:  getEnv("TRACING_SERVICE_NAME", defaults.Tracing.ServiceName),
+			OTLPEndpoint: getEnv("OTEL_EXPORTER_OTLP_ENDPOINT", defaults.Tracing.OTLPEndpoint),
+			Insecure:     getEnvAsBool("OTEL_EXPORTER_OTLP_INSECURE", defaults.Tracing.Insecure),
+			SampleRatio:  getEnvAsFloat("TRACING_SAMPLE_RATIO", defaults.Tracing.SampleRatio),
+		},
+		Security: SecurityConfig{
+			BCryptCost:               getEnvAsInt("BCRYPT_COST", defaults.Security.BCryptCost),
+			SessionTimeout:           getEnvAsDuration("SESSION_TIMEOUT", defaults.Security.SessionTimeout),
+			MaxLoginAttempts:         getEnvAsInt("MAX_LOGIN_ATTEMPTS", defaults.Security.MaxLoginAttempts),
+			LockoutDuration:          getEnvAsDuration("LOCKOUT_DURATION", defaults.Security.LockoutDuration),
+			ShutdownTimeout:          getEnvAsDuration("SHUTDOWN_TIMEOUT", defaults.Security.ShutdownTimeout),
+			RequireEmailVerification: getEnvAsBool("REQUIRE_EMAIL_VERIFICATION", defaults.Security.RequireEmailVerification),
+			PublicMethods:            getEnvAsSlice("AUTH_PUBLIC_METHODS", defaults.Security.PublicMethods),
+			TrustedProxies:           getEnvAsSlice("TRUSTED_PROXIES", defaults.Security.TrustedProxies),
+			AuthzModelPath:           getEnv("AUTHZ_MODEL_PATH", defaults.Security.AuthzModelPath),
+			AuthzPolicyPath:          getEnv("AUTHZ_POLICY_PATH", defaults.Security.AuthzPolicyPath),
+			CacheOutage: CacheOutageConfig{
+				RateLimit:       getEnv("CACHE_OUTAGE_RATE_LIMIT", defaults.Security.CacheOutage.RateLimit),
+				LoginAttempts:   getEnv("CACHE_OUTAGE_LOGIN_ATTEMPTS", defaults.Security.CacheOutage.LoginAttempts),
+				TokenRevocation: getEnv("CACHE_OUTAGE_TOKEN_REVOCATION", defaults.Security.CacheOutage.TokenRevocation),
+				RefreshTokens:   getEnv("CACHE_OUTAGE_REFRESH_TOKENS", defaults.Security.CacheOutage.RefreshTokens),
+				Idempotency:     getEnv("CACHE_OUTAGE_IDEMPOTENCY", defaults.Security.CacheOutage.Idempotency),
+			},
+		},
+		Audit: AuditConfig{
+			Enabled:      getEnvAsBool("AUDIT_LOG_ENABLED", defaults.Audit.Enabled),
+			BufferSize:   getEnvAsInt("AUDIT_LOG_BUFFER_SIZE", defaults.Audit.BufferSize),
+			RedactFields: getEnvAsSlice("AUDIT_LOG_REDACT_FIELDS", defaults.Audit.RedactFields),
+		},
+		XDS: XDSConfig{
+			Enabled:            getEnvAsBool("XDS_ENABLED", defaults.XDS.Enabled),
+			LoadReportInterval: getEnvAsDuration("XDS_LOAD_REPORT_INTERVAL", defaults.XDS.LoadReportInterval),
+		},
+		Outbox: OutboxConfig{
+			Enabled:           getEnvAsBool("OUTBOX_ENABLED", defaults.Outbox.Enabled),
+			Publisher:         getEnv("OUTBOX_PUBLISHER", defaults.Outbox.Publisher),
+			RedisStream:       getEnv("OUTBOX_REDIS_STREAM", defaults.Outbox.RedisStream),
+			RedisStreamMaxLen: getEnvAsInt("OUTBOX_REDIS_STREAM_MAX_LEN", defaults.Outbox.RedisStreamMaxLen),
+			WebhookURL:        getEnv("OUTBOX_WEBHOOK_URL", defaults.Outbox.WebhookURL),
+			WebhookSecret:     getEnv("OUTBOX_WEBHOOK_SECRET", defaults.Outbox.WebhookSecret),
+			WebhookTimeout:    getEnvAsDuration("OUTBOX_WEBHOOK_TIMEOUT", defaults.Outbox.WebhookTimeout),
+			PollInterval:      getEnvAsDuration("OUTBOX_POLL_INTERVAL", defaults.Outbox.PollInterval),
+			BatchSize:         getEnvAsInt("OUTBOX_BATCH_SIZE", defaults.Outbox.BatchSize),
+			Retention:         getEnvAsDuration("OUTBOX_RETENTION", defaults.Outbox.Retention),
+		},
+		StartupRetry: StartupRetryConfig{
+			MaxAttempts:    getEnvAsInt("STARTUP_RETRY_MAX_ATTEMPTS", defaults.StartupRetry.MaxAttempts),
+			InitialBackoff: getEnvAsDuration("STARTUP_RETRY_INITIAL_BACKOFF", defaults.StartupRetry.InitialBackoff),
+			MaxBackoff:     getEnvAsDuration("STARTUP_RETRY_MAX_BACKOFF", defaults.StartupRetry.MaxBackoff),
+		},
+		Tenancy: TenancyConfig{
+			Enabled:  getEnvAsBool("TENANCY_ENABLED", defaults.Tenancy.Enabled),
+			CacheTTL: getEnvAsDuration("TENANT_CACHE_TTL", defaults.Tenancy.CacheTTL),
+		},
+		UserCache: UserCacheConfig{
+			Enabled:    getEnvAsBool("USER_CACHE_ENABLED", defaults.UserCache.Enabled),
+			TTL:        getEnvAsDuration("USER_CACHE_TTL", defaults.UserCache.TTL),
+			MaxEntries: getEnvAsInt("USER_CACHE_MAX_ENTRIES", defaults.UserCache.MaxEntries),
+			SharedTTL:  getEnvAsDuration("USER_CACHE_SHARED_TTL", defaults.UserCache.SharedTTL),
+		},
+		Encryption: EncryptionConfig{
+			Keys:        getEnvAsSlice("ENCRYPTION_KEYS", defaults.Encryption.Keys),
+			ActiveKeyID: getEnv("ENCRYPTION_ACTIVE_KEY", defaults.Encryption.ActiveKeyID),
+		},
+		Vault: VaultConfig{
+			Address:           getEnv("VAULT_ADDR", defaults.Vault.Address),
+			Token:             getEnv("VAULT_TOKEN", defaults.Vault.Token),
+			TransitMount:      getEnv("VAULT_TRANSIT_MOUNT", defaults.Vault.TransitMount),
+			PublicKeyCacheTTL: getEnvAsDuration("VAULT_PUBLIC_KEY_CACHE_TTL", defaults.Vault.PublicKeyCacheTTL),
+		},
+		Embedded: EmbeddedConfig{
+			Postgres: getEnvAsBool("EMBEDDED_POSTGRES", defaults.Embedded.Postgres),
+			Redis:    getEnvAsBool("EMBEDDED_REDIS", defaults.Embedded.Redis),
+			DataDir:  getEnv("EMBEDDED_DATA_DIR", defaults.Embedded.DataDir),
+		},
 	}
-
-	return cfg, nil
 }
 
 // Validate checks if the configuration is valid
@@ -196,9 +1039,208 @@ func (c *Config) Validate() error {
 	if c.Database.DBName == "" {
 		return fmt.Errorf("DB_NAME is required")
 	}
+	if len(c.Database.Replicas) > 0 && c.Database.ReplicaCheckInterval <= 0 {
+		return fmt.Errorf("DB_REPLICA_CHECK_INTERVAL must be positive")
+	}
+	if c.Monitoring.MetricsEnabled && c.Monitoring.PoolSampleInterval <= 0 {
+		return fmt.Errorf("METRICS_POOL_SAMPLE_INTERVAL must be positive")
+	}
+	if c.Database.ReadRetries < 0 {
+		return fmt.Errorf("DB_READ_RETRIES must not be negative")
+	}
+	if len(c.Redis.SentinelAddrs) > 0 && c.Redis.SentinelMaster == "" {
+		return fmt.Errorf("REDIS_SENTINEL_MASTER is required with REDIS_SENTINEL_ADDRS")
+	}
+	if c.Redis.URL != "" && len(c.Redis.SentinelAddrs) > 0 {
+		return fmt.Errorf("REDIS_URL cannot be used with REDIS_SENTINEL_ADDRS")
+	}
+	switch c.Redis.Fallback {
+	case "local":
+		if c.Redis.FallbackMaxEntries < 1 {
+			return fmt.Errorf("REDIS_FALLBACK_MAX_ENTRIES must be positive")
+		}
+	case "none":
+	default:
+		return fmt.Errorf("REDIS_FALLBACK must be local or none, got %q", c.Redis.Fallback)
+	}
+	if (c.Redis.TLSCertFile == "") != (c.Redis.TLSKeyFile == "") {
+		return fmt.Errorf("REDIS_TLS_CERT_FILE and REDIS_TLS_KEY_FILE must be set together")
+	}
+	switch c.Cache.Backend {
+	case "redis":
+	case "memory":
+		if c.Embedded.Redis {
+			return fmt.Errorf("EMBEDDED_REDIS cannot be used with CACHE_BACKEND=memory")
+		}
+		if c.Outbox.Enabled && c.Outbox.Publisher == "redis" {
+			return fmt.Errorf("OUTBOX_PUBLISHER=redis requires CACHE_BACKEND=redis")
+		}
+	default:
+		return fmt.Errorf("CACHE_BACKEND must be redis or memory, got %q", c.Cache.Backend)
+	}
+	if c.Security.MaxLoginAttempts < 1 || c.Security.LockoutDuration <= 0 {
+		return fmt.Errorf("MAX_LOGIN_ATTEMPTS and LOCKOUT_DURATION must be positive")
+	}
+	for _, outage := range []struct{ name, mode string }{
+		{"CACHE_OUTAGE_RATE_LIMIT", c.Security.CacheOutage.RateLimit},
+		{"CACHE_OUTAGE_LOGIN_ATTEMPTS", c.Security.CacheOutage.LoginAttempts},
+		{"CACHE_OUTAGE_TOKEN_REVOCATION", c.Security.CacheOutage.TokenRevocation},
+		{"CACHE_OUTAGE_REFRESH_TOKENS", c.Security.CacheOutage.RefreshTokens},
+		{"CACHE_OUTAGE_IDEMPOTENCY", c.Security.CacheOutage.Idempotency},
+	} {
+		if outage.mode != FailOpen && outage.mode != FailClosed {
+			return fmt.Errorf("%s must be open or closed, got %q", outage.name, outage.mode)
+		}
+	}
+	if len(c.Idempotency.Methods) > 0 && c.Idempotency.TTL <= 0 {
+		return fmt.Errorf("IDEMPOTENCY_TTL must be positive")
+	}
 	if c.JWT.Issuer == "" {
 		return fmt.Errorf("JWT_ISSUER is required")
 	}
+	switch c.JWT.Algorithm {
+	case "RS256", "ES256", "EdDSA":
+		if c.JWT.Secret != "" {
+			return fmt.Errorf("JWT_SECRET is only used with JWT_ALGORITHM=HS256")
+		}
+	case "HS256":
+		if len(c.JWT.Keys) > 0 || c.JWT.PrivateKeyPath != "" || c.JWT.PublicKeyPath != "" {
+			return fmt.Errorf("JWT_ALGORITHM=HS256 signs with JWT_SECRET and cannot be used with JWT_KEYS, JWT_PRIVATE_KEY_PATH or JWT_PUBLIC_KEY_PATH")
+		}
+		if c.JWT.Secret == "" && c.Environment.Environment == "production" {
+			return fmt.Errorf("JWT_SECRET is required in production when JWT_ALGORITHM=HS256")
+		}
+		if c.JWT.Secret != "" && len(c.JWT.Secret) < MinJWTSecretLength {
+			return fmt.Errorf("JWT_SECRET must be at least %d bytes, got %d", MinJWTSecretLength, len(c.JWT.Secret))
+		}
+	default:
+		return fmt.Errorf("JWT_ALGORITHM must be RS256, ES256, EdDSA or HS256, got %q", c.JWT.Algorithm)
+	}
+	if c.JWT.RSAKeyBits != 2048 && c.JWT.RSAKeyBits != 3072 && c.JWT.RSAKeyBits != 4096 {
+		return fmt.Errorf("JWT_RSA_KEY_BITS must be 2048, 3072 or 4096, got %d", c.JWT.RSAKeyBits)
+	}
+	switch c.JWT.Format {
+	case TokenFormatJWT:
+	case TokenFormatPASETO:
+		if c.JWT.Algorithm != "EdDSA" {
+			return fmt.Errorf("JWT_TOKEN_FORMAT=paseto signs v4.public tokens with Ed25519 keys, so JWT_ALGORITHM must be EdDSA")
+		}
+	default:
+		return fmt.Errorf("JWT_TOKEN_FORMAT must be jwt or paseto, got %q", c.JWT.Format)
+	}
+	if c.JWT.Leeway < 0 {
+		return fmt.Errorf("JWT_LEEWAY must not be negative")
+	}
+	if c.JWT.RefreshTokenFormat != RefreshTokenJWT && c.JWT.RefreshTokenFormat != RefreshTokenOpaque {
+		return fmt.Errorf("JWT_REFRESH_TOKEN_FORMAT must be jwt or opaque, got %q", c.JWT.RefreshTokenFormat)
+	}
+	if len(c.JWT.Keys) > 0 && (c.JWT.PrivateKeyPath != "" || c.JWT.PublicKeyPath != "") {
+		return fmt.Errorf("JWT_KEYS cannot be used with JWT_PRIVATE_KEY_PATH or JWT_PUBLIC_KEY_PATH")
+	}
+	switch c.JWT.KMSProvider {
+	case "":
+		if c.JWT.KMSKey != "" {
+			return fmt.Errorf("JWT_KMS_KEY requires JWT_KMS_PROVIDER")
+		}
+	case KMSProviderAWS, KMSProviderGCP, KMSProviderVault:
+		if c.JWT.KMSKey == "" {
+			return fmt.Errorf("JWT_KMS_KEY is required with JWT_KMS_PROVIDER")
+		}
+		if c.JWT.KMSProvider != KMSProviderVault && c.JWT.Algorithm != "RS256" && c.JWT.Algorithm != "ES256" {
+			return fmt.Errorf("JWT_KMS_PROVIDER=%s signs with RSA or P-256 keys, so JWT_ALGORITHM must be RS256 or ES256", c.JWT.KMSProvider)
+		}
+		if c.JWT.Algorithm == "HS256" {
+			return fmt.Errorf("JWT_KMS_PROVIDER cannot be used with JWT_ALGORITHM=HS256")
+		}
+		if c.JWT.PrivateKeyPath != "" || c.JWT.PublicKeyPath != "" {
+			return fmt.Errorf("JWT_KMS_PROVIDER cannot be used with JWT_PRIVATE_KEY_PATH or JWT_PUBLIC_KEY_PATH")
+		}
+	default:
+		return fmt.Errorf("JWT_KMS_PROVIDER must be aws, gcp or vault, got %q", c.JWT.KMSProvider)
+	}
+	if len(c.JWT.EncryptionKeys) > 0 {
+		if c.JWT.EncryptionActiveKey == "" {
+			return fmt.Errorf("JWT_ENCRYPTION_ACTIVE_KEY is required with JWT_ENCRYPTION_KEYS")
+		}
+		if c.JWT.Format == TokenFormatPASETO {
+			return fmt.Errorf("JWT_ENCRYPTION_KEYS encrypts JWTs, so it cannot be used with JWT_TOKEN_FORMAT=paseto")
+		}
+	}
+	if c.JWT.KMSProvider == KMSProviderVault {
+		if c.Vault.Address == "" || c.Vault.Token == "" {
+			return fmt.Errorf("VAULT_ADDR and VAULT_TOKEN are required with JWT_KMS_PROVIDER=vault")
+		}
+		if c.Vault.PublicKeyCacheTTL <= 0 {
+			return fmt.Errorf("VAULT_PUBLIC_KEY_CACHE_TTL must be positive")
+		}
+	}
+	if c.LoadShed.Enabled && (c.LoadShed.MinLimit < 1 || c.LoadShed.MinLimit > c.LoadShed.MaxLimit) {
+		return fmt.Errorf("LOAD_SHED_MIN_LIMIT must be at least 1 and at most LOAD_SHED_MAX_LIMIT")
+	}
+	if c.Environment.LogPayloads && c.Environment.Environment == "production" {
+		return fmt.Errorf("LOG_PAYLOADS must not be enabled in production")
+	}
+	if c.Database.Seed && c.Environment.Environment == "production" {
+		return fmt.Errorf("DB_SEED must not be enabled in production")
+	}
+	if len(c.Encryption.Keys) == 0 && c.Environment.Environment == "production" {
+		return fmt.Errorf("ENCRYPTION_KEYS is required in production; see Upgrading under Personal Data Encryption in the README")
+	}
+	if c.Password.MinLength < 1 || c.Password.MaxLength < c.Password.MinLength {
+		return fmt.Errorf("PASSWORD_MIN_LENGTH must be at least 1 and at most PASSWORD_MAX_LENGTH")
+	}
+	if c.Password.MinScore < 0 || c.Password.MinScore > 4 {
+		return fmt.Errorf("PASSWORD_MIN_SCORE must be from 0 to 4")
+	}
+	if len(c.Password.Peppers) > 0 && c.Password.ActivePepper == "" {
+		return fmt.Errorf("PASSWORD_ACTIVE_PEPPER is required with PASSWORD_PEPPERS")
+	}
+	if len(c.Encryption.Keys) > 0 && c.Encryption.ActiveKeyID == "" {
+		return fmt.Errorf("ENCRYPTION_ACTIVE_KEY is required with ENCRYPTION_KEYS")
+	}
+	if c.Embedded.Redis && (len(c.Redis.SentinelAddrs) > 0 || c.Redis.URL != "" || c.Redis.TLSEnabled) {
+		return fmt.Errorf("EMBEDDED_REDIS cannot be used with REDIS_SENTINEL_ADDRS, REDIS_URL or REDIS_TLS_ENABLED")
+	}
+	if (c.Embedded.Postgres || c.Embedded.Redis) && c.Environment.Environment == "production" {
+		return fmt.Errorf("EMBEDDED_POSTGRES and EMBEDDED_REDIS must not be enabled in production")
+	}
+	if c.XDS.Enabled && os.Getenv("GRPC_XDS_BOOTSTRAP") == "" && os.Getenv("GRPC_XDS_BOOTSTRAP_CONFIG") == "" {
+		return fmt.Errorf("XDS_ENABLED requires GRPC_XDS_BOOTSTRAP or GRPC_XDS_BOOTSTRAP_CONFIG")
+	}
+	if c.StartupRetry.MaxAttempts < 1 {
+		return fmt.Errorf("STARTUP_RETRY_MAX_ATTEMPTS must be at least 1")
+	}
+	if c.StartupRetry.InitialBackoff <= 0 || c.StartupRetry.MaxBackoff < c.StartupRetry.InitialBackoff {
+		return fmt.Errorf("STARTUP_RETRY_INITIAL_BACKOFF must be positive and no greater than STARTUP_RETRY_MAX_BACKOFF")
+	}
+	if c.Outbox.Enabled {
+		switch c.Outbox.Publisher {
+		case "redis":
+		case "webhook":
+			if c.Outbox.WebhookURL == "" {
+				return fmt.Errorf("OUTBOX_WEBHOOK_URL is required when OUTBOX_PUBLISHER is webhook")
+			}
+		default:
+			return fmt.Errorf("OUTBOX_PUBLISHER must be redis or webhook, got %q", c.Outbox.Publisher)
+		}
+		if c.Outbox.PollInterval <= 0 || c.Outbox.BatchSize < 1 {
+			return fmt.Errorf("OUTBOX_POLL_INTERVAL and OUTBOX_BATCH_SIZE must be positive")
+		}
+	}
+	if c.UserCache.Enabled && (c.UserCache.TTL <= 0 || c.UserCache.MaxEntries < 1) {
+		return fmt.Errorf("USER_CACHE_TTL and USER_CACHE_MAX_ENTRIES must be positive")
+	}
+	if c.UserCache.SharedTTL < 0 {
+		return fmt.Errorf("USER_CACHE_SHARED_TTL must not be negative")
+	}
+	if c.XDS.Enabled && c.Server.GRPCWebEnabled {
+		return fmt.Errorf("GRPC_WEB_ENABLED is not supported with XDS_ENABLED; use CONNECT_ENABLED instead")
+	}
+	switch c.Server.CompressionMode {
+	case "off", "prefer", "always":
+	default:
+		return fmt.Errorf("GRPC_COMPRESSION must be one of off, prefer, always")
+	}
 	return nil
 }
 
@@ -235,6 +1277,18 @@ func getEnvAsBool(key string, defaultValue bool) bool {
 	return value
 }
 
+func getEnvAsFloat(key string, defaultValue float64) float64 {
+	valueStr := os.Getenv(key)
+	if valueStr == "" {
+		return defaultValue
+	}
+	value, err := strconv.ParseFloat(valueStr, 64)
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
 func getEnvAsDuration(key string, defaultValue time.Duration) time.Duration {
 	valueStr := os.Getenv(key)
 	if valueStr == "" {
@@ -247,6 +1301,48 @@ func getEnvAsDuration(key string, defaultValue time.Duration) time.Duration {
 	return value
 }
 
+// getEnvAsDurationMap parses comma-separated key=duration pairs,
+// e.g. "/auth.AuthService/Login=5s,/auth.AuthService/SignUp=8s"
+func getEnvAsDurationMap(key string, defaultValue map[string]time.Duration) map[string]time.Duration {
+	valueStr := os.Getenv(key)
+	if valueStr == "" {
+		return defaultValue
+	}
+	result := make(map[string]time.Duration)
+	for _, pair := range splitString(valueStr, ',') {
+		parts := splitString(pair, '=')
+		if len(parts) != 2 {
+			continue
+		}
+		duration, err := time.ParseDuration(trimSpace(parts[1]))
+		if err != nil {
+			continue
+		}
+		result[trimSpace(parts[0])] = duration
+	}
+	return result
+}
+
+func getEnvAsIntMap(key string, defaultValue map[string]int) map[string]int {
+	valueStr := os.Getenv(key)
+	if valueStr == "" {
+		return defaultValue
+	}
+	result := make(map[string]int)
+	for _, pair := range splitString(valueStr, ',') {
+		parts := splitString(pair, '=')
+		if len(parts) != 2 {
+			continue
+		}
+		value, err := strconv.Atoi(trimSpace(parts[1]))
+		if err != nil {
+			continue
+		}
+		result[trimSpace(parts[0])] = value
+	}
+	return result
+}
+
 func getEnvAsSlice(key string, defaultValue []string) []string {
 	valueStr := os.Getenv(key)
 	if valueStr == "" {
@@ -308,11 +1404,49 @@ func (c *Config) GetDatabaseDSN() string {
 	)
 }
 
+// GetReplicaDSN returns the connection string for the read replica at addr,
+// a host or host:port from Database.Replicas
+func (c *Config) GetReplicaDSN(addr string) string {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		host, port = addr, c.Database.Port
+	}
+	return fmt.Sprintf(
+		"host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
+		host,
+		port,
+		c.Database.User,
+		c.Database.Password,
+		c.Database.DBName,
+		c.Database.SSLMode,
+	)
+}
+
+// GetGRPCDialAddr returns the address in-process clients (such as the REST
+// gateway) use to reach the gRPC server
+func (c *Config) GetGRPCDialAddr() string {
+	host := c.Server.Host
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = "localhost"
+	}
+	return fmt.Sprintf("%s:%s", host, c.Server.Port)
+}
+
 // GetRedisAddr returns the Redis address
 func (c *Config) GetRedisAddr() string {
 	return fmt.Sprintf("%s:%s", c.Redis.Host, c.Redis.Port)
 }
 
+// IsOriginAllowed reports whether a browser origin passes CORS_ALLOWED_ORIGINS
+func (c *Config) IsOriginAllowed(origin string) bool {
+	for _, allowed := range c.CORS.AllowedOrigins {
+		if allowed == "*" || allowed == origin {
+			return true
+		}
+	}
+	return false
+}
+
 // IsDevelopment returns true if running in development mode
 func (c *Config) IsDevelopment() bool {
 	return c.Environment.Environment == "development"
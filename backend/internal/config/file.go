@@ -0,0 +1,98 @@
+package config
+
+import (
+	"bytes"
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// loadFile returns the built-in defaults with the settings of the YAML, JSON
+// or TOML config file at path applied. Keys follow the yaml tags of Config, one
+// section per field, with the EnvironmentConfig settings at the top level:
+//
+//	environment: production
+//	jwt:
+//	  access_token_expiry: 15m
+//
+// Durations are written like "15m" and lists as YAML lists. A map, such as
+// load_shed.method_limits, replaces the default one rather than adding to
+// it. Unknown keys are rejected, so a misspelled setting can't be ignored.
+// A TOML file has the same keys, with a [table] per section.
+func loadFile(path string) (*Config, error) {
+	isTOML := false
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml", ".json":
+	case ".toml":
+		isTOML = true
+	default:
+		return nil, fmt.Errorf("unsupported config file %s: want .yaml, .yml, .json or .toml", path)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	if isTOML {
+		if content, err = tomlToYAML(content); err != nil {
+			return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+		}
+	}
+
+	// The environment decides some defaults, so it is read first; ENVIRONMENT
+	// overrides the file's as it does every other setting
+	var file struct {
+		Environment string `yaml:"environment"`
+	}
+	if err := yaml.Unmarshal(content, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	cfg := defaultConfig(getEnv("ENVIRONMENT", cmp.Or(file.Environment, "development")))
+
+	// yaml.v3 adds the entries of a map to the one already there, so the
+	// default maps are kept aside and only restored if the file sets none
+	methodTimeouts, methodLimits := cfg.Server.MethodTimeouts, cfg.LoadShed.MethodLimits
+	cfg.Server.MethodTimeouts, cfg.LoadShed.MethodLimits = nil, nil
+
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		var typeErr *yaml.TypeError
+		if isTOML && errors.As(err, &typeErr) {
+			// The lines are those of the converted document, not the file
+			for i, msg := range typeErr.Errors {
+				typeErr.Errors[i] = yamlLine.ReplaceAllString(msg, "")
+			}
+		}
+		return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+
+	if cfg.Server.MethodTimeouts == nil {
+		cfg.Server.MethodTimeouts = methodTimeouts
+	}
+	if cfg.LoadShed.MethodLimits == nil {
+		cfg.LoadShed.MethodLimits = methodLimits
+	}
+	return cfg, nil
+}
+
+// yamlLine is the line number yaml.v3 starts its decoding errors with
+var yamlLine = regexp.MustCompile(`^line \d+: `)
+
+// tomlToYAML converts a TOML document to YAML, so it is decoded, and its keys
+// checked, exactly like a YAML file
+func tomlToYAML(content []byte) ([]byte, error) {
+	var doc map[string]interface{}
+	if err := toml.Unmarshal(content, &doc); err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(doc)
+}
@@ -0,0 +1,148 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeConfigFile writes content to a file named name in a temporary
+// directory and returns its path
+func writeConfigFile(t *testing.T, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	return path
+}
+
+func TestLoadFile(t *testing.T) {
+	t.Setenv("ENVIRONMENT", "")
+
+	tests := []struct {
+		name string
+		file string
+		// content sets environment production, database port 6543, access
+		// tokens for 5m and a Login timeout of 2s
+		content string
+	}{
+		{
+			name: "YAML",
+			file: "config.yaml",
+			content: `
+environment: production
+database:
+  port: 6543
+jwt:
+  access_token_expiry: 5m
+server:
+  method_timeouts:
+    /auth.AuthService/Login: 2s
+`,
+		},
+		{
+			name: "JSON",
+			file: "config.json",
+			content: `{
+  "environment": "production",
+  "database": {"port": 6543},
+  "jwt": {"access_token_expiry": "5m"},
+  "server": {"method_timeouts": {"/auth.AuthService/Login": "2s"}}
+}`,
+		},
+		{
+			name: "TOML",
+			file: "config.toml",
+			content: `
+environment = "production"
+
+[database]
+port = 6543
+
+[jwt]
+access_token_expiry = "5m"
+
+[server.method_timeouts]
+"/auth.AuthService/Login" = "2s"
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := loadFile(writeConfigFile(t, tt.file, tt.content))
+			if err != nil {
+				t.Fatalf("loadFile: %v", err)
+			}
+			if cfg.Environment.Environment != "production" {
+				t.Errorf("environment = %q, want production", cfg.Environment.Environment)
+			}
+			if cfg.Database.Port != "6543" {
+				t.Errorf("database port = %q, want 6543", cfg.Database.Port)
+			}
+			if cfg.JWT.AccessTokenExpiry != 5*time.Minute {
+				t.Errorf("access token expiry = %v, want 5m", cfg.JWT.AccessTokenExpiry)
+			}
+			want := map[string]time.Duration{"/auth.AuthService/Login": 2 * time.Second}
+			if len(cfg.Server.MethodTimeouts) != len(want) || cfg.Server.MethodTimeouts["/auth.AuthService/Login"] != want["/auth.AuthService/Login"] {
+				t.Errorf("method timeouts = %v, want %v", cfg.Server.MethodTimeouts, want)
+			}
+			// Settings the file leaves out keep their defaults
+			if defaults := defaultConfig("production"); cfg.Redis.Port != defaults.Redis.Port {
+				t.Errorf("redis port = %q, want the default %q", cfg.Redis.Port, defaults.Redis.Port)
+			}
+		})
+	}
+}
+
+func TestLoadFileRejects(t *testing.T) {
+	t.Setenv("ENVIRONMENT", "")
+
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		// wantErr is part of the error
+		wantErr string
+	}{
+		{name: "YAML unknown key", file: "config.yaml", content: "database:\n  prot: 6543\n", wantErr: "field prot not found"},
+		{name: "TOML unknown key", file: "config.toml", content: "[database]\nprot = 6543\n", wantErr: "field prot not found"},
+		{name: "TOML unknown section", file: "config.toml", content: "[databse]\nport = 6543\n", wantErr: "field databse not found"},
+		{name: "TOML wrong type", file: "config.toml", content: "[database]\nmax_open_conns = \"many\"\n", wantErr: "cannot unmarshal"},
+		{name: "TOML syntax", file: "config.toml", content: "[database\n", wantErr: "failed to parse"},
+		{name: "unsupported extension", file: "config.ini", content: "", wantErr: "unsupported config file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadFile(writeConfigFile(t, tt.file, tt.content))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("loadFile error = %v, want one containing %q", err, tt.wantErr)
+			}
+			if strings.HasSuffix(tt.file, ".toml") && strings.Contains(err.Error(), "line ") && !strings.Contains(tt.wantErr, "parse") {
+				t.Errorf("error %q names a line of the converted document", err)
+			}
+		})
+	}
+}
+
+// TestExampleFiles checks that the example config files load, and that the
+// TOML one says the same as the YAML one
+func TestExampleFiles(t *testing.T) {
+	t.Setenv("ENVIRONMENT", "")
+
+	fromYAML, err := loadFile("../../config.example.yaml")
+	if err != nil {
+		t.Fatalf("config.example.yaml: %v", err)
+	}
+	fromTOML, err := loadFile("../../config.example.toml")
+	if err != nil {
+		t.Fatalf("config.example.toml: %v", err)
+	}
+	if !reflect.DeepEqual(fromYAML, fromTOML) {
+		t.Errorf("config.example.toml and config.example.yaml load different settings")
+	}
+}
@@ -0,0 +1,109 @@
+// Package connectapi serves the gRPC services over connect-go, which speaks
+// the Connect, gRPC, and gRPC-Web protocols on one handler over HTTP/1.1 and
+// HTTP/2. Browser and Flutter web clients can call it with plain JSON.
+package connectapi
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/config"
+	pb "github.com/sahays/grpc-proto-go-flutter-template/proto"
+	adminpb "github.com/sahays/grpc-proto-go-flutter-template/proto/admin"
+	"github.com/sahays/grpc-proto-go-flutter-template/proto/admin/adminconnect"
+	"github.com/sahays/grpc-proto-go-flutter-template/proto/authconnect"
+	chatpb "github.com/sahays/grpc-proto-go-flutter-template/proto/chat"
+	"github.com/sahays/grpc-proto-go-flutter-template/proto/chat/chatconnect"
+)
+
+// protocolHeaders are the request headers Connect, gRPC, and gRPC-Web
+// clients send in addition to CORS_ALLOWED_HEADERS
+var protocolHeaders = []string{
+	"Connect-Protocol-Version",
+	"Connect-Timeout-Ms",
+	"Connect-Accept-Encoding",
+	"Connect-Content-Encoding",
+	"Grpc-Timeout",
+	"X-Grpc-Web",
+	"X-User-Agent",
+	"Accept-Language",
+	"X-Request-Id",
+	"X-Tenant",
+	"Idempotency-Key",
+}
+
+// exposedHeaders are the response headers browser clients need to read
+// status and errors
+var exposedHeaders = []string{
+	"Grpc-Status",
+	"Grpc-Message",
+	"Grpc-Status-Details-Bin",
+	"Connect-Content-Encoding",
+	"X-Request-Id",
+}
+
+// New creates the Connect handler. Requests are proxied to the gRPC server
+// over a loopback connection so they pass through the same interceptors as
+// native gRPC calls. The connection is closed when ctx is done.
+func New(ctx context.Context, cfg *config.Config) (http.Handler, error) {
+	conn, err := grpc.NewClient(cfg.GetGRPCDialAddr(),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial gRPC server: %w", err)
+	}
+
+	go func() {
+		<-ctx.Done()
+		_ = conn.Close()
+	}()
+
+	mux := http.NewServeMux()
+	mux.Handle(authconnect.NewAuthServiceHandler(&authService{client: pb.NewAuthServiceClient(conn)}))
+	mux.Handle(adminconnect.NewAdminServiceHandler(&adminService{client: adminpb.NewAdminServiceClient(conn)}))
+	mux.Handle(chatconnect.NewChatServiceHandler(&chatService{client: chatpb.NewChatServiceClient(conn)}))
+
+	// h2c serves HTTP/2 without TLS, which bidirectional streams require
+	return h2c.NewHandler(withCORS(cfg, mux), &http2.Server{}), nil
+}
+
+// withCORS answers preflight requests and sets CORS headers for origins that
+// pass CORS_ALLOWED_ORIGINS
+func withCORS(cfg *config.Config, next http.Handler) http.Handler {
+	allowedHeaders := strings.Join(append(append([]string{}, cfg.CORS.AllowedHeaders...), protocolHeaders...), ", ")
+	exposed := strings.Join(exposedHeaders, ", ")
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if !cfg.IsOriginAllowed(origin) {
+			http.Error(w, "origin not allowed", http.StatusForbidden)
+			return
+		}
+
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Add("Vary", "Origin")
+		w.Header().Set("Access-Control-Expose-Headers", exposed)
+
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST")
+			w.Header().Set("Access-Control-Allow-Headers", allowedHeaders)
+			w.Header().Set("Access-Control-Max-Age", "7200")
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
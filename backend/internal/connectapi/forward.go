@@ -0,0 +1,196 @@
+package connectapi
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+
+	"connectrpc.com/connect"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/middleware"
+)
+
+// forwardedHeaders are the request headers copied into gRPC metadata
+var forwardedHeaders = []string{
+	"authorization",
+	middleware.RequestIDHeader,
+	middleware.AcceptLanguageHeader,
+	middleware.TenantHeader,
+	middleware.IdempotencyKeyHeader,
+	"x-forwarded-for",
+}
+
+// unary forwards a unary call to the gRPC server
+func unary[Req, Res any](
+	ctx context.Context,
+	req *connect.Request[Req],
+	call func(context.Context, *Req, ...grpc.CallOption) (*Res, error),
+) (*connect.Response[Res], error) {
+	var header, trailer metadata.MD
+	msg, err := call(outgoing(ctx, req.Header(), req.Peer().Addr), req.Msg, grpc.Header(&header), grpc.Trailer(&trailer))
+	if err != nil {
+		return nil, connectError(err, header, trailer)
+	}
+
+	res := connect.NewResponse(msg)
+	copyMetadata(res.Header(), header)
+	copyMetadata(res.Trailer(), trailer)
+	return res, nil
+}
+
+// serverStream forwards a server-streaming call to the gRPC server
+func serverStream[Req, Res any](
+	ctx context.Context,
+	req *connect.Request[Req],
+	stream *connect.ServerStream[Res],
+	call func(context.Context, *Req, ...grpc.CallOption) (grpc.ServerStreamingClient[Res], error),
+) error {
+	upstream, err := call(outgoing(ctx, req.Header(), req.Peer().Addr), req.Msg)
+	if err != nil {
+		return connectError(err, nil, nil)
+	}
+
+	// Header fails only if the call failed, which Recv reports below
+	if header, err := upstream.Header(); err == nil {
+		copyMetadata(stream.ResponseHeader(), header)
+	}
+
+	for {
+		msg, err := upstream.Recv()
+		if errors.Is(err, io.EOF) {
+			copyMetadata(stream.ResponseTrailer(), upstream.Trailer())
+			return nil
+		}
+		if err != nil {
+			return connectError(err, nil, upstream.Trailer())
+		}
+
+		if err := stream.Send(msg); err != nil {
+			return err
+		}
+	}
+}
+
+// bidiStream forwards a bidirectional call to the gRPC server
+func bidiStream[Req, Res any](
+	ctx context.Context,
+	stream *connect.BidiStream[Req, Res],
+	call func(context.Context, ...grpc.CallOption) (grpc.BidiStreamingClient[Req, Res], error),
+) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	upstream, err := call(outgoing(ctx, stream.RequestHeader(), stream.Peer().Addr))
+	if err != nil {
+		return connectError(err, nil, nil)
+	}
+
+	// Relay client messages on a separate goroutine. A half-close is passed
+	// on; any other receive error means the client is gone, so the upstream
+	// call is canceled. Receive returns once this handler exits.
+	go func() {
+		for {
+			msg, err := stream.Receive()
+			if errors.Is(err, io.EOF) {
+				_ = upstream.CloseSend()
+				return
+			}
+			if err != nil {
+				cancel()
+				return
+			}
+
+			if err := upstream.Send(msg); err != nil {
+				return
+			}
+		}
+	}()
+
+	if header, err := upstream.Header(); err == nil {
+		copyMetadata(stream.ResponseHeader(), header)
+	}
+
+	for {
+		msg, err := upstream.Recv()
+		if errors.Is(err, io.EOF) {
+			copyMetadata(stream.ResponseTrailer(), upstream.Trailer())
+			return nil
+		}
+		if err != nil {
+			return connectError(err, nil, upstream.Trailer())
+		}
+
+		if err := stream.Send(msg); err != nil {
+			return err
+		}
+	}
+}
+
+// outgoing returns ctx with the forwarded request headers as outgoing gRPC
+// metadata. The caller's address is appended to x-forwarded-for, the same
+// way the REST gateway does, so the client IP resolver sees the real client.
+func outgoing(ctx context.Context, header http.Header, peerAddr string) context.Context {
+	md := metadata.MD{}
+	for _, key := range forwardedHeaders {
+		if values := header.Values(key); len(values) > 0 {
+			md.Set(key, values...)
+		}
+	}
+
+	if host, _, err := net.SplitHostPort(peerAddr); err == nil {
+		if prior := md.Get("x-forwarded-for"); len(prior) > 0 {
+			md.Set("x-forwarded-for", strings.Join(prior, ", ")+", "+host)
+		} else {
+			md.Set("x-forwarded-for", host)
+		}
+	}
+
+	return metadata.NewOutgoingContext(ctx, md)
+}
+
+// copyMetadata copies gRPC response metadata into HTTP headers, skipping the
+// keys the gRPC transport reserves
+func copyMetadata(dst http.Header, md metadata.MD) {
+	for key, values := range md {
+		if key == "content-type" || strings.HasPrefix(key, "grpc-") {
+			continue
+		}
+
+		for _, value := range values {
+			if strings.HasSuffix(key, "-bin") {
+				value = connect.EncodeBinaryHeader([]byte(value))
+			}
+			dst.Add(key, value)
+		}
+	}
+}
+
+// connectError converts a gRPC status error into a Connect error with the
+// same code, message, details, and metadata
+func connectError(err error, header, trailer metadata.MD) error {
+	st, ok := status.FromError(err)
+	if !ok {
+		return connect.NewError(connect.CodeUnknown, err)
+	}
+
+	connectErr := connect.NewError(connect.Code(st.Code()), errors.New(st.Message()))
+	for _, detail := range st.Proto().GetDetails() {
+		msg, err := detail.UnmarshalNew()
+		if err != nil {
+			continue
+		}
+		if errDetail, err := connect.NewErrorDetail(msg); err == nil {
+			connectErr.AddDetail(errDetail)
+		}
+	}
+
+	copyMetadata(connectErr.Meta(), header)
+	copyMetadata(connectErr.Meta(), trailer)
+	return connectErr
+}
@@ -0,0 +1,141 @@
+package connectapi
+
+import (
+	"context"
+
+	"connectrpc.com/connect"
+
+	pb "github.com/sahays/grpc-proto-go-flutter-template/proto"
+	adminpb "github.com/sahays/grpc-proto-go-flutter-template/proto/admin"
+	chatpb "github.com/sahays/grpc-proto-go-flutter-template/proto/chat"
+)
+
+// authService implements authconnect.AuthServiceHandler by forwarding to the
+// gRPC AuthService
+type authService struct {
+	client pb.AuthServiceClient
+}
+
+func (s *authService) SignUp(ctx context.Context, req *connect.Request[pb.SignUpRequest]) (*connect.Response[pb.SignUpResponse], error) {
+	return unary(ctx, req, s.client.SignUp)
+}
+
+func (s *authService) Login(ctx context.Context, req *connect.Request[pb.LoginRequest]) (*connect.Response[pb.LoginResponse], error) {
+	return unary(ctx, req, s.client.Login)
+}
+
+func (s *authService) ForgotPassword(ctx context.Context, req *connect.Request[pb.ForgotPasswordRequest]) (*connect.Response[pb.ForgotPasswordResponse], error) {
+	return unary(ctx, req, s.client.ForgotPassword)
+}
+
+func (s *authService) ResetPassword(ctx context.Context, req *connect.Request[pb.ResetPasswordRequest]) (*connect.Response[pb.ResetPasswordResponse], error) {
+	return unary(ctx, req, s.client.ResetPassword)
+}
+
+func (s *authService) CheckPasswordStrength(ctx context.Context, req *connect.Request[pb.CheckPasswordStrengthRequest]) (*connect.Response[pb.CheckPasswordStrengthResponse], error) {
+	return unary(ctx, req, s.client.CheckPasswordStrength)
+}
+
+func (s *authService) ValidateToken(ctx context.Context, req *connect.Request[pb.ValidateTokenRequest]) (*connect.Response[pb.ValidateTokenResponse], error) {
+	return unary(ctx, req, s.client.ValidateToken)
+}
+
+func (s *authService) ChangePassword(ctx context.Context, req *connect.Request[pb.ChangePasswordRequest]) (*connect.Response[pb.ChangePasswordResponse], error) {
+	return unary(ctx, req, s.client.ChangePassword)
+}
+
+func (s *authService) UpdateProfile(ctx context.Context, req *connect.Request[pb.UpdateProfileRequest]) (*connect.Response[pb.UpdateProfileResponse], error) {
+	return unary(ctx, req, s.client.UpdateProfile)
+}
+
+func (s *authService) SetRecoveryEmail(ctx context.Context, req *connect.Request[pb.SetRecoveryEmailRequest]) (*connect.Response[pb.SetRecoveryEmailResponse], error) {
+	return unary(ctx, req, s.client.SetRecoveryEmail)
+}
+
+func (s *authService) VerifyRecoveryEmail(ctx context.Context, req *connect.Request[pb.VerifyRecoveryEmailRequest]) (*connect.Response[pb.VerifyRecoveryEmailResponse], error) {
+	return unary(ctx, req, s.client.VerifyRecoveryEmail)
+}
+
+func (s *authService) VerifyEmail(ctx context.Context, req *connect.Request[pb.VerifyEmailRequest]) (*connect.Response[pb.VerifyEmailResponse], error) {
+	return unary(ctx, req, s.client.VerifyEmail)
+}
+
+func (s *authService) ResendVerification(ctx context.Context, req *connect.Request[pb.ResendVerificationRequest]) (*connect.Response[pb.ResendVerificationResponse], error) {
+	return unary(ctx, req, s.client.ResendVerification)
+}
+
+func (s *authService) RefreshToken(ctx context.Context, req *connect.Request[pb.RefreshTokenRequest]) (*connect.Response[pb.RefreshTokenResponse], error) {
+	return unary(ctx, req, s.client.RefreshToken)
+}
+
+func (s *authService) Logout(ctx context.Context, req *connect.Request[pb.LogoutRequest]) (*connect.Response[pb.LogoutResponse], error) {
+	return unary(ctx, req, s.client.Logout)
+}
+
+func (s *authService) StreamSecurityEvents(ctx context.Context, req *connect.Request[pb.StreamSecurityEventsRequest], stream *connect.ServerStream[pb.SecurityEvent]) error {
+	return serverStream(ctx, req, stream, s.client.StreamSecurityEvents)
+}
+
+// adminService implements adminconnect.AdminServiceHandler by forwarding to
+// the gRPC AdminService
+type adminService struct {
+	client adminpb.AdminServiceClient
+}
+
+func (s *adminService) DenyIP(ctx context.Context, req *connect.Request[adminpb.DenyIPRequest]) (*connect.Response[adminpb.DenyIPResponse], error) {
+	return unary(ctx, req, s.client.DenyIP)
+}
+
+func (s *adminService) RemoveDeniedIP(ctx context.Context, req *connect.Request[adminpb.RemoveDeniedIPRequest]) (*connect.Response[adminpb.RemoveDeniedIPResponse], error) {
+	return unary(ctx, req, s.client.RemoveDeniedIP)
+}
+
+func (s *adminService) ListDeniedIPs(ctx context.Context, req *connect.Request[adminpb.ListDeniedIPsRequest]) (*connect.Response[adminpb.ListDeniedIPsResponse], error) {
+	return unary(ctx, req, s.client.ListDeniedIPs)
+}
+
+func (s *adminService) CreateTenant(ctx context.Context, req *connect.Request[adminpb.CreateTenantRequest]) (*connect.Response[adminpb.CreateTenantResponse], error) {
+	return unary(ctx, req, s.client.CreateTenant)
+}
+
+func (s *adminService) GetTenant(ctx context.Context, req *connect.Request[adminpb.GetTenantRequest]) (*connect.Response[adminpb.GetTenantResponse], error) {
+	return unary(ctx, req, s.client.GetTenant)
+}
+
+func (s *adminService) ListTenants(ctx context.Context, req *connect.Request[adminpb.ListTenantsRequest]) (*connect.Response[adminpb.ListTenantsResponse], error) {
+	return unary(ctx, req, s.client.ListTenants)
+}
+
+func (s *adminService) SetTenantActive(ctx context.Context, req *connect.Request[adminpb.SetTenantActiveRequest]) (*connect.Response[adminpb.SetTenantActiveResponse], error) {
+	return unary(ctx, req, s.client.SetTenantActive)
+}
+
+func (s *adminService) SetUserActive(ctx context.Context, req *connect.Request[adminpb.SetUserActiveRequest]) (*connect.Response[adminpb.SetUserActiveResponse], error) {
+	return unary(ctx, req, s.client.SetUserActive)
+}
+
+func (s *adminService) SetUserRole(ctx context.Context, req *connect.Request[adminpb.SetUserRoleRequest]) (*connect.Response[adminpb.SetUserRoleResponse], error) {
+	return unary(ctx, req, s.client.SetUserRole)
+}
+
+func (s *adminService) SetUserMustResetPassword(ctx context.Context, req *connect.Request[adminpb.SetUserMustResetPasswordRequest]) (*connect.Response[adminpb.SetUserMustResetPasswordResponse], error) {
+	return unary(ctx, req, s.client.SetUserMustResetPassword)
+}
+
+func (s *adminService) ListUserHistory(ctx context.Context, req *connect.Request[adminpb.ListUserHistoryRequest]) (*connect.Response[adminpb.ListUserHistoryResponse], error) {
+	return unary(ctx, req, s.client.ListUserHistory)
+}
+
+func (s *adminService) GetHealth(ctx context.Context, req *connect.Request[adminpb.GetHealthRequest]) (*connect.Response[adminpb.GetHealthResponse], error) {
+	return unary(ctx, req, s.client.GetHealth)
+}
+
+// chatService implements chatconnect.ChatServiceHandler by forwarding to the
+// gRPC ChatService
+type chatService struct {
+	client chatpb.ChatServiceClient
+}
+
+func (s *chatService) Chat(ctx context.Context, stream *connect.BidiStream[chatpb.ChatRequest, chatpb.ChatEvent]) error {
+	return bidiStream(ctx, stream, s.client.Chat)
+}
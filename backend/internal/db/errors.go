@@ -0,0 +1,72 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"syscall"
+
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// ErrorClass is how a failed database call should be handled
+type ErrorClass int
+
+const (
+	// ErrorPermanent errors fail the same way if retried, such as constraint
+	// violations, missing rows and bad SQL
+	ErrorPermanent ErrorClass = iota
+	// ErrorTransient errors may not happen again: the connection was lost or
+	// refused, or the server is shutting down, starting up or failing over
+	ErrorTransient
+	// ErrorConflict errors come from losing a race with a concurrent
+	// transaction: serialization failures, deadlocks, and queries on a
+	// replica canceled by replication. The whole transaction may be retried.
+	ErrorConflict
+)
+
+// Classify returns the class of err, which may be wrapped. Cancellation and
+// deadlines are permanent: the caller has given up.
+func Classify(err error) ErrorClass {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return ErrorPermanent
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch {
+		case pgErr.Code == pgerrcode.SerializationFailure,
+			pgErr.Code == pgerrcode.DeadlockDetected:
+			return ErrorConflict
+		case pgerrcode.IsConnectionException(pgErr.Code),
+			pgErr.Code == pgerrcode.AdminShutdown,
+			pgErr.Code == pgerrcode.CrashShutdown,
+			pgErr.Code == pgerrcode.CannotConnectNow,
+			pgErr.Code == pgerrcode.TooManyConnections,
+			// A write reached a primary that was demoted by a failover
+			pgErr.Code == pgerrcode.ReadOnlySQLTransaction:
+			return ErrorTransient
+		default:
+			return ErrorPermanent
+		}
+	}
+
+	// Failures to connect, or to reach the server at all
+	var connectErr *pgconn.ConnectError
+	var netErr net.Error
+	if errors.As(err, &connectErr) || errors.As(err, &netErr) || pgconn.SafeToRetry(err) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.EPIPE) {
+		return ErrorTransient
+	}
+
+	return ErrorPermanent
+}
+
+// IsRetryable reports whether err is transient or a conflict, so retrying
+// the call, or the transaction it was part of, may succeed
+func IsRetryable(err error) bool {
+	return Classify(err) != ErrorPermanent
+}
@@ -0,0 +1,92 @@
+package db
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	minListenBackoff = time.Second
+	maxListenBackoff = 30 * time.Second
+)
+
+// NotificationHandler receives the notifications a Listener delivers
+type NotificationHandler interface {
+	// HandleNotification is called with each notification's payload
+	HandleNotification(payload string)
+	// SetListening is called with true once the listener is listening and
+	// with false when it loses its connection. Notifications sent while it
+	// is not listening are lost.
+	SetListening(listening bool)
+}
+
+// Listener delivers PostgreSQL notifications on one channel (see NOTIFY) to
+// a handler. It holds a dedicated connection outside the pool, and
+// reconnects with backoff if it drops. LISTEN needs a session, so the
+// connection must not go through a transaction-pooling proxy.
+type Listener struct {
+	connConfig *pgx.ConnConfig
+	channel    string
+	handler    NotificationHandler
+}
+
+// NewListener creates a listener connecting with pool's settings. Call Run
+// to start listening.
+func NewListener(pool *pgxpool.Pool, channel string, handler NotificationHandler) *Listener {
+	return &Listener{
+		connConfig: pool.Config().ConnConfig.Copy(),
+		channel:    channel,
+		handler:    handler,
+	}
+}
+
+// Run listens until ctx ends
+func (l *Listener) Run(ctx context.Context) {
+	backoff := minListenBackoff
+	for {
+		listened, err := l.listen(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if listened {
+			backoff = minListenBackoff
+		}
+
+		log.Printf("Listener on %s disconnected, reconnecting in %s: %v", l.channel, backoff, err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxListenBackoff)
+	}
+}
+
+// listen connects, listens and delivers notifications until the connection
+// fails or ctx ends. It reports whether it got as far as listening.
+func (l *Listener) listen(ctx context.Context) (bool, error) {
+	conn, err := pgx.ConnectConfig(ctx, l.connConfig)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close(context.WithoutCancel(ctx))
+
+	if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{l.channel}.Sanitize()); err != nil {
+		return false, err
+	}
+
+	l.handler.SetListening(true)
+	defer l.handler.SetListening(false)
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return true, err
+		}
+		l.handler.HandleNotification(notification.Payload)
+	}
+}
@@ -0,0 +1,124 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	queryDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "db_query_duration_seconds",
+			Help:    "Histogram of database query latency (seconds).",
+			Buckets: []float64{0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5},
+		},
+		[]string{"pool", "operation"},
+	)
+
+	queryRows = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "db_query_rows",
+			Help:    "Histogram of rows affected by database writes.",
+			Buckets: prometheus.ExponentialBuckets(1, 4, 8),
+		},
+		[]string{"pool", "operation"},
+	)
+
+	queryErrorsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "db_query_errors_total",
+			Help: "Total number of database queries that returned an error.",
+		},
+		[]string{"pool", "operation"},
+	)
+)
+
+var (
+	// sqlStringLiteral and sqlNumberLiteral match inline values, leaving
+	// $N placeholders alone
+	sqlStringLiteral = regexp.MustCompile(`'(?:[^']|'')*'`)
+	sqlNumberLiteral = regexp.MustCompile(`(^|[^$\w])\d+(?:\.\d+)?\b`)
+)
+
+type queryStartKey struct{}
+
+// queryStart is what TraceQueryStart hands to TraceQueryEnd
+type queryStart struct {
+	at  time.Time
+	sql string
+}
+
+// queryMetrics records every query's latency, rows and errors, and logs
+// queries slower than slowThreshold. Query arguments are never logged.
+type queryMetrics struct {
+	pool          string
+	slowThreshold time.Duration
+}
+
+func newQueryMetrics(pool string, slowThreshold time.Duration) *queryMetrics {
+	return &queryMetrics{pool: pool, slowThreshold: slowThreshold}
+}
+
+// TraceQueryStart notes when the query started
+func (m *queryMetrics) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	return context.WithValue(ctx, queryStartKey{}, queryStart{at: time.Now(), sql: data.SQL})
+}
+
+// TraceQueryEnd records the query. No rows is an expected outcome, not an
+// error.
+func (m *queryMetrics) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	start, ok := ctx.Value(queryStartKey{}).(queryStart)
+	if !ok {
+		return
+	}
+	elapsed := time.Since(start.at)
+	op := operation(start.sql)
+	failed := data.Err != nil && !errors.Is(data.Err, pgx.ErrNoRows)
+
+	queryDuration.WithLabelValues(m.pool, op).Observe(elapsed.Seconds())
+	if failed {
+		queryErrorsTotal.WithLabelValues(m.pool, op).Inc()
+	} else if op != "SELECT" {
+		queryRows.WithLabelValues(m.pool, op).Observe(float64(data.CommandTag.RowsAffected()))
+	}
+
+	if m.slowThreshold > 0 && elapsed >= m.slowThreshold {
+		if failed {
+			log.Printf("Slow query (%s, %s, error: %v): %s", m.pool, elapsed.Round(time.Millisecond), data.Err, redactSQL(start.sql))
+		} else {
+			log.Printf("Slow query (%s, %s, %d rows): %s", m.pool, elapsed.Round(time.Millisecond), data.CommandTag.RowsAffected(), redactSQL(start.sql))
+		}
+	}
+}
+
+// operation returns the statement's leading keyword, such as SELECT or
+// INSERT, to keep metric labels low-cardinality
+func operation(sql string) string {
+	fields := strings.Fields(sql)
+	if len(fields) == 0 {
+		return "UNKNOWN"
+	}
+
+	switch op := strings.ToUpper(strings.TrimLeft(fields[0], "(")); op {
+	case "SELECT", "INSERT", "UPDATE", "DELETE", "WITH", "BEGIN", "COMMIT", "ROLLBACK":
+		return op
+	default:
+		return "OTHER"
+	}
+}
+
+// redactSQL collapses sql onto one line and masks inline literals so values
+// written into the statement don't reach the logs
+func redactSQL(sql string) string {
+	sql = sqlStringLiteral.ReplaceAllString(sql, "'?'")
+	sql = sqlNumberLiteral.ReplaceAllString(sql, "${1}?")
+	return strings.Join(strings.Fields(sql), " ")
+}
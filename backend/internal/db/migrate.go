@@ -0,0 +1,170 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+
+	"github.com/golang-migrate/migrate/v4"
+	pgxmigrate "github.com/golang-migrate/migrate/v4/database/pgx/v5"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/jackc/pgx/v5/stdlib"
+
+	"github.com/sahays/grpc-proto-go-flutter-template/migrations"
+)
+
+// MigrationStatus is the schema version recorded in the schema_migrations
+// table alongside the newest embedded migration
+type MigrationStatus struct {
+	// Version is the last applied migration, or 0 if none has been applied
+	Version uint
+	// Dirty is set when a migration failed part-way. Nothing else runs until
+	// the schema is repaired by hand and the version forced.
+	Dirty bool
+	// Latest is the newest embedded migration
+	Latest uint
+}
+
+// Pending reports whether embedded migrations have not been applied yet
+func (s MigrationStatus) Pending() bool {
+	return s.Version < s.Latest
+}
+
+// RunMigrations applies all pending embedded migrations. It fails without
+// changing anything when the database is dirty.
+func (db *DB) RunMigrations(ctx context.Context) error {
+	return db.withMigrate(ctx, func(m *migrate.Migrate) error {
+		if err := m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+			return migrationError(err)
+		}
+		return nil
+	})
+}
+
+// MigrateDown rolls back the last steps applied migrations
+func (db *DB) MigrateDown(ctx context.Context, steps int) error {
+	if steps < 1 {
+		return fmt.Errorf("steps must be at least 1")
+	}
+
+	return db.withMigrate(ctx, func(m *migrate.Migrate) error {
+		if err := m.Steps(-steps); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+			return migrationError(err)
+		}
+		return nil
+	})
+}
+
+// ForceMigrationVersion records version as applied and clears the dirty
+// flag without running any migration. Use it after repairing a failed
+// migration by hand; -1 means no migration applied.
+func (db *DB) ForceMigrationVersion(ctx context.Context, version int) error {
+	return db.withMigrate(ctx, func(m *migrate.Migrate) error {
+		return m.Force(version)
+	})
+}
+
+// MigrationStatus returns the applied schema version
+func (db *DB) MigrationStatus(ctx context.Context) (MigrationStatus, error) {
+	var status MigrationStatus
+
+	latest, err := latestMigration()
+	if err != nil {
+		return status, err
+	}
+	status.Latest = latest
+
+	err = db.withMigrate(ctx, func(m *migrate.Migrate) error {
+		version, dirty, err := m.Version()
+		if errors.Is(err, migrate.ErrNilVersion) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		status.Version, status.Dirty = version, dirty
+		return nil
+	})
+	return status, err
+}
+
+// withMigrate runs fn with a migrator over the embedded migrations. The
+// migrator borrows connections from the pool through database/sql, which
+// golang-migrate requires.
+func (db *DB) withMigrate(ctx context.Context, fn func(*migrate.Migrate) error) error {
+	source, err := iofs.New(migrations.FS, ".")
+	if err != nil {
+		return fmt.Errorf("failed to read embedded migrations: %w", err)
+	}
+
+	// Closing this *sql.DB releases its connections back to the pool, which
+	// stays open
+	sqlDB := stdlib.OpenDBFromPool(db.Pool)
+	if err := sqlDB.PingContext(ctx); err != nil {
+		_ = sqlDB.Close()
+		return fmt.Errorf("failed to reach database: %w", err)
+	}
+
+	// The driver closes sqlDB when it is closed
+	driver, err := pgxmigrate.WithInstance(sqlDB, &pgxmigrate.Config{})
+	if err != nil {
+		_ = sqlDB.Close()
+		return fmt.Errorf("failed to initialize migration driver: %w", err)
+	}
+
+	m, err := migrate.NewWithInstance("iofs", source, "pgx5", driver)
+	if err != nil {
+		_ = driver.Close()
+		return fmt.Errorf("failed to initialize migrations: %w", err)
+	}
+	defer m.Close()
+
+	m.Log = migrateLogger{}
+	return fn(m)
+}
+
+// latestMigration returns the newest embedded migration version
+func latestMigration() (uint, error) {
+	source, err := iofs.New(migrations.FS, ".")
+	if err != nil {
+		return 0, fmt.Errorf("failed to read embedded migrations: %w", err)
+	}
+	defer source.Close()
+
+	version, err := source.First()
+	if err != nil {
+		return 0, fmt.Errorf("no embedded migrations: %w", err)
+	}
+	for {
+		next, err := source.Next(version)
+		if errors.Is(err, fs.ErrNotExist) {
+			return version, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+		version = next
+	}
+}
+
+// migrationError explains how to recover from a dirty database
+func migrationError(err error) error {
+	var dirty migrate.ErrDirty
+	if errors.As(err, &dirty) {
+		return fmt.Errorf("migration %d failed part-way and left the database dirty; repair the schema, then force the version: %w", dirty.Version, err)
+	}
+	return err
+}
+
+// migrateLogger prints each applied migration
+type migrateLogger struct{}
+
+func (migrateLogger) Printf(format string, v ...interface{}) {
+	log.Printf("migrate: "+format, v...)
+}
+
+func (migrateLogger) Verbose() bool {
+	return false
+}
@@ -0,0 +1,113 @@
+package db
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	// DefaultPageSize is used when a list request doesn't ask for a size
+	DefaultPageSize = 50
+	// MaxPageSize caps the size a list request may ask for
+	MaxPageSize = 100
+)
+
+// ErrInvalidPageToken is returned for a page token that is malformed or was
+// issued for a different sort order
+var ErrInvalidPageToken = errors.New("invalid page token")
+
+// Keyset pages through rows by their sort keys instead of OFFSET, so every
+// page costs the same however deep it is. Columns must identify a row
+// uniquely (end with the primary key) and be backed by an index in the same
+// order.
+type Keyset struct {
+	Columns []string
+	// Descending sorts every column newest/largest first
+	Descending bool
+}
+
+// OrderBy returns the ORDER BY list for the keyset
+func (k Keyset) OrderBy() string {
+	dir := " ASC"
+	if k.Descending {
+		dir = " DESC"
+	}
+	return strings.Join(k.Columns, dir+", ") + dir
+}
+
+// After returns the condition selecting rows that follow the page token's
+// row, using placeholders $first onwards for the decoded sort keys
+func (k Keyset) After(first int) string {
+	placeholders := make([]string, len(k.Columns))
+	for i := range placeholders {
+		placeholders[i] = fmt.Sprintf("$%d", first+i)
+	}
+
+	op := ">"
+	if k.Descending {
+		op = "<"
+	}
+	return fmt.Sprintf("(%s) %s (%s)", strings.Join(k.Columns, ", "), op, strings.Join(placeholders, ", "))
+}
+
+// PageSize clamps a requested page size to (0, MaxPageSize], using
+// DefaultPageSize when none was requested
+func PageSize(requested int) int {
+	switch {
+	case requested <= 0:
+		return DefaultPageSize
+	case requested > MaxPageSize:
+		return MaxPageSize
+	default:
+		return requested
+	}
+}
+
+// EncodePageToken returns an opaque token holding a row's sort keys, in
+// Keyset.Columns order
+func EncodePageToken(keys ...interface{}) (string, error) {
+	raw, err := json.Marshal(keys)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode page token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+// DecodePageToken reads the sort keys in token into dest, which are pointers
+// in Keyset.Columns order
+func DecodePageToken(token string, dest ...interface{}) error {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return ErrInvalidPageToken
+	}
+
+	var keys []json.RawMessage
+	if err := json.Unmarshal(raw, &keys); err != nil || len(keys) != len(dest) {
+		return ErrInvalidPageToken
+	}
+	for i, key := range keys {
+		if err := json.Unmarshal(key, dest[i]); err != nil {
+			return ErrInvalidPageToken
+		}
+	}
+	return nil
+}
+
+// NextPage trims rows fetched with LIMIT size+1 to size and returns the
+// token for the following page, or "" if this is the last one. keys returns
+// a row's sort keys in Keyset.Columns order.
+func NextPage[T any](rows []T, size int, keys func(T) []interface{}) ([]T, string, error) {
+	if len(rows) <= size {
+		return rows, "", nil
+	}
+
+	rows = rows[:size]
+	token, err := EncodePageToken(keys(rows[size-1])...)
+	if err != nil {
+		return nil, "", err
+	}
+	return rows, token, nil
+}
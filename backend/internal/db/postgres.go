@@ -2,51 +2,80 @@ package db
 
 import (
 	"context"
-	"database/sql"
+	"errors"
 	"fmt"
 	"time"
 
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/multitracer"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+
 	"github.com/sahays/grpc-proto-go-flutter-template/internal/config"
-	_ "github.com/lib/pq"
 )
 
-// DB wraps the database connection
+// DB wraps the connection pool
 type DB struct {
-	*sql.DB
+	*pgxpool.Pool
 	config *config.Config
 }
 
-// New creates a new database connection
+// New creates a new connection pool. Statements are prepared and cached per
+// connection on first use, or as soon as it opens for hot statements (see
+// Prepare).
 func New(cfg *config.Config) (*DB, error) {
-	dsn := cfg.GetDatabaseDSN()
-
-	db, err := sql.Open("postgres", dsn)
+	poolConfig, err := newPoolConfig(cfg, cfg.GetDatabaseDSN(), "primary")
 	if err != nil {
-		return nil, fmt.Errorf("failed to open database: %w", err)
+		return nil, err
 	}
 
-	// Set connection pool settings
-	db.SetMaxOpenConns(cfg.Database.MaxOpenConns)
-	db.SetMaxIdleConns(cfg.Database.MaxIdleConns)
-	db.SetConnMaxLifetime(cfg.Database.ConnMaxLifetime)
+	pool, err := pgxpool.NewWithConfig(context.Background(), poolConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create connection pool: %w", err)
+	}
 
 	// Verify connection
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	if err := db.PingContext(ctx); err != nil {
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
 	return &DB{
-		DB:     db,
+		Pool:   pool,
 		config: cfg,
 	}, nil
 }
 
-// Close closes the database connection
-func (db *DB) Close() error {
-	return db.DB.Close()
+// newPoolConfig applies the configured pool settings to dsn. name labels the
+// pool's query metrics.
+func newPoolConfig(cfg *config.Config, dsn, name string) (*pgxpool.Config, error) {
+	poolConfig, err := pgxpool.ParseConfig(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("invalid database configuration: %w", err)
+	}
+
+	// Set connection pool settings
+	poolConfig.MaxConns = int32(cfg.Database.MaxOpenConns)
+	poolConfig.MinConns = int32(cfg.Database.MinConns)
+	poolConfig.MaxConnLifetime = cfg.Database.ConnMaxLifetime
+	poolConfig.MaxConnIdleTime = cfg.Database.ConnMaxIdleTime
+
+	// Metrics for every query, and child spans when tracing is enabled
+	tracers := []pgx.QueryTracer{newQueryMetrics(name, cfg.Database.SlowQueryThreshold)}
+	if cfg.Tracing.Enabled {
+		tracers = append(tracers, newQueryTracer())
+	}
+	poolConfig.ConnConfig.Tracer = multitracer.New(tracers...)
+
+	if cfg.Database.PrepareStatements {
+		poolConfig.AfterConnect = prepareHotStatements
+	}
+
+	return poolConfig, nil
 }
 
 // Health checks database health
@@ -54,13 +83,13 @@ func (db *DB) Health(ctx context.Context) error {
 	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
 	defer cancel()
 
-	if err := db.PingContext(ctx); err != nil {
+	if err := db.Ping(ctx); err != nil {
 		return fmt.Errorf("database ping failed: %w", err)
 	}
 
 	// Check if we can execute a simple query
 	var result int
-	err := db.QueryRowContext(ctx, "SELECT 1").Scan(&result)
+	err := db.QueryRow(ctx, "SELECT 1").Scan(&result)
 	if err != nil {
 		return fmt.Errorf("database query failed: %w", err)
 	}
@@ -68,31 +97,9 @@ func (db *DB) Health(ctx context.Context) error {
 	return nil
 }
 
-// Stats returns database statistics
-func (db *DB) Stats() sql.DBStats {
-	return db.DB.Stats()
-}
-
-// RunMigrations runs database migrations
-// Note: In production, use migrate CLI or a migration library
-func (db *DB) RunMigrations(ctx context.Context) error {
-	// This is a placeholder - actual migrations should be run using golang-migrate CLI
-	// or a migration library in production
-
-	// For now, just check if migrations table exists
-	var exists bool
-	query := `
-		SELECT EXISTS (
-			SELECT FROM information_schema.tables
-			WHERE table_schema = 'public'
-			AND table_name = 'users'
-		)
-	`
-
-	err := db.QueryRowContext(ctx, query).Scan(&exists)
-	if err != nil {
-		return fmt.Errorf("failed to check migrations: %w", err)
-	}
-
-	return nil
+// IsUniqueViolation reports whether err is a PostgreSQL unique constraint
+// violation
+func IsUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation
 }
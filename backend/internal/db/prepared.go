@@ -0,0 +1,45 @@
+package db
+
+import (
+	"context"
+	"log"
+	"sync"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	hotStatementsMu sync.Mutex
+	hotStatements   []string
+)
+
+// Prepare registers sql as a hot statement and returns it unchanged, so
+// repositories can declare hot queries as package-level variables. With
+// DB_PREPARE_STATEMENTS enabled, each new connection prepares the hot
+// statements as soon as it opens, named by their SQL. Queries run with the
+// same SQL then use them directly, even on a connection's first request.
+// Other statements are prepared and cached on first use, as usual.
+func Prepare(sql string) string {
+	hotStatementsMu.Lock()
+	defer hotStatementsMu.Unlock()
+	hotStatements = append(hotStatements, sql)
+	return sql
+}
+
+// prepareHotStatements is the pool's AfterConnect hook when
+// DB_PREPARE_STATEMENTS is enabled. If preparing fails, for instance before
+// migrations have created the tables, the connection is still usable and the
+// remaining statements are prepared on first use instead.
+func prepareHotStatements(ctx context.Context, conn *pgx.Conn) error {
+	hotStatementsMu.Lock()
+	statements := append([]string(nil), hotStatements...)
+	hotStatementsMu.Unlock()
+
+	for _, sql := range statements {
+		if _, err := conn.Prepare(ctx, sql, sql); err != nil {
+			log.Printf("Hot statements not prepared, preparing on first use: %v", err)
+			break
+		}
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package db
+
+import (
+	"context"
+	"math/rand/v2"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// readRetryBackoff is the wait before the first retry of a read; it
+// doubles for each further retry
+const readRetryBackoff = 25 * time.Millisecond
+
+var readRetriesTotal = promauto.NewCounter(prometheus.CounterOpts{
+	Name: "db_read_retries_total",
+	Help: "Total number of read-only queries retried after a transient failure.",
+})
+
+// retryingReader runs read-only queries, retrying them up to retries times
+// when they fail with a retryable error (see Classify). Each attempt picks
+// its executor afresh, so a retry can land on another replica or the
+// primary. Only failures reported before any row is read are retried: by
+// QueryRow's Scan, or by Query itself. Exec is passed through unretried.
+type retryingReader struct {
+	pick    func() Executor
+	retries int
+}
+
+func (r *retryingReader) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	return r.pick().Exec(ctx, sql, args...)
+}
+
+func (r *retryingReader) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	var rows pgx.Rows
+	err := r.do(ctx, func() (err error) {
+		rows, err = r.pick().Query(ctx, sql, args...)
+		return err
+	})
+	return rows, err
+}
+
+// QueryRow defers the query to Scan, which can then retry it
+func (r *retryingReader) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	return &retryingRow{reader: r, ctx: ctx, sql: sql, args: args}
+}
+
+// do calls fn until it succeeds, fails permanently, or has been retried
+// r.retries times
+func (r *retryingReader) do(ctx context.Context, fn func() error) error {
+	backoff := readRetryBackoff
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= r.retries || !IsRetryable(err) {
+			return err
+		}
+		readRetriesTotal.Inc()
+
+		// Random wait up to backoff, so retries of a shared failure spread out
+		timer := time.NewTimer(rand.N(backoff) + 1)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+		backoff *= 2
+	}
+}
+
+type retryingRow struct {
+	reader *retryingReader
+	ctx    context.Context
+	sql    string
+	args   []interface{}
+}
+
+func (row *retryingRow) Scan(dest ...interface{}) error {
+	return row.reader.do(row.ctx, func() error {
+		return row.reader.pick().QueryRow(row.ctx, row.sql, row.args...).Scan(dest...)
+	})
+}
@@ -0,0 +1,224 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/config"
+)
+
+// replicaLagQuery reports how far the server is behind its primary, in
+// seconds. A replica that has replayed everything it received is current
+// even if the primary has been idle since. A server that is not replicating
+// reports 0.
+const replicaLagQuery = `
+	SELECT CASE
+		WHEN pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+		ELSE COALESCE(EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()), 0)
+	END::float8
+`
+
+// Router sends read-only queries to read replicas and everything else to the
+// primary. Replicas that are unreachable or lag behind the primary by more
+// than the configured maximum are skipped until they catch up; with none
+// available, reads go to the primary.
+type Router struct {
+	primary     *pgxpool.Pool
+	replicas    []*replica
+	maxLag      time.Duration
+	readRetries int
+	next        atomic.Uint64
+	stop        chan struct{}
+	done        chan struct{}
+}
+
+// replica is one read replica's pool and last measured state
+type replica struct {
+	addr    string
+	pool    *pgxpool.Pool
+	healthy atomic.Bool
+
+	mu      sync.Mutex
+	lastLag time.Duration
+	lastErr error
+}
+
+// ReplicaStatus is a read replica's state as of its last lag check
+type ReplicaStatus struct {
+	Addr string
+	// Healthy is set while the replica is serving reads
+	Healthy bool
+	// Lag is the last measured replication lag, zero if the check failed
+	Lag time.Duration
+	// Err is why the last check failed, if it did
+	Err  error
+	Pool *pgxpool.Stat
+}
+
+// NewRouter creates pools for the configured read replicas and starts
+// measuring their lag. Replicas are not required to be up: one that cannot
+// be reached is skipped until it can.
+func NewRouter(cfg *config.Config, primary *pgxpool.Pool) (*Router, error) {
+	r := &Router{
+		primary:     primary,
+		maxLag:      cfg.Database.ReplicaMaxLag,
+		readRetries: cfg.Database.ReadRetries,
+		stop:        make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+
+	for _, addr := range cfg.Database.Replicas {
+		poolConfig, err := newPoolConfig(cfg, cfg.GetReplicaDSN(addr), "replica")
+		if err != nil {
+			r.closeReplicas()
+			return nil, fmt.Errorf("read replica %s: %w", addr, err)
+		}
+
+		pool, err := pgxpool.NewWithConfig(context.Background(), poolConfig)
+		if err != nil {
+			r.closeReplicas()
+			return nil, fmt.Errorf("failed to create pool for read replica %s: %w", addr, err)
+		}
+		r.replicas = append(r.replicas, &replica{addr: addr, pool: pool})
+	}
+
+	if len(r.replicas) == 0 {
+		close(r.done)
+		return r, nil
+	}
+
+	r.checkReplicas()
+	go r.run(cfg.Database.ReplicaCheckInterval)
+
+	return r, nil
+}
+
+// Primary returns the transaction in ctx, or the primary pool outside one
+func (r *Router) Primary(ctx context.Context) Executor {
+	return ExecutorFrom(ctx, r.primary)
+}
+
+// Replica returns an executor for a read-only query. Inside a transaction it
+// is the transaction, so reads see the transaction's own writes. Otherwise
+// it rotates through healthy replicas, falling back to the primary, and
+// retries queries that fail transiently up to DB_READ_RETRIES times.
+func (r *Router) Replica(ctx context.Context) Executor {
+	if tx, ok := ctx.Value(txKey{}).(pgx.Tx); ok {
+		return tx
+	}
+	if r.readRetries > 0 {
+		return &retryingReader{pick: r.pickReplica, retries: r.readRetries}
+	}
+	return r.pickReplica()
+}
+
+// pickReplica returns the next healthy replica, or the primary if none is
+func (r *Router) pickReplica() Executor {
+	n := uint64(len(r.replicas))
+	if n == 0 {
+		return r.primary
+	}
+
+	start := r.next.Add(1)
+	for i := uint64(0); i < n; i++ {
+		if rep := r.replicas[(start+i)%n]; rep.healthy.Load() {
+			return rep.pool
+		}
+	}
+	return r.primary
+}
+
+// Replicas reports the state of each read replica
+func (r *Router) Replicas() []ReplicaStatus {
+	statuses := make([]ReplicaStatus, 0, len(r.replicas))
+	for _, rep := range r.replicas {
+		rep.mu.Lock()
+		statuses = append(statuses, ReplicaStatus{
+			Addr:    rep.addr,
+			Healthy: rep.healthy.Load(),
+			Lag:     rep.lastLag,
+			Err:     rep.lastErr,
+			Pool:    rep.pool.Stat(),
+		})
+		rep.mu.Unlock()
+	}
+	return statuses
+}
+
+// Close stops lag checks and closes the replica pools. The primary pool is
+// left open for its owner to close.
+func (r *Router) Close() {
+	if len(r.replicas) > 0 {
+		close(r.stop)
+	}
+	<-r.done
+	r.closeReplicas()
+}
+
+func (r *Router) closeReplicas() {
+	for _, rep := range r.replicas {
+		rep.pool.Close()
+	}
+}
+
+// run re-measures replica lag every interval until Close
+func (r *Router) run(interval time.Duration) {
+	defer close(r.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-ticker.C:
+			r.checkReplicas()
+		}
+	}
+}
+
+// checkReplicas marks each replica healthy if it answers within the lag
+// limit, logging when a replica leaves or rejoins the rotation
+func (r *Router) checkReplicas() {
+	for _, rep := range r.replicas {
+		lag, err := rep.lag()
+
+		rep.mu.Lock()
+		rep.lastLag, rep.lastErr = lag, err
+		rep.mu.Unlock()
+
+		healthy := err == nil && lag <= r.maxLag
+		if rep.healthy.Swap(healthy) == healthy {
+			continue
+		}
+
+		switch {
+		case healthy:
+			log.Printf("Read replica %s is serving reads (lag %s)", rep.addr, lag)
+		case err != nil:
+			log.Printf("Read replica %s removed from rotation: %v", rep.addr, err)
+		default:
+			log.Printf("Read replica %s removed from rotation: lag %s exceeds %s", rep.addr, lag, r.maxLag)
+		}
+	}
+}
+
+// lag measures how far the replica is behind the primary
+func (rep *replica) lag() (time.Duration, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	var seconds float64
+	if err := rep.pool.QueryRow(ctx, replicaLagQuery).Scan(&seconds); err != nil {
+		return 0, fmt.Errorf("lag check failed: %w", err)
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
@@ -0,0 +1,39 @@
+package db
+
+import (
+	"context"
+	"errors"
+)
+
+// DefaultTenantID is the tenant created by the tenancy migration. Existing
+// users belong to it, platform operators sign in to it, and with tenancy
+// disabled every call runs in it.
+const DefaultTenantID = "00000000-0000-0000-0000-000000000001"
+
+// ErrNoTenant is returned by tenant-scoped repositories called without a
+// tenant in the context
+var ErrNoTenant = errors.New("no tenant in context")
+
+type tenantKey struct{}
+
+// WithTenant returns a copy of ctx scoped to the tenant. Tenant-scoped
+// repositories read and write only that tenant's rows.
+func WithTenant(ctx context.Context, tenantID string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenantID)
+}
+
+// TenantFrom returns the tenant ctx is scoped to
+func TenantFrom(ctx context.Context) (string, bool) {
+	tenantID, ok := ctx.Value(tenantKey{}).(string)
+	return tenantID, ok && tenantID != ""
+}
+
+// RequireTenant returns the tenant ctx is scoped to, or ErrNoTenant. Every
+// tenant-scoped query starts with it, so a missing tenant fails closed.
+func RequireTenant(ctx context.Context) (string, error) {
+	tenantID, ok := TenantFrom(ctx)
+	if !ok {
+		return "", ErrNoTenant
+	}
+	return tenantID, nil
+}
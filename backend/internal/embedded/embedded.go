@@ -0,0 +1,95 @@
+// Package embedded starts PostgreSQL and Redis along with the server, so
+// contributors can run the server without Docker. The SQL stays PostgreSQL:
+// the embedded database is a real PostgreSQL server, so LISTEN/NOTIFY, row
+// locks and the migrations behave exactly as in production.
+package embedded
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"strconv"
+
+	"github.com/alicebob/miniredis/v2"
+	embeddedpostgres "github.com/fergusstrange/embedded-postgres"
+
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/config"
+)
+
+// Start starts the embedded services enabled in cfg where cfg expects to
+// find them, and returns a function that stops them
+func Start(cfg *config.Config) (stop func(), err error) {
+	var stops []func()
+	stop = func() {
+		for i := len(stops) - 1; i >= 0; i-- {
+			stops[i]()
+		}
+	}
+
+	if cfg.Embedded.Postgres {
+		stopPostgres, err := startPostgres(cfg)
+		if err != nil {
+			return nil, err
+		}
+		stops = append(stops, stopPostgres)
+	}
+
+	if cfg.Embedded.Redis {
+		stopRedis, err := startRedis(cfg)
+		if err != nil {
+			stop()
+			return nil, err
+		}
+		stops = append(stops, stopRedis)
+	}
+
+	return stop, nil
+}
+
+// startPostgres starts PostgreSQL on DB_PORT with the configured database
+// and credentials. Data persists in the data directory between runs. The
+// binaries are downloaded on first use and cached in the user's home.
+func startPostgres(cfg *config.Config) (func(), error) {
+	port, err := strconv.ParseUint(cfg.Database.Port, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid DB_PORT %q", cfg.Database.Port)
+	}
+
+	dir := cfg.Embedded.DataDir
+	postgres := embeddedpostgres.NewDatabase(embeddedpostgres.DefaultConfig().
+		Version(embeddedpostgres.V15).
+		Port(uint32(port)).
+		Database(cfg.Database.DBName).
+		Username(cfg.Database.User).
+		Password(cfg.Database.Password).
+		DataPath(filepath.Join(dir, "postgres", "data")).
+		RuntimePath(filepath.Join(dir, "postgres", "runtime")).
+		Logger(log.Writer()))
+
+	if err := postgres.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start embedded PostgreSQL: %w", err)
+	}
+	log.Printf("Embedded PostgreSQL listening on port %d", port)
+
+	return func() {
+		if err := postgres.Stop(); err != nil {
+			log.Printf("Embedded PostgreSQL shutdown error: %v", err)
+		}
+	}, nil
+}
+
+// startRedis starts an in-memory Redis on REDIS_HOST:REDIS_PORT. Its data
+// is lost on shutdown.
+func startRedis(cfg *config.Config) (func(), error) {
+	redis := miniredis.NewMiniRedis()
+	if cfg.Redis.Password != "" {
+		redis.RequireAuth(cfg.Redis.Password)
+	}
+
+	if err := redis.StartAddr(cfg.GetRedisAddr()); err != nil {
+		return nil, fmt.Errorf("failed to start embedded Redis: %w", err)
+	}
+	log.Printf("Embedded Redis listening on %s", redis.Addr())
+
+	return redis.Close, nil
+}
@@ -0,0 +1,95 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/config"
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/middleware"
+	"github.com/sahays/grpc-proto-go-flutter-template/pkg/jwt"
+	pb "github.com/sahays/grpc-proto-go-flutter-template/proto"
+)
+
+// New creates the REST/JSON gateway handler. Requests are proxied to the gRPC
+// server over a loopback connection so they pass through the same interceptors
+// as native gRPC calls. It also publishes jwtService's public keys at
+// /.well-known/jwks.json.
+func New(ctx context.Context, cfg *config.Config, jwtService *jwt.Service) (http.Handler, error) {
+	mux := runtime.NewServeMux(
+		runtime.WithIncomingHeaderMatcher(incomingHeaderMatcher),
+		runtime.WithOutgoingHeaderMatcher(outgoingHeaderMatcher),
+		runtime.WithMarshalerOption(runtime.MIMEWildcard, &runtime.JSONPb{
+			MarshalOptions: protojson.MarshalOptions{
+				UseProtoNames:   true,
+				EmitUnpopulated: true,
+			},
+			UnmarshalOptions: protojson.UnmarshalOptions{
+				DiscardUnknown: true,
+			},
+		}),
+	)
+
+	opts := []grpc.DialOption{
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	}
+
+	if err := pb.RegisterAuthServiceHandlerFromEndpoint(ctx, mux, cfg.GetGRPCDialAddr(), opts); err != nil {
+		return nil, fmt.Errorf("failed to register AuthService gateway: %w", err)
+	}
+
+	root := http.NewServeMux()
+	root.Handle("/", mux)
+
+	if cfg.Server.OpenAPIEnabled {
+		root.HandleFunc("/openapi.json", serveOpenAPISpec)
+	}
+
+	if cfg.Server.JWKSEnabled {
+		serveJWKS, err := jwksHandler(jwtService)
+		if err != nil {
+			return nil, err
+		}
+		root.HandleFunc(jwksPath, serveJWKS)
+	}
+
+	if cfg.Server.OpenAPIEnabled && cfg.Server.SwaggerUIEnabled {
+		root.HandleFunc("/docs/", serveSwaggerUI)
+	}
+
+	return root, nil
+}
+
+// incomingHeaderMatcher forwards x-request-id, Accept-Language, x-tenant and
+// Idempotency-Key to gRPC metadata under their own names in addition to the
+// default set of permanent HTTP headers
+func incomingHeaderMatcher(key string) (string, bool) {
+	if strings.EqualFold(key, middleware.RequestIDHeader) {
+		return middleware.RequestIDHeader, true
+	}
+	if strings.EqualFold(key, middleware.AcceptLanguageHeader) {
+		return middleware.AcceptLanguageHeader, true
+	}
+	if strings.EqualFold(key, middleware.TenantHeader) {
+		return middleware.TenantHeader, true
+	}
+	if strings.EqualFold(key, middleware.IdempotencyKeyHeader) {
+		return middleware.IdempotencyKeyHeader, true
+	}
+	return runtime.DefaultHeaderMatcher(key)
+}
+
+// outgoingHeaderMatcher returns x-request-id as a plain HTTP header and keeps
+// the default Grpc-Metadata- prefix for everything else
+func outgoingHeaderMatcher(key string) (string, bool) {
+	if key == middleware.RequestIDHeader {
+		return "X-Request-Id", true
+	}
+	return fmt.Sprintf("%s%s", runtime.MetadataHeaderPrefix, key), true
+}
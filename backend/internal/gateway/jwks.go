@@ -0,0 +1,38 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/sahays/grpc-proto-go-flutter-template/pkg/jwt"
+)
+
+// jwksPath is where the JWT public keys are published
+const jwksPath = "/.well-known/jwks.json"
+
+// jwksMaxAge is how long verifiers may cache the keys. A new key signs as
+// soon as it is deployed, so verifiers should fetch the keys again when they
+// see an unknown kid.
+const jwksMaxAge = 5 * time.Minute
+
+// jwksHandler serves the public keys tokens are verified with. They only
+// change on restart, so they are encoded once.
+func jwksHandler(jwtService *jwt.Service) (http.HandlerFunc, error) {
+	body, err := json.Marshal(jwtService.JWKS())
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode JWKS: %w", err)
+	}
+	cacheControl := fmt.Sprintf("public, max-age=%d", int(jwksMaxAge.Seconds()))
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", cacheControl)
+		_, _ = w.Write(body)
+	}, nil
+}
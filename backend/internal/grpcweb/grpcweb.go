@@ -0,0 +1,29 @@
+package grpcweb
+
+import (
+	"net/http"
+
+	improbable "github.com/improbable-eng/grpc-web/go/grpcweb"
+	"google.golang.org/grpc"
+
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/config"
+)
+
+// New wraps the gRPC server with an in-process gRPC-Web handler so browser
+// clients (Flutter web) can call it directly without an Envoy sidecar.
+// Cross-origin requests are checked against CORSConfig.
+func New(grpcServer *grpc.Server, cfg *config.Config) http.Handler {
+	wrapped := improbable.WrapServer(grpcServer,
+		improbable.WithOriginFunc(cfg.IsOriginAllowed),
+		improbable.WithAllowedRequestHeaders(cfg.CORS.AllowedHeaders),
+		improbable.WithCorsForRegisteredEndpointsOnly(true),
+	)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if wrapped.IsGrpcWebRequest(r) || wrapped.IsAcceptableGrpcCorsRequest(r) {
+			wrapped.ServeHTTP(w, r)
+			return
+		}
+		http.Error(w, "not a gRPC-Web request", http.StatusUnsupportedMediaType)
+	})
+}
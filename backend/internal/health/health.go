@@ -0,0 +1,204 @@
+// Package health reports the state of each dependency of the server, for the
+// -health-check flag and the GetHealth admin RPC: whether it answers, how
+// fast, its connection pool, and the last error seen. It also exports the
+// connection pools' statistics as Prometheus metrics.
+package health
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/cache"
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/db"
+)
+
+// Component names
+const (
+	Postgres        = "postgres"
+	PostgresReplica = "postgres_replica"
+	Redis           = "redis"
+	// KMS is the key management service tokens are signed in, suffixed with
+	// ":" and its provider
+	KMS = "kms"
+)
+
+// Dependency is a component checked by a round trip of its own, such as
+// the Vault server holding the token signing key
+type Dependency interface {
+	Health(ctx context.Context) error
+}
+
+// Component is the state of one dependency
+type Component struct {
+	// Name is one of the component names; replicas are suffixed with
+	// ":" and their address
+	Name    string
+	Healthy bool
+	// Latency is the round trip of the check, zero if none was made
+	Latency time.Duration
+	// Details are component-specific facts, such as pool statistics
+	Details map[string]string
+	// LastError is the most recent failure seen, which may predate this
+	// check if the component has since recovered
+	LastError   string
+	LastErrorAt time.Time
+}
+
+// Report is the result of one check of every component
+type Report struct {
+	// Healthy is set when every component is
+	Healthy    bool
+	CheckedAt  time.Time
+	Components []Component
+}
+
+// Checker checks PostgreSQL, its read replicas, Redis and any added
+// dependencies. It remembers each component's last error across checks.
+type Checker struct {
+	database     *db.DB
+	router       *db.Router
+	cache        *cache.Cache
+	dependencies []namedDependency
+
+	mu         sync.Mutex
+	lastErrors map[string]lastError
+}
+
+type namedDependency struct {
+	name string
+	Dependency
+}
+
+type lastError struct {
+	message string
+	at      time.Time
+}
+
+// NewChecker creates a checker. router may be nil, for no read replicas.
+func NewChecker(database *db.DB, router *db.Router, c *cache.Cache) *Checker {
+	return &Checker{
+		database:   database,
+		router:     router,
+		cache:      c,
+		lastErrors: make(map[string]lastError),
+	}
+}
+
+// Add adds a dependency to check as the component name. It must be called
+// before the checker is used.
+func (c *Checker) Add(name string, dependency Dependency) {
+	c.dependencies = append(c.dependencies, namedDependency{name: name, Dependency: dependency})
+}
+
+// Check checks every component
+func (c *Checker) Check(ctx context.Context) Report {
+	report := Report{Healthy: true, CheckedAt: time.Now()}
+
+	report.Components = append(report.Components, c.checkPostgres(ctx), c.checkRedis(ctx))
+	if c.router != nil {
+		report.Components = append(report.Components, c.checkReplicas()...)
+	}
+	for _, dependency := range c.dependencies {
+		start := time.Now()
+		err := dependency.Health(ctx)
+		report.Components = append(report.Components, c.component(dependency.name, time.Since(start), nil, err))
+	}
+
+	for _, component := range report.Components {
+		report.Healthy = report.Healthy && component.Healthy
+	}
+	return report
+}
+
+func (c *Checker) checkPostgres(ctx context.Context) Component {
+	start := time.Now()
+	err := c.database.Health(ctx)
+	latency := time.Since(start)
+
+	details := poolDetails(c.database.Stat())
+	if err == nil {
+		var status db.MigrationStatus
+		status, err = c.database.MigrationStatus(ctx)
+		if err == nil {
+			details["migration_version"] = strconv.FormatUint(uint64(status.Version), 10)
+			details["migration_latest"] = strconv.FormatUint(uint64(status.Latest), 10)
+			details["migration_dirty"] = strconv.FormatBool(status.Dirty)
+			if status.Dirty {
+				err = fmt.Errorf("migration %d failed part-way and must be repaired", status.Version)
+			}
+		}
+	}
+
+	return c.component(Postgres, latency, details, err)
+}
+
+func (c *Checker) checkRedis(ctx context.Context) Component {
+	start := time.Now()
+	err := c.cache.Health(ctx)
+	latency := time.Since(start)
+
+	stats := c.cache.Stats()
+	details := map[string]string{
+		"backend":     c.cache.Backend(),
+		"total_conns": strconv.FormatUint(uint64(stats.TotalConns), 10),
+		"idle_conns":  strconv.FormatUint(uint64(stats.IdleConns), 10),
+		"stale_conns": strconv.FormatUint(uint64(stats.StaleConns), 10),
+		"hits":        strconv.FormatUint(uint64(stats.Hits), 10),
+		"misses":      strconv.FormatUint(uint64(stats.Misses), 10),
+		"timeouts":    strconv.FormatUint(uint64(stats.Timeouts), 10),
+	}
+
+	return c.component(Redis, latency, details, err)
+}
+
+// checkReplicas reports the replicas' state as of their last lag check, so
+// it makes no round trip of its own
+func (c *Checker) checkReplicas() []Component {
+	var components []Component
+	for _, replica := range c.router.Replicas() {
+		details := poolDetails(replica.Pool)
+		details["lag"] = replica.Lag.String()
+
+		err := replica.Err
+		if err == nil && !replica.Healthy {
+			err = fmt.Errorf("lag %s exceeds the maximum", replica.Lag)
+		}
+
+		components = append(components, c.component(PostgresReplica+":"+replica.Addr, 0, details, err))
+	}
+	return components
+}
+
+// component builds a component's state, recording err as its last error
+func (c *Checker) component(name string, latency time.Duration, details map[string]string, err error) Component {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err != nil {
+		c.lastErrors[name] = lastError{message: err.Error(), at: time.Now()}
+	}
+	last := c.lastErrors[name]
+
+	return Component{
+		Name:        name,
+		Healthy:     err == nil,
+		Latency:     latency,
+		Details:     details,
+		LastError:   last.message,
+		LastErrorAt: last.at,
+	}
+}
+
+func poolDetails(stat *pgxpool.Stat) map[string]string {
+	return map[string]string{
+		"total_conns":    strconv.Itoa(int(stat.TotalConns())),
+		"acquired_conns": strconv.Itoa(int(stat.AcquiredConns())),
+		"idle_conns":     strconv.Itoa(int(stat.IdleConns())),
+		"max_conns":      strconv.Itoa(int(stat.MaxConns())),
+	}
+}
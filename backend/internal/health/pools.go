@@ -0,0 +1,137 @@
+package health
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/cache"
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/db"
+)
+
+// PostgreSQL pool gauges, labeled "primary" or "replica:" and the address.
+// The wait gauges are running totals since startup.
+var (
+	dbPoolOpen = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "db_pool_connections_open",
+		Help: "Open PostgreSQL connections, in use or idle.",
+	}, []string{"pool"})
+
+	dbPoolInUse = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "db_pool_connections_in_use",
+		Help: "PostgreSQL connections acquired by a query or transaction.",
+	}, []string{"pool"})
+
+	dbPoolIdle = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "db_pool_connections_idle",
+		Help: "Idle PostgreSQL connections.",
+	}, []string{"pool"})
+
+	dbPoolMax = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "db_pool_connections_max",
+		Help: "Maximum PostgreSQL connections in the pool.",
+	}, []string{"pool"})
+
+	dbPoolWaitCount = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "db_pool_wait_count",
+		Help: "Connection acquires that had to wait because the pool had no idle connection.",
+	}, []string{"pool"})
+
+	dbPoolWaitDuration = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "db_pool_wait_duration_seconds",
+		Help: "Total time spent acquiring PostgreSQL connections (seconds).",
+	}, []string{"pool"})
+)
+
+// Redis pool gauges. The wait and timeout gauges are running totals.
+var (
+	redisPoolOpen = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "redis_pool_connections_open",
+		Help: "Open Redis connections, in use or idle.",
+	})
+
+	redisPoolInUse = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "redis_pool_connections_in_use",
+		Help: "Redis connections taken by a command.",
+	})
+
+	redisPoolIdle = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "redis_pool_connections_idle",
+		Help: "Idle Redis connections.",
+	})
+
+	redisPoolWaitCount = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "redis_pool_wait_count",
+		Help: "Times a command waited for a free Redis connection.",
+	})
+
+	redisPoolWaitDuration = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "redis_pool_wait_duration_seconds",
+		Help: "Total time spent waiting for a free Redis connection (seconds).",
+	})
+
+	redisPoolTimeouts = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "redis_pool_timeouts",
+		Help: "Times a command gave up waiting for a free Redis connection.",
+	})
+)
+
+// PoolSampler copies the PostgreSQL and Redis connection pool statistics
+// into Prometheus gauges
+type PoolSampler struct {
+	database *db.DB
+	router   *db.Router
+	cache    *cache.Cache
+}
+
+// NewPoolSampler creates a sampler. router may be nil, for no read
+// replicas.
+func NewPoolSampler(database *db.DB, router *db.Router, c *cache.Cache) *PoolSampler {
+	return &PoolSampler{database: database, router: router, cache: c}
+}
+
+// Run samples the pools every interval until ctx is done
+func (s *PoolSampler) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	s.Sample(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.Sample(ctx)
+		}
+	}
+}
+
+// Sample updates the gauges once
+func (s *PoolSampler) Sample(ctx context.Context) {
+	setPoolGauges("primary", s.database.Stat())
+	if s.router != nil {
+		for _, replica := range s.router.Replicas() {
+			setPoolGauges("replica:"+replica.Addr, replica.Pool)
+		}
+	}
+
+	stats := s.cache.Stats()
+	redisPoolOpen.Set(float64(stats.TotalConns))
+	redisPoolInUse.Set(float64(stats.TotalConns - min(stats.IdleConns, stats.TotalConns)))
+	redisPoolIdle.Set(float64(stats.IdleConns))
+	redisPoolWaitCount.Set(float64(stats.WaitCount))
+	redisPoolWaitDuration.Set(stats.WaitDuration.Seconds())
+	redisPoolTimeouts.Set(float64(stats.Timeouts))
+}
+
+func setPoolGauges(pool string, stat *pgxpool.Stat) {
+	dbPoolOpen.WithLabelValues(pool).Set(float64(stat.TotalConns()))
+	dbPoolInUse.WithLabelValues(pool).Set(float64(stat.AcquiredConns()))
+	dbPoolIdle.WithLabelValues(pool).Set(float64(stat.IdleConns()))
+	dbPoolMax.WithLabelValues(pool).Set(float64(stat.MaxConns()))
+	dbPoolWaitCount.WithLabelValues(pool).Set(float64(stat.EmptyAcquireCount()))
+	dbPoolWaitDuration.WithLabelValues(pool).Set(stat.AcquireDuration().Seconds())
+}
@@ -0,0 +1,74 @@
+// Package history records who changed a user, and how, in the user_history
+// table. Each change is recorded in the same transaction as the change
+// itself, with snapshots of the user before and after.
+package history
+
+import (
+	"context"
+
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/authz"
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/db"
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/middleware"
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/models"
+)
+
+// Recorder runs user changes and records them
+type Recorder struct {
+	users     *models.UserRepository
+	entries   *models.UserHistoryRepository
+	txManager *db.TxManager
+}
+
+// NewRecorder creates a recorder
+func NewRecorder(users *models.UserRepository, entries *models.UserHistoryRepository, txManager *db.TxManager) *Recorder {
+	return &Recorder{users: users, entries: entries, txManager: txManager}
+}
+
+// Track runs change, which modifies the user, and records the change as
+// action. The user is locked from the before snapshot to the end of the
+// transaction, so the snapshots bracket exactly this change. The actor is
+// the authenticated caller in ctx, if any. Errors from change are returned
+// unwrapped, and roll the change back unrecorded.
+func (r *Recorder) Track(ctx context.Context, userID, action string, change func(ctx context.Context) error) (*models.UserHistoryEntry, error) {
+	var entry *models.UserHistoryEntry
+
+	err := r.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		before, err := r.users.GetForUpdate(ctx, userID)
+		if err != nil {
+			return err
+		}
+
+		if err := change(ctx); err != nil {
+			return err
+		}
+
+		after, err := r.users.GetByID(ctx, userID)
+		if err != nil {
+			return err
+		}
+
+		entry = &models.UserHistoryEntry{
+			TenantID:  before.TenantID,
+			UserID:    userID,
+			Action:    action,
+			ActorRole: authz.RoleAnonymous,
+			RequestID: middleware.RequestIDFromContext(ctx),
+			Before:    models.SnapshotOf(before),
+			After:     models.SnapshotOf(after),
+		}
+		if claims, ok := middleware.ClaimsFromContext(ctx); ok {
+			entry.ActorID = claims.UserID
+			entry.ActorRole = claims.Role
+			if entry.ActorRole == "" {
+				entry.ActorRole = authz.RoleUser
+			}
+		}
+
+		return r.entries.Add(ctx, entry)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return entry, nil
+}
@@ -0,0 +1,158 @@
+package i18n
+
+// catalogES is the Spanish catalog
+var catalogES = map[string]string{
+	// Authentication
+	"authentication required":                                "se requiere autenticación",
+	"authorization token is required":                        "se requiere un token de autorización",
+	"missing metadata":                                       "faltan los metadatos de la solicitud",
+	"invalid or expired token":                               "el token no es válido o ha caducado",
+	"token scope does not allow this method":                 "el alcance del token no permite este método",
+	"invalid email or password":                              "correo electrónico o contraseña incorrectos",
+	"account is disabled":                                    "la cuenta está deshabilitada",
+	"password must be changed; sign in again to change it":   "debe cambiar la contraseña; inicie sesión de nuevo para cambiarla",
+	"email address is not verified":                          "la dirección de correo electrónico no está verificada",
+	"too many failed login attempts, please try again later": "demasiados intentos de inicio de sesión fallidos, inténtalo de nuevo más tarde",
+	"server is overloaded, please try again later":           "el servidor está sobrecargado, inténtalo de nuevo más tarde",
+	"rate limit exceeded, please try again later":            "se superó el límite de solicitudes, inténtalo de nuevo más tarde",
+	"service is unavailable, please try again later":         "el servicio no está disponible, inténtalo de nuevo más tarde",
+	"user not found":                                         "usuario no encontrado",
+	"access from this address is not allowed":                "no se permite el acceso desde esta dirección",
+	"not allowed to call this method":                        "no tienes permiso para llamar a este método",
+
+	// Account management
+	"email already registered":                   "el correo electrónico ya está registrado",
+	"current password is incorrect":              "la contraseña actual es incorrecta",
+	"invalid or expired reset token":             "el token de restablecimiento no es válido o ha caducado",
+	"invalid or expired verification token":      "el token de verificación no es válido o ha caducado",
+	"invalid refresh token":                      "el token de actualización no es válido",
+	"refresh token was issued to another device": "el token de actualización se emitió para otro dispositivo",
+	"failed to subscribe to security events":     "no se pudo suscribir a los eventos de seguridad",
+	"security event subscription closed":         "se cerró la suscripción a los eventos de seguridad",
+	"is already registered":                      "ya está registrado",
+	"is incorrect":                               "es incorrecta",
+	"is invalid":                                 "no es válido",
+	"is invalid or expired":                      "no es válido o ha caducado",
+	"must differ from current password":          "debe ser distinta de la contraseña actual",
+	"must differ from primary email":             "debe ser distinto del correo electrónico principal",
+	"is a commonly used password":                "es una contraseña muy usada",
+	"is too easy to guess":                       "es demasiado fácil de adivinar",
+	"user was changed by another request":        "otra solicitud modificó el usuario",
+
+	// Password strength feedback (see pkg/password)
+	"This is a top-10 common password":                                       "Es una de las 10 contraseñas más comunes",
+	"This is a top-100 common password":                                      "Es una de las 100 contraseñas más comunes",
+	"This is a very common password":                                         "Es una contraseña muy común",
+	"This is similar to a commonly used password":                            "Se parece a una contraseña muy usada",
+	"A word by itself is easy to guess":                                      "Una palabra sola es fácil de adivinar",
+	"Your name or email address is easy to guess":                            "Tu nombre o tu correo electrónico son fáciles de adivinar",
+	"Straight rows of keys are easy to guess":                                "Las filas de teclas seguidas son fáciles de adivinar",
+	`Repeats like "aaa" are easy to guess`:                                   `Las repeticiones como "aaa" son fáciles de adivinar`,
+	`Repeats like "abcabcabc" are only slightly harder to guess than "abc"`:  `Las repeticiones como "abcabcabc" son apenas más difíciles de adivinar que "abc"`,
+	"Sequences like abc or 6543 are easy to guess":                           "Las secuencias como abc o 6543 son fáciles de adivinar",
+	"Recent years are easy to guess":                                         "Los años recientes son fáciles de adivinar",
+	"Dates are often easy to guess":                                          "Las fechas suelen ser fáciles de adivinar",
+	"Use a few words, avoid common phrases":                                  "Usa varias palabras y evita frases comunes",
+	"No need for symbols, digits, or uppercase letters":                      "No hacen falta símbolos, números ni mayúsculas",
+	"Add another word or two. Uncommon words are better.":                    "Añade una o dos palabras más. Mejor si son poco comunes.",
+	"Capitalization doesn't help very much":                                  "Las mayúsculas no ayudan mucho",
+	"All-uppercase is almost as easy to guess as all-lowercase":              "Todo en mayúsculas es casi tan fácil de adivinar como todo en minúsculas",
+	"Reversed words aren't much harder to guess":                             "Las palabras al revés no son mucho más difíciles de adivinar",
+	"Predictable substitutions like '@' instead of 'a' don't help very much": "Las sustituciones previsibles como '@' en lugar de 'a' no ayudan mucho",
+	"Use a longer keyboard pattern with more turns":                          "Usa un patrón de teclado más largo y con más giros",
+	"Avoid repeated words and characters":                                    "Evita repetir palabras y caracteres",
+	"Avoid sequences":                                                        "Evita las secuencias",
+	"Avoid recent years and years that are associated with you":              "Evita los años recientes y los que tengan que ver contigo",
+	"Avoid dates and years that are associated with you":                     "Evita las fechas y los años que tengan que ver contigo",
+
+	// Idempotency
+	"invalid idempotency key":                            "la clave de idempotencia no es válida",
+	"idempotency key was used with a different request":  "la clave de idempotencia se usó con otra solicitud",
+	"a request with this idempotency key is in progress": "hay una solicitud en curso con esta clave de idempotencia",
+
+	// Field validation, the messages of the buf.validate rules
+	"value is required":                                  "el valor es obligatorio",
+	"exactly one field is required in oneof":             "se requiere exactamente uno de los campos",
+	"value must equal {0}":                               "el valor debe ser igual a {0}",
+	"value length must be {0} characters":                "el valor debe tener {0} caracteres",
+	"value length must be at least {0} characters":       "el valor debe tener al menos {0} caracteres",
+	"value length must be at most {0} characters":        "el valor debe tener como máximo {0} caracteres",
+	"value length must be {0} bytes":                     "el valor debe tener {0} bytes",
+	"value length must be at least {0} bytes":            "el valor debe tener al menos {0} bytes",
+	"value length must be at most {0} bytes":             "el valor debe tener como máximo {0} bytes",
+	"value does not match regex pattern {0}":             "el valor no coincide con el patrón {0}",
+	"value does not have prefix {0}":                     "el valor no empieza por {0}",
+	"value does not have suffix {0}":                     "el valor no termina en {0}",
+	"value does not contain substring {0}":               "el valor no contiene {0}",
+	"value contains substring {0}":                       "el valor contiene {0}",
+	"value must be in list {0}":                          "el valor debe estar en la lista {0}",
+	"value must not be in list {0}":                      "el valor no debe estar en la lista {0}",
+	"value must be a valid email address":                "el valor debe ser una dirección de correo electrónico válida",
+	"value is empty, which is not a valid email address": "el valor está vacío, lo que no es una dirección de correo electrónico válida",
+	"value must be a valid hostname":                     "el valor debe ser un nombre de host válido",
+	"value must be a valid IP address":                   "el valor debe ser una dirección IP válida",
+	"value must be a valid URI":                          "el valor debe ser un URI válido",
+	"value must be a valid UUID":                         "el valor debe ser un UUID válido",
+	"value is empty, which is not a valid UUID":          "el valor está vacío, lo que no es un UUID válido",
+	"value must be greater than {0}":                     "el valor debe ser mayor que {0}",
+	"value must be greater than or equal to {0}":         "el valor debe ser mayor o igual que {0}",
+	"value must be less than {0}":                        "el valor debe ser menor que {0}",
+	"value must be less than or equal to {0}":            "el valor debe ser menor o igual que {0}",
+	"value must contain at least {0} item(s)":            "el valor debe contener al menos {0} elementos",
+	"value must contain no more than {0} item(s)":        "el valor no debe contener más de {0} elementos",
+	"repeated value must contain unique items":           "los elementos del valor repetido deben ser únicos",
+	"map must be at least {0} entries":                   "el mapa debe tener al menos {0} entradas",
+	"map must be at most {0} entries":                    "el mapa debe tener como máximo {0} entradas",
+	"value must be one of the defined enum values":       "el valor debe ser uno de los valores definidos de la enumeración",
+
+	// Admin
+	"must be an IP address or CIDR range": "debe ser una dirección IP o un rango CIDR",
+
+	// Tenancy
+	"tenant is required":                      "se requiere un inquilino",
+	"unknown tenant":                          "inquilino desconocido",
+	"tenant is inactive":                      "el inquilino está inactivo",
+	"token was issued for a different tenant": "el token se emitió para otro inquilino",
+	"only operators of the default tenant may call this method": "solo los operadores del inquilino predeterminado pueden llamar a este método",
+	"tenant not found":               "inquilino no encontrado",
+	"tenant slug already in use":     "el identificador del inquilino ya está en uso",
+	"must not be the default tenant": "no debe ser el inquilino predeterminado",
+	"is already in use":              "ya está en uso",
+	"invalid page token":             "el token de página no es válido",
+
+	// Chat
+	"first message must join a room":         "el primer mensaje debe unirse a una sala",
+	"already joined a room":                  "ya te has unido a una sala",
+	"client is not keeping up with the room": "el cliente no procesa los mensajes de la sala a tiempo",
+	"server is shutting down":                "el servidor se está apagando",
+
+	// Internal errors
+	"internal server error":               "error interno del servidor",
+	"failed to check email existence":     "no se pudo comprobar el correo electrónico",
+	"failed to create access token":       "no se pudo crear el token de acceso",
+	"failed to create refresh token":      "no se pudo crear el token de actualización",
+	"failed to create reset token":        "no se pudo crear el token de restablecimiento",
+	"failed to create user":               "no se pudo crear el usuario",
+	"failed to create verification token": "no se pudo crear el token de verificación",
+	"failed to load IP denylist":          "no se pudo cargar la lista de IP bloqueadas",
+	"failed to update IP denylist":        "no se pudo actualizar la lista de IP bloqueadas",
+	"failed to hash password":             "no se pudo procesar la contraseña",
+	"failed to revoke access token":       "no se pudo revocar el token de acceso",
+	"failed to revoke refresh token":      "no se pudo revocar el token de actualización",
+	"failed to revoke user tokens":        "no se pudieron revocar los tokens del usuario",
+	"failed to set recovery email":        "no se pudo establecer el correo de recuperación",
+	"failed to store refresh token":       "no se pudo guardar el token de actualización",
+	"failed to update profile":            "no se pudo actualizar el perfil",
+	"failed to update password":           "no se pudo actualizar la contraseña",
+	"failed to verify email":              "no se pudo verificar el correo electrónico",
+	"failed to resolve tenant":            "no se pudo determinar el inquilino",
+	"failed to create tenant":             "no se pudo crear el inquilino",
+	"failed to load tenant":               "no se pudo cargar el inquilino",
+	"failed to list tenants":              "no se pudieron listar los inquilinos",
+	"failed to update tenant":             "no se pudo actualizar el inquilino",
+	"failed to update user":               "no se pudo actualizar el usuario",
+	"failed to load user":                 "no se pudo cargar el usuario",
+	"failed to list user history":         "no se pudo listar el historial del usuario",
+	"failed to load user history":         "no se pudo cargar el historial del usuario",
+	"authorization failed":                "no se pudo comprobar la autorización",
+}
@@ -0,0 +1,87 @@
+// Package i18n translates the human-readable text of API errors. Machine
+// codes (status codes, ErrorInfo reasons, field names) are never translated.
+package i18n
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"golang.org/x/text/language"
+)
+
+// catalogs maps a base language to its English→translated message catalog.
+// English is the source language and needs no catalog. Keys may contain a
+// {0} placeholder that matches any text and is copied into the translation.
+var catalogs = map[string]map[string]string{
+	"es": catalogES,
+}
+
+// supported lists the languages clients can ask for, in priority order; the
+// first is the fallback when nothing in Accept-Language matches
+var supported = []language.Tag{
+	language.English,
+	language.Spanish,
+}
+
+var matcher = language.NewMatcher(supported)
+
+// templates holds the compiled placeholder keys of each catalog
+var templates = compileTemplates()
+
+type template struct {
+	pattern     *regexp.Regexp
+	translation string
+}
+
+// Language returns the best supported base language (e.g. "es") for an
+// Accept-Language value
+func Language(acceptLanguage string) string {
+	tag, _ := language.MatchStrings(matcher, acceptLanguage)
+	base, _ := tag.Base()
+	return base.String()
+}
+
+// Translate returns message in lang, or message unchanged when lang is
+// English or the catalog has no entry for it
+func Translate(lang, message string) string {
+	catalog, ok := catalogs[lang]
+	if !ok {
+		return message
+	}
+
+	if translated, ok := catalog[message]; ok {
+		return translated
+	}
+
+	for _, t := range templates[lang] {
+		if match := t.pattern.FindStringSubmatch(message); match != nil {
+			return strings.Replace(t.translation, "{0}", match[1], 1)
+		}
+	}
+
+	return message
+}
+
+func compileTemplates() map[string][]template {
+	compiled := make(map[string][]template, len(catalogs))
+	for lang, catalog := range catalogs {
+		for key, translation := range catalog {
+			if !strings.Contains(key, "{0}") {
+				continue
+			}
+			expr := "^" + strings.Replace(regexp.QuoteMeta(key), `\{0\}`, "(.+)", 1) + "$"
+			compiled[lang] = append(compiled[lang], template{
+				pattern:     regexp.MustCompile(expr),
+				translation: translation,
+			})
+		}
+
+		// Try longer keys first so "must contain at least {0} items" wins
+		// over "must contain {0}"
+		sort.Slice(compiled[lang], func(i, j int) bool {
+			return len(compiled[lang][i].pattern.String()) > len(compiled[lang][j].pattern.String())
+		})
+	}
+	return compiled
+}
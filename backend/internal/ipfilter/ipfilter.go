@@ -0,0 +1,140 @@
+// Package ipfilter decides whether a client address may reach the server,
+// from static CIDR allow/deny lists and the Redis-backed dynamic denylist
+package ipfilter
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/cache"
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/clientip"
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/config"
+)
+
+// Filter holds the compiled lists. The dynamic denylist is an in-memory copy
+// of Redis, so checks never wait on the network.
+type Filter struct {
+	allow   []*net.IPNet
+	deny    []*net.IPNet
+	dynamic atomic.Pointer[[]*net.IPNet]
+
+	cache           *cache.Cache
+	refreshInterval time.Duration
+	logger          *zap.Logger
+}
+
+// New compiles the configured lists. Call Run to keep the dynamic denylist
+// in sync.
+func New(cfg *config.Config, c *cache.Cache, logger *zap.Logger) (*Filter, error) {
+	allow, err := clientip.ParseCIDRs(cfg.IPFilter.Allowlist)
+	if err != nil {
+		return nil, fmt.Errorf("IP_ALLOWLIST: %w", err)
+	}
+
+	deny, err := clientip.ParseCIDRs(cfg.IPFilter.Denylist)
+	if err != nil {
+		return nil, fmt.Errorf("IP_DENYLIST: %w", err)
+	}
+
+	f := &Filter{
+		allow:           allow,
+		deny:            deny,
+		cache:           c,
+		refreshInterval: cfg.IPFilter.RefreshInterval,
+		logger:          logger,
+	}
+	f.dynamic.Store(&[]*net.IPNet{})
+
+	return f, nil
+}
+
+// Run loads the dynamic denylist and reloads it whenever an instance changes
+// it and every refresh interval (to drop expired entries), until ctx ends.
+// If subscribing fails it is retried every refresh interval.
+func (f *Filter) Run(ctx context.Context) {
+	f.refresh(ctx)
+
+	var sub cache.Subscription
+	defer func() {
+		if sub != nil {
+			_ = sub.Close()
+		}
+	}()
+	var changes <-chan []byte
+	subscribe := func() {
+		var err error
+		sub, err = f.cache.SubscribeIPDenylist(ctx)
+		if err != nil {
+			if ctx.Err() == nil {
+				f.logger.Warn("failed to subscribe to IP denylist changes", zap.Error(err))
+			}
+			return
+		}
+		changes = sub.Messages()
+	}
+	subscribe()
+
+	ticker := time.NewTicker(f.refreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case _, ok := <-changes:
+			if !ok {
+				// Closed: resubscribe on the next tick
+				sub, changes = nil, nil
+				continue
+			}
+			f.refresh(ctx)
+		case <-ticker.C:
+			if sub == nil {
+				subscribe()
+			}
+			f.refresh(ctx)
+		}
+	}
+}
+
+// refresh replaces the in-memory dynamic denylist. On a cache error the
+// previous copy stays in effect.
+func (f *Filter) refresh(ctx context.Context) {
+	entries, err := f.cache.DeniedIPs(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			f.logger.Warn("failed to load IP denylist", zap.Error(err))
+		}
+		return
+	}
+
+	nets := make([]*net.IPNet, 0, len(entries))
+	for _, entry := range entries {
+		_, ipNet, err := net.ParseCIDR(entry.CIDR)
+		if err != nil {
+			f.logger.Warn("skipping invalid IP denylist entry", zap.String("cidr", entry.CIDR))
+			continue
+		}
+		nets = append(nets, ipNet)
+	}
+	f.dynamic.Store(&nets)
+}
+
+// Allowed reports whether ip may connect. Denylists take precedence over the
+// allowlist; an unparseable address is only allowed when no allowlist is set.
+func (f *Filter) Allowed(ip net.IP) bool {
+	if ip == nil {
+		return len(f.allow) == 0
+	}
+
+	if clientip.Contains(f.deny, ip) || clientip.Contains(*f.dynamic.Load(), ip) {
+		return false
+	}
+
+	return len(f.allow) == 0 || clientip.Contains(f.allow, ip)
+}
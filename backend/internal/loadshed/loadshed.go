@@ -0,0 +1,196 @@
+// Package loadshed rejects work the server cannot absorb instead of letting
+// queues build up until everything times out. A global concurrency limit
+// adapts to observed latency (additive increase, multiplicative decrease) and
+// fixed per-method caps protect expensive handlers such as password hashing.
+package loadshed
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/config"
+)
+
+// ErrOverloaded is returned when a request can't get a slot in time
+var ErrOverloaded = errors.New("server is overloaded")
+
+// backoffRatio is applied to the limit on each slow or failed request
+const backoffRatio = 0.9
+
+// Limiter admits requests under the global and per-method limits
+type Limiter struct {
+	mu       sync.Mutex
+	limit    float64
+	inflight int
+	queue    []chan struct{}
+
+	minLimit      float64
+	maxLimit      float64
+	targetLatency time.Duration
+	maxQueue      int
+	queueTimeout  time.Duration
+
+	// methods holds a semaphore per capped method
+	methods map[string]chan struct{}
+}
+
+// New creates a limiter from cfg
+func New(cfg config.LoadShedConfig) *Limiter {
+	l := &Limiter{
+		limit:         float64(cfg.InitialLimit),
+		minLimit:      float64(cfg.MinLimit),
+		maxLimit:      float64(cfg.MaxLimit),
+		targetLatency: cfg.TargetLatency,
+		maxQueue:      cfg.MaxQueue,
+		queueTimeout:  cfg.QueueTimeout,
+		methods:       make(map[string]chan struct{}, len(cfg.MethodLimits)),
+	}
+	l.limit = clamp(l.limit, l.minLimit, l.maxLimit)
+
+	for method, n := range cfg.MethodLimits {
+		if n > 0 {
+			l.methods[method] = make(chan struct{}, n)
+		}
+	}
+
+	return l
+}
+
+// Acquire waits for a slot for method. On success the caller must call
+// release once the request finishes, reporting whether it failed in a way
+// that signals overload (e.g. a deadline was exceeded).
+func (l *Limiter) Acquire(ctx context.Context, method string) (release func(overloaded bool), err error) {
+	sem := l.methods[method]
+	if sem != nil {
+		if err := l.acquireMethod(ctx, sem); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := l.acquire(ctx); err != nil {
+		if sem != nil {
+			<-sem
+		}
+		return nil, err
+	}
+
+	start := time.Now()
+	return func(overloaded bool) {
+		l.release(time.Since(start), overloaded)
+		if sem != nil {
+			<-sem
+		}
+	}, nil
+}
+
+// Limit returns the current global concurrency limit
+func (l *Limiter) Limit() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return int(l.limit)
+}
+
+// Inflight returns the number of requests holding a global slot
+func (l *Limiter) Inflight() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.inflight
+}
+
+func (l *Limiter) acquireMethod(ctx context.Context, sem chan struct{}) error {
+	select {
+	case sem <- struct{}{}:
+		return nil
+	default:
+	}
+
+	timer := time.NewTimer(l.queueTimeout)
+	defer timer.Stop()
+
+	select {
+	case sem <- struct{}{}:
+		return nil
+	case <-timer.C:
+		return ErrOverloaded
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (l *Limiter) acquire(ctx context.Context) error {
+	l.mu.Lock()
+	if len(l.queue) == 0 && l.inflight < int(l.limit) {
+		l.inflight++
+		l.mu.Unlock()
+		return nil
+	}
+	if len(l.queue) >= l.maxQueue {
+		l.mu.Unlock()
+		return ErrOverloaded
+	}
+
+	// release hands the slot over by closing ready, already counted in inflight
+	ready := make(chan struct{})
+	l.queue = append(l.queue, ready)
+	l.mu.Unlock()
+
+	timer := time.NewTimer(l.queueTimeout)
+	defer timer.Stop()
+
+	var err error
+	select {
+	case <-ready:
+		return nil
+	case <-timer.C:
+		err = ErrOverloaded
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for i, waiter := range l.queue {
+		if waiter == ready {
+			l.queue = append(l.queue[:i], l.queue[i+1:]...)
+			return err
+		}
+	}
+
+	// The slot was handed over while timing out; keep it
+	return nil
+}
+
+func (l *Limiter) release(latency time.Duration, overloaded bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// Grow only while the limit is actually being used, so an idle server
+	// doesn't drift up to the maximum
+	utilized := l.inflight >= int(l.limit)/2
+	l.inflight--
+
+	if overloaded || latency > l.targetLatency {
+		l.limit = clamp(l.limit*backoffRatio, l.minLimit, l.maxLimit)
+	} else if utilized {
+		l.limit = clamp(l.limit+1/l.limit, l.minLimit, l.maxLimit)
+	}
+
+	for len(l.queue) > 0 && l.inflight < int(l.limit) {
+		ready := l.queue[0]
+		l.queue = l.queue[1:]
+		l.inflight++
+		close(ready)
+	}
+}
+
+func clamp(v, lo, hi float64) float64 {
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
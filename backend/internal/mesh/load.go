@@ -0,0 +1,50 @@
+package mesh
+
+import (
+	"context"
+	"runtime/metrics"
+	"time"
+
+	"google.golang.org/grpc/orca"
+)
+
+// sampleInterval is how often CPU utilization is recomputed. It is shorter
+// than any ORCA report interval so per-call reports stay current.
+const sampleInterval = 5 * time.Second
+
+// cpuMetrics are the Go runtime's estimates of CPU time available to the
+// process (GOMAXPROCS × wall time) and of the part of it left idle
+var cpuMetrics = []string{
+	"/cpu/classes/total:cpu-seconds",
+	"/cpu/classes/idle:cpu-seconds",
+}
+
+// sampleLoad records the process's CPU utilization over each sample interval
+// until ctx is done
+func sampleLoad(ctx context.Context, recorder orca.ServerMetricsRecorder) {
+	samples := make([]metrics.Sample, len(cpuMetrics))
+	for i, name := range cpuMetrics {
+		samples[i].Name = name
+	}
+
+	metrics.Read(samples)
+	prevTotal, prevIdle := samples[0].Value.Float64(), samples[1].Value.Float64()
+
+	ticker := time.NewTicker(sampleInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		metrics.Read(samples)
+		total, idle := samples[0].Value.Float64(), samples[1].Value.Float64()
+		if elapsed := total - prevTotal; elapsed > 0 {
+			recorder.SetCPUUtilization(1 - (idle-prevIdle)/elapsed)
+		}
+		prevTotal, prevIdle = total, idle
+	}
+}
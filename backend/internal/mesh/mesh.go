@@ -0,0 +1,78 @@
+// Package mesh builds the main gRPC server, either as a plain server or as an
+// xDS-managed one whose listener, TLS, and routing come from a service mesh
+// control plane
+package mesh
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	xdscreds "google.golang.org/grpc/credentials/xds"
+	"google.golang.org/grpc/orca"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/xds"
+
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/config"
+)
+
+// Server is what the process needs from *grpc.Server and *xds.GRPCServer
+type Server interface {
+	reflection.GRPCServer
+	Serve(net.Listener) error
+	GracefulStop()
+	Stop()
+}
+
+// NewServer returns a plain gRPC server, or an xDS-managed one when cfg is
+// enabled. In xDS mode TLS certificates come from the bootstrap's certificate
+// providers, falling back to plaintext when the control plane configures
+// none, and load is reported through ORCA: per call in response trailers and
+// out of band to clients that subscribe. The load sampler stops with ctx.
+func NewServer(ctx context.Context, cfg config.XDSConfig, logger *zap.Logger, opts ...grpc.ServerOption) (Server, error) {
+	if !cfg.Enabled {
+		return grpc.NewServer(opts...), nil
+	}
+
+	creds, err := xdscreds.NewServerCredentials(xdscreds.ServerOptions{
+		FallbackCreds: insecure.NewCredentials(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create xDS credentials: %w", err)
+	}
+
+	recorder := orca.NewServerMetricsRecorder()
+
+	opts = append(opts,
+		grpc.Creds(creds),
+		orca.CallMetricsServerOption(recorder),
+		// The server only accepts connections once the control plane has
+		// sent a matching Listener resource
+		xds.ServingModeCallback(func(addr net.Addr, args xds.ServingModeChangeArgs) {
+			logger.Info("xDS serving mode changed",
+				zap.String("addr", addr.String()),
+				zap.String("mode", args.Mode.String()),
+				zap.Error(args.Err),
+			)
+		}),
+	)
+
+	srv, err := xds.NewGRPCServer(opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create xDS server: %w", err)
+	}
+
+	if err := orca.Register(srv, orca.ServiceOptions{
+		ServerMetricsProvider: recorder,
+		MinReportingInterval:  cfg.LoadReportInterval,
+	}); err != nil {
+		return nil, fmt.Errorf("failed to register ORCA service: %w", err)
+	}
+
+	go sampleLoad(ctx, recorder)
+
+	return srv, nil
+}
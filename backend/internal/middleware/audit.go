@@ -0,0 +1,114 @@
+package middleware
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/audit"
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/models"
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/redact"
+)
+
+// mutatingMethods caches whether each full method name has side effects
+var mutatingMethods sync.Map
+
+// AuditInterceptor records every mutating unary RPC to the audit log with
+// its caller, target, result, and redacted request. Methods are read-only
+// when declared with `option idempotency_level = NO_SIDE_EFFECTS`. It must
+// run after AuthInterceptor so the caller is known; a nil writer disables it.
+func AuditInterceptor(writer *audit.Writer, redactFields []string) grpc.UnaryServerInterceptor {
+	redactor := redact.New(redactFields)
+
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		if writer == nil || !isMutating(info.FullMethod) {
+			return handler(ctx, req)
+		}
+
+		start := time.Now()
+		resp, err := handler(ctx, req)
+
+		entry := models.AuditLog{
+			OccurredAt: start,
+			Method:     info.FullMethod,
+			TargetID:   targetID(req, resp),
+			StatusCode: status.Code(err).String(),
+			ClientIP:   ClientIPFromContext(ctx),
+			Latency:    time.Since(start),
+			RequestID:  RequestIDFromContext(ctx),
+		}
+		if claims, ok := ClaimsFromContext(ctx); ok {
+			entry.ActorID = claims.UserID
+		}
+		if msg, ok := req.(proto.Message); ok {
+			if payload, err := redactor.JSON(msg); err == nil {
+				entry.Payload = payload
+			}
+		}
+
+		writer.Record(entry)
+
+		return resp, err
+	}
+}
+
+// isMutating reports whether method may have side effects. Methods missing
+// from the registry are assumed to.
+func isMutating(fullMethod string) bool {
+	if cached, ok := mutatingMethods.Load(fullMethod); ok {
+		return cached.(bool)
+	}
+
+	mutating := true
+	// "/pkg.Service/Method" -> "pkg.Service.Method"
+	name := protoreflect.FullName(strings.Replace(strings.TrimPrefix(fullMethod, "/"), "/", ".", 1))
+	if desc, err := protoregistry.GlobalFiles.FindDescriptorByName(name); err == nil {
+		if method, ok := desc.(protoreflect.MethodDescriptor); ok {
+			opts, _ := method.Options().(*descriptorpb.MethodOptions)
+			mutating = opts.GetIdempotencyLevel() != descriptorpb.MethodOptions_NO_SIDE_EFFECTS
+		}
+	}
+
+	mutatingMethods.Store(fullMethod, mutating)
+	return mutating
+}
+
+// targetID returns the user a call acted on: the request's user_id field, or
+// the id of the user in the response (e.g. the account created by SignUp)
+func targetID(req, resp interface{}) string {
+	if msg, ok := req.(proto.Message); ok {
+		if id := stringField(msg.ProtoReflect(), "user_id"); id != "" {
+			return id
+		}
+	}
+
+	if msg, ok := resp.(proto.Message); ok {
+		m := msg.ProtoReflect()
+		if fd := m.Descriptor().Fields().ByName("user"); fd != nil && fd.Message() != nil && m.Has(fd) {
+			return stringField(m.Get(fd).Message(), "id")
+		}
+	}
+
+	return ""
+}
+
+func stringField(m protoreflect.Message, name protoreflect.Name) string {
+	fd := m.Descriptor().Fields().ByName(name)
+	if fd == nil || fd.Kind() != protoreflect.StringKind || fd.IsList() || fd.IsMap() {
+		return ""
+	}
+	return m.Get(fd).String()
+}
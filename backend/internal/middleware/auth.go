@@ -0,0 +1,186 @@
+package middleware
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/apierror"
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/cache"
+	"github.com/sahays/grpc-proto-go-flutter-template/pkg/jwt"
+	pb "github.com/sahays/grpc-proto-go-flutter-template/proto"
+)
+
+type claimsContextKey struct{}
+
+// scopedMethods lists the only methods a token with a restricting scope may call
+var scopedMethods = map[string][]string{
+	jwt.ScopePasswordChange: {pb.AuthService_ChangePassword_FullMethodName},
+}
+
+// AuthInterceptor validates the bearer token in the `authorization` metadata,
+// rejects it if revoked or signed out in c, and stores its claims in the
+// context. Methods in publicMethods skip authentication entirely. If the
+// cache fails, tokens are accepted or rejected as outage says
+// (config.FailOpen or FailClosed).
+func AuthInterceptor(jwtService *jwt.Service, c *cache.Cache, publicMethods []string, outage string) grpc.UnaryServerInterceptor {
+	public := make(map[string]bool, len(publicMethods))
+	for _, method := range publicMethods {
+		public[method] = true
+	}
+
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		if public[info.FullMethod] {
+			return handler(ctx, req)
+		}
+
+		claims, err := authenticate(ctx, jwtService, c, info.FullMethod, outage)
+		if err != nil {
+			return nil, err
+		}
+
+		return handler(ContextWithClaims(ctx, claims), req)
+	}
+}
+
+// StreamAuthInterceptor is the streaming counterpart of AuthInterceptor; the
+// token is checked once when the stream is opened
+func StreamAuthInterceptor(jwtService *jwt.Service, c *cache.Cache, publicMethods []string, outage string) grpc.StreamServerInterceptor {
+	public := make(map[string]bool, len(publicMethods))
+	for _, method := range publicMethods {
+		public[method] = true
+	}
+
+	return func(
+		srv interface{},
+		ss grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		if public[info.FullMethod] {
+			return handler(srv, ss)
+		}
+
+		claims, err := authenticate(ss.Context(), jwtService, c, info.FullMethod, outage)
+		if err != nil {
+			return err
+		}
+
+		return handler(srv, &wrappedStream{ServerStream: ss, ctx: ContextWithClaims(ss.Context(), claims)})
+	}
+}
+
+// authenticate validates the bearer token, checks it hasn't been revoked or
+// its session signed out, and checks its scope permits method
+func authenticate(ctx context.Context, jwtService *jwt.Service, c *cache.Cache, method, outage string) (*jwt.Claims, error) {
+	token, err := bearerToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	claims, err := jwtService.ValidateAccessToken(token)
+	if err != nil {
+		return nil, apierror.New(codes.Unauthenticated, apierror.ReasonInvalidToken, "invalid or expired token")
+	}
+
+	active, err := tokenActive(ctx, c, claims, outage)
+	if err != nil {
+		return nil, err
+	}
+	if !active {
+		return nil, apierror.New(codes.Unauthenticated, apierror.ReasonInvalidToken, "invalid or expired token")
+	}
+
+	if claims.IsRestricted() && !scopeAllows(claims.Scope, method) {
+		return nil, apierror.New(codes.PermissionDenied, apierror.ReasonTokenScopeRestricted, "token scope does not allow this method")
+	}
+
+	return claims, nil
+}
+
+// tokenActive reports whether the token with claims is neither revoked (see
+// cache.IsTokenRevoked) nor its session signed out, reading both in one
+// round trip, and records activity on the session. If the cache fails it
+// returns cacheOutage's answer, accepting the token when failing open.
+func tokenActive(ctx context.Context, c *cache.Cache, claims *jwt.Claims, outage string) (bool, error) {
+	var issuedAt time.Time
+	if claims.IssuedAt != nil {
+		issuedAt = claims.IssuedAt.Time
+	}
+
+	status, err := c.CheckAccessToken(ctx, claims.ID, claims.UserID, claims.SessionID, issuedAt)
+	if err != nil {
+		return true, cacheOutage(outage)
+	}
+	if status.Revoked {
+		return false, nil
+	}
+
+	// Tokens without a session are only checked for revocation
+	if claims.SessionID == "" {
+		return true, nil
+	}
+	if status.Session == nil {
+		return false, nil
+	}
+	_ = c.RecordSessionActivity(ctx, status.Session, ClientIPFromContext(ctx), UserAgentFromContext(ctx))
+	return true, nil
+}
+
+// ContextWithClaims returns a copy of ctx carrying the authenticated claims
+func ContextWithClaims(ctx context.Context, claims *jwt.Claims) context.Context {
+	return context.WithValue(ctx, claimsContextKey{}, claims)
+}
+
+// ClaimsFromContext returns the claims stored by AuthInterceptor
+func ClaimsFromContext(ctx context.Context) (*jwt.Claims, bool) {
+	claims, ok := ctx.Value(claimsContextKey{}).(*jwt.Claims)
+	return claims, ok
+}
+
+func scopeAllows(scope, method string) bool {
+	for _, allowed := range scopedMethods[scope] {
+		if allowed == method {
+			return true
+		}
+	}
+	return false
+}
+
+// maxTokenLength bounds the bearer tokens read. Signed tokens carrying roles
+// and permissions, signed with a 4096-bit RSA key and then encrypted as a
+// JWE, come to a few KiB; auth.proto allows as much for tokens in requests.
+const maxTokenLength = 8 << 10
+
+// bearerToken extracts the access token from the authorization metadata
+func bearerToken(ctx context.Context) (string, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return "", apierror.New(codes.Unauthenticated, apierror.ReasonUnauthenticated, "missing metadata")
+	}
+
+	values := md.Get("authorization")
+	if len(values) == 0 {
+		return "", apierror.New(codes.Unauthenticated, apierror.ReasonUnauthenticated, "authorization token is required")
+	}
+
+	token := strings.TrimSpace(values[0])
+	if len(token) > 7 && strings.EqualFold(token[:7], "bearer ") {
+		token = strings.TrimSpace(token[7:])
+	}
+
+	if token == "" || len(token) > maxTokenLength {
+		return "", apierror.New(codes.Unauthenticated, apierror.ReasonUnauthenticated, "authorization token is required")
+	}
+
+	return token, nil
+}
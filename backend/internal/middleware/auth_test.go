@@ -0,0 +1,181 @@
+package middleware
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/apierror"
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/cache"
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/config"
+	"github.com/sahays/grpc-proto-go-flutter-template/pkg/jwt"
+	pb "github.com/sahays/grpc-proto-go-flutter-template/proto"
+)
+
+// newTestCache returns a cache in a miniredis server, which the test may
+// close to simulate an outage
+func newTestCache(t *testing.T) (*cache.Cache, *miniredis.Miniredis) {
+	t.Helper()
+
+	m := miniredis.RunT(t)
+	cfg := &config.Config{}
+	cfg.Redis.Host = m.Host()
+	cfg.Redis.Port = m.Port()
+	// Fail at once when the server is closed
+	cfg.Redis.MaxRetries = -1
+	cfg.JWT.AccessTokenExpiry = time.Minute
+	cfg.JWT.RefreshTokenExpiry = time.Hour
+
+	c, err := cache.New(cfg)
+	if err != nil {
+		t.Fatalf("cache.New: %v", err)
+	}
+	t.Cleanup(func() { _ = c.Close() })
+	return c, m
+}
+
+// checkStatus fails the test unless err has code and, in its ErrorInfo,
+// reason
+func checkStatus(t *testing.T, err error, code codes.Code, reason string) {
+	t.Helper()
+
+	st := status.Convert(err)
+	var gotReason string
+	for _, detail := range st.Details() {
+		if info, ok := detail.(*errdetails.ErrorInfo); ok {
+			gotReason = info.Reason
+		}
+	}
+	if st.Code() != code || gotReason != reason {
+		t.Errorf("error = %v %s (%v), want %v %s", st.Code(), gotReason, err, code, reason)
+	}
+}
+
+func TestAuthInterceptor(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.JWT.Algorithm = jwt.AlgorithmHS256
+	cfg.JWT.Format = config.TokenFormatJWT
+	cfg.JWT.Issuer = "test-issuer"
+	cfg.JWT.AccessTokenExpiry = time.Minute
+	jwtService, err := jwt.New(cfg)
+	if err != nil {
+		t.Fatalf("jwt.New: %v", err)
+	}
+
+	c, _ := newTestCache(t)
+	downCache, m := newTestCache(t)
+	m.Close()
+
+	ctx := context.Background()
+	if err := c.CreateSession(ctx, &cache.Session{ID: "live", UserID: "user-1", ExpiresAt: time.Now().Add(time.Hour)}); err != nil {
+		t.Fatalf("CreateSession: %v", err)
+	}
+
+	id := jwt.Identity{UserID: "user-1", Email: "ada@example.com", Role: "user"}
+	newToken := func(sessionID string) string {
+		token, err := jwtService.CreateAccessToken(id, sessionID)
+		if err != nil {
+			t.Fatalf("CreateAccessToken: %v", err)
+		}
+		return token
+	}
+	withSession := newToken("live")
+	withoutSession := newToken("")
+	signedOut := newToken("signed-out")
+	denied := newToken("live")
+	deniedClaims, err := jwtService.ValidateAccessToken(denied)
+	if err != nil {
+		t.Fatalf("ValidateAccessToken: %v", err)
+	}
+	if err := c.DenyToken(ctx, deniedClaims.ID, deniedClaims.ExpiresAt.Time); err != nil {
+		t.Fatalf("DenyToken: %v", err)
+	}
+	scoped, err := jwtService.CreateScopedAccessToken(id, jwt.ScopePasswordChange)
+	if err != nil {
+		t.Fatalf("CreateScopedAccessToken: %v", err)
+	}
+
+	const publicMethod = "/auth.AuthService/Login"
+	const method = "/auth.AuthService/UpdateProfile"
+
+	tests := []struct {
+		name   string
+		method string
+		// md is the call's metadata; nil for none
+		md        metadata.MD
+		cacheDown bool
+		outage    string
+		// wantUserID is the user of the claims the handler gets; "" for none
+		wantUserID string
+		wantCode   codes.Code
+		wantReason string
+	}{
+		{name: "public method", method: publicMethod, md: metadata.MD{}},
+		{name: "bearer token", method: method, md: metadata.Pairs("authorization", "Bearer "+withSession), wantUserID: "user-1"},
+		{name: "scheme is case-insensitive", method: method, md: metadata.Pairs("authorization", "bearer  "+withSession), wantUserID: "user-1"},
+		{name: "bare token", method: method, md: metadata.Pairs("authorization", withSession), wantUserID: "user-1"},
+		{name: "token without session", method: method, md: metadata.Pairs("authorization", "Bearer "+withoutSession), wantUserID: "user-1"},
+		{name: "no metadata", method: method, wantCode: codes.Unauthenticated, wantReason: apierror.ReasonUnauthenticated},
+		{name: "no token", method: method, md: metadata.MD{}, wantCode: codes.Unauthenticated, wantReason: apierror.ReasonUnauthenticated},
+		{name: "blank token", method: method, md: metadata.Pairs("authorization", " "), wantCode: codes.Unauthenticated, wantReason: apierror.ReasonUnauthenticated},
+		{name: "oversized token", method: method, md: metadata.Pairs("authorization", "Bearer "+strings.Repeat("a", maxTokenLength+1)), wantCode: codes.Unauthenticated, wantReason: apierror.ReasonUnauthenticated},
+		{name: "invalid token", method: method, md: metadata.Pairs("authorization", "Bearer "+withSession+"x"), wantCode: codes.Unauthenticated, wantReason: apierror.ReasonInvalidToken},
+		{name: "denied token", method: method, md: metadata.Pairs("authorization", "Bearer "+denied), wantCode: codes.Unauthenticated, wantReason: apierror.ReasonInvalidToken},
+		{name: "session signed out", method: method, md: metadata.Pairs("authorization", "Bearer "+signedOut), wantCode: codes.Unauthenticated, wantReason: apierror.ReasonInvalidToken},
+		{name: "scoped token, its method", method: pb.AuthService_ChangePassword_FullMethodName, md: metadata.Pairs("authorization", "Bearer "+scoped), wantUserID: "user-1"},
+		{name: "scoped token, other method", method: method, md: metadata.Pairs("authorization", "Bearer "+scoped), wantCode: codes.PermissionDenied, wantReason: apierror.ReasonTokenScopeRestricted},
+		{name: "cache down, failing open", method: method, md: metadata.Pairs("authorization", "Bearer "+withSession), cacheDown: true, outage: config.FailOpen, wantUserID: "user-1"},
+		{name: "cache down, failing closed", method: method, md: metadata.Pairs("authorization", "Bearer "+withSession), cacheDown: true, outage: config.FailClosed, wantCode: codes.Unavailable, wantReason: apierror.ReasonUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			interceptorCache := c
+			if tt.cacheDown {
+				interceptorCache = downCache
+			}
+			outage := tt.outage
+			if outage == "" {
+				outage = config.FailOpen
+			}
+			interceptor := AuthInterceptor(jwtService, interceptorCache, []string{publicMethod}, outage)
+
+			ctx := context.Background()
+			if tt.md != nil {
+				ctx = metadata.NewIncomingContext(ctx, tt.md)
+			}
+
+			called := false
+			var gotUserID string
+			handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+				called = true
+				if claims, ok := ClaimsFromContext(ctx); ok {
+					gotUserID = claims.UserID
+				}
+				return nil, nil
+			}
+
+			_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: tt.method}, handler)
+			if tt.wantCode != codes.OK {
+				checkStatus(t, err, tt.wantCode, tt.wantReason)
+				if called {
+					t.Error("handler called for a rejected call")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("interceptor: %v", err)
+			}
+			if !called || gotUserID != tt.wantUserID {
+				t.Errorf("handler called %v with user %q, want called with %q", called, gotUserID, tt.wantUserID)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/apierror"
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/authz"
+)
+
+// AuthorizationInterceptor checks the caller against the authorization policy
+// before the handler runs. It must run after AuthInterceptor; callers without
+// claims are evaluated as anonymous.
+func AuthorizationInterceptor(authorizer *authz.Authorizer, logger *zap.Logger) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		if err := authorize(ctx, authorizer, logger, info.FullMethod, authz.ResourceOf(req)); err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// StreamAuthorizationInterceptor is the streaming counterpart of
+// AuthorizationInterceptor. The check runs when the stream is opened, before
+// any message is received, so rules on r.res see an empty resource.
+func StreamAuthorizationInterceptor(authorizer *authz.Authorizer, logger *zap.Logger) grpc.StreamServerInterceptor {
+	return func(
+		srv interface{},
+		ss grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		if err := authorize(ss.Context(), authorizer, logger, info.FullMethod, authz.Resource{}); err != nil {
+			return err
+		}
+
+		return handler(srv, ss)
+	}
+}
+
+// authorize returns PermissionDenied unless the policy grants method to the caller
+func authorize(ctx context.Context, authorizer *authz.Authorizer, logger *zap.Logger, method string, res authz.Resource) error {
+	sub := authz.Subject{Role: authz.RoleAnonymous}
+	if claims, ok := ClaimsFromContext(ctx); ok {
+		sub.ID = claims.UserID
+		sub.Role = claims.Role
+		// Tokens issued before roles existed carry none
+		if sub.Role == "" {
+			sub.Role = authz.RoleUser
+		}
+	}
+
+	allowed, err := authorizer.Authorize(sub, method, res)
+	if err != nil {
+		// A broken rule must not open access
+		logger.Error("authorization policy evaluation failed",
+			zap.String("method", method),
+			zap.Error(err),
+		)
+		return apierror.Internal("authorization failed")
+	}
+
+	if !allowed {
+		return apierror.New(codes.PermissionDenied, apierror.ReasonPermissionDenied, "not allowed to call this method")
+	}
+
+	return nil
+}
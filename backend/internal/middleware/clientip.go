@@ -0,0 +1,87 @@
+package middleware
+
+import (
+	"context"
+	"net"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/clientip"
+)
+
+type clientIPContextKey struct{}
+
+// ClientIPInterceptor resolves the real client address, looking through
+// trusted proxies, and stores it in the context for ClientIPFromContext
+func ClientIPInterceptor(resolver *clientip.Resolver) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		return handler(contextWithClientIP(ctx, resolver), req)
+	}
+}
+
+// StreamClientIPInterceptor is the streaming counterpart of ClientIPInterceptor
+func StreamClientIPInterceptor(resolver *clientip.Resolver) grpc.StreamServerInterceptor {
+	return func(
+		srv interface{},
+		ss grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		return handler(srv, &wrappedStream{ServerStream: ss, ctx: contextWithClientIP(ss.Context(), resolver)})
+	}
+}
+
+// ClientIPFromContext returns the client address stored by
+// ClientIPInterceptor, or the directly connected peer's if there is none
+func ClientIPFromContext(ctx context.Context) string {
+	if ip, ok := ctx.Value(clientIPContextKey{}).(string); ok {
+		return ip
+	}
+	return peerIP(ctx)
+}
+
+// UserAgentFromContext returns the client's user agent. Calls through the
+// REST gateway carry the browser's as grpcgateway-user-agent, and the
+// gateway's own as user-agent.
+func UserAgentFromContext(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, key := range []string{"grpcgateway-user-agent", "user-agent"} {
+		if values := md.Get(key); len(values) > 0 {
+			return values[0]
+		}
+	}
+	return ""
+}
+
+func contextWithClientIP(ctx context.Context, resolver *clientip.Resolver) context.Context {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	ip := peerIP(ctx)
+	if resolved := resolver.Resolve(net.ParseIP(ip), md); resolved != nil {
+		ip = resolved.String()
+	}
+
+	return context.WithValue(ctx, clientIPContextKey{}, ip)
+}
+
+// peerIP returns the IP of the directly connected client
+func peerIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return "unknown"
+	}
+
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+
+	return host
+}
@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/protobuf/proto"
+)
+
+// Compression modes for CompressionInterceptor
+const (
+	// CompressionOff leaves compression entirely to the client's request
+	CompressionOff = "off"
+	// CompressionPrefer compresses responses at or above the size threshold
+	CompressionPrefer = "prefer"
+	// CompressionAlways compresses every response
+	CompressionAlways = "always"
+)
+
+// CompressionInterceptor gzips responses for clients that advertise gzip in
+// grpc-accept-encoding, according to mode and minSize (in bytes)
+func CompressionInterceptor(mode string, minSize int) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		resp, err := handler(ctx, req)
+		if err != nil || mode == CompressionOff {
+			return resp, err
+		}
+
+		if mode == CompressionPrefer {
+			msg, ok := resp.(proto.Message)
+			if !ok || proto.Size(msg) < minSize {
+				return resp, err
+			}
+		}
+
+		if clientAcceptsGzip(ctx) {
+			_ = grpc.SetSendCompressor(ctx, gzip.Name)
+		}
+
+		return resp, err
+	}
+}
+
+func clientAcceptsGzip(ctx context.Context) bool {
+	supported, err := grpc.ClientSupportedCompressors(ctx)
+	if err != nil {
+		return false
+	}
+	for _, name := range supported {
+		if name == gzip.Name {
+			return true
+		}
+	}
+	return false
+}
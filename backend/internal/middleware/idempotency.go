@@ -0,0 +1,250 @@
+package middleware
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/apierror"
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/cache"
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/config"
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/db"
+	"github.com/sahays/grpc-proto-go-flutter-template/pkg/crypto"
+)
+
+// IdempotencyKeyHeader is the metadata key clients use to mark retries of
+// the same request
+const IdempotencyKeyHeader = "idempotency-key"
+
+const (
+	// maxIdempotencyKeyLength fits a UUID or ULID with room to spare
+	maxIdempotencyKeyLength = 255
+	// idempotencyPendingTTL holds a key while its request runs, for calls
+	// without a deadline
+	idempotencyPendingTTL = time.Minute
+	// idempotencyStoreTimeout bounds storing a response after the call's own
+	// context ended, such as when the client gave up and will retry
+	idempotencyStoreTimeout = 2 * time.Second
+	// idempotencyRetryDelay is the back-off suggested to a retry that
+	// arrives while the first request still runs
+	idempotencyRetryDelay = time.Second
+)
+
+// idempotentResult is the response stored under an idempotency key, with the
+// fingerprint of the request that produced it
+type idempotentResult struct {
+	Fingerprint string `json:"fingerprint"`
+	// Response is the marshaled anypb.Any of the response
+	Response []byte `json:"response"`
+}
+
+// IdempotencyInterceptor makes the configured methods safe to retry. A call
+// carrying an idempotency-key header runs once; later calls with the same
+// key and request get its response again instead of running, so a mobile
+// client that lost the response can retry without creating duplicates.
+// Reusing a key for a different request is rejected, as is a retry that
+// arrives while the first call still runs. Failed calls are not stored, so
+// they can be retried.
+//
+// Keys are scoped to the method, tenant and caller, so it must run after
+// AuthInterceptor and TenantInterceptor. Responses are stored encrypted
+// with the personal data keyring. If the cache fails, calls run without the
+// guarantee or are rejected as outage says (config.FailOpen or FailClosed).
+func IdempotencyInterceptor(c *cache.Cache, keyring *crypto.Keyring, cfg config.IdempotencyConfig, outage string) grpc.UnaryServerInterceptor {
+	methods := make(map[string]bool, len(cfg.Methods))
+	for _, method := range cfg.Methods {
+		methods[method] = true
+	}
+
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		if !methods[info.FullMethod] {
+			return handler(ctx, req)
+		}
+		key := incomingIdempotencyKey(ctx)
+		if key == "" {
+			return handler(ctx, req)
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			return nil, apierror.New(codes.InvalidArgument, apierror.ReasonInvalidArgument, "invalid idempotency key")
+		}
+		msg, ok := req.(proto.Message)
+		if !ok {
+			return handler(ctx, req)
+		}
+
+		fingerprint, err := requestFingerprint(info.FullMethod, msg)
+		if err != nil {
+			return handler(ctx, req)
+		}
+		key = scopedIdempotencyKey(ctx, info.FullMethod, key)
+
+		stored, reserved, err := c.ReserveIdempotencyKey(ctx, key, pendingTTL(ctx))
+		if err != nil {
+			if err := cacheOutage(outage); err != nil {
+				return nil, err
+			}
+			return handler(ctx, req)
+		}
+		if !reserved {
+			return replayIdempotent(keyring, key, fingerprint, stored)
+		}
+
+		resp, err := handler(ctx, req)
+
+		storeCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), idempotencyStoreTimeout)
+		defer cancel()
+		if err != nil {
+			_ = c.ReleaseIdempotencyKey(storeCtx, key)
+			return resp, err
+		}
+		if result, err := sealIdempotentResult(keyring, key, fingerprint, resp); err == nil {
+			_ = c.StoreIdempotentResult(storeCtx, key, result, cfg.TTL)
+		} else {
+			_ = c.ReleaseIdempotencyKey(storeCtx, key)
+		}
+		return resp, nil
+	}
+}
+
+// incomingIdempotencyKey returns the call's idempotency key, or "" if it
+// sent none
+func incomingIdempotencyKey(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	if values := md.Get(IdempotencyKeyHeader); len(values) > 0 {
+		return strings.TrimSpace(values[0])
+	}
+	return ""
+}
+
+// scopedIdempotencyKey hashes the client's key with the method, tenant and
+// caller, so keys chosen by different clients never collide
+func scopedIdempotencyKey(ctx context.Context, method, key string) string {
+	tenantID, _ := db.TenantFrom(ctx)
+	var userID string
+	if claims, ok := ClaimsFromContext(ctx); ok {
+		userID = claims.UserID
+	}
+
+	sum := sha256.Sum256([]byte(strings.Join([]string{method, tenantID, userID, key}, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
+
+// requestFingerprint identifies the request, to tell a retry from a reused
+// key. It is only stored encrypted, as requests hold passwords.
+func requestFingerprint(method string, req proto.Message) (string, error) {
+	data, err := proto.MarshalOptions{Deterministic: true}.Marshal(req)
+	if err != nil {
+		return "", err
+	}
+
+	hash := sha256.New()
+	hash.Write([]byte(method))
+	hash.Write([]byte{0})
+	hash.Write(data)
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// pendingTTL holds a key until the call's deadline, after which the call
+// has ended either way
+func pendingTTL(ctx context.Context) time.Duration {
+	if deadline, ok := ctx.Deadline(); ok {
+		if ttl := time.Until(deadline) + idempotencyStoreTimeout; ttl > 0 {
+			return ttl
+		}
+	}
+	return idempotencyPendingTTL
+}
+
+// idempotencyContext binds a stored result to its scoped key, like
+// piiContext
+func idempotencyContext(key string) []byte {
+	return []byte("idempotency:" + key)
+}
+
+// sealIdempotentResult encodes and encrypts resp for storing under key
+func sealIdempotentResult(keyring *crypto.Keyring, key, fingerprint string, resp interface{}) ([]byte, error) {
+	msg, ok := resp.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("response is a %T, not a proto message", resp)
+	}
+	response, err := anypb.New(msg)
+	if err != nil {
+		return nil, err
+	}
+	data, err := proto.Marshal(response)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := json.Marshal(idempotentResult{Fingerprint: fingerprint, Response: data})
+	if err != nil {
+		return nil, err
+	}
+	return keyring.Encrypt(plaintext, idempotencyContext(key))
+}
+
+// openIdempotentResult decrypts and decodes a result stored under key
+func openIdempotentResult(keyring *crypto.Keyring, key string, stored []byte) (*idempotentResult, error) {
+	plaintext, err := keyring.Decrypt(stored, idempotencyContext(key))
+	if err != nil {
+		return nil, err
+	}
+	result := &idempotentResult{}
+	if err := json.Unmarshal(plaintext, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// response decodes the stored response
+func (r *idempotentResult) response() (proto.Message, error) {
+	response := &anypb.Any{}
+	if err := proto.Unmarshal(r.Response, response); err != nil {
+		return nil, err
+	}
+	return response.UnmarshalNew()
+}
+
+// replayIdempotent answers a call whose key is already taken with the stored
+// response, or rejects it if the key's first call is still running or was
+// for a different request. A stored result that can't be read, such as one
+// encrypted with a retired key, fails the call rather than risk running it
+// twice.
+func replayIdempotent(keyring *crypto.Keyring, key, fingerprint string, stored []byte) (interface{}, error) {
+	if len(stored) == 0 {
+		return nil, apierror.New(codes.Aborted, apierror.ReasonIdempotencyKeyInUse, "a request with this idempotency key is in progress",
+			apierror.RetryAfter(idempotencyRetryDelay))
+	}
+
+	result, err := openIdempotentResult(keyring, key, stored)
+	if err != nil {
+		return nil, apierror.Internal("internal server error")
+	}
+	if result.Fingerprint != fingerprint {
+		return nil, apierror.New(codes.InvalidArgument, apierror.ReasonIdempotencyKeyReused, "idempotency key was used with a different request")
+	}
+
+	resp, err := result.response()
+	if err != nil {
+		return nil, apierror.Internal("internal server error")
+	}
+	return resp, nil
+}
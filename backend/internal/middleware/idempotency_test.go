@@ -0,0 +1,167 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/apierror"
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/config"
+	"github.com/sahays/grpc-proto-go-flutter-template/pkg/crypto"
+	"github.com/sahays/grpc-proto-go-flutter-template/pkg/jwt"
+	pb "github.com/sahays/grpc-proto-go-flutter-template/proto"
+)
+
+func newTestKeyring(t *testing.T) *crypto.Keyring {
+	t.Helper()
+
+	keyring, err := crypto.NewKeyring(map[string][]byte{"test": bytes.Repeat([]byte{1}, 32)}, "test")
+	if err != nil {
+		t.Fatalf("NewKeyring: %v", err)
+	}
+	return keyring
+}
+
+func TestIdempotencyInterceptor(t *testing.T) {
+	const method = "/auth.AuthService/SignUp"
+
+	c, _ := newTestCache(t)
+	cfg := config.IdempotencyConfig{Methods: []string{method}, TTL: time.Hour}
+	interceptor := IdempotencyInterceptor(c, newTestKeyring(t), cfg, config.FailOpen)
+
+	// runs counts the handler's runs; each response is its run's number
+	runs := 0
+
+	// The calls run in order, each seeing what those before it stored, so
+	// they don't run in parallel
+	tests := []struct {
+		name   string
+		method string
+		// userID is the caller; "" for an unauthenticated call
+		userID string
+		key    string
+		email  string
+		// fail makes the handler fail
+		fail bool
+		// wantRun is the number of the run whose response is returned; 0 for
+		// an error
+		wantRun    int
+		wantCode   codes.Code
+		wantReason string
+	}{
+		{name: "no key", method: method, email: "a@example.com", wantRun: 1},
+		{name: "no key, again", method: method, email: "a@example.com", wantRun: 2},
+		{name: "first call", method: method, key: "k1", email: "a@example.com", wantRun: 3},
+		{name: "retry", method: method, key: "k1", email: "a@example.com", wantRun: 3},
+		{name: "retry with spaces around the key", method: method, key: " k1 ", email: "a@example.com", wantRun: 3},
+		{name: "key reused for another request", method: method, key: "k1", email: "b@example.com", wantCode: codes.InvalidArgument, wantReason: apierror.ReasonIdempotencyKeyReused},
+		{name: "same key, another caller", method: method, userID: "user-1", key: "k1", email: "a@example.com", wantRun: 4},
+		{name: "same key, another caller, retry", method: method, userID: "user-1", key: "k1", email: "a@example.com", wantRun: 4},
+		{name: "method without idempotency", method: "/auth.AuthService/Login", key: "k1", email: "a@example.com", wantRun: 5},
+		{name: "failed call", method: method, key: "k2", email: "a@example.com", fail: true, wantCode: codes.Internal, wantReason: apierror.ReasonInternal},
+		{name: "retry of a failed call runs", method: method, key: "k2", email: "a@example.com", wantRun: 7},
+		{name: "oversized key", method: method, key: strings.Repeat("k", maxIdempotencyKeyLength+1), email: "a@example.com", wantCode: codes.InvalidArgument, wantReason: apierror.ReasonInvalidArgument},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.key != "" {
+				ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(IdempotencyKeyHeader, tt.key))
+			}
+			if tt.userID != "" {
+				ctx = ContextWithClaims(ctx, &jwt.Claims{UserID: tt.userID})
+			}
+
+			handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+				runs++
+				if tt.fail {
+					return nil, apierror.Internal("failed")
+				}
+				return &pb.SignUpResponse{Message: strconv.Itoa(runs)}, nil
+			}
+
+			resp, err := interceptor(ctx, &pb.SignUpRequest{Email: tt.email}, &grpc.UnaryServerInfo{FullMethod: tt.method}, handler)
+			if tt.wantCode != codes.OK {
+				checkStatus(t, err, tt.wantCode, tt.wantReason)
+				return
+			}
+			if err != nil {
+				t.Fatalf("interceptor: %v", err)
+			}
+			if got := resp.(*pb.SignUpResponse).Message; got != strconv.Itoa(tt.wantRun) {
+				t.Errorf("response of run %s, want %d", got, tt.wantRun)
+			}
+		})
+	}
+}
+
+func TestIdempotencyInterceptorInProgress(t *testing.T) {
+	const method = "/auth.AuthService/SignUp"
+
+	c, _ := newTestCache(t)
+	cfg := config.IdempotencyConfig{Methods: []string{method}, TTL: time.Hour}
+	interceptor := IdempotencyInterceptor(c, newTestKeyring(t), cfg, config.FailOpen)
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(IdempotencyKeyHeader, "k1"))
+	req := &pb.SignUpRequest{Email: "a@example.com"}
+	info := &grpc.UnaryServerInfo{FullMethod: method}
+
+	// A retry arriving while the first call runs
+	var retryErr error
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		_, retryErr = interceptor(ctx, req, info, func(context.Context, interface{}) (interface{}, error) {
+			t.Error("retry ran while the first call was running")
+			return nil, nil
+		})
+		return &pb.SignUpResponse{}, nil
+	}
+
+	if _, err := interceptor(ctx, req, info, handler); err != nil {
+		t.Fatalf("interceptor: %v", err)
+	}
+	checkStatus(t, retryErr, codes.Aborted, apierror.ReasonIdempotencyKeyInUse)
+}
+
+func TestIdempotencyInterceptorCacheOutage(t *testing.T) {
+	const method = "/auth.AuthService/SignUp"
+
+	tests := []struct {
+		name     string
+		outage   string
+		wantCode codes.Code
+	}{
+		{name: "failing open", outage: config.FailOpen},
+		{name: "failing closed", outage: config.FailClosed, wantCode: codes.Unavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, m := newTestCache(t)
+			m.Close()
+			cfg := config.IdempotencyConfig{Methods: []string{method}, TTL: time.Hour}
+			interceptor := IdempotencyInterceptor(c, newTestKeyring(t), cfg, tt.outage)
+
+			ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(IdempotencyKeyHeader, "k1"))
+			called := false
+			handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+				called = true
+				return &pb.SignUpResponse{}, nil
+			}
+
+			_, err := interceptor(ctx, &pb.SignUpRequest{}, &grpc.UnaryServerInfo{FullMethod: method}, handler)
+			if tt.wantCode != codes.OK {
+				checkStatus(t, err, tt.wantCode, apierror.ReasonUnavailable)
+				return
+			}
+			if err != nil || !called {
+				t.Errorf("interceptor error = %v, handler called %v; want it to run", err, called)
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"context"
+	"net"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/apierror"
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/ipfilter"
+)
+
+// IPFilterInterceptor rejects clients outside the allowlist or on a denylist
+// before any handler work is done. It must run after ClientIPInterceptor.
+func IPFilterInterceptor(filter *ipfilter.Filter) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		if err := checkIP(ctx, filter); err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// StreamIPFilterInterceptor is the streaming counterpart of IPFilterInterceptor;
+// the address is checked once when the stream is opened
+func StreamIPFilterInterceptor(filter *ipfilter.Filter) grpc.StreamServerInterceptor {
+	return func(
+		srv interface{},
+		ss grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		if err := checkIP(ss.Context(), filter); err != nil {
+			return err
+		}
+
+		return handler(srv, ss)
+	}
+}
+
+func checkIP(ctx context.Context, filter *ipfilter.Filter) error {
+	if !filter.Allowed(net.ParseIP(ClientIPFromContext(ctx))) {
+		return apierror.New(codes.PermissionDenied, apierror.ReasonIPDenied, "access from this address is not allowed")
+	}
+
+	return nil
+}
@@ -0,0 +1,87 @@
+package middleware
+
+import (
+	"context"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/apierror"
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/config"
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/loadshed"
+)
+
+var (
+	concurrencyLimit = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "grpc_server_concurrency_limit",
+			Help: "Current adaptive limit on concurrent unary RPCs.",
+		},
+	)
+
+	inflightRequests = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "grpc_server_inflight_requests",
+			Help: "Unary RPCs currently holding a concurrency slot.",
+		},
+	)
+
+	shedTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "grpc_server_shed_total",
+			Help: "Total number of RPCs rejected because the server was overloaded.",
+		},
+		[]string{"grpc_method"},
+	)
+)
+
+// LoadShedInterceptor admits unary RPCs through a concurrency limiter and
+// rejects the rest with Unavailable and a RetryInfo hint. Streams are not
+// limited: their lifetime says nothing about server load.
+func LoadShedInterceptor(cfg config.LoadShedConfig) grpc.UnaryServerInterceptor {
+	if !cfg.Enabled {
+		return func(
+			ctx context.Context,
+			req interface{},
+			info *grpc.UnaryServerInfo,
+			handler grpc.UnaryHandler,
+		) (interface{}, error) {
+			return handler(ctx, req)
+		}
+	}
+
+	limiter := loadshed.New(cfg)
+	concurrencyLimit.Set(float64(limiter.Limit()))
+
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		release, err := limiter.Acquire(ctx, info.FullMethod)
+		if err != nil {
+			if err != loadshed.ErrOverloaded {
+				// The caller gave up while queued
+				return nil, status.FromContextError(err).Err()
+			}
+
+			shedTotal.WithLabelValues(info.FullMethod).Inc()
+			return nil, apierror.New(codes.Unavailable, apierror.ReasonOverloaded, "server is overloaded, please try again later",
+				apierror.RetryAfter(cfg.RetryAfter))
+		}
+
+		resp, err := handler(ctx, req)
+
+		code := status.Code(err)
+		release(code == codes.DeadlineExceeded || code == codes.ResourceExhausted)
+
+		concurrencyLimit.Set(float64(limiter.Limit()))
+		inflightRequests.Set(float64(limiter.Inflight()))
+
+		return resp, err
+	}
+}
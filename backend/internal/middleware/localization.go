@@ -0,0 +1,112 @@
+package middleware
+
+import (
+	"context"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/anypb"
+
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/i18n"
+)
+
+// AcceptLanguageHeader is the metadata key clients use to request a language
+const AcceptLanguageHeader = "accept-language"
+
+// LocalizationInterceptor translates error messages and field violation
+// descriptions into the language requested in accept-language metadata and
+// adds a LocalizedMessage detail. Codes and ErrorInfo reasons are untouched.
+func LocalizationInterceptor() grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		resp, err := handler(ctx, req)
+		if err != nil {
+			err = localizeError(ctx, err)
+		}
+
+		return resp, err
+	}
+}
+
+// StreamLocalizationInterceptor is the streaming counterpart of
+// LocalizationInterceptor
+func StreamLocalizationInterceptor() grpc.StreamServerInterceptor {
+	return func(
+		srv interface{},
+		ss grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		err := handler(srv, ss)
+		if err != nil {
+			err = localizeError(ss.Context(), err)
+		}
+
+		return err
+	}
+}
+
+// Language returns the base language (e.g. "es") the caller asked for in
+// accept-language metadata, for handlers that localize text in responses;
+// "en" if it asked for none
+func Language(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get(AcceptLanguageHeader)
+	if len(values) == 0 {
+		return "en"
+	}
+	return i18n.Language(values[0])
+}
+
+// localizeError returns err with its text translated for the caller, or err
+// unchanged if the caller wants English or err is not a status error
+func localizeError(ctx context.Context, err error) error {
+	lang := Language(ctx)
+	if lang == "en" {
+		return err
+	}
+
+	st, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+
+	// Proto returns a copy, so the original error is left intact
+	localized := st.Proto()
+	message := i18n.Translate(lang, localized.Message)
+
+	for i, detail := range localized.Details {
+		badRequest := &errdetails.BadRequest{}
+		if detail.UnmarshalTo(badRequest) != nil {
+			continue
+		}
+
+		for j, violation := range badRequest.FieldViolations {
+			translated := i18n.Translate(lang, violation.Description)
+
+			// Validation messages are "<field> <description>" for the first violation
+			if j == 0 && localized.Message == violation.Field+" "+violation.Description {
+				message = violation.Field + " " + translated
+			}
+
+			violation.Description = translated
+		}
+
+		if packed, err := anypb.New(badRequest); err == nil {
+			localized.Details[i] = packed
+		}
+	}
+
+	localized.Message = message
+	if packed, err := anypb.New(&errdetails.LocalizedMessage{Locale: lang, Message: message}); err == nil {
+		localized.Details = append(localized.Details, packed)
+	}
+
+	return status.FromProto(localized).Err()
+}
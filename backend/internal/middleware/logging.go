@@ -2,16 +2,24 @@ package middleware
 
 import (
 	"context"
+	"encoding/json"
 	"time"
 
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/config"
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/redact"
 )
 
-// LoggingInterceptor logs all gRPC requests
-func LoggingInterceptor(logger *zap.Logger) grpc.UnaryServerInterceptor {
+// LoggingInterceptor logs all gRPC requests. With cfg.LogPayloads set it
+// also logs request and response bodies, redacted.
+func LoggingInterceptor(logger *zap.Logger, cfg config.EnvironmentConfig) grpc.UnaryServerInterceptor {
+	redactor := payloadRedactor(cfg)
+
 	return func(
 		ctx context.Context,
 		req interface{},
@@ -36,14 +44,110 @@ func LoggingInterceptor(logger *zap.Logger) grpc.UnaryServerInterceptor {
 			}
 		}
 
-		// Log the request
-		logger.Info("gRPC request",
+		fields := []zap.Field{
+			zap.String("request_id", RequestIDFromContext(ctx)),
+			zap.String("client_ip", ClientIPFromContext(ctx)),
 			zap.String("method", info.FullMethod),
 			zap.String("code", code.String()),
 			zap.Duration("duration", duration),
 			zap.Error(err),
-		)
+		}
+		if redactor != nil {
+			fields = append(fields, payloadField("request", redactor, req))
+			if err == nil {
+				fields = append(fields, payloadField("response", redactor, resp))
+			}
+		}
+
+		// Log the request
+		logger.Info("gRPC request", fields...)
 
 		return resp, err
 	}
 }
+
+// StreamLoggingInterceptor logs each stream once it completes. With
+// cfg.LogPayloads set it also logs every message, redacted, as it passes.
+func StreamLoggingInterceptor(logger *zap.Logger, cfg config.EnvironmentConfig) grpc.StreamServerInterceptor {
+	redactor := payloadRedactor(cfg)
+
+	return func(
+		srv interface{},
+		ss grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		start := time.Now()
+
+		stream := ss
+		if redactor != nil {
+			stream = &payloadLoggingStream{ServerStream: ss, logger: logger, redactor: redactor, method: info.FullMethod}
+		}
+
+		err := handler(srv, stream)
+
+		logger.Info("gRPC stream",
+			zap.String("request_id", RequestIDFromContext(ss.Context())),
+			zap.String("client_ip", ClientIPFromContext(ss.Context())),
+			zap.String("method", info.FullMethod),
+			zap.String("code", status.Code(err).String()),
+			zap.Duration("duration", time.Since(start)),
+			zap.Error(err),
+		)
+
+		return err
+	}
+}
+
+// payloadLoggingStream logs each message sent or received on a stream
+type payloadLoggingStream struct {
+	grpc.ServerStream
+	logger   *zap.Logger
+	redactor *redact.Redactor
+	method   string
+}
+
+func (s *payloadLoggingStream) RecvMsg(m interface{}) error {
+	err := s.ServerStream.RecvMsg(m)
+	if err == nil {
+		s.log("received", m)
+	}
+	return err
+}
+
+func (s *payloadLoggingStream) SendMsg(m interface{}) error {
+	s.log("sent", m)
+	return s.ServerStream.SendMsg(m)
+}
+
+func (s *payloadLoggingStream) log(direction string, m interface{}) {
+	s.logger.Info("gRPC stream message",
+		zap.String("request_id", RequestIDFromContext(s.Context())),
+		zap.String("method", s.method),
+		zap.String("direction", direction),
+		payloadField("payload", s.redactor, m),
+	)
+}
+
+// payloadRedactor returns nil unless payload logging is on
+func payloadRedactor(cfg config.EnvironmentConfig) *redact.Redactor {
+	if !cfg.LogPayloads {
+		return nil
+	}
+	return redact.New(cfg.LogRedactFields)
+}
+
+// payloadField renders a message as a JSON log field with secrets masked
+func payloadField(key string, redactor *redact.Redactor, m interface{}) zap.Field {
+	msg, ok := m.(proto.Message)
+	if !ok {
+		return zap.Skip()
+	}
+
+	payload, err := redactor.JSON(msg)
+	if err != nil {
+		return zap.String(key, "<unserializable: "+err.Error()+">")
+	}
+
+	return zap.Reflect(key, json.RawMessage(payload))
+}
@@ -0,0 +1,85 @@
+package middleware
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/apierror"
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/cache"
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/config"
+)
+
+// RateLimitInterceptor limits how many requests a caller may make per window.
+// Authenticated callers are counted by user ID against the authenticated
+// limit, everyone else by client IP against the public limit, so it must run
+// after AuthInterceptor and ClientIPInterceptor. If the cache fails, calls go
+// ahead or are rejected as outage says (config.FailOpen or FailClosed).
+func RateLimitInterceptor(c *cache.Cache, cfg config.RateLimitConfig, outage string) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		if err := checkRateLimit(ctx, c, cfg, outage); err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// StreamRateLimitInterceptor counts each new stream as one request, using the
+// same limits as RateLimitInterceptor
+func StreamRateLimitInterceptor(c *cache.Cache, cfg config.RateLimitConfig, outage string) grpc.StreamServerInterceptor {
+	return func(
+		srv interface{},
+		ss grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		if err := checkRateLimit(ss.Context(), c, cfg, outage); err != nil {
+			return err
+		}
+
+		return handler(srv, ss)
+	}
+}
+
+// checkRateLimit returns ResourceExhausted with a RetryInfo detail once the
+// caller is over its limit. Each caller may burst up to its limit, then
+// regains one request every window/limit (see cache.Limit).
+func checkRateLimit(ctx context.Context, c *cache.Cache, cfg config.RateLimitConfig, outage string) error {
+	identifier, limit := "ip:"+ClientIPFromContext(ctx), cfg.Public
+	if claims, ok := ClaimsFromContext(ctx); ok {
+		identifier, limit = "user:"+claims.UserID, cfg.Authenticated
+	}
+
+	if limit <= 0 || cfg.Window <= 0 {
+		return nil
+	}
+
+	result, err := c.AllowRequest(ctx, identifier, cache.PerPeriod(limit, cfg.Window))
+	if err != nil {
+		return cacheOutage(outage)
+	}
+
+	if result.Allowed {
+		return nil
+	}
+
+	return apierror.New(codes.ResourceExhausted, apierror.ReasonRateLimited, "rate limit exceeded, please try again later",
+		apierror.RetryAfter(result.RetryAfter))
+}
+
+// cacheOutage returns the error for a check that couldn't reach the cache:
+// none if it fails open, so an outage doesn't take the API down with it, or
+// UNAVAILABLE if it fails closed
+func cacheOutage(outage string) error {
+	if outage == config.FailClosed {
+		return apierror.CacheUnavailable()
+	}
+	return nil
+}
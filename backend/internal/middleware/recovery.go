@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"context"
+	"runtime/debug"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/apierror"
+)
+
+var panicsTotal = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "grpc_server_panics_total",
+		Help: "Total number of panics recovered in gRPC handlers.",
+	},
+	[]string{"grpc_method"},
+)
+
+// RecoveryInterceptor converts panics in unary handlers into codes.Internal
+// errors instead of crashing the server
+func RecoveryInterceptor(logger *zap.Logger) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (resp interface{}, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = recoverPanic(ctx, logger, info.FullMethod, r)
+			}
+		}()
+
+		return handler(ctx, req)
+	}
+}
+
+// StreamRecoveryInterceptor converts panics in stream handlers into
+// codes.Internal errors instead of crashing the server
+func StreamRecoveryInterceptor(logger *zap.Logger) grpc.StreamServerInterceptor {
+	return func(
+		srv interface{},
+		ss grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = recoverPanic(ss.Context(), logger, info.FullMethod, r)
+			}
+		}()
+
+		return handler(srv, ss)
+	}
+}
+
+// recoverPanic logs the panic with its stack, records it, and returns the
+// error sent to the client (which never includes panic details)
+func recoverPanic(ctx context.Context, logger *zap.Logger, method string, r interface{}) error {
+	panicsTotal.WithLabelValues(method).Inc()
+
+	logger.Error("gRPC handler panic",
+		zap.String("request_id", RequestIDFromContext(ctx)),
+		zap.String("method", method),
+		zap.Any("panic", r),
+		zap.ByteString("stack", debug.Stack()),
+	)
+
+	return apierror.Internal("internal server error")
+}
@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/apierror"
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/authz"
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/cache"
+	"github.com/sahays/grpc-proto-go-flutter-template/pkg/jwt"
+)
+
+// reflectionPrefix matches every version of the server reflection service
+const reflectionPrefix = "/grpc.reflection."
+
+// StreamReflectionAuthInterceptor serves server reflection to admins only
+// when requireAdmin is set. Reflection is public to the regular auth
+// interceptors (so grpcurl works in development); this filter closes it
+// without touching AUTH_PUBLIC_METHODS or the authorization policy.
+func StreamReflectionAuthInterceptor(jwtService *jwt.Service, c *cache.Cache, requireAdmin bool, outage string) grpc.StreamServerInterceptor {
+	return func(
+		srv interface{},
+		ss grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		if !requireAdmin || !strings.HasPrefix(info.FullMethod, reflectionPrefix) {
+			return handler(srv, ss)
+		}
+
+		claims, err := authenticate(ss.Context(), jwtService, c, info.FullMethod, outage)
+		if err != nil {
+			return err
+		}
+
+		if !claims.HasRole(authz.RoleAdmin) {
+			return apierror.New(codes.PermissionDenied, apierror.ReasonPermissionDenied, "not allowed to call this method")
+		}
+
+		return handler(srv, ss)
+	}
+}
@@ -0,0 +1,111 @@
+package middleware
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// RequestIDHeader is the metadata key used to accept and echo request IDs
+const RequestIDHeader = "x-request-id"
+
+type requestIDContextKey struct{}
+
+// RequestIDInterceptor accepts a client-supplied x-request-id (or generates
+// one), stores it in the context, echoes it in the response header, and
+// attaches it to error responses as a RequestInfo detail
+func RequestIDInterceptor() grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		requestID := incomingRequestID(ctx)
+		ctx = ContextWithRequestID(ctx, requestID)
+
+		_ = grpc.SetHeader(ctx, metadata.Pairs(RequestIDHeader, requestID))
+
+		resp, err := handler(ctx, req)
+		if err != nil {
+			err = withRequestInfo(err, requestID)
+		}
+
+		return resp, err
+	}
+}
+
+// StreamRequestIDInterceptor is the streaming counterpart of
+// RequestIDInterceptor
+func StreamRequestIDInterceptor() grpc.StreamServerInterceptor {
+	return func(
+		srv interface{},
+		ss grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		requestID := incomingRequestID(ss.Context())
+		ctx := ContextWithRequestID(ss.Context(), requestID)
+
+		_ = ss.SetHeader(metadata.Pairs(RequestIDHeader, requestID))
+
+		err := handler(srv, &wrappedStream{ServerStream: ss, ctx: ctx})
+		if err != nil {
+			err = withRequestInfo(err, requestID)
+		}
+
+		return err
+	}
+}
+
+// ContextWithRequestID returns a copy of ctx carrying the request ID
+func ContextWithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDContextKey{}, requestID)
+}
+
+// RequestIDFromContext returns the request ID stored by RequestIDInterceptor
+func RequestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDContextKey{}).(string)
+	return requestID
+}
+
+// incomingRequestID returns the client's request ID if it is well-formed,
+// otherwise a freshly generated one
+func incomingRequestID(ctx context.Context) string {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(RequestIDHeader); len(values) > 0 && isValidRequestID(values[0]) {
+			return values[0]
+		}
+	}
+	return uuid.New().String()
+}
+
+func isValidRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for _, char := range id {
+		if char < '!' || char > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+func withRequestInfo(err error, requestID string) error {
+	st, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+
+	detailed, detailErr := st.WithDetails(&errdetails.RequestInfo{RequestId: requestID})
+	if detailErr != nil {
+		return err
+	}
+
+	return detailed.Err()
+}
@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"context"
+	"sync"
+
+	"google.golang.org/grpc"
+)
+
+// StreamCloser tracks the open streams of each authenticated user, so
+// signing a user out can end them. The token of a stream is only checked
+// when it opens, so revoking it alone leaves the stream running.
+type StreamCloser struct {
+	mu      sync.Mutex
+	streams map[string]map[*context.CancelFunc]struct{}
+}
+
+// NewStreamCloser creates a StreamCloser tracking no streams
+func NewStreamCloser() *StreamCloser {
+	return &StreamCloser{streams: make(map[string]map[*context.CancelFunc]struct{})}
+}
+
+// CloseUser cancels the context of every open stream of the user
+func (c *StreamCloser) CloseUser(userID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for cancel := range c.streams[userID] {
+		(*cancel)()
+	}
+	delete(c.streams, userID)
+}
+
+func (c *StreamCloser) track(userID string, cancel *context.CancelFunc) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.streams[userID] == nil {
+		c.streams[userID] = make(map[*context.CancelFunc]struct{})
+	}
+	c.streams[userID][cancel] = struct{}{}
+}
+
+func (c *StreamCloser) untrack(userID string, cancel *context.CancelFunc) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.streams[userID], cancel)
+	if len(c.streams[userID]) == 0 {
+		delete(c.streams, userID)
+	}
+}
+
+// StreamCloserInterceptor registers authenticated streams with closer for
+// as long as they run. It must come after StreamAuthInterceptor.
+func StreamCloserInterceptor(closer *StreamCloser) grpc.StreamServerInterceptor {
+	return func(
+		srv interface{},
+		ss grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		claims, ok := ClaimsFromContext(ss.Context())
+		if !ok {
+			return handler(srv, ss)
+		}
+
+		ctx, cancel := context.WithCancel(ss.Context())
+		defer cancel()
+		closer.track(claims.UserID, &cancel)
+		defer closer.untrack(claims.UserID, &cancel)
+
+		return handler(srv, &wrappedStream{ServerStream: ss, ctx: ctx})
+	}
+}
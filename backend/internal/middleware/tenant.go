@@ -0,0 +1,153 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/apierror"
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/db"
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/models"
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/tenant"
+	pb "github.com/sahays/grpc-proto-go-flutter-template/proto"
+	adminpb "github.com/sahays/grpc-proto-go-flutter-template/proto/admin"
+)
+
+// TenantHeader is the metadata key clients use to name their tenant, by slug
+// or ID
+const TenantHeader = "x-tenant"
+
+var (
+	// tenantScopedPrefix covers the methods that read or write tenant data
+	// and so must know their tenant
+	tenantScopedPrefix = "/" + pb.AuthService_ServiceDesc.ServiceName + "/"
+	// operatorPrefix covers platform-wide methods, open only to the default
+	// tenant
+	operatorPrefix = "/" + adminpb.AdminService_ServiceDesc.ServiceName + "/"
+)
+
+// TenantInterceptor scopes each call to a tenant (see db.WithTenant).
+// Authenticated calls run in their token's tenant, and x-tenant, if sent,
+// must name the same one. Unauthenticated calls name their tenant with
+// x-tenant. Unknown and inactive tenants are rejected. With tenancy disabled
+// every call runs in the default tenant.
+func TenantInterceptor(resolver *tenant.Resolver, enabled bool) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		ctx, err := scopeToTenant(ctx, resolver, enabled, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// StreamTenantInterceptor is the streaming counterpart of TenantInterceptor
+func StreamTenantInterceptor(resolver *tenant.Resolver, enabled bool) grpc.StreamServerInterceptor {
+	return func(
+		srv interface{},
+		ss grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		ctx, err := scopeToTenant(ss.Context(), resolver, enabled, info.FullMethod)
+		if err != nil {
+			return err
+		}
+
+		return handler(srv, &wrappedStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// scopeToTenant returns ctx scoped to the call's tenant. Calls outside the
+// tenant-scoped and operator services may run without one.
+func scopeToTenant(ctx context.Context, resolver *tenant.Resolver, enabled bool, method string) (context.Context, error) {
+	if !enabled {
+		return db.WithTenant(ctx, db.DefaultTenantID), nil
+	}
+
+	tenantID, err := callTenant(ctx, resolver)
+	if err != nil {
+		return nil, err
+	}
+
+	scoped := strings.HasPrefix(method, tenantScopedPrefix)
+	operator := strings.HasPrefix(method, operatorPrefix)
+
+	if tenantID == "" {
+		if scoped || operator {
+			return nil, apierror.New(codes.InvalidArgument, apierror.ReasonTenantRequired, "tenant is required")
+		}
+		return ctx, nil
+	}
+
+	if operator && tenantID != db.DefaultTenantID {
+		return nil, apierror.New(codes.PermissionDenied, apierror.ReasonPermissionDenied, "only operators of the default tenant may call this method")
+	}
+
+	return db.WithTenant(ctx, tenantID), nil
+}
+
+// callTenant returns the ID of the tenant the call is for, or "" if it names
+// none
+func callTenant(ctx context.Context, resolver *tenant.Resolver) (string, error) {
+	var ref string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(TenantHeader); len(values) > 0 {
+			ref = strings.TrimSpace(values[0])
+		}
+	}
+
+	claims, authenticated := ClaimsFromContext(ctx)
+	if authenticated {
+		// Tokens issued before tenancy belong to the default tenant
+		tokenTenant := claims.TenantID
+		if tokenTenant == "" {
+			tokenTenant = db.DefaultTenantID
+		}
+
+		t, err := resolveTenant(ctx, resolver, tokenTenant)
+		if err != nil {
+			return "", err
+		}
+		if ref != "" && ref != t.ID && ref != t.Slug {
+			return "", apierror.New(codes.PermissionDenied, apierror.ReasonTenantMismatch, "token was issued for a different tenant")
+		}
+		return t.ID, nil
+	}
+
+	if ref == "" {
+		return "", nil
+	}
+
+	t, err := resolveTenant(ctx, resolver, ref)
+	if err != nil {
+		return "", err
+	}
+	return t.ID, nil
+}
+
+// resolveTenant looks up an active tenant by ID or slug
+func resolveTenant(ctx context.Context, resolver *tenant.Resolver, ref string) (*models.Tenant, error) {
+	t, err := resolver.Resolve(ctx, ref)
+	if errors.Is(err, models.ErrTenantNotFound) {
+		return nil, apierror.New(codes.NotFound, apierror.ReasonTenantNotFound, "unknown tenant")
+	}
+	if err != nil {
+		return nil, apierror.Database(err, "failed to resolve tenant")
+	}
+
+	if !t.IsActive {
+		return nil, apierror.New(codes.PermissionDenied, apierror.ReasonTenantInactive, "tenant is inactive")
+	}
+	return t, nil
+}
@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+// TimeoutInterceptor bounds how long a handler may run. Calls without a
+// client deadline get the method's configured timeout (or defaultTimeout);
+// client deadlines further away than maxTimeout are capped.
+func TimeoutInterceptor(defaultTimeout, maxTimeout time.Duration, methodTimeouts map[string]time.Duration) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		timeout := defaultTimeout
+		if methodTimeout, ok := methodTimeouts[info.FullMethod]; ok {
+			timeout = methodTimeout
+		}
+
+		if deadline, ok := ctx.Deadline(); ok {
+			// Honor the client's deadline unless it exceeds the hard cap
+			if maxTimeout <= 0 || time.Until(deadline) <= maxTimeout {
+				return handler(ctx, req)
+			}
+			timeout = maxTimeout
+		}
+
+		if timeout <= 0 {
+			return handler(ctx, req)
+		}
+
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		return handler(ctx, req)
+	}
+}
@@ -0,0 +1,135 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"buf.build/gen/go/bufbuild/protovalidate/protocolbuffers/go/buf/validate"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/apierror"
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/protovalidate"
+)
+
+// ValidationInterceptor rejects requests that violate the buf.validate rules
+// declared in the proto files before they reach the handler. Violations are
+// returned as an InvalidArgument status carrying a BadRequest detail with one
+// entry per violation; the status message describes the first one.
+//
+// Fields with the email rule are trimmed of surrounding whitespace in place
+// before they're checked, so handlers store and look up the same address
+// however it was typed.
+func ValidationInterceptor(v *protovalidate.Validator) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		if msg, ok := req.(proto.Message); ok {
+			if err := validateRequest(v, msg); err != nil {
+				return nil, err
+			}
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// StreamValidationInterceptor applies the same checks to every message a
+// client sends on a stream; the first invalid message ends the stream
+func StreamValidationInterceptor(v *protovalidate.Validator) grpc.StreamServerInterceptor {
+	return func(
+		srv interface{},
+		ss grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		return handler(srv, &validatingStream{ServerStream: ss, validator: v})
+	}
+}
+
+// validatingStream validates each message as it is received
+type validatingStream struct {
+	grpc.ServerStream
+	validator *protovalidate.Validator
+}
+
+func (s *validatingStream) RecvMsg(m interface{}) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+
+	if msg, ok := m.(proto.Message); ok {
+		return validateRequest(s.validator, msg)
+	}
+
+	return nil
+}
+
+// validateRequest trims msg's email fields and checks it against its rules
+func validateRequest(v *protovalidate.Validator, msg proto.Message) error {
+	trimEmails(msg.ProtoReflect())
+
+	err := v.Validate(msg)
+	var validationErr *protovalidate.ValidationError
+	if errors.As(err, &validationErr) {
+		violations := make([]*errdetails.BadRequest_FieldViolation, len(validationErr.Violations))
+		for i, violation := range validationErr.Violations {
+			violations[i] = &errdetails.BadRequest_FieldViolation{
+				Field:       violation.Field,
+				Description: violation.Message,
+			}
+		}
+
+		first := violations[0]
+		return apierror.New(codes.InvalidArgument, apierror.ReasonInvalidArgument, first.Field+" "+first.Description,
+			&errdetails.BadRequest{FieldViolations: violations})
+	}
+	if err != nil {
+		return apierror.Internal("failed to validate request")
+	}
+	return nil
+}
+
+// trimEmails trims surrounding whitespace from the fields of m, and of the
+// messages it holds, that have the email rule
+func trimEmails(m protoreflect.Message) {
+	// Set after Range, which mustn't see the message change
+	var emails []protoreflect.FieldDescriptor
+	m.Range(func(fd protoreflect.FieldDescriptor, value protoreflect.Value) bool {
+		switch {
+		case fd.IsMap():
+			if fd.MapValue().Message() != nil {
+				value.Map().Range(func(_ protoreflect.MapKey, item protoreflect.Value) bool {
+					trimEmails(item.Message())
+					return true
+				})
+			}
+		case fd.IsList():
+			if fd.Message() != nil {
+				list := value.List()
+				for i := 0; i < list.Len(); i++ {
+					trimEmails(list.Get(i).Message())
+				}
+			}
+		case fd.Message() != nil:
+			trimEmails(value.Message())
+		case fd.Kind() == protoreflect.StringKind:
+			rules, _ := proto.GetExtension(fd.Options(), validate.E_Field).(*validate.FieldRules)
+			if s := value.String(); rules.GetString().GetEmail() && strings.TrimSpace(s) != s {
+				emails = append(emails, fd)
+			}
+		}
+		return true
+	})
+
+	for _, fd := range emails {
+		m.Set(fd, protoreflect.ValueOfString(strings.TrimSpace(m.Get(fd).String())))
+	}
+}
@@ -0,0 +1,93 @@
+package middleware
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/protovalidate"
+	pb "github.com/sahays/grpc-proto-go-flutter-template/proto"
+)
+
+func TestValidationInterceptor(t *testing.T) {
+	v, err := protovalidate.New()
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	interceptor := ValidationInterceptor(v)
+	info := &grpc.UnaryServerInfo{FullMethod: "/auth.AuthService/Login"}
+
+	tests := []struct {
+		name string
+		req  *pb.LoginRequest
+		// wantEmail is the email the handler sees; "" if it isn't called
+		wantEmail string
+		// wantMessage is the status message of the rejection
+		wantMessage string
+		// wantFields are the fields of the BadRequest violations
+		wantFields []string
+	}{
+		{
+			name:      "valid",
+			req:       &pb.LoginRequest{Email: "ada@example.com", Password: "secret"},
+			wantEmail: "ada@example.com",
+		},
+		{
+			name:      "email trimmed",
+			req:       &pb.LoginRequest{Email: "  ada@example.com\n", Password: "secret"},
+			wantEmail: "ada@example.com",
+		},
+		{
+			name:        "invalid",
+			req:         &pb.LoginRequest{Email: "ada", DeviceName: strings.Repeat("x", 101)},
+			wantMessage: "email value must be a valid email address",
+			wantFields:  []string{"email", "password", "device_name"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotEmail string
+			handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+				gotEmail = req.(*pb.LoginRequest).Email
+				return nil, nil
+			}
+
+			_, err := interceptor(context.Background(), tt.req, info, handler)
+			if gotEmail != tt.wantEmail {
+				t.Errorf("handler email = %q, want %q", gotEmail, tt.wantEmail)
+			}
+			if tt.wantMessage == "" {
+				if err != nil {
+					t.Fatalf("interceptor: %v", err)
+				}
+				return
+			}
+
+			st := status.Convert(err)
+			if st.Code() != codes.InvalidArgument || st.Message() != tt.wantMessage {
+				t.Errorf("status = %v %q, want InvalidArgument %q", st.Code(), st.Message(), tt.wantMessage)
+			}
+			var gotFields []string
+			for _, detail := range st.Details() {
+				if badRequest, ok := detail.(*errdetails.BadRequest); ok {
+					for _, violation := range badRequest.FieldViolations {
+						gotFields = append(gotFields, violation.Field)
+					}
+				}
+			}
+			if len(gotFields) != len(tt.wantFields) {
+				t.Fatalf("violation fields = %q, want %q", gotFields, tt.wantFields)
+			}
+			for i := range gotFields {
+				if gotFields[i] != tt.wantFields[i] {
+					t.Errorf("violation fields = %q, want %q", gotFields, tt.wantFields)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// AuditLog is one recorded RPC
+type AuditLog struct {
+	OccurredAt time.Time
+	Method     string
+	// ActorID is the authenticated caller, empty for anonymous calls
+	ActorID string
+	// TargetID is the user the call acted on, if it names one
+	TargetID   string
+	StatusCode string
+	ClientIP   string
+	Latency    time.Duration
+	RequestID  string
+	// Payload is the redacted request as JSON, nil if not recorded
+	Payload []byte
+}
+
+// auditLogColumns is the number of columns InsertBatch writes per entry
+const auditLogColumns = 9
+
+// AuditLogRepository handles audit log database operations
+type AuditLogRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewAuditLogRepository creates a new audit log repository
+func NewAuditLogRepository(db *pgxpool.Pool) *AuditLogRepository {
+	return &AuditLogRepository{db: db}
+}
+
+// InsertBatch writes entries in a single statement
+func (r *AuditLogRepository) InsertBatch(ctx context.Context, entries []AuditLog) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	placeholders := make([]string, 0, len(entries))
+	args := make([]interface{}, 0, len(entries)*auditLogColumns)
+	for i, e := range entries {
+		n := i * auditLogColumns
+		placeholders = append(placeholders, fmt.Sprintf("($%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d)",
+			n+1, n+2, n+3, n+4, n+5, n+6, n+7, n+8, n+9))
+
+		var payload interface{}
+		if e.Payload != nil {
+			payload = string(e.Payload)
+		}
+
+		args = append(args,
+			e.OccurredAt,
+			e.Method,
+			nullString(e.ActorID),
+			nullString(e.TargetID),
+			e.StatusCode,
+			nullString(e.ClientIP),
+			e.Latency.Milliseconds(),
+			nullString(e.RequestID),
+			payload,
+		)
+	}
+
+	query := `
+		INSERT INTO audit_log (occurred_at, method, actor_id, target_id, status_code, client_ip, latency_ms, request_id, payload)
+		VALUES ` + strings.Join(placeholders, ", ")
+
+	if _, err := r.db.Exec(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed to insert audit log: %w", err)
+	}
+
+	return nil
+}
+
+// nullString maps an empty string to NULL
+func nullString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
@@ -0,0 +1,16 @@
+package models
+
+import "github.com/google/uuid"
+
+// IDGenerator generates primary keys
+type IDGenerator func() (uuid.UUID, error)
+
+// NewIDGenerator returns a generator of time-ordered UUIDv7s if v7 is set,
+// or random UUIDv4s otherwise. UUIDv7s sort by creation time, so inserts
+// append to the primary key index instead of splitting pages at random.
+func NewIDGenerator(v7 bool) IDGenerator {
+	if v7 {
+		return uuid.NewV7
+	}
+	return uuid.NewRandom
+}
@@ -0,0 +1,135 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/db"
+)
+
+// Outbound event types
+const (
+	EventUserCreated     = "user.created"
+	EventPasswordChanged = "password.changed"
+)
+
+// OutboxEvent is an event waiting in, or delivered from, the outbox
+type OutboxEvent struct {
+	ID   int64
+	Type string
+	// AggregateID is the entity the event is about, such as a user ID
+	AggregateID string
+	// Payload is the event body as JSON
+	Payload   []byte
+	CreatedAt time.Time
+	// Attempts counts failed deliveries so far
+	Attempts int
+}
+
+// OutboxRepository handles outbox database operations
+type OutboxRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewOutboxRepository creates a new outbox repository
+func NewOutboxRepository(db *pgxpool.Pool) *OutboxRepository {
+	return &OutboxRepository{db: db}
+}
+
+// Add records an event. Call it inside db.TxManager.WithinTx so the event is
+// committed if and only if the change it describes is.
+func (r *OutboxRepository) Add(ctx context.Context, eventType, aggregateID string, payload []byte) error {
+	query := `
+		INSERT INTO outbox (event_type, aggregate_id, payload)
+		VALUES ($1, $2, $3)
+	`
+
+	if _, err := db.ExecutorFrom(ctx, r.db).Exec(ctx, query, eventType, aggregateID, string(payload)); err != nil {
+		return fmt.Errorf("failed to add outbox event: %w", err)
+	}
+
+	return nil
+}
+
+// ClaimPending locks up to limit undelivered events that are due, oldest
+// first. Events locked by another relay are skipped. Call it inside
+// db.TxManager.WithinTx; the locks are held until the transaction ends.
+func (r *OutboxRepository) ClaimPending(ctx context.Context, limit int) ([]OutboxEvent, error) {
+	query := `
+		SELECT id, event_type, aggregate_id, payload, created_at, attempts
+		FROM outbox
+		WHERE published_at IS NULL AND available_at <= NOW()
+		ORDER BY id
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED
+	`
+
+	rows, err := db.ExecutorFrom(ctx, r.db).Query(ctx, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim outbox events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []OutboxEvent
+	for rows.Next() {
+		var e OutboxEvent
+		var payload string
+		if err := rows.Scan(&e.ID, &e.Type, &e.AggregateID, &payload, &e.CreatedAt, &e.Attempts); err != nil {
+			return nil, fmt.Errorf("failed to scan outbox event: %w", err)
+		}
+		e.Payload = []byte(payload)
+		events = append(events, e)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating outbox events: %w", err)
+	}
+
+	return events, nil
+}
+
+// MarkPublished records the events as delivered
+func (r *OutboxRepository) MarkPublished(ctx context.Context, ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	query := `UPDATE outbox SET published_at = NOW(), last_error = NULL WHERE id = ANY($1)`
+
+	if _, err := db.ExecutorFrom(ctx, r.db).Exec(ctx, query, ids); err != nil {
+		return fmt.Errorf("failed to mark outbox events published: %w", err)
+	}
+
+	return nil
+}
+
+// MarkFailed records a failed delivery and holds the event back for retryIn
+func (r *OutboxRepository) MarkFailed(ctx context.Context, id int64, deliveryErr string, retryIn time.Duration) error {
+	query := `
+		UPDATE outbox
+		SET attempts = attempts + 1, last_error = $1, available_at = NOW() + $2::interval
+		WHERE id = $3
+	`
+
+	if _, err := db.ExecutorFrom(ctx, r.db).Exec(ctx, query, deliveryErr, retryIn, id); err != nil {
+		return fmt.Errorf("failed to mark outbox event failed: %w", err)
+	}
+
+	return nil
+}
+
+// DeletePublishedBefore deletes events delivered before cutoff and returns
+// how many were deleted
+func (r *OutboxRepository) DeletePublishedBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	query := `DELETE FROM outbox WHERE published_at < $1`
+
+	result, err := r.db.Exec(ctx, query, cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete published outbox events: %w", err)
+	}
+
+	return result.RowsAffected(), nil
+}
@@ -0,0 +1,413 @@
+package models
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	embeddedpostgres "github.com/fergusstrange/embedded-postgres"
+
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/config"
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/db"
+	"github.com/sahays/grpc-proto-go-flutter-template/pkg/crypto"
+)
+
+// testDB is the migrated database of the integration tests, started by
+// TestMain in an embedded PostgreSQL, the same server EMBEDDED_POSTGRES runs
+var testDB *db.DB
+
+// testDBErr is why testDB is nil
+var testDBErr error
+
+func TestMain(m *testing.M) {
+	flag.Parse()
+
+	var stop func()
+	if testing.Short() {
+		testDBErr = errors.New("skipped in short mode")
+	} else {
+		stop, testDBErr = startTestDB()
+	}
+
+	code := m.Run()
+	if stop != nil {
+		stop()
+	}
+	os.Exit(code)
+}
+
+// startTestDB starts PostgreSQL in a temporary directory on a free port and
+// migrates it. Its binaries are downloaded on first use and cached in the
+// user's home, as for EMBEDDED_POSTGRES.
+func startTestDB() (stop func(), err error) {
+	dir, err := os.MkdirTemp("", "models-test-")
+	if err != nil {
+		return nil, err
+	}
+	stops := []func(){func() { _ = os.RemoveAll(dir) }}
+	stopAll := func() {
+		for i := len(stops) - 1; i >= 0; i-- {
+			stops[i]()
+		}
+	}
+	defer func() {
+		if err != nil {
+			stopAll()
+		}
+	}()
+
+	port, err := freePort()
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &config.Config{}
+	cfg.Database = config.DatabaseConfig{
+		Host:               "localhost",
+		Port:               fmt.Sprint(port),
+		User:               "postgres",
+		Password:           "postgres",
+		DBName:             "models_test",
+		SSLMode:            "disable",
+		MaxOpenConns:       4,
+		ConnMaxLifetime:    time.Hour,
+		ConnMaxIdleTime:    time.Minute,
+		SlowQueryThreshold: time.Second,
+	}
+
+	// The server's output is only shown if it fails to start
+	var logs bytes.Buffer
+	postgres := embeddedpostgres.NewDatabase(embeddedpostgres.DefaultConfig().
+		Version(embeddedpostgres.V15).
+		Port(uint32(port)).
+		Database(cfg.Database.DBName).
+		Username(cfg.Database.User).
+		Password(cfg.Database.Password).
+		DataPath(filepath.Join(dir, "data")).
+		RuntimePath(filepath.Join(dir, "runtime")).
+		Logger(&logs))
+	if err := postgres.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start embedded PostgreSQL: %w\n%s", err, logs.String())
+	}
+	stops = append(stops, func() { _ = postgres.Stop() })
+
+	database, err := db.New(cfg)
+	if err != nil {
+		return nil, err
+	}
+	stops = append(stops, database.Close)
+
+	if err := database.RunMigrations(context.Background()); err != nil {
+		return nil, err
+	}
+
+	testDB = database
+	return stopAll, nil
+}
+
+// freePort returns a TCP port nothing listens on
+func freePort() (int, error) {
+	listener, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		return 0, err
+	}
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port, nil
+}
+
+// requireDB skips the test without the embedded database, such as in short
+// mode or when its binaries can't be downloaded
+func requireDB(t *testing.T) *db.DB {
+	t.Helper()
+
+	if testDB == nil {
+		t.Skipf("PostgreSQL unavailable: %v", testDBErr)
+	}
+	return testDB
+}
+
+// newTestRepository returns a UserRepository on the embedded database,
+// without a cache
+func newTestRepository(t *testing.T) *UserRepository {
+	t.Helper()
+
+	database := requireDB(t)
+	router, err := db.NewRouter(&config.Config{}, database.Pool)
+	if err != nil {
+		t.Fatalf("NewRouter: %v", err)
+	}
+	t.Cleanup(router.Close)
+
+	keyring, err := crypto.NewKeyring(map[string][]byte{"test": bytes.Repeat([]byte{1}, 32)}, "test")
+	if err != nil {
+		t.Fatalf("NewKeyring: %v", err)
+	}
+	return NewUserRepository(router, nil, nil, keyring)
+}
+
+// newTestUser creates an active user with a unique email in the tenant of
+// ctx
+func newTestUser(t *testing.T, ctx context.Context, repo *UserRepository) *User {
+	t.Helper()
+
+	user := &User{
+		Email:        fmt.Sprintf("user-%d@example.com", time.Now().UnixNano()),
+		PasswordHash: "hash",
+		FirstName:    "Ada",
+		LastName:     "Lovelace",
+		IsActive:     true,
+	}
+	if err := repo.Create(ctx, user); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	return user
+}
+
+// TestMigrations rolls every migration back and applies them again, so each
+// down migration undoes its up migration
+func TestMigrations(t *testing.T) {
+	database := requireDB(t)
+	ctx := context.Background()
+
+	status, err := database.MigrationStatus(ctx)
+	if err != nil {
+		t.Fatalf("MigrationStatus: %v", err)
+	}
+	if status.Version != status.Latest || status.Dirty {
+		t.Fatalf("status after RunMigrations = %+v, want version %d, clean", status, status.Latest)
+	}
+
+	if err := database.MigrateDown(ctx, int(status.Latest)); err != nil {
+		t.Fatalf("MigrateDown: %v", err)
+	}
+	status, err = database.MigrationStatus(ctx)
+	if err != nil {
+		t.Fatalf("MigrationStatus: %v", err)
+	}
+	if status.Version != 0 || !status.Pending() {
+		t.Errorf("status after MigrateDown = %+v, want version 0, pending", status)
+	}
+	var tables int
+	if err := database.QueryRow(ctx, `SELECT count(*) FROM pg_tables WHERE schemaname = 'public' AND tablename <> 'schema_migrations'`).Scan(&tables); err != nil {
+		t.Fatalf("count tables: %v", err)
+	}
+	if tables != 0 {
+		t.Errorf("%d tables left after rolling back every migration", tables)
+	}
+
+	if err := database.RunMigrations(ctx); err != nil {
+		t.Fatalf("RunMigrations: %v", err)
+	}
+	// Applying them again does nothing
+	if err := database.RunMigrations(ctx); err != nil {
+		t.Fatalf("RunMigrations, again: %v", err)
+	}
+	status, err = database.MigrationStatus(ctx)
+	if err != nil {
+		t.Fatalf("MigrationStatus: %v", err)
+	}
+	if status.Version != status.Latest || status.Dirty {
+		t.Errorf("status after RunMigrations = %+v, want version %d, clean", status, status.Latest)
+	}
+}
+
+func TestUserRepositoryCreate(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := db.WithTenant(context.Background(), db.DefaultTenantID)
+
+	user := newTestUser(t, ctx, repo)
+	if user.ID == "" || user.TenantID != db.DefaultTenantID || user.Version != 1 || user.CreatedAt.IsZero() {
+		t.Errorf("created user = %+v, want an ID, the tenant, version 1 and a creation time", user)
+	}
+
+	tenant, err := NewTenantRepository(testDB.Pool).Create(context.Background(), fmt.Sprintf("t-%d", time.Now().UnixNano()), "Other")
+	if err != nil {
+		t.Fatalf("Create tenant: %v", err)
+	}
+	otherTenant := db.WithTenant(context.Background(), tenant.ID)
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		wantErr error
+		// wantAnyErr is for errors without a sentinel
+		wantAnyErr bool
+	}{
+		{name: "email taken in the tenant", ctx: ctx, wantErr: ErrEmailTaken},
+		{name: "email free in another tenant", ctx: otherTenant},
+		{name: "no tenant", ctx: context.Background(), wantAnyErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := repo.Create(tt.ctx, &User{Email: user.Email, PasswordHash: "hash", FirstName: "Ada", LastName: "Lovelace"})
+			switch {
+			case tt.wantAnyErr:
+				if err == nil {
+					t.Error("Create succeeded, want an error")
+				}
+			case !errors.Is(err, tt.wantErr):
+				t.Errorf("Create error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestUserRepositoryTenantIsolation(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := db.WithTenant(context.Background(), db.DefaultTenantID)
+	user := newTestUser(t, ctx, repo)
+
+	tenant, err := NewTenantRepository(testDB.Pool).Create(context.Background(), fmt.Sprintf("t-%d", time.Now().UnixNano()), "Other")
+	if err != nil {
+		t.Fatalf("Create tenant: %v", err)
+	}
+	otherTenant := db.WithTenant(context.Background(), tenant.ID)
+
+	tests := []struct {
+		name   string
+		lookup func(ctx context.Context) error
+	}{
+		{name: "GetByID", lookup: func(ctx context.Context) error { _, err := repo.GetByID(ctx, user.ID); return err }},
+		{name: "GetByEmail", lookup: func(ctx context.Context) error { _, err := repo.GetByEmail(ctx, user.Email); return err }},
+		{name: "UpdatePassword", lookup: func(ctx context.Context) error { return repo.UpdatePassword(ctx, user.ID, "hash") }},
+		{name: "UpdateLastLogin", lookup: func(ctx context.Context) error { return repo.UpdateLastLogin(ctx, user.ID) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.lookup(ctx); err != nil {
+				t.Errorf("in the user's tenant: %v", err)
+			}
+			if err := tt.lookup(otherTenant); err == nil {
+				t.Error("found the user from another tenant")
+			}
+		})
+	}
+}
+
+func TestUserRepositoryUpdate(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := db.WithTenant(context.Background(), db.DefaultTenantID)
+	user := newTestUser(t, ctx, repo)
+
+	phone := "+15555550100"
+	user.FirstName = "Augusta"
+	user.PhoneNumber = &phone
+	user.Metadata = map[string]string{"theme": "dark"}
+	if err := repo.Update(ctx, user); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if user.Version != 2 {
+		t.Errorf("version after Update = %d, want 2", user.Version)
+	}
+
+	got, err := repo.GetByID(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	if got.FirstName != "Augusta" || got.PhoneNumber == nil || *got.PhoneNumber != phone || got.Metadata["theme"] != "dark" || got.Version != 2 {
+		t.Errorf("user read back = %+v, want the update, decrypted, at version 2", got)
+	}
+
+	// Personal data is stored encrypted
+	var stored []byte
+	if err := testDB.QueryRow(ctx, `SELECT phone_number_encrypted FROM users WHERE id = $1`, user.ID).Scan(&stored); err != nil {
+		t.Fatalf("select phone number: %v", err)
+	}
+	if bytes.Contains(stored, []byte(phone)) {
+		t.Error("phone number stored in plaintext")
+	}
+
+	// A copy read before the update is outdated
+	stale := *got
+	stale.Version = 1
+	if err := repo.Update(ctx, &stale); !errors.Is(err, ErrVersionConflict) {
+		t.Errorf("Update of a stale copy error = %v, want %v", err, ErrVersionConflict)
+	}
+
+	gone := *got
+	gone.ID = "00000000-0000-0000-0000-00000000dead"
+	if err := repo.Update(ctx, &gone); err == nil || errors.Is(err, ErrVersionConflict) {
+		t.Errorf("Update of a missing user error = %v, want not found", err)
+	}
+}
+
+func TestUserRepositoryPassword(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := db.WithTenant(context.Background(), db.DefaultTenantID)
+	user := newTestUser(t, ctx, repo)
+	if err := repo.SetMustResetPassword(ctx, user.ID, true); err != nil {
+		t.Fatalf("SetMustResetPassword: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		change   func() error
+		wantHash string
+	}{
+		{name: "update", change: func() error { return repo.UpdatePassword(ctx, user.ID, "new-hash") }, wantHash: "new-hash"},
+		{name: "rehash", change: func() error { return repo.RehashPassword(ctx, user.ID, "new-hash", "rehashed") }, wantHash: "rehashed"},
+		// The password changed since the hash being replaced was read
+		{name: "rehash of an old hash", change: func() error { return repo.RehashPassword(ctx, user.ID, "new-hash", "stale") }, wantHash: "rehashed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.change(); err != nil {
+				t.Fatalf("change: %v", err)
+			}
+			got, err := repo.GetByID(ctx, user.ID)
+			if err != nil {
+				t.Fatalf("GetByID: %v", err)
+			}
+			if got.PasswordHash != tt.wantHash || got.MustResetPassword {
+				t.Errorf("hash %q, must reset %v; want %q, cleared", got.PasswordHash, got.MustResetPassword, tt.wantHash)
+			}
+		})
+	}
+}
+
+func TestUserRepositoryWithinTx(t *testing.T) {
+	repo := newTestRepository(t)
+	txManager := db.NewTxManager(testDB.Pool)
+	ctx := db.WithTenant(context.Background(), db.DefaultTenantID)
+	errRollback := errors.New("roll back")
+
+	tests := []struct {
+		name       string
+		fnErr      error
+		wantExists bool
+	}{
+		{name: "commit", wantExists: true},
+		{name: "rollback", fnErr: errRollback},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var user *User
+			err := txManager.WithinTx(ctx, func(ctx context.Context) error {
+				user = newTestUser(t, ctx, repo)
+				// The transaction sees its own write
+				if _, err := repo.GetByID(ctx, user.ID); err != nil {
+					t.Errorf("GetByID in the transaction: %v", err)
+				}
+				return tt.fnErr
+			})
+			if !errors.Is(err, tt.fnErr) {
+				t.Fatalf("WithinTx error = %v, want %v", err, tt.fnErr)
+			}
+
+			exists, err := repo.EmailExists(ctx, user.Email)
+			if err != nil {
+				t.Fatalf("EmailExists: %v", err)
+			}
+			if exists != tt.wantExists {
+				t.Errorf("user exists after the transaction = %v, want %v", exists, tt.wantExists)
+			}
+		})
+	}
+}
@@ -0,0 +1,177 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/db"
+)
+
+// Tenant is an isolated customer of the service. Users, and everything
+// scoped to them, belong to exactly one tenant.
+type Tenant struct {
+	ID string
+	// Slug is the lowercase identifier clients send to select the tenant
+	Slug      string
+	Name      string
+	IsActive  bool
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// ErrTenantNotFound is returned when no tenant matches
+var ErrTenantNotFound = errors.New("tenant not found")
+
+// ErrSlugTaken is returned when a tenant slug is already in use
+var ErrSlugTaken = errors.New("tenant slug already in use")
+
+// tenantColumns is the column list shared by all tenant SELECT queries, in
+// scanTenant order
+const tenantColumns = `id, slug, name, is_active, created_at, updated_at`
+
+// scanTenant scans a row selected with tenantColumns into a Tenant
+func scanTenant(row rowScanner) (*Tenant, error) {
+	tenant := &Tenant{}
+	err := row.Scan(
+		&tenant.ID,
+		&tenant.Slug,
+		&tenant.Name,
+		&tenant.IsActive,
+		&tenant.CreatedAt,
+		&tenant.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return tenant, nil
+}
+
+// TenantRepository handles tenant database operations. Tenants are not
+// themselves tenant-scoped.
+type TenantRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewTenantRepository creates a new tenant repository
+func NewTenantRepository(db *pgxpool.Pool) *TenantRepository {
+	return &TenantRepository{db: db}
+}
+
+// Create creates a new, active tenant
+func (r *TenantRepository) Create(ctx context.Context, slug, name string) (*Tenant, error) {
+	query := `
+		INSERT INTO tenants (slug, name)
+		VALUES ($1, $2)
+		RETURNING ` + tenantColumns
+
+	tenant, err := scanTenant(db.ExecutorFrom(ctx, r.db).QueryRow(ctx, query, slug, name))
+	if db.IsUniqueViolation(err) {
+		return nil, ErrSlugTaken
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create tenant: %w", err)
+	}
+
+	return tenant, nil
+}
+
+// GetByID retrieves a tenant by ID
+func (r *TenantRepository) GetByID(ctx context.Context, id string) (*Tenant, error) {
+	query := `SELECT ` + tenantColumns + ` FROM tenants WHERE id = $1`
+	return r.get(ctx, query, id)
+}
+
+// GetBySlug retrieves a tenant by slug
+func (r *TenantRepository) GetBySlug(ctx context.Context, slug string) (*Tenant, error) {
+	query := `SELECT ` + tenantColumns + ` FROM tenants WHERE slug = $1`
+	return r.get(ctx, query, slug)
+}
+
+func (r *TenantRepository) get(ctx context.Context, query string, arg string) (*Tenant, error) {
+	tenant, err := scanTenant(db.ExecutorFrom(ctx, r.db).QueryRow(ctx, query, arg))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrTenantNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tenant: %w", err)
+	}
+
+	return tenant, nil
+}
+
+// SetActive activates or deactivates a tenant and returns it
+func (r *TenantRepository) SetActive(ctx context.Context, id string, active bool) (*Tenant, error) {
+	query := `
+		UPDATE tenants
+		SET is_active = $1
+		WHERE id = $2
+		RETURNING ` + tenantColumns
+
+	tenant, err := scanTenant(db.ExecutorFrom(ctx, r.db).QueryRow(ctx, query, active, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrTenantNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update tenant: %w", err)
+	}
+
+	return tenant, nil
+}
+
+// tenantsByCreatedAt pages tenants newest first
+var tenantsByCreatedAt = db.Keyset{Columns: []string{"created_at", "id"}, Descending: true}
+
+// List retrieves a page of tenants, newest first. pageToken is "" for the
+// first page, then the token returned with the previous page. The returned
+// token is "" on the last page.
+func (r *TenantRepository) List(ctx context.Context, pageSize int, pageToken string) ([]*Tenant, string, error) {
+	size := db.PageSize(pageSize)
+
+	where := "TRUE"
+	args := []interface{}{size + 1}
+	if pageToken != "" {
+		var createdAt time.Time
+		var id string
+		if err := db.DecodePageToken(pageToken, &createdAt, &id); err != nil {
+			return nil, "", err
+		}
+		where = tenantsByCreatedAt.After(2)
+		args = append(args, createdAt, id)
+	}
+
+	query := `
+		SELECT ` + tenantColumns + `
+		FROM tenants
+		WHERE ` + where + `
+		ORDER BY ` + tenantsByCreatedAt.OrderBy() + `
+		LIMIT $1
+	`
+
+	rows, err := db.ExecutorFrom(ctx, r.db).Query(ctx, query, args...)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to list tenants: %w", err)
+	}
+	defer rows.Close()
+
+	var tenants []*Tenant
+	for rows.Next() {
+		tenant, err := scanTenant(rows)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to scan tenant: %w", err)
+		}
+		tenants = append(tenants, tenant)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, "", fmt.Errorf("error iterating tenants: %w", err)
+	}
+
+	return db.NextPage(tenants, size, func(t *Tenant) []interface{} {
+		return []interface{}{t.CreatedAt, t.ID}
+	})
+}
@@ -2,62 +2,235 @@ package models
 
 import (
 	"context"
-	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/db"
+	"github.com/sahays/grpc-proto-go-flutter-template/pkg/crypto"
 )
 
 // User represents a user in the system
 type User struct {
-	ID            string
-	Email         string
-	PasswordHash  string
-	FirstName     string
-	LastName      string
-	CreatedAt     time.Time
-	UpdatedAt     time.Time
-	LastLoginAt   *time.Time
-	IsActive      bool
-	IsVerified    bool
-}
-
-// UserRepository handles user database operations
+	ID                string
+	Email             string
+	PasswordHash      string
+	FirstName         string
+	LastName          string
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+	LastLoginAt       *time.Time
+	IsActive          bool
+	IsVerified        bool
+	MustResetPassword bool
+	// RecoveryEmail is a secondary address used only for account recovery
+	RecoveryEmail         *string
+	RecoveryEmailVerified bool
+	// Role is the subject the authorization policy matches against
+	Role string
+	// TenantID is the tenant the user belongs to; emails are unique within it
+	TenantID string
+	// Version increments with every change except UpdateLastLogin. Update
+	// applies only if the row is still at this version. The database sets
+	// CreatedAt, and UpdatedAt whenever Version changes.
+	Version int64
+	// PhoneNumber and Metadata are personal data. They are stored encrypted
+	// (see pkg/crypto) and decrypted on read; Update writes them.
+	PhoneNumber *string
+	Metadata    map[string]string
+}
+
+// userColumns is the column list shared by all user SELECT queries, in scanUser order
+const userColumns = `id, email, password_hash, first_name, last_name,
+		       created_at, updated_at, last_login_at, is_active, is_verified,
+		       must_reset_password, recovery_email, recovery_email_verified, role,
+		       tenant_id, version, phone_number_encrypted, metadata_encrypted`
+
+// rowScanner is implemented by pgx.Row and pgx.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanUser scans a row selected with userColumns into a User, decrypting
+// its personal data
+func (r *UserRepository) scanUser(row rowScanner) (*User, error) {
+	user := &User{}
+	var phoneNumber, metadata []byte
+	err := row.Scan(
+		&user.ID,
+		&user.Email,
+		&user.PasswordHash,
+		&user.FirstName,
+		&user.LastName,
+		&user.CreatedAt,
+		&user.UpdatedAt,
+		&user.LastLoginAt,
+		&user.IsActive,
+		&user.IsVerified,
+		&user.MustResetPassword,
+		&user.RecoveryEmail,
+		&user.RecoveryEmailVerified,
+		&user.Role,
+		&user.TenantID,
+		&user.Version,
+		&phoneNumber,
+		&metadata,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if phoneNumber != nil {
+		plaintext, err := r.keyring.Decrypt(phoneNumber, piiContext("phone_number", user.ID))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt phone number: %w", err)
+		}
+		number := string(plaintext)
+		user.PhoneNumber = &number
+	}
+	if metadata != nil {
+		plaintext, err := r.keyring.Decrypt(metadata, piiContext("metadata", user.ID))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt metadata: %w", err)
+		}
+		if err := json.Unmarshal(plaintext, &user.Metadata); err != nil {
+			return nil, fmt.Errorf("invalid metadata: %w", err)
+		}
+	}
+	return user, nil
+}
+
+// encryptPII returns the stored form of the user's personal data, nil for
+// none
+func (r *UserRepository) encryptPII(user *User) (phoneNumber, metadata []byte, err error) {
+	if user.PhoneNumber != nil {
+		phoneNumber, err = r.keyring.Encrypt([]byte(*user.PhoneNumber), piiContext("phone_number", user.ID))
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to encrypt phone number: %w", err)
+		}
+	}
+	if len(user.Metadata) > 0 {
+		plaintext, err := json.Marshal(user.Metadata)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to encode metadata: %w", err)
+		}
+		metadata, err = r.keyring.Encrypt(plaintext, piiContext("metadata", user.ID))
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to encrypt metadata: %w", err)
+		}
+	}
+	return phoneNumber, metadata, nil
+}
+
+// piiContext binds an encrypted value to its column and user, so it can't
+// be decrypted after being copied elsewhere
+func piiContext(column, userID string) []byte {
+	return []byte("users." + column + ":" + userID)
+}
+
+// UserRepository handles user database operations. Every query is scoped to
+// the tenant in its context (see db.WithTenant) and fails without one.
 type UserRepository struct {
-	db *sql.DB
+	db      *db.Router
+	cache   *UserCache
+	newID   IDGenerator
+	keyring *crypto.Keyring
+}
+
+// ErrEmailTaken is returned when a write would duplicate another user's email
+var ErrEmailTaken = errors.New("email already registered")
+
+// ErrVersionConflict is returned by Update when the user changed since it
+// was read
+var ErrVersionConflict = errors.New("user was changed concurrently")
+
+// NewUserRepository creates a new user repository. GetByID and GetByEmail
+// are served from cache when it is non-nil. Create assigns IDs from newID,
+// or random UUIDs when it is nil. keyring encrypts personal data.
+func NewUserRepository(router *db.Router, cache *UserCache, newID IDGenerator, keyring *crypto.Keyring) *UserRepository {
+	if newID == nil {
+		newID = NewIDGenerator(false)
+	}
+	return &UserRepository{db: router, cache: cache, newID: newID, keyring: keyring}
+}
+
+// exec returns the transaction started by db.TxManager.WithinTx in ctx, if
+// any, so every method joins it; otherwise the primary
+func (r *UserRepository) exec(ctx context.Context) db.Executor {
+	return r.db.Primary(ctx)
+}
+
+// read is exec for lookups that tolerate replication lag. Outside a
+// transaction they may be served by a read replica.
+func (r *UserRepository) read(ctx context.Context) db.Executor {
+	return r.db.Replica(ctx)
+}
+
+// cacheFor returns the user cache for lookups in ctx. Transactions bypass
+// it, so they see their own writes.
+func (r *UserRepository) cacheFor(ctx context.Context) *UserCache {
+	if db.InTx(ctx) {
+		return nil
+	}
+	return r.cache
 }
 
-// NewUserRepository creates a new user repository
-func NewUserRepository(db *sql.DB) *UserRepository {
-	return &UserRepository{db: db}
+// lookup is read for lookups that fill cache. With a cache they go to the
+// primary, since a replica may still have a copy older than the change
+// notification that invalidated the cached one.
+func (r *UserRepository) lookup(ctx context.Context, cache *UserCache) db.Executor {
+	if cache != nil {
+		return r.exec(ctx)
+	}
+	return r.read(ctx)
 }
 
-// Create creates a new user
+// createUserSQL, like the other login and sign-up path queries, is a hot
+// statement (see db.Prepare)
+var createUserSQL = db.Prepare(`
+		INSERT INTO users (id, tenant_id, email, password_hash, first_name, last_name, is_active, is_verified, must_reset_password)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING created_at, updated_at, version
+	`)
+
+// Create creates a new user in the context's tenant, assigning an ID if it
+// has none
 func (r *UserRepository) Create(ctx context.Context, user *User) error {
-	query := `
-		INSERT INTO users (id, email, password_hash, first_name, last_name, is_active, is_verified)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
-		RETURNING created_at, updated_at
-	`
+	tenantID, err := db.RequireTenant(ctx)
+	if err != nil {
+		return err
+	}
+	user.TenantID = tenantID
 
-	// Generate UUID if not provided
 	if user.ID == "" {
-		user.ID = uuid.New().String()
+		id, err := r.newID()
+		if err != nil {
+			return fmt.Errorf("failed to generate user ID: %w", err)
+		}
+		user.ID = id.String()
 	}
 
-	err := r.db.QueryRowContext(
+	err = r.exec(ctx).QueryRow(
 		ctx,
-		query,
+		createUserSQL,
 		user.ID,
+		user.TenantID,
 		user.Email,
 		user.PasswordHash,
 		user.FirstName,
 		user.LastName,
 		user.IsActive,
 		user.IsVerified,
-	).Scan(&user.CreatedAt, &user.UpdatedAt)
+		user.MustResetPassword,
+	).Scan(&user.CreatedAt, &user.UpdatedAt, &user.Version)
 
+	if db.IsUniqueViolation(err) {
+		return ErrEmailTaken
+	}
 	if err != nil {
 		return fmt.Errorf("failed to create user: %w", err)
 	}
@@ -68,61 +241,90 @@ func (r *UserRepository) Create(ctx context.Context, user *User) error {
 // GetByID retrieves a user by ID
 func (r *UserRepository) GetByID(ctx context.Context, id string) (*User, error) {
 	query := `
-		SELECT id, email, password_hash, first_name, last_name,
-		       created_at, updated_at, last_login_at, is_active, is_verified
+		SELECT ` + userColumns + `
 		FROM users
-		WHERE id = $1
+		WHERE id = $1 AND tenant_id = $2
 	`
 
-	user := &User{}
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
-		&user.ID,
-		&user.Email,
-		&user.PasswordHash,
-		&user.FirstName,
-		&user.LastName,
-		&user.CreatedAt,
-		&user.UpdatedAt,
-		&user.LastLoginAt,
-		&user.IsActive,
-		&user.IsVerified,
-	)
+	tenantID, err := db.RequireTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	cache := r.cacheFor(ctx)
+	if user, ok := cache.Get(ctx, tenantID, id); ok {
+		return user, nil
+	}
+	version := cache.Version()
+
+	user, err := r.scanUser(r.lookup(ctx, cache).QueryRow(ctx, query, id, tenantID))
 
-	if err == sql.ErrNoRows {
+	if errors.Is(err, pgx.ErrNoRows) {
 		return nil, fmt.Errorf("user not found: %s", id)
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user: %w", err)
 	}
 
+	cache.Put(ctx, user, version)
 	return user, nil
 }
 
+var getUserByEmailSQL = db.Prepare(`
+		SELECT ` + userColumns + `
+		FROM users
+		WHERE email = $1 AND tenant_id = $2
+	`)
+
 // GetByEmail retrieves a user by email
 func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*User, error) {
+	tenantID, err := db.RequireTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	cache := r.cacheFor(ctx)
+	if user, ok := cache.GetByEmail(ctx, tenantID, email); ok {
+		return user, nil
+	}
+	version := cache.Version()
+
+	user, err := r.scanUser(r.lookup(ctx, cache).QueryRow(ctx, getUserByEmailSQL, email, tenantID))
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("user not found with email: %s", email)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	cache.Put(ctx, user, version)
+	return user, nil
+}
+
+// GetForUpdate retrieves a user by ID and locks the row until the
+// transaction in ctx ends. It must be called within db.TxManager.WithinTx.
+func (r *UserRepository) GetForUpdate(ctx context.Context, id string) (*User, error) {
+	if !db.InTx(ctx) {
+		return nil, errors.New("GetForUpdate requires a transaction")
+	}
+
 	query := `
-		SELECT id, email, password_hash, first_name, last_name,
-		       created_at, updated_at, last_login_at, is_active, is_verified
+		SELECT ` + userColumns + `
 		FROM users
-		WHERE email = $1
+		WHERE id = $1 AND tenant_id = $2
+		FOR UPDATE
 	`
 
-	user := &User{}
-	err := r.db.QueryRowContext(ctx, query, email).Scan(
-		&user.ID,
-		&user.Email,
-		&user.PasswordHash,
-		&user.FirstName,
-		&user.LastName,
-		&user.CreatedAt,
-		&user.UpdatedAt,
-		&user.LastLoginAt,
-		&user.IsActive,
-		&user.IsVerified,
-	)
+	tenantID, err := db.RequireTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
 
-	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("user not found with email: %s", email)
+	user, err := r.scanUser(r.exec(ctx).QueryRow(ctx, query, id, tenantID))
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("user not found: %s", id)
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user: %w", err)
@@ -131,17 +333,33 @@ func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*User, e
 	return user, nil
 }
 
-// Update updates a user
+// Update writes the user's profile and account fields, including personal
+// data, provided the row is still at user.Version, and advances
+// user.Version. It returns ErrVersionConflict if another write got there
+// first; the caller should reload the user and retry. The last login time is
+// not written (see UpdateLastLogin).
 func (r *UserRepository) Update(ctx context.Context, user *User) error {
 	query := `
 		UPDATE users
 		SET email = $1, password_hash = $2, first_name = $3, last_name = $4,
-		    is_active = $5, is_verified = $6, last_login_at = $7
-		WHERE id = $8
-		RETURNING updated_at
+		    is_active = $5, is_verified = $6, must_reset_password = $7,
+		    phone_number_encrypted = $11, metadata_encrypted = $12,
+		    version = version + 1
+		WHERE id = $8 AND tenant_id = $9 AND version = $10
+		RETURNING updated_at, version
 	`
 
-	err := r.db.QueryRowContext(
+	tenantID, err := db.RequireTenant(ctx)
+	if err != nil {
+		return err
+	}
+
+	phoneNumber, metadata, err := r.encryptPII(user)
+	if err != nil {
+		return err
+	}
+
+	err = r.exec(ctx).QueryRow(
 		ctx,
 		query,
 		user.Email,
@@ -150,67 +368,226 @@ func (r *UserRepository) Update(ctx context.Context, user *User) error {
 		user.LastName,
 		user.IsActive,
 		user.IsVerified,
-		user.LastLoginAt,
+		user.MustResetPassword,
 		user.ID,
-	).Scan(&user.UpdatedAt)
-
-	if err == sql.ErrNoRows {
-		return fmt.Errorf("user not found: %s", user.ID)
+		tenantID,
+		user.Version,
+		phoneNumber,
+		metadata,
+	).Scan(&user.UpdatedAt, &user.Version)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return r.updateMissed(ctx, user.ID, tenantID)
+	}
+	if db.IsUniqueViolation(err) {
+		return ErrEmailTaken
 	}
 	if err != nil {
 		return fmt.Errorf("failed to update user: %w", err)
 	}
 
+	r.cache.Invalidate(ctx, user.ID)
 	return nil
 }
 
+// updateMissed explains an Update that matched no row: the user is gone, or
+// is at another version
+func (r *UserRepository) updateMissed(ctx context.Context, userID, tenantID string) error {
+	query := `SELECT EXISTS (SELECT 1 FROM users WHERE id = $1 AND tenant_id = $2)`
+
+	var exists bool
+	if err := r.exec(ctx).QueryRow(ctx, query, userID, tenantID).Scan(&exists); err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+	if !exists {
+		return fmt.Errorf("user not found: %s", userID)
+	}
+	return ErrVersionConflict
+}
+
+var updateLastLoginSQL = db.Prepare(`
+		UPDATE users
+		SET last_login_at = NOW()
+		WHERE id = $1 AND tenant_id = $2
+	`)
+
 // UpdateLastLogin updates the last login timestamp
 func (r *UserRepository) UpdateLastLogin(ctx context.Context, userID string) error {
+	tenantID, err := db.RequireTenant(ctx)
+	if err != nil {
+		return err
+	}
+
+	result, err := r.exec(ctx).Exec(ctx, updateLastLoginSQL, userID, tenantID)
+	if err != nil {
+		return fmt.Errorf("failed to update last login: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("user not found: %s", userID)
+	}
+
+	r.cache.Invalidate(ctx, userID)
+	return nil
+}
+
+// UpdatePassword updates the user's password and clears any pending forced reset
+func (r *UserRepository) UpdatePassword(ctx context.Context, userID, passwordHash string) error {
 	query := `
 		UPDATE users
-		SET last_login_at = NOW()
-		WHERE id = $1
+		SET password_hash = $1, must_reset_password = false, version = version + 1
+		WHERE id = $2 AND tenant_id = $3
 	`
 
-	result, err := r.db.ExecContext(ctx, query, userID)
+	tenantID, err := db.RequireTenant(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to update last login: %w", err)
+		return err
 	}
 
-	rows, err := result.RowsAffected()
+	result, err := r.exec(ctx).Exec(ctx, query, passwordHash, userID, tenantID)
 	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
+		return fmt.Errorf("failed to update password: %w", err)
 	}
 
-	if rows == 0 {
+	if result.RowsAffected() == 0 {
 		return fmt.Errorf("user not found: %s", userID)
 	}
 
+	r.cache.Invalidate(ctx, userID)
 	return nil
 }
 
-// UpdatePassword updates the user's password
-func (r *UserRepository) UpdatePassword(ctx context.Context, userID, passwordHash string) error {
+// RehashPassword replaces the user's password hash oldHash with newHash, a
+// hash of the same password with a stronger algorithm or parameters. It
+// does nothing if the hash is no longer oldHash, as when the password was
+// changed meanwhile.
+func (r *UserRepository) RehashPassword(ctx context.Context, userID, oldHash, newHash string) error {
 	query := `
 		UPDATE users
-		SET password_hash = $1
-		WHERE id = $2
+		SET password_hash = $1, version = version + 1
+		WHERE id = $2 AND tenant_id = $3 AND password_hash = $4
 	`
 
-	result, err := r.db.ExecContext(ctx, query, passwordHash, userID)
+	tenantID, err := db.RequireTenant(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to update password: %w", err)
+		return err
+	}
+
+	result, err := r.exec(ctx).Exec(ctx, query, newHash, userID, tenantID, oldHash)
+	if err != nil {
+		return fmt.Errorf("failed to rehash password: %w", err)
+	}
+
+	if result.RowsAffected() > 0 {
+		r.cache.Invalidate(ctx, userID)
+	}
+	return nil
+}
+
+// MarkVerified marks the user's primary email as verified
+func (r *UserRepository) MarkVerified(ctx context.Context, userID string) error {
+	query := `
+		UPDATE users
+		SET is_verified = true, version = version + 1
+		WHERE id = $1 AND tenant_id = $2
+	`
+
+	tenantID, err := db.RequireTenant(ctx)
+	if err != nil {
+		return err
+	}
+
+	result, err := r.exec(ctx).Exec(ctx, query, userID, tenantID)
+	if err != nil {
+		return fmt.Errorf("failed to mark user verified: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("user not found: %s", userID)
+	}
+
+	r.cache.Invalidate(ctx, userID)
+	return nil
+}
+
+// SetMustResetPassword flags (or unflags) a user as required to change their
+// password before receiving full-access tokens. Intended for admin tooling,
+// e.g. forcing resets across affected accounts after a breach.
+func (r *UserRepository) SetMustResetPassword(ctx context.Context, userID string, mustReset bool) error {
+	query := `
+		UPDATE users
+		SET must_reset_password = $1, version = version + 1
+		WHERE id = $2 AND tenant_id = $3
+	`
+
+	tenantID, err := db.RequireTenant(ctx)
+	if err != nil {
+		return err
+	}
+
+	result, err := r.exec(ctx).Exec(ctx, query, mustReset, userID, tenantID)
+	if err != nil {
+		return fmt.Errorf("failed to set must_reset_password: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("user not found: %s", userID)
+	}
+
+	r.cache.Invalidate(ctx, userID)
+	return nil
+}
+
+// SetRecoveryEmail stores a new, unverified recovery email for the user
+func (r *UserRepository) SetRecoveryEmail(ctx context.Context, userID, recoveryEmail string) error {
+	query := `
+		UPDATE users
+		SET recovery_email = $1, recovery_email_verified = false, version = version + 1
+		WHERE id = $2 AND tenant_id = $3
+	`
+
+	tenantID, err := db.RequireTenant(ctx)
+	if err != nil {
+		return err
 	}
 
-	rows, err := result.RowsAffected()
+	result, err := r.exec(ctx).Exec(ctx, query, recoveryEmail, userID, tenantID)
 	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
+		return fmt.Errorf("failed to set recovery email: %w", err)
 	}
 
-	if rows == 0 {
+	if result.RowsAffected() == 0 {
 		return fmt.Errorf("user not found: %s", userID)
 	}
 
+	r.cache.Invalidate(ctx, userID)
+	return nil
+}
+
+// VerifyRecoveryEmail marks the user's recovery email as verified, provided it
+// still matches the address the verification token was issued for
+func (r *UserRepository) VerifyRecoveryEmail(ctx context.Context, userID, recoveryEmail string) error {
+	query := `
+		UPDATE users
+		SET recovery_email_verified = true, version = version + 1
+		WHERE id = $1 AND recovery_email = $2 AND tenant_id = $3
+	`
+
+	tenantID, err := db.RequireTenant(ctx)
+	if err != nil {
+		return err
+	}
+
+	result, err := r.exec(ctx).Exec(ctx, query, userID, recoveryEmail, tenantID)
+	if err != nil {
+		return fmt.Errorf("failed to verify recovery email: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("recovery email no longer matches for user: %s", userID)
+	}
+
+	r.cache.Invalidate(ctx, userID)
 	return nil
 }
 
@@ -218,99 +595,172 @@ func (r *UserRepository) UpdatePassword(ctx context.Context, userID, passwordHas
 func (r *UserRepository) Delete(ctx context.Context, userID string) error {
 	query := `
 		UPDATE users
-		SET is_active = false
-		WHERE id = $1
+		SET is_active = false, version = version + 1
+		WHERE id = $1 AND tenant_id = $2
 	`
 
-	result, err := r.db.ExecContext(ctx, query, userID)
+	tenantID, err := db.RequireTenant(ctx)
+	if err != nil {
+		return err
+	}
+
+	result, err := r.exec(ctx).Exec(ctx, query, userID, tenantID)
 	if err != nil {
 		return fmt.Errorf("failed to delete user: %w", err)
 	}
 
-	rows, err := result.RowsAffected()
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("user not found: %s", userID)
+	}
+
+	r.cache.Invalidate(ctx, userID)
+	return nil
+}
+
+// SetActive activates or deactivates a user
+func (r *UserRepository) SetActive(ctx context.Context, userID string, active bool) error {
+	query := `
+		UPDATE users
+		SET is_active = $1, version = version + 1
+		WHERE id = $2 AND tenant_id = $3
+	`
+
+	tenantID, err := db.RequireTenant(ctx)
+	if err != nil {
+		return err
+	}
+
+	result, err := r.exec(ctx).Exec(ctx, query, active, userID, tenantID)
+	if err != nil {
+		return fmt.Errorf("failed to set user active: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("user not found: %s", userID)
+	}
+
+	r.cache.Invalidate(ctx, userID)
+	return nil
+}
+
+// SetRole changes the user's authorization role. Tokens issued before keep
+// the old role until they expire.
+func (r *UserRepository) SetRole(ctx context.Context, userID, role string) error {
+	query := `
+		UPDATE users
+		SET role = $1, version = version + 1
+		WHERE id = $2 AND tenant_id = $3
+	`
+
+	tenantID, err := db.RequireTenant(ctx)
+	if err != nil {
+		return err
+	}
+
+	result, err := r.exec(ctx).Exec(ctx, query, role, userID, tenantID)
 	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
+		return fmt.Errorf("failed to set user role: %w", err)
 	}
 
-	if rows == 0 {
+	if result.RowsAffected() == 0 {
 		return fmt.Errorf("user not found: %s", userID)
 	}
 
+	r.cache.Invalidate(ctx, userID)
 	return nil
 }
 
 // HardDelete permanently deletes a user
 func (r *UserRepository) HardDelete(ctx context.Context, userID string) error {
-	query := `DELETE FROM users WHERE id = $1`
+	query := `DELETE FROM users WHERE id = $1 AND tenant_id = $2`
 
-	result, err := r.db.ExecContext(ctx, query, userID)
+	tenantID, err := db.RequireTenant(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to hard delete user: %w", err)
+		return err
 	}
 
-	rows, err := result.RowsAffected()
+	result, err := r.exec(ctx).Exec(ctx, query, userID, tenantID)
 	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
+		return fmt.Errorf("failed to hard delete user: %w", err)
 	}
 
-	if rows == 0 {
+	if result.RowsAffected() == 0 {
 		return fmt.Errorf("user not found: %s", userID)
 	}
 
+	r.cache.Invalidate(ctx, userID)
 	return nil
 }
 
-// List retrieves a list of users with pagination
-func (r *UserRepository) List(ctx context.Context, limit, offset int) ([]*User, error) {
+// usersByCreatedAt pages a tenant's active users newest first
+var usersByCreatedAt = db.Keyset{Columns: []string{"created_at", "id"}, Descending: true}
+
+// List retrieves a page of active users, newest first. pageToken is "" for
+// the first page, then the token returned with the previous page. The
+// returned token is "" on the last page.
+func (r *UserRepository) List(ctx context.Context, pageSize int, pageToken string) ([]*User, string, error) {
+	tenantID, err := db.RequireTenant(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+
+	size := db.PageSize(pageSize)
+
+	where := "tenant_id = $2 AND is_active = true"
+	args := []interface{}{size + 1, tenantID}
+	if pageToken != "" {
+		var createdAt time.Time
+		var id string
+		if err := db.DecodePageToken(pageToken, &createdAt, &id); err != nil {
+			return nil, "", err
+		}
+		where += " AND " + usersByCreatedAt.After(3)
+		args = append(args, createdAt, id)
+	}
+
 	query := `
-		SELECT id, email, password_hash, first_name, last_name,
-		       created_at, updated_at, last_login_at, is_active, is_verified
+		SELECT ` + userColumns + `
 		FROM users
-		WHERE is_active = true
-		ORDER BY created_at DESC
-		LIMIT $1 OFFSET $2
+		WHERE ` + where + `
+		ORDER BY ` + usersByCreatedAt.OrderBy() + `
+		LIMIT $1
 	`
 
-	rows, err := r.db.QueryContext(ctx, query, limit, offset)
+	rows, err := r.read(ctx).Query(ctx, query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list users: %w", err)
+		return nil, "", fmt.Errorf("failed to list users: %w", err)
 	}
 	defer rows.Close()
 
 	var users []*User
 	for rows.Next() {
-		user := &User{}
-		err := rows.Scan(
-			&user.ID,
-			&user.Email,
-			&user.PasswordHash,
-			&user.FirstName,
-			&user.LastName,
-			&user.CreatedAt,
-			&user.UpdatedAt,
-			&user.LastLoginAt,
-			&user.IsActive,
-			&user.IsVerified,
-		)
+		user, err := r.scanUser(rows)
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan user: %w", err)
+			return nil, "", fmt.Errorf("failed to scan user: %w", err)
 		}
 		users = append(users, user)
 	}
 
 	if err = rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating users: %w", err)
+		return nil, "", fmt.Errorf("error iterating users: %w", err)
 	}
 
-	return users, nil
+	return db.NextPage(users, size, func(u *User) []interface{} {
+		return []interface{}{u.CreatedAt, u.ID}
+	})
 }
 
 // Count returns the total number of active users
 func (r *UserRepository) Count(ctx context.Context) (int64, error) {
-	query := `SELECT COUNT(*) FROM users WHERE is_active = true`
+	query := `SELECT COUNT(*) FROM users WHERE tenant_id = $1 AND is_active = true`
+
+	tenantID, err := db.RequireTenant(ctx)
+	if err != nil {
+		return 0, err
+	}
 
 	var count int64
-	err := r.db.QueryRowContext(ctx, query).Scan(&count)
+	err = r.exec(ctx).QueryRow(ctx, query, tenantID).Scan(&count)
 	if err != nil {
 		return 0, fmt.Errorf("failed to count users: %w", err)
 	}
@@ -318,15 +768,105 @@ func (r *UserRepository) Count(ctx context.Context) (int64, error) {
 	return count, nil
 }
 
+var emailExistsSQL = db.Prepare(`SELECT EXISTS(SELECT 1 FROM users WHERE email = $1 AND tenant_id = $2)`)
+
 // EmailExists checks if an email already exists
 func (r *UserRepository) EmailExists(ctx context.Context, email string) (bool, error) {
-	query := `SELECT EXISTS(SELECT 1 FROM users WHERE email = $1)`
+	tenantID, err := db.RequireTenant(ctx)
+	if err != nil {
+		return false, err
+	}
 
 	var exists bool
-	err := r.db.QueryRowContext(ctx, query, email).Scan(&exists)
+	err = r.exec(ctx).QueryRow(ctx, emailExistsSQL, email, tenantID).Scan(&exists)
 	if err != nil {
 		return false, fmt.Errorf("failed to check email existence: %w", err)
 	}
 
 	return exists, nil
 }
+
+// RewrapPII moves the context's tenant's encrypted personal data to the
+// active key (see crypto.Keyring.Rewrap), pageSize users at a time, and
+// returns how many users it changed. A value changed concurrently is left
+// for the next run, which it no longer needs if the change re-encrypted it.
+func (r *UserRepository) RewrapPII(ctx context.Context, pageSize int) (int, error) {
+	selectQuery := `
+		SELECT id, phone_number_encrypted, metadata_encrypted
+		FROM users
+		WHERE tenant_id = $1 AND id > $2::uuid
+		  AND (phone_number_encrypted IS NOT NULL OR metadata_encrypted IS NOT NULL)
+		ORDER BY id
+		LIMIT $3
+	`
+	updateQuery := `
+		UPDATE users
+		SET phone_number_encrypted = $1, metadata_encrypted = $2
+		WHERE id = $3 AND tenant_id = $4
+		  AND phone_number_encrypted IS NOT DISTINCT FROM $5
+		  AND metadata_encrypted IS NOT DISTINCT FROM $6
+	`
+
+	tenantID, err := db.RequireTenant(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	type encryptedUser struct {
+		id                    string
+		phoneNumber, metadata []byte
+	}
+
+	rewrap := func(ciphertext []byte) ([]byte, bool, error) {
+		if ciphertext == nil || !r.keyring.NeedsRewrap(ciphertext) {
+			return ciphertext, false, nil
+		}
+		rewrapped, err := r.keyring.Rewrap(ciphertext)
+		return rewrapped, err == nil, err
+	}
+
+	changed := 0
+	after := uuid.Nil.String()
+	for {
+		rows, err := r.exec(ctx).Query(ctx, selectQuery, tenantID, after, pageSize)
+		if err != nil {
+			return changed, fmt.Errorf("failed to list encrypted users: %w", err)
+		}
+		page, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (encryptedUser, error) {
+			var u encryptedUser
+			err := row.Scan(&u.id, &u.phoneNumber, &u.metadata)
+			return u, err
+		})
+		if err != nil {
+			return changed, fmt.Errorf("failed to scan encrypted users: %w", err)
+		}
+
+		for _, u := range page {
+			phoneNumber, phoneChanged, err := rewrap(u.phoneNumber)
+			if err != nil {
+				return changed, fmt.Errorf("failed to rewrap phone number of user %s: %w", u.id, err)
+			}
+			metadata, metadataChanged, err := rewrap(u.metadata)
+			if err != nil {
+				return changed, fmt.Errorf("failed to rewrap metadata of user %s: %w", u.id, err)
+			}
+			if !phoneChanged && !metadataChanged {
+				continue
+			}
+
+			result, err := r.exec(ctx).Exec(ctx, updateQuery, phoneNumber, metadata, u.id, tenantID, u.phoneNumber, u.metadata)
+			if err != nil {
+				return changed, fmt.Errorf("failed to rewrap user %s: %w", u.id, err)
+			}
+			if result.RowsAffected() > 0 {
+				changed++
+				r.cache.Invalidate(ctx, u.id)
+			}
+		}
+
+		if len(page) < pageSize {
+			return changed, nil
+		}
+		after = page[len(page)-1].id
+	}
+}
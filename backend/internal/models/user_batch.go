@@ -0,0 +1,264 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/authz"
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/db"
+)
+
+// batchSize is the most users written by one statement. Each batch is one
+// multi-row INSERT, with the users passed as an array per column, rather
+// than COPY, which can't skip or update existing rows.
+const batchSize = 1000
+
+// UpsertOutcome is what UpsertBatch did with a user
+type UpsertOutcome int
+
+// Upsert outcomes
+const (
+	UpsertCreated UpsertOutcome = iota + 1
+	UpsertUpdated
+	UpsertUnchanged
+)
+
+// insertUsersSQL inserts the users unnested from batchArgs
+const insertUsersSQL = `
+		INSERT INTO users (id, tenant_id, email, password_hash, first_name, last_name,
+		                   is_active, is_verified, must_reset_password, role)
+		SELECT t.id::uuid, $1::uuid, t.email, t.password_hash, t.first_name, t.last_name,
+		       t.is_active, t.is_verified, t.must_reset_password, t.role
+		FROM unnest($2::text[], $3::text[], $4::text[], $5::text[], $6::text[],
+		            $7::bool[], $8::bool[], $9::bool[], $10::text[])
+		     AS t(id, email, password_hash, first_name, last_name,
+		          is_active, is_verified, must_reset_password, role)
+	`
+
+// CreateBatch creates users in the context's tenant as Create does, but a
+// thousand per round trip. Users whose email or ID is taken are skipped, as
+// are repeats of an email earlier in users. It returns the users it
+// created, in order, with their IDs, timestamps and version set. Their role
+// is written too, defaulting to authz.RoleUser; personal data is not.
+// Batches commit separately unless ctx is in a transaction.
+func (r *UserRepository) CreateBatch(ctx context.Context, users []*User) ([]*User, error) {
+	query := insertUsersSQL + `
+		ON CONFLICT DO NOTHING
+		RETURNING id, created_at, updated_at, version
+	`
+
+	tenantID, err := db.RequireTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var created []*User
+	for start := 0; start < len(users); start += batchSize {
+		batch := users[start:min(start+batchSize, len(users))]
+
+		args, err := r.batchArgs(tenantID, batch)
+		if err != nil {
+			return created, err
+		}
+
+		rows, err := r.exec(ctx).Query(ctx, query, args...)
+		if err != nil {
+			return created, fmt.Errorf("failed to create users: %w", err)
+		}
+
+		inserted := make(map[string]batchRow, len(batch))
+		for rows.Next() {
+			var row batchRow
+			if err := rows.Scan(&row.id, &row.createdAt, &row.updatedAt, &row.version); err != nil {
+				rows.Close()
+				return created, fmt.Errorf("failed to scan created user: %w", err)
+			}
+			inserted[row.id] = row
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return created, fmt.Errorf("failed to create users: %w", err)
+		}
+
+		for _, user := range batch {
+			row, ok := inserted[user.ID]
+			if !ok {
+				continue
+			}
+			// A repeated ID is only created once
+			delete(inserted, user.ID)
+			row.apply(user, tenantID)
+			created = append(created, user)
+		}
+	}
+
+	return created, nil
+}
+
+// UpsertBatch creates the users in the context's tenant whose email is not
+// taken, as CreateBatch does, and updates the names and the active,
+// verified and must-reset-password flags of those whose email is. Existing
+// users keep their ID, password and role. A user is only updated, and its
+// version advanced, if one of those fields differs. Every user gets its ID,
+// timestamps and version set, and the returned outcomes say what happened
+// to each. An email may appear only once in users.
+func (r *UserRepository) UpsertBatch(ctx context.Context, users []*User) ([]UpsertOutcome, error) {
+	upsertQuery := insertUsersSQL + `
+		ON CONFLICT (tenant_id, email) DO UPDATE
+		SET first_name = EXCLUDED.first_name, last_name = EXCLUDED.last_name,
+		    is_active = EXCLUDED.is_active, is_verified = EXCLUDED.is_verified,
+		    must_reset_password = EXCLUDED.must_reset_password,
+		    version = users.version + 1
+		WHERE (users.first_name, users.last_name, users.is_active, users.is_verified, users.must_reset_password)
+		      IS DISTINCT FROM
+		      (EXCLUDED.first_name, EXCLUDED.last_name, EXCLUDED.is_active, EXCLUDED.is_verified, EXCLUDED.must_reset_password)
+		RETURNING id, email, created_at, updated_at, version, xmax = 0
+	`
+	unchangedQuery := `
+		SELECT id, email, created_at, updated_at, version
+		FROM users
+		WHERE tenant_id = $1 AND email = ANY($2)
+	`
+
+	tenantID, err := db.RequireTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// ON CONFLICT DO UPDATE can't change a row twice in one statement
+	seen := make(map[string]int, len(users))
+	for i, user := range users {
+		if j, ok := seen[user.Email]; ok {
+			return nil, fmt.Errorf("users %d and %d have the same email", j, i)
+		}
+		seen[user.Email] = i
+	}
+
+	outcomes := make([]UpsertOutcome, len(users))
+	for start := 0; start < len(users); start += batchSize {
+		batch := users[start:min(start+batchSize, len(users))]
+
+		args, err := r.batchArgs(tenantID, batch)
+		if err != nil {
+			return outcomes, err
+		}
+
+		rows, err := r.exec(ctx).Query(ctx, upsertQuery, args...)
+		if err != nil {
+			return outcomes, fmt.Errorf("failed to upsert users: %w", err)
+		}
+
+		written := make(map[string]batchRow, len(batch))
+		for rows.Next() {
+			var row batchRow
+			var email string
+			var inserted bool
+			if err := rows.Scan(&row.id, &email, &row.createdAt, &row.updatedAt, &row.version, &inserted); err != nil {
+				rows.Close()
+				return outcomes, fmt.Errorf("failed to scan upserted user: %w", err)
+			}
+			row.outcome = UpsertUpdated
+			if inserted {
+				row.outcome = UpsertCreated
+			}
+			written[email] = row
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return outcomes, fmt.Errorf("failed to upsert users: %w", err)
+		}
+
+		// Unchanged users aren't returned by the upsert, so read them
+		var unchanged []string
+		for _, user := range batch {
+			if _, ok := written[user.Email]; !ok {
+				unchanged = append(unchanged, user.Email)
+			}
+		}
+		if len(unchanged) > 0 {
+			rows, err := r.exec(ctx).Query(ctx, unchangedQuery, tenantID, unchanged)
+			if err != nil {
+				return outcomes, fmt.Errorf("failed to read unchanged users: %w", err)
+			}
+			for rows.Next() {
+				row := batchRow{outcome: UpsertUnchanged}
+				var email string
+				if err := rows.Scan(&row.id, &email, &row.createdAt, &row.updatedAt, &row.version); err != nil {
+					rows.Close()
+					return outcomes, fmt.Errorf("failed to scan unchanged user: %w", err)
+				}
+				written[email] = row
+			}
+			rows.Close()
+			if err := rows.Err(); err != nil {
+				return outcomes, fmt.Errorf("failed to read unchanged users: %w", err)
+			}
+		}
+
+		for i, user := range batch {
+			row, ok := written[user.Email]
+			if !ok {
+				// Deleted since the upsert
+				continue
+			}
+			row.apply(user, tenantID)
+			outcomes[start+i] = row.outcome
+		}
+	}
+
+	return outcomes, nil
+}
+
+// batchRow is what the database returns for a user written in a batch
+type batchRow struct {
+	id                   string
+	createdAt, updatedAt time.Time
+	version              int64
+	outcome              UpsertOutcome
+}
+
+func (row batchRow) apply(user *User, tenantID string) {
+	user.ID = row.id
+	user.TenantID = tenantID
+	user.CreatedAt = row.createdAt
+	user.UpdatedAt = row.updatedAt
+	user.Version = row.version
+}
+
+// batchArgs returns the arguments of insertUsersSQL for users, assigning
+// IDs to those that have none
+func (r *UserRepository) batchArgs(tenantID string, users []*User) ([]interface{}, error) {
+	n := len(users)
+	ids, emails, passwordHashes := make([]string, n), make([]string, n), make([]string, n)
+	firstNames, lastNames, roles := make([]string, n), make([]string, n), make([]string, n)
+	active, verified, mustReset := make([]bool, n), make([]bool, n), make([]bool, n)
+
+	for i, user := range users {
+		if user.ID == "" {
+			id, err := r.newID()
+			if err != nil {
+				return nil, fmt.Errorf("failed to generate user ID: %w", err)
+			}
+			user.ID = id.String()
+		}
+		roles[i] = user.Role
+		if roles[i] == "" {
+			roles[i] = authz.RoleUser
+		}
+
+		ids[i] = user.ID
+		emails[i] = user.Email
+		passwordHashes[i] = user.PasswordHash
+		firstNames[i] = user.FirstName
+		lastNames[i] = user.LastName
+		active[i] = user.IsActive
+		verified[i] = user.IsVerified
+		mustReset[i] = user.MustResetPassword
+	}
+
+	return []interface{}{
+		tenantID, ids, emails, passwordHashes, firstNames, lastNames,
+		active, verified, mustReset, roles,
+	}, nil
+}
@@ -0,0 +1,250 @@
+package models
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/db"
+)
+
+// UserChangedChannel is the channel the users table announces changed and
+// deleted users on, with the user ID as payload
+const UserChangedChannel = "user_changed"
+
+var userCacheLookupsTotal = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "user_cache_lookups_total",
+		Help: "Total number of user cache lookups, by tier (memory or shared) and result (hit or miss).",
+	},
+	[]string{"tier", "result"},
+)
+
+// countLookup records a lookup in tier
+func countLookup(tier string, hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	userCacheLookupsTotal.WithLabelValues(tier, result).Inc()
+}
+
+// UserCache keeps recently read users in memory so hot lookups, such as
+// token validation, skip the database. Each instance drops a user as soon as
+// a user_changed notification for it arrives (see db.Listener). Nothing is
+// cached while the listener is disconnected, since a change could go
+// unannounced. Entries also expire after the TTL. Users missing from memory
+// are looked up in the shared cache, if there is one.
+//
+// A nil *UserCache caches nothing.
+type UserCache struct {
+	ttl        time.Duration
+	maxEntries int
+	shared     *SharedUserCache
+
+	mu        sync.Mutex
+	listening bool
+	// version changes with every invalidation, so a lookup that raced one
+	// doesn't cache what it read
+	version uint64
+	byID    map[string]userCacheEntry
+	// byEmail maps tenant ID and email to user ID
+	byEmail map[string]string
+}
+
+type userCacheEntry struct {
+	user    *User
+	expires time.Time
+}
+
+var _ db.NotificationHandler = (*UserCache)(nil)
+
+// NewUserCache creates a cache holding up to maxEntries users for ttl each,
+// in front of shared, which may be nil. It caches nothing in memory until
+// its listener reports it is listening.
+func NewUserCache(ttl time.Duration, maxEntries int, shared *SharedUserCache) *UserCache {
+	return &UserCache{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		shared:     shared,
+		byID:       make(map[string]userCacheEntry),
+		byEmail:    make(map[string]string),
+	}
+}
+
+// Get returns a copy of the cached user with the given ID in the tenant
+func (c *UserCache) Get(ctx context.Context, tenantID, id string) (*User, bool) {
+	if c == nil {
+		return nil, false
+	}
+
+	c.mu.Lock()
+	user, ok := c.get(tenantID, id)
+	version := c.version
+	c.mu.Unlock()
+	countLookup("memory", ok)
+	if ok || c.shared == nil {
+		return user, ok
+	}
+
+	user, ok = c.shared.get(ctx, tenantID, id)
+	countLookup("shared", ok)
+	if ok {
+		c.putLocal(user, version)
+	}
+	return user, ok
+}
+
+// GetByEmail returns a copy of the cached user with the given email in the
+// tenant
+func (c *UserCache) GetByEmail(ctx context.Context, tenantID, email string) (*User, bool) {
+	if c == nil {
+		return nil, false
+	}
+
+	c.mu.Lock()
+	var user *User
+	id, ok := c.byEmail[emailKey(tenantID, email)]
+	if ok {
+		user, ok = c.get(tenantID, id)
+	}
+	version := c.version
+	c.mu.Unlock()
+	countLookup("memory", ok)
+	if ok || c.shared == nil {
+		return user, ok
+	}
+
+	user, ok = c.shared.getByEmail(ctx, tenantID, email)
+	countLookup("shared", ok)
+	if ok {
+		c.putLocal(user, version)
+	}
+	return user, ok
+}
+
+func (c *UserCache) get(tenantID, id string) (*User, bool) {
+	e, ok := c.byID[id]
+	if !ok || e.user.TenantID != tenantID || time.Now().After(e.expires) {
+		return nil, false
+	}
+	user := *e.user
+	return &user, true
+}
+
+// Version returns the version to pass to Put for a lookup about to start
+func (c *UserCache) Version() uint64 {
+	if c == nil {
+		return 0
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.version
+}
+
+// Put caches a copy of user, read by a lookup that started at version. It
+// does nothing if a user was invalidated since, as the copy may predate it.
+func (c *UserCache) Put(ctx context.Context, user *User, version uint64) {
+	if c == nil {
+		return
+	}
+
+	if c.putLocal(user, version) && c.shared != nil {
+		c.shared.put(ctx, user)
+	}
+}
+
+// putLocal caches a copy of user in memory, if listening, and reports
+// whether no user was invalidated since version
+func (c *UserCache) putLocal(user *User, version uint64) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.version != version {
+		return false
+	}
+	if !c.listening {
+		return true
+	}
+
+	if _, ok := c.byID[user.ID]; ok {
+		c.remove(user.ID)
+	} else if len(c.byID) >= c.maxEntries {
+		c.evict()
+	}
+
+	copied := *user
+	c.byID[user.ID] = userCacheEntry{user: &copied, expires: time.Now().Add(c.ttl)}
+	c.byEmail[emailKey(user.TenantID, user.Email)] = user.ID
+	return true
+}
+
+// evict makes room for one entry, dropping expired entries or, failing
+// that, an arbitrary one
+func (c *UserCache) evict() {
+	now := time.Now()
+	for id, e := range c.byID {
+		if now.After(e.expires) {
+			c.remove(id)
+		}
+	}
+
+	if len(c.byID) < c.maxEntries {
+		return
+	}
+	for id := range c.byID {
+		c.remove(id)
+		return
+	}
+}
+
+// Invalidate drops the user with the given ID, from the shared cache too
+func (c *UserCache) Invalidate(ctx context.Context, id string) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	c.version++
+	c.remove(id)
+	c.mu.Unlock()
+
+	if c.shared != nil {
+		c.shared.invalidate(ctx, id)
+	}
+}
+
+func (c *UserCache) remove(id string) {
+	if e, ok := c.byID[id]; ok {
+		delete(c.byEmail, emailKey(e.user.TenantID, e.user.Email))
+		delete(c.byID, id)
+	}
+}
+
+// HandleNotification drops the user named by a user_changed notification.
+// Notifications arrive after the change commits, so this also drops a copy
+// another lookup put in the shared cache between the write and the commit.
+func (c *UserCache) HandleNotification(payload string) {
+	ctx, cancel := context.WithTimeout(context.Background(), sharedUserCacheTimeout)
+	defer cancel()
+	c.Invalidate(ctx, payload)
+}
+
+// SetListening empties the cache, and enables it only while listening
+func (c *UserCache) SetListening(listening bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.listening = listening
+	c.version++
+	c.byID = make(map[string]userCacheEntry)
+	c.byEmail = make(map[string]string)
+}
+
+func emailKey(tenantID, email string) string {
+	return tenantID + "/" + email
+}
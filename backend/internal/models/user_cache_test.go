@@ -0,0 +1,84 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sahays/grpc-proto-go-flutter-template/pkg/crypto"
+)
+
+// downStore is a shared store whose every call fails, like Redis during an
+// outage
+type downStore struct{}
+
+var errStoreDown = errors.New("connection refused")
+
+func (downStore) Get(context.Context, string) (string, error) { return "", errStoreDown }
+
+func (downStore) Set(context.Context, string, string, time.Duration) error { return errStoreDown }
+
+func (downStore) Delete(context.Context, ...string) error { return errStoreDown }
+
+func newTestKeyring(t *testing.T) *crypto.Keyring {
+	t.Helper()
+
+	keyring, err := crypto.NewKeyring(map[string][]byte{"test": make([]byte, 32)}, "test")
+	if err != nil {
+		t.Fatalf("NewKeyring: %v", err)
+	}
+	return keyring
+}
+
+// TestUserCacheSharedStoreDown checks that users cached in memory are still
+// found while the shared store is down, and that other lookups are misses
+// the caller reads from PostgreSQL
+func TestUserCacheSharedStoreDown(t *testing.T) {
+	ctx := context.Background()
+	c := NewUserCache(time.Minute, 10, NewSharedUserCache(downStore{}, time.Minute, newTestKeyring(t)))
+	c.SetListening(true)
+
+	cached := &User{ID: "user-1", TenantID: "tenant-1", Email: "a@example.com"}
+	c.Put(ctx, cached, c.Version())
+
+	tests := []struct {
+		name   string
+		lookup func() (*User, bool)
+		want   *User
+	}{
+		{
+			name:   "cached by ID",
+			lookup: func() (*User, bool) { return c.Get(ctx, "tenant-1", "user-1") },
+			want:   cached,
+		},
+		{
+			name:   "cached by email",
+			lookup: func() (*User, bool) { return c.GetByEmail(ctx, "tenant-1", "a@example.com") },
+			want:   cached,
+		},
+		{
+			name:   "other tenant",
+			lookup: func() (*User, bool) { return c.Get(ctx, "tenant-2", "user-1") },
+		},
+		{
+			name:   "not cached by ID",
+			lookup: func() (*User, bool) { return c.Get(ctx, "tenant-1", "user-2") },
+		},
+		{
+			name:   "not cached by email",
+			lookup: func() (*User, bool) { return c.GetByEmail(ctx, "tenant-1", "b@example.com") },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user, ok := tt.lookup()
+			if ok != (tt.want != nil) {
+				t.Fatalf("found = %v, want %v", ok, tt.want != nil)
+			}
+			if ok && user.ID != tt.want.ID {
+				t.Errorf("got user %s, want %s", user.ID, tt.want.ID)
+			}
+		})
+	}
+}
@@ -0,0 +1,206 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/db"
+)
+
+// User history actions
+const (
+	HistoryProfileUpdated        = "profile_updated"
+	HistoryPasswordChanged       = "password_changed"
+	HistoryPasswordReset         = "password_reset"
+	HistoryEmailVerified         = "email_verified"
+	HistoryRecoveryEmailSet      = "recovery_email_set"
+	HistoryRecoveryEmailVerified = "recovery_email_verified"
+	HistoryActivated             = "activated"
+	HistoryDeactivated           = "deactivated"
+	HistoryRoleChanged           = "role_changed"
+	HistoryResetRequired         = "password_reset_required"
+	HistoryResetLifted           = "password_reset_lifted"
+)
+
+// UserSnapshot is the state of a user recorded in the history. Secrets such
+// as the password hash, and personal data stored encrypted, are left out.
+type UserSnapshot struct {
+	Email                 string  `json:"email"`
+	FirstName             string  `json:"first_name"`
+	LastName              string  `json:"last_name"`
+	Role                  string  `json:"role"`
+	IsActive              bool    `json:"is_active"`
+	IsVerified            bool    `json:"is_verified"`
+	MustResetPassword     bool    `json:"must_reset_password"`
+	RecoveryEmail         *string `json:"recovery_email"`
+	RecoveryEmailVerified bool    `json:"recovery_email_verified"`
+	Version               int64   `json:"version"`
+}
+
+// SnapshotOf returns the recorded state of user
+func SnapshotOf(user *User) UserSnapshot {
+	return UserSnapshot{
+		Email:                 user.Email,
+		FirstName:             user.FirstName,
+		LastName:              user.LastName,
+		Role:                  user.Role,
+		IsActive:              user.IsActive,
+		IsVerified:            user.IsVerified,
+		MustResetPassword:     user.MustResetPassword,
+		RecoveryEmail:         user.RecoveryEmail,
+		RecoveryEmailVerified: user.RecoveryEmailVerified,
+		Version:               user.Version,
+	}
+}
+
+// UserHistoryEntry is one recorded change to a user
+type UserHistoryEntry struct {
+	ID       int64
+	TenantID string
+	UserID   string
+	Action   string
+	// ActorID is the authenticated caller who made the change, empty for
+	// token-based flows such as password reset
+	ActorID   string
+	ActorRole string
+	RequestID string
+	Before    UserSnapshot
+	After     UserSnapshot
+	CreatedAt time.Time
+}
+
+// userHistoryColumns is the column list shared by all history SELECT
+// queries, in scanUserHistory order
+const userHistoryColumns = `id, tenant_id, user_id, action, COALESCE(actor_id::text, ''),
+		       actor_role, COALESCE(request_id, ''), before, after, created_at`
+
+func scanUserHistory(row rowScanner) (*UserHistoryEntry, error) {
+	entry := &UserHistoryEntry{}
+	var before, after []byte
+	err := row.Scan(
+		&entry.ID,
+		&entry.TenantID,
+		&entry.UserID,
+		&entry.Action,
+		&entry.ActorID,
+		&entry.ActorRole,
+		&entry.RequestID,
+		&before,
+		&after,
+		&entry.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(before, &entry.Before); err != nil {
+		return nil, fmt.Errorf("invalid snapshot: %w", err)
+	}
+	if err := json.Unmarshal(after, &entry.After); err != nil {
+		return nil, fmt.Errorf("invalid snapshot: %w", err)
+	}
+	return entry, nil
+}
+
+// UserHistoryRepository handles user history database operations
+type UserHistoryRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewUserHistoryRepository creates a new user history repository
+func NewUserHistoryRepository(db *pgxpool.Pool) *UserHistoryRepository {
+	return &UserHistoryRepository{db: db}
+}
+
+// Add records entry, joining the transaction in ctx if there is one, and
+// sets its ID and creation time
+func (r *UserHistoryRepository) Add(ctx context.Context, entry *UserHistoryEntry) error {
+	before, err := json.Marshal(entry.Before)
+	if err != nil {
+		return fmt.Errorf("failed to encode snapshot: %w", err)
+	}
+	after, err := json.Marshal(entry.After)
+	if err != nil {
+		return fmt.Errorf("failed to encode snapshot: %w", err)
+	}
+
+	query := `
+		INSERT INTO user_history (tenant_id, user_id, action, actor_id, actor_role, request_id, before, after)
+		VALUES ($1, $2, $3, NULLIF($4, '')::uuid, $5, NULLIF($6, ''), $7, $8)
+		RETURNING id, created_at
+	`
+
+	err = db.ExecutorFrom(ctx, r.db).QueryRow(
+		ctx,
+		query,
+		entry.TenantID,
+		entry.UserID,
+		entry.Action,
+		entry.ActorID,
+		entry.ActorRole,
+		entry.RequestID,
+		string(before),
+		string(after),
+	).Scan(&entry.ID, &entry.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record user history: %w", err)
+	}
+
+	return nil
+}
+
+// historyByID pages history newest first
+var historyByID = db.Keyset{Columns: []string{"id"}, Descending: true}
+
+// ListForUser retrieves a page of a user's history, newest first. pageToken
+// is "" for the first page, then the token returned with the previous page.
+// The returned token is "" on the last page.
+func (r *UserHistoryRepository) ListForUser(ctx context.Context, tenantID, userID string, pageSize int, pageToken string) ([]*UserHistoryEntry, string, error) {
+	size := db.PageSize(pageSize)
+
+	where := "tenant_id = $2 AND user_id = $3"
+	args := []interface{}{size + 1, tenantID, userID}
+	if pageToken != "" {
+		var id int64
+		if err := db.DecodePageToken(pageToken, &id); err != nil {
+			return nil, "", err
+		}
+		where += " AND " + historyByID.After(4)
+		args = append(args, id)
+	}
+
+	query := `
+		SELECT ` + userHistoryColumns + `
+		FROM user_history
+		WHERE ` + where + `
+		ORDER BY ` + historyByID.OrderBy() + `
+		LIMIT $1
+	`
+
+	rows, err := db.ExecutorFrom(ctx, r.db).Query(ctx, query, args...)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to list user history: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []*UserHistoryEntry
+	for rows.Next() {
+		entry, err := scanUserHistory(rows)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to scan user history: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, "", fmt.Errorf("error iterating user history: %w", err)
+	}
+
+	return db.NextPage(entries, size, func(e *UserHistoryEntry) []interface{} {
+		return []interface{}{e.ID}
+	})
+}
@@ -0,0 +1,116 @@
+package models
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"github.com/sahays/grpc-proto-go-flutter-template/pkg/crypto"
+)
+
+// sharedUserCacheTimeout bounds shared cache calls made without a request
+// context, when a change notification arrives
+const sharedUserCacheTimeout = time.Second
+
+// SharedStore is the store behind a SharedUserCache, implemented by
+// *cache.Cache. Get returns an error for a missing key.
+type SharedStore interface {
+	Get(ctx context.Context, key string) (string, error)
+	Set(ctx context.Context, key, value string, ttl time.Duration) error
+	Delete(ctx context.Context, keys ...string) error
+}
+
+// SharedUserCache keeps users in a store shared by every instance, such as
+// Redis, behind each instance's UserCache. An instance that starts or
+// scales out then finds recently read users without a query. Entries are
+// deleted on every write, and again by each instance when the change is
+// announced, but a lookup that raced the write can cache the old copy
+// until the TTL, so the TTL should be short.
+//
+// Users are stored encrypted with the personal data keyring, since they
+// include personal data and password hashes; emails are stored hashed.
+type SharedUserCache struct {
+	store   SharedStore
+	ttl     time.Duration
+	keyring *crypto.Keyring
+}
+
+// NewSharedUserCache creates a shared cache holding users for ttl
+func NewSharedUserCache(store SharedStore, ttl time.Duration, keyring *crypto.Keyring) *SharedUserCache {
+	return &SharedUserCache{store: store, ttl: ttl, keyring: keyring}
+}
+
+func sharedUserKey(id string) string {
+	return "user:" + id
+}
+
+func sharedUserEmailKey(tenantID, email string) string {
+	sum := sha256.Sum256([]byte(emailKey(tenantID, email)))
+	return "user_email:" + hex.EncodeToString(sum[:])
+}
+
+// sharedUserContext binds a cached user to its ID, like piiContext
+func sharedUserContext(id string) []byte {
+	return []byte("users.cache:" + id)
+}
+
+// get returns the cached user with the given ID in the tenant. Store and
+// decryption errors count as misses.
+func (c *SharedUserCache) get(ctx context.Context, tenantID, id string) (*User, bool) {
+	data, err := c.store.Get(ctx, sharedUserKey(id))
+	if err != nil {
+		return nil, false
+	}
+
+	plaintext, err := c.keyring.Decrypt([]byte(data), sharedUserContext(id))
+	if err != nil {
+		return nil, false
+	}
+
+	user := &User{}
+	if err := json.Unmarshal(plaintext, user); err != nil || user.ID != id || user.TenantID != tenantID {
+		return nil, false
+	}
+	return user, true
+}
+
+// getByEmail returns the cached user with the given email in the tenant
+func (c *SharedUserCache) getByEmail(ctx context.Context, tenantID, email string) (*User, bool) {
+	id, err := c.store.Get(ctx, sharedUserEmailKey(tenantID, email))
+	if err != nil {
+		return nil, false
+	}
+
+	// The email index outlives email changes, so check the user still has it
+	user, ok := c.get(ctx, tenantID, id)
+	if !ok || user.Email != email {
+		return nil, false
+	}
+	return user, true
+}
+
+// put caches user, best effort
+func (c *SharedUserCache) put(ctx context.Context, user *User) {
+	plaintext, err := json.Marshal(user)
+	if err != nil {
+		return
+	}
+
+	data, err := c.keyring.Encrypt(plaintext, sharedUserContext(user.ID))
+	if err != nil {
+		return
+	}
+
+	if err := c.store.Set(ctx, sharedUserKey(user.ID), string(data), c.ttl); err != nil {
+		return
+	}
+	_ = c.store.Set(ctx, sharedUserEmailKey(user.TenantID, user.Email), user.ID, c.ttl)
+}
+
+// invalidate deletes the user with the given ID, best effort. Its email
+// index entry is left to expire, as getByEmail checks it.
+func (c *SharedUserCache) invalidate(ctx context.Context, id string) {
+	_ = c.store.Delete(ctx, sharedUserKey(id))
+}
@@ -0,0 +1,177 @@
+// Package outbox delivers events recorded in the outbox table. Services
+// write an event in the same transaction as the change it describes; the
+// relay publishes it afterwards, so an event is never lost if the process
+// dies mid-request and never sent for a change that rolled back. Delivery is
+// at least once: consumers deduplicate on the event ID.
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/cache"
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/config"
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/db"
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/models"
+)
+
+const (
+	// maxRetryDelay caps how long a failing event is held back
+	maxRetryDelay = 5 * time.Minute
+	// cleanupInterval is how often delivered events past retention are deleted
+	cleanupInterval = time.Hour
+)
+
+// Publisher delivers one event
+type Publisher interface {
+	Publish(ctx context.Context, event models.OutboxEvent) error
+}
+
+// NewPublisher returns the publisher selected by cfg.Publisher
+func NewPublisher(cfg config.OutboxConfig, c *cache.Cache) (Publisher, error) {
+	switch cfg.Publisher {
+	case "redis":
+		return NewRedisPublisher(c, cfg.RedisStream, int64(cfg.RedisStreamMaxLen)), nil
+	case "webhook":
+		return NewWebhookPublisher(cfg.WebhookURL, cfg.WebhookSecret, cfg.WebhookTimeout), nil
+	default:
+		return nil, fmt.Errorf("unknown outbox publisher %q", cfg.Publisher)
+	}
+}
+
+// Relay moves events from the outbox to a publisher. Any number of relays
+// may run against the same database; each event is claimed by one at a time.
+type Relay struct {
+	repo      *models.OutboxRepository
+	txManager *db.TxManager
+	publisher Publisher
+	locker    *cache.Cache
+	logger    *zap.Logger
+
+	pollInterval time.Duration
+	batchSize    int
+	retention    time.Duration
+}
+
+// NewRelay creates a relay. Call Run to start delivering. locker takes the
+// lock that lets one relay at a time delete old events.
+func NewRelay(cfg config.OutboxConfig, repo *models.OutboxRepository, txManager *db.TxManager, publisher Publisher, locker *cache.Cache, logger *zap.Logger) *Relay {
+	return &Relay{
+		repo:         repo,
+		txManager:    txManager,
+		publisher:    publisher,
+		locker:       locker,
+		logger:       logger,
+		pollInterval: cfg.PollInterval,
+		batchSize:    cfg.BatchSize,
+		retention:    cfg.Retention,
+	}
+}
+
+// Run delivers pending events every poll interval and deletes delivered ones
+// past retention, until ctx ends
+func (r *Relay) Run(ctx context.Context) {
+	poll := time.NewTicker(r.pollInterval)
+	defer poll.Stop()
+
+	cleanup := time.NewTicker(cleanupInterval)
+	defer cleanup.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-poll.C:
+			r.drain(ctx)
+		case <-cleanup.C:
+			r.cleanup(ctx)
+		}
+	}
+}
+
+// drain delivers batches until the outbox has no more due events
+func (r *Relay) drain(ctx context.Context) {
+	for ctx.Err() == nil {
+		n, err := r.deliverBatch(ctx)
+		if err != nil {
+			r.logger.Warn("outbox delivery failed", zap.Error(err))
+			return
+		}
+		if n < r.batchSize {
+			return
+		}
+	}
+}
+
+// deliverBatch claims and publishes up to batchSize events in one
+// transaction, returning how many were claimed. The claim locks the events,
+// so a crash mid-batch only re-delivers them.
+func (r *Relay) deliverBatch(ctx context.Context) (int, error) {
+	var claimed int
+
+	err := r.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		events, err := r.repo.ClaimPending(ctx, r.batchSize)
+		if err != nil {
+			return err
+		}
+		claimed = len(events)
+
+		published := make([]int64, 0, len(events))
+		for _, event := range events {
+			if err := r.publisher.Publish(ctx, event); err != nil {
+				delay := retryDelay(event.Attempts)
+				r.logger.Warn("failed to publish outbox event",
+					zap.Int64("event_id", event.ID),
+					zap.String("event_type", event.Type),
+					zap.Int("attempt", event.Attempts+1),
+					zap.Duration("retry_in", delay),
+					zap.Error(err),
+				)
+				if err := r.repo.MarkFailed(ctx, event.ID, err.Error(), delay); err != nil {
+					return err
+				}
+				// The sink is most likely down; leave the rest of the batch
+				// for the next poll rather than wait on each in turn.
+				// Reporting nothing claimed ends this drain.
+				claimed = 0
+				break
+			}
+			published = append(published, event.ID)
+		}
+
+		return r.repo.MarkPublished(ctx, published)
+	})
+
+	return claimed, err
+}
+
+// cleanup deletes delivered events older than the retention period. Only
+// one relay across all instances does so per cleanupInterval: the lock is
+// left to expire. If the lock can't be checked, it deletes anyway, which is
+// harmless.
+func (r *Relay) cleanup(ctx context.Context) {
+	if _, err := r.locker.Lock(ctx, "outbox_cleanup", cleanupInterval); errors.Is(err, cache.ErrLockHeld) {
+		return
+	}
+
+	deleted, err := r.repo.DeletePublishedBefore(ctx, time.Now().Add(-r.retention))
+	if err != nil {
+		r.logger.Warn("failed to delete delivered outbox events", zap.Error(err))
+		return
+	}
+	if deleted > 0 {
+		r.logger.Info("deleted delivered outbox events", zap.Int64("count", deleted))
+	}
+}
+
+// retryDelay doubles from one second per failed attempt, up to maxRetryDelay
+func retryDelay(attempts int) time.Duration {
+	if attempts >= 9 {
+		return maxRetryDelay
+	}
+	return min(time.Second<<attempts, maxRetryDelay)
+}
@@ -0,0 +1,36 @@
+package outbox
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/cache"
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/models"
+)
+
+// RedisPublisher adds each event to a Redis stream, where consumer groups
+// can read it
+type RedisPublisher struct {
+	cache  *cache.Cache
+	stream string
+	maxLen int64
+}
+
+// NewRedisPublisher creates a publisher for stream, trimmed to about maxLen
+// entries
+func NewRedisPublisher(c *cache.Cache, stream string, maxLen int64) *RedisPublisher {
+	return &RedisPublisher{cache: c, stream: stream, maxLen: maxLen}
+}
+
+// Publish adds the event to the stream
+func (p *RedisPublisher) Publish(ctx context.Context, event models.OutboxEvent) error {
+	_, err := p.cache.AddToStream(ctx, p.stream, p.maxLen, map[string]interface{}{
+		"id":           strconv.FormatInt(event.ID, 10),
+		"type":         event.Type,
+		"aggregate_id": event.AggregateID,
+		"payload":      string(event.Payload),
+		"created_at":   event.CreatedAt.UTC().Format(time.RFC3339Nano),
+	})
+	return err
+}
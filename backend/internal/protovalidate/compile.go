@@ -0,0 +1,375 @@
+package protovalidate
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"buf.build/gen/go/bufbuild/protovalidate/protocolbuffers/go/buf/validate"
+	"github.com/google/cel-go/cel"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// messageRules are the compiled rules of a message type
+type messageRules struct {
+	// cel are the (buf.validate.message).cel rules, on the whole message
+	cel []*program
+	// requiredOneofs are the oneofs with (buf.validate.oneof).required set
+	requiredOneofs []protoreflect.OneofDescriptor
+	// oneofs are the (buf.validate.message).oneof rules
+	oneofs []*oneofRule
+	fields []*fieldRules
+}
+
+// oneofRule requires at most one of fields to be set, or exactly one if
+// required
+type oneofRule struct {
+	fields   []protoreflect.FieldDescriptor
+	names    string
+	required bool
+}
+
+// fieldRules are the compiled (buf.validate.field) rules of a field
+type fieldRules struct {
+	desc     protoreflect.FieldDescriptor
+	required bool
+	ignore   validate.Ignore
+	// value applies to the field's value: the whole list or map of a
+	// repeated or map field
+	value valueRules
+	// items applies to each item of a list or value of a map, and keys to
+	// each key of a map
+	items, keys *valueRules
+}
+
+// valueRules are the compiled rules of one value
+type valueRules struct {
+	ignore validate.Ignore
+	// rules is the message of the type rules, such as StringRules, bound to
+	// rules in their expressions
+	rules    proto.Message
+	programs []*program
+	// definedOnly is the enum a value must be defined in
+	definedOnly protoreflect.EnumDescriptor
+	// anyIn and anyNotIn are the type URLs an Any must, and must not, hold
+	anyIn, anyNotIn []string
+}
+
+// program is a compiled CEL rule
+type program struct {
+	id string
+	// message is reported if the rule returns false
+	message string
+	prg     cel.Program
+	// rule is the value of the rule's field, bound to rule in a standard
+	// rule's expression
+	rule any
+}
+
+// compileMessage compiles the rules of desc
+func (v *Validator) compileMessage(desc protoreflect.MessageDescriptor) (*messageRules, error) {
+	env, err := v.env.Extend(cel.TypeDescs(desc.ParentFile()))
+	if err != nil {
+		return nil, err
+	}
+
+	rules := &messageRules{}
+	if mr, _ := proto.GetExtension(desc.Options(), validate.E_Message).(*validate.MessageRules); mr != nil {
+		for _, rule := range mr.GetCel() {
+			p, err := compileCustom(env, cel.ObjectType(string(desc.FullName())), rule)
+			if err != nil {
+				return nil, err
+			}
+			rules.cel = append(rules.cel, p)
+		}
+
+		for _, oneof := range mr.GetOneof() {
+			rule := &oneofRule{names: strings.Join(oneof.GetFields(), ", "), required: oneof.GetRequired()}
+			for _, name := range oneof.GetFields() {
+				fd := desc.Fields().ByName(protoreflect.Name(name))
+				if fd == nil {
+					return nil, fmt.Errorf("oneof rule names unknown field %q", name)
+				}
+				rule.fields = append(rule.fields, fd)
+			}
+			rules.oneofs = append(rules.oneofs, rule)
+		}
+	}
+
+	oneofs := desc.Oneofs()
+	for i := 0; i < oneofs.Len(); i++ {
+		od := oneofs.Get(i)
+		if or, _ := proto.GetExtension(od.Options(), validate.E_Oneof).(*validate.OneofRules); or.GetRequired() {
+			rules.requiredOneofs = append(rules.requiredOneofs, od)
+		}
+	}
+
+	fields := desc.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		f, err := compileField(env, fd)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", fd.Name(), err)
+		}
+		rules.fields = append(rules.fields, f)
+	}
+	return rules, nil
+}
+
+// compileField compiles the rules of fd
+func compileField(env *cel.Env, fd protoreflect.FieldDescriptor) (*fieldRules, error) {
+	fr, _ := proto.GetExtension(fd.Options(), validate.E_Field).(*validate.FieldRules)
+	f := &fieldRules{desc: fd, required: fr.GetRequired(), ignore: fr.GetIgnore()}
+	if fr == nil {
+		return f, nil
+	}
+
+	// Custom rules apply to the field's whole value
+	for _, rule := range fr.GetCel() {
+		p, err := compileCustom(env, fieldType(fd), rule)
+		if err != nil {
+			return nil, err
+		}
+		f.value.programs = append(f.value.programs, p)
+	}
+
+	typed := typeRules(fr)
+	if typed == nil {
+		return f, nil
+	}
+
+	switch {
+	case fd.IsList():
+		repeated, ok := typed.Interface().(*validate.RepeatedRules)
+		if !ok {
+			return nil, fmt.Errorf("%s rules on a repeated field", typed.Descriptor().Name())
+		}
+		if err := compileTypeRules(env, &f.value, fieldType(fd), repeated); err != nil {
+			return nil, err
+		}
+		if repeated.Items != nil {
+			items, err := compileValue(env, fd, repeated.GetItems())
+			if err != nil {
+				return nil, fmt.Errorf("items: %w", err)
+			}
+			f.items = items
+		}
+
+	case fd.IsMap():
+		m, ok := typed.Interface().(*validate.MapRules)
+		if !ok {
+			return nil, fmt.Errorf("%s rules on a map field", typed.Descriptor().Name())
+		}
+		if err := compileTypeRules(env, &f.value, fieldType(fd), m); err != nil {
+			return nil, err
+		}
+		if m.Keys != nil {
+			keys, err := compileValue(env, fd.MapKey(), m.GetKeys())
+			if err != nil {
+				return nil, fmt.Errorf("keys: %w", err)
+			}
+			f.keys = keys
+		}
+		if m.Values != nil {
+			values, err := compileValue(env, fd.MapValue(), m.GetValues())
+			if err != nil {
+				return nil, fmt.Errorf("values: %w", err)
+			}
+			f.items = values
+		}
+
+	default:
+		if err := compileScalar(env, &f.value, fd, typed); err != nil {
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+// compileValue compiles the rules of the items of a list, or the keys or
+// values of a map, of fd's type
+func compileValue(env *cel.Env, fd protoreflect.FieldDescriptor, fr *validate.FieldRules) (*valueRules, error) {
+	if fr.GetRequired() {
+		return nil, errors.New("required is not supported on items, keys or values")
+	}
+
+	r := &valueRules{ignore: fr.GetIgnore()}
+	for _, rule := range fr.GetCel() {
+		p, err := compileCustom(env, valueType(fd), rule)
+		if err != nil {
+			return nil, err
+		}
+		r.programs = append(r.programs, p)
+	}
+	if typed := typeRules(fr); typed != nil {
+		if err := compileScalar(env, r, fd, typed); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// compileScalar compiles the type rules typed of a single value of fd's
+// type, which they must match
+func compileScalar(env *cel.Env, r *valueRules, fd protoreflect.FieldDescriptor, typed protoreflect.Message) error {
+	name := typed.Descriptor().Name()
+	switch want := rulesFor(fd); want {
+	case name:
+	case "":
+		return fmt.Errorf("%s rules on a field that takes none", name)
+	default:
+		return fmt.Errorf("%s rules on a field that takes %s", name, want)
+	}
+
+	switch rules := typed.Interface().(type) {
+	case *validate.EnumRules:
+		if rules.GetDefinedOnly() {
+			r.definedOnly = fd.Enum()
+		}
+	case *validate.AnyRules:
+		r.anyIn, r.anyNotIn = rules.GetIn(), rules.GetNotIn()
+	}
+	return compileTypeRules(env, r, valueType(fd), typed.Interface())
+}
+
+// compileTypeRules compiles the standard rules set in rules, such as the
+// min_len of StringRules, by their (buf.validate.predefined) expressions,
+// on values of type this
+func compileTypeRules(env *cel.Env, r *valueRules, this *cel.Type, rules proto.Message) error {
+	r.rules = rules
+	var err error
+	rules.ProtoReflect().Range(func(fd protoreflect.FieldDescriptor, value protoreflect.Value) bool {
+		predefined, _ := proto.GetExtension(fd.Options(), validate.E_Predefined).(*validate.PredefinedRules)
+		if len(predefined.GetCel()) == 0 {
+			// Rules without expressions, such as items, are applied in code
+			return true
+		}
+
+		var ruleEnv *cel.Env
+		ruleEnv, err = env.Extend(
+			cel.Variable("this", this),
+			cel.Variable("rules", cel.ObjectType(string(rules.ProtoReflect().Descriptor().FullName()))),
+			cel.Variable("rule", fieldType(fd)),
+		)
+		if err != nil {
+			return false
+		}
+		for _, rule := range predefined.GetCel() {
+			var p *program
+			if p, err = compile(ruleEnv, rule); err != nil {
+				return false
+			}
+			p.rule = celValue(fd, value)
+			r.programs = append(r.programs, p)
+		}
+		return true
+	})
+	return err
+}
+
+// compileCustom compiles a custom rule on values of type this
+func compileCustom(env *cel.Env, this *cel.Type, rule *validate.Rule) (*program, error) {
+	ruleEnv, err := env.Extend(cel.Variable("this", this))
+	if err != nil {
+		return nil, err
+	}
+	return compile(ruleEnv, rule)
+}
+
+// compile compiles rule, whose expression must return a bool or a string
+func compile(env *cel.Env, rule *validate.Rule) (*program, error) {
+	ast, issues := env.Compile(rule.GetExpression())
+	if issues.Err() != nil {
+		return nil, fmt.Errorf("rule %s: %w", rule.GetId(), issues.Err())
+	}
+	if out := ast.OutputType(); !out.IsExactType(cel.BoolType) && !out.IsExactType(cel.StringType) {
+		return nil, fmt.Errorf("rule %s returns %s, not a bool or string", rule.GetId(), out)
+	}
+
+	prg, err := env.Program(ast)
+	if err != nil {
+		return nil, fmt.Errorf("rule %s: %w", rule.GetId(), err)
+	}
+
+	message := rule.GetMessage()
+	if message == "" {
+		message = fmt.Sprintf("%q returned false", rule.GetExpression())
+	}
+	return &program{id: rule.GetId(), message: message, prg: prg}, nil
+}
+
+// typeRules returns the type rules set in fr, such as its StringRules, or
+// nil
+func typeRules(fr *validate.FieldRules) protoreflect.Message {
+	m := fr.ProtoReflect()
+	fd := m.WhichOneof(m.Descriptor().Oneofs().ByName("type"))
+	if fd == nil {
+		return nil
+	}
+	return m.Get(fd).Message()
+}
+
+// rulesFor returns the name of the type rules message for values of fd's
+// type, or "" if there is none
+func rulesFor(fd protoreflect.FieldDescriptor) protoreflect.Name {
+	if msg := fd.Message(); msg != nil {
+		switch msg.FullName() {
+		case "google.protobuf.Duration":
+			return "DurationRules"
+		case "google.protobuf.Timestamp":
+			return "TimestampRules"
+		case "google.protobuf.Any":
+			return "AnyRules"
+		}
+		if wrapped := unwrap(msg); wrapped != nil {
+			return rulesFor(wrapped)
+		}
+		return ""
+	}
+
+	switch fd.Kind() {
+	case protoreflect.FloatKind:
+		return "FloatRules"
+	case protoreflect.DoubleKind:
+		return "DoubleRules"
+	case protoreflect.Int32Kind:
+		return "Int32Rules"
+	case protoreflect.Int64Kind:
+		return "Int64Rules"
+	case protoreflect.Uint32Kind:
+		return "UInt32Rules"
+	case protoreflect.Uint64Kind:
+		return "UInt64Rules"
+	case protoreflect.Sint32Kind:
+		return "SInt32Rules"
+	case protoreflect.Sint64Kind:
+		return "SInt64Rules"
+	case protoreflect.Fixed32Kind:
+		return "Fixed32Rules"
+	case protoreflect.Fixed64Kind:
+		return "Fixed64Rules"
+	case protoreflect.Sfixed32Kind:
+		return "SFixed32Rules"
+	case protoreflect.Sfixed64Kind:
+		return "SFixed64Rules"
+	case protoreflect.BoolKind:
+		return "BoolRules"
+	case protoreflect.StringKind:
+		return "StringRules"
+	case protoreflect.BytesKind:
+		return "BytesRules"
+	case protoreflect.EnumKind:
+		return "EnumRules"
+	}
+	return ""
+}
+
+// unwrap returns the value field of a google.protobuf wrapper type, such
+// as StringValue, or nil
+func unwrap(msg protoreflect.MessageDescriptor) protoreflect.FieldDescriptor {
+	if !isWellKnown(msg) || !strings.HasSuffix(string(msg.Name()), "Value") || msg.Fields().Len() != 1 {
+		return nil
+	}
+	return msg.Fields().ByName("value")
+}
@@ -0,0 +1,37 @@
+package protovalidate
+
+import "strings"
+
+// Violation is a rule a field, or a message, broke
+type Violation struct {
+	// Field is the path of the field, such as metadata.values["theme"], or
+	// empty for a rule on the message itself
+	Field string
+	// RuleID is the ID of the rule, such as string.email
+	RuleID string
+	// Message describes the violation, such as "value must be a valid email
+	// address"
+	Message string
+}
+
+// ValidationError is returned by Validate for a message breaking its rules
+type ValidationError struct {
+	Violations []*Violation
+}
+
+func (e *ValidationError) Error() string {
+	var b strings.Builder
+	b.WriteString("validation error:")
+	for _, v := range e.Violations {
+		b.WriteString("\n - ")
+		if v.Field != "" {
+			b.WriteString(v.Field)
+			b.WriteString(": ")
+		}
+		b.WriteString(v.Message)
+		b.WriteString(" [")
+		b.WriteString(v.RuleID)
+		b.WriteString("]")
+	}
+	return b.String()
+}
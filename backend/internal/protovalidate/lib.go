@@ -0,0 +1,237 @@
+package protovalidate
+
+import (
+	"bytes"
+	"math"
+	"net/netip"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	"github.com/google/cel-go/common/types/traits"
+	"github.com/google/cel-go/ext"
+)
+
+// emailRegex is the WHATWG definition of a valid email address, the one
+// protovalidate's isEmail uses
+var emailRegex = regexp.MustCompile(`^[a-zA-Z0-9.!#$%&'*+/=?^_` + "`" + `{|}~-]+@[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(?:\.[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*$`)
+
+// library returns the CEL functions the buf.validate rules are written
+// with, beyond the standard ones
+func library() []cel.EnvOption {
+	return []cel.EnvOption{
+		ext.Strings(),
+		cel.Function("getField",
+			cel.Overload("get_field_any_string", []*cel.Type{cel.DynType, cel.StringType}, cel.DynType,
+				cel.BinaryBinding(getField))),
+		cel.Function("isNan",
+			cel.MemberOverload("double_is_nan_bool", []*cel.Type{cel.DoubleType}, cel.BoolType,
+				cel.UnaryBinding(func(v ref.Val) ref.Val {
+					return types.Bool(math.IsNaN(float64(v.(types.Double))))
+				}))),
+		cel.Function("isInf",
+			cel.MemberOverload("double_is_inf_bool", []*cel.Type{cel.DoubleType}, cel.BoolType,
+				cel.UnaryBinding(func(v ref.Val) ref.Val {
+					return types.Bool(math.IsInf(float64(v.(types.Double)), 0))
+				})),
+			cel.MemberOverload("double_int_is_inf_bool", []*cel.Type{cel.DoubleType, cel.IntType}, cel.BoolType,
+				cel.BinaryBinding(func(v, sign ref.Val) ref.Val {
+					return types.Bool(math.IsInf(float64(v.(types.Double)), int(sign.(types.Int))))
+				}))),
+		cel.Function("isHostname",
+			cel.MemberOverload("string_is_hostname_bool", []*cel.Type{cel.StringType}, cel.BoolType,
+				cel.UnaryBinding(func(v ref.Val) ref.Val {
+					return types.Bool(isHostname(string(v.(types.String))))
+				}))),
+		cel.Function("isEmail",
+			cel.MemberOverload("string_is_email_bool", []*cel.Type{cel.StringType}, cel.BoolType,
+				cel.UnaryBinding(func(v ref.Val) ref.Val {
+					return types.Bool(emailRegex.MatchString(string(v.(types.String))))
+				}))),
+		cel.Function("isIp",
+			cel.MemberOverload("string_is_ip_bool", []*cel.Type{cel.StringType}, cel.BoolType,
+				cel.UnaryBinding(func(v ref.Val) ref.Val {
+					return types.Bool(isIP(string(v.(types.String)), 0))
+				})),
+			cel.MemberOverload("string_int_is_ip_bool", []*cel.Type{cel.StringType, cel.IntType}, cel.BoolType,
+				cel.BinaryBinding(func(v, version ref.Val) ref.Val {
+					return types.Bool(isIP(string(v.(types.String)), int64(version.(types.Int))))
+				}))),
+		cel.Function("isIpPrefix",
+			cel.MemberOverload("string_is_ip_prefix_bool", []*cel.Type{cel.StringType}, cel.BoolType,
+				cel.UnaryBinding(func(v ref.Val) ref.Val {
+					return types.Bool(isIPPrefix(string(v.(types.String)), 0, false))
+				})),
+			cel.MemberOverload("string_int_is_ip_prefix_bool", []*cel.Type{cel.StringType, cel.IntType}, cel.BoolType,
+				cel.BinaryBinding(func(v, version ref.Val) ref.Val {
+					return types.Bool(isIPPrefix(string(v.(types.String)), int64(version.(types.Int)), false))
+				})),
+			cel.MemberOverload("string_bool_is_ip_prefix_bool", []*cel.Type{cel.StringType, cel.BoolType}, cel.BoolType,
+				cel.BinaryBinding(func(v, strict ref.Val) ref.Val {
+					return types.Bool(isIPPrefix(string(v.(types.String)), 0, bool(strict.(types.Bool))))
+				})),
+			cel.MemberOverload("string_int_bool_is_ip_prefix_bool", []*cel.Type{cel.StringType, cel.IntType, cel.BoolType}, cel.BoolType,
+				cel.FunctionBinding(func(args ...ref.Val) ref.Val {
+					return types.Bool(isIPPrefix(string(args[0].(types.String)), int64(args[1].(types.Int)), bool(args[2].(types.Bool))))
+				}))),
+		cel.Function("isUri",
+			cel.MemberOverload("string_is_uri_bool", []*cel.Type{cel.StringType}, cel.BoolType,
+				cel.UnaryBinding(func(v ref.Val) ref.Val {
+					u, err := url.Parse(string(v.(types.String)))
+					return types.Bool(err == nil && u.IsAbs())
+				}))),
+		cel.Function("isUriRef",
+			cel.MemberOverload("string_is_uri_ref_bool", []*cel.Type{cel.StringType}, cel.BoolType,
+				cel.UnaryBinding(func(v ref.Val) ref.Val {
+					_, err := url.Parse(string(v.(types.String)))
+					return types.Bool(err == nil)
+				}))),
+		cel.Function("isHostAndPort",
+			cel.MemberOverload("string_bool_is_host_and_port_bool", []*cel.Type{cel.StringType, cel.BoolType}, cel.BoolType,
+				cel.BinaryBinding(func(v, portRequired ref.Val) ref.Val {
+					return types.Bool(isHostAndPort(string(v.(types.String)), bool(portRequired.(types.Bool))))
+				}))),
+		cel.Function("unique",
+			cel.MemberOverload("list_unique_bool", []*cel.Type{cel.ListType(cel.TypeParamType("T"))}, cel.BoolType,
+				cel.UnaryBinding(unique))),
+		cel.Function("contains",
+			cel.MemberOverload("bytes_contains_bytes", []*cel.Type{cel.BytesType, cel.BytesType}, cel.BoolType,
+				cel.BinaryBinding(func(v, sub ref.Val) ref.Val {
+					return types.Bool(bytes.Contains(v.(types.Bytes), sub.(types.Bytes)))
+				}))),
+		cel.Function("startsWith",
+			cel.MemberOverload("bytes_starts_with_bytes", []*cel.Type{cel.BytesType, cel.BytesType}, cel.BoolType,
+				cel.BinaryBinding(func(v, prefix ref.Val) ref.Val {
+					return types.Bool(bytes.HasPrefix(v.(types.Bytes), prefix.(types.Bytes)))
+				}))),
+		cel.Function("endsWith",
+			cel.MemberOverload("bytes_ends_with_bytes", []*cel.Type{cel.BytesType, cel.BytesType}, cel.BoolType,
+				cel.BinaryBinding(func(v, suffix ref.Val) ref.Val {
+					return types.Bool(bytes.HasSuffix(v.(types.Bytes), suffix.(types.Bytes)))
+				}))),
+	}
+}
+
+// getField returns the field of a message named name. The rules use it for
+// fields named after CEL keywords, such as const and in.
+func getField(msg, name ref.Val) ref.Val {
+	indexer, ok := msg.(traits.Indexer)
+	if !ok {
+		return types.NewErr("getField: %s is not a message", msg.Type())
+	}
+	return indexer.Get(name)
+}
+
+// unique reports whether no two items of a list are equal
+func unique(list ref.Val) ref.Val {
+	lister, ok := list.(traits.Lister)
+	if !ok {
+		return types.NewErr("unique: %s is not a list", list.Type())
+	}
+
+	size := int(lister.Size().(types.Int))
+	for i := 0; i < size; i++ {
+		item := lister.Get(types.Int(i))
+		for j := i + 1; j < size; j++ {
+			if item.Equal(lister.Get(types.Int(j))) == types.True {
+				return types.False
+			}
+		}
+	}
+	return types.True
+}
+
+// isHostname reports whether s is a valid hostname: dot-separated labels of
+// letters, digits and inner hyphens, the last of which is not all digits,
+// optionally ending in a dot
+func isHostname(s string) bool {
+	s = strings.TrimSuffix(s, ".")
+	if s == "" || len(s) > 253 {
+		return false
+	}
+
+	labels := strings.Split(s, ".")
+	for _, label := range labels {
+		if label == "" || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+		for _, char := range label {
+			if !((char >= 'a' && char <= 'z') ||
+				(char >= 'A' && char <= 'Z') ||
+				(char >= '0' && char <= '9') ||
+				char == '-') {
+				return false
+			}
+		}
+	}
+
+	_, err := strconv.Atoi(labels[len(labels)-1])
+	return err != nil
+}
+
+// isIP reports whether s is an IP address of version, or of either version
+// if it is 0
+func isIP(s string, version int64) bool {
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return false
+	}
+	return matchesVersion(addr, version)
+}
+
+// isIPPrefix reports whether s is an IP address with a prefix length, of
+// version or of either if it is 0. If strict, the address must be the
+// network address, with no host bits set.
+func isIPPrefix(s string, version int64, strict bool) bool {
+	prefix, err := netip.ParsePrefix(s)
+	if err != nil || !matchesVersion(prefix.Addr(), version) {
+		return false
+	}
+	return !strict || prefix.Masked() == prefix
+}
+
+func matchesVersion(addr netip.Addr, version int64) bool {
+	switch version {
+	case 0:
+		return true
+	case 4:
+		return addr.Is4()
+	case 6:
+		return addr.Is6() && !addr.Is4In6()
+	default:
+		return false
+	}
+}
+
+// isHostAndPort reports whether s is a hostname or IP address, IPv6
+// addresses in brackets, followed by a port if portRequired, or optionally
+// if not
+func isHostAndPort(s string, portRequired bool) bool {
+	host, port := s, ""
+	if i := strings.LastIndexByte(s, ':'); i >= 0 && !strings.HasSuffix(s, "]") {
+		host, port = s[:i], s[i+1:]
+		if !isPort(port) {
+			return false
+		}
+	} else if portRequired {
+		return false
+	}
+
+	if strings.HasPrefix(host, "[") && strings.HasSuffix(host, "]") {
+		return isIP(host[1:len(host)-1], 6)
+	}
+	return isHostname(host) || isIP(host, 4)
+}
+
+// isPort reports whether s is a port number, without leading zeros
+func isPort(s string) bool {
+	if s == "" || (len(s) > 1 && s[0] == '0') {
+		return false
+	}
+	port, err := strconv.ParseUint(s, 10, 16)
+	return err == nil && port <= math.MaxUint16
+}
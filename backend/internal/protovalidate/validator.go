@@ -0,0 +1,374 @@
+// Package protovalidate checks messages against the buf.validate rules
+// declared in their proto files, such as
+//
+//	string email = 1 [(buf.validate.field).string.email = true];
+//
+// Standard rules are evaluated by the CEL expressions buf/validate/validate.proto
+// defines for them, as buf.build/go/protovalidate does, so a rule means the
+// same here as in every protovalidate implementation, and its violations
+// carry the same rule IDs and messages. Custom CEL rules on fields, oneofs
+// and messages are supported too.
+package protovalidate
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"buf.build/gen/go/bufbuild/protovalidate/protocolbuffers/go/buf/validate"
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Validator validates messages. The rules of a message type are compiled
+// the first time a message of it is validated, and reused after.
+type Validator struct {
+	env *cel.Env
+
+	mu       sync.RWMutex
+	messages map[protoreflect.FullName]*messageRules
+}
+
+// Option configures a Validator
+type Option func(*options)
+
+type options struct {
+	descriptors []protoreflect.MessageDescriptor
+}
+
+// WithMessageDescriptors compiles the rules of descs, and of the messages
+// their fields hold, in New, so an invalid rule fails there rather than at
+// the first message of its type
+func WithMessageDescriptors(descs ...protoreflect.MessageDescriptor) Option {
+	return func(o *options) {
+		o.descriptors = append(o.descriptors, descs...)
+	}
+}
+
+// New creates a validator
+func New(opts ...Option) (*Validator, error) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	env, err := cel.NewEnv(append(library(),
+		cel.TypeDescs(validate.File_buf_validate_validate_proto),
+		cel.Variable("now", cel.TimestampType),
+	)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CEL environment: %w", err)
+	}
+
+	v := &Validator{env: env, messages: make(map[protoreflect.FullName]*messageRules)}
+	seen := make(map[protoreflect.FullName]bool)
+	for _, desc := range o.descriptors {
+		if err := v.precompile(desc, seen); err != nil {
+			return nil, err
+		}
+	}
+	return v, nil
+}
+
+// precompile compiles the rules of desc and of the messages its fields
+// hold, skipping those in seen
+func (v *Validator) precompile(desc protoreflect.MessageDescriptor, seen map[protoreflect.FullName]bool) error {
+	if seen[desc.FullName()] {
+		return nil
+	}
+	seen[desc.FullName()] = true
+
+	if _, err := v.rules(desc); err != nil {
+		return err
+	}
+	fields := desc.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		if fd.IsMap() {
+			fd = fd.MapValue()
+		}
+		if fd.Message() != nil {
+			if err := v.precompile(fd.Message(), seen); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Validate checks msg against its rules, and those of the messages it
+// holds. It returns a *ValidationError listing the rules broken, or another
+// error if the rules can't be compiled or evaluated.
+func (v *Validator) Validate(msg proto.Message) error {
+	e := &evaluation{now: time.Now()}
+	if err := v.validateMessage(e, msg.ProtoReflect(), ""); err != nil {
+		return err
+	}
+	if len(e.violations) > 0 {
+		return &ValidationError{Violations: e.violations}
+	}
+	return nil
+}
+
+var (
+	defaultValidator     *Validator
+	defaultValidatorErr  error
+	defaultValidatorOnce sync.Once
+)
+
+// Validate checks msg with a validator shared by the process; see
+// Validator.Validate
+func Validate(msg proto.Message) error {
+	defaultValidatorOnce.Do(func() {
+		defaultValidator, defaultValidatorErr = New()
+	})
+	if defaultValidatorErr != nil {
+		return defaultValidatorErr
+	}
+	return defaultValidator.Validate(msg)
+}
+
+// evaluation is the state of one Validate call
+type evaluation struct {
+	now        time.Time
+	violations []*Violation
+}
+
+func (e *evaluation) add(field, ruleID, message string) {
+	e.violations = append(e.violations, &Violation{Field: field, RuleID: ruleID, Message: message})
+}
+
+// rules returns the compiled rules of desc, compiling them if needed
+func (v *Validator) rules(desc protoreflect.MessageDescriptor) (*messageRules, error) {
+	v.mu.RLock()
+	rules, ok := v.messages[desc.FullName()]
+	v.mu.RUnlock()
+	if ok {
+		return rules, nil
+	}
+
+	rules, err := v.compileMessage(desc)
+	if err != nil {
+		return nil, fmt.Errorf("invalid validation rules of %s: %w", desc.FullName(), err)
+	}
+
+	v.mu.Lock()
+	v.messages[desc.FullName()] = rules
+	v.mu.Unlock()
+	return rules, nil
+}
+
+// validateMessage checks m, at path, against its rules
+func (v *Validator) validateMessage(e *evaluation, m protoreflect.Message, path string) error {
+	rules, err := v.rules(m.Descriptor())
+	if err != nil {
+		return err
+	}
+
+	for _, p := range rules.cel {
+		if err := p.eval(e, path, m.Interface(), nil); err != nil {
+			return err
+		}
+	}
+
+	for _, od := range rules.requiredOneofs {
+		if m.WhichOneof(od) == nil {
+			e.add(join(path, string(od.Name())), "required", "exactly one field is required in oneof")
+		}
+	}
+
+	for _, oneof := range rules.oneofs {
+		set := 0
+		for _, fd := range oneof.fields {
+			if m.Has(fd) {
+				set++
+			}
+		}
+		switch {
+		case set > 1:
+			e.add(path, "message.oneof", fmt.Sprintf("only one of %s can be set", oneof.names))
+		case set == 0 && oneof.required:
+			e.add(path, "message.oneof", fmt.Sprintf("one of %s must be set", oneof.names))
+		}
+	}
+
+	for _, f := range rules.fields {
+		if err := v.validateField(e, m, f, join(path, string(f.desc.Name()))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateField checks the field of m described by f, at path
+func (v *Validator) validateField(e *evaluation, m protoreflect.Message, f *fieldRules, path string) error {
+	fd := f.desc
+	if f.ignore == validate.Ignore_IGNORE_ALWAYS {
+		return nil
+	}
+
+	// Has reports scalars without presence as unset when they are zero, and
+	// lists and maps when they are empty
+	if !m.Has(fd) {
+		if f.required {
+			e.add(path, "required", "value is required")
+			return nil
+		}
+		// Rules apply to the zero value of a field without presence, unless
+		// told to ignore it
+		if fd.HasPresence() || f.ignore == validate.Ignore_IGNORE_IF_ZERO_VALUE {
+			return nil
+		}
+	}
+	value := m.Get(fd)
+
+	switch {
+	case fd.IsList():
+		list := value.List()
+		if err := f.value.evalRules(e, path, listValue(list, fd)); err != nil {
+			return err
+		}
+		for i := 0; i < list.Len(); i++ {
+			if err := v.validateValue(e, f.items, fd, list.Get(i), fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case fd.IsMap():
+		if err := f.value.evalRules(e, path, mapValue(value.Map(), fd)); err != nil {
+			return err
+		}
+		var err error
+		sortedRange(value.Map(), func(key protoreflect.MapKey, val protoreflect.Value) bool {
+			entryPath := path + "[" + formatKey(key) + "]"
+			if err = v.validateValue(e, f.keys, fd.MapKey(), key.Value(), entryPath); err != nil {
+				return false
+			}
+			err = v.validateValue(e, f.items, fd.MapValue(), val, entryPath)
+			return err == nil
+		})
+		return err
+
+	default:
+		return v.validateValue(e, &f.value, fd, value, path)
+	}
+}
+
+// validateValue checks a single value of the field fd, at path, against
+// rules, and a message against its own rules as well
+func (v *Validator) validateValue(e *evaluation, rules *valueRules, fd protoreflect.FieldDescriptor, value protoreflect.Value, path string) error {
+	if rules != nil {
+		if rules.ignore == validate.Ignore_IGNORE_ALWAYS {
+			return nil
+		}
+		if rules.ignore == validate.Ignore_IGNORE_IF_ZERO_VALUE && isZero(fd, value) {
+			return nil
+		}
+
+		if rules.definedOnly != nil && rules.definedOnly.Values().ByNumber(value.Enum()) == nil {
+			e.add(path, "enum.defined_only", "value must be one of the defined enum values")
+		}
+		if len(rules.anyIn) > 0 || len(rules.anyNotIn) > 0 {
+			any := value.Message()
+			typeURL := any.Get(any.Descriptor().Fields().ByName("type_url")).String()
+			if len(rules.anyIn) > 0 && !slices.Contains(rules.anyIn, typeURL) {
+				e.add(path, "any.in", "type URL must be in the allow list")
+			}
+			if slices.Contains(rules.anyNotIn, typeURL) {
+				e.add(path, "any.not_in", "type URL must not be in the block list")
+			}
+		}
+		if err := rules.evalRules(e, path, scalarValue(fd, value)); err != nil {
+			return err
+		}
+	}
+
+	if fd.Message() != nil && !isWellKnown(fd.Message()) {
+		return v.validateMessage(e, value.Message(), path)
+	}
+	return nil
+}
+
+// evalRules evaluates the CEL rules of r on this
+func (r *valueRules) evalRules(e *evaluation, path string, this any) error {
+	if r == nil {
+		return nil
+	}
+	for _, p := range r.programs {
+		if err := p.eval(e, path, this, r.rules); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// eval evaluates a rule on this, adding a violation if it fails. A rule
+// fails if it returns false or a non-empty message.
+func (p *program) eval(e *evaluation, path string, this any, rules proto.Message) error {
+	vars := map[string]any{"this": this, "now": e.now}
+	if rules != nil {
+		vars["rules"] = rules
+		vars["rule"] = p.rule
+	}
+
+	out, _, err := p.prg.Eval(vars)
+	if err != nil {
+		return fmt.Errorf("failed to evaluate rule %s of %s: %w", p.id, cmp.Or(path, "message"), err)
+	}
+	switch out := out.(type) {
+	case types.String:
+		if out != "" {
+			e.add(path, p.id, string(out))
+		}
+	case types.Bool:
+		if !out {
+			e.add(path, p.id, p.message)
+		}
+	}
+	return nil
+}
+
+// join adds the field name to path
+func join(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// formatKey formats a map key as it appears in a field path
+func formatKey(key protoreflect.MapKey) string {
+	if s, ok := key.Interface().(string); ok {
+		return strconv.Quote(s)
+	}
+	return key.String()
+}
+
+// sortedRange calls f for each entry of m, in key order, so violations are
+// reported in the same order every time
+func sortedRange(m protoreflect.Map, f func(protoreflect.MapKey, protoreflect.Value) bool) {
+	keys := make([]protoreflect.MapKey, 0, m.Len())
+	m.Range(func(key protoreflect.MapKey, _ protoreflect.Value) bool {
+		keys = append(keys, key)
+		return true
+	})
+	sortKeys(keys)
+	for _, key := range keys {
+		if !f(key, m.Get(key)) {
+			return
+		}
+	}
+}
+
+// isWellKnown reports whether desc is one of the google.protobuf types,
+// which have no rules of their own
+func isWellKnown(desc protoreflect.MessageDescriptor) bool {
+	return strings.HasPrefix(string(desc.FullName()), "google.protobuf.")
+}
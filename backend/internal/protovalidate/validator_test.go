@@ -0,0 +1,194 @@
+package protovalidate
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	pb "github.com/sahays/grpc-proto-go-flutter-template/proto"
+	adminpb "github.com/sahays/grpc-proto-go-flutter-template/proto/admin"
+	chatpb "github.com/sahays/grpc-proto-go-flutter-template/proto/chat"
+)
+
+func newTestValidator(t *testing.T) *Validator {
+	t.Helper()
+
+	v, err := New(WithMessageDescriptors(
+		(&pb.SignUpRequest{}).ProtoReflect().Descriptor(),
+		(&pb.UpdateProfileRequest{}).ProtoReflect().Descriptor(),
+		(&adminpb.DenyIPRequest{}).ProtoReflect().Descriptor(),
+		(&chatpb.ChatRequest{}).ProtoReflect().Descriptor(),
+	))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return v
+}
+
+// validProfile returns an UpdateProfileRequest breaking no rules
+func validProfile() *pb.UpdateProfileRequest {
+	return &pb.UpdateProfileRequest{FirstName: "Ada", LastName: "Lovelace", Version: 1}
+}
+
+func TestValidate(t *testing.T) {
+	v := newTestValidator(t)
+
+	tests := []struct {
+		name string
+		msg  proto.Message
+		// want are the violations, as "field: message [rule id]"
+		want []string
+	}{
+		{
+			name: "valid",
+			msg:  &pb.SignUpRequest{Email: "ada@example.com", Password: "correct horse", FirstName: "Ada", LastName: "Lovelace"},
+		},
+		{
+			name: "required string short-circuits its other rules",
+			msg:  &pb.SignUpRequest{Password: "correct horse", FirstName: "Ada", LastName: "Lovelace"},
+			want: []string{"email: value is required [required]"},
+		},
+		{
+			name: "string rules",
+			msg:  &pb.SignUpRequest{Email: "ada", Password: "short", FirstName: "Ada!", LastName: "L"},
+			want: []string{
+				"email: value must be a valid email address [string.email]",
+				"password: value length must be at least 8 characters [string.min_len]",
+				"first_name: value does not match regex pattern `^[a-zA-Z '-]+$` [string.pattern]",
+				"last_name: value length must be at least 2 characters [string.min_len]",
+			},
+		},
+		{
+			name: "lengths count characters",
+			msg: func() proto.Message {
+				req := validProfile()
+				req.Metadata = &pb.ProfileMetadata{Values: map[string]string{"bio": strings.Repeat("é", 512)}}
+				return req
+			}(),
+		},
+		{
+			name: "int64 gt",
+			msg:  &pb.UpdateProfileRequest{FirstName: "Ada", LastName: "Lovelace"},
+			want: []string{"version: value must be greater than 0 [int64.gt]"},
+		},
+		{
+			name: "optional string unset",
+			msg:  validProfile(),
+		},
+		{
+			name: "optional string set",
+			msg: func() proto.Message {
+				req := validProfile()
+				req.PhoneNumber = proto.String("555")
+				return req
+			}(),
+			want: []string{"phone_number: value does not match regex pattern `^(\\+[1-9][0-9]{6,14})?$` [string.pattern]"},
+		},
+		{
+			name: "map rules",
+			msg: func() proto.Message {
+				req := validProfile()
+				req.Metadata = &pb.ProfileMetadata{Values: map[string]string{
+					"":                      "empty key",
+					"theme":                 "dark",
+					strings.Repeat("k", 65): "long key",
+					"bio":                   strings.Repeat("v", 513),
+				}}
+				return req
+			}(),
+			want: []string{
+				`metadata.values[""]: value length must be at least 1 characters [string.min_len]`,
+				`metadata.values["bio"]: value length must be at most 512 characters [string.max_len]`,
+				`metadata.values["` + strings.Repeat("k", 65) + `"]: value length must be at most 64 characters [string.max_len]`,
+			},
+		},
+		{
+			name: "map max_pairs",
+			msg: func() proto.Message {
+				req := validProfile()
+				req.Metadata = &pb.ProfileMetadata{Values: make(map[string]string)}
+				for i := 0; i < 21; i++ {
+					req.Metadata.Values[strings.Repeat("k", i+1)] = "v"
+				}
+				return req
+			}(),
+			want: []string{"metadata.values: map must be at most 20 entries [map.max_pairs]"},
+		},
+		{
+			name: "duration unset",
+			msg:  &adminpb.DenyIPRequest{Cidr: "203.0.113.7"},
+		},
+		{
+			name: "duration gt",
+			msg:  &adminpb.DenyIPRequest{Cidr: "203.0.113.7", Ttl: durationpb.New(-time.Minute)},
+			want: []string{"ttl: value must be greater than 0s [duration.gt]"},
+		},
+		{
+			name: "required oneof",
+			msg:  &chatpb.ChatRequest{},
+			want: []string{"payload: exactly one field is required in oneof [required]"},
+		},
+		{
+			name: "nested message",
+			msg:  &chatpb.ChatRequest{Payload: &chatpb.ChatRequest_Join{Join: &chatpb.JoinRoom{Room: "lobby!"}}},
+			want: []string{"join.room: value does not match regex pattern `^[a-zA-Z0-9_-]+$` [string.pattern]"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := v.Validate(tt.msg)
+			if tt.want == nil {
+				if err != nil {
+					t.Fatalf("Validate: %v", err)
+				}
+				return
+			}
+
+			var validationErr *ValidationError
+			if !errors.As(err, &validationErr) {
+				t.Fatalf("Validate error = %v, want a *ValidationError", err)
+			}
+			var got []string
+			for _, violation := range validationErr.Violations {
+				got = append(got, violation.Field+": "+violation.Message+" ["+violation.RuleID+"]")
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("violations = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidationErrorString(t *testing.T) {
+	err := &ValidationError{Violations: []*Violation{
+		{Field: "email", RuleID: "string.email", Message: "value must be a valid email address"},
+		{RuleID: "message.oneof", Message: "one of a, b must be set"},
+	}}
+
+	want := "validation error:\n - email: value must be a valid email address [string.email]\n - one of a, b must be set [message.oneof]"
+	if got := err.Error(); got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+}
+
+// TestServiceRules compiles the rules of every message in the service proto
+// files, as the server does at startup
+func TestServiceRules(t *testing.T) {
+	var descs []protoreflect.MessageDescriptor
+	for _, file := range []protoreflect.FileDescriptor{pb.File_auth_proto, adminpb.File_admin_admin_proto, chatpb.File_chat_chat_proto} {
+		messages := file.Messages()
+		for i := 0; i < messages.Len(); i++ {
+			descs = append(descs, messages.Get(i))
+		}
+	}
+
+	if _, err := New(WithMessageDescriptors(descs...)); err != nil {
+		t.Fatalf("New: %v", err)
+	}
+}
@@ -0,0 +1,162 @@
+package protovalidate
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+
+	"github.com/google/cel-go/cel"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// celValue converts v, the value of fd, to the Go value CEL is given for it
+func celValue(fd protoreflect.FieldDescriptor, v protoreflect.Value) any {
+	switch {
+	case fd.IsList():
+		return listValue(v.List(), fd)
+	case fd.IsMap():
+		return mapValue(v.Map(), fd)
+	default:
+		return scalarValue(fd, v)
+	}
+}
+
+// listValue converts the items of list, the value of fd
+func listValue(list protoreflect.List, fd protoreflect.FieldDescriptor) any {
+	items := make([]any, list.Len())
+	for i := range items {
+		items[i] = scalarValue(fd, list.Get(i))
+	}
+	return items
+}
+
+// mapValue converts the entries of m, the value of fd
+func mapValue(m protoreflect.Map, fd protoreflect.FieldDescriptor) any {
+	entries := make(map[any]any, m.Len())
+	m.Range(func(key protoreflect.MapKey, val protoreflect.Value) bool {
+		entries[scalarValue(fd.MapKey(), key.Value())] = scalarValue(fd.MapValue(), val)
+		return true
+	})
+	return entries
+}
+
+// scalarValue converts a single value of fd's type. Enums are ints to CEL,
+// and messages are converted by CEL itself, which unwraps the wrapper types.
+func scalarValue(fd protoreflect.FieldDescriptor, v protoreflect.Value) any {
+	switch fd.Kind() {
+	case protoreflect.EnumKind:
+		return int64(v.Enum())
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return v.Message().Interface()
+	default:
+		return v.Interface()
+	}
+}
+
+// fieldType returns the CEL type of fd's value: a list or map for repeated
+// and map fields
+func fieldType(fd protoreflect.FieldDescriptor) *cel.Type {
+	switch {
+	case fd.IsList():
+		return cel.ListType(valueType(fd))
+	case fd.IsMap():
+		return cel.MapType(valueType(fd.MapKey()), valueType(fd.MapValue()))
+	default:
+		return valueType(fd)
+	}
+}
+
+// valueType returns the CEL type of a single value of fd's type
+func valueType(fd protoreflect.FieldDescriptor) *cel.Type {
+	if msg := fd.Message(); msg != nil {
+		switch msg.FullName() {
+		case "google.protobuf.Duration":
+			return cel.DurationType
+		case "google.protobuf.Timestamp":
+			return cel.TimestampType
+		case "google.protobuf.Any":
+			return cel.AnyType
+		case "google.protobuf.Struct", "google.protobuf.Value", "google.protobuf.ListValue":
+			return cel.DynType
+		}
+		if wrapped := unwrap(msg); wrapped != nil {
+			return valueType(wrapped)
+		}
+		return cel.ObjectType(string(msg.FullName()))
+	}
+
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		return cel.BoolType
+	case protoreflect.EnumKind,
+		protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind,
+		protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return cel.IntType
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind,
+		protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return cel.UintType
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		return cel.DoubleType
+	case protoreflect.StringKind:
+		return cel.StringType
+	case protoreflect.BytesKind:
+		return cel.BytesType
+	}
+	return cel.DynType
+}
+
+// isZero reports whether v is the zero value of fd's type
+func isZero(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+	switch fd.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return !v.Message().IsValid() || isEmpty(v.Message())
+	case protoreflect.EnumKind:
+		return v.Enum() == 0
+	case protoreflect.BoolKind:
+		return !v.Bool()
+	case protoreflect.StringKind:
+		return v.String() == ""
+	case protoreflect.BytesKind:
+		return len(v.Bytes()) == 0
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		return v.Float() == 0
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind,
+		protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return v.Uint() == 0
+	default:
+		return v.Int() == 0
+	}
+}
+
+// isEmpty reports whether m has no fields set
+func isEmpty(m protoreflect.Message) bool {
+	empty := true
+	m.Range(func(protoreflect.FieldDescriptor, protoreflect.Value) bool {
+		empty = false
+		return false
+	})
+	return empty
+}
+
+// sortKeys sorts map keys, which are all of one type, in ascending order
+func sortKeys(keys []protoreflect.MapKey) {
+	slices.SortFunc(keys, func(a, b protoreflect.MapKey) int {
+		switch a.Interface().(type) {
+		case string:
+			return strings.Compare(a.String(), b.String())
+		case bool:
+			return cmp.Compare(boolInt(a.Bool()), boolInt(b.Bool()))
+		case int32, int64:
+			return cmp.Compare(a.Int(), b.Int())
+		default:
+			return cmp.Compare(a.Uint(), b.Uint())
+		}
+	})
+}
+
+func boolInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
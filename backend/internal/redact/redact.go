@@ -0,0 +1,75 @@
+// Package redact renders proto messages for logs and audit records with
+// sensitive fields masked. Fields are sensitive when marked
+// `[debug_redact = true]` in the proto or named in the extra field list.
+package redact
+
+import (
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// Mask replaces the value of redacted string fields
+const Mask = "[REDACTED]"
+
+// Redactor masks sensitive fields
+type Redactor struct {
+	names map[protoreflect.Name]bool
+}
+
+// New creates a redactor that also masks fields with the given proto names
+// (e.g. "ssn"), wherever they appear
+func New(extraFields []string) *Redactor {
+	names := make(map[protoreflect.Name]bool, len(extraFields))
+	for _, name := range extraFields {
+		names[protoreflect.Name(name)] = true
+	}
+	return &Redactor{names: names}
+}
+
+// JSON returns msg as JSON, using proto field names, with sensitive fields
+// masked. msg itself is not modified.
+func (r *Redactor) JSON(msg proto.Message) ([]byte, error) {
+	clone := proto.Clone(msg)
+	r.redact(clone.ProtoReflect())
+	return protojson.MarshalOptions{UseProtoNames: true}.Marshal(clone)
+}
+
+func (r *Redactor) redact(m protoreflect.Message) {
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		if r.sensitive(fd) {
+			// Keep string fields visible as present but masked; clear the rest
+			if fd.Kind() == protoreflect.StringKind && !fd.IsList() && !fd.IsMap() {
+				m.Set(fd, protoreflect.ValueOfString(Mask))
+			} else {
+				m.Clear(fd)
+			}
+			return true
+		}
+
+		switch {
+		case fd.IsList() && fd.Message() != nil:
+			list := v.List()
+			for i := 0; i < list.Len(); i++ {
+				r.redact(list.Get(i).Message())
+			}
+		case fd.IsMap() && fd.MapValue().Message() != nil:
+			v.Map().Range(func(_ protoreflect.MapKey, mv protoreflect.Value) bool {
+				r.redact(mv.Message())
+				return true
+			})
+		case !fd.IsList() && !fd.IsMap() && fd.Message() != nil:
+			r.redact(v.Message())
+		}
+		return true
+	})
+}
+
+func (r *Redactor) sensitive(fd protoreflect.FieldDescriptor) bool {
+	if r.names[fd.Name()] {
+		return true
+	}
+	opts, ok := fd.Options().(*descriptorpb.FieldOptions)
+	return ok && opts.GetDebugRedact()
+}
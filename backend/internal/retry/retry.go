@@ -0,0 +1,48 @@
+// Package retry waits out dependencies that are still starting, such as a
+// database container brought up alongside the server by docker-compose.
+package retry
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"time"
+
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/config"
+)
+
+// Do calls fn until it succeeds, cfg.MaxAttempts calls have failed, or ctx
+// is done, and returns fn's last error. name identifies the dependency in
+// log messages.
+func Do(ctx context.Context, cfg config.StartupRetryConfig, name string, fn func() error) error {
+	backoff := cfg.InitialBackoff
+
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= cfg.MaxAttempts {
+			return err
+		}
+
+		wait := jitter(backoff)
+		log.Printf("%s not ready (attempt %d/%d), retrying in %s: %v",
+			name, attempt, cfg.MaxAttempts, wait.Round(time.Millisecond), err)
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("%w (gave up waiting: %v)", err, ctx.Err())
+		case <-timer.C:
+		}
+
+		backoff = min(backoff*2, cfg.MaxBackoff)
+	}
+}
+
+// jitter returns a random duration between half of d and d, so instances
+// restarted together don't retry in lockstep
+func jitter(d time.Duration) time.Duration {
+	half := d / 2
+	return half + rand.N(d-half+1)
+}
@@ -0,0 +1,28 @@
+package seed
+
+// tenantNames are the demo tenants, created in order
+var tenantNames = []struct{ slug, name string }{
+	{"acme", "Acme Corporation"},
+	{"globex", "Globex"},
+	{"initech", "Initech"},
+	{"umbrella", "Umbrella"},
+	{"hooli", "Hooli"},
+	{"stark", "Stark Industries"},
+	{"wayne", "Wayne Enterprises"},
+	{"cyberdyne", "Cyberdyne Systems"},
+}
+
+var firstNames = []string{
+	"Aaliyah", "Amara", "Ana", "Ben", "Carlos", "Chen", "Chloe", "Daniel",
+	"Diego", "Elena", "Emma", "Fatima", "Grace", "Hannah", "Hiro", "Isabel",
+	"Ivan", "James", "Jin", "Kofi", "Layla", "Liam", "Lucas", "Maya",
+	"Mei", "Mohammed", "Nadia", "Noah", "Olivia", "Omar", "Priya", "Rafael",
+	"Ravi", "Sara", "Sofia", "Tariq", "Yara", "Yusuf", "Zoe", "Zara",
+}
+
+var lastNames = []string{
+	"Adeyemi", "Alvarez", "Andersen", "Brown", "Chen", "Costa", "Dubois", "Fischer",
+	"Garcia", "Gupta", "Haddad", "Ivanova", "Jensen", "Kim", "Kowalski", "Lee",
+	"Martin", "Mensah", "Meyer", "Nakamura", "Nguyen", "Novak", "Okafor", "Patel",
+	"Rossi", "Santos", "Schmidt", "Silva", "Singh", "Smith", "Tanaka", "Williams",
+}
@@ -0,0 +1,261 @@
+// Package seed fills a development database with a reproducible demo data
+// set: tenants, users with realistic names, and signed-in sessions. The same
+// seed always produces the same data, and seeding again adds nothing that is
+// already there.
+package seed
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/authz"
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/cache"
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/db"
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/models"
+)
+
+// Well-known accounts in the default tenant, for signing in right away
+const (
+	AdminEmail = "admin@example.com"
+	DemoEmail  = "demo@example.com"
+)
+
+// Options controls the size and content of the data set
+type Options struct {
+	// Seed makes the data set reproducible
+	Seed int64
+	// Tenants is the number of tenants created besides the default one
+	Tenants int
+	// UsersPerTenant is the number of generated users in each tenant,
+	// besides the well-known accounts
+	UsersPerTenant int
+	// Password is every seeded user's password
+	Password string
+	// SessionTTL is how long seeded sessions last
+	SessionTTL time.Duration
+}
+
+// DefaultOptions returns the options used when none are given
+func DefaultOptions() Options {
+	return Options{
+		Seed:           1,
+		Tenants:        3,
+		UsersPerTenant: 20,
+		Password:       "Password123!",
+		SessionTTL:     7 * 24 * time.Hour,
+	}
+}
+
+// MaxTenants is the most tenants Options.Tenants may ask for
+var MaxTenants = len(tenantNames)
+
+// Summary counts what a run created. Records that already existed are
+// counted as skipped.
+type Summary struct {
+	TenantsCreated int
+	UsersCreated   int
+	UsersSkipped   int
+	Sessions       int
+}
+
+// SessionStore stores sessions, implemented by *cache.Cache
+type SessionStore interface {
+	CreateSession(ctx context.Context, session *cache.Session) error
+}
+
+// Hasher hashes the seeded password, implemented by *password.Service
+type Hasher interface {
+	Hash(password string) (string, error)
+}
+
+// Seeder writes the demo data set
+type Seeder struct {
+	tenants  *models.TenantRepository
+	users    *models.UserRepository
+	sessions SessionStore
+	hasher   Hasher
+}
+
+// New creates a seeder
+func New(tenants *models.TenantRepository, users *models.UserRepository, sessions SessionStore, hasher Hasher) *Seeder {
+	return &Seeder{tenants: tenants, users: users, sessions: sessions, hasher: hasher}
+}
+
+// Run seeds the data set described by opts
+func (s *Seeder) Run(ctx context.Context, opts Options) (Summary, error) {
+	var summary Summary
+
+	if opts.Tenants < 0 || opts.Tenants > MaxTenants {
+		return summary, fmt.Errorf("tenants must be between 0 and %d", MaxTenants)
+	}
+	if opts.UsersPerTenant < 0 {
+		return summary, errors.New("users per tenant must not be negative")
+	}
+
+	// Every user shares one hash; Argon2 is too slow to run per user
+	passwordHash, err := s.hasher.Hash(opts.Password)
+	if err != nil {
+		return summary, fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	// Every draw happens whether or not its record already exists, so a
+	// seed always yields the same data
+	rng := rand.New(rand.NewSource(opts.Seed))
+	g := &generator{rng: rng, passwordHash: passwordHash}
+
+	tenantIDs := []string{db.DefaultTenantID}
+	domains := []string{"example.com"}
+	for _, t := range tenantNames[:opts.Tenants] {
+		tenant, created, err := s.ensureTenant(ctx, t.slug, t.name)
+		if err != nil {
+			return summary, err
+		}
+		if created {
+			summary.TenantsCreated++
+		}
+		tenantIDs = append(tenantIDs, tenant.ID)
+		domains = append(domains, t.slug+".example.com")
+	}
+
+	for i, tenantID := range tenantIDs {
+		tenantCtx := db.WithTenant(ctx, tenantID)
+
+		var users []*models.User
+		if tenantID == db.DefaultTenantID {
+			admin := g.user("Ada", "Admin", AdminEmail)
+			admin.Role = authz.RoleAdmin
+			users = append(users, admin, g.user("Demo", "User", DemoEmail))
+		}
+
+		seen := make(map[string]bool)
+		for range opts.UsersPerTenant {
+			users = append(users, g.randomUser(domains[i], seen))
+		}
+
+		sessions := make(map[*models.User][]string, len(users))
+		for _, user := range users {
+			sessions[user] = g.sessionIDs(user)
+		}
+
+		// Users whose ID or email is taken are skipped
+		created, err := s.users.CreateBatch(tenantCtx, users)
+		if err != nil {
+			return summary, err
+		}
+		summary.UsersCreated += len(created)
+		summary.UsersSkipped += len(users) - len(created)
+
+		for _, user := range created {
+			for j, tokenID := range sessions[user] {
+				now := time.Now()
+				session := &cache.Session{
+					ID:         tokenID,
+					UserID:     user.ID,
+					Device:     sessionDevices[j%len(sessionDevices)],
+					CreatedAt:  now,
+					LastSeenAt: now,
+					ExpiresAt:  now.Add(opts.SessionTTL),
+				}
+				if err := s.sessions.CreateSession(ctx, session); err != nil {
+					return summary, fmt.Errorf("failed to store session: %w", err)
+				}
+				summary.Sessions++
+			}
+			if len(sessions[user]) > 0 {
+				if err := s.users.UpdateLastLogin(tenantCtx, user.ID); err != nil {
+					return summary, err
+				}
+			}
+		}
+	}
+
+	return summary, nil
+}
+
+// ensureTenant creates a tenant unless its slug is taken, and reports
+// whether it did
+func (s *Seeder) ensureTenant(ctx context.Context, slug, name string) (*models.Tenant, bool, error) {
+	tenant, err := s.tenants.Create(ctx, slug, name)
+	if errors.Is(err, models.ErrSlugTaken) {
+		tenant, err = s.tenants.GetBySlug(ctx, slug)
+		return tenant, false, err
+	}
+	return tenant, err == nil, err
+}
+
+// generator draws users from a seeded source
+type generator struct {
+	rng          *rand.Rand
+	passwordHash string
+}
+
+// user returns an active, verified user with the given name and email
+func (g *generator) user(firstName, lastName, email string) *models.User {
+	return &models.User{
+		ID:           g.uuid(),
+		Email:        email,
+		PasswordHash: g.passwordHash,
+		FirstName:    firstName,
+		LastName:     lastName,
+		IsActive:     true,
+		IsVerified:   true,
+	}
+}
+
+// randomUser returns a user with a random name and an email at domain not
+// yet in seen. Most are active and verified; a few are not, or must reset
+// their password, so every state shows up in the data.
+func (g *generator) randomUser(domain string, seen map[string]bool) *models.User {
+	firstName := firstNames[g.rng.Intn(len(firstNames))]
+	lastName := lastNames[g.rng.Intn(len(lastNames))]
+
+	local := strings.ToLower(firstName + "." + lastName)
+	email := local + "@" + domain
+	for n := 2; seen[email]; n++ {
+		email = fmt.Sprintf("%s%d@%s", local, n, domain)
+	}
+	seen[email] = true
+
+	user := g.user(firstName, lastName, email)
+	switch p := g.rng.Intn(100); {
+	case p < 5:
+		user.IsActive = false
+	case p < 15:
+		user.IsVerified = false
+	case p < 20:
+		user.MustResetPassword = true
+	}
+	return user
+}
+
+// sessionDevices names seeded sessions, in order
+var sessionDevices = []string{"Seeded laptop", "Seeded phone"}
+
+// sessionIDs returns the IDs of up to two sessions for an active, verified
+// user
+func (g *generator) sessionIDs(user *models.User) []string {
+	n := g.rng.Intn(3)
+	ids := make([]string, n)
+	for i := range ids {
+		ids[i] = g.uuid()
+	}
+	if !user.IsActive || !user.IsVerified {
+		return nil
+	}
+	return ids
+}
+
+func (g *generator) uuid() string {
+	id, err := uuid.NewRandomFromReader(g.rng)
+	if err != nil {
+		// Reading from a math/rand source never fails
+		panic(err)
+	}
+	return id.String()
+}
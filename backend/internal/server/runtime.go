@@ -0,0 +1,154 @@
+// Package server runs the process's listeners (gRPC, REST gateway, gRPC-Web,
+// metrics, pprof, admin) as one unit with ordered startup and shutdown
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"time"
+)
+
+// Runtime owns a set of listeners. Components start in the order they were
+// added and stop in reverse, so add backends before the fronts that call them.
+type Runtime struct {
+	shutdownTimeout time.Duration
+	components      []*component
+	hooks           []func()
+}
+
+type component struct {
+	name     string
+	addr     string
+	listener net.Listener
+	serve    func(net.Listener) error
+	shutdown func(context.Context) error
+}
+
+// New creates a runtime that gives components shutdownTimeout in total to
+// drain before they are forced closed
+func New(shutdownTimeout time.Duration) *Runtime {
+	return &Runtime{shutdownTimeout: shutdownTimeout}
+}
+
+// GRPCServer is a gRPC server the runtime can serve and stop, such as
+// *grpc.Server or an xDS-managed server
+type GRPCServer interface {
+	Serve(net.Listener) error
+	GracefulStop()
+	Stop()
+}
+
+// AddGRPC adds a gRPC server listening on addr. On shutdown it stops
+// gracefully, falling back to a hard stop when the timeout runs out.
+func (r *Runtime) AddGRPC(name, addr string, srv GRPCServer) {
+	r.components = append(r.components, &component{
+		name:  name,
+		addr:  addr,
+		serve: srv.Serve,
+		shutdown: func(ctx context.Context) error {
+			done := make(chan struct{})
+			go func() {
+				srv.GracefulStop()
+				close(done)
+			}()
+
+			select {
+			case <-done:
+				return nil
+			case <-ctx.Done():
+				srv.Stop()
+				return fmt.Errorf("graceful stop timed out, forced stop")
+			}
+		},
+	})
+}
+
+// AddHTTP adds an HTTP server for handler listening on addr
+func (r *Runtime) AddHTTP(name, addr string, handler http.Handler) {
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	r.components = append(r.components, &component{
+		name:     name,
+		addr:     addr,
+		serve:    srv.Serve,
+		shutdown: srv.Shutdown,
+	})
+}
+
+// OnShutdown registers fn to run when shutdown begins, before any component
+// stops (e.g. to end long-lived streams that would hold up a graceful stop)
+func (r *Runtime) OnShutdown(fn func()) {
+	r.hooks = append(r.hooks, fn)
+}
+
+// Run binds every listener up front, so a port conflict aborts startup
+// before anything serves traffic, then serves until ctx is canceled or a
+// component fails, and finally shuts everything down. It returns the first
+// component failure, if any.
+func (r *Runtime) Run(ctx context.Context) error {
+	for _, c := range r.components {
+		listener, err := net.Listen("tcp", c.addr)
+		if err != nil {
+			r.closeListeners()
+			return fmt.Errorf("%s: failed to listen on %s: %w", c.name, c.addr, err)
+		}
+		c.listener = listener
+	}
+
+	failed := make(chan error, len(r.components))
+	for _, c := range r.components {
+		c := c
+		log.Printf("%s listening on %s", c.name, c.listener.Addr())
+		go func() {
+			if err := c.serve(c.listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				failed <- fmt.Errorf("%s: %w", c.name, err)
+			}
+		}()
+	}
+
+	var runErr error
+	select {
+	case <-ctx.Done():
+		log.Println("Shutting down server...")
+	case runErr = <-failed:
+		log.Printf("Shutting down after failure: %v", runErr)
+	}
+
+	r.shutdown()
+	return runErr
+}
+
+// shutdown runs the hooks and stops components in reverse start order
+func (r *Runtime) shutdown() {
+	ctx, cancel := context.WithTimeout(context.Background(), r.shutdownTimeout)
+	defer cancel()
+
+	for _, hook := range r.hooks {
+		hook()
+	}
+
+	for i := len(r.components) - 1; i >= 0; i-- {
+		c := r.components[i]
+		if err := c.shutdown(ctx); err != nil {
+			log.Printf("%s shutdown error: %v", c.name, err)
+		}
+	}
+
+	log.Println("Server stopped")
+}
+
+func (r *Runtime) closeListeners() {
+	for _, c := range r.components {
+		if c.listener != nil {
+			_ = c.listener.Close()
+		}
+	}
+}
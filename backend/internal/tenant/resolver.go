@@ -0,0 +1,105 @@
+// Package tenant resolves the tenant a call is made for. The tenant ID is
+// threaded through the context with db.WithTenant, and tenant-scoped
+// repositories filter every query by it.
+package tenant
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/models"
+)
+
+// Store looks tenants up, implemented by *models.TenantRepository
+type Store interface {
+	GetByID(ctx context.Context, id string) (*models.Tenant, error)
+	GetBySlug(ctx context.Context, slug string) (*models.Tenant, error)
+}
+
+// Resolver looks tenants up by ID or slug and caches what it finds, so calls
+// don't each cost a query. A tenant's changes, such as deactivation, reach
+// other instances when they Forget it, or else within the cache TTL.
+type Resolver struct {
+	store Store
+	ttl   time.Duration
+
+	mu      sync.RWMutex
+	entries map[string]entry
+}
+
+type entry struct {
+	tenant  *models.Tenant
+	expires time.Time
+}
+
+// NewResolver creates a resolver caching lookups for ttl
+func NewResolver(store Store, ttl time.Duration) *Resolver {
+	return &Resolver{
+		store:   store,
+		ttl:     ttl,
+		entries: make(map[string]entry),
+	}
+}
+
+// Resolve returns the tenant with the given ID or slug. It returns
+// models.ErrTenantNotFound if there is none.
+func (r *Resolver) Resolve(ctx context.Context, ref string) (*models.Tenant, error) {
+	r.mu.RLock()
+	e, ok := r.entries[ref]
+	r.mu.RUnlock()
+	if ok && time.Now().Before(e.expires) {
+		return e.tenant, nil
+	}
+
+	var tenant *models.Tenant
+	var err error
+	if uuid.Validate(ref) == nil {
+		tenant, err = r.store.GetByID(ctx, ref)
+	} else {
+		tenant, err = r.store.GetBySlug(ctx, ref)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	r.Remember(tenant)
+	return tenant, nil
+}
+
+// Remember caches tenant under its ID and slug, replacing any earlier copy.
+// Call it after changing a tenant so this instance sees the change at once.
+func (r *Resolver) Remember(tenant *models.Tenant) {
+	e := entry{tenant: tenant, expires: time.Now().Add(r.ttl)}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Drop expired entries now and then so the map tracks live tenants only
+	if len(r.entries) > 1024 {
+		now := time.Now()
+		for key, old := range r.entries {
+			if now.After(old.expires) {
+				delete(r.entries, key)
+			}
+		}
+	}
+
+	r.entries[tenant.ID] = e
+	r.entries[tenant.Slug] = e
+}
+
+// Forget drops the tenant with the given ID, under its ID and slug, so the
+// next lookup reads it again
+func (r *Resolver) Forget(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for key, e := range r.entries {
+		if e.tenant.ID == id {
+			delete(r.entries, key)
+		}
+	}
+}
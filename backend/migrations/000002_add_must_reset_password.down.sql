@@ -0,0 +1,2 @@
+-- Drop must_reset_password flag
+ALTER TABLE users DROP COLUMN IF EXISTS must_reset_password;
@@ -0,0 +1,2 @@
+-- Flag accounts that must choose a new password before regaining full access
+ALTER TABLE users ADD COLUMN IF NOT EXISTS must_reset_password BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,3 @@
+-- Drop recovery email columns
+ALTER TABLE users DROP COLUMN IF EXISTS recovery_email_verified;
+ALTER TABLE users DROP COLUMN IF EXISTS recovery_email;
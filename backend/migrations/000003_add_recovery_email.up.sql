@@ -0,0 +1,3 @@
+-- Secondary email used only for account recovery
+ALTER TABLE users ADD COLUMN IF NOT EXISTS recovery_email VARCHAR(255);
+ALTER TABLE users ADD COLUMN IF NOT EXISTS recovery_email_verified BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,2 @@
+-- Drop role
+ALTER TABLE users DROP COLUMN IF EXISTS role;
@@ -0,0 +1,2 @@
+-- Role used by the authorization policy (see internal/authz)
+ALTER TABLE users ADD COLUMN IF NOT EXISTS role VARCHAR(32) NOT NULL DEFAULT 'user';
@@ -0,0 +1,17 @@
+-- Record of every mutating RPC, written asynchronously by the audit interceptor
+CREATE TABLE IF NOT EXISTS audit_log (
+    id BIGSERIAL PRIMARY KEY,
+    occurred_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    method VARCHAR(255) NOT NULL,
+    actor_id UUID,
+    target_id VARCHAR(255),
+    status_code VARCHAR(32) NOT NULL,
+    client_ip VARCHAR(45),
+    latency_ms INTEGER NOT NULL,
+    request_id VARCHAR(64),
+    payload JSONB
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_occurred_at ON audit_log(occurred_at);
+CREATE INDEX IF NOT EXISTS idx_audit_log_actor_id ON audit_log(actor_id, occurred_at);
+CREATE INDEX IF NOT EXISTS idx_audit_log_target_id ON audit_log(target_id, occurred_at);
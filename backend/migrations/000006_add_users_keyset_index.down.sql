@@ -0,0 +1,2 @@
+-- Drop keyset pagination index
+DROP INDEX IF EXISTS idx_users_active_created_at_id;
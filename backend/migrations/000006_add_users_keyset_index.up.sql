@@ -0,0 +1,2 @@
+-- Keyset pagination of active users, newest first (see db.Keyset)
+CREATE INDEX IF NOT EXISTS idx_users_active_created_at_id ON users(created_at DESC, id DESC) WHERE is_active;
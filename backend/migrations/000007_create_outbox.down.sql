@@ -0,0 +1,2 @@
+-- Drop outbox
+DROP TABLE IF EXISTS outbox;
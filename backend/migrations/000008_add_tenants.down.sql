@@ -0,0 +1,11 @@
+-- Drop tenants. Fails if two tenants have users with the same email.
+DROP INDEX IF EXISTS idx_users_tenant_active_created_at_id;
+CREATE INDEX IF NOT EXISTS idx_users_active_created_at_id ON users(created_at DESC, id DESC) WHERE is_active;
+
+DROP INDEX IF EXISTS idx_users_tenant_email;
+CREATE INDEX IF NOT EXISTS idx_users_email ON users(email);
+ALTER TABLE users ADD CONSTRAINT users_email_key UNIQUE (email);
+
+ALTER TABLE users DROP COLUMN IF EXISTS tenant_id;
+
+DROP TABLE IF EXISTS tenants;
@@ -0,0 +1,3 @@
+-- Stop announcing user changes
+DROP TRIGGER IF EXISTS notify_users_changed ON users;
+DROP FUNCTION IF EXISTS notify_user_changed();
@@ -0,0 +1,14 @@
+-- Announce changed and deleted users on the user_changed channel, so every
+-- server instance can drop its cached copy (see models.UserCache). The
+-- payload is the user ID. Notifications are sent when the transaction
+-- commits, and not at all if it rolls back.
+CREATE OR REPLACE FUNCTION notify_user_changed()
+RETURNS TRIGGER AS $$
+BEGIN
+    PERFORM pg_notify('user_changed', OLD.id::text);
+    RETURN NULL;
+END;
+$$ language 'plpgsql';
+
+CREATE TRIGGER notify_users_changed AFTER UPDATE OR DELETE ON users
+    FOR EACH ROW EXECUTE FUNCTION notify_user_changed();
@@ -0,0 +1,2 @@
+-- Remove user row version
+ALTER TABLE users DROP COLUMN IF EXISTS version;
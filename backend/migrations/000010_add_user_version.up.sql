@@ -0,0 +1,4 @@
+-- Row version for optimistic locking: every change to a user except
+-- recording a login increments it, and UserRepository.Update only applies
+-- if the row is still at the version the caller read
+ALTER TABLE users ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 1;
@@ -0,0 +1,2 @@
+-- Drop user history
+DROP TABLE IF EXISTS user_history;
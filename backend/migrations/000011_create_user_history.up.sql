@@ -0,0 +1,17 @@
+-- Before/after snapshots of every change to a user, with who made it, for
+-- compliance and support investigations. user_id has no foreign key so the
+-- history outlives the user.
+CREATE TABLE IF NOT EXISTS user_history (
+    id BIGSERIAL PRIMARY KEY,
+    tenant_id UUID NOT NULL REFERENCES tenants(id),
+    user_id UUID NOT NULL,
+    action VARCHAR(64) NOT NULL,
+    actor_id UUID,
+    actor_role VARCHAR(32) NOT NULL,
+    request_id VARCHAR(64),
+    before JSONB NOT NULL,
+    after JSONB NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_history_user_id ON user_history(user_id, id DESC);
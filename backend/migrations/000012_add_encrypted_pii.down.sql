@@ -0,0 +1,3 @@
+-- Remove encrypted personal data columns
+ALTER TABLE users DROP COLUMN IF EXISTS metadata_encrypted;
+ALTER TABLE users DROP COLUMN IF EXISTS phone_number_encrypted;
@@ -0,0 +1,4 @@
+-- Personal data encrypted at rest by the application (see pkg/crypto). Each
+-- value names the key that encrypted it, so keys can be rotated.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS phone_number_encrypted BYTEA;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS metadata_encrypted BYTEA;
@@ -0,0 +1,19 @@
+-- users.updated_at records the last change to the user: it moves with
+-- version, so recording a login or re-wrapping encrypted data under a new
+-- key leaves it alone. The repository never writes either timestamp.
+CREATE OR REPLACE FUNCTION update_users_updated_at_column()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF NEW.version IS DISTINCT FROM OLD.version THEN
+        NEW.updated_at = NOW();
+    ELSE
+        NEW.updated_at = OLD.updated_at;
+    END IF;
+    NEW.created_at = OLD.created_at;
+    RETURN NEW;
+END;
+$$ language 'plpgsql';
+
+DROP TRIGGER IF EXISTS update_users_updated_at ON users;
+CREATE TRIGGER update_users_updated_at BEFORE UPDATE ON users
+    FOR EACH ROW EXECUTE FUNCTION update_users_updated_at_column();
@@ -0,0 +1,11 @@
+// Package migrations embeds the versioned SQL schema migrations so the server
+// binary can apply them without the files on disk
+package migrations
+
+import "embed"
+
+// FS holds the NNNNNN_name.up.sql and NNNNNN_name.down.sql pairs, in the
+// golang-migrate file format
+//
+//go:embed *.sql
+var FS embed.FS
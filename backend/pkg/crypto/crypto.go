@@ -0,0 +1,237 @@
+// Package crypto encrypts individual values, such as personal data stored in
+// database columns, with envelope encryption. Each value is encrypted with
+// its own random data key, which is in turn encrypted ("wrapped") by a key
+// encryption key from the keyring. Ciphertexts name the key that wrapped
+// their data key, so keys can be rotated: new values use the active key,
+// older ones stay readable while their key is configured, and Rewrap moves
+// them to the active key without decrypting the value.
+package crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/config"
+)
+
+// Ciphertext layout:
+//
+//	version (1) | key ID length (1) | key ID | wrapped data key | nonce | sealed value
+//
+// The wrapped data key is a nonce followed by the sealed 32-byte data key.
+const (
+	formatVersion  = 1
+	keySize        = 32
+	nonceSize      = 12
+	tagSize        = 16
+	wrappedKeySize = nonceSize + keySize + tagSize
+)
+
+// developmentKeyID names the key used when ENCRYPTION_KEYS is unset outside
+// production
+const developmentKeyID = "dev"
+
+var (
+	// ErrUnknownKey is returned when a ciphertext was wrapped by a key that
+	// is not in the keyring
+	ErrUnknownKey = errors.New("encryption key not configured")
+	// ErrInvalidCiphertext is returned when a ciphertext is malformed, was
+	// tampered with, or is decrypted with the wrong associated data
+	ErrInvalidCiphertext = errors.New("invalid ciphertext")
+)
+
+// Keyring holds the key encryption keys, by ID
+type Keyring struct {
+	keys     map[string]cipher.AEAD
+	activeID string
+}
+
+// New creates a keyring from ENCRYPTION_KEYS and ENCRYPTION_ACTIVE_KEY. If
+// no keys are configured, which configuration validation allows only
+// outside production, a fixed development key is used; values encrypted
+// with it are not protected.
+func New(cfg *config.Config) (*Keyring, error) {
+	keys := make(map[string][]byte)
+	for _, entry := range cfg.Encryption.Keys {
+		id, encoded, ok := strings.Cut(entry, ":")
+		if !ok || id == "" {
+			return nil, fmt.Errorf("invalid ENCRYPTION_KEYS entry %q: want id:base64key", id)
+		}
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ENCRYPTION_KEYS entry %q: %w", id, err)
+		}
+		keys[id] = key
+	}
+
+	activeID := cfg.Encryption.ActiveKeyID
+	if len(keys) == 0 {
+		log.Printf("ENCRYPTION_KEYS not set; encrypting with the insecure development key")
+		key := sha256.Sum256([]byte("grpc-proto-go-flutter-template development key"))
+		keys[developmentKeyID] = key[:]
+		activeID = developmentKeyID
+	}
+
+	return NewKeyring(keys, activeID)
+}
+
+// NewKeyring creates a keyring from 32-byte AES-256 keys. New values are
+// encrypted with the key named activeID.
+func NewKeyring(keys map[string][]byte, activeID string) (*Keyring, error) {
+	k := &Keyring{keys: make(map[string]cipher.AEAD), activeID: activeID}
+	for id, key := range keys {
+		if len(id) > 255 {
+			return nil, fmt.Errorf("encryption key ID %q is too long", id[:16]+"...")
+		}
+		if len(key) != keySize {
+			return nil, fmt.Errorf("encryption key %q must be %d bytes, got %d", id, keySize, len(key))
+		}
+		aead, err := newAEAD(key)
+		if err != nil {
+			return nil, err
+		}
+		k.keys[id] = aead
+	}
+
+	if _, ok := k.keys[activeID]; !ok {
+		return nil, fmt.Errorf("active encryption key %q is not configured", activeID)
+	}
+	return k, nil
+}
+
+// Encrypt encrypts plaintext under a new data key wrapped by the active key.
+// aad binds the ciphertext to its context, such as the table, column and row
+// it is stored in; Decrypt requires the same aad, so a ciphertext copied to
+// another row does not decrypt.
+func (k *Keyring) Encrypt(plaintext, aad []byte) ([]byte, error) {
+	dataKey := make([]byte, keySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, fmt.Errorf("failed to generate data key: %w", err)
+	}
+	data, err := newAEAD(dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	out := k.header(k.activeID)
+	out, err = k.wrap(out, k.activeID, dataKey)
+	if err != nil {
+		return nil, err
+	}
+	return seal(out, data, plaintext, aad)
+}
+
+// Decrypt decrypts a ciphertext from Encrypt or Rewrap with the same aad
+func (k *Keyring) Decrypt(ciphertext, aad []byte) ([]byte, error) {
+	keyID, wrapped, sealed, err := parse(ciphertext)
+	if err != nil {
+		return nil, err
+	}
+
+	dataKey, err := k.unwrap(keyID, wrapped)
+	if err != nil {
+		return nil, err
+	}
+	data, err := newAEAD(dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := data.Open(nil, sealed[:nonceSize], sealed[nonceSize:], aad)
+	if err != nil {
+		return nil, ErrInvalidCiphertext
+	}
+	return plaintext, nil
+}
+
+// NeedsRewrap reports whether ciphertext's data key is wrapped by a key
+// other than the active one
+func (k *Keyring) NeedsRewrap(ciphertext []byte) bool {
+	keyID, _, _, err := parse(ciphertext)
+	return err == nil && keyID != k.activeID
+}
+
+// Rewrap re-wraps ciphertext's data key with the active key. The value
+// itself is not re-encrypted, so no aad is needed. Once no ciphertext
+// names a key, it can be removed from the keyring.
+func (k *Keyring) Rewrap(ciphertext []byte) ([]byte, error) {
+	keyID, wrapped, sealed, err := parse(ciphertext)
+	if err != nil {
+		return nil, err
+	}
+
+	dataKey, err := k.unwrap(keyID, wrapped)
+	if err != nil {
+		return nil, err
+	}
+
+	out := k.header(k.activeID)
+	out, err = k.wrap(out, k.activeID, dataKey)
+	if err != nil {
+		return nil, err
+	}
+	return append(out, sealed...), nil
+}
+
+func (k *Keyring) header(keyID string) []byte {
+	out := make([]byte, 0, 2+len(keyID)+wrappedKeySize)
+	out = append(out, formatVersion, byte(len(keyID)))
+	return append(out, keyID...)
+}
+
+// wrap appends dataKey sealed with the key named keyID. The key ID is
+// authenticated, so a ciphertext can't be relabeled with another key.
+func (k *Keyring) wrap(out []byte, keyID string, dataKey []byte) ([]byte, error) {
+	return seal(out, k.keys[keyID], dataKey, []byte(keyID))
+}
+
+func (k *Keyring) unwrap(keyID string, wrapped []byte) ([]byte, error) {
+	kek, ok := k.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownKey, keyID)
+	}
+	dataKey, err := kek.Open(nil, wrapped[:nonceSize], wrapped[nonceSize:], []byte(keyID))
+	if err != nil {
+		return nil, ErrInvalidCiphertext
+	}
+	return dataKey, nil
+}
+
+// parse splits a ciphertext into its key ID, wrapped data key, and sealed
+// value with its nonce
+func parse(ciphertext []byte) (keyID string, wrapped, sealed []byte, err error) {
+	if len(ciphertext) < 2 || ciphertext[0] != formatVersion {
+		return "", nil, nil, ErrInvalidCiphertext
+	}
+	idLen := int(ciphertext[1])
+	rest := ciphertext[2:]
+	if len(rest) < idLen+wrappedKeySize+nonceSize+tagSize {
+		return "", nil, nil, ErrInvalidCiphertext
+	}
+	return string(rest[:idLen]), rest[idLen : idLen+wrappedKeySize], rest[idLen+wrappedKeySize:], nil
+}
+
+// seal appends a random nonce and plaintext sealed with aead
+func seal(out []byte, aead cipher.AEAD, plaintext, aad []byte) ([]byte, error) {
+	nonce := make([]byte, nonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	out = append(out, nonce...)
+	return aead.Seal(out, nonce, plaintext, aad), nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
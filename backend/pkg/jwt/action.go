@@ -0,0 +1,133 @@
+package jwt
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// MaxActionTokenTTL is the longest an action token or signed URL may live
+const MaxActionTokenTTL = 24 * time.Hour
+
+// SignedURLParam is the query parameter a signed URL carries its token in
+const SignedURLParam = "sig"
+
+// Action is a one-off action an action token authorizes, such as
+// downloading an avatar or confirming an email address
+type Action struct {
+	// Purpose names the action, such as "avatar_download"; a token minted
+	// for one purpose is rejected for any other
+	Purpose string
+	// Audience is where the token may be presented, such as the handler
+	// that performs the action
+	Audience string
+	// Subject is what the action is on, such as the ID of the user whose
+	// email is confirmed
+	Subject string
+	// TTL is how long the token is valid, at most MaxActionTokenTTL
+	TTL time.Duration
+}
+
+// CreateActionToken creates a short-lived token authorizing action. It is
+// signed like access tokens, but its token_type, purpose and aud claims
+// keep it from being accepted as one, or for another action or audience.
+// It can be replayed until it expires, so actions that must happen once
+// should record its ID.
+func (s *Service) CreateActionToken(action Action) (string, error) {
+	if action.Purpose == "" || action.Audience == "" {
+		return "", fmt.Errorf("action tokens need a purpose and an audience")
+	}
+	if action.TTL <= 0 || action.TTL > MaxActionTokenTTL {
+		return "", fmt.Errorf("action token TTL must be positive and at most %s, got %s", MaxActionTokenTTL, action.TTL)
+	}
+
+	now := time.Now()
+	claims := Claims{
+		TokenType: TokenTypeAction,
+		Purpose:   action.Purpose,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(now.Add(action.TTL)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			Issuer:    s.config.JWT.Issuer,
+			Audience:  jwt.ClaimStrings{action.Audience},
+			Subject:   action.Subject,
+			ID:        uuid.New().String(),
+		},
+	}
+
+	return s.sign(claims)
+}
+
+// ValidateActionToken validates an action token minted for purpose and
+// audience and returns its claims. The action's subject is the sub claim.
+func (s *Service) ValidateActionToken(tokenString, purpose, audience string) (*Claims, error) {
+	claims, err := s.validateToken(tokenString, []string{audience})
+	if err != nil {
+		return nil, err
+	}
+	if claims.TokenType != TokenTypeAction || claims.Purpose != purpose {
+		return nil, fmt.Errorf("not a %s action token", purpose)
+	}
+	return claims, nil
+}
+
+// SignURL returns rawURL with an action token for purpose and subject in
+// its SignedURLParam query parameter, valid for ttl. The token's audience
+// is the URL's path and query, so it can't be moved to another URL, but
+// not its host, so it verifies behind proxies that rewrite it.
+func (s *Service) SignURL(rawURL, purpose, subject string, ttl time.Duration) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid URL: %w", err)
+	}
+
+	token, err := s.CreateActionToken(Action{
+		Purpose:  purpose,
+		Audience: signedURLAudience(u),
+		Subject:  subject,
+		TTL:      ttl,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	query := u.Query()
+	query.Set(SignedURLParam, token)
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
+
+// VerifyURL validates the token of a URL signed by SignURL for purpose and
+// returns its claims. rawURL may be absolute or, as handlers see it, a
+// request URI (see http.Request.RequestURI).
+func (s *Service) VerifyURL(rawURL, purpose string) (*Claims, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL: %w", err)
+	}
+	token := u.Query().Get(SignedURLParam)
+	if token == "" {
+		return nil, fmt.Errorf("URL is not signed")
+	}
+	return s.ValidateActionToken(token, purpose, signedURLAudience(u))
+}
+
+// signedURLAudience is the audience of a signed URL: its path and its query
+// without the signature, in sorted order
+func signedURLAudience(u *url.URL) string {
+	query := u.Query()
+	query.Del(SignedURLParam)
+
+	audience := u.EscapedPath()
+	if audience == "" {
+		audience = "/"
+	}
+	if len(query) > 0 {
+		audience += "?" + query.Encode()
+	}
+	return audience
+}
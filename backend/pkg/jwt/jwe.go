@@ -0,0 +1,93 @@
+package jwt
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/go-jose/go-jose/v4"
+)
+
+// encryptionKeySize is the size of a JWT_ENCRYPTION_KEYS key, an A256KW
+// key-wrapping key
+const encryptionKeySize = 32
+
+// encryptionKeys encrypt signed tokens as nested JWTs (RFC 7519 section
+// 11.2): the JWS is the payload of a JWE, so its claims, which may name
+// the user, can't be read without a key. Each token's content key is
+// random and wrapped (A256KW) by the active key, which the JWE's kid
+// header names, so keys can be rotated like signing keys.
+type encryptionKeys struct {
+	active string
+	byID   map[string][]byte
+}
+
+// newEncryptionKeys parses JWT_ENCRYPTION_KEYS entries of "id:base64key";
+// nil for none, when tokens aren't encrypted
+func newEncryptionKeys(entries []string, activeID string) (*encryptionKeys, error) {
+	if len(entries) == 0 {
+		return nil, nil
+	}
+
+	keys := &encryptionKeys{active: activeID, byID: make(map[string][]byte, len(entries))}
+	for _, entry := range entries {
+		id, encoded, ok := strings.Cut(entry, ":")
+		if !ok || id == "" {
+			return nil, fmt.Errorf("invalid JWT_ENCRYPTION_KEYS entry %q: want id:base64key", id)
+		}
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("invalid JWT_ENCRYPTION_KEYS entry %q: %w", id, err)
+		}
+		if len(key) != encryptionKeySize {
+			return nil, fmt.Errorf("JWT encryption key %q must be %d bytes, got %d", id, encryptionKeySize, len(key))
+		}
+		keys.byID[id] = key
+	}
+
+	if _, ok := keys.byID[activeID]; !ok {
+		return nil, fmt.Errorf("active JWT encryption key %q is not configured", activeID)
+	}
+	return keys, nil
+}
+
+// encrypt wraps a signed token in a JWE encrypted with the active key
+func (k *encryptionKeys) encrypt(signed string) (string, error) {
+	encrypter, err := jose.NewEncrypter(jose.A256GCM,
+		jose.Recipient{Algorithm: jose.A256KW, Key: k.byID[k.active], KeyID: k.active},
+		(&jose.EncrypterOptions{}).WithContentType("JWT"))
+	if err != nil {
+		return "", err
+	}
+
+	object, err := encrypter.Encrypt([]byte(signed))
+	if err != nil {
+		return "", err
+	}
+	return object.CompactSerialize()
+}
+
+// decrypt returns the signed token inside a JWE, decrypted with the key its
+// kid header names
+func (k *encryptionKeys) decrypt(token string) (string, error) {
+	object, err := jose.ParseEncryptedCompact(token, []jose.KeyAlgorithm{jose.A256KW}, []jose.ContentEncryption{jose.A256GCM})
+	if err != nil {
+		return "", err
+	}
+
+	key, ok := k.byID[object.Header.KeyID]
+	if !ok {
+		return "", fmt.Errorf("unknown encryption key ID %q", object.Header.KeyID)
+	}
+	signed, err := object.Decrypt(key)
+	if err != nil {
+		return "", err
+	}
+	return string(signed), nil
+}
+
+// isEncrypted reports whether token is a JWE rather than a JWS: its compact
+// form has five parts, not three
+func isEncrypted(token string) bool {
+	return strings.Count(token, ".") == 4
+}
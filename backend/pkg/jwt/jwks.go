@@ -0,0 +1,35 @@
+package jwt
+
+// JWK is a public key in JSON Web Key form (RFC 7517). RSA keys set N and
+// E; EC and OKP (Ed25519) keys set Crv and X, and EC keys Y.
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// JWKS is a JSON Web Key Set, as served at /.well-known/jwks.json
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public half of every configured key, newest first, so
+// other services can verify tokens without calling ValidateToken. HS256
+// secrets are never published, so with one the set is empty.
+func (s *Service) JWKS() JWKS {
+	keys := s.keys.ordered
+	set := JWKS{Keys: make([]JWK, 0, len(keys))}
+	for i := len(keys) - 1; i >= 0; i-- {
+		if keys[i].public == nil {
+			continue
+		}
+		set.Keys = append(set.Keys, keys[i].jwk())
+	}
+	return set
+}
@@ -1,12 +1,17 @@
 package jwt
 
 import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
 	"encoding/pem"
 	"fmt"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -16,99 +21,371 @@ import (
 
 // Service handles JWT token operations
 type Service struct {
-	privateKey *rsa.PrivateKey
-	publicKey  *rsa.PublicKey
+	keys *keySet
+	// encryption is nil unless JWT_ENCRYPTION_KEYS is set
+	encryption *encryptionKeys
 	config     *config.Config
 }
 
+// ScopePasswordChange restricts an access token to the ChangePassword RPC
+const ScopePasswordChange = "password_change"
+
+// Token types, as in the token_type claim
+const (
+	TokenTypeAccess  = "access"
+	TokenTypeRefresh = "refresh"
+	// TokenTypeAction authorizes one action; see CreateActionToken
+	TokenTypeAction = "action"
+)
+
 // Claims represents JWT claims
 type Claims struct {
 	UserID string `json:"user_id"`
-	Email  string `json:"email"`
+	// TenantID is the tenant the user belongs to; empty in tokens issued
+	// before tenancy, which belong to the default tenant
+	TenantID string `json:"tid,omitempty"`
+	Email    string `json:"email"`
+	// Role is the authorization policy subject; see internal/authz
+	Role string `json:"role,omitempty"`
+	// Roles are Role and the roles it inherits; empty in tokens issued
+	// before they were added
+	Roles []string `json:"roles,omitempty"`
+	// Permissions are the method patterns Roles are granted unconditionally,
+	// as in the authorization policy; empty in scoped tokens
+	Permissions []string `json:"perms,omitempty"`
+	// Scope is empty for full-access tokens
+	Scope string `json:"scope,omitempty"`
+	// TokenType is TokenTypeAccess, TokenTypeRefresh or TokenTypeAction, so
+	// one can't be used as another; empty in tokens issued before it was
+	// added
+	TokenType string `json:"token_type,omitempty"`
+	// Purpose names the action an action token authorizes
+	Purpose string `json:"purpose,omitempty"`
+	// SessionID is the ID of the session the access token was issued for,
+	// which is its refresh token's ID; empty in scoped tokens and tokens
+	// issued before sessions were tracked
+	SessionID string `json:"sid,omitempty"`
 	jwt.RegisteredClaims
 }
 
-// New creates a new JWT service
+// IsRestricted reports whether the token carries a limiting scope
+func (c *Claims) IsRestricted() bool {
+	return c.Scope != ""
+}
+
+// HasRole reports whether the token's holder has role, directly or by
+// inheritance
+func (c *Claims) HasRole(role string) bool {
+	if c.Role == role {
+		return true
+	}
+	for _, r := range c.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// HasPermission reports whether the token grants calling the full gRPC
+// method. Patterns match like the policy's keyMatch: a * matches the rest of
+// the method.
+func (c *Claims) HasPermission(method string) bool {
+	for _, pattern := range c.Permissions {
+		prefix, _, wildcard := strings.Cut(pattern, "*")
+		if method == pattern || wildcard && strings.HasPrefix(method, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Identity is who an access token is issued to
+type Identity struct {
+	UserID   string
+	TenantID string
+	Email    string
+	Role     string
+	// Roles and Permissions are the user's grants; see authz.Authorizer.Grants
+	Roles       []string
+	Permissions []string
+}
+
+// New creates a new JWT service with the keys in JWT_KEYS, or the key pair
+// at JWT_PRIVATE_KEY_PATH and JWT_PUBLIC_KEY_PATH, whose ID is its JWK
+// thumbprint, or for HS256 the JWT_SECRET. The signing key must be of the
+// JWT_ALGORITHM type. Without keys, a key pair or secret for JWT_ALGORITHM
+// is generated in memory.
 func New(cfg *config.Config) (*Service, error) {
-	var privateKey *rsa.PrivateKey
-	var publicKey *rsa.PublicKey
-	var err error
+	var keys []*signingKey
 
-	// Try to load keys from files if paths are provided
-	if cfg.JWT.PrivateKeyPath != "" && cfg.JWT.PublicKeyPath != "" {
-		privateKey, err = loadPrivateKey(cfg.JWT.PrivateKeyPath)
+	switch {
+	case cfg.JWT.Algorithm == AlgorithmHS256:
+		secret := []byte(cfg.JWT.Secret)
+		if len(secret) == 0 {
+			// Generate a secret in memory (for development)
+			secret = make([]byte, config.MinJWTSecretLength)
+			if _, err := rand.Read(secret); err != nil {
+				return nil, fmt.Errorf("failed to generate HS256 secret: %w", err)
+			}
+		}
+		keys = append(keys, newSecretKey(secret))
+	case len(cfg.JWT.Keys) > 0:
+		var err error
+		keys, err = loadKeys(cfg.JWT.Keys)
+		if err != nil {
+			return nil, err
+		}
+	case cfg.JWT.PrivateKeyPath != "" && cfg.JWT.PublicKeyPath != "":
+		key, err := loadPrivateKey(cfg.JWT.PrivateKeyPath)
 		if err != nil {
 			return nil, fmt.Errorf("failed to load private key: %w", err)
 		}
 
-		publicKey, err = loadPublicKey(cfg.JWT.PublicKeyPath)
+		publicKey, err := loadPublicKey(cfg.JWT.PublicKeyPath)
 		if err != nil {
 			return nil, fmt.Errorf("failed to load public key: %w", err)
 		}
-	} else {
+		// Every standard library public key type has Equal
+		if !publicKey.public.(interface{ Equal(crypto.PublicKey) bool }).Equal(key.public) {
+			return nil, fmt.Errorf("public key does not match the private key")
+		}
+		key.id = key.thumbprint()
+		keys = append(keys, key)
+	default:
 		// Generate new key pair in memory (for development)
-		privateKey, err = rsa.GenerateKey(rand.Reader, 2048)
+		privateKey, err := generateKey(cfg.JWT.Algorithm, cfg.JWT.RSAKeyBits)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate %s key: %w", cfg.JWT.Algorithm, err)
+		}
+		key, err := newPrivateKey(privateKey)
 		if err != nil {
-			return nil, fmt.Errorf("failed to generate RSA key: %w", err)
+			return nil, err
 		}
-		publicKey = &privateKey.PublicKey
+		key.id = key.thumbprint()
+		keys = append(keys, key)
+	}
+
+	return newService(cfg, keys)
+}
+
+// NewWithSigner creates a JWT service that signs with signer, such as a KMS
+// key, so the private key never has to be on disk or in memory. signer's ID
+// is its JWK thumbprint; the keys in JWT_KEYS are older keys, which only
+// verify tokens signed before the switch. signer must be an RSA, P-256 or
+// Ed25519 key of the JWT_ALGORITHM type. If it is also a PublicKeySource,
+// tokens signed with its key's other versions verify too.
+func NewWithSigner(cfg *config.Config, signer crypto.Signer) (*Service, error) {
+	keys, err := loadKeys(cfg.JWT.Keys)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := newPrivateKey(signer)
+	if err != nil {
+		return nil, err
+	}
+	key.id = key.thumbprint()
+	s, err := newService(cfg, append(keys, key))
+	if err != nil {
+		return nil, err
+	}
+	s.keys.source, _ = signer.(PublicKeySource)
+	return s, nil
+}
+
+// newService creates a JWT service signing with the newest of keys, oldest
+// first
+func newService(cfg *config.Config, keys []*signingKey) (*Service, error) {
+	set, err := newKeySet(keys)
+	if err != nil {
+		return nil, err
+	}
+	if alg := set.signing.method.Alg(); alg != cfg.JWT.Algorithm {
+		return nil, fmt.Errorf("signing key %q is an %s key, but JWT_ALGORITHM is %s", set.signing.id, alg, cfg.JWT.Algorithm)
+	}
+	encryption, err := newEncryptionKeys(cfg.JWT.EncryptionKeys, cfg.JWT.EncryptionActiveKey)
+	if err != nil {
+		return nil, err
 	}
 
-	return &Service{
-		privateKey: privateKey,
-		publicKey:  publicKey,
+	s := &Service{
+		keys:       set,
+		encryption: encryption,
 		config:     cfg,
-	}, nil
+	}
+	if err := s.selfTest(); err != nil {
+		return nil, fmt.Errorf("signing key %q failed its self-test: %w", set.signing.id, err)
+	}
+	return s, nil
 }
 
-// CreateAccessToken creates a new access token
-func (s *Service) CreateAccessToken(userID, email string) (string, error) {
+// selfTest signs a token with the signing key and verifies it, so a key
+// that can't sign, or a signer whose signatures don't match its public key,
+// fails at startup rather than at the first login
+func (s *Service) selfTest() error {
+	now := time.Now()
+	token, err := s.signJWT(Claims{RegisteredClaims: jwt.RegisteredClaims{
+		ExpiresAt: jwt.NewNumericDate(now.Add(time.Minute)),
+		IssuedAt:  jwt.NewNumericDate(now),
+	}})
+	if err != nil {
+		return err
+	}
+	_, err = jwt.ParseWithClaims(token, &Claims{}, s.keys.verificationKey)
+	return err
+}
+
+// CreateAccessToken creates a new access token for the session sessionID
+func (s *Service) CreateAccessToken(id Identity, sessionID string) (string, error) {
+	return s.createAccessToken(id, "", sessionID)
+}
+
+// CreateScopedAccessToken creates an access token limited to the given
+// scope. It carries no permissions, as the scope replaces them.
+func (s *Service) CreateScopedAccessToken(id Identity, scope string) (string, error) {
+	id.Permissions = nil
+	return s.createAccessToken(id, scope, "")
+}
+
+func (s *Service) createAccessToken(id Identity, scope, sessionID string) (string, error) {
 	now := time.Now()
 	claims := Claims{
-		UserID: userID,
-		Email:  email,
+		UserID:      id.UserID,
+		TenantID:    id.TenantID,
+		Email:       id.Email,
+		Role:        id.Role,
+		Roles:       id.Roles,
+		Permissions: id.Permissions,
+		Scope:       scope,
+		TokenType:   TokenTypeAccess,
+		SessionID:   sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(now.Add(s.config.JWT.AccessTokenExpiry)),
 			IssuedAt:  jwt.NewNumericDate(now),
 			NotBefore: jwt.NewNumericDate(now),
 			Issuer:    s.config.JWT.Issuer,
-			Subject:   userID,
+			Audience:  s.config.JWT.Audiences,
+			Subject:   id.UserID,
 			ID:        uuid.New().String(),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
-	return token.SignedString(s.privateKey)
+	return s.sign(claims)
 }
 
 // CreateRefreshToken creates a new refresh token
-func (s *Service) CreateRefreshToken(userID string) (string, error) {
+func (s *Service) CreateRefreshToken(userID, tenantID string) (string, error) {
 	now := time.Now()
 	claims := Claims{
-		UserID: userID,
+		UserID:    userID,
+		TenantID:  tenantID,
+		TokenType: TokenTypeRefresh,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(now.Add(s.config.JWT.RefreshTokenExpiry)),
 			IssuedAt:  jwt.NewNumericDate(now),
 			NotBefore: jwt.NewNumericDate(now),
 			Issuer:    s.config.JWT.Issuer,
+			Audience:  s.config.JWT.Audiences,
 			Subject:   userID,
 			ID:        uuid.New().String(),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
-	return token.SignedString(s.privateKey)
+	return s.sign(claims)
 }
 
-// ValidateToken validates a token and returns claims
-func (s *Service) ValidateToken(tokenString string) (*Claims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		// Verify signing method
-		if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+// sign signs claims with the newest key, naming it in the kid header, or
+// in the footer of a PASETO token. With JWT_ENCRYPTION_KEYS set, the signed
+// JWT is then encrypted.
+func (s *Service) sign(claims Claims) (string, error) {
+	if s.config.JWT.Format == config.TokenFormatPASETO {
+		return s.signPASETO(claims)
+	}
+
+	signed, err := s.signJWT(claims)
+	if err != nil || s.encryption == nil {
+		return signed, err
+	}
+	return s.encryption.encrypt(signed)
+}
+
+// signJWT signs claims as a JWT with the newest key
+func (s *Service) signJWT(claims Claims) (string, error) {
+	key := s.keys.signing
+	token := jwt.NewWithClaims(key.method, claims)
+	token.Header["kid"] = key.id
+	if key.secret != nil {
+		return token.SignedString(key.secret)
+	}
+
+	signingInput, err := token.SigningString()
+	if err != nil {
+		return "", err
+	}
+	signature, err := key.signature([]byte(signingInput))
+	if err != nil {
+		return "", fmt.Errorf("failed to sign token: %w", err)
+	}
+	return signingInput + "." + token.EncodeSegment(signature), nil
+}
+
+// ValidateAccessToken validates an access token and returns its claims.
+// Refresh tokens, and tokens issued before token types, are rejected.
+func (s *Service) ValidateAccessToken(tokenString string) (*Claims, error) {
+	claims, err := s.validateToken(tokenString, s.config.JWT.Audiences)
+	if err != nil {
+		return nil, err
+	}
+	if claims.TokenType != TokenTypeAccess {
+		return nil, fmt.Errorf("not an access token")
+	}
+	return claims, nil
+}
+
+// ValidateRefreshToken validates a refresh token and returns its claims.
+// Access tokens are rejected. Refresh tokens issued before token types are
+// accepted, as they outlive any deploy; access tokens from then can't pass
+// as them, as they have no session.
+func (s *Service) ValidateRefreshToken(tokenString string) (*Claims, error) {
+	claims, err := s.validateToken(tokenString, s.config.JWT.Audiences)
+	if err != nil {
+		return nil, err
+	}
+	if claims.TokenType != TokenTypeRefresh && claims.TokenType != "" {
+		return nil, fmt.Errorf("not a refresh token")
+	}
+	return claims, nil
+}
+
+// validateToken validates a token of any type, issued by JWT_ISSUER and
+// signed with any configured key, and returns claims. With audiences,
+// JWT_AUDIENCE for access and refresh tokens, the token's aud claim must name
+// one of them, so tokens minted for other APIs are rejected. Only tokens of
+// the configured format are accepted.
+func (s *Service) validateToken(tokenString string, audiences []string) (*Claims, error) {
+	if s.config.JWT.Format == config.TokenFormatPASETO {
+		claims, err := s.parsePASETO(tokenString, audiences)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse token: %w", err)
 		}
-		return s.publicKey, nil
-	})
+		return claims, nil
+	}
+
+	// Plain JWTs are still accepted with encryption on, so turning it on
+	// doesn't sign anyone out
+	if isEncrypted(tokenString) {
+		if s.encryption == nil {
+			return nil, fmt.Errorf("failed to parse token: encrypted tokens are not accepted")
+		}
+		signed, err := s.encryption.decrypt(tokenString)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt token: %w", err)
+		}
+		tokenString = signed
+	}
+
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, s.keys.verificationKey, s.parserOptions(audiences)...)
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse token: %w", err)
@@ -121,9 +398,24 @@ func (s *Service) ValidateToken(tokenString string) (*Claims, error) {
 	return nil, fmt.Errorf("invalid token")
 }
 
-// GetTokenID extracts the token ID from a token string
+// parserOptions are the claim checks beyond expiry a token for audiences
+// must pass, and the leeway given to exp and nbf. The iss claim must be
+// JWT_ISSUER, so tokens another service signs with a shared key set are
+// rejected.
+func (s *Service) parserOptions(audiences []string) []jwt.ParserOption {
+	opts := []jwt.ParserOption{
+		jwt.WithLeeway(s.config.JWT.Leeway),
+		jwt.WithIssuer(s.config.JWT.Issuer),
+	}
+	if len(audiences) > 0 {
+		opts = append(opts, jwt.WithAudience(audiences...))
+	}
+	return opts
+}
+
+// GetTokenID extracts the token ID from a refresh token
 func (s *Service) GetTokenID(tokenString string) (string, error) {
-	claims, err := s.ValidateToken(tokenString)
+	claims, err := s.ValidateRefreshToken(tokenString)
 	if err != nil {
 		return "", err
 	}
@@ -132,77 +424,85 @@ func (s *Service) GetTokenID(tokenString string) (string, error) {
 
 // Helper functions to load keys from files
 
-func loadPrivateKey(path string) (*rsa.PrivateKey, error) {
-	keyData, err := os.ReadFile(path)
+func loadPrivateKey(path string) (*signingKey, error) {
+	block, err := readPEM(path)
 	if err != nil {
 		return nil, err
 	}
 
-	block, _ := pem.Decode(keyData)
-	if block == nil {
-		return nil, fmt.Errorf("failed to decode PEM block")
-	}
-
-	key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	private, err := parsePrivateKey(block.Bytes)
 	if err != nil {
-		// Try PKCS8 format
-		parsedKey, err := x509.ParsePKCS8PrivateKey(block.Bytes)
-		if err != nil {
-			return nil, err
-		}
-		var ok bool
-		key, ok = parsedKey.(*rsa.PrivateKey)
-		if !ok {
-			return nil, fmt.Errorf("not an RSA private key")
-		}
+		return nil, err
 	}
+	return newPrivateKey(private)
+}
 
-	return key, nil
+// parsePrivateKey parses a PKCS #1 RSA, SEC 1 EC or PKCS #8 private key
+func parsePrivateKey(der []byte) (interface{}, error) {
+	if key, err := x509.ParsePKCS1PrivateKey(der); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParseECPrivateKey(der); err == nil {
+		return key, nil
+	}
+	return x509.ParsePKCS8PrivateKey(der)
 }
 
-func loadPublicKey(path string) (*rsa.PublicKey, error) {
-	keyData, err := os.ReadFile(path)
+func loadPublicKey(path string) (*signingKey, error) {
+	block, err := readPEM(path)
 	if err != nil {
 		return nil, err
 	}
 
-	block, _ := pem.Decode(keyData)
-	if block == nil {
-		return nil, fmt.Errorf("failed to decode PEM block")
-	}
-
-	pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+	public, err := parsePublicKey(block.Bytes)
 	if err != nil {
 		return nil, err
 	}
+	return newPublicKey(public)
+}
 
-	key, ok := pub.(*rsa.PublicKey)
-	if !ok {
-		return nil, fmt.Errorf("not an RSA public key")
-	}
+func parsePublicKey(der []byte) (interface{}, error) {
+	return x509.ParsePKIXPublicKey(der)
+}
 
-	return key, nil
+// generateKey generates a private key for algorithm, of rsaBits for RS256
+func generateKey(algorithm string, rsaBits int) (crypto.Signer, error) {
+	switch algorithm {
+	case AlgorithmES256:
+		return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case AlgorithmEdDSA:
+		_, private, err := ed25519.GenerateKey(rand.Reader)
+		return private, err
+	default:
+		return rsa.GenerateKey(rand.Reader, rsaBits)
+	}
 }
 
-// GenerateKeyPair generates and saves a new RSA key pair
-func GenerateKeyPair(privateKeyPath, publicKeyPath string) error {
-	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+// GenerateKeyPair generates a key pair for algorithm, of rsaBits for RS256,
+// and saves it as PEM, RSA private keys in PKCS #1 and others in PKCS #8
+func GenerateKeyPair(algorithm string, rsaBits int, privateKeyPath, publicKeyPath string) error {
+	if algorithm == AlgorithmRS256 && rsaBits < config.MinRSAKeyBits {
+		return fmt.Errorf("RSA keys must be at least %d bits, got %d", config.MinRSAKeyBits, rsaBits)
+	}
+	privateKey, err := generateKey(algorithm, rsaBits)
 	if err != nil {
 		return err
 	}
 
 	// Save private key
-	privateKeyBytes := x509.MarshalPKCS1PrivateKey(privateKey)
-	privateKeyPEM := pem.EncodeToMemory(&pem.Block{
-		Type:  "RSA PRIVATE KEY",
-		Bytes: privateKeyBytes,
-	})
-	if err := os.WriteFile(privateKeyPath, privateKeyPEM, 0600); err != nil {
+	privateKeyBlock := &pem.Block{Type: "PRIVATE KEY"}
+	if rsaKey, ok := privateKey.(*rsa.PrivateKey); ok {
+		privateKeyBlock.Type = "RSA PRIVATE KEY"
+		privateKeyBlock.Bytes = x509.MarshalPKCS1PrivateKey(rsaKey)
+	} else if privateKeyBlock.Bytes, err = x509.MarshalPKCS8PrivateKey(privateKey); err != nil {
+		return err
+	}
+	if err := writeKeyFile(privateKeyPath, pem.EncodeToMemory(privateKeyBlock), 0600); err != nil {
 		return err
 	}
 
 	// Save public key
-	publicKeyBytes, err := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+	publicKeyBytes, err := x509.MarshalPKIXPublicKey(privateKey.Public())
 	if err != nil {
 		return err
 	}
@@ -210,9 +510,18 @@ func GenerateKeyPair(privateKeyPath, publicKeyPath string) error {
 		Type:  "PUBLIC KEY",
 		Bytes: publicKeyBytes,
 	})
-	if err := os.WriteFile(publicKeyPath, publicKeyPEM, 0644); err != nil {
+	if err := writeKeyFile(publicKeyPath, publicKeyPEM, 0644); err != nil {
 		return err
 	}
 
 	return nil
 }
+
+// writeKeyFile writes a key file with perm, even over an existing file,
+// whose permissions os.WriteFile would keep
+func writeKeyFile(path string, data []byte, perm os.FileMode) error {
+	if err := os.WriteFile(path, data, perm); err != nil {
+		return err
+	}
+	return os.Chmod(path, perm)
+}
@@ -0,0 +1,171 @@
+package jwt
+
+import (
+	"crypto/x509"
+	"testing"
+	"time"
+
+	gojwt "github.com/golang-jwt/jwt/v5"
+
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/config"
+)
+
+// newTestConfig returns the JWT settings of a service with a key generated
+// in memory
+func newTestConfig(algorithm, format string) *config.Config {
+	cfg := &config.Config{}
+	cfg.JWT.Algorithm = algorithm
+	cfg.JWT.RSAKeyBits = config.MinRSAKeyBits
+	cfg.JWT.Format = format
+	cfg.JWT.RefreshTokenFormat = config.RefreshTokenJWT
+	cfg.JWT.Issuer = "test-issuer"
+	cfg.JWT.AccessTokenExpiry = time.Minute
+	cfg.JWT.RefreshTokenExpiry = time.Hour
+	return cfg
+}
+
+func newTestService(t *testing.T, cfg *config.Config) *Service {
+	t.Helper()
+
+	s, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return s
+}
+
+func TestValidateIssuer(t *testing.T) {
+	tests := []struct {
+		name      string
+		algorithm string
+		format    string
+	}{
+		{name: "JWT", algorithm: AlgorithmES256, format: config.TokenFormatJWT},
+		{name: "PASETO", algorithm: AlgorithmEdDSA, format: config.TokenFormatPASETO},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService(t, newTestConfig(tt.algorithm, tt.format))
+			// Another service sharing the key set, with its own issuer
+			otherConfig := *s.config
+			otherConfig.JWT.Issuer = "other-issuer"
+			other := *s
+			other.config = &otherConfig
+
+			id := Identity{UserID: "user-1", TenantID: "tenant-1", Role: "user"}
+			for _, issuer := range []*Service{s, &other} {
+				access, err := issuer.CreateAccessToken(id, "session-1")
+				if err != nil {
+					t.Fatalf("CreateAccessToken: %v", err)
+				}
+				refresh, err := issuer.CreateRefreshToken(id.UserID, id.TenantID)
+				if err != nil {
+					t.Fatalf("CreateRefreshToken: %v", err)
+				}
+
+				wantErr := issuer != s
+				if _, err := s.ValidateAccessToken(access); (err != nil) != wantErr {
+					t.Errorf("ValidateAccessToken of a %s token: error = %v, want error %v", issuer.config.JWT.Issuer, err, wantErr)
+				}
+				if _, err := s.ValidateRefreshToken(refresh); (err != nil) != wantErr {
+					t.Errorf("ValidateRefreshToken of a %s token: error = %v, want error %v", issuer.config.JWT.Issuer, err, wantErr)
+				}
+			}
+		})
+	}
+}
+
+// newTestKey generates a key for algorithm named id
+func newTestKey(t *testing.T, algorithm, id string) *signingKey {
+	t.Helper()
+
+	private, err := generateKey(algorithm, config.MinRSAKeyBits)
+	if err != nil {
+		t.Fatalf("generateKey: %v", err)
+	}
+	key, err := newPrivateKey(private)
+	if err != nil {
+		t.Fatalf("newPrivateKey: %v", err)
+	}
+	key.id = id
+	return key
+}
+
+// TestKeyBinding checks that a token verifies only with the key its kid
+// header names, and only in that key's algorithm
+func TestKeyBinding(t *testing.T) {
+	rsaKey := newTestKey(t, AlgorithmRS256, "rsa")
+	ecKey := newTestKey(t, AlgorithmES256, "ec")
+	edKey := newTestKey(t, AlgorithmEdDSA, "ed")
+
+	cfg := newTestConfig(AlgorithmEdDSA, config.TokenFormatJWT)
+	// Rotated from RS256 to ES256 to EdDSA, so tokens of all three verify
+	s, err := newService(cfg, []*signingKey{rsaKey, ecKey, edKey})
+	if err != nil {
+		t.Fatalf("newService: %v", err)
+	}
+
+	ecPublic, err := x509.MarshalPKIXPublicKey(ecKey.public)
+	if err != nil {
+		t.Fatalf("MarshalPKIXPublicKey: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		method gojwt.SigningMethod
+		// kid is the kid header; nil for none
+		kid     interface{}
+		signKey interface{}
+		wantErr bool
+	}{
+		{name: "newest key", method: gojwt.SigningMethodEdDSA, kid: "ed", signKey: edKey.private},
+		{name: "older key", method: gojwt.SigningMethodES256, kid: "ec", signKey: ecKey.private},
+		{name: "RS256 without kid, from before keys were named", method: gojwt.SigningMethodRS256, signKey: rsaKey.private},
+		{name: "ES256 without kid", method: gojwt.SigningMethodES256, signKey: ecKey.private, wantErr: true},
+		{name: "unknown kid", method: gojwt.SigningMethodES256, kid: "other", signKey: ecKey.private, wantErr: true},
+		{name: "kid of another algorithm's key", method: gojwt.SigningMethodES256, kid: "ed", signKey: ecKey.private, wantErr: true},
+		{name: "kid of the right algorithm, other key", method: gojwt.SigningMethodES256, kid: "ec", signKey: newTestKey(t, AlgorithmES256, "ec").private, wantErr: true},
+		// HMAC keyed with the public key, which anyone can fetch from JWKS
+		{name: "HS256 with a public key as secret", method: gojwt.SigningMethodHS256, kid: "ec", signKey: ecPublic, wantErr: true},
+		{name: "none", method: gojwt.SigningMethodNone, kid: "ec", signKey: gojwt.UnsafeAllowNoneSignatureType, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Now()
+			token := gojwt.NewWithClaims(tt.method, Claims{
+				UserID:    "user-1",
+				TokenType: TokenTypeAccess,
+				RegisteredClaims: gojwt.RegisteredClaims{
+					ExpiresAt: gojwt.NewNumericDate(now.Add(time.Minute)),
+					IssuedAt:  gojwt.NewNumericDate(now),
+					Issuer:    cfg.JWT.Issuer,
+				},
+			})
+			if tt.kid != nil {
+				token.Header["kid"] = tt.kid
+			}
+			signed, err := token.SignedString(tt.signKey)
+			if err != nil {
+				t.Fatalf("SignedString: %v", err)
+			}
+
+			_, err = s.ValidateAccessToken(signed)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateAccessToken error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	// Without an RSA key, there are no legacy tokens to accept
+	withoutRSA, err := newService(cfg, []*signingKey{ecKey, edKey})
+	if err != nil {
+		t.Fatalf("newService: %v", err)
+	}
+	signed, err := gojwt.NewWithClaims(gojwt.SigningMethodRS256, Claims{UserID: "user-1", TokenType: TokenTypeAccess}).SignedString(rsaKey.private)
+	if err != nil {
+		t.Fatalf("SignedString: %v", err)
+	}
+	if _, err := withoutRSA.ValidateAccessToken(signed); err == nil {
+		t.Error("ValidateAccessToken accepted an RS256 token without kid and no RSA key")
+	}
+}
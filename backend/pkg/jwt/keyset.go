@@ -0,0 +1,363 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/config"
+)
+
+// Signing algorithms, as named in the alg header and JWT_ALGORITHM
+const (
+	AlgorithmRS256 = "RS256"
+	AlgorithmES256 = "ES256"
+	AlgorithmEdDSA = "EdDSA"
+	AlgorithmHS256 = "HS256"
+)
+
+// signingKey is a key tokens are verified with, and signed with if it has
+// its private half. Its algorithm follows from its type: RSA keys sign
+// RS256, P-256 keys ES256 and Ed25519 keys EdDSA. An HS256 key is a shared
+// secret instead, which both signs and verifies.
+type signingKey struct {
+	// id is the kid header of the tokens it signs
+	id     string
+	method jwt.SigningMethod
+	// private is an *rsa.PrivateKey, *ecdsa.PrivateKey or ed25519.PrivateKey,
+	// or a KMS signer of one of their public key types
+	private crypto.Signer
+	// public is an *rsa.PublicKey, *ecdsa.PublicKey or ed25519.PublicKey
+	public crypto.PublicKey
+	// secret is the HMAC key of an HS256 key, which has no private or public
+	// half
+	secret []byte
+}
+
+// newSecretKey returns the HS256 key of a shared secret. Its ID is an HMAC
+// of a fixed label, which names the secret without revealing a hash of it.
+func newSecretKey(secret []byte) *signingKey {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte("jwt key id"))
+	id := base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:16])
+	return &signingKey{id: id, method: jwt.SigningMethodHS256, secret: secret}
+}
+
+// newPrivateKey returns the signing key of a parsed private key
+func newPrivateKey(private interface{}) (*signingKey, error) {
+	signer, ok := private.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key type %T", private)
+	}
+	key, err := newPublicKey(signer.Public())
+	if err != nil {
+		return nil, err
+	}
+	key.private = signer
+	return key, nil
+}
+
+// newPublicKey returns the verification-only key of a parsed public key
+func newPublicKey(public interface{}) (*signingKey, error) {
+	switch public := public.(type) {
+	case *rsa.PublicKey:
+		if bits := public.N.BitLen(); bits < config.MinRSAKeyBits {
+			return nil, fmt.Errorf("RSA key is %d bits, but at least %d are required", bits, config.MinRSAKeyBits)
+		}
+		return &signingKey{method: jwt.SigningMethodRS256, public: public}, nil
+	case *ecdsa.PublicKey:
+		if public.Curve != elliptic.P256() {
+			return nil, fmt.Errorf("unsupported ECDSA curve %s: ES256 needs P-256", public.Curve.Params().Name)
+		}
+		return &signingKey{method: jwt.SigningMethodES256, public: public}, nil
+	case ed25519.PublicKey:
+		return &signingKey{method: jwt.SigningMethodEdDSA, public: public}, nil
+	default:
+		return nil, fmt.Errorf("unsupported public key type %T", public)
+	}
+}
+
+// signature signs a token's signing input with the private half of the key,
+// in the form its algorithm puts in a JWS. The key is only used through
+// crypto.Signer, so it may live in a KMS rather than in memory.
+func (k *signingKey) signature(signingInput []byte) ([]byte, error) {
+	if k.method == jwt.SigningMethodEdDSA {
+		// Ed25519 signs the message itself, not a digest
+		return k.private.Sign(rand.Reader, signingInput, crypto.Hash(0))
+	}
+
+	digest := sha256.Sum256(signingInput)
+	signature, err := k.private.Sign(rand.Reader, digest[:], crypto.SHA256)
+	if err != nil || k.method != jwt.SigningMethodES256 {
+		return signature, err
+	}
+
+	// crypto.Signer returns ECDSA signatures ASN.1 encoded, but JWS wants
+	// R and S padded to the curve size (RFC 7518 section 3.4)
+	var rs struct{ R, S *big.Int }
+	if rest, err := asn1.Unmarshal(signature, &rs); err != nil || len(rest) > 0 {
+		return nil, fmt.Errorf("malformed ECDSA signature")
+	}
+	out := make([]byte, 64)
+	rs.R.FillBytes(out[:32])
+	rs.S.FillBytes(out[32:])
+	return out, nil
+}
+
+// verifier is what the key verifies tokens with
+func (k *signingKey) verifier() interface{} {
+	if k.secret != nil {
+		return k.secret
+	}
+	return k.public
+}
+
+// jwk returns the public half of the key as a JWK. Secret keys have none.
+func (k *signingKey) jwk() JWK {
+	jwk := JWK{Kid: k.id, Use: "sig", Alg: k.method.Alg()}
+	switch public := k.public.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+	case *ecdsa.PublicKey:
+		// Coordinates are padded to the curve size (RFC 7518 section 6.2.1)
+		point, _ := public.ECDH()
+		raw := point.Bytes()[1:]
+		jwk.Kty = "EC"
+		jwk.Crv = "P-256"
+		jwk.X = base64.RawURLEncoding.EncodeToString(raw[:len(raw)/2])
+		jwk.Y = base64.RawURLEncoding.EncodeToString(raw[len(raw)/2:])
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(public)
+	}
+	return jwk
+}
+
+// thumbprint is the RFC 7638 JWK thumbprint of the key, the ID of keys
+// configured without one: a hash of the JWK's required members, in
+// lexicographic order
+func (k *signingKey) thumbprint() string {
+	jwk := k.jwk()
+	var members string
+	switch jwk.Kty {
+	case "RSA":
+		members = `{"e":"` + jwk.E + `","kty":"RSA","n":"` + jwk.N + `"}`
+	case "EC":
+		members = `{"crv":"` + jwk.Crv + `","kty":"EC","x":"` + jwk.X + `","y":"` + jwk.Y + `"}`
+	default:
+		members = `{"crv":"` + jwk.Crv + `","kty":"OKP","x":"` + jwk.X + `"}`
+	}
+
+	sum := sha256.Sum256([]byte(members))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// PublicKeySource is implemented by signers whose key has other versions
+// kept elsewhere, such as a Vault transit key rotated in Vault. Tokens naming
+// a key ID the service doesn't know are checked against those versions'
+// public keys, whose IDs are their JWK thumbprints, so tokens signed by
+// instances that already use a newer version verify everywhere.
+type PublicKeySource interface {
+	// PublicKeys returns the public key of every version of the key
+	PublicKeys() ([]crypto.PublicKey, error)
+}
+
+// sourceRefreshInterval is the least time between lookups in a
+// PublicKeySource. Tokens naming unknown key IDs in between are rejected
+// without one, so tokens with made-up IDs can't make every request call the
+// source, such as Vault; a version rotated to in the source verifies here
+// within the interval.
+const sourceRefreshInterval = 10 * time.Second
+
+// keySet holds a Service's keys by ID. New tokens are signed with the newest
+// key and name it in their kid header. Tokens signed with older keys stay
+// valid while those keys are configured, so keys can be rotated without
+// signing everyone out, even to another algorithm.
+type keySet struct {
+	signing *signingKey
+	// ordered are the keys oldest first
+	ordered []*signingKey
+	// legacy verifies tokens without a kid, issued with an RSA key before
+	// keys were named
+	legacy jwt.VerificationKeySet
+	// source, if set, resolves key IDs that aren't configured
+	source PublicKeySource
+	// refreshMu serializes source lookups, and refreshedAt is the last
+	refreshMu   sync.Mutex
+	refreshedAt time.Time
+
+	mu   sync.RWMutex
+	byID map[string]*signingKey
+}
+
+// newKeySet creates a key set of keys, oldest first
+func newKeySet(keys []*signingKey) (*keySet, error) {
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no JWT keys configured")
+	}
+
+	set := &keySet{byID: make(map[string]*signingKey, len(keys)), ordered: keys}
+	for _, key := range keys {
+		if _, ok := set.byID[key.id]; ok {
+			return nil, fmt.Errorf("duplicate JWT key ID %q", key.id)
+		}
+		set.byID[key.id] = key
+		if key.method == jwt.SigningMethodRS256 {
+			set.legacy.Keys = append(set.legacy.Keys, key.public)
+		}
+	}
+
+	set.signing = keys[len(keys)-1]
+	if set.signing.private == nil && set.signing.secret == nil {
+		return nil, fmt.Errorf("newest JWT key %q must be a private key", set.signing.id)
+	}
+	return set, nil
+}
+
+// key returns the key named id. Keys that aren't configured are looked up
+// in the source, at most once per sourceRefreshInterval; the keys found
+// there are kept, so each is looked up once.
+func (s *keySet) key(id string) (*signingKey, error) {
+	if key, ok := s.lookup(id); ok {
+		return key, nil
+	}
+	if s.source == nil {
+		return nil, fmt.Errorf("unknown key ID %q", id)
+	}
+
+	s.refreshMu.Lock()
+	defer s.refreshMu.Unlock()
+	// Another token may have brought the key in while this one waited
+	if key, ok := s.lookup(id); ok {
+		return key, nil
+	}
+	if time.Since(s.refreshedAt) < sourceRefreshInterval {
+		return nil, fmt.Errorf("unknown key ID %q", id)
+	}
+	// Failures count too, so an unreachable source isn't retried per token
+	s.refreshedAt = time.Now()
+
+	publicKeys, err := s.source.PublicKeys()
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up key ID %q: %w", id, err)
+	}
+	for _, public := range publicKeys {
+		key, err := newPublicKey(public)
+		if err != nil {
+			continue
+		}
+		key.id = key.thumbprint()
+		s.mu.Lock()
+		if _, ok := s.byID[key.id]; !ok {
+			s.byID[key.id] = key
+		}
+		s.mu.Unlock()
+	}
+
+	if key, ok := s.lookup(id); ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown key ID %q", id)
+}
+
+// lookup returns the key named id if it is known
+func (s *keySet) lookup(id string) (*signingKey, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	key, ok := s.byID[id]
+	return key, ok
+}
+
+// verificationKey is the jwt.Keyfunc that picks the key named by a token's
+// kid header. The token must use that key's algorithm, so a key can't be
+// misused with another.
+func (s *keySet) verificationKey(token *jwt.Token) (interface{}, error) {
+	kid, ok := token.Header["kid"].(string)
+	if !ok {
+		if token.Method != jwt.SigningMethodRS256 || len(s.legacy.Keys) == 0 {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return s.legacy, nil
+	}
+
+	key, err := s.key(kid)
+	if err != nil {
+		return nil, err
+	}
+	if token.Method != key.method {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+	return key.verifier(), nil
+}
+
+// loadKeys reads JWT_KEYS entries of "kid:path"
+func loadKeys(entries []string) ([]*signingKey, error) {
+	keys := make([]*signingKey, 0, len(entries))
+	for _, entry := range entries {
+		id, path, ok := strings.Cut(entry, ":")
+		if !ok || id == "" || path == "" {
+			return nil, fmt.Errorf("invalid JWT_KEYS entry %q: want kid:path", entry)
+		}
+
+		key, err := loadKey(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load JWT key %q: %w", id, err)
+		}
+		key.id = id
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// loadKey reads a PEM private key, or a public key that only verifies
+func loadKey(path string) (*signingKey, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.Contains(block.Type, "PRIVATE KEY") {
+		private, err := parsePrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return newPrivateKey(private)
+	}
+
+	public, err := parsePublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	return newPublicKey(public)
+}
+
+func readPEM(path string) (*pem.Block, error) {
+	keyData, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(keyData)
+	if block == nil {
+		return nil, fmt.Errorf("failed to decode PEM block")
+	}
+	return block, nil
+}
@@ -0,0 +1,175 @@
+package jwt
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// pasetoHeader starts every PASETO v4.public token. The version fixes the
+// algorithm to Ed25519, so tokens can't name another.
+const pasetoHeader = "v4.public."
+
+// pasetoTimeClaims are the registered claims PASETO encodes as RFC 3339
+// strings rather than the numbers JWTs use
+var pasetoTimeClaims = []string{"exp", "nbf", "iat"}
+
+// pasetoFooter is the token's unencrypted footer, naming the key that
+// signed it
+type pasetoFooter struct {
+	Kid string `json:"kid"`
+}
+
+// signPASETO signs claims as a v4.public token with the newest key, which
+// must be an Ed25519 key
+func (s *Service) signPASETO(claims Claims) (string, error) {
+	key := s.keys.signing
+	if key.method != jwt.SigningMethodEdDSA {
+		return "", fmt.Errorf("PASETO v4 needs an Ed25519 key, but key %q is %s", key.id, key.method.Alg())
+	}
+
+	message, err := pasetoPayload(claims)
+	if err != nil {
+		return "", err
+	}
+	footer, err := json.Marshal(pasetoFooter{Kid: key.id})
+	if err != nil {
+		return "", err
+	}
+
+	signature, err := key.signature(pae([]byte(pasetoHeader), message, footer, nil))
+	if err != nil {
+		return "", fmt.Errorf("failed to sign token: %w", err)
+	}
+	return pasetoHeader +
+		base64.RawURLEncoding.EncodeToString(append(message, signature...)) + "." +
+		base64.RawURLEncoding.EncodeToString(footer), nil
+}
+
+// parsePASETO verifies a v4.public token against the key its footer names
+// and returns its claims, which are then validated like a JWT's for
+// audiences
+func (s *Service) parsePASETO(token string, audiences []string) (*Claims, error) {
+	body, ok := strings.CutPrefix(token, pasetoHeader)
+	if !ok {
+		return nil, fmt.Errorf("not a PASETO v4.public token")
+	}
+	encodedPayload, encodedFooter, _ := strings.Cut(body, ".")
+
+	signed, err := base64.RawURLEncoding.DecodeString(encodedPayload)
+	if err != nil || len(signed) < ed25519.SignatureSize {
+		return nil, fmt.Errorf("malformed PASETO payload")
+	}
+	footer, err := base64.RawURLEncoding.DecodeString(encodedFooter)
+	if err != nil {
+		return nil, fmt.Errorf("malformed PASETO footer")
+	}
+
+	var f pasetoFooter
+	if err := json.Unmarshal(footer, &f); err != nil || f.Kid == "" {
+		return nil, fmt.Errorf("PASETO footer names no key")
+	}
+	key, err := s.keys.key(f.Kid)
+	if err != nil {
+		return nil, err
+	}
+	public, ok := key.public.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("key %q is not an Ed25519 key", f.Kid)
+	}
+
+	message, signature := signed[:len(signed)-ed25519.SignatureSize], signed[len(signed)-ed25519.SignatureSize:]
+	if !ed25519.Verify(public, pae([]byte(pasetoHeader), message, footer, nil), signature) {
+		return nil, fmt.Errorf("invalid PASETO signature")
+	}
+
+	claims, err := pasetoClaims(message)
+	if err != nil {
+		return nil, err
+	}
+	if err := jwt.NewValidator(s.parserOptions(audiences)...).Validate(claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// pasetoPayload encodes claims as JSON with PASETO's time format
+func pasetoPayload(claims Claims) ([]byte, error) {
+	data, err := json.Marshal(claims)
+	if err != nil {
+		return nil, err
+	}
+	var payload map[string]json.RawMessage
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return nil, err
+	}
+
+	for _, name := range pasetoTimeClaims {
+		raw, ok := payload[name]
+		if !ok {
+			continue
+		}
+		var date jwt.NumericDate
+		if err := json.Unmarshal(raw, &date); err != nil {
+			return nil, err
+		}
+		if payload[name], err = json.Marshal(date.UTC().Format(time.RFC3339)); err != nil {
+			return nil, err
+		}
+	}
+	return json.Marshal(payload)
+}
+
+// pasetoClaims decodes a PASETO payload, turning its RFC 3339 times back
+// into the numbers Claims holds
+func pasetoClaims(message []byte) (*Claims, error) {
+	var payload map[string]json.RawMessage
+	if err := json.Unmarshal(message, &payload); err != nil {
+		return nil, fmt.Errorf("malformed PASETO claims: %w", err)
+	}
+
+	for _, name := range pasetoTimeClaims {
+		raw, ok := payload[name]
+		if !ok {
+			continue
+		}
+		var value string
+		if err := json.Unmarshal(raw, &value); err != nil {
+			return nil, fmt.Errorf("malformed PASETO %s claim", name)
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return nil, fmt.Errorf("malformed PASETO %s claim", name)
+		}
+		if payload[name], err = json.Marshal(jwt.NewNumericDate(t)); err != nil {
+			return nil, err
+		}
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	claims := &Claims{}
+	if err := json.Unmarshal(data, claims); err != nil {
+		return nil, fmt.Errorf("malformed PASETO claims: %w", err)
+	}
+	return claims, nil
+}
+
+// pae is PASETO's pre-authentication encoding of pieces: their count and
+// each piece, prefixed by its length, as little-endian 64-bit integers
+func pae(pieces ...[]byte) []byte {
+	out := binary.LittleEndian.AppendUint64(nil, uint64(len(pieces)))
+	for _, piece := range pieces {
+		out = binary.LittleEndian.AppendUint64(out, uint64(len(piece)))
+		out = append(out, piece...)
+	}
+	return out
+}
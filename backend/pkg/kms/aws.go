@@ -0,0 +1,74 @@
+package kms
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/aws/aws-sdk-go-v2/service/kms/types"
+)
+
+// newAWSSigner returns a signer for the AWS KMS key keyID, a key ID, ARN or
+// alias, using the default credential chain (environment, shared config or
+// the instance role)
+func newAWSSigner(ctx context.Context, keyID string) (crypto.Signer, error) {
+	cfg, err := awsconfig.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+	client := kms.NewFromConfig(cfg)
+
+	out, err := client.GetPublicKey(ctx, &kms.GetPublicKeyInput{KeyId: aws.String(keyID)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get public key of AWS KMS key %q: %w", keyID, err)
+	}
+	if out.KeyUsage != types.KeyUsageTypeSignVerify {
+		return nil, fmt.Errorf("AWS KMS key %q is not a signing key", keyID)
+	}
+	public, err := x509.ParsePKIXPublicKey(out.PublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse public key of AWS KMS key %q: %w", keyID, err)
+	}
+	if err := checkPublicKey(public); err != nil {
+		return nil, err
+	}
+
+	var algorithm types.SigningAlgorithmSpec
+	switch public.(type) {
+	case *rsa.PublicKey:
+		algorithm = types.SigningAlgorithmSpecRsassaPkcs1V15Sha256
+	case *ecdsa.PublicKey:
+		algorithm = types.SigningAlgorithmSpecEcdsaSha256
+	default:
+		return nil, fmt.Errorf("AWS KMS key %q is a %T, which can't sign RS256 or ES256", keyID, public)
+	}
+	supported := false
+	for _, a := range out.SigningAlgorithms {
+		supported = supported || a == algorithm
+	}
+	if !supported {
+		return nil, fmt.Errorf("AWS KMS key %q does not support %s", keyID, algorithm)
+	}
+
+	return &remoteSigner{
+		public: public,
+		sign: func(ctx context.Context, digest []byte) ([]byte, error) {
+			out, err := client.Sign(ctx, &kms.SignInput{
+				KeyId:            aws.String(keyID),
+				Message:          digest,
+				MessageType:      types.MessageTypeDigest,
+				SigningAlgorithm: algorithm,
+			})
+			if err != nil {
+				return nil, fmt.Errorf("AWS KMS sign failed: %w", err)
+			}
+			return out.Signature, nil
+		},
+	}, nil
+}
@@ -0,0 +1,114 @@
+package kms
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+
+	"golang.org/x/oauth2/google"
+)
+
+// gcpEndpoint is the Cloud KMS REST API
+const gcpEndpoint = "https://cloudkms.googleapis.com/v1/"
+
+// gcpAlgorithms are the Cloud KMS key algorithms that sign RS256 or ES256
+var gcpAlgorithms = map[string]bool{
+	"RSA_SIGN_PKCS1_2048_SHA256": true,
+	"RSA_SIGN_PKCS1_3072_SHA256": true,
+	"RSA_SIGN_PKCS1_4096_SHA256": true,
+	"EC_SIGN_P256_SHA256":        true,
+}
+
+// newGCPSigner returns a signer for the Cloud KMS key version name
+// (projects/*/locations/*/keyRings/*/cryptoKeys/*/cryptoKeyVersions/*),
+// using Application Default Credentials
+func newGCPSigner(ctx context.Context, name string) (crypto.Signer, error) {
+	client, err := google.DefaultClient(ctx, "https://www.googleapis.com/auth/cloudkms")
+	if err != nil {
+		return nil, fmt.Errorf("failed to load Google credentials: %w", err)
+	}
+
+	var key struct {
+		Pem       string `json:"pem"`
+		Algorithm string `json:"algorithm"`
+	}
+	if err := gcpCall(ctx, client, http.MethodGet, name+"/publicKey", nil, &key); err != nil {
+		return nil, fmt.Errorf("failed to get public key of Cloud KMS key %q: %w", name, err)
+	}
+	if !gcpAlgorithms[key.Algorithm] {
+		return nil, fmt.Errorf("Cloud KMS key %q is a %s key, which can't sign RS256 or ES256", name, key.Algorithm)
+	}
+	block, _ := pem.Decode([]byte(key.Pem))
+	if block == nil {
+		return nil, fmt.Errorf("failed to decode public key of Cloud KMS key %q", name)
+	}
+	public, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse public key of Cloud KMS key %q: %w", name, err)
+	}
+	if err := checkPublicKey(public); err != nil {
+		return nil, err
+	}
+
+	return &remoteSigner{
+		public: public,
+		sign: func(ctx context.Context, digest []byte) ([]byte, error) {
+			// []byte fields are base64 encoded, as the API expects
+			req := struct {
+				Digest struct {
+					SHA256 []byte `json:"sha256"`
+				} `json:"digest"`
+			}{}
+			req.Digest.SHA256 = digest
+			var resp struct {
+				Signature []byte `json:"signature"`
+			}
+			if err := gcpCall(ctx, client, http.MethodPost, name+":asymmetricSign", req, &resp); err != nil {
+				return nil, fmt.Errorf("Cloud KMS sign failed: %w", err)
+			}
+			return resp.Signature, nil
+		},
+	}, nil
+}
+
+// gcpCall calls the Cloud KMS method path with body, if any, as JSON and
+// decodes the JSON response into out
+func gcpCall(ctx context.Context, client *http.Client, method, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, gcpEndpoint+path, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %s", resp.Status, bytes.TrimSpace(data))
+	}
+	return json.Unmarshal(data, out)
+}
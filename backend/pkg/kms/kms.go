@@ -0,0 +1,91 @@
+// Package kms signs with keys held in a key management service, AWS KMS,
+// Google Cloud KMS or HashiCorp Vault's transit engine, through
+// crypto.Signer. Only digests (or, for Ed25519, messages) are sent to the
+// KMS and only signatures come back, so the private key never reaches the
+// disk or memory of the app server.
+package kms
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/config"
+)
+
+// callTimeout bounds calls to the KMS made without a context, such as
+// signing, as crypto.Signer takes none
+const callTimeout = 10 * time.Second
+
+// NewSigner returns a signer for JWT_KMS_KEY in JWT_KMS_PROVIDER. Its public
+// key is fetched once, here, so a missing key or one that can't sign fails
+// at startup rather than at the first login.
+func NewSigner(ctx context.Context, cfg *config.Config) (crypto.Signer, error) {
+	switch cfg.JWT.KMSProvider {
+	case config.KMSProviderAWS:
+		return newAWSSigner(ctx, cfg.JWT.KMSKey)
+	case config.KMSProviderGCP:
+		return newGCPSigner(ctx, cfg.JWT.KMSKey)
+	case config.KMSProviderVault:
+		signer, err := newVaultSigner(ctx, cfg.Vault, cfg.JWT.KMSKey)
+		if err != nil {
+			return nil, err
+		}
+		return signer, nil
+	default:
+		return nil, fmt.Errorf("unsupported KMS provider %q", cfg.JWT.KMSProvider)
+	}
+}
+
+// remoteSigner is a crypto.Signer whose private key is in a KMS, which
+// signs SHA-256 digests, or Ed25519 messages, with sign
+type remoteSigner struct {
+	public crypto.PublicKey
+	sign   func(ctx context.Context, data []byte) ([]byte, error)
+}
+
+func (s *remoteSigner) Public() crypto.PublicKey {
+	return s.public
+}
+
+// Sign signs a SHA-256 digest, or for Ed25519 the message itself. Like the
+// standard library's signers, RSA keys return PKCS #1 v1.5 signatures and
+// ECDSA keys ASN.1 encoded ones.
+func (s *remoteSigner) Sign(_ io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	if _, ok := s.public.(ed25519.PublicKey); ok {
+		if opts.HashFunc() != crypto.Hash(0) {
+			return nil, fmt.Errorf("Ed25519 KMS keys only sign whole messages")
+		}
+	} else if opts.HashFunc() != crypto.SHA256 || len(digest) != crypto.SHA256.Size() {
+		return nil, fmt.Errorf("KMS keys only sign SHA-256 digests")
+	}
+	if _, ok := opts.(*rsa.PSSOptions); ok {
+		return nil, fmt.Errorf("KMS keys don't sign RSA-PSS")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), callTimeout)
+	defer cancel()
+	return s.sign(ctx, digest)
+}
+
+// checkPublicKey reports whether public is a key type tokens can be signed
+// with: RSA, ECDSA P-256 or Ed25519
+func checkPublicKey(public crypto.PublicKey) error {
+	switch public := public.(type) {
+	case *rsa.PublicKey, ed25519.PublicKey:
+		return nil
+	case *ecdsa.PublicKey:
+		if public.Curve != elliptic.P256() {
+			return fmt.Errorf("unsupported ECDSA curve %s: ES256 needs P-256", public.Curve.Params().Name)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported public key type %T", public)
+	}
+}
@@ -0,0 +1,230 @@
+package kms
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/config"
+)
+
+// vaultSigner signs with a HashiCorp Vault transit key. It signs with the
+// key's latest version at startup; versions Vault rotates to later are
+// picked up on restart, and meanwhile PublicKeys lets tokens other
+// instances sign with them verify here.
+type vaultSigner struct {
+	remoteSigner
+	client  *http.Client
+	cfg     config.VaultConfig
+	name    string
+	version int
+
+	// The public keys of every version, cached for cfg.PublicKeyCacheTTL
+	mu        sync.Mutex
+	versions  []crypto.PublicKey
+	fetchedAt time.Time
+}
+
+// vaultKey is a transit key as GET {mount}/keys/{name} describes it
+type vaultKey struct {
+	Type            string `json:"type"`
+	LatestVersion   int    `json:"latest_version"`
+	SupportsSigning bool   `json:"supports_signing"`
+	// Keys are the versions by number: objects with a public_key, PEM or
+	// for Ed25519 the raw key in base64, for asymmetric keys, and creation
+	// times for symmetric ones
+	Keys map[string]json.RawMessage `json:"keys"`
+}
+
+// newVaultSigner returns a signer for the transit key name
+func newVaultSigner(ctx context.Context, cfg config.VaultConfig, name string) (*vaultSigner, error) {
+	s := &vaultSigner{client: &http.Client{}, cfg: cfg, name: name}
+
+	key, versions, err := s.readKey(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Vault transit key %q: %w", name, err)
+	}
+	if !key.SupportsSigning {
+		return nil, fmt.Errorf("Vault transit key %q is a %s key, which can't sign", name, key.Type)
+	}
+	public, ok := versions[key.LatestVersion]
+	if !ok {
+		return nil, fmt.Errorf("Vault transit key %q has no public key for version %d", name, key.LatestVersion)
+	}
+	if err := checkPublicKey(public); err != nil {
+		return nil, err
+	}
+
+	s.version = key.LatestVersion
+	s.remoteSigner = remoteSigner{public: public, sign: s.signData}
+	return s, nil
+}
+
+// PublicKeys returns the public key of every version of the transit key,
+// from the cache while it is fresh, so looking up unknown key IDs can't
+// flood Vault. It makes the signer a jwt.PublicKeySource.
+func (s *vaultSigner) PublicKeys() ([]crypto.PublicKey, error) {
+	s.mu.Lock()
+	if time.Since(s.fetchedAt) < s.cfg.PublicKeyCacheTTL {
+		defer s.mu.Unlock()
+		return s.versions, nil
+	}
+	s.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), callTimeout)
+	defer cancel()
+	if _, _, err := s.readKey(ctx); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.versions, nil
+}
+
+// Health reads the transit key, which checks that Vault is reachable and
+// unsealed and that the token may still use the key. It refreshes the
+// public key cache as it goes.
+func (s *vaultSigner) Health(ctx context.Context) error {
+	_, _, err := s.readKey(ctx)
+	return err
+}
+
+// readKey reads the transit key and its versions' public keys, which it
+// caches
+func (s *vaultSigner) readKey(ctx context.Context) (*vaultKey, map[int]crypto.PublicKey, error) {
+	var key vaultKey
+	if err := s.call(ctx, http.MethodGet, "keys/"+url.PathEscape(s.name), nil, &key); err != nil {
+		return nil, nil, err
+	}
+
+	versions := make(map[int]crypto.PublicKey, len(key.Keys))
+	cached := make([]crypto.PublicKey, 0, len(key.Keys))
+	for v, raw := range key.Keys {
+		var k struct {
+			PublicKey string `json:"public_key"`
+		}
+		if json.Unmarshal(raw, &k) != nil || k.PublicKey == "" {
+			continue
+		}
+		version, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, nil, fmt.Errorf("malformed key version %q", v)
+		}
+		public, err := parseVaultPublicKey(k.PublicKey)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to parse public key of version %d: %w", version, err)
+		}
+		versions[version] = public
+		cached = append(cached, public)
+	}
+
+	s.mu.Lock()
+	s.versions, s.fetchedAt = cached, time.Now()
+	s.mu.Unlock()
+	return &key, versions, nil
+}
+
+// signData signs a SHA-256 digest, or an Ed25519 message, with the version
+// the signer was created with
+func (s *vaultSigner) signData(ctx context.Context, data []byte) ([]byte, error) {
+	req := map[string]interface{}{
+		"input":       base64.StdEncoding.EncodeToString(data),
+		"key_version": s.version,
+	}
+	if _, ok := s.public.(ed25519.PublicKey); !ok {
+		req["prehashed"] = true
+		req["hash_algorithm"] = "sha2-256"
+		req["marshaling_algorithm"] = "asn1"
+		if _, ok := s.public.(*rsa.PublicKey); ok {
+			// Vault defaults to PSS, which RS256 isn't
+			req["signature_algorithm"] = "pkcs1v15"
+		}
+	}
+
+	var resp struct {
+		Signature string `json:"signature"`
+	}
+	if err := s.call(ctx, http.MethodPost, "sign/"+url.PathEscape(s.name), req, &resp); err != nil {
+		return nil, fmt.Errorf("Vault sign failed: %w", err)
+	}
+
+	// Signatures are "vault:v<version>:<base64>"
+	i := strings.LastIndex(resp.Signature, ":")
+	if i < 0 {
+		return nil, fmt.Errorf("malformed Vault signature")
+	}
+	return base64.StdEncoding.DecodeString(resp.Signature[i+1:])
+}
+
+// call calls the transit engine's path with body, if any, as JSON and
+// decodes the data of the response into out
+func (s *vaultSigner) call(ctx context.Context, method, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	endpoint := strings.TrimSuffix(s.cfg.Address, "/") + "/v1/" + strings.Trim(s.cfg.TransitMount, "/") + "/" + path
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Vault-Token", s.cfg.Token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %s", resp.Status, bytes.TrimSpace(data))
+	}
+	var envelope struct {
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return err
+	}
+	return json.Unmarshal(envelope.Data, out)
+}
+
+// parseVaultPublicKey parses a transit public key: PKIX PEM, or for Ed25519
+// the raw key in base64
+func parseVaultPublicKey(s string) (crypto.PublicKey, error) {
+	if block, _ := pem.Decode([]byte(s)); block != nil {
+		return x509.ParsePKIXPublicKey(block.Bytes)
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(s)
+	if err != nil || len(raw) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("unrecognized public key")
+	}
+	return ed25519.PublicKey(raw), nil
+}
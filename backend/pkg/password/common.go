@@ -0,0 +1,156 @@
+package password
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// minCommonCoreLength is the shortest part of a password left once the
+// digits and symbols around it are dropped that is looked up on its own, so
+// "Summer2024!" is found as "summer" but "ab2024!" isn't as "ab"
+const minCommonCoreLength = 4
+
+// minNearMatchLength is the shortest form of a password matched against the
+// common password lists one edit away: nearly every shorter string is one
+// edit from a common password
+const minNearMatchLength = 6
+
+// commonCandidates returns the forms of password looked up in the common
+// password lists: lowercase, without the digits and symbols around it, and
+// each with l33t substitutions undone
+func commonCandidates(password string) []string {
+	lower := strings.ToLower(Normalize(password))
+	notLetter := func(r rune) bool { return !unicode.IsLetter(r) }
+
+	candidates := []string{lower}
+	for _, core := range []string{
+		strings.TrimFunc(lower, notLetter),
+		strings.TrimRightFunc(lower, notLetter),
+		strings.TrimLeftFunc(lower, notLetter),
+	} {
+		if len([]rune(core)) >= minCommonCoreLength && core != lower {
+			candidates = append(candidates, core)
+		}
+	}
+	for _, candidate := range candidates {
+		if unl33t := unl33t(candidate); unl33t != candidate {
+			candidates = append(candidates, unl33t)
+		}
+	}
+	return candidates
+}
+
+// unl33t returns s with l33t characters replaced by the letter they most
+// often stand for
+func unl33t(s string) string {
+	return strings.Map(func(r rune) rune {
+		if letters, ok := l33tTable[r]; ok {
+			return letters[0]
+		}
+		return r
+	}, s)
+}
+
+// listShape is the characters and longest word of a password list, which
+// bound the near matches tried against it
+type listShape struct {
+	alphabet  []rune
+	maxLength int
+}
+
+// shapeOf returns the shape of the words of list
+func shapeOf[V any](list map[string]V) listShape {
+	var shape listShape
+	seen := make(map[rune]bool)
+	for word := range list {
+		length := 0
+		for _, r := range word {
+			if !seen[r] {
+				seen[r] = true
+				shape.alphabet = append(shape.alphabet, r)
+			}
+			length++
+		}
+		shape.maxLength = max(shape.maxLength, length)
+	}
+	return shape
+}
+
+// hasNearMatch reports whether known holds a word one insertion, deletion or
+// substitution of a character away from candidate. Only characters of the
+// list are inserted or substituted, and candidates too long to be one edit
+// from any of its words aren't tried.
+func (s listShape) hasNearMatch(candidate string, known func(string) bool) bool {
+	runes := []rune(candidate)
+	if len(runes) > s.maxLength+1 {
+		return false
+	}
+
+	edit := make([]rune, 0, len(runes)+1)
+	for i := 0; i <= len(runes); i++ {
+		if i < len(runes) {
+			edit = append(append(edit[:0], runes[:i]...), runes[i+1:]...)
+			if known(string(edit)) {
+				return true
+			}
+		}
+		for _, r := range s.alphabet {
+			edit = append(append(append(edit[:0], runes[:i]...), r), runes[i:]...)
+			if known(string(edit)) {
+				return true
+			}
+			if i < len(runes) && r != runes[i] {
+				edit = append(append(append(edit[:0], runes[:i]...), r), runes[i+1:]...)
+				if known(string(edit)) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// isNearMatchCandidate reports whether candidate is long enough to be
+// matched one edit away
+func isNearMatchCandidate(candidate string) bool {
+	return utf8.RuneCountInString(candidate) >= minNearMatchLength
+}
+
+// loadBlocklist reads PASSWORD_BLOCKLIST_PATH: passwords one per line,
+// gzip-compressed if the path ends in .gz, stored lowercase and normalized
+// like the passwords checked against them
+func loadBlocklist(path string) (map[string]struct{}, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open password blocklist: %w", err)
+	}
+	defer file.Close()
+
+	var reader io.Reader = file
+	if strings.HasSuffix(strings.ToLower(path), ".gz") {
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read password blocklist: %w", err)
+		}
+		defer gz.Close()
+		reader = gz
+	}
+
+	blocklist := make(map[string]struct{})
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			blocklist[strings.ToLower(Normalize(line))] = struct{}{}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read password blocklist: %w", err)
+	}
+	return blocklist, nil
+}
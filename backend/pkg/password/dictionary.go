@@ -0,0 +1,74 @@
+package password
+
+import (
+	_ "embed"
+	"strings"
+)
+
+// maxWordLength bounds the dictionary lookups of a password's substrings
+const maxWordLength = 20
+
+// commonPasswordList is the most common passwords of leaked password
+// lists, one per line, most common first: the password frequency list of
+// zxcvbn, as distributed with zxcvbn-go (MIT License)
+//
+//go:embed common_passwords.txt
+var commonPasswordList string
+
+// commonPasswords ranks commonPasswordList
+var commonPasswords = rank(commonPasswordList)
+
+// commonShape is the shape of commonPasswords, for near matches
+var commonShape = shapeOf(commonPasswords)
+
+func isCommonPassword(candidate string) bool {
+	_, ok := commonPasswords[candidate]
+	return ok
+}
+
+// commonWords ranks common words and names people build passwords from,
+// most common first
+var commonWords = rank(`
+love the you and baby girl boy angel star life blue red black green pink
+king queen lucky happy sweet sexy cool hot god jesus mom dad sister brother
+family friend friends forever heart kitty cat dog puppy pussy honey sugar
+summer winter spring autumn fall sun moon sky rain snow fire water ice
+rock music dance party game play player ball soccer football baseball
+basketball hockey golf team super power magic dream dreams hope faith peace
+war blood death dead evil devil demon hell heaven dragon tiger lion wolf
+bear eagle horse monkey shark snake spider bird fish chicken cow pig duck
+apple orange banana cherry lemon peach mango candy cookie chocolate pizza
+coffee beer wine whiskey money cash gold silver diamond crystal pearl ruby
+house home school work job car truck bike boat plane train city country
+world earth land road street park beach ocean sea river lake mountain
+island forest garden flower rose lily daisy tree leaf grass stone
+computer internet email phone mobile apple google yahoo windows linux
+secret private access admin user login pass word key lock door open
+hello welcome thanks please sorry yes no maybe good bad best better nice
+great big small little long short high low new old young first last
+one two three four five six seven eight nine ten hundred thousand million
+alpha beta gamma delta omega zero hero legend master champion winner
+michael john david james robert william richard joseph thomas charles
+chris daniel matthew anthony mark paul steven andrew joshua kevin brian
+george edward ryan jason jacob nicholas eric jonathan justin brandon
+mary jennifer linda patricia elizabeth susan jessica sarah karen nancy
+lisa betty helen sandra ashley emily donna michelle dorothy carol amanda
+melissa deborah stephanie rebecca laura sharon cynthia kathleen amy anna
+jordan taylor morgan charlie maggie bailey buddy max rocky jack sam
+correct horse battery staple purple yellow orange silver shadow hunter
+monday tuesday wednesday thursday friday saturday sunday january february
+march april may june july august september october november december
+`)
+
+// rank returns the rank of each whitespace-separated word in list, from 1
+// for the first; a word listed twice keeps its first rank
+func rank(list string) map[string]int {
+	words := strings.Fields(list)
+	ranks := make(map[string]int, len(words))
+	for i, word := range words {
+		if _, ok := ranks[word]; !ok {
+			ranks[word] = i + 1
+		}
+	}
+	return ranks
+}
@@ -1,37 +1,123 @@
 package password
 
 import (
+	"crypto/hmac"
 	"crypto/rand"
+	"crypto/sha256"
 	"crypto/subtle"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"strings"
 
 	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/text/unicode/norm"
 	"github.com/sahays/grpc-proto-go-flutter-template/internal/config"
 )
 
+// minPepperSize is the shortest pepper PASSWORD_PEPPERS accepts, in bytes
+const minPepperSize = 32
+
+// MaxMemory and MaxIterations bound the Argon2 parameters of the hashes the
+// service makes and verifies, so a corrupt or hostile stored hash can't make
+// a verification take gigabytes or minutes. MaxMemory is in KiB (4 GiB).
+const (
+	MaxMemory     = 4 * 1024 * 1024
+	MaxIterations = 64
+)
+
 // Service handles password hashing and verification
 type Service struct {
 	config *config.Config
+	// peppers are the PASSWORD_PEPPERS secrets by ID. A password is keyed
+	// with one by HMAC-SHA256 before Argon2, and the hash names it in a
+	// keyid parameter, so peppers can be rotated as users log in.
+	peppers      map[string][]byte
+	activePepper string
+	// policy is the rules new passwords must meet
+	policy *Policy
+	// dummyHash is a hash of a random password, made like Hash makes them,
+	// for VerifyDummy to verify against
+	dummyHash string
 }
 
-// New creates a new password service
-func New(cfg *config.Config) *Service {
-	return &Service{config: cfg}
+// New creates a new password service from the Argon2 parameters,
+// PASSWORD_PEPPERS, which are typically injected from a secret manager, and
+// the password policy
+func New(cfg *config.Config) (*Service, error) {
+	argon := cfg.Argon2
+	if argon.Memory < 1 || argon.Memory > MaxMemory {
+		return nil, fmt.Errorf("ARGON2_MEMORY must be between 1 and %d KiB", MaxMemory)
+	}
+	if argon.Iterations < 1 || argon.Iterations > MaxIterations {
+		return nil, fmt.Errorf("ARGON2_ITERATIONS must be between 1 and %d", MaxIterations)
+	}
+	if argon.Parallelism < 1 {
+		return nil, fmt.Errorf("ARGON2_PARALLELISM must be at least 1")
+	}
+
+	s := &Service{config: cfg, peppers: make(map[string][]byte)}
+	for _, entry := range cfg.Password.Peppers {
+		id, encoded, ok := strings.Cut(entry, ":")
+		if !ok || id == "" {
+			return nil, fmt.Errorf("invalid PASSWORD_PEPPERS entry %q: want id:base64pepper", id)
+		}
+		// The ID is stored in hashes as a PHC string parameter value
+		if strings.Trim(id, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789.-") != "" {
+			return nil, fmt.Errorf("pepper ID %q must only contain letters, digits, dots and dashes", id)
+		}
+		pepper, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("invalid PASSWORD_PEPPERS entry %q: %w", id, err)
+		}
+		if len(pepper) < minPepperSize {
+			return nil, fmt.Errorf("pepper %q must be at least %d bytes, got %d", id, minPepperSize, len(pepper))
+		}
+		s.peppers[id] = pepper
+	}
+
+	if len(s.peppers) > 0 {
+		if _, ok := s.peppers[cfg.Password.ActivePepper]; !ok {
+			return nil, fmt.Errorf("active pepper %q is not configured", cfg.Password.ActivePepper)
+		}
+		s.activePepper = cfg.Password.ActivePepper
+	}
+
+	policy, err := NewPolicy(cfg.Password)
+	if err != nil {
+		return nil, err
+	}
+	s.policy = policy
+
+	dummy := make([]byte, 32)
+	if _, err := rand.Read(dummy); err != nil {
+		return nil, fmt.Errorf("failed to generate dummy password: %w", err)
+	}
+	if s.dummyHash, err = s.Hash(base64.RawStdEncoding.EncodeToString(dummy)); err != nil {
+		return nil, err
+	}
+	return s, nil
 }
 
-// Hash generates an Argon2id hash of the password
+// Hash generates an Argon2id hash of the password, normalized to NFKC
 func (s *Service) Hash(password string) (string, error) {
+	password = Normalize(password)
+
 	// Generate a cryptographically secure random salt
 	salt := make([]byte, s.config.Argon2.SaltLength)
 	if _, err := rand.Read(salt); err != nil {
 		return "", fmt.Errorf("failed to generate salt: %w", err)
 	}
 
+	input, err := s.pepper(password, s.activePepper)
+	if err != nil {
+		return "", err
+	}
+
 	// Generate the hash
 	hash := argon2.IDKey(
-		[]byte(password),
+		input,
 		salt,
 		s.config.Argon2.Iterations,
 		s.config.Argon2.Memory,
@@ -43,116 +129,197 @@ func (s *Service) Hash(password string) (string, error) {
 	b64Salt := base64.RawStdEncoding.EncodeToString(salt)
 	b64Hash := base64.RawStdEncoding.EncodeToString(hash)
 
-	// Format: $argon2id$v=19$m=65536,t=3,p=2$salt$hash
-	encodedHash := fmt.Sprintf(
-		"$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
-		argon2.Version,
-		s.config.Argon2.Memory,
-		s.config.Argon2.Iterations,
-		s.config.Argon2.Parallelism,
-		b64Salt,
-		b64Hash,
-	)
+	// Format: $argon2id$v=19$m=65536,t=3,p=2[,keyid=pepper]$salt$hash
+	params := fmt.Sprintf("m=%d,t=%d,p=%d", s.config.Argon2.Memory, s.config.Argon2.Iterations, s.config.Argon2.Parallelism)
+	if s.activePepper != "" {
+		params += ",keyid=" + s.activePepper
+	}
+	encodedHash := fmt.Sprintf("$argon2id$v=%d$%s$%s$%s", argon2.Version, params, b64Salt, b64Hash)
 
 	return encodedHash, nil
 }
 
-// Verify compares a password with a hash
+// Verify compares a password with a hash: an Argon2id hash from Hash, or a
+// legacy bcrypt hash, as user bases imported from other systems have. The
+// password is normalized like Hash does, and tried as typed as well for
+// hashes made before passwords were normalized.
 func (s *Service) Verify(password, encodedHash string) (bool, error) {
-	// Parse the encoded hash
-	parts := strings.Split(encodedHash, "$")
-	if len(parts) != 6 {
-		return false, fmt.Errorf("invalid hash format")
+	normalized := Normalize(password)
+	valid, err := s.verify(normalized, encodedHash)
+	if valid || err != nil || normalized == password {
+		return valid, err
 	}
+	return s.verify(password, encodedHash)
+}
 
-	if parts[1] != "argon2id" {
-		return false, fmt.Errorf("unsupported algorithm: %s", parts[1])
-	}
+// VerifyDummy verifies password against a hash no password matches, taking
+// as long as Verify takes for a user's hash. Callers that find no user to
+// verify a password for call it so the time they take to answer doesn't
+// reveal whether the user exists.
+func (s *Service) VerifyDummy(password string) {
+	_, _ = s.Verify(password, s.dummyHash)
+}
 
-	// Parse parameters
-	var version int
-	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
-		return false, fmt.Errorf("invalid version: %w", err)
-	}
+// Normalize returns password in Unicode normalization form NFKC, so the same
+// passphrase typed on keyboards that compose characters differently, such
+// as "é" as one code point or as "e" and a combining accent, hashes the same
+func Normalize(password string) string {
+	return norm.NFKC.String(password)
+}
 
-	var memory, iterations uint32
-	var parallelism uint8
-	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &iterations, &parallelism); err != nil {
-		return false, fmt.Errorf("invalid parameters: %w", err)
+// verify compares a password with a hash as it is
+func (s *Service) verify(password, encodedHash string) (bool, error) {
+	if isBcrypt(encodedHash) {
+		err := bcrypt.CompareHashAndPassword([]byte(encodedHash), []byte(password))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return false, nil
+		}
+		return err == nil, err
 	}
 
-	// Decode salt
-	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	hash, err := parseHash(encodedHash)
 	if err != nil {
-		return false, fmt.Errorf("invalid salt: %w", err)
+		return false, err
 	}
 
-	// Decode hash
-	decodedHash, err := base64.RawStdEncoding.DecodeString(parts[5])
+	input, err := s.pepper(password, hash.pepperID)
 	if err != nil {
-		return false, fmt.Errorf("invalid hash: %w", err)
+		return false, err
 	}
 
 	// Generate hash with the same parameters
 	comparisonHash := argon2.IDKey(
-		[]byte(password),
-		salt,
-		iterations,
-		memory,
-		parallelism,
-		uint32(len(decodedHash)),
+		input,
+		hash.salt,
+		hash.iterations,
+		hash.memory,
+		hash.parallelism,
+		uint32(len(hash.key)),
 	)
 
 	// Use constant-time comparison to prevent timing attacks
-	if subtle.ConstantTimeCompare(decodedHash, comparisonHash) == 1 {
+	if subtle.ConstantTimeCompare(hash.key, comparisonHash) == 1 {
 		return true, nil
 	}
 
 	return false, nil
 }
 
-// ValidateStrength checks if a password meets strength requirements
-func (s *Service) ValidateStrength(password string) error {
-	if len(password) < 8 {
-		return fmt.Errorf("password must be at least 8 characters long")
+// NeedsRehash reports whether a hash that verified should be replaced with
+// one from Hash: true for bcrypt hashes, so users migrate to Argon2id as
+// they sign in, and for Argon2id hashes whose parameters or pepper differ
+// from the configured ones, so changing them takes effect without forcing
+// resets
+func (s *Service) NeedsRehash(encodedHash string) bool {
+	if isBcrypt(encodedHash) {
+		return true
 	}
 
-	if len(password) > 128 {
-		return fmt.Errorf("password must not exceed 128 characters")
+	hash, err := parseHash(encodedHash)
+	if err != nil {
+		return false
 	}
+	cfg := s.config.Argon2
+	return hash.version != argon2.Version ||
+		hash.memory != cfg.Memory ||
+		hash.iterations != cfg.Iterations ||
+		hash.parallelism != cfg.Parallelism ||
+		hash.pepperID != s.activePepper ||
+		uint32(len(hash.salt)) != cfg.SaltLength ||
+		uint32(len(hash.key)) != cfg.KeyLength
+}
 
-	var (
-		hasUpper   bool
-		hasLower   bool
-		hasNumber  bool
-		hasSpecial bool
-	)
+// argon2Hash is a decoded Argon2id hash
+type argon2Hash struct {
+	version     int
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+	// pepperID names the pepper the password was keyed with, if any
+	pepperID string
+	salt     []byte
+	key      []byte
+}
 
-	for _, char := range password {
-		switch {
-		case char >= 'A' && char <= 'Z':
-			hasUpper = true
-		case char >= 'a' && char <= 'z':
-			hasLower = true
-		case char >= '0' && char <= '9':
-			hasNumber = true
-		case char >= '!' && char <= '/' || char >= ':' && char <= '@' || char >= '[' && char <= '`' || char >= '{' && char <= '~':
-			hasSpecial = true
-		}
+// parseHash decodes a hash in the format Hash produces
+func parseHash(encodedHash string) (*argon2Hash, error) {
+	parts := strings.Split(encodedHash, "$")
+	if len(parts) != 6 {
+		return nil, fmt.Errorf("invalid hash format")
+	}
+
+	if parts[1] != "argon2id" {
+		return nil, fmt.Errorf("unsupported algorithm: %s", parts[1])
+	}
+
+	// Parse parameters
+	var hash argon2Hash
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &hash.version); err != nil {
+		return nil, fmt.Errorf("invalid version: %w", err)
 	}
 
-	if !hasUpper {
-		return fmt.Errorf("password must contain at least one uppercase letter")
+	params, pepperID, _ := strings.Cut(parts[3], ",keyid=")
+	hash.pepperID = pepperID
+	if _, err := fmt.Sscanf(params, "m=%d,t=%d,p=%d", &hash.memory, &hash.iterations, &hash.parallelism); err != nil {
+		return nil, fmt.Errorf("invalid parameters: %w", err)
 	}
-	if !hasLower {
-		return fmt.Errorf("password must contain at least one lowercase letter")
+	// argon2.IDKey panics on no threads, and the others bound the cost
+	if hash.iterations < 1 || hash.iterations > MaxIterations {
+		return nil, fmt.Errorf("invalid parameters: t=%d out of range", hash.iterations)
 	}
-	if !hasNumber {
-		return fmt.Errorf("password must contain at least one number")
+	if hash.parallelism < 1 {
+		return nil, fmt.Errorf("invalid parameters: p=%d out of range", hash.parallelism)
 	}
-	if !hasSpecial {
-		return fmt.Errorf("password must contain at least one special character")
+	if hash.memory > MaxMemory {
+		return nil, fmt.Errorf("invalid parameters: m=%d out of range", hash.memory)
 	}
 
-	return nil
+	// Decode salt
+	var err error
+	hash.salt, err = base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return nil, fmt.Errorf("invalid salt: %w", err)
+	}
+
+	// Decode hash
+	hash.key, err = base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return nil, fmt.Errorf("invalid hash: %w", err)
+	}
+
+	return &hash, nil
+}
+
+// pepper returns the Argon2 input for password: password keyed with the
+// pepper pepperID names by HMAC-SHA256, or password itself if it names none
+func (s *Service) pepper(password, pepperID string) ([]byte, error) {
+	if pepperID == "" {
+		return []byte(password), nil
+	}
+	pepper, ok := s.peppers[pepperID]
+	if !ok {
+		return nil, fmt.Errorf("pepper %q is not configured", pepperID)
+	}
+	mac := hmac.New(sha256.New, pepper)
+	mac.Write([]byte(password))
+	return mac.Sum(nil), nil
+}
+
+// isBcrypt reports whether encodedHash is a bcrypt hash ($2a$, $2b$ or $2y$)
+func isBcrypt(encodedHash string) bool {
+	return strings.HasPrefix(encodedHash, "$2a$") ||
+		strings.HasPrefix(encodedHash, "$2b$") ||
+		strings.HasPrefix(encodedHash, "$2y$")
+}
+
+// Policy returns the rules new passwords must meet
+func (s *Service) Policy() *Policy {
+	return s.policy
+}
+
+// ValidateStrength checks that a new password meets the policy, returning
+// a *Violation if it doesn't. userInputs are what EstimateStrength tries
+// first, such as the user's name and email address.
+func (s *Service) ValidateStrength(password string, userInputs ...string) error {
+	return s.policy.Check(password, userInputs...)
 }
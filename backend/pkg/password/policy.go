@@ -0,0 +1,122 @@
+package password
+
+import (
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/config"
+)
+
+// Policy is the rules a new password must meet, set from PasswordConfig.
+// Hashing and verification don't apply it, so tightening it never locks
+// anyone out of an existing password.
+type Policy struct {
+	// MinLength and MaxLength bound the length of the password, in
+	// characters once normalized
+	MinLength int
+	MaxLength int
+	// MinScore is the lowest EstimateStrength score accepted
+	MinScore int
+	// BlockCommon rejects common passwords and near matches (see IsCommon)
+	BlockCommon bool
+
+	// blocklist is the passwords of PASSWORD_BLOCKLIST_PATH, which IsCommon
+	// checks besides the embedded list, and its shape
+	blocklist      map[string]struct{}
+	blocklistShape listShape
+}
+
+// Violation is a Policy rule a password breaks
+type Violation struct {
+	// Description completes a sentence about the password, such as "is too
+	// easy to guess"
+	Description string
+	// Strength is the password's estimate, for a score below MinScore
+	Strength *Strength
+}
+
+func (v *Violation) Error() string {
+	return "password " + v.Description
+}
+
+// NewPolicy returns the policy cfg describes, reading its blocklist file
+func NewPolicy(cfg config.PasswordConfig) (*Policy, error) {
+	policy := &Policy{
+		MinLength:   cfg.MinLength,
+		MaxLength:   cfg.MaxLength,
+		MinScore:    cfg.MinScore,
+		BlockCommon: cfg.BlockCommon,
+	}
+	if cfg.BlockCommon && cfg.BlocklistPath != "" {
+		blocklist, err := loadBlocklist(cfg.BlocklistPath)
+		if err != nil {
+			return nil, err
+		}
+		policy.blocklist = blocklist
+		policy.blocklistShape = shapeOf(blocklist)
+	}
+	return policy, nil
+}
+
+// Check returns a *Violation for the first rule password breaks, or nil if
+// it meets them all. userInputs are what EstimateStrength tries first, such
+// as the user's name and email address.
+func (p *Policy) Check(password string, userInputs ...string) error {
+	length := utf8.RuneCountInString(Normalize(password))
+	if length < p.MinLength {
+		return &Violation{Description: fmt.Sprintf("must be at least %d characters long", p.MinLength)}
+	}
+	if length > p.MaxLength {
+		return &Violation{Description: fmt.Sprintf("must not exceed %d characters", p.MaxLength)}
+	}
+
+	if p.IsCommon(password) {
+		return &Violation{Description: "is a commonly used password"}
+	}
+
+	if strength := EstimateStrength(password, userInputs...); strength.Score < p.MinScore {
+		if len(strength.Suggestions) == 0 {
+			// Scores EstimateStrength has no feedback for, when the policy
+			// asks for more
+			strength.Suggestions = []string{suggestionAddWord}
+		}
+		return &Violation{Description: "is too easy to guess", Strength: &strength}
+	}
+
+	return nil
+}
+
+// IsCommon reports whether password is a common password, or nearly is: with
+// other capitals, l33t substitutions such as "@" for "a", digits and symbols
+// before or after it, or, from six characters, one character inserted,
+// deleted or changed. It is false if BlockCommon is off.
+func (p *Policy) IsCommon(password string) bool {
+	if !p.BlockCommon {
+		return false
+	}
+
+	candidates := commonCandidates(password)
+	for _, candidate := range candidates {
+		if p.isListed(candidate) {
+			return true
+		}
+	}
+	for _, candidate := range candidates {
+		if isNearMatchCandidate(candidate) &&
+			(commonShape.hasNearMatch(candidate, isCommonPassword) ||
+				p.blocklistShape.hasNearMatch(candidate, p.isBlocklisted)) {
+			return true
+		}
+	}
+	return false
+}
+
+// isListed reports whether candidate is in the embedded list or the blocklist
+func (p *Policy) isListed(candidate string) bool {
+	return isCommonPassword(candidate) || p.isBlocklisted(candidate)
+}
+
+func (p *Policy) isBlocklisted(candidate string) bool {
+	_, ok := p.blocklist[candidate]
+	return ok
+}
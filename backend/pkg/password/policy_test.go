@@ -0,0 +1,87 @@
+package password
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sahays/grpc-proto-go-flutter-template/internal/config"
+)
+
+func TestPolicyCheck(t *testing.T) {
+	blocklistPath := filepath.Join(t.TempDir(), "blocklist.txt")
+	if err := os.WriteFile(blocklistPath, []byte("Violet-Tundra\n\n  ledger-staple  \n"), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	defaults := config.PasswordConfig{MinLength: 8, MaxLength: 128, MinScore: 3, BlockCommon: true}
+	// lenient checks length only
+	lenient := config.PasswordConfig{MinLength: 8, MaxLength: 128}
+	withBlocklist := defaults
+	withBlocklist.BlocklistPath = blocklistPath
+
+	tests := []struct {
+		name     string
+		cfg      config.PasswordConfig
+		password string
+		// want is the violation's description, empty for none
+		want         string
+		wantStrength bool
+	}{
+		{name: "strong", cfg: defaults, password: "correct horse battery staple"},
+		{name: "too short", cfg: defaults, password: "short", want: "must be at least 8 characters long"},
+		{name: "too long", cfg: defaults, password: strings.Repeat("a", 129), want: "must not exceed 128 characters"},
+		{name: "length counts characters, not bytes", cfg: lenient, password: strings.Repeat("é", 128)},
+		{name: "length counts normalized characters", cfg: lenient, password: "ﬀﬀﬀﬀ"},
+		{name: "common", cfg: defaults, password: "password", want: "is a commonly used password"},
+		{name: "common with substitutions", cfg: defaults, password: "P@ssw0rd!", want: "is a commonly used password"},
+		{name: "common with digits after", cfg: defaults, password: "Password2024", want: "is a commonly used password"},
+		{name: "one character from common", cfg: defaults, password: "passwxrd", want: "is a commonly used password"},
+		{name: "common, not blocked", cfg: lenient, password: "password"},
+		{name: "blocklist", cfg: withBlocklist, password: "violet-tundra", want: "is a commonly used password"},
+		{name: "blocklist, trimmed entry", cfg: withBlocklist, password: "Ledger-Staple!", want: "is a commonly used password"},
+		{name: "blocklisted only where configured", cfg: defaults, password: "violet-tundra-ledger-87"},
+		{name: "built from user inputs", cfg: defaults, password: "lovelace1815", want: "is too easy to guess", wantStrength: true},
+		{name: "score below minimum", cfg: defaults, password: "Lovelace1815!", want: "is too easy to guess", wantStrength: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy, err := NewPolicy(tt.cfg)
+			if err != nil {
+				t.Fatalf("NewPolicy: %v", err)
+			}
+
+			err = policy.Check(tt.password, "ada@example.com", "Ada", "Lovelace")
+			if tt.want == "" {
+				if err != nil {
+					t.Fatalf("Check: %v", err)
+				}
+				return
+			}
+
+			var violation *Violation
+			if !errors.As(err, &violation) {
+				t.Fatalf("Check error = %v, want a *Violation", err)
+			}
+			if violation.Description != tt.want {
+				t.Errorf("Description = %q, want %q", violation.Description, tt.want)
+			}
+			if tt.wantStrength {
+				if violation.Strength == nil || violation.Strength.Score >= tt.cfg.MinScore || len(violation.Strength.Suggestions) == 0 {
+					t.Errorf("Strength = %+v, want a score below %d with suggestions", violation.Strength, tt.cfg.MinScore)
+				}
+			} else if violation.Strength != nil {
+				t.Errorf("Strength = %+v, want none", violation.Strength)
+			}
+		})
+	}
+}
+
+func TestNewPolicyMissingBlocklist(t *testing.T) {
+	cfg := config.PasswordConfig{MinLength: 8, MaxLength: 128, BlockCommon: true, BlocklistPath: filepath.Join(t.TempDir(), "missing.txt")}
+	if _, err := NewPolicy(cfg); err == nil {
+		t.Error("NewPolicy succeeded without its blocklist file")
+	}
+}
@@ -0,0 +1,760 @@
+package password
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// feedbackScore is the score from which EstimateStrength gives no feedback,
+// as zxcvbn does
+const feedbackScore = 3
+
+// Strength is an estimate of how hard a password is to guess, in the manner
+// of zxcvbn: the password is split into the patterns an attacker would try
+// first (common passwords and words, keyboard rows, sequences, repeats,
+// dates), and the number of guesses is that of the cheapest split.
+type Strength struct {
+	// Score is 0 (too guessable) to 4 (very unguessable)
+	Score int
+	// GuessesLog10 is the estimated number of guesses, as a power of ten
+	GuessesLog10 float64
+	// Warning explains what makes the password weak; empty if it isn't
+	Warning string
+	// Suggestions are ways to make the password stronger
+	Suggestions []string
+}
+
+// Feedback for weak passwords. They are shown to users, so each has an
+// entry in the i18n catalogs.
+const (
+	warningTop10           = "This is a top-10 common password"
+	warningTop100          = "This is a top-100 common password"
+	warningCommon          = "This is a very common password"
+	warningSimilarToCommon = "This is similar to a commonly used password"
+	warningWord            = "A word by itself is easy to guess"
+	warningPersonal        = "Your name or email address is easy to guess"
+	warningStraightRow     = "Straight rows of keys are easy to guess"
+	warningRepeatedChar    = `Repeats like "aaa" are easy to guess`
+	warningRepeated        = `Repeats like "abcabcabc" are only slightly harder to guess than "abc"`
+	warningSequence        = "Sequences like abc or 6543 are easy to guess"
+	warningYear            = "Recent years are easy to guess"
+	warningDate            = "Dates are often easy to guess"
+
+	suggestionWords        = "Use a few words, avoid common phrases"
+	suggestionNoSymbols    = "No need for symbols, digits, or uppercase letters"
+	suggestionAddWord      = "Add another word or two. Uncommon words are better."
+	suggestionCapitals     = "Capitalization doesn't help very much"
+	suggestionAllUppercase = "All-uppercase is almost as easy to guess as all-lowercase"
+	suggestionReversed     = "Reversed words aren't much harder to guess"
+	suggestionSubstitution = "Predictable substitutions like '@' instead of 'a' don't help very much"
+	suggestionKeyboard     = "Use a longer keyboard pattern with more turns"
+	suggestionRepeats      = "Avoid repeated words and characters"
+	suggestionSequences    = "Avoid sequences"
+	suggestionYears        = "Avoid recent years and years that are associated with you"
+	suggestionDates        = "Avoid dates and years that are associated with you"
+)
+
+// Guess counts of the estimator, from zxcvbn
+const (
+	// minGuessesBeforeGrowingSequenceLog10 penalizes splitting a password
+	// into more patterns: each one past the first costs 10^4 guesses
+	minGuessesBeforeGrowingSequenceLog10 = 4
+	// bruteforceCardinality is the guesses per character not in a pattern
+	bruteforceCardinality = 10
+	// referenceYear is the year years are guessed outward from
+	referenceYear = 2026
+	// minYearSpace is the fewest years a year or date is guessed among
+	minYearSpace = 20
+	// keyboardStartingPositions and keyboardAverageDegree describe the
+	// qwerty layout: the keys a pattern can start on and the average
+	// number of keys next to each
+	keyboardStartingPositions = 94
+	keyboardAverageDegree     = 4.6
+)
+
+// Kinds of pattern a password is split into
+const (
+	patternDictionary = iota
+	patternSpatial
+	patternRepeat
+	patternSequence
+	patternDate
+	patternBruteforce
+)
+
+// Dictionaries a dictionary pattern is found in
+const (
+	dictionaryPasswords = iota
+	dictionaryWords
+	dictionaryPersonal
+)
+
+// match is a pattern found in the runes i to j of a password, inclusive
+type match struct {
+	pattern int
+	i, j    int
+	token   string
+	// guessesLog10 is how many guesses finding the pattern takes
+	guessesLog10 float64
+
+	// Dictionary patterns
+	dictionary int
+	rank       int
+	reversed   bool
+	l33t       bool
+	// Repeat patterns: the repeated token
+	base string
+	// Date patterns: whether only a year was found
+	yearOnly bool
+}
+
+// EstimateStrength estimates how hard password is to guess, once normalized
+// as it is hashed. userInputs are what an attacker targeting the user would
+// try, such as their name and email address.
+func EstimateStrength(password string, userInputs ...string) Strength {
+	personal := make(map[string]int)
+	for _, input := range userInputs {
+		for _, word := range strings.FieldsFunc(strings.ToLower(input), func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		}) {
+			if _, ok := personal[word]; !ok && len(word) > 1 {
+				personal[word] = len(personal) + 1
+			}
+		}
+	}
+
+	guessesLog10, sequence := mostGuessableSequence(Normalize(password), personal)
+	strength := Strength{
+		Score:        score(guessesLog10),
+		GuessesLog10: guessesLog10,
+	}
+	strength.Warning, strength.Suggestions = feedback(strength.Score, sequence)
+	return strength
+}
+
+// score maps a guess count to a score, at the thresholds zxcvbn uses
+func score(guessesLog10 float64) int {
+	switch {
+	case guessesLog10 < 3:
+		return 0
+	case guessesLog10 < 6:
+		return 1
+	case guessesLog10 < 8:
+		return 2
+	case guessesLog10 < 10:
+		return 3
+	default:
+		return 4
+	}
+}
+
+// mostGuessableSequence splits password into the patterns that take the
+// fewest guesses together and returns that count with the patterns. A split
+// into l patterns takes l! times the product of their guesses, as they may
+// come in any order, plus the penalty for l.
+func mostGuessableSequence(password string, personal map[string]int) (float64, []match) {
+	runes := []rune(password)
+	n := len(runes)
+	if n == 0 {
+		return 0, nil
+	}
+
+	matches := findMatches(runes, personal)
+	byEnd := make([][]match, n)
+	for _, m := range matches {
+		byEnd[m.j] = append(byEnd[m.j], m)
+	}
+
+	// best[k][l] is the cheapest split of the first k+1 runes into l
+	// patterns: the log10 of the product of their guesses, and the last
+	// pattern, to trace the split back from
+	type step struct {
+		productLog10 float64
+		last         match
+		ok           bool
+	}
+	best := make([][]step, n)
+	for k := range best {
+		best[k] = make([]step, n+1)
+	}
+	consider := func(m match) {
+		if m.i == 0 {
+			if s := &best[m.j][1]; !s.ok || m.guessesLog10 < s.productLog10 {
+				*s = step{productLog10: m.guessesLog10, last: m, ok: true}
+			}
+			return
+		}
+		for l, prev := range best[m.i-1] {
+			if !prev.ok {
+				continue
+			}
+			product := prev.productLog10 + m.guessesLog10
+			if s := &best[m.j][l+1]; !s.ok || product < s.productLog10 {
+				*s = step{productLog10: product, last: m, ok: true}
+			}
+		}
+	}
+	for k := 0; k < n; k++ {
+		for _, m := range byEnd[k] {
+			consider(m)
+		}
+		for i := 0; i <= k; i++ {
+			consider(bruteforceMatch(runes, i, k))
+		}
+	}
+
+	bestLog10, bestLength := math.Inf(1), 0
+	for l, s := range best[n-1] {
+		if !s.ok {
+			continue
+		}
+		factorialLog10, _ := math.Lgamma(float64(l + 1))
+		total := addLog10(factorialLog10/math.Ln10+s.productLog10, float64(minGuessesBeforeGrowingSequenceLog10*(l-1)))
+		if total < bestLog10 {
+			bestLog10, bestLength = total, l
+		}
+	}
+
+	sequence := make([]match, bestLength)
+	for k, l := n-1, bestLength; l > 0; l-- {
+		m := best[k][l].last
+		sequence[l-1] = m
+		k = m.i - 1
+	}
+	return bestLog10, sequence
+}
+
+// addLog10 returns log10(10^a + 10^b) without overflowing
+func addLog10(a, b float64) float64 {
+	if a < b {
+		a, b = b, a
+	}
+	return a + math.Log10(1+math.Pow(10, b-a))
+}
+
+// findMatches returns every pattern found in runes
+func findMatches(runes []rune, personal map[string]int) []match {
+	lower := []rune(strings.ToLower(string(runes)))
+	if len(lower) != len(runes) {
+		// Lowercasing changed the length, so indexes wouldn't line up
+		lower = runes
+	}
+
+	var matches []match
+	matches = append(matches, dictionaryMatches(runes, lower)...)
+	matches = append(matches, personalMatches(runes, lower, personal)...)
+	matches = append(matches, spatialMatches(runes)...)
+	matches = append(matches, sequenceMatches(runes)...)
+	matches = append(matches, dateMatches(runes)...)
+	matches = append(matches, repeatMatches(runes, personal)...)
+	return matches
+}
+
+// dictionaryMatches finds common passwords and words, including reversed
+// and with l33t substitutions
+func dictionaryMatches(runes, lower []rune) []match {
+	var matches []match
+	for _, variant := range l33tVariants(lower) {
+		for i := range variant {
+			for j := i + 1; j < len(variant) && j-i < maxWordLength; j++ {
+				word := string(variant[i : j+1])
+				l33t := word != string(lower[i:j+1])
+				if !l33t && &variant[0] != &lower[0] {
+					// Found in lower already
+					continue
+				}
+				if rank, ok := commonPasswords[word]; ok {
+					matches = append(matches, dictionaryMatch(runes, i, j, dictionaryPasswords, rank, false, l33t))
+				}
+				if rank, ok := commonWords[word]; ok {
+					matches = append(matches, dictionaryMatch(runes, i, j, dictionaryWords, rank, false, l33t))
+				}
+			}
+		}
+	}
+
+	// Reversed words, found in the reversed password
+	n := len(lower)
+	reversed := make([]rune, n)
+	for i, r := range lower {
+		reversed[n-1-i] = r
+	}
+	for i := range reversed {
+		for j := i + 2; j < n && j-i < maxWordLength; j++ {
+			word := string(reversed[i : j+1])
+			if isPalindrome(word) {
+				continue
+			}
+			if rank, ok := commonPasswords[word]; ok {
+				matches = append(matches, dictionaryMatch(runes, n-1-j, n-1-i, dictionaryPasswords, rank, true, false))
+			}
+			if rank, ok := commonWords[word]; ok {
+				matches = append(matches, dictionaryMatch(runes, n-1-j, n-1-i, dictionaryWords, rank, true, false))
+			}
+		}
+	}
+	return matches
+}
+
+// personalMatches finds the user's own words, such as their name
+func personalMatches(runes, lower []rune, personal map[string]int) []match {
+	var matches []match
+	for i := range lower {
+		for j := i + 1; j < len(lower); j++ {
+			if rank, ok := personal[string(lower[i:j+1])]; ok {
+				matches = append(matches, dictionaryMatch(runes, i, j, dictionaryPersonal, rank, false, false))
+			}
+		}
+	}
+	return matches
+}
+
+// dictionaryMatch returns the match of the dictionary word of rank found in
+// runes i to j. Capitals, reversal and substitutions each multiply the
+// guesses by the number of ways they could have been applied.
+func dictionaryMatch(runes []rune, i, j, dictionary, rank int, reversed, l33t bool) match {
+	token := string(runes[i : j+1])
+	guesses := math.Log10(float64(rank)) + math.Log10(uppercaseVariations(token))
+	if reversed {
+		guesses += math.Log10(2)
+	}
+	if l33t {
+		guesses += math.Log10(l33tVariations(token))
+	}
+	return match{
+		pattern:      patternDictionary,
+		i:            i,
+		j:            j,
+		token:        token,
+		guessesLog10: guesses,
+		dictionary:   dictionary,
+		rank:         rank,
+		reversed:     reversed,
+		l33t:         l33t,
+	}
+}
+
+// uppercaseVariations is how many ways token's capitals could be placed:
+// 1 for none, 2 for a capital first or last letter or all capitals, and
+// the number of mixes of as many capitals otherwise
+func uppercaseVariations(token string) float64 {
+	var upper, lower int
+	for _, r := range token {
+		switch {
+		case unicode.IsUpper(r):
+			upper++
+		case unicode.IsLower(r):
+			lower++
+		}
+	}
+	if upper == 0 {
+		return 1
+	}
+	runes := []rune(token)
+	if lower == 0 || upper == 1 && (unicode.IsUpper(runes[0]) || unicode.IsUpper(runes[len(runes)-1])) {
+		return 2
+	}
+
+	var variations float64
+	for k := 1; k <= upper && k <= lower; k++ {
+		variations += binomial(upper+lower, k)
+	}
+	return variations
+}
+
+// l33tVariations is how many ways token's substitutions could be made: 2
+// for each substituted character
+func l33tVariations(token string) float64 {
+	variations := 1.0
+	for _, r := range token {
+		if _, ok := l33tTable[unicode.ToLower(r)]; ok {
+			variations *= 2
+		}
+	}
+	return variations
+}
+
+// l33tTable maps the characters commonly substituted for letters to them
+var l33tTable = map[rune][]rune{
+	'4': {'a'},
+	'@': {'a'},
+	'8': {'b'},
+	'(': {'c'},
+	'3': {'e'},
+	'6': {'g'},
+	'1': {'i', 'l'},
+	'!': {'i'},
+	'|': {'i', 'l'},
+	'0': {'o'},
+	'$': {'s'},
+	'5': {'s'},
+	'7': {'t'},
+	'+': {'t'},
+	'2': {'z'},
+}
+
+// l33tVariants returns lower and, if it has l33t characters, lower with
+// them replaced by the letters they stand for, one variant per choice of
+// letter
+func l33tVariants(lower []rune) [][]rune {
+	variants := [][]rune{lower}
+	substituted := make([]rune, len(lower))
+	copy(substituted, lower)
+	changed := false
+	for i, r := range lower {
+		if letters, ok := l33tTable[r]; ok {
+			substituted[i] = letters[0]
+			changed = true
+		}
+	}
+	if !changed {
+		return variants
+	}
+	variants = append(variants, substituted)
+
+	// The second letter of ambiguous characters, such as 1 for l
+	alternative := make([]rune, len(substituted))
+	copy(alternative, substituted)
+	ambiguous := false
+	for i, r := range lower {
+		if letters := l33tTable[r]; len(letters) > 1 {
+			alternative[i] = letters[1]
+			ambiguous = true
+		}
+	}
+	if ambiguous {
+		variants = append(variants, alternative)
+	}
+	return variants
+}
+
+// keyboardRows are the rows of the qwerty layout, unshifted and shifted
+var keyboardRows = []string{
+	"`1234567890-=",
+	"qwertyuiop[]\\",
+	"asdfghjkl;'",
+	"zxcvbnm,./",
+	"~!@#$%^&*()_+",
+	"QWERTYUIOP{}|",
+	"ASDFGHJKL:\"",
+	"ZXCVBNM<>?",
+}
+
+// spatialMatches finds runs of at least three keys along a keyboard row,
+// in either direction
+func spatialMatches(runes []rune) []match {
+	var matches []match
+	for i := 0; i+2 < len(runes); {
+		row, direction := keyboardStep(runes[i], runes[i+1])
+		j := i + 1
+		for row != "" && j+1 < len(runes) {
+			next := strings.IndexRune(row, runes[j+1])
+			if next < 0 || next-strings.IndexRune(row, runes[j]) != direction {
+				break
+			}
+			j++
+		}
+		if j-i < 2 {
+			i++
+			continue
+		}
+
+		token := string(runes[i : j+1])
+		guesses := math.Log10(keyboardStartingPositions * keyboardAverageDegree * float64(j-i))
+		if strings.ContainsAny(token, "~!@#$%^&*()_+{}|:\"<>?") || strings.ToLower(token) != token {
+			// Shifted keys
+			guesses += math.Log10(2)
+		}
+		matches = append(matches, match{pattern: patternSpatial, i: i, j: j, token: token, guessesLog10: guesses})
+		i = j + 1
+	}
+	return matches
+}
+
+// keyboardStep returns the keyboard row a and b are next to each other in,
+// and the direction from a to b along it, 1 or -1; "" if they aren't
+func keyboardStep(a, b rune) (string, int) {
+	for _, row := range keyboardRows {
+		ia, ib := strings.IndexRune(row, a), strings.IndexRune(row, b)
+		if ia >= 0 && ib >= 0 && (ib-ia == 1 || ib-ia == -1) {
+			return row, ib - ia
+		}
+	}
+	return "", 0
+}
+
+// sequenceMatches finds runs of at least three characters that step by
+// one up or down, such as "abc" or "9876"
+func sequenceMatches(runes []rune) []match {
+	var matches []match
+	for i := 0; i+2 < len(runes); {
+		delta := runes[i+1] - runes[i]
+		j := i + 1
+		if delta == 1 || delta == -1 {
+			for j+1 < len(runes) && runes[j+1]-runes[j] == delta {
+				j++
+			}
+		}
+		if j-i >= 2 {
+			token := string(runes[i : j+1])
+			var base float64
+			switch first := unicode.ToLower(runes[i]); {
+			case strings.ContainsRune("az019", first):
+				base = 4
+			case unicode.IsDigit(first):
+				base = 10
+			default:
+				base = 26
+			}
+			if delta < 0 {
+				base *= 2
+			}
+			matches = append(matches, match{
+				pattern:      patternSequence,
+				i:            i,
+				j:            j,
+				token:        token,
+				guessesLog10: math.Log10(base * float64(j-i+1)),
+			})
+			i = j
+			continue
+		}
+		i++
+	}
+	return matches
+}
+
+// dateMatches finds years from 1900 to 2099 and dates of six or eight
+// digits, such as "310199" or "19990131", in any order of day, month and
+// year
+func dateMatches(runes []rune) []match {
+	var matches []match
+	for i := range runes {
+		for _, length := range []int{4, 6, 8} {
+			j := i + length - 1
+			if j >= len(runes) || !allDigits(runes[i:j+1]) {
+				continue
+			}
+			token := string(runes[i : j+1])
+			year, yearOnly := 0, length == 4
+			if yearOnly {
+				year, _ = strconv.Atoi(token)
+				if year < 1900 || year > 2099 {
+					continue
+				}
+			} else if year = parseDate(token); year == 0 {
+				continue
+			}
+
+			yearSpace := math.Max(math.Abs(float64(year-referenceYear)), minYearSpace)
+			guesses := yearSpace
+			if !yearOnly {
+				guesses *= 365
+			}
+			matches = append(matches, match{
+				pattern:      patternDate,
+				i:            i,
+				j:            j,
+				token:        token,
+				guessesLog10: math.Log10(guesses),
+				yearOnly:     yearOnly,
+			})
+		}
+	}
+	return matches
+}
+
+// parseDate returns the year of a date of six or eight digits, or 0 if the
+// digits aren't one
+func parseDate(digits string) int {
+	yearDigits := len(digits) - 4
+	splits := [][3]int{
+		// Offsets of day, month and year
+		{0, 2, 4},                       // ddmmyy(yy)
+		{2, 0, 4},                       // mmddyy(yy)
+		{yearDigits + 2, yearDigits, 0}, // yy(yy)mmdd
+	}
+	for _, split := range splits {
+		day, _ := strconv.Atoi(digits[split[0] : split[0]+2])
+		month, _ := strconv.Atoi(digits[split[1] : split[1]+2])
+		year, _ := strconv.Atoi(digits[split[2] : split[2]+yearDigits])
+		if day < 1 || day > 31 || month < 1 || month > 12 {
+			continue
+		}
+		if yearDigits == 2 {
+			// Two-digit years are the closest to the reference year
+			if year > referenceYear%100 {
+				year += 1900
+			} else {
+				year += 2000
+			}
+		}
+		if year >= 1900 && year <= 2099 {
+			return year
+		}
+	}
+	return 0
+}
+
+// repeatMatches finds a token repeated at least twice in a row, such as
+// "aaa" or "abcabc". Guessing one takes the guesses of its token times the
+// number of repeats.
+func repeatMatches(runes []rune, personal map[string]int) []match {
+	var matches []match
+	for i := 0; i < len(runes); {
+		bestLength, bestBase := 0, 0
+		for base := 1; i+2*base <= len(runes); base++ {
+			repeats := 1
+			for i+(repeats+1)*base <= len(runes) && runesEqual(runes[i:i+base], runes[i+repeats*base:i+(repeats+1)*base]) {
+				repeats++
+			}
+			if repeats >= 2 && repeats*base > bestLength {
+				bestLength, bestBase = repeats*base, base
+			}
+		}
+		if bestLength == 0 {
+			i++
+			continue
+		}
+
+		base := string(runes[i : i+bestBase])
+		baseGuesses, _ := mostGuessableSequence(base, personal)
+		j := i + bestLength - 1
+		matches = append(matches, match{
+			pattern:      patternRepeat,
+			i:            i,
+			j:            j,
+			token:        string(runes[i : j+1]),
+			guessesLog10: baseGuesses + math.Log10(float64(bestLength/bestBase)),
+			base:         base,
+		})
+		i = j + 1
+	}
+	return matches
+}
+
+// bruteforceMatch is runes i to j guessed character by character
+func bruteforceMatch(runes []rune, i, j int) match {
+	length := j - i + 1
+	guesses := float64(length) * math.Log10(bruteforceCardinality)
+	// A single character needs at least as many guesses as a digit, and
+	// longer ones more than any pattern of the same length
+	if length == 1 {
+		guesses = math.Max(guesses, math.Log10(bruteforceCardinality+1))
+	} else {
+		guesses = math.Max(guesses, math.Log10(51))
+	}
+	return match{pattern: patternBruteforce, i: i, j: j, token: string(runes[i : j+1]), guessesLog10: guesses}
+}
+
+// feedback returns the warning and suggestions for a password of score
+// split into sequence. Strong passwords get none; weak ones are told about
+// their longest pattern, which is usually what makes them weak.
+func feedback(score int, sequence []match) (string, []string) {
+	if score >= feedbackScore {
+		return "", nil
+	}
+	if len(sequence) == 0 {
+		return "", []string{suggestionWords, suggestionNoSymbols}
+	}
+
+	// Characters guessed one by one have nothing to warn about
+	longest := match{pattern: patternBruteforce}
+	for _, m := range sequence {
+		if m.pattern != patternBruteforce && len(m.token) > len(longest.token) {
+			longest = m
+		}
+	}
+
+	warning, suggestions := matchFeedback(longest, len(sequence) == 1)
+	return warning, append([]string{suggestionAddWord}, suggestions...)
+}
+
+// matchFeedback returns the warning and suggestions for m; sole reports
+// whether m is the whole password
+func matchFeedback(m match, sole bool) (string, []string) {
+	switch m.pattern {
+	case patternDictionary:
+		var warning string
+		switch {
+		case m.dictionary == dictionaryPasswords && sole && !m.l33t && !m.reversed && m.rank <= 10:
+			warning = warningTop10
+		case m.dictionary == dictionaryPasswords && sole && !m.l33t && !m.reversed && m.rank <= 100:
+			warning = warningTop100
+		case m.dictionary == dictionaryPasswords && sole && !m.l33t && !m.reversed:
+			warning = warningCommon
+		case m.dictionary == dictionaryPasswords && m.guessesLog10 <= 4:
+			warning = warningSimilarToCommon
+		case m.dictionary == dictionaryWords && sole:
+			warning = warningWord
+		case m.dictionary == dictionaryPersonal:
+			warning = warningPersonal
+		}
+
+		var suggestions []string
+		runes := []rune(m.token)
+		switch {
+		case strings.ToUpper(m.token) == m.token && strings.ToLower(m.token) != m.token:
+			suggestions = append(suggestions, suggestionAllUppercase)
+		case unicode.IsUpper(runes[0]):
+			suggestions = append(suggestions, suggestionCapitals)
+		}
+		if m.reversed {
+			suggestions = append(suggestions, suggestionReversed)
+		}
+		if m.l33t {
+			suggestions = append(suggestions, suggestionSubstitution)
+		}
+		return warning, suggestions
+	case patternSpatial:
+		return warningStraightRow, []string{suggestionKeyboard}
+	case patternRepeat:
+		if len([]rune(m.base)) == 1 {
+			return warningRepeatedChar, []string{suggestionRepeats}
+		}
+		return warningRepeated, []string{suggestionRepeats}
+	case patternSequence:
+		return warningSequence, []string{suggestionSequences}
+	case patternDate:
+		if m.yearOnly {
+			return warningYear, []string{suggestionYears}
+		}
+		return warningDate, []string{suggestionDates}
+	default:
+		return "", nil
+	}
+}
+
+// binomial returns n choose k
+func binomial(n, k int) float64 {
+	result := 1.0
+	for i := 1; i <= k; i++ {
+		result = result * float64(n-k+i) / float64(i)
+	}
+	return result
+}
+
+func allDigits(runes []rune) bool {
+	for _, r := range runes {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func runesEqual(a, b []rune) bool {
+	return string(a) == string(b)
+}
+
+func isPalindrome(s string) bool {
+	runes := []rune(s)
+	for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+		if runes[i] != runes[j] {
+			return false
+		}
+	}
+	return true
+}
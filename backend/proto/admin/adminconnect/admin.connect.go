@@ -0,0 +1,479 @@
+// Code generated by protoc-gen-connect-go. DO NOT EDIT.
+//
+// Source: admin/admin.proto
+
+package adminconnect
+
+import (
+	connect "connectrpc.com/connect"
+	context "context"
+	errors "errors"
+	admin "github.com/sahays/grpc-proto-go-flutter-template/proto/admin"
+	http "net/http"
+	strings "strings"
+)
+
+// This is a compile-time assertion to ensure that this generated file and the connect package are
+// compatible. If you get a compiler error that this constant is not defined, this code was
+// generated with a version of connect newer than the one compiled into your binary. You can fix the
+// problem by either regenerating this code with an older version of connect or updating the connect
+// version compiled into your binary.
+const _ = connect.IsAtLeastVersion1_13_0
+
+const (
+	// AdminServiceName is the fully-qualified name of the AdminService service.
+	AdminServiceName = "admin.AdminService"
+)
+
+// These constants are the fully-qualified names of the RPCs defined in this package. They're
+// exposed at runtime as Spec.Procedure and as the final two segments of the HTTP route.
+//
+// Note that these are different from the fully-qualified method names used by
+// google.golang.org/protobuf/reflect/protoreflect. To convert from these constants to
+// reflection-formatted method names, remove the leading slash and convert the remaining slash to a
+// period.
+const (
+	// AdminServiceDenyIPProcedure is the fully-qualified name of the AdminService's DenyIP RPC.
+	AdminServiceDenyIPProcedure = "/admin.AdminService/DenyIP"
+	// AdminServiceRemoveDeniedIPProcedure is the fully-qualified name of the AdminService's
+	// RemoveDeniedIP RPC.
+	AdminServiceRemoveDeniedIPProcedure = "/admin.AdminService/RemoveDeniedIP"
+	// AdminServiceListDeniedIPsProcedure is the fully-qualified name of the AdminService's
+	// ListDeniedIPs RPC.
+	AdminServiceListDeniedIPsProcedure = "/admin.AdminService/ListDeniedIPs"
+	// AdminServiceCreateTenantProcedure is the fully-qualified name of the AdminService's CreateTenant
+	// RPC.
+	AdminServiceCreateTenantProcedure = "/admin.AdminService/CreateTenant"
+	// AdminServiceGetTenantProcedure is the fully-qualified name of the AdminService's GetTenant RPC.
+	AdminServiceGetTenantProcedure = "/admin.AdminService/GetTenant"
+	// AdminServiceListTenantsProcedure is the fully-qualified name of the AdminService's ListTenants
+	// RPC.
+	AdminServiceListTenantsProcedure = "/admin.AdminService/ListTenants"
+	// AdminServiceSetTenantActiveProcedure is the fully-qualified name of the AdminService's
+	// SetTenantActive RPC.
+	AdminServiceSetTenantActiveProcedure = "/admin.AdminService/SetTenantActive"
+	// AdminServiceSetUserActiveProcedure is the fully-qualified name of the AdminService's
+	// SetUserActive RPC.
+	AdminServiceSetUserActiveProcedure = "/admin.AdminService/SetUserActive"
+	// AdminServiceSetUserRoleProcedure is the fully-qualified name of the AdminService's SetUserRole
+	// RPC.
+	AdminServiceSetUserRoleProcedure = "/admin.AdminService/SetUserRole"
+	// AdminServiceSetUserMustResetPasswordProcedure is the fully-qualified name of the AdminService's
+	// SetUserMustResetPassword RPC.
+	AdminServiceSetUserMustResetPasswordProcedure = "/admin.AdminService/SetUserMustResetPassword"
+	// AdminServiceListUserHistoryProcedure is the fully-qualified name of the AdminService's
+	// ListUserHistory RPC.
+	AdminServiceListUserHistoryProcedure = "/admin.AdminService/ListUserHistory"
+	// AdminServiceGetHealthProcedure is the fully-qualified name of the AdminService's GetHealth RPC.
+	AdminServiceGetHealthProcedure = "/admin.AdminService/GetHealth"
+)
+
+// AdminServiceClient is a client for the admin.AdminService service.
+type AdminServiceClient interface {
+	// Adds an address or CIDR range to the dynamic IP denylist. Every server
+	// instance starts rejecting matching clients within seconds.
+	DenyIP(context.Context, *connect.Request[admin.DenyIPRequest]) (*connect.Response[admin.DenyIPResponse], error)
+	// Removes an entry from the dynamic IP denylist. Static IP_DENYLIST entries
+	// can only be changed through configuration.
+	RemoveDeniedIP(context.Context, *connect.Request[admin.RemoveDeniedIPRequest]) (*connect.Response[admin.RemoveDeniedIPResponse], error)
+	// Lists the unexpired entries of the dynamic IP denylist
+	ListDeniedIPs(context.Context, *connect.Request[admin.ListDeniedIPsRequest]) (*connect.Response[admin.ListDeniedIPsResponse], error)
+	// Creates a tenant. Its users sign up and sign in with the tenant's slug
+	// in x-tenant metadata.
+	CreateTenant(context.Context, *connect.Request[admin.CreateTenantRequest]) (*connect.Response[admin.CreateTenantResponse], error)
+	// Looks up a tenant by ID or slug
+	GetTenant(context.Context, *connect.Request[admin.GetTenantRequest]) (*connect.Response[admin.GetTenantResponse], error)
+	// Lists tenants, newest first
+	ListTenants(context.Context, *connect.Request[admin.ListTenantsRequest]) (*connect.Response[admin.ListTenantsResponse], error)
+	// Deactivates or reactivates a tenant. Every call made for an inactive
+	// tenant is rejected, including with tokens issued before.
+	SetTenantActive(context.Context, *connect.Request[admin.SetTenantActiveRequest]) (*connect.Response[admin.SetTenantActiveResponse], error)
+	// Deactivates or reactivates a user. Deactivated users can't sign in, and
+	// their tokens stop passing ValidateToken.
+	SetUserActive(context.Context, *connect.Request[admin.SetUserActiveRequest]) (*connect.Response[admin.SetUserActiveResponse], error)
+	// Changes a user's authorization role. Tokens issued before keep the old
+	// role until they expire.
+	SetUserRole(context.Context, *connect.Request[admin.SetUserRoleRequest]) (*connect.Response[admin.SetUserRoleResponse], error)
+	// Requires a user to change their password, such as after a breach, or
+	// lifts the requirement. A flagged user is signed out everywhere, and
+	// until they change their password, Login only issues a token for
+	// ChangePassword and RefreshToken refuses their sessions.
+	SetUserMustResetPassword(context.Context, *connect.Request[admin.SetUserMustResetPasswordRequest]) (*connect.Response[admin.SetUserMustResetPasswordResponse], error)
+	// Lists the recorded changes to a user, newest first
+	ListUserHistory(context.Context, *connect.Request[admin.ListUserHistoryRequest]) (*connect.Response[admin.ListUserHistoryResponse], error)
+	// Reports the state of each dependency of the instance that serves the
+	// call: PostgreSQL, its read replicas and Redis
+	GetHealth(context.Context, *connect.Request[admin.GetHealthRequest]) (*connect.Response[admin.GetHealthResponse], error)
+}
+
+// NewAdminServiceClient constructs a client for the admin.AdminService service. By default, it uses
+// the Connect protocol with the binary Protobuf Codec, asks for gzipped responses, and sends
+// uncompressed requests. To use the gRPC or gRPC-Web protocols, supply the connect.WithGRPC() or
+// connect.WithGRPCWeb() options.
+//
+// The URL supplied here should be the base URL for the Connect or gRPC server (for example,
+// http://api.acme.com or https://acme.com/grpc).
+func NewAdminServiceClient(httpClient connect.HTTPClient, baseURL string, opts ...connect.ClientOption) AdminServiceClient {
+	baseURL = strings.TrimRight(baseURL, "/")
+	adminServiceMethods := admin.File_admin_admin_proto.Services().ByName("AdminService").Methods()
+	return &adminServiceClient{
+		denyIP: connect.NewClient[admin.DenyIPRequest, admin.DenyIPResponse](
+			httpClient,
+			baseURL+AdminServiceDenyIPProcedure,
+			connect.WithSchema(adminServiceMethods.ByName("DenyIP")),
+			connect.WithClientOptions(opts...),
+		),
+		removeDeniedIP: connect.NewClient[admin.RemoveDeniedIPRequest, admin.RemoveDeniedIPResponse](
+			httpClient,
+			baseURL+AdminServiceRemoveDeniedIPProcedure,
+			connect.WithSchema(adminServiceMethods.ByName("RemoveDeniedIP")),
+			connect.WithClientOptions(opts...),
+		),
+		listDeniedIPs: connect.NewClient[admin.ListDeniedIPsRequest, admin.ListDeniedIPsResponse](
+			httpClient,
+			baseURL+AdminServiceListDeniedIPsProcedure,
+			connect.WithSchema(adminServiceMethods.ByName("ListDeniedIPs")),
+			connect.WithIdempotency(connect.IdempotencyNoSideEffects),
+			connect.WithClientOptions(opts...),
+		),
+		createTenant: connect.NewClient[admin.CreateTenantRequest, admin.CreateTenantResponse](
+			httpClient,
+			baseURL+AdminServiceCreateTenantProcedure,
+			connect.WithSchema(adminServiceMethods.ByName("CreateTenant")),
+			connect.WithClientOptions(opts...),
+		),
+		getTenant: connect.NewClient[admin.GetTenantRequest, admin.GetTenantResponse](
+			httpClient,
+			baseURL+AdminServiceGetTenantProcedure,
+			connect.WithSchema(adminServiceMethods.ByName("GetTenant")),
+			connect.WithIdempotency(connect.IdempotencyNoSideEffects),
+			connect.WithClientOptions(opts...),
+		),
+		listTenants: connect.NewClient[admin.ListTenantsRequest, admin.ListTenantsResponse](
+			httpClient,
+			baseURL+AdminServiceListTenantsProcedure,
+			connect.WithSchema(adminServiceMethods.ByName("ListTenants")),
+			connect.WithIdempotency(connect.IdempotencyNoSideEffects),
+			connect.WithClientOptions(opts...),
+		),
+		setTenantActive: connect.NewClient[admin.SetTenantActiveRequest, admin.SetTenantActiveResponse](
+			httpClient,
+			baseURL+AdminServiceSetTenantActiveProcedure,
+			connect.WithSchema(adminServiceMethods.ByName("SetTenantActive")),
+			connect.WithClientOptions(opts...),
+		),
+		setUserActive: connect.NewClient[admin.SetUserActiveRequest, admin.SetUserActiveResponse](
+			httpClient,
+			baseURL+AdminServiceSetUserActiveProcedure,
+			connect.WithSchema(adminServiceMethods.ByName("SetUserActive")),
+			connect.WithClientOptions(opts...),
+		),
+		setUserRole: connect.NewClient[admin.SetUserRoleRequest, admin.SetUserRoleResponse](
+			httpClient,
+			baseURL+AdminServiceSetUserRoleProcedure,
+			connect.WithSchema(adminServiceMethods.ByName("SetUserRole")),
+			connect.WithClientOptions(opts...),
+		),
+		setUserMustResetPassword: connect.NewClient[admin.SetUserMustResetPasswordRequest, admin.SetUserMustResetPasswordResponse](
+			httpClient,
+			baseURL+AdminServiceSetUserMustResetPasswordProcedure,
+			connect.WithSchema(adminServiceMethods.ByName("SetUserMustResetPassword")),
+			connect.WithClientOptions(opts...),
+		),
+		listUserHistory: connect.NewClient[admin.ListUserHistoryRequest, admin.ListUserHistoryResponse](
+			httpClient,
+			baseURL+AdminServiceListUserHistoryProcedure,
+			connect.WithSchema(adminServiceMethods.ByName("ListUserHistory")),
+			connect.WithIdempotency(connect.IdempotencyNoSideEffects),
+			connect.WithClientOptions(opts...),
+		),
+		getHealth: connect.NewClient[admin.GetHealthRequest, admin.GetHealthResponse](
+			httpClient,
+			baseURL+AdminServiceGetHealthProcedure,
+			connect.WithSchema(adminServiceMethods.ByName("GetHealth")),
+			connect.WithIdempotency(connect.IdempotencyNoSideEffects),
+			connect.WithClientOptions(opts...),
+		),
+	}
+}
+
+// adminServiceClient implements AdminServiceClient.
+type adminServiceClient struct {
+	denyIP                   *connect.Client[admin.DenyIPRequest, admin.DenyIPResponse]
+	removeDeniedIP           *connect.Client[admin.RemoveDeniedIPRequest, admin.RemoveDeniedIPResponse]
+	listDeniedIPs            *connect.Client[admin.ListDeniedIPsRequest, admin.ListDeniedIPsResponse]
+	createTenant             *connect.Client[admin.CreateTenantRequest, admin.CreateTenantResponse]
+	getTenant                *connect.Client[admin.GetTenantRequest, admin.GetTenantResponse]
+	listTenants              *connect.Client[admin.ListTenantsRequest, admin.ListTenantsResponse]
+	setTenantActive          *connect.Client[admin.SetTenantActiveRequest, admin.SetTenantActiveResponse]
+	setUserActive            *connect.Client[admin.SetUserActiveRequest, admin.SetUserActiveResponse]
+	setUserRole              *connect.Client[admin.SetUserRoleRequest, admin.SetUserRoleResponse]
+	setUserMustResetPassword *connect.Client[admin.SetUserMustResetPasswordRequest, admin.SetUserMustResetPasswordResponse]
+	listUserHistory          *connect.Client[admin.ListUserHistoryRequest, admin.ListUserHistoryResponse]
+	getHealth                *connect.Client[admin.GetHealthRequest, admin.GetHealthResponse]
+}
+
+// DenyIP calls admin.AdminService.DenyIP.
+func (c *adminServiceClient) DenyIP(ctx context.Context, req *connect.Request[admin.DenyIPRequest]) (*connect.Response[admin.DenyIPResponse], error) {
+	return c.denyIP.CallUnary(ctx, req)
+}
+
+// RemoveDeniedIP calls admin.AdminService.RemoveDeniedIP.
+func (c *adminServiceClient) RemoveDeniedIP(ctx context.Context, req *connect.Request[admin.RemoveDeniedIPRequest]) (*connect.Response[admin.RemoveDeniedIPResponse], error) {
+	return c.removeDeniedIP.CallUnary(ctx, req)
+}
+
+// ListDeniedIPs calls admin.AdminService.ListDeniedIPs.
+func (c *adminServiceClient) ListDeniedIPs(ctx context.Context, req *connect.Request[admin.ListDeniedIPsRequest]) (*connect.Response[admin.ListDeniedIPsResponse], error) {
+	return c.listDeniedIPs.CallUnary(ctx, req)
+}
+
+// CreateTenant calls admin.AdminService.CreateTenant.
+func (c *adminServiceClient) CreateTenant(ctx context.Context, req *connect.Request[admin.CreateTenantRequest]) (*connect.Response[admin.CreateTenantResponse], error) {
+	return c.createTenant.CallUnary(ctx, req)
+}
+
+// GetTenant calls admin.AdminService.GetTenant.
+func (c *adminServiceClient) GetTenant(ctx context.Context, req *connect.Request[admin.GetTenantRequest]) (*connect.Response[admin.GetTenantResponse], error) {
+	return c.getTenant.CallUnary(ctx, req)
+}
+
+// ListTenants calls admin.AdminService.ListTenants.
+func (c *adminServiceClient) ListTenants(ctx context.Context, req *connect.Request[admin.ListTenantsRequest]) (*connect.Response[admin.ListTenantsResponse], error) {
+	return c.listTenants.CallUnary(ctx, req)
+}
+
+// SetTenantActive calls admin.AdminService.SetTenantActive.
+func (c *adminServiceClient) SetTenantActive(ctx context.Context, req *connect.Request[admin.SetTenantActiveRequest]) (*connect.Response[admin.SetTenantActiveResponse], error) {
+	return c.setTenantActive.CallUnary(ctx, req)
+}
+
+// SetUserActive calls admin.AdminService.SetUserActive.
+func (c *adminServiceClient) SetUserActive(ctx context.Context, req *connect.Request[admin.SetUserActiveRequest]) (*connect.Response[admin.SetUserActiveResponse], error) {
+	return c.setUserActive.CallUnary(ctx, req)
+}
+
+// SetUserRole calls admin.AdminService.SetUserRole.
+func (c *adminServiceClient) SetUserRole(ctx context.Context, req *connect.Request[admin.SetUserRoleRequest]) (*connect.Response[admin.SetUserRoleResponse], error) {
+	return c.setUserRole.CallUnary(ctx, req)
+}
+
+// SetUserMustResetPassword calls admin.AdminService.SetUserMustResetPassword.
+func (c *adminServiceClient) SetUserMustResetPassword(ctx context.Context, req *connect.Request[admin.SetUserMustResetPasswordRequest]) (*connect.Response[admin.SetUserMustResetPasswordResponse], error) {
+	return c.setUserMustResetPassword.CallUnary(ctx, req)
+}
+
+// ListUserHistory calls admin.AdminService.ListUserHistory.
+func (c *adminServiceClient) ListUserHistory(ctx context.Context, req *connect.Request[admin.ListUserHistoryRequest]) (*connect.Response[admin.ListUserHistoryResponse], error) {
+	return c.listUserHistory.CallUnary(ctx, req)
+}
+
+// GetHealth calls admin.AdminService.GetHealth.
+func (c *adminServiceClient) GetHealth(ctx context.Context, req *connect.Request[admin.GetHealthRequest]) (*connect.Response[admin.GetHealthResponse], error) {
+	return c.getHealth.CallUnary(ctx, req)
+}
+
+// AdminServiceHandler is an implementation of the admin.AdminService service.
+type AdminServiceHandler interface {
+	// Adds an address or CIDR range to the dynamic IP denylist. Every server
+	// instance starts rejecting matching clients within seconds.
+	DenyIP(context.Context, *connect.Request[admin.DenyIPRequest]) (*connect.Response[admin.DenyIPResponse], error)
+	// Removes an entry from the dynamic IP denylist. Static IP_DENYLIST entries
+	// can only be changed through configuration.
+	RemoveDeniedIP(context.Context, *connect.Request[admin.RemoveDeniedIPRequest]) (*connect.Response[admin.RemoveDeniedIPResponse], error)
+	// Lists the unexpired entries of the dynamic IP denylist
+	ListDeniedIPs(context.Context, *connect.Request[admin.ListDeniedIPsRequest]) (*connect.Response[admin.ListDeniedIPsResponse], error)
+	// Creates a tenant. Its users sign up and sign in with the tenant's slug
+	// in x-tenant metadata.
+	CreateTenant(context.Context, *connect.Request[admin.CreateTenantRequest]) (*connect.Response[admin.CreateTenantResponse], error)
+	// Looks up a tenant by ID or slug
+	GetTenant(context.Context, *connect.Request[admin.GetTenantRequest]) (*connect.Response[admin.GetTenantResponse], error)
+	// Lists tenants, newest first
+	ListTenants(context.Context, *connect.Request[admin.ListTenantsRequest]) (*connect.Response[admin.ListTenantsResponse], error)
+	// Deactivates or reactivates a tenant. Every call made for an inactive
+	// tenant is rejected, including with tokens issued before.
+	SetTenantActive(context.Context, *connect.Request[admin.SetTenantActiveRequest]) (*connect.Response[admin.SetTenantActiveResponse], error)
+	// Deactivates or reactivates a user. Deactivated users can't sign in, and
+	// their tokens stop passing ValidateToken.
+	SetUserActive(context.Context, *connect.Request[admin.SetUserActiveRequest]) (*connect.Response[admin.SetUserActiveResponse], error)
+	// Changes a user's authorization role. Tokens issued before keep the old
+	// role until they expire.
+	SetUserRole(context.Context, *connect.Request[admin.SetUserRoleRequest]) (*connect.Response[admin.SetUserRoleResponse], error)
+	// Requires a user to change their password, such as after a breach, or
+	// lifts the requirement. A flagged user is signed out everywhere, and
+	// until they change their password, Login only issues a token for
+	// ChangePassword and RefreshToken refuses their sessions.
+	SetUserMustResetPassword(context.Context, *connect.Request[admin.SetUserMustResetPasswordRequest]) (*connect.Response[admin.SetUserMustResetPasswordResponse], error)
+	// Lists the recorded changes to a user, newest first
+	ListUserHistory(context.Context, *connect.Request[admin.ListUserHistoryRequest]) (*connect.Response[admin.ListUserHistoryResponse], error)
+	// Reports the state of each dependency of the instance that serves the
+	// call: PostgreSQL, its read replicas and Redis
+	GetHealth(context.Context, *connect.Request[admin.GetHealthRequest]) (*connect.Response[admin.GetHealthResponse], error)
+}
+
+// NewAdminServiceHandler builds an HTTP handler from the service implementation. It returns the
+// path on which to mount the handler and the handler itself.
+//
+// By default, handlers support the Connect, gRPC, and gRPC-Web protocols with the binary Protobuf
+// and JSON codecs. They also support gzip compression.
+func NewAdminServiceHandler(svc AdminServiceHandler, opts ...connect.HandlerOption) (string, http.Handler) {
+	adminServiceMethods := admin.File_admin_admin_proto.Services().ByName("AdminService").Methods()
+	adminServiceDenyIPHandler := connect.NewUnaryHandler(
+		AdminServiceDenyIPProcedure,
+		svc.DenyIP,
+		connect.WithSchema(adminServiceMethods.ByName("DenyIP")),
+		connect.WithHandlerOptions(opts...),
+	)
+	adminServiceRemoveDeniedIPHandler := connect.NewUnaryHandler(
+		AdminServiceRemoveDeniedIPProcedure,
+		svc.RemoveDeniedIP,
+		connect.WithSchema(adminServiceMethods.ByName("RemoveDeniedIP")),
+		connect.WithHandlerOptions(opts...),
+	)
+	adminServiceListDeniedIPsHandler := connect.NewUnaryHandler(
+		AdminServiceListDeniedIPsProcedure,
+		svc.ListDeniedIPs,
+		connect.WithSchema(adminServiceMethods.ByName("ListDeniedIPs")),
+		connect.WithIdempotency(connect.IdempotencyNoSideEffects),
+		connect.WithHandlerOptions(opts...),
+	)
+	adminServiceCreateTenantHandler := connect.NewUnaryHandler(
+		AdminServiceCreateTenantProcedure,
+		svc.CreateTenant,
+		connect.WithSchema(adminServiceMethods.ByName("CreateTenant")),
+		connect.WithHandlerOptions(opts...),
+	)
+	adminServiceGetTenantHandler := connect.NewUnaryHandler(
+		AdminServiceGetTenantProcedure,
+		svc.GetTenant,
+		connect.WithSchema(adminServiceMethods.ByName("GetTenant")),
+		connect.WithIdempotency(connect.IdempotencyNoSideEffects),
+		connect.WithHandlerOptions(opts...),
+	)
+	adminServiceListTenantsHandler := connect.NewUnaryHandler(
+		AdminServiceListTenantsProcedure,
+		svc.ListTenants,
+		connect.WithSchema(adminServiceMethods.ByName("ListTenants")),
+		connect.WithIdempotency(connect.IdempotencyNoSideEffects),
+		connect.WithHandlerOptions(opts...),
+	)
+	adminServiceSetTenantActiveHandler := connect.NewUnaryHandler(
+		AdminServiceSetTenantActiveProcedure,
+		svc.SetTenantActive,
+		connect.WithSchema(adminServiceMethods.ByName("SetTenantActive")),
+		connect.WithHandlerOptions(opts...),
+	)
+	adminServiceSetUserActiveHandler := connect.NewUnaryHandler(
+		AdminServiceSetUserActiveProcedure,
+		svc.SetUserActive,
+		connect.WithSchema(adminServiceMethods.ByName("SetUserActive")),
+		connect.WithHandlerOptions(opts...),
+	)
+	adminServiceSetUserRoleHandler := connect.NewUnaryHandler(
+		AdminServiceSetUserRoleProcedure,
+		svc.SetUserRole,
+		connect.WithSchema(adminServiceMethods.ByName("SetUserRole")),
+		connect.WithHandlerOptions(opts...),
+	)
+	adminServiceSetUserMustResetPasswordHandler := connect.NewUnaryHandler(
+		AdminServiceSetUserMustResetPasswordProcedure,
+		svc.SetUserMustResetPassword,
+		connect.WithSchema(adminServiceMethods.ByName("SetUserMustResetPassword")),
+		connect.WithHandlerOptions(opts...),
+	)
+	adminServiceListUserHistoryHandler := connect.NewUnaryHandler(
+		AdminServiceListUserHistoryProcedure,
+		svc.ListUserHistory,
+		connect.WithSchema(adminServiceMethods.ByName("ListUserHistory")),
+		connect.WithIdempotency(connect.IdempotencyNoSideEffects),
+		connect.WithHandlerOptions(opts...),
+	)
+	adminServiceGetHealthHandler := connect.NewUnaryHandler(
+		AdminServiceGetHealthProcedure,
+		svc.GetHealth,
+		connect.WithSchema(adminServiceMethods.ByName("GetHealth")),
+		connect.WithIdempotency(connect.IdempotencyNoSideEffects),
+		connect.WithHandlerOptions(opts...),
+	)
+	return "/admin.AdminService/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case AdminServiceDenyIPProcedure:
+			adminServiceDenyIPHandler.ServeHTTP(w, r)
+		case AdminServiceRemoveDeniedIPProcedure:
+			adminServiceRemoveDeniedIPHandler.ServeHTTP(w, r)
+		case AdminServiceListDeniedIPsProcedure:
+			adminServiceListDeniedIPsHandler.ServeHTTP(w, r)
+		case AdminServiceCreateTenantProcedure:
+			adminServiceCreateTenantHandler.ServeHTTP(w, r)
+		case AdminServiceGetTenantProcedure:
+			adminServiceGetTenantHandler.ServeHTTP(w, r)
+		case AdminServiceListTenantsProcedure:
+			adminServiceListTenantsHandler.ServeHTTP(w, r)
+		case AdminServiceSetTenantActiveProcedure:
+			adminServiceSetTenantActiveHandler.ServeHTTP(w, r)
+		case AdminServiceSetUserActiveProcedure:
+			adminServiceSetUserActiveHandler.ServeHTTP(w, r)
+		case AdminServiceSetUserRoleProcedure:
+			adminServiceSetUserRoleHandler.ServeHTTP(w, r)
+		case AdminServiceSetUserMustResetPasswordProcedure:
+			adminServiceSetUserMustResetPasswordHandler.ServeHTTP(w, r)
+		case AdminServiceListUserHistoryProcedure:
+			adminServiceListUserHistoryHandler.ServeHTTP(w, r)
+		case AdminServiceGetHealthProcedure:
+			adminServiceGetHealthHandler.ServeHTTP(w, r)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+}
+
+// UnimplementedAdminServiceHandler returns CodeUnimplemented from all methods.
+type UnimplementedAdminServiceHandler struct{}
+
+func (UnimplementedAdminServiceHandler) DenyIP(context.Context, *connect.Request[admin.DenyIPRequest]) (*connect.Response[admin.DenyIPResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("admin.AdminService.DenyIP is not implemented"))
+}
+
+func (UnimplementedAdminServiceHandler) RemoveDeniedIP(context.Context, *connect.Request[admin.RemoveDeniedIPRequest]) (*connect.Response[admin.RemoveDeniedIPResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("admin.AdminService.RemoveDeniedIP is not implemented"))
+}
+
+func (UnimplementedAdminServiceHandler) ListDeniedIPs(context.Context, *connect.Request[admin.ListDeniedIPsRequest]) (*connect.Response[admin.ListDeniedIPsResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("admin.AdminService.ListDeniedIPs is not implemented"))
+}
+
+func (UnimplementedAdminServiceHandler) CreateTenant(context.Context, *connect.Request[admin.CreateTenantRequest]) (*connect.Response[admin.CreateTenantResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("admin.AdminService.CreateTenant is not implemented"))
+}
+
+func (UnimplementedAdminServiceHandler) GetTenant(context.Context, *connect.Request[admin.GetTenantRequest]) (*connect.Response[admin.GetTenantResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("admin.AdminService.GetTenant is not implemented"))
+}
+
+func (UnimplementedAdminServiceHandler) ListTenants(context.Context, *connect.Request[admin.ListTenantsRequest]) (*connect.Response[admin.ListTenantsResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("admin.AdminService.ListTenants is not implemented"))
+}
+
+func (UnimplementedAdminServiceHandler) SetTenantActive(context.Context, *connect.Request[admin.SetTenantActiveRequest]) (*connect.Response[admin.SetTenantActiveResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("admin.AdminService.SetTenantActive is not implemented"))
+}
+
+func (UnimplementedAdminServiceHandler) SetUserActive(context.Context, *connect.Request[admin.SetUserActiveRequest]) (*connect.Response[admin.SetUserActiveResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("admin.AdminService.SetUserActive is not implemented"))
+}
+
+func (UnimplementedAdminServiceHandler) SetUserRole(context.Context, *connect.Request[admin.SetUserRoleRequest]) (*connect.Response[admin.SetUserRoleResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("admin.AdminService.SetUserRole is not implemented"))
+}
+
+func (UnimplementedAdminServiceHandler) SetUserMustResetPassword(context.Context, *connect.Request[admin.SetUserMustResetPasswordRequest]) (*connect.Response[admin.SetUserMustResetPasswordResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("admin.AdminService.SetUserMustResetPassword is not implemented"))
+}
+
+func (UnimplementedAdminServiceHandler) ListUserHistory(context.Context, *connect.Request[admin.ListUserHistoryRequest]) (*connect.Response[admin.ListUserHistoryResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("admin.AdminService.ListUserHistory is not implemented"))
+}
+
+func (UnimplementedAdminServiceHandler) GetHealth(context.Context, *connect.Request[admin.GetHealthRequest]) (*connect.Response[admin.GetHealthResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("admin.AdminService.GetHealth is not implemented"))
+}
@@ -0,0 +1,533 @@
+// Code generated by protoc-gen-connect-go. DO NOT EDIT.
+//
+// Source: auth.proto
+
+package authconnect
+
+import (
+	connect "connectrpc.com/connect"
+	context "context"
+	errors "errors"
+	proto "github.com/sahays/grpc-proto-go-flutter-template/proto"
+	http "net/http"
+	strings "strings"
+)
+
+// This is a compile-time assertion to ensure that this generated file and the connect package are
+// compatible. If you get a compiler error that this constant is not defined, this code was
+// generated with a version of connect newer than the one compiled into your binary. You can fix the
+// problem by either regenerating this code with an older version of connect or updating the connect
+// version compiled into your binary.
+const _ = connect.IsAtLeastVersion1_13_0
+
+const (
+	// AuthServiceName is the fully-qualified name of the AuthService service.
+	AuthServiceName = "auth.AuthService"
+)
+
+// These constants are the fully-qualified names of the RPCs defined in this package. They're
+// exposed at runtime as Spec.Procedure and as the final two segments of the HTTP route.
+//
+// Note that these are different from the fully-qualified method names used by
+// google.golang.org/protobuf/reflect/protoreflect. To convert from these constants to
+// reflection-formatted method names, remove the leading slash and convert the remaining slash to a
+// period.
+const (
+	// AuthServiceSignUpProcedure is the fully-qualified name of the AuthService's SignUp RPC.
+	AuthServiceSignUpProcedure = "/auth.AuthService/SignUp"
+	// AuthServiceLoginProcedure is the fully-qualified name of the AuthService's Login RPC.
+	AuthServiceLoginProcedure = "/auth.AuthService/Login"
+	// AuthServiceForgotPasswordProcedure is the fully-qualified name of the AuthService's
+	// ForgotPassword RPC.
+	AuthServiceForgotPasswordProcedure = "/auth.AuthService/ForgotPassword"
+	// AuthServiceResetPasswordProcedure is the fully-qualified name of the AuthService's ResetPassword
+	// RPC.
+	AuthServiceResetPasswordProcedure = "/auth.AuthService/ResetPassword"
+	// AuthServiceCheckPasswordStrengthProcedure is the fully-qualified name of the AuthService's
+	// CheckPasswordStrength RPC.
+	AuthServiceCheckPasswordStrengthProcedure = "/auth.AuthService/CheckPasswordStrength"
+	// AuthServiceValidateTokenProcedure is the fully-qualified name of the AuthService's ValidateToken
+	// RPC.
+	AuthServiceValidateTokenProcedure = "/auth.AuthService/ValidateToken"
+	// AuthServiceChangePasswordProcedure is the fully-qualified name of the AuthService's
+	// ChangePassword RPC.
+	AuthServiceChangePasswordProcedure = "/auth.AuthService/ChangePassword"
+	// AuthServiceUpdateProfileProcedure is the fully-qualified name of the AuthService's UpdateProfile
+	// RPC.
+	AuthServiceUpdateProfileProcedure = "/auth.AuthService/UpdateProfile"
+	// AuthServiceSetRecoveryEmailProcedure is the fully-qualified name of the AuthService's
+	// SetRecoveryEmail RPC.
+	AuthServiceSetRecoveryEmailProcedure = "/auth.AuthService/SetRecoveryEmail"
+	// AuthServiceVerifyRecoveryEmailProcedure is the fully-qualified name of the AuthService's
+	// VerifyRecoveryEmail RPC.
+	AuthServiceVerifyRecoveryEmailProcedure = "/auth.AuthService/VerifyRecoveryEmail"
+	// AuthServiceVerifyEmailProcedure is the fully-qualified name of the AuthService's VerifyEmail RPC.
+	AuthServiceVerifyEmailProcedure = "/auth.AuthService/VerifyEmail"
+	// AuthServiceResendVerificationProcedure is the fully-qualified name of the AuthService's
+	// ResendVerification RPC.
+	AuthServiceResendVerificationProcedure = "/auth.AuthService/ResendVerification"
+	// AuthServiceRefreshTokenProcedure is the fully-qualified name of the AuthService's RefreshToken
+	// RPC.
+	AuthServiceRefreshTokenProcedure = "/auth.AuthService/RefreshToken"
+	// AuthServiceLogoutProcedure is the fully-qualified name of the AuthService's Logout RPC.
+	AuthServiceLogoutProcedure = "/auth.AuthService/Logout"
+	// AuthServiceStreamSecurityEventsProcedure is the fully-qualified name of the AuthService's
+	// StreamSecurityEvents RPC.
+	AuthServiceStreamSecurityEventsProcedure = "/auth.AuthService/StreamSecurityEvents"
+)
+
+// AuthServiceClient is a client for the auth.AuthService service.
+type AuthServiceClient interface {
+	SignUp(context.Context, *connect.Request[proto.SignUpRequest]) (*connect.Response[proto.SignUpResponse], error)
+	Login(context.Context, *connect.Request[proto.LoginRequest]) (*connect.Response[proto.LoginResponse], error)
+	ForgotPassword(context.Context, *connect.Request[proto.ForgotPasswordRequest]) (*connect.Response[proto.ForgotPasswordResponse], error)
+	ResetPassword(context.Context, *connect.Request[proto.ResetPasswordRequest]) (*connect.Response[proto.ResetPasswordResponse], error)
+	// Scores a password as SignUp, ResetPassword and ChangePassword do, with
+	// feedback to show as the user types it. Nothing is stored.
+	CheckPasswordStrength(context.Context, *connect.Request[proto.CheckPasswordStrengthRequest]) (*connect.Response[proto.CheckPasswordStrengthResponse], error)
+	ValidateToken(context.Context, *connect.Request[proto.ValidateTokenRequest]) (*connect.Response[proto.ValidateTokenResponse], error)
+	ChangePassword(context.Context, *connect.Request[proto.ChangePasswordRequest]) (*connect.Response[proto.ChangePasswordResponse], error)
+	// Updates the authenticated user's name. Fails with ABORTED if the user
+	// changed since the version the client read.
+	UpdateProfile(context.Context, *connect.Request[proto.UpdateProfileRequest]) (*connect.Response[proto.UpdateProfileResponse], error)
+	SetRecoveryEmail(context.Context, *connect.Request[proto.SetRecoveryEmailRequest]) (*connect.Response[proto.SetRecoveryEmailResponse], error)
+	VerifyRecoveryEmail(context.Context, *connect.Request[proto.VerifyRecoveryEmailRequest]) (*connect.Response[proto.VerifyRecoveryEmailResponse], error)
+	VerifyEmail(context.Context, *connect.Request[proto.VerifyEmailRequest]) (*connect.Response[proto.VerifyEmailResponse], error)
+	ResendVerification(context.Context, *connect.Request[proto.ResendVerificationRequest]) (*connect.Response[proto.ResendVerificationResponse], error)
+	// Issues a new access token for the session of a refresh token. Sessions
+	// bound to a device at login only refresh from that device.
+	RefreshToken(context.Context, *connect.Request[proto.RefreshTokenRequest]) (*connect.Response[proto.RefreshTokenResponse], error)
+	Logout(context.Context, *connect.Request[proto.LogoutRequest]) (*connect.Response[proto.LogoutResponse], error)
+	// Pushes security events for the authenticated user as they happen. The
+	// stream stays open until the client cancels it.
+	StreamSecurityEvents(context.Context, *connect.Request[proto.StreamSecurityEventsRequest]) (*connect.ServerStreamForClient[proto.SecurityEvent], error)
+}
+
+// NewAuthServiceClient constructs a client for the auth.AuthService service. By default, it uses
+// the Connect protocol with the binary Protobuf Codec, asks for gzipped responses, and sends
+// uncompressed requests. To use the gRPC or gRPC-Web protocols, supply the connect.WithGRPC() or
+// connect.WithGRPCWeb() options.
+//
+// The URL supplied here should be the base URL for the Connect or gRPC server (for example,
+// http://api.acme.com or https://acme.com/grpc).
+func NewAuthServiceClient(httpClient connect.HTTPClient, baseURL string, opts ...connect.ClientOption) AuthServiceClient {
+	baseURL = strings.TrimRight(baseURL, "/")
+	authServiceMethods := proto.File_auth_proto.Services().ByName("AuthService").Methods()
+	return &authServiceClient{
+		signUp: connect.NewClient[proto.SignUpRequest, proto.SignUpResponse](
+			httpClient,
+			baseURL+AuthServiceSignUpProcedure,
+			connect.WithSchema(authServiceMethods.ByName("SignUp")),
+			connect.WithClientOptions(opts...),
+		),
+		login: connect.NewClient[proto.LoginRequest, proto.LoginResponse](
+			httpClient,
+			baseURL+AuthServiceLoginProcedure,
+			connect.WithSchema(authServiceMethods.ByName("Login")),
+			connect.WithClientOptions(opts...),
+		),
+		forgotPassword: connect.NewClient[proto.ForgotPasswordRequest, proto.ForgotPasswordResponse](
+			httpClient,
+			baseURL+AuthServiceForgotPasswordProcedure,
+			connect.WithSchema(authServiceMethods.ByName("ForgotPassword")),
+			connect.WithClientOptions(opts...),
+		),
+		resetPassword: connect.NewClient[proto.ResetPasswordRequest, proto.ResetPasswordResponse](
+			httpClient,
+			baseURL+AuthServiceResetPasswordProcedure,
+			connect.WithSchema(authServiceMethods.ByName("ResetPassword")),
+			connect.WithClientOptions(opts...),
+		),
+		checkPasswordStrength: connect.NewClient[proto.CheckPasswordStrengthRequest, proto.CheckPasswordStrengthResponse](
+			httpClient,
+			baseURL+AuthServiceCheckPasswordStrengthProcedure,
+			connect.WithSchema(authServiceMethods.ByName("CheckPasswordStrength")),
+			connect.WithIdempotency(connect.IdempotencyNoSideEffects),
+			connect.WithClientOptions(opts...),
+		),
+		validateToken: connect.NewClient[proto.ValidateTokenRequest, proto.ValidateTokenResponse](
+			httpClient,
+			baseURL+AuthServiceValidateTokenProcedure,
+			connect.WithSchema(authServiceMethods.ByName("ValidateToken")),
+			connect.WithIdempotency(connect.IdempotencyNoSideEffects),
+			connect.WithClientOptions(opts...),
+		),
+		changePassword: connect.NewClient[proto.ChangePasswordRequest, proto.ChangePasswordResponse](
+			httpClient,
+			baseURL+AuthServiceChangePasswordProcedure,
+			connect.WithSchema(authServiceMethods.ByName("ChangePassword")),
+			connect.WithClientOptions(opts...),
+		),
+		updateProfile: connect.NewClient[proto.UpdateProfileRequest, proto.UpdateProfileResponse](
+			httpClient,
+			baseURL+AuthServiceUpdateProfileProcedure,
+			connect.WithSchema(authServiceMethods.ByName("UpdateProfile")),
+			connect.WithClientOptions(opts...),
+		),
+		setRecoveryEmail: connect.NewClient[proto.SetRecoveryEmailRequest, proto.SetRecoveryEmailResponse](
+			httpClient,
+			baseURL+AuthServiceSetRecoveryEmailProcedure,
+			connect.WithSchema(authServiceMethods.ByName("SetRecoveryEmail")),
+			connect.WithClientOptions(opts...),
+		),
+		verifyRecoveryEmail: connect.NewClient[proto.VerifyRecoveryEmailRequest, proto.VerifyRecoveryEmailResponse](
+			httpClient,
+			baseURL+AuthServiceVerifyRecoveryEmailProcedure,
+			connect.WithSchema(authServiceMethods.ByName("VerifyRecoveryEmail")),
+			connect.WithClientOptions(opts...),
+		),
+		verifyEmail: connect.NewClient[proto.VerifyEmailRequest, proto.VerifyEmailResponse](
+			httpClient,
+			baseURL+AuthServiceVerifyEmailProcedure,
+			connect.WithSchema(authServiceMethods.ByName("VerifyEmail")),
+			connect.WithClientOptions(opts...),
+		),
+		resendVerification: connect.NewClient[proto.ResendVerificationRequest, proto.ResendVerificationResponse](
+			httpClient,
+			baseURL+AuthServiceResendVerificationProcedure,
+			connect.WithSchema(authServiceMethods.ByName("ResendVerification")),
+			connect.WithClientOptions(opts...),
+		),
+		refreshToken: connect.NewClient[proto.RefreshTokenRequest, proto.RefreshTokenResponse](
+			httpClient,
+			baseURL+AuthServiceRefreshTokenProcedure,
+			connect.WithSchema(authServiceMethods.ByName("RefreshToken")),
+			connect.WithClientOptions(opts...),
+		),
+		logout: connect.NewClient[proto.LogoutRequest, proto.LogoutResponse](
+			httpClient,
+			baseURL+AuthServiceLogoutProcedure,
+			connect.WithSchema(authServiceMethods.ByName("Logout")),
+			connect.WithClientOptions(opts...),
+		),
+		streamSecurityEvents: connect.NewClient[proto.StreamSecurityEventsRequest, proto.SecurityEvent](
+			httpClient,
+			baseURL+AuthServiceStreamSecurityEventsProcedure,
+			connect.WithSchema(authServiceMethods.ByName("StreamSecurityEvents")),
+			connect.WithIdempotency(connect.IdempotencyNoSideEffects),
+			connect.WithClientOptions(opts...),
+		),
+	}
+}
+
+// authServiceClient implements AuthServiceClient.
+type authServiceClient struct {
+	signUp                *connect.Client[proto.SignUpRequest, proto.SignUpResponse]
+	login                 *connect.Client[proto.LoginRequest, proto.LoginResponse]
+	forgotPassword        *connect.Client[proto.ForgotPasswordRequest, proto.ForgotPasswordResponse]
+	resetPassword         *connect.Client[proto.ResetPasswordRequest, proto.ResetPasswordResponse]
+	checkPasswordStrength *connect.Client[proto.CheckPasswordStrengthRequest, proto.CheckPasswordStrengthResponse]
+	validateToken         *connect.Client[proto.ValidateTokenRequest, proto.ValidateTokenResponse]
+	changePassword        *connect.Client[proto.ChangePasswordRequest, proto.ChangePasswordResponse]
+	updateProfile         *connect.Client[proto.UpdateProfileRequest, proto.UpdateProfileResponse]
+	setRecoveryEmail      *connect.Client[proto.SetRecoveryEmailRequest, proto.SetRecoveryEmailResponse]
+	verifyRecoveryEmail   *connect.Client[proto.VerifyRecoveryEmailRequest, proto.VerifyRecoveryEmailResponse]
+	verifyEmail           *connect.Client[proto.VerifyEmailRequest, proto.VerifyEmailResponse]
+	resendVerification    *connect.Client[proto.ResendVerificationRequest, proto.ResendVerificationResponse]
+	refreshToken          *connect.Client[proto.RefreshTokenRequest, proto.RefreshTokenResponse]
+	logout                *connect.Client[proto.LogoutRequest, proto.LogoutResponse]
+	streamSecurityEvents  *connect.Client[proto.StreamSecurityEventsRequest, proto.SecurityEvent]
+}
+
+// SignUp calls auth.AuthService.SignUp.
+func (c *authServiceClient) SignUp(ctx context.Context, req *connect.Request[proto.SignUpRequest]) (*connect.Response[proto.SignUpResponse], error) {
+	return c.signUp.CallUnary(ctx, req)
+}
+
+// Login calls auth.AuthService.Login.
+func (c *authServiceClient) Login(ctx context.Context, req *connect.Request[proto.LoginRequest]) (*connect.Response[proto.LoginResponse], error) {
+	return c.login.CallUnary(ctx, req)
+}
+
+// ForgotPassword calls auth.AuthService.ForgotPassword.
+func (c *authServiceClient) ForgotPassword(ctx context.Context, req *connect.Request[proto.ForgotPasswordRequest]) (*connect.Response[proto.ForgotPasswordResponse], error) {
+	return c.forgotPassword.CallUnary(ctx, req)
+}
+
+// ResetPassword calls auth.AuthService.ResetPassword.
+func (c *authServiceClient) ResetPassword(ctx context.Context, req *connect.Request[proto.ResetPasswordRequest]) (*connect.Response[proto.ResetPasswordResponse], error) {
+	return c.resetPassword.CallUnary(ctx, req)
+}
+
+// CheckPasswordStrength calls auth.AuthService.CheckPasswordStrength.
+func (c *authServiceClient) CheckPasswordStrength(ctx context.Context, req *connect.Request[proto.CheckPasswordStrengthRequest]) (*connect.Response[proto.CheckPasswordStrengthResponse], error) {
+	return c.checkPasswordStrength.CallUnary(ctx, req)
+}
+
+// ValidateToken calls auth.AuthService.ValidateToken.
+func (c *authServiceClient) ValidateToken(ctx context.Context, req *connect.Request[proto.ValidateTokenRequest]) (*connect.Response[proto.ValidateTokenResponse], error) {
+	return c.validateToken.CallUnary(ctx, req)
+}
+
+// ChangePassword calls auth.AuthService.ChangePassword.
+func (c *authServiceClient) ChangePassword(ctx context.Context, req *connect.Request[proto.ChangePasswordRequest]) (*connect.Response[proto.ChangePasswordResponse], error) {
+	return c.changePassword.CallUnary(ctx, req)
+}
+
+// UpdateProfile calls auth.AuthService.UpdateProfile.
+func (c *authServiceClient) UpdateProfile(ctx context.Context, req *connect.Request[proto.UpdateProfileRequest]) (*connect.Response[proto.UpdateProfileResponse], error) {
+	return c.updateProfile.CallUnary(ctx, req)
+}
+
+// SetRecoveryEmail calls auth.AuthService.SetRecoveryEmail.
+func (c *authServiceClient) SetRecoveryEmail(ctx context.Context, req *connect.Request[proto.SetRecoveryEmailRequest]) (*connect.Response[proto.SetRecoveryEmailResponse], error) {
+	return c.setRecoveryEmail.CallUnary(ctx, req)
+}
+
+// VerifyRecoveryEmail calls auth.AuthService.VerifyRecoveryEmail.
+func (c *authServiceClient) VerifyRecoveryEmail(ctx context.Context, req *connect.Request[proto.VerifyRecoveryEmailRequest]) (*connect.Response[proto.VerifyRecoveryEmailResponse], error) {
+	return c.verifyRecoveryEmail.CallUnary(ctx, req)
+}
+
+// VerifyEmail calls auth.AuthService.VerifyEmail.
+func (c *authServiceClient) VerifyEmail(ctx context.Context, req *connect.Request[proto.VerifyEmailRequest]) (*connect.Response[proto.VerifyEmailResponse], error) {
+	return c.verifyEmail.CallUnary(ctx, req)
+}
+
+// ResendVerification calls auth.AuthService.ResendVerification.
+func (c *authServiceClient) ResendVerification(ctx context.Context, req *connect.Request[proto.ResendVerificationRequest]) (*connect.Response[proto.ResendVerificationResponse], error) {
+	return c.resendVerification.CallUnary(ctx, req)
+}
+
+// RefreshToken calls auth.AuthService.RefreshToken.
+func (c *authServiceClient) RefreshToken(ctx context.Context, req *connect.Request[proto.RefreshTokenRequest]) (*connect.Response[proto.RefreshTokenResponse], error) {
+	return c.refreshToken.CallUnary(ctx, req)
+}
+
+// Logout calls auth.AuthService.Logout.
+func (c *authServiceClient) Logout(ctx context.Context, req *connect.Request[proto.LogoutRequest]) (*connect.Response[proto.LogoutResponse], error) {
+	return c.logout.CallUnary(ctx, req)
+}
+
+// StreamSecurityEvents calls auth.AuthService.StreamSecurityEvents.
+func (c *authServiceClient) StreamSecurityEvents(ctx context.Context, req *connect.Request[proto.StreamSecurityEventsRequest]) (*connect.ServerStreamForClient[proto.SecurityEvent], error) {
+	return c.streamSecurityEvents.CallServerStream(ctx, req)
+}
+
+// AuthServiceHandler is an implementation of the auth.AuthService service.
+type AuthServiceHandler interface {
+	SignUp(context.Context, *connect.Request[proto.SignUpRequest]) (*connect.Response[proto.SignUpResponse], error)
+	Login(context.Context, *connect.Request[proto.LoginRequest]) (*connect.Response[proto.LoginResponse], error)
+	ForgotPassword(context.Context, *connect.Request[proto.ForgotPasswordRequest]) (*connect.Response[proto.ForgotPasswordResponse], error)
+	ResetPassword(context.Context, *connect.Request[proto.ResetPasswordRequest]) (*connect.Response[proto.ResetPasswordResponse], error)
+	// Scores a password as SignUp, ResetPassword and ChangePassword do, with
+	// feedback to show as the user types it. Nothing is stored.
+	CheckPasswordStrength(context.Context, *connect.Request[proto.CheckPasswordStrengthRequest]) (*connect.Response[proto.CheckPasswordStrengthResponse], error)
+	ValidateToken(context.Context, *connect.Request[proto.ValidateTokenRequest]) (*connect.Response[proto.ValidateTokenResponse], error)
+	ChangePassword(context.Context, *connect.Request[proto.ChangePasswordRequest]) (*connect.Response[proto.ChangePasswordResponse], error)
+	// Updates the authenticated user's name. Fails with ABORTED if the user
+	// changed since the version the client read.
+	UpdateProfile(context.Context, *connect.Request[proto.UpdateProfileRequest]) (*connect.Response[proto.UpdateProfileResponse], error)
+	SetRecoveryEmail(context.Context, *connect.Request[proto.SetRecoveryEmailRequest]) (*connect.Response[proto.SetRecoveryEmailResponse], error)
+	VerifyRecoveryEmail(context.Context, *connect.Request[proto.VerifyRecoveryEmailRequest]) (*connect.Response[proto.VerifyRecoveryEmailResponse], error)
+	VerifyEmail(context.Context, *connect.Request[proto.VerifyEmailRequest]) (*connect.Response[proto.VerifyEmailResponse], error)
+	ResendVerification(context.Context, *connect.Request[proto.ResendVerificationRequest]) (*connect.Response[proto.ResendVerificationResponse], error)
+	// Issues a new access token for the session of a refresh token. Sessions
+	// bound to a device at login only refresh from that device.
+	RefreshToken(context.Context, *connect.Request[proto.RefreshTokenRequest]) (*connect.Response[proto.RefreshTokenResponse], error)
+	Logout(context.Context, *connect.Request[proto.LogoutRequest]) (*connect.Response[proto.LogoutResponse], error)
+	// Pushes security events for the authenticated user as they happen. The
+	// stream stays open until the client cancels it.
+	StreamSecurityEvents(context.Context, *connect.Request[proto.StreamSecurityEventsRequest], *connect.ServerStream[proto.SecurityEvent]) error
+}
+
+// NewAuthServiceHandler builds an HTTP handler from the service implementation. It returns the path
+// on which to mount the handler and the handler itself.
+//
+// By default, handlers support the Connect, gRPC, and gRPC-Web protocols with the binary Protobuf
+// and JSON codecs. They also support gzip compression.
+func NewAuthServiceHandler(svc AuthServiceHandler, opts ...connect.HandlerOption) (string, http.Handler) {
+	authServiceMethods := proto.File_auth_proto.Services().ByName("AuthService").Methods()
+	authServiceSignUpHandler := connect.NewUnaryHandler(
+		AuthServiceSignUpProcedure,
+		svc.SignUp,
+		connect.WithSchema(authServiceMethods.ByName("SignUp")),
+		connect.WithHandlerOptions(opts...),
+	)
+	authServiceLoginHandler := connect.NewUnaryHandler(
+		AuthServiceLoginProcedure,
+		svc.Login,
+		connect.WithSchema(authServiceMethods.ByName("Login")),
+		connect.WithHandlerOptions(opts...),
+	)
+	authServiceForgotPasswordHandler := connect.NewUnaryHandler(
+		AuthServiceForgotPasswordProcedure,
+		svc.ForgotPassword,
+		connect.WithSchema(authServiceMethods.ByName("ForgotPassword")),
+		connect.WithHandlerOptions(opts...),
+	)
+	authServiceResetPasswordHandler := connect.NewUnaryHandler(
+		AuthServiceResetPasswordProcedure,
+		svc.ResetPassword,
+		connect.WithSchema(authServiceMethods.ByName("ResetPassword")),
+		connect.WithHandlerOptions(opts...),
+	)
+	authServiceCheckPasswordStrengthHandler := connect.NewUnaryHandler(
+		AuthServiceCheckPasswordStrengthProcedure,
+		svc.CheckPasswordStrength,
+		connect.WithSchema(authServiceMethods.ByName("CheckPasswordStrength")),
+		connect.WithIdempotency(connect.IdempotencyNoSideEffects),
+		connect.WithHandlerOptions(opts...),
+	)
+	authServiceValidateTokenHandler := connect.NewUnaryHandler(
+		AuthServiceValidateTokenProcedure,
+		svc.ValidateToken,
+		connect.WithSchema(authServiceMethods.ByName("ValidateToken")),
+		connect.WithIdempotency(connect.IdempotencyNoSideEffects),
+		connect.WithHandlerOptions(opts...),
+	)
+	authServiceChangePasswordHandler := connect.NewUnaryHandler(
+		AuthServiceChangePasswordProcedure,
+		svc.ChangePassword,
+		connect.WithSchema(authServiceMethods.ByName("ChangePassword")),
+		connect.WithHandlerOptions(opts...),
+	)
+	authServiceUpdateProfileHandler := connect.NewUnaryHandler(
+		AuthServiceUpdateProfileProcedure,
+		svc.UpdateProfile,
+		connect.WithSchema(authServiceMethods.ByName("UpdateProfile")),
+		connect.WithHandlerOptions(opts...),
+	)
+	authServiceSetRecoveryEmailHandler := connect.NewUnaryHandler(
+		AuthServiceSetRecoveryEmailProcedure,
+		svc.SetRecoveryEmail,
+		connect.WithSchema(authServiceMethods.ByName("SetRecoveryEmail")),
+		connect.WithHandlerOptions(opts...),
+	)
+	authServiceVerifyRecoveryEmailHandler := connect.NewUnaryHandler(
+		AuthServiceVerifyRecoveryEmailProcedure,
+		svc.VerifyRecoveryEmail,
+		connect.WithSchema(authServiceMethods.ByName("VerifyRecoveryEmail")),
+		connect.WithHandlerOptions(opts...),
+	)
+	authServiceVerifyEmailHandler := connect.NewUnaryHandler(
+		AuthServiceVerifyEmailProcedure,
+		svc.VerifyEmail,
+		connect.WithSchema(authServiceMethods.ByName("VerifyEmail")),
+		connect.WithHandlerOptions(opts...),
+	)
+	authServiceResendVerificationHandler := connect.NewUnaryHandler(
+		AuthServiceResendVerificationProcedure,
+		svc.ResendVerification,
+		connect.WithSchema(authServiceMethods.ByName("ResendVerification")),
+		connect.WithHandlerOptions(opts...),
+	)
+	authServiceRefreshTokenHandler := connect.NewUnaryHandler(
+		AuthServiceRefreshTokenProcedure,
+		svc.RefreshToken,
+		connect.WithSchema(authServiceMethods.ByName("RefreshToken")),
+		connect.WithHandlerOptions(opts...),
+	)
+	authServiceLogoutHandler := connect.NewUnaryHandler(
+		AuthServiceLogoutProcedure,
+		svc.Logout,
+		connect.WithSchema(authServiceMethods.ByName("Logout")),
+		connect.WithHandlerOptions(opts...),
+	)
+	authServiceStreamSecurityEventsHandler := connect.NewServerStreamHandler(
+		AuthServiceStreamSecurityEventsProcedure,
+		svc.StreamSecurityEvents,
+		connect.WithSchema(authServiceMethods.ByName("StreamSecurityEvents")),
+		connect.WithIdempotency(connect.IdempotencyNoSideEffects),
+		connect.WithHandlerOptions(opts...),
+	)
+	return "/auth.AuthService/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case AuthServiceSignUpProcedure:
+			authServiceSignUpHandler.ServeHTTP(w, r)
+		case AuthServiceLoginProcedure:
+			authServiceLoginHandler.ServeHTTP(w, r)
+		case AuthServiceForgotPasswordProcedure:
+			authServiceForgotPasswordHandler.ServeHTTP(w, r)
+		case AuthServiceResetPasswordProcedure:
+			authServiceResetPasswordHandler.ServeHTTP(w, r)
+		case AuthServiceCheckPasswordStrengthProcedure:
+			authServiceCheckPasswordStrengthHandler.ServeHTTP(w, r)
+		case AuthServiceValidateTokenProcedure:
+			authServiceValidateTokenHandler.ServeHTTP(w, r)
+		case AuthServiceChangePasswordProcedure:
+			authServiceChangePasswordHandler.ServeHTTP(w, r)
+		case AuthServiceUpdateProfileProcedure:
+			authServiceUpdateProfileHandler.ServeHTTP(w, r)
+		case AuthServiceSetRecoveryEmailProcedure:
+			authServiceSetRecoveryEmailHandler.ServeHTTP(w, r)
+		case AuthServiceVerifyRecoveryEmailProcedure:
+			authServiceVerifyRecoveryEmailHandler.ServeHTTP(w, r)
+		case AuthServiceVerifyEmailProcedure:
+			authServiceVerifyEmailHandler.ServeHTTP(w, r)
+		case AuthServiceResendVerificationProcedure:
+			authServiceResendVerificationHandler.ServeHTTP(w, r)
+		case AuthServiceRefreshTokenProcedure:
+			authServiceRefreshTokenHandler.ServeHTTP(w, r)
+		case AuthServiceLogoutProcedure:
+			authServiceLogoutHandler.ServeHTTP(w, r)
+		case AuthServiceStreamSecurityEventsProcedure:
+			authServiceStreamSecurityEventsHandler.ServeHTTP(w, r)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+}
+
+// UnimplementedAuthServiceHandler returns CodeUnimplemented from all methods.
+type UnimplementedAuthServiceHandler struct{}
+
+func (UnimplementedAuthServiceHandler) SignUp(context.Context, *connect.Request[proto.SignUpRequest]) (*connect.Response[proto.SignUpResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("auth.AuthService.SignUp is not implemented"))
+}
+
+func (UnimplementedAuthServiceHandler) Login(context.Context, *connect.Request[proto.LoginRequest]) (*connect.Response[proto.LoginResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("auth.AuthService.Login is not implemented"))
+}
+
+func (UnimplementedAuthServiceHandler) ForgotPassword(context.Context, *connect.Request[proto.ForgotPasswordRequest]) (*connect.Response[proto.ForgotPasswordResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("auth.AuthService.ForgotPassword is not implemented"))
+}
+
+func (UnimplementedAuthServiceHandler) ResetPassword(context.Context, *connect.Request[proto.ResetPasswordRequest]) (*connect.Response[proto.ResetPasswordResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("auth.AuthService.ResetPassword is not implemented"))
+}
+
+func (UnimplementedAuthServiceHandler) CheckPasswordStrength(context.Context, *connect.Request[proto.CheckPasswordStrengthRequest]) (*connect.Response[proto.CheckPasswordStrengthResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("auth.AuthService.CheckPasswordStrength is not implemented"))
+}
+
+func (UnimplementedAuthServiceHandler) ValidateToken(context.Context, *connect.Request[proto.ValidateTokenRequest]) (*connect.Response[proto.ValidateTokenResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("auth.AuthService.ValidateToken is not implemented"))
+}
+
+func (UnimplementedAuthServiceHandler) ChangePassword(context.Context, *connect.Request[proto.ChangePasswordRequest]) (*connect.Response[proto.ChangePasswordResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("auth.AuthService.ChangePassword is not implemented"))
+}
+
+func (UnimplementedAuthServiceHandler) UpdateProfile(context.Context, *connect.Request[proto.UpdateProfileRequest]) (*connect.Response[proto.UpdateProfileResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("auth.AuthService.UpdateProfile is not implemented"))
+}
+
+func (UnimplementedAuthServiceHandler) SetRecoveryEmail(context.Context, *connect.Request[proto.SetRecoveryEmailRequest]) (*connect.Response[proto.SetRecoveryEmailResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("auth.AuthService.SetRecoveryEmail is not implemented"))
+}
+
+func (UnimplementedAuthServiceHandler) VerifyRecoveryEmail(context.Context, *connect.Request[proto.VerifyRecoveryEmailRequest]) (*connect.Response[proto.VerifyRecoveryEmailResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("auth.AuthService.VerifyRecoveryEmail is not implemented"))
+}
+
+func (UnimplementedAuthServiceHandler) VerifyEmail(context.Context, *connect.Request[proto.VerifyEmailRequest]) (*connect.Response[proto.VerifyEmailResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("auth.AuthService.VerifyEmail is not implemented"))
+}
+
+func (UnimplementedAuthServiceHandler) ResendVerification(context.Context, *connect.Request[proto.ResendVerificationRequest]) (*connect.Response[proto.ResendVerificationResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("auth.AuthService.ResendVerification is not implemented"))
+}
+
+func (UnimplementedAuthServiceHandler) RefreshToken(context.Context, *connect.Request[proto.RefreshTokenRequest]) (*connect.Response[proto.RefreshTokenResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("auth.AuthService.RefreshToken is not implemented"))
+}
+
+func (UnimplementedAuthServiceHandler) Logout(context.Context, *connect.Request[proto.LogoutRequest]) (*connect.Response[proto.LogoutResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("auth.AuthService.Logout is not implemented"))
+}
+
+func (UnimplementedAuthServiceHandler) StreamSecurityEvents(context.Context, *connect.Request[proto.StreamSecurityEventsRequest], *connect.ServerStream[proto.SecurityEvent]) error {
+	return connect.NewError(connect.CodeUnimplemented, errors.New("auth.AuthService.StreamSecurityEvents is not implemented"))
+}
@@ -22,7 +22,12 @@ OUT_DIR="lib/generated"
 protoc \
   --dart_out=grpc:${OUT_DIR} \
   --proto_path=${PROTO_DIR} \
-  ${PROTO_DIR}/*.proto
+  ${PROTO_DIR}/*.proto \
+  ${PROTO_DIR}/chat/*.proto \
+  ${PROTO_DIR}/admin/*.proto \
+  ${PROTO_DIR}/google/api/*.proto \
+  ${PROTO_DIR}/protoc-gen-openapiv2/options/*.proto \
+  ${PROTO_DIR}/buf/validate/*.proto
 
 echo -e "${GREEN}Proto generation complete!${NC}"
 echo -e "${GREEN}Generated files are in ${OUT_DIR}${NC}"